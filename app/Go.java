@@ -1,6 +1,9 @@
 package go;
 
+import android.content.ComponentCallbacks2;
 import android.content.Context;
+import android.content.res.Configuration;
+import android.os.Build;
 import android.os.Looper;
 import android.util.Log;
 
@@ -22,8 +25,6 @@ public final class Go {
 		}
 		running = true;
 
-		// TODO(crawshaw): context.registerComponentCallbacks for runtime.GC
-
 		System.loadLibrary("gojni");
 
 		new Thread("GoMain") {
@@ -34,6 +35,16 @@ public final class Go {
 
 		Go.waitForRun();
 
+		// Pass memory warnings to the app.LowMemory callback.
+		// ComponentCallbacks2 was added in API level 14.
+		if (Build.VERSION.SDK_INT >= 14) {
+			ctx.registerComponentCallbacks(new ComponentCallbacks2() {
+				public void onTrimMemory(int level) { Go.trimMemory(level); }
+				public void onLowMemory() { Go.trimMemory(TRIM_MEMORY_COMPLETE); }
+				public void onConfigurationChanged(Configuration config) {}
+			});
+		}
+
 		new Thread("GoReceive") {
 			public void run() { Seq.receive(); }
 		}.start();
@@ -43,4 +54,5 @@ public final class Go {
 
 	private static native void run(Context ctx);
 	private static native void waitForRun();
+	private static native void trimMemory(int level);
 }
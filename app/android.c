@@ -19,6 +19,24 @@
 #define LOG_INFO(...) __android_log_print(ANDROID_LOG_INFO, "Go", __VA_ARGS__)
 #define LOG_FATAL(...) __android_log_print(ANDROID_LOG_FATAL, "Go", __VA_ARGS__)
 
+// The Go class of the bindings is in the Java package go, unless
+// gomobile bind -supportpkg relocates it. GOMOBILE_SUPPORT_PKG is then
+// the package, mangled as in JNI function names.
+#ifndef GOMOBILE_SUPPORT_PKG
+#define GOMOBILE_SUPPORT_PKG go
+#endif
+
+#define JNI_NAME(pkg, name) Java_ ## pkg ## _Go_ ## name
+#define GO_JNI(pkg, name) JNI_NAME(pkg, name)
+// GO(name) is the name of the JNI function of the native method name.
+#define GO(name) GO_JNI(GOMOBILE_SUPPORT_PKG, name)
+
+#define VIEW_JNI_NAME(pkg, name) Java_ ## pkg ## _GoView_ ## name
+#define GO_VIEW_JNI(pkg, name) VIEW_JNI_NAME(pkg, name)
+// GO_VIEW(name) is the name of the JNI function of the native method
+// name of the GoView generated by gomobile build -activity=fragment.
+#define GO_VIEW(name) GO_VIEW_JNI(GOMOBILE_SUPPORT_PKG, name)
+
 jint JNI_OnLoad(JavaVM* vm, void* reserved) {
 	current_vm = vm;
 	current_ctx = NULL;
@@ -135,6 +153,12 @@ static void wait_go_runtime() {
 	LOG_INFO("runtime started");
 }
 
+// contentRectChanged calls onContentRectChanged, whose export takes a
+// rect that is not const.
+static void contentRectChanged(ANativeActivity* activity, const ARect* rect) {
+	onContentRectChanged(activity, (ARect*)rect);
+}
+
 pthread_t nativeactivity_t;
 
 // Runtime entry point when embedding Go in other libraries.
@@ -175,35 +199,106 @@ void ANativeActivity_onCreate(ANativeActivity *activity, void* savedState, size_
 	activity->callbacks->onNativeWindowDestroyed = onNativeWindowDestroyed;
 	activity->callbacks->onInputQueueCreated = onInputQueueCreated;
 	activity->callbacks->onInputQueueDestroyed = onInputQueueDestroyed;
-	// TODO(crawshaw): Type mismatch for onContentRectChanged.
-	//activity->callbacks->onContentRectChanged = onContentRectChanged;
+	activity->callbacks->onContentRectChanged = contentRectChanged;
 	activity->callbacks->onConfigurationChanged = onConfigurationChanged;
 	activity->callbacks->onLowMemory = onLowMemory;
 
 	onCreate(activity);
 }
 
+// init_asset_manager sets asset_manager from current_ctx.
+static void init_asset_manager(JNIEnv* env) {
+	if (current_ctx == NULL) {
+		return;
+	}
+	jclass context_clazz = find_class(env, "android/content/Context");
+	jmethodID getassets = find_method(
+		env, context_clazz, "getAssets", "()Landroid/content/res/AssetManager;");
+	// Prevent the java AssetManager from being GC'd
+	jobject asset_manager_ref = (*env)->NewGlobalRef(
+		env, (*env)->CallObjectMethod(env, current_ctx, getassets));
+	asset_manager = AAssetManager_fromJava(env, asset_manager_ref);
+}
+
 // Runtime entry point when embedding Go in a Java App.
 JNIEXPORT void JNICALL
-Java_go_Go_run(JNIEnv* env, jclass clazz, jobject ctx) {
+GO(run)(JNIEnv* env, jclass clazz, jobject ctx) {
 	current_ctx = (*env)->NewGlobalRef(env, ctx);
-
-	if (current_ctx != NULL) {
-		// Init asset_manager.
-		jclass context_clazz = find_class(env, "android/content/Context");
-		jmethodID getassets = find_method(
-			env, context_clazz, "getAssets", "()Landroid/content/res/AssetManager;");
-		// Prevent the java AssetManager from being GC'd
-		jobject asset_manager_ref = (*env)->NewGlobalRef(
-			env, (*env)->CallObjectMethod(env, current_ctx, getassets));
-		asset_manager = AAssetManager_fromJava(env, asset_manager_ref);
-	}
-
+	init_asset_manager(env);
 	init_go_runtime(NULL);
 }
 
 // Used by Java initialization code to know when it can use cgocall.
 JNIEXPORT void JNICALL
-Java_go_Go_waitForRun(JNIEnv* env, jclass clazz) {
+GO(waitForRun)(JNIEnv* env, jclass clazz) {
 	wait_go_runtime();
 }
+
+// Called by Go.java when the system asks the app to trim its memory.
+JNIEXPORT void JNICALL
+GO(trimMemory)(JNIEnv* env, jclass clazz, jint level) {
+	onTrimMemory(level);
+}
+
+// Runtime entry point of an all-Go app shown by a GoView. It returns
+// once app.Run is called.
+JNIEXPORT void JNICALL
+GO_VIEW(init)(JNIEnv* env, jclass clazz, jobject ctx, jint dpi) {
+	current_ctx = (*env)->NewGlobalRef(env, ctx);
+	init_asset_manager(env);
+	view_hosted = 1;
+	view_dpi = dpi;
+	InitGoRuntime();
+}
+
+// Called by the GoView for the major version of OpenGL ES of its
+// context, given whether the device supports OpenGL ES 3.
+JNIEXPORT jint JNICALL
+GO_VIEW(glVersion)(JNIEnv* env, jclass clazz, jboolean es3) {
+	return onViewGLVersion(es3);
+}
+
+// The methods of the GLSurfaceView.Renderer of a GoView, called on its
+// renderer thread.
+
+JNIEXPORT void JNICALL
+GO_VIEW(surfaceCreated)(JNIEnv* env, jclass clazz) {
+	onViewSurfaceCreated();
+}
+
+JNIEXPORT void JNICALL
+GO_VIEW(surfaceChanged)(JNIEnv* env, jclass clazz, jint width, jint height, jfloat refreshRate) {
+	onViewSurfaceChanged(width, height, refreshRate);
+}
+
+JNIEXPORT void JNICALL
+GO_VIEW(drawFrame)(JNIEnv* env, jclass clazz, jlong nanos) {
+	onViewDrawFrame(nanos);
+}
+
+// Called by the GoView, on its renderer thread, for a motion event with
+// the pointer IDs, tool types and the x, y, pressure and size of each
+// pointer.
+JNIEXPORT void JNICALL
+GO_VIEW(touch)(JNIEnv* env, jclass clazz, jint action, jintArray ids, jintArray tools, jfloatArray values) {
+	jint n = (*env)->GetArrayLength(env, ids);
+	jint* cids = (*env)->GetIntArrayElements(env, ids, NULL);
+	jint* ctools = (*env)->GetIntArrayElements(env, tools, NULL);
+	jfloat* cvalues = (*env)->GetFloatArrayElements(env, values, NULL);
+	onViewTouch(action, n, cids, ctools, cvalues);
+	(*env)->ReleaseFloatArrayElements(env, values, cvalues, JNI_ABORT);
+	(*env)->ReleaseIntArrayElements(env, tools, ctools, JNI_ABORT);
+	(*env)->ReleaseIntArrayElements(env, ids, cids, JNI_ABORT);
+}
+
+// Called by the GoView, on its renderer thread, when it is paused.
+JNIEXPORT void JNICALL
+GO_VIEW(stop)(JNIEnv* env, jclass clazz) {
+	onViewStop();
+}
+
+// Called by the GoView when the system asks the app to trim its memory.
+JNIEXPORT void JNICALL
+GO_VIEW(trimMemory)(JNIEnv* env, jclass clazz, jint level) {
+	onTrimMemory(level);
+}
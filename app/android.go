@@ -43,8 +43,16 @@ ANativeActivity* current_native_activity;
 // asset_manager is the asset manager of the app.
 // For all-Go app, this is initialized in onCreate.
 // For go library app, this is set from the context passed to Go.run.
+// For an all-Go app in a GoView, it is set from the context passed to
+// GoView.init.
 AAssetManager* asset_manager;
 
+// view_hosted is 1 for an all-Go app shown by the GoView of gomobile
+// build -activity=fragment, whose renderer drives the app, and
+// view_dpi is then the density of its screen.
+int view_hosted;
+int view_dpi;
+
 // build_auxv builds an ELF auxiliary vector for initializing the Go
 // runtime. While there does not appear to be any spec for this
 // format, there are some notes in
@@ -62,14 +70,18 @@ void build_auxv(uint32_t *auxv, size_t len);
 */
 import "C"
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"sync"
 	"unsafe"
 
 	"golang.org/x/mobile/app/internal/callfn"
+	"golang.org/x/mobile/event"
 	"golang.org/x/mobile/geom"
 )
 
@@ -175,16 +187,22 @@ func onInputQueueDestroyed(activity *C.ANativeActivity, queue *C.AInputQueue) {
 	queue = nil
 }
 
-//export onContentRectChanged
-func onContentRectChanged(activity *C.ANativeActivity, rect *C.ARect) {
-}
-
 //export onConfigurationChanged
 func onConfigurationChanged(activity *C.ANativeActivity) {
 }
 
 //export onLowMemory
 func onLowMemory(activity *C.ANativeActivity) {
+	sendLowMemory(event.LowMemory{Level: event.TrimComplete})
+}
+
+// onTrimMemory is called by Go.java for the onTrimMemory calls of
+// ComponentCallbacks2, in a library. The NativeActivity of an all-Go app
+// only reports onLowMemory.
+//
+//export onTrimMemory
+func onTrimMemory(level C.int) {
+	sendLowMemory(event.LowMemory{Level: event.TrimLevel(level)})
 }
 
 type androidState struct {
@@ -222,6 +240,58 @@ type asset struct {
 	name string
 }
 
+var (
+	assetDirsOnce sync.Once
+	assetDirs     map[string]bool
+)
+
+func readDir(name string) ([]os.FileInfo, error) {
+	assetDirsOnce.Do(func() {
+		assetDirs = make(map[string]bool)
+		f, err := openAsset(assetDirIndex)
+		if err != nil {
+			return // no assets, or not packed by gomobile
+		}
+		defer f.Close()
+		if data, err := ioutil.ReadAll(f); err == nil {
+			assetDirs = parseAssetDirIndex(data)
+		}
+	})
+	return readAssetDir(ndkAssetManager{}, assetDirs, name)
+}
+
+// ndkAssetManager lists assets with the NDK AAssetManager.
+type ndkAssetManager struct{}
+
+func (ndkAssetManager) files(dir string) ([]string, error) {
+	cdir := C.CString(dir)
+	defer C.free(unsafe.Pointer(cdir))
+	d := C.AAssetManager_openDir(C.asset_manager, cdir)
+	if d == nil {
+		return nil, &os.PathError{Op: "readdir", Path: dir, Err: errors.New("bad asset directory")}
+	}
+	defer C.AAssetDir_close(d)
+	var names []string
+	for {
+		n := C.AAssetDir_getNextFileName(d)
+		if n == nil {
+			return names, nil
+		}
+		names = append(names, C.GoString(n))
+	}
+}
+
+func (ndkAssetManager) size(name string) (int64, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	a := C.AAssetManager_open(C.asset_manager, cname, C.AASSET_MODE_UNKNOWN)
+	if a == nil {
+		return 0, &os.PathError{Op: "stat", Path: name, Err: errors.New("bad asset")}
+	}
+	defer C.AAsset_close(a)
+	return int64(C.AAsset_getLength(a)), nil
+}
+
 func (a *asset) errorf(op string, format string, v ...interface{}) error {
 	return &os.PathError{
 		Op:   op,
@@ -273,6 +343,8 @@ func notifyInitDone() {
 }
 
 func run(cb Callbacks) {
+	defer reportCrash()
+
 	// We want to keep the event loop on a consistent OS thread.
 	runtime.LockOSThread()
 
@@ -282,7 +354,12 @@ func run(cb Callbacks) {
 	C.free(unsafe.Pointer(ctag))
 	C.free(unsafe.Pointer(cstr))
 
-	if C.current_native_activity == nil {
+	if C.view_hosted != 0 {
+		geom.PixelsPerPt = float32(C.view_dpi) / 72
+		runView(cb)
+		notifyInitDone()
+		select {}
+	} else if C.current_native_activity == nil {
 		runStart(cb)
 		notifyInitDone()
 		select {}
@@ -7,7 +7,9 @@
 package app
 
 import (
+	"image/color"
 	"io"
+	"os"
 
 	"golang.org/x/mobile/event"
 )
@@ -58,11 +60,52 @@ type Callbacks struct {
 	// Draw is called by the render loop to draw the screen.
 	//
 	// Drawing is done into a framebuffer, which is then swapped onto the
-	// screen when Draw returns. It is called 60 times a second.
+	// screen when Draw returns. It is called once for each refresh of
+	// the display, usually 60 times a second.
 	Draw func()
 
+	// Viewport makes the app set the OpenGL viewport to the whole
+	// window before Draw, each time the window changes size. Apps
+	// setting their own viewport leave it false.
+	Viewport bool
+
+	// ClearColor, if not nil, is the color the app clears the screen
+	// to before each Draw. If it is nil, the app leaves the screen as
+	// it is, except on iOS and OS X, where it clears it to black.
+	ClearColor color.Color
+
+	// Frame is called before each Draw with the timing of the frame,
+	// which is paced by the vsync of the display.
+	Frame func(event.Frame)
+
 	// Touch is called by the app when a touch event occurs.
 	Touch func(event.Touch)
+
+	// Keyboard is called when the soft keyboard shown by ShowKeyboard
+	// appears, disappears or changes size, so the app can move what the
+	// keyboard covers.
+	Keyboard func(event.Keyboard)
+
+	// LowMemory is called when the operating system is running low on
+	// memory. The app should release what it can, such as caches.
+	// Afterwards, the memory freed is returned to the operating system.
+	//
+	// LowMemory is called on the same goroutine as the other callbacks.
+	// It is equivalent to onTrimMemory() and onLowMemory() on Android
+	// and didReceiveMemoryWarning on iOS.
+	LowMemory func(event.LowMemory)
+
+	// Power is called with the battery and thermal state of the device
+	// when the app starts, and then when it changes. Android reports
+	// the changes within a few seconds. Power is not called on desktops.
+	Power func(event.Power)
+
+	// GLVersion is the major version of the OpenGL ES context created
+	// for an all-Go app on Android and iOS: 2, the default, or 3.
+	// If the device does not provide the requested version, the app
+	// logs ErrGLVersion and falls back to OpenGL ES 2. Use gl.Version
+	// to find the version of the context.
+	GLVersion int
 }
 
 // Open opens a named asset.
@@ -81,6 +124,15 @@ func Open(name string) (ReadSeekCloser, error) {
 	return openAsset(name)
 }
 
+// ReadDir returns the entries of the named asset directory, sorted by
+// name. The top-level assets are listed by ReadDir(".").
+//
+// On Android, the directories of an app are known only if it was built
+// by gomobile, which packs an index of them with the assets.
+func ReadDir(name string) ([]os.FileInfo, error) {
+	return readDir(name)
+}
+
 // ReadSeekCloser is an io.ReadSeeker and io.Closer.
 type ReadSeekCloser interface {
 	io.ReadSeeker
@@ -0,0 +1,108 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// assetDirIndex is the name of the asset listing the directories of the
+// other assets, one slash-separated name per line, packed by gomobile
+// build. The NDK lists only the files of an asset directory, so the
+// index is how ReadDir finds its subdirectories. It must match the name
+// in golang.org/x/mobile/cmd/gomobile.
+const assetDirIndex = "_gomobile_dirs"
+
+// An assetManager lists the files of packed assets, as the Android
+// AAssetManager does.
+type assetManager interface {
+	// files returns the names of the files directly in the asset
+	// directory dir, which is "" for the top level. Subdirectories
+	// are not listed.
+	files(dir string) ([]string, error)
+
+	// size returns the size of the named asset file.
+	size(name string) (int64, error)
+}
+
+// parseAssetDirIndex returns the directory names in the contents of the
+// asset directory index.
+func parseAssetDirIndex(data []byte) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			dirs[line] = true
+		}
+	}
+	return dirs
+}
+
+// readAssetDir returns the entries of the asset directory name, sorted by
+// name. The files come from m and the subdirectories from dirs, the
+// directories of the asset directory index.
+func readAssetDir(m assetManager, dirs map[string]bool, name string) ([]os.FileInfo, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name != "" && !dirs[name] {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+	files, err := m.files(name)
+	if err != nil {
+		return nil, err
+	}
+	var infos []os.FileInfo
+	for _, f := range files {
+		if name == "" && f == assetDirIndex {
+			continue
+		}
+		size, err := m.size(path.Join(name, f))
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, &assetInfo{name: f, size: size})
+	}
+	for dir := range dirs {
+		parent := path.Dir(dir)
+		if parent == "." {
+			parent = ""
+		}
+		if parent == name {
+			infos = append(infos, &assetInfo{name: path.Base(dir), dir: true})
+		}
+	}
+	sort.Sort(byInfoName(infos))
+	return infos, nil
+}
+
+// assetInfo describes an asset read by readAssetDir.
+type assetInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (a *assetInfo) Name() string       { return a.name }
+func (a *assetInfo) Size() int64        { return a.size }
+func (a *assetInfo) ModTime() time.Time { return time.Time{} }
+func (a *assetInfo) IsDir() bool        { return a.dir }
+func (a *assetInfo) Sys() interface{}   { return nil }
+
+func (a *assetInfo) Mode() os.FileMode {
+	if a.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+type byInfoName []os.FileInfo
+
+func (s byInfoName) Len() int           { return len(s) }
+func (s byInfoName) Less(i, j int) bool { return s[i].Name() < s[j].Name() }
+func (s byInfoName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,92 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// fakeAssetManager holds asset files, by name, as an APK does.
+type fakeAssetManager map[string]string
+
+func (m fakeAssetManager) files(dir string) ([]string, error) {
+	var names []string
+	for name := range m {
+		d := path.Dir(name)
+		if d == "." {
+			d = ""
+		}
+		if d == dir {
+			names = append(names, path.Base(name))
+		}
+	}
+	return names, nil
+}
+
+func (m fakeAssetManager) size(name string) (int64, error) {
+	data, ok := m[name]
+	if !ok {
+		return 0, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return int64(len(data)), nil
+}
+
+func TestReadAssetDir(t *testing.T) {
+	m := fakeAssetManager{
+		"hello.txt":        "hello",
+		"img/b.png":        "png",
+		"img/a.png":        "pngpng",
+		"img/icons/c.png":  "",
+		"ui/fonts/mono.tt": "font",
+		assetDirIndex:      "img\nimg/icons\nui\nui/fonts\n",
+	}
+	dirs := parseAssetDirIndex([]byte(m[assetDirIndex]))
+
+	type entry struct {
+		name string
+		size int64
+		dir  bool
+	}
+	tests := []struct {
+		dir  string
+		want []entry
+	}{
+		{".", []entry{{"hello.txt", 5, false}, {"img", 0, true}, {"ui", 0, true}}},
+		{"", []entry{{"hello.txt", 5, false}, {"img", 0, true}, {"ui", 0, true}}},
+		{"img", []entry{{"a.png", 6, false}, {"b.png", 3, false}, {"icons", 0, true}}},
+		{"img/", []entry{{"a.png", 6, false}, {"b.png", 3, false}, {"icons", 0, true}}},
+		{"img/icons", []entry{{"c.png", 0, false}}},
+		{"ui", []entry{{"fonts", 0, true}}},
+		{"ui/fonts", []entry{{"mono.tt", 4, false}}},
+	}
+	for _, tt := range tests {
+		infos, err := readAssetDir(m, dirs, tt.dir)
+		if err != nil {
+			t.Errorf("readAssetDir(%q): %v", tt.dir, err)
+			continue
+		}
+		var got []entry
+		for _, fi := range infos {
+			got = append(got, entry{fi.Name(), fi.Size(), fi.IsDir()})
+			if fi.IsDir() != fi.Mode().IsDir() {
+				t.Errorf("readAssetDir(%q): %s has mode %v", tt.dir, fi.Name(), fi.Mode())
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readAssetDir(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+
+	for _, dir := range []string{"missing", "hello.txt", "img/missing"} {
+		if _, err := readAssetDir(m, dirs, dir); !os.IsNotExist(err) {
+			t.Errorf("readAssetDir(%q): got %v, want a not exist error", dir, err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// writeCrash, if not nil, writes the report of a panic crashing the app
+// where the crash reporters of the platform find it. The runtime prints
+// its own report to stderr, but on Android stderr reaches the log through
+// a pipe, and the process aborts before the lines are read from it.
+var writeCrash func(report string)
+
+// crashReport formats the report of a panic with value p, raised by the
+// goroutine whose trace is stack.
+func crashReport(p interface{}, stack []byte) string {
+	return fmt.Sprintf("panic: %v\n\n%s", p, stack)
+}
+
+// reportCrash, deferred by the goroutines running the callbacks of the
+// app, reports a panic with writeCrash and panics again with the same
+// value, so the app still crashes.
+func reportCrash() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if writeCrash != nil {
+		writeCrash(crashReport(p, debug.Stack()))
+	}
+	panic(p)
+}
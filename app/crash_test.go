@@ -0,0 +1,60 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCrashReport(t *testing.T) {
+	stack := []byte("goroutine 1 [running]:\nmain.main()\n")
+	tests := []struct {
+		p    interface{}
+		want string
+	}{
+		{"boom", "panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n"},
+		{errors.New("no window"), "panic: no window\n\ngoroutine 1 [running]:\nmain.main()\n"},
+		{42, "panic: 42\n\ngoroutine 1 [running]:\nmain.main()\n"},
+	}
+	for _, tt := range tests {
+		if got := crashReport(tt.p, stack); got != tt.want {
+			t.Errorf("crashReport(%v) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestReportCrash(t *testing.T) {
+	defer func(w func(string)) { writeCrash = w }(writeCrash)
+	var report string
+	writeCrash = func(r string) { report = r }
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the panic to go on with boom", p)
+			}
+		}()
+		defer reportCrash()
+		panic("boom")
+	}()
+	if !strings.HasPrefix(report, "panic: boom\n\n") {
+		t.Errorf("report does not start with the panic:\n%s", report)
+	}
+	if !strings.Contains(report, "TestReportCrash") {
+		t.Errorf("report does not hold the stack of the panicking goroutine:\n%s", report)
+	}
+
+	report = ""
+	func() {
+		defer reportCrash()
+	}()
+	if report != "" {
+		t.Errorf("reported a crash without a panic:\n%s", report)
+	}
+}
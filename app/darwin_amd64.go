@@ -29,6 +29,7 @@ uint64 threadID();
 */
 import "C"
 import (
+	"image/color"
 	"log"
 	"runtime"
 	"sync"
@@ -81,6 +82,8 @@ func initGL() {
 
 var cb Callbacks
 var initGLOnce sync.Once
+var clock frameClock
+var setup glSetup
 
 var touchEvents struct {
 	sync.Mutex
@@ -96,6 +99,7 @@ func sendTouch(ty event.TouchType, x, y float32) {
 			X: geom.Pt(x / geom.PixelsPerPt),
 			Y: geom.Height - geom.Pt(y/geom.PixelsPerPt),
 		},
+		Tool: event.ToolMouse,
 	})
 	touchEvents.Unlock()
 }
@@ -109,8 +113,11 @@ func eventMouseDragged(x, y float32) { sendTouch(event.TouchMove, x, y) }
 //export eventMouseEnd
 func eventMouseEnd(x, y float32) { sendTouch(event.TouchEnd, x, y) }
 
+// drawgl draws the frame presented at time t, in seconds, on a display
+// refreshed every period seconds.
+//
 //export drawgl
-func drawgl(ctx C.GLintptr) {
+func drawgl(ctx C.GLintptr, t, period float64) {
 	// The call to lockContext loads the OpenGL context into
 	// thread-local storage for use by the underlying GL calls
 	// done in the user's Draw function. We need to stay on
@@ -130,9 +137,10 @@ func drawgl(ctx C.GLintptr) {
 		}
 	}
 
-	// TODO: is the library or the app responsible for clearing the buffers?
-	gl.ClearColor(0, 0, 0, 1)
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	handleFrame(cb, &clock, secondsDuration(t), secondsDuration(period))
+
+	// The screen has always been cleared to black before Draw.
+	setup.beforeDraw(cb, color.Black)
 	if cb.Draw != nil {
 		cb.Draw()
 	}
@@ -144,3 +152,7 @@ func drawgl(ctx C.GLintptr) {
 	// C.runApp, which won't give the thread up.
 	runtime.UnlockOSThread()
 }
+
+// Desktops have a physical keyboard, and no soft keyboard to show.
+func showKeyboard(mode KeyboardMode) {}
+func hideKeyboard()                  {}
@@ -18,7 +18,8 @@ static CVReturn displayLinkDraw(CVDisplayLinkRef displayLink, const CVTimeStamp*
 {
 	NSOpenGLView* view = displayLinkContext;
 	NSOpenGLContext *currentContext = [view openGLContext];
-	drawgl((GLintptr)currentContext);
+	double scale = outputTime->videoTimeScale;
+	drawgl((GLintptr)currentContext, outputTime->videoTime / scale, outputTime->videoRefreshPeriod / scale);
 	return kCVReturnSuccess;
 }
 
@@ -12,20 +12,27 @@ package app
 #include <sys/utsname.h>
 #include <stdint.h>
 #include <pthread.h>
+#include <stdlib.h>
 
 extern struct utsname sysInfo;
 
 void runApp(void);
 void setContext(void* context);
+int newGLContext(int version);
 uint64_t threadID();
+void reportGoPanic(char* report);
+void showKeyboard(int mode);
+void hideKeyboard();
 */
 import "C"
 import (
+	"image/color"
 	"log"
 	"runtime"
 	"sync"
 	"unsafe"
 
+	"golang.org/x/mobile/event"
 	"golang.org/x/mobile/geom"
 	"golang.org/x/mobile/gl"
 )
@@ -42,6 +49,11 @@ func init() {
 	// https://groups.google.com/forum/#!msg/golang-nuts/IiWZ2hUuLDA/SNKYYZBelsYJ
 	runtime.LockOSThread()
 	initThreadID = uint64(C.threadID())
+	writeCrash = func(report string) {
+		creport := C.CString(report)
+		C.reportGoPanic(creport)
+		C.free(unsafe.Pointer(creport))
+	}
 }
 
 func run(callbacks Callbacks) {
@@ -114,14 +126,61 @@ func setGeom(width, height int) {
 	geom.Height = geom.Pt(float32(height) / geom.PixelsPerPt)
 }
 
+//export createGLContext
+func createGLContext() {
+	create := func(version int) bool {
+		return C.newGLContext(C.int(version)) != 0
+	}
+	if _, err := negotiateGLVersion(cb.GLVersion, create); err != nil {
+		log.Print(err)
+	}
+}
+
 func initGL() {
 }
 
 var cb Callbacks
 var initGLOnce sync.Once
+var clock frameClock
+var setup glSetup
+
+func showKeyboard(mode KeyboardMode) {
+	C.showKeyboard(C.int(mode))
+}
+
+func hideKeyboard() {
+	C.hideKeyboard()
+}
+
+// keyboardChanged is called when the soft keyboard is shown, hidden or
+// resized, with the height in pixels of the screen it covers.
+//
+//export keyboardChanged
+func keyboardChanged(height int) {
+	sendKeyboard(keyboardCovering(height, 0, geom.PixelsPerPt))
+}
 
+// powerChanged is called with the battery level and state of UIDevice,
+// and the thermal state of NSProcessInfo, or -1 before iOS 11.
+//
+//export powerChanged
+func powerChanged(level float32, state, thermal int) {
+	sendPower(iosPower(level, state, thermal))
+}
+
+//export lowMemoryWarning
+func lowMemoryWarning() {
+	// iOS reports no level of memory pressure.
+	sendLowMemory(event.LowMemory{Level: event.TrimComplete})
+}
+
+// drawgl draws the frame presented at time t, in seconds, on a display
+// refreshed every period seconds.
+//
 //export drawgl
-func drawgl(ctx uintptr) {
+func drawgl(ctx uintptr, t, period float64) {
+	defer reportCrash()
+
 	// The call to lockContext loads the OpenGL context into
 	// thread-local storage for use by the underlying GL calls
 	// done in the user's Draw function. We need to stay on
@@ -131,9 +190,26 @@ func drawgl(ctx uintptr) {
 
 	initGLOnce.Do(initGL)
 
-	// TODO not here?
-	gl.ClearColor(0, 0, 0, 1)
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	select {
+	case e := <-lowMemory:
+		handleLowMemory(cb, e)
+	default:
+	}
+	select {
+	case e := <-keyboard:
+		handleKeyboard(cb, e)
+	default:
+	}
+	select {
+	case e := <-power:
+		handlePower(cb, e)
+	default:
+	}
+
+	handleFrame(cb, &clock, secondsDuration(t), secondsDuration(period))
+
+	// The screen has always been cleared to black before Draw.
+	setup.beforeDraw(cb, color.Black)
 	if cb.Draw != nil {
 		cb.Draw()
 	}
@@ -11,6 +11,7 @@
 
 #import <UIKit/UIKit.h>
 #import <GLKit/GLKit.h>
+#import <QuartzCore/QuartzCore.h>
 
 struct utsname sysInfo;
 
@@ -32,25 +33,99 @@ struct utsname sysInfo;
 }
 @end
 
+EAGLContext *glContext; // set by newGLContext
+
+// keyboardField is the text field, of zero size, that is the first
+// responder while the soft keyboard is shown.
+UITextField *keyboardField;
+
+// newGLContext creates an OpenGL ES context of the given major version,
+// and reports whether it succeeded.
+int newGLContext(int version) {
+	EAGLRenderingAPI api = kEAGLRenderingAPIOpenGLES2;
+	if (version == 3) {
+		api = kEAGLRenderingAPIOpenGLES3;
+	}
+	glContext = [[EAGLContext alloc] initWithAPI:api];
+	return glContext != nil;
+}
+
 @interface AppController ()
 @property (strong, nonatomic) EAGLContext *context;
+@property (strong, nonatomic) CADisplayLink *displayLink;
 @end
 
 @implementation AppController
 - (void)viewDidLoad {
 	[super viewDidLoad];
-	self.context = [[EAGLContext alloc] initWithAPI:kEAGLRenderingAPIOpenGLES2];
+	createGLContext();
+	self.context = glContext;
 	GLKView *view = (GLKView *)self.view;
 	view.context = self.context;
 	view.drawableDepthFormat = GLKViewDrawableDepthFormat24;
+
+	// The frames are paced by a CADisplayLink, which times them,
+	// rather than by the GLKViewController.
+	self.paused = YES;
+	self.resumeOnDidBecomeActive = NO;
+	self.displayLink = [CADisplayLink displayLinkWithTarget:self selector:@selector(frame:)];
+	[self.displayLink addToRunLoop:[NSRunLoop mainRunLoop] forMode:NSDefaultRunLoopMode];
+
+	keyboardField = [[UITextField alloc] initWithFrame:CGRectZero];
+	keyboardField.autocorrectionType = UITextAutocorrectionTypeNo;
+	[view addSubview:keyboardField];
+	[[NSNotificationCenter defaultCenter] addObserver:self
+		selector:@selector(keyboardWillChangeFrame:)
+		name:UIKeyboardWillChangeFrameNotification
+		object:nil];
+
+	[UIDevice currentDevice].batteryMonitoringEnabled = YES;
+	NSArray *powerNotifications = @[
+		UIDeviceBatteryLevelDidChangeNotification,
+		UIDeviceBatteryStateDidChangeNotification,
+		@"NSProcessInfoThermalStateDidChangeNotification", // iOS 11
+	];
+	for (NSString *name in powerNotifications) {
+		[[NSNotificationCenter defaultCenter] addObserver:self
+			selector:@selector(powerChanged:)
+			name:name
+			object:nil];
+	}
+	[self powerChanged:nil];
+}
+- (void)powerChanged:(NSNotification *)n {
+	UIDevice *device = [UIDevice currentDevice];
+	NSProcessInfo *info = [NSProcessInfo processInfo];
+	GoInt thermal = -1;
+	if ([info respondsToSelector:@selector(thermalState)]) {
+		thermal = (GoInt)[info thermalState];
+	}
+	powerChanged(device.batteryLevel, (GoInt)device.batteryState, thermal);
+}
+- (void)keyboardWillChangeFrame:(NSNotification *)n {
+	CGRect frame = [n.userInfo[UIKeyboardFrameEndUserInfoKey] CGRectValue];
+	CGRect covered = CGRectIntersection(frame, [UIScreen mainScreen].bounds);
+	CGFloat height = CGRectIsNull(covered) ? 0 : covered.size.height;
+	keyboardChanged((GoInt)(height * [UIScreen mainScreen].scale));
 }
-- (void)update {
+- (void)frame:(CADisplayLink *)link {
+	// The time the frame is presented is targetTimestamp, from iOS 10.
+	CFTimeInterval t = link.timestamp + link.duration;
+	if ([link respondsToSelector:@selector(targetTimestamp)]) {
+		t = link.targetTimestamp;
+	}
+
 	GLKView *view = (GLKView *)self.view;
 	int w = [view drawableWidth];
 	int h = [view drawableHeight];
 	setGeom(w, h);
 
-	drawgl((GoUintptr)self.context);
+	drawgl((GoUintptr)self.context, t, link.duration);
+	[view display];
+}
+- (void)didReceiveMemoryWarning {
+	[super didReceiveMemoryWarning];
+	lowMemoryWarning();
 }
 @end
 
@@ -75,3 +150,41 @@ uint64_t threadID() {
 	}
 	return id;
 }
+
+// reportGoPanic logs the report of a Go panic crashing the app, and
+// passes it to the uncaught exception handler that crash reporters set
+// with NSSetUncaughtExceptionHandler. Go then aborts the process.
+void reportGoPanic(char* report) {
+	NSString *reason = [NSString stringWithUTF8String:report];
+	NSLog(@"%@", reason);
+	NSUncaughtExceptionHandler *handler = NSGetUncaughtExceptionHandler();
+	if (handler != NULL) {
+		handler([NSException exceptionWithName:@"GoPanic" reason:reason userInfo:nil]);
+	}
+}
+
+// keyboardTypes maps the KeyboardModes of keyboard.go to the keyboard
+// types of UIKit.
+static UIKeyboardType keyboardTypes[] = {
+	UIKeyboardTypeDefault,      // KeyboardText
+	UIKeyboardTypeNumberPad,    // KeyboardNumber
+	UIKeyboardTypeEmailAddress, // KeyboardEmail
+};
+
+void showKeyboard(int mode) {
+	UIKeyboardType type = UIKeyboardTypeDefault;
+	if (mode >= 0 && mode < sizeof(keyboardTypes)/sizeof(keyboardTypes[0])) {
+		type = keyboardTypes[mode];
+	}
+	dispatch_async(dispatch_get_main_queue(), ^{
+		keyboardField.keyboardType = type;
+		[keyboardField reloadInputViews];
+		[keyboardField becomeFirstResponder];
+	});
+}
+
+void hideKeyboard() {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[keyboardField resignFirstResponder];
+	});
+}
@@ -7,6 +7,7 @@
 package app
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 )
@@ -23,3 +24,10 @@ func openAsset(name string) (ReadSeekCloser, error) {
 	}
 	return f, nil
 }
+
+func readDir(name string) ([]os.FileInfo, error) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join("assets", name)
+	}
+	return ioutil.ReadDir(name)
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!android darwin
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "app-readdir-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"hello.txt", "img/b.png", "img/a.png", "img/icons/c.png"} {
+		p := filepath.Join(dir, "assets", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{".", []string{"hello.txt", "img/"}},
+		{"img", []string{"a.png", "b.png", "icons/"}},
+		{"img/icons", []string{"c.png"}},
+	}
+	for _, tt := range tests {
+		infos, err := ReadDir(tt.dir)
+		if err != nil {
+			t.Errorf("ReadDir(%q): %v", tt.dir, err)
+			continue
+		}
+		var got []string
+		for _, fi := range infos {
+			name := fi.Name()
+			if fi.IsDir() {
+				name += "/"
+			}
+			got = append(got, name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReadDir(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+	if _, err := ReadDir("missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadDir(missing): got %v, want a not exist error", err)
+	}
+}
@@ -40,6 +40,10 @@ on iOS, a single UIWindow. Touch events will be accessible via this
 package. When Android support is out of preview, all APIs supported by
 the Android NDK will be exposed via a Go package.
 
+A native app built with gomobile build -activity=fragment is instead
+shown by a view, go.GoView, which a Java app places in its own
+activities. The renderer thread of the view calls the Callbacks.
+
 See http://golang.org/x/mobile/example/sprite for an example app.
 
 Lifecycle in Native Apps
@@ -0,0 +1,77 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"time"
+
+	"golang.org/x/mobile/event"
+)
+
+const (
+	// defaultRefreshRate is the refresh rate reported when the
+	// operating system does not report one.
+	defaultRefreshRate = 60
+
+	// maxFrameDelta is the longest time between two frames that is
+	// reported as such. Longer gaps, such as the time the app was
+	// stopped, are reported as one refresh period, so animations do
+	// not jump ahead.
+	maxFrameDelta = 250 * time.Millisecond
+)
+
+// frameClock turns the vsync timestamps of the operating system into
+// Frame events. The timestamps are on the clock of the operating
+// system, whose origin is unknown.
+type frameClock struct {
+	started bool
+	last    time.Duration // timestamp of the previous frame
+	time    time.Duration // Time of the previous frame
+}
+
+// frame returns the Frame presented at timestamp t, on a display
+// refreshed every period. A period of zero is unknown.
+func (c *frameClock) frame(t, period time.Duration) event.Frame {
+	rate := float32(defaultRefreshRate)
+	if period > 0 {
+		rate = float32(time.Second) / float32(period)
+	} else {
+		period = time.Second / defaultRefreshRate
+	}
+	if !c.started {
+		c.started = true
+		c.last = t
+		return event.Frame{RefreshRate: rate}
+	}
+	delta := t - c.last
+	if delta < 0 {
+		// A timestamp earlier than the previous one does not move
+		// the animation back.
+		return event.Frame{Time: c.time, RefreshRate: rate}
+	}
+	if delta > maxFrameDelta {
+		delta = period
+	}
+	c.last = t
+	c.time += delta
+	return event.Frame{Time: c.time, Delta: delta, RefreshRate: rate}
+}
+
+// handleFrame calls Callbacks.Frame with the Frame presented at
+// timestamp t, before the app draws it.
+func handleFrame(cb Callbacks, c *frameClock, t, period time.Duration) {
+	e := c.frame(t, period)
+	if cb.Frame != nil {
+		cb.Frame(e)
+	}
+}
+
+// secondsDuration converts a timestamp in seconds, as reported on iOS
+// and OS X, to a time.Duration.
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
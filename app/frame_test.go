@@ -0,0 +1,63 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/mobile/event"
+)
+
+func TestFrameClock(t *testing.T) {
+	const ms = time.Millisecond
+	period := 16 * ms
+	tests := []struct {
+		t    time.Duration
+		want event.Frame
+	}{
+		// Time starts at the first frame, whatever the clock.
+		{5000 * ms, event.Frame{Time: 0, Delta: 0}},
+		{5016 * ms, event.Frame{Time: 16 * ms, Delta: 16 * ms}},
+		// A dropped frame is a longer delta.
+		{5048 * ms, event.Frame{Time: 48 * ms, Delta: 32 * ms}},
+		// A timestamp going back does not move the animation back.
+		{5040 * ms, event.Frame{Time: 48 * ms, Delta: 0}},
+		{5064 * ms, event.Frame{Time: 64 * ms, Delta: 16 * ms}},
+		// A long pause advances one period.
+		{9000 * ms, event.Frame{Time: 80 * ms, Delta: 16 * ms}},
+		{9016 * ms, event.Frame{Time: 96 * ms, Delta: 16 * ms}},
+	}
+	var c frameClock
+	for _, tt := range tests {
+		got := c.frame(tt.t, period)
+		tt.want.RefreshRate = 62.5
+		if got != tt.want {
+			t.Errorf("frame(%v) = %+v, want %+v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestFrameClockRefreshRate(t *testing.T) {
+	var c frameClock
+	if got := c.frame(0, 0).RefreshRate; got != defaultRefreshRate {
+		t.Errorf("unknown period: RefreshRate = %v, want %v", got, defaultRefreshRate)
+	}
+	// A long pause advances one default period.
+	if got, want := c.frame(time.Second, 0).Delta, time.Second/defaultRefreshRate; got != want {
+		t.Errorf("unknown period: Delta after pause = %v, want %v", got, want)
+	}
+	if got := c.frame(0, 10*time.Millisecond).RefreshRate; got != 100 {
+		t.Errorf("RefreshRate = %v, want 100", got)
+	}
+}
+
+func TestSecondsDuration(t *testing.T) {
+	if got, want := secondsDuration(1.5), 1500*time.Millisecond; got != want {
+		t.Errorf("secondsDuration(1.5) = %v, want %v", got, want)
+	}
+}
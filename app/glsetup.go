@@ -0,0 +1,67 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"image/color"
+
+	"golang.org/x/mobile/geom"
+	"golang.org/x/mobile/gl"
+)
+
+// glSetup prepares the screen for each Draw, as configured by the
+// Viewport and ClearColor fields of Callbacks.
+type glSetup struct {
+	w, h int // viewport last set, in pixels
+}
+
+// viewportSize returns the size in pixels of a window of the given
+// width and height, at pixelsPerPt pixels per point.
+func viewportSize(width, height geom.Pt, pixelsPerPt float32) (w, h int) {
+	// The window size in pixels was divided by pixelsPerPt; round
+	// the product back to whole pixels.
+	w = int(float32(width)*pixelsPerPt + 0.5)
+	h = int(float32(height)*pixelsPerPt + 0.5)
+	return w, h
+}
+
+// viewport returns the viewport covering a window of the given size,
+// and reports whether it differs from the one returned before.
+func (s *glSetup) viewport(width, height geom.Pt, pixelsPerPt float32) (w, h int, changed bool) {
+	w, h = viewportSize(width, height, pixelsPerPt)
+	if w == s.w && h == s.h {
+		return w, h, false
+	}
+	s.w, s.h = w, h
+	return w, h, true
+}
+
+// glColor returns the components of c as OpenGL color components,
+// between 0 and 1.
+func glColor(c color.Color) (r, g, b, a float32) {
+	cr, cg, cb, ca := c.RGBA()
+	return float32(cr) / 0xffff, float32(cg) / 0xffff, float32(cb) / 0xffff, float32(ca) / 0xffff
+}
+
+// beforeDraw sets the viewport to the window if it changed size, and
+// clears the screen, as configured by cb. The screen is cleared to
+// defaultClear if cb.ClearColor is nil, and not at all if both are.
+func (s *glSetup) beforeDraw(cb Callbacks, defaultClear color.Color) {
+	if cb.Viewport {
+		if w, h, changed := s.viewport(geom.Width, geom.Height, geom.PixelsPerPt); changed {
+			gl.Viewport(0, 0, w, h)
+		}
+	}
+	c := cb.ClearColor
+	if c == nil {
+		c = defaultClear
+	}
+	if c != nil {
+		gl.ClearColor(glColor(c))
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	}
+}
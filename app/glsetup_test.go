@@ -0,0 +1,41 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"image/color"
+	"testing"
+
+	"golang.org/x/mobile/geom"
+)
+
+func TestViewport(t *testing.T) {
+	// A 1080x1920 pixel screen at 420 dpi, whose size in points is
+	// not a whole number.
+	ppt := float32(420) / 72
+	width := geom.Pt(float32(1080) / ppt)
+	height := geom.Pt(float32(1920) / ppt)
+
+	var s glSetup
+	if w, h, changed := s.viewport(width, height, ppt); w != 1080 || h != 1920 || !changed {
+		t.Errorf("first viewport = %dx%d, changed %v, want 1080x1920, changed true", w, h, changed)
+	}
+	if _, _, changed := s.viewport(width, height, ppt); changed {
+		t.Error("viewport of the same window changed")
+	}
+	// Rotating the screen swaps the sides.
+	if w, h, changed := s.viewport(height, width, ppt); w != 1920 || h != 1080 || !changed {
+		t.Errorf("rotated viewport = %dx%d, changed %v, want 1920x1080, changed true", w, h, changed)
+	}
+}
+
+func TestGLColor(t *testing.T) {
+	r, g, b, a := glColor(color.RGBA{0x33, 0x66, 0x99, 0xff})
+	if r != 0.2 || g != 0.4 || b != 0.6 || a != 1 {
+		t.Errorf("glColor = %v, %v, %v, %v, want 0.2, 0.4, 0.6, 1", r, g, b, a)
+	}
+}
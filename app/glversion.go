@@ -0,0 +1,35 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import "errors"
+
+// ErrGLVersion is logged when the OpenGL ES version requested by
+// Callbacks.GLVersion is not available.
+var ErrGLVersion = errors.New("app: requested OpenGL ES version not available, using OpenGL ES 2")
+
+var errNoGLContext = errors.New("app: cannot create an OpenGL ES 2 context")
+
+// negotiateGLVersion creates the OpenGL ES context of an app with
+// create, which reports whether it created a context of the given
+// major version. It tries the requested version, falling back to
+// OpenGL ES 2, and returns the version created.
+func negotiateGLVersion(requested int, create func(version int) bool) (int, error) {
+	if requested == 0 {
+		requested = 2
+	}
+	if requested == 3 && create(3) {
+		return 3, nil
+	}
+	if !create(2) {
+		return 0, errNoGLContext
+	}
+	if requested != 2 {
+		return 2, ErrGLVersion
+	}
+	return 2, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNegotiateGLVersion(t *testing.T) {
+	tests := []struct {
+		requested int
+		available []int // versions the fake context factory creates
+		version   int
+		err       error
+		tried     []int
+	}{
+		{requested: 0, available: []int{2, 3}, version: 2, tried: []int{2}},
+		{requested: 2, available: []int{2, 3}, version: 2, tried: []int{2}},
+		{requested: 3, available: []int{2, 3}, version: 3, tried: []int{3}},
+		{requested: 3, available: []int{2}, version: 2, err: ErrGLVersion, tried: []int{3, 2}},
+		{requested: 4, available: []int{2, 3}, version: 2, err: ErrGLVersion, tried: []int{2}},
+		{requested: 3, available: nil, version: 0, err: errNoGLContext, tried: []int{3, 2}},
+		{requested: 2, available: nil, version: 0, err: errNoGLContext, tried: []int{2}},
+	}
+	for _, tt := range tests {
+		var tried []int
+		create := func(version int) bool {
+			tried = append(tried, version)
+			for _, v := range tt.available {
+				if v == version {
+					return true
+				}
+			}
+			return false
+		}
+		version, err := negotiateGLVersion(tt.requested, create)
+		if version != tt.version || err != tt.err {
+			t.Errorf("request %d of %v: got version %d, %v, want %d, %v", tt.requested, tt.available, version, err, tt.version, tt.err)
+		}
+		if !reflect.DeepEqual(tried, tt.tried) {
+			t.Errorf("request %d of %v: tried versions %v, want %v", tt.requested, tt.available, tried, tt.tried)
+		}
+	}
+}
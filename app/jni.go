@@ -0,0 +1,94 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A jniMethod is a Java method called through JNI: its class, in the
+// slash-separated form of FindClass, its name and its type signature.
+type jniMethod struct {
+	class, name, sig string
+}
+
+func (m jniMethod) String() string {
+	return m.class + "." + m.name + m.sig
+}
+
+// jniMethodIDs resolves jniMethods to their JNI method IDs, once for
+// each method, as the IDs stay valid while the class is loaded.
+type jniMethodIDs struct {
+	// resolve returns the ID of a method, or 0 if it is not found.
+	resolve func(m jniMethod) uintptr
+
+	mu  sync.Mutex
+	ids map[jniMethod]uintptr
+}
+
+// get returns the IDs of methods, in order. A method not found is an
+// error, and is looked up again by the next call.
+func (c *jniMethodIDs) get(methods ...jniMethod) ([]uintptr, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ids == nil {
+		c.ids = make(map[jniMethod]uintptr)
+	}
+	ids := make([]uintptr, len(methods))
+	for i, m := range methods {
+		id, ok := c.ids[m]
+		if !ok {
+			if id = c.resolve(m); id == 0 {
+				return nil, fmt.Errorf("app: cannot find the Java method %v", m)
+			}
+			c.ids[m] = id
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// keyboardMethods are the methods showing and hiding the soft keyboard
+// of a NativeActivity, in the order setKeyboard of keyboard_android.go
+// expects them:
+//
+//	imm = activity.getSystemService("input_method")
+//	view = activity.getWindow().getDecorView()
+//	imm.showSoftInput(view, InputMethodManager.SHOW_FORCED)
+//	imm.hideSoftInputFromWindow(view.getWindowToken(), 0)
+var keyboardMethods = []jniMethod{
+	{"android/content/Context", "getSystemService", "(Ljava/lang/String;)Ljava/lang/Object;"},
+	{"android/app/Activity", "getWindow", "()Landroid/view/Window;"},
+	{"android/view/Window", "getDecorView", "()Landroid/view/View;"},
+	{"android/view/View", "getWindowToken", "()Landroid/os/IBinder;"},
+	{"android/view/inputmethod/InputMethodManager", "showSoftInput", "(Landroid/view/View;I)Z"},
+	{"android/view/inputmethod/InputMethodManager", "hideSoftInputFromWindow", "(Landroid/os/IBinder;I)Z"},
+}
+
+// powerMethods are the methods reading the battery and thermal state
+// with the context of an all-Go app, in the order readPower of
+// power_android.go expects them:
+//
+//	filter = new IntentFilter(Intent.ACTION_BATTERY_CHANGED)
+//	intent = ctx.registerReceiver(null, filter)
+//	intent.getIntExtra(name, -1)
+//	intent.getBooleanExtra(BatteryManager.EXTRA_PRESENT, false)
+//	pm = ctx.getSystemService("power")
+//
+// Registering no receiver returns the last, sticky, battery intent.
+var powerMethods = []jniMethod{
+	{"android/content/IntentFilter", "<init>", "(Ljava/lang/String;)V"},
+	{"android/content/Context", "registerReceiver", "(Landroid/content/BroadcastReceiver;Landroid/content/IntentFilter;)Landroid/content/Intent;"},
+	{"android/content/Intent", "getIntExtra", "(Ljava/lang/String;I)I"},
+	{"android/content/Intent", "getBooleanExtra", "(Ljava/lang/String;Z)Z"},
+	{"android/content/Context", "getSystemService", "(Ljava/lang/String;)Ljava/lang/Object;"},
+}
+
+// thermalStatusMethod is pm.getCurrentThermalStatus(), added in API
+// level 29.
+var thermalStatusMethod = jniMethod{"android/os/PowerManager", "getCurrentThermalStatus", "()I"}
@@ -0,0 +1,55 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+/*
+#include <jni.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+extern JavaVM* current_vm;
+
+// activityEnv returns the JNIEnv of the calling thread, attached to the
+// VM of the app, or NULL.
+JNIEnv* activityEnv() {
+	JavaVM* vm = current_vm;
+	JNIEnv* env;
+	if ((*vm)->AttachCurrentThread(vm, &env, NULL) != JNI_OK) {
+		return NULL;
+	}
+	return env;
+}
+
+// findMethod returns the ID of a method, or 0 if it is not found.
+static uintptr_t findMethod(char* className, char* name, char* sig) {
+	JNIEnv* env = activityEnv();
+	if (env == NULL) {
+		return 0;
+	}
+	jclass clazz = (*env)->FindClass(env, className);
+	if (clazz == NULL) {
+		(*env)->ExceptionClear(env);
+		return 0;
+	}
+	jmethodID m = (*env)->GetMethodID(env, clazz, name, sig);
+	if (m == NULL) {
+		(*env)->ExceptionClear(env);
+	}
+	(*env)->DeleteLocalRef(env, clazz);
+	return (uintptr_t)m;
+}
+*/
+import "C"
+import "unsafe"
+
+// activityMethodIDs are the IDs of the Java methods called on behalf of
+// an all-Go app, in its NativeActivity or its GoView.
+var activityMethodIDs = jniMethodIDs{resolve: func(m jniMethod) uintptr {
+	class, name, sig := C.CString(m.class), C.CString(m.name), C.CString(m.sig)
+	defer C.free(unsafe.Pointer(class))
+	defer C.free(unsafe.Pointer(name))
+	defer C.free(unsafe.Pointer(sig))
+	return uintptr(C.findMethod(class, name, sig))
+}}
@@ -0,0 +1,77 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"golang.org/x/mobile/event"
+	"golang.org/x/mobile/geom"
+)
+
+// A KeyboardMode is the kind of soft keyboard shown by ShowKeyboard.
+type KeyboardMode int
+
+// The values index the keyboardTypes of darwin_arm.m.
+const (
+	KeyboardText   KeyboardMode = iota // letters, the default
+	KeyboardNumber                     // digits
+	KeyboardEmail                      // email addresses
+)
+
+// ShowKeyboard shows the soft keyboard of the platform in the given
+// mode. Callbacks.Keyboard is then called with the height of the screen
+// it covers.
+//
+// On Android, the keyboard is shown with the InputMethodManager of the
+// NativeActivity of an all-Go app, which only offers the text keyboard,
+// whatever the mode. On iOS, a text field of zero size becomes the first
+// responder. ShowKeyboard does nothing on desktops.
+func ShowKeyboard(mode KeyboardMode) {
+	showKeyboard(mode)
+}
+
+// HideKeyboard hides the soft keyboard shown by ShowKeyboard.
+func HideKeyboard() {
+	hideKeyboard()
+}
+
+// keyboard carries the changes of the soft keyboard, which arrive on
+// the threads of the operating system, to the goroutine running the app.
+// It holds the latest change, which replaces a pending one.
+var keyboard = make(chan event.Keyboard, 1)
+
+// sendKeyboard queues a change of the soft keyboard for the app.
+func sendKeyboard(e event.Keyboard) {
+	for {
+		select {
+		case keyboard <- e:
+			return
+		default:
+		}
+		select {
+		case <-keyboard:
+		default:
+		}
+	}
+}
+
+// handleKeyboard calls Callbacks.Keyboard on the goroutine running the
+// app.
+func handleKeyboard(cb Callbacks, e event.Keyboard) {
+	if cb.Keyboard != nil {
+		cb.Keyboard(e)
+	}
+}
+
+// keyboardCovering returns the Keyboard covering the bottom of a window
+// of the given height, in pixels, whose content ends at contentBottom.
+func keyboardCovering(windowHeight, contentBottom int, pixelsPerPt float32) event.Keyboard {
+	h := windowHeight - contentBottom
+	if h <= 0 || pixelsPerPt <= 0 {
+		return event.Keyboard{}
+	}
+	return event.Keyboard{Visible: true, Height: geom.Pt(float32(h) / pixelsPerPt)}
+}
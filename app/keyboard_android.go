@@ -0,0 +1,72 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+/*
+#include <android/native_activity.h>
+#include <jni.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+extern ANativeActivity* current_native_activity;
+
+JNIEnv* activityEnv();
+
+// setKeyboard shows or hides the soft keyboard of the activity, with
+// the IDs of the keyboardMethods.
+static void setKeyboard(uintptr_t* ids, int show) {
+	JNIEnv* env = activityEnv();
+	if (env == NULL || (*env)->PushLocalFrame(env, 8) != JNI_OK) {
+		return;
+	}
+	jobject activity = current_native_activity->clazz;
+	jstring service = (*env)->NewStringUTF(env, "input_method");
+	jobject imm = (*env)->CallObjectMethod(env, activity, (jmethodID)ids[0], service);
+	jobject window = (*env)->CallObjectMethod(env, activity, (jmethodID)ids[1]);
+	jobject view = (*env)->CallObjectMethod(env, window, (jmethodID)ids[2]);
+	if (show) {
+		const jint SHOW_FORCED = 2;
+		(*env)->CallBooleanMethod(env, imm, (jmethodID)ids[4], view, SHOW_FORCED);
+	} else {
+		jobject token = (*env)->CallObjectMethod(env, view, (jmethodID)ids[3]);
+		(*env)->CallBooleanMethod(env, imm, (jmethodID)ids[5], token, 0);
+	}
+	if ((*env)->ExceptionCheck(env)) {
+		(*env)->ExceptionDescribe(env);
+		(*env)->ExceptionClear(env);
+	}
+	(*env)->PopLocalFrame(env, NULL);
+}
+*/
+import "C"
+import (
+	"log"
+	"unsafe"
+)
+
+func showKeyboard(mode KeyboardMode) {
+	setKeyboard(true)
+}
+
+func hideKeyboard() {
+	setKeyboard(false)
+}
+
+func setKeyboard(show bool) {
+	if C.current_native_activity == nil {
+		log.Print("app: the soft keyboard is only available to all-Go apps")
+		return
+	}
+	ids, err := activityMethodIDs.get(keyboardMethods...)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	cshow := C.int(0)
+	if show {
+		cshow = 1
+	}
+	C.setKeyboard((*C.uintptr_t)(unsafe.Pointer(&ids[0])), cshow)
+}
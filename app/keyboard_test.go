@@ -0,0 +1,154 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"testing"
+
+	"golang.org/x/mobile/event"
+)
+
+func TestKeyboardCovering(t *testing.T) {
+	tests := []struct {
+		windowHeight, contentBottom int
+		want                        event.Keyboard
+	}{
+		{1920, 1920, event.Keyboard{}},
+		{1920, 1200, event.Keyboard{Visible: true, Height: 240}},
+		{1920, 2000, event.Keyboard{}},
+		{0, 0, event.Keyboard{}},
+	}
+	for _, tt := range tests {
+		if got := keyboardCovering(tt.windowHeight, tt.contentBottom, 3); got != tt.want {
+			t.Errorf("keyboardCovering(%d, %d, 3) = %+v, want %+v", tt.windowHeight, tt.contentBottom, got, tt.want)
+		}
+	}
+}
+
+func TestSendKeyboard(t *testing.T) {
+	sendKeyboard(event.Keyboard{Visible: true, Height: 100})
+	sendKeyboard(event.Keyboard{})
+	select {
+	case e := <-keyboard:
+		if e.Visible {
+			t.Errorf("pending change %+v, want the latest, hidden", e)
+		}
+	default:
+		t.Fatal("no pending change")
+	}
+	select {
+	case e := <-keyboard:
+		t.Errorf("second pending change %+v", e)
+	default:
+	}
+}
+
+func TestJNIMethodIDs(t *testing.T) {
+	var lookups []jniMethod
+	missing := jniMethod{"android/view/View", "noSuchMethod", "()V"}
+	ids := jniMethodIDs{resolve: func(m jniMethod) uintptr {
+		lookups = append(lookups, m)
+		if m == missing {
+			return 0
+		}
+		return uintptr(len(lookups))
+	}}
+
+	got, err := ids.get(keyboardMethods...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range got {
+		if id != uintptr(i+1) {
+			t.Errorf("ID of %v = %d, want %d", keyboardMethods[i], id, i+1)
+		}
+	}
+	if _, err := ids.get(keyboardMethods...); err != nil {
+		t.Fatal(err)
+	}
+	if len(lookups) != len(keyboardMethods) {
+		t.Errorf("%d lookups, want %d, one per method", len(lookups), len(keyboardMethods))
+	}
+
+	lookups = nil
+	if _, err := ids.get(keyboardMethods[0], missing); err == nil {
+		t.Errorf("missing method: got nil error")
+	}
+	if _, err := ids.get(missing); err == nil {
+		t.Errorf("missing method, looked up again: got nil error")
+	}
+	if len(lookups) != 2 {
+		t.Errorf("lookups %v, want the missing method twice", lookups)
+	}
+}
+
+// validJNISig reports whether sig is a JNI method signature.
+func validJNISig(sig string) bool {
+	if len(sig) == 0 || sig[0] != '(' {
+		return false
+	}
+	i := 1
+	// typ consumes one field type at sig[i:], returning whether it is valid.
+	typ := func(ret bool) bool {
+		for i < len(sig) && sig[i] == '[' {
+			i++
+		}
+		if i == len(sig) {
+			return false
+		}
+		switch c := sig[i]; {
+		case c == 'V':
+			i++
+			return ret
+		case c == 'L':
+			j := i + 1
+			for j < len(sig) && sig[j] != ';' {
+				if sig[j] == '.' {
+					return false
+				}
+				j++
+			}
+			if j == len(sig) || j == i+1 {
+				return false
+			}
+			i = j + 1
+			return true
+		case c == 'Z' || c == 'B' || c == 'C' || c == 'S' || c == 'I' || c == 'J' || c == 'F' || c == 'D':
+			i++
+			return true
+		}
+		return false
+	}
+	for i < len(sig) && sig[i] != ')' {
+		if !typ(false) {
+			return false
+		}
+	}
+	if i == len(sig) {
+		return false
+	}
+	i++
+	return typ(true) && i == len(sig)
+}
+
+func TestKeyboardMethods(t *testing.T) {
+	for _, m := range keyboardMethods {
+		if !validJNISig(m.sig) {
+			t.Errorf("%v: bad signature", m)
+		}
+	}
+	for _, sig := range []string{"", "()", "(V)V", "(Ljava.lang.String;)V", "(L;)V", "(I)Lfoo", "()II"} {
+		if validJNISig(sig) {
+			t.Errorf("validJNISig(%q) = true", sig)
+		}
+	}
+	for _, sig := range []string{"()V", "([BI)Z", "(Ljava/lang/String;)[Ljava/lang/Object;"} {
+		if !validJNISig(sig) {
+			t.Errorf("validJNISig(%q) = false", sig)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"strings"
+	"sync"
+)
+
+// A LogPriority is the priority of a line of log output. The values are
+// those of the Android log.
+type LogPriority int
+
+const (
+	LogVerbose LogPriority = 2
+	LogDebug   LogPriority = 3
+	LogInfo    LogPriority = 4
+	LogWarn    LogPriority = 5
+	LogError   LogPriority = 6
+	LogFatal   LogPriority = 7
+)
+
+// DefaultLogTag is the tag of the log output of the app until SetLogTag
+// is called.
+const DefaultLogTag = "GoLog"
+
+var logConfig = struct {
+	sync.Mutex
+	tag string
+	min LogPriority
+}{tag: DefaultLogTag, min: LogVerbose}
+
+// SetLogTag sets the tag of the lines that the log package, stdout and
+// stderr write to the Android log.
+func SetLogTag(tag string) {
+	logConfig.Lock()
+	logConfig.tag = tag
+	logConfig.Unlock()
+}
+
+// SetLogLevel sets the minimum priority of the lines written to the
+// Android log. Lines of a lower priority are dropped. Fatal lines, such
+// as those of a panic, are always written.
+func SetLogLevel(min LogPriority) {
+	logConfig.Lock()
+	logConfig.min = min
+	logConfig.Unlock()
+}
+
+// logPrefixes maps the prefixes of log lines to their priority. A line
+// written without one of them has the priority of its output.
+var logPrefixes = []struct {
+	prefix   string
+	priority LogPriority
+}{
+	{"verbose:", LogVerbose},
+	{"debug:", LogDebug},
+	{"info:", LogInfo},
+	{"warning:", LogWarn},
+	{"warn:", LogWarn},
+	{"error:", LogError},
+	{"fatal:", LogFatal},
+	{"panic:", LogFatal},
+	{"fatal error:", LogFatal}, // runtime.throw
+}
+
+// linePriority returns the priority of line: the priority named by its
+// prefix, matched without regard to case, or else def.
+func linePriority(line string, def LogPriority) LogPriority {
+	for _, p := range logPrefixes {
+		if len(line) >= len(p.prefix) && strings.EqualFold(line[:len(p.prefix)], p.prefix) {
+			return p.priority
+		}
+	}
+	return def
+}
+
+// A logLines writes the lines of an output to the log, with the tag and
+// minimum priority set by SetLogTag and SetLogLevel.
+type logLines struct {
+	def   LogPriority                                  // priority of the lines without a prefix
+	write func(priority LogPriority, tag, line string) // writes to the log
+
+	// crashes is set for stderr, where the runtime reports a panic.
+	// The goroutine traces and the rest of the output of the crash
+	// follow the first fatal line, so they are fatal too.
+	crashes bool
+	fatal   bool
+}
+
+func (l *logLines) line(line string) {
+	priority := linePriority(line, l.def)
+	if l.crashes && (l.fatal || priority == LogFatal) {
+		l.fatal = true
+		priority = LogFatal
+	}
+	logConfig.Lock()
+	tag, min := logConfig.tag, logConfig.min
+	logConfig.Unlock()
+	if priority < min && priority != LogFatal {
+		return
+	}
+	l.write(priority, tag, line)
+}
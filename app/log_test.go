@@ -0,0 +1,84 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || darwin
+// +build linux darwin
+
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLinePriority(t *testing.T) {
+	tests := []struct {
+		line string
+		def  LogPriority
+		want LogPriority
+	}{
+		{"hello", LogInfo, LogInfo},
+		{"hello", LogError, LogError},
+		{"debug: frame 3", LogInfo, LogDebug},
+		{"Warning: low battery", LogInfo, LogWarn},
+		{"warn: slow frame", LogError, LogWarn},
+		{"ERROR: no window", LogInfo, LogError},
+		{"panic: boom", LogError, LogFatal},
+		{"fatal error: all goroutines are asleep - deadlock!", LogError, LogFatal},
+		{"debugging", LogInfo, LogInfo},
+		{"", LogInfo, LogInfo},
+	}
+	for _, tt := range tests {
+		if got := linePriority(tt.line, tt.def); got != tt.want {
+			t.Errorf("linePriority(%q, %d) = %d, want %d", tt.line, tt.def, got, tt.want)
+		}
+	}
+}
+
+type logRecord struct {
+	priority LogPriority
+	tag      string
+	line     string
+}
+
+func TestLogLines(t *testing.T) {
+	defer SetLogTag(DefaultLogTag)
+	defer SetLogLevel(LogVerbose)
+
+	var got []logRecord
+	write := func(priority LogPriority, tag, line string) {
+		got = append(got, logRecord{priority, tag, line})
+	}
+	stderr := &logLines{def: LogError, write: write, crashes: true}
+	stdout := &logLines{def: LogInfo, write: write}
+
+	SetLogTag("myapp")
+	SetLogLevel(LogWarn)
+	stdout.line("hello")         // dropped
+	stdout.line("warning: slow") // kept
+	stdout.line("panic: not a crash")
+	stdout.line("world") // dropped, stdout does not crash
+	stderr.line("oops")
+	stderr.line("panic: boom")
+	stderr.line("goroutine 1 [running]:")
+
+	want := []logRecord{
+		{LogWarn, "myapp", "warning: slow"},
+		{LogFatal, "myapp", "panic: not a crash"},
+		{LogError, "myapp", "oops"},
+		{LogFatal, "myapp", "panic: boom"},
+		{LogFatal, "myapp", "goroutine 1 [running]:"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("log records:\n%v\nwant:\n%v", got, want)
+	}
+
+	// Fatal lines are written whatever the level.
+	got = nil
+	SetLogLevel(LogFatal + 1)
+	stderr.line("the rest of the crash")
+	if len(got) != 1 || got[0].priority != LogFatal {
+		t.Errorf("fatal line above the minimum priority: %v", got)
+	}
+}
@@ -5,24 +5,20 @@
 package app
 
 /*
-#cgo android LDFLAGS: -llog -landroid -lEGL -lGLESv2
+#cgo android LDFLAGS: -llog -landroid -lEGL -lGLESv2 -ldl
+#include <dlfcn.h>
 #include <android/log.h>
+#include <android/looper.h>
 #include <android/native_activity.h>
 #include <android/input.h>
 #include <EGL/egl.h>
 #include <GLES/gl.h>
+#include <stdint.h>
+#include <time.h>
 
-// TODO(crawshaw): Test configuration on more devices.
-const EGLint RGB_888[] = {
-	EGL_RENDERABLE_TYPE, EGL_OPENGL_ES2_BIT,
-	EGL_SURFACE_TYPE, EGL_WINDOW_BIT,
-	EGL_BLUE_SIZE, 8,
-	EGL_GREEN_SIZE, 8,
-	EGL_RED_SIZE, 8,
-	EGL_DEPTH_SIZE, 16,
-	EGL_CONFIG_CAVEAT, EGL_NONE,
-	EGL_NONE
-};
+#ifndef EGL_OPENGL_ES3_BIT_KHR
+#define EGL_OPENGL_ES3_BIT_KHR 0x0040
+#endif
 
 EGLint windowWidth;
 EGLint windowHeight;
@@ -31,12 +27,115 @@ EGLSurface surface;
 
 #define LOG_ERROR(...) __android_log_print(ANDROID_LOG_ERROR, "Go", __VA_ARGS__)
 
+// motionToolType returns the tool type of pointer i of the motion event
+// e, or 0 (AMOTION_EVENT_TOOL_TYPE_UNKNOWN) before API level 14, which
+// added AMotionEvent_getToolType. It is looked up at run time, so apps
+// still load on older versions.
+int32_t motionToolType(const AInputEvent* e, size_t i) {
+	static int32_t (*getToolType)(const AInputEvent*, size_t);
+	static int looked;
+	if (!looked) {
+		getToolType = dlsym(RTLD_DEFAULT, "AMotionEvent_getToolType");
+		looked = 1;
+	}
+	if (getToolType == NULL) {
+		return 0;
+	}
+	return getToolType(e, i);
+}
+
+extern ANativeActivity* current_native_activity;
+
+typedef struct AChoreographer AChoreographer;
+
+// The Choreographer of the NDK is looked up at run time, so apps still
+// load before API level 24, which added it. The callback taking a long
+// is only used where long holds the 64-bit frame time.
+static AChoreographer* choreographer;
+static void (*postFrameCallback64)(AChoreographer*, void (*)(int64_t, void*), void*);
+static void (*postFrameCallback)(AChoreographer*, void (*)(long, void*), void*);
+static int64_t frameTimeNanos;
+
+static void onFrame64(int64_t t, void* data) { frameTimeNanos = t; }
+static void onFrame(long t, void* data) { frameTimeNanos = t; }
+
+// initChoreographer prepares a looper for the calling thread and gets
+// the Choreographer calling back on it. It returns 0 if the device has
+// no Choreographer.
+int initChoreographer() {
+	AChoreographer* (*getInstance)(void) = dlsym(RTLD_DEFAULT, "AChoreographer_getInstance");
+	postFrameCallback64 = dlsym(RTLD_DEFAULT, "AChoreographer_postFrameCallback64");
+	if (sizeof(long) == sizeof(int64_t)) {
+		postFrameCallback = dlsym(RTLD_DEFAULT, "AChoreographer_postFrameCallback");
+	}
+	if (getInstance == NULL || (postFrameCallback64 == NULL && postFrameCallback == NULL)) {
+		return 0;
+	}
+	ALooper_prepare(0);
+	choreographer = getInstance();
+	return choreographer != NULL;
+}
+
+// waitFrame runs the looper of the thread until the Choreographer calls
+// back for the next frame, and returns the time of its vsync in
+// nanoseconds. It returns 0 if there is no call within timeoutMillis.
+int64_t waitFrame(int timeoutMillis) {
+	frameTimeNanos = 0;
+	if (postFrameCallback64 != NULL) {
+		postFrameCallback64(choreographer, onFrame64, NULL);
+	} else {
+		postFrameCallback(choreographer, onFrame, NULL);
+	}
+	ALooper_pollOnce(timeoutMillis, NULL, NULL, NULL);
+	return frameTimeNanos;
+}
+
+// monotonicNanos returns the time of the clock of the Choreographer,
+// for frames drawn without it.
+int64_t monotonicNanos() {
+	struct timespec ts;
+	clock_gettime(CLOCK_MONOTONIC, &ts);
+	return (int64_t)ts.tv_sec * 1000000000 + ts.tv_nsec;
+}
+
+// displayRefreshRate returns the refresh rate of the display of the
+// activity, from getWindowManager().getDefaultDisplay().getRefreshRate(),
+// or 0 if it is unknown.
+float displayRefreshRate(ANativeActivity* activity) {
+	JavaVM* vm = activity->vm;
+	JNIEnv* env;
+	if ((*vm)->AttachCurrentThread(vm, &env, NULL) != JNI_OK) {
+		return 0;
+	}
+	float rate = 0;
+	jclass activityClass = (*env)->GetObjectClass(env, activity->clazz);
+	jmethodID getWindowManager = (*env)->GetMethodID(env, activityClass, "getWindowManager", "()Landroid/view/WindowManager;");
+	jclass wmClass = (*env)->FindClass(env, "android/view/WindowManager");
+	jmethodID getDefaultDisplay = (*env)->GetMethodID(env, wmClass, "getDefaultDisplay", "()Landroid/view/Display;");
+	jclass displayClass = (*env)->FindClass(env, "android/view/Display");
+	jmethodID getRefreshRate = (*env)->GetMethodID(env, displayClass, "getRefreshRate", "()F");
+	if (getWindowManager != NULL && getDefaultDisplay != NULL && getRefreshRate != NULL) {
+		jobject wm = (*env)->CallObjectMethod(env, activity->clazz, getWindowManager);
+		jobject disp = (*env)->CallObjectMethod(env, wm, getDefaultDisplay);
+		rate = (*env)->CallFloatMethod(env, disp, getRefreshRate);
+	}
+	if ((*env)->ExceptionCheck(env)) {
+		(*env)->ExceptionClear(env);
+		rate = 0;
+	}
+	return rate;
+}
+
 void querySurfaceWidthAndHeight() {
 	eglQuerySurface(display, surface, EGL_WIDTH, &windowWidth);
 	eglQuerySurface(display, surface, EGL_HEIGHT, &windowHeight);
 }
 
-void createEGLWindow(ANativeWindow* window) {
+// createEGLWindow creates a surface for window and an OpenGL ES context
+// of the given major version, and makes them current. On failure, it
+// releases the surface and returns EGL_FALSE, so it can be called again
+// with another version.
+EGLBoolean createEGLWindow(ANativeWindow* window, EGLint version) {
 	EGLint numConfigs, format;
 	EGLConfig config;
 	EGLContext context;
@@ -44,39 +143,60 @@ void createEGLWindow(ANativeWindow* window) {
 	display = eglGetDisplay(EGL_DEFAULT_DISPLAY);
 	if (!eglInitialize(display, 0, 0)) {
 		LOG_ERROR("EGL initialize failed");
-		return;
+		return EGL_FALSE;
 	}
 
+	// TODO(crawshaw): Test configuration on more devices.
+	const EGLint RGB_888[] = {
+		EGL_RENDERABLE_TYPE, version == 3 ? EGL_OPENGL_ES3_BIT_KHR : EGL_OPENGL_ES2_BIT,
+		EGL_SURFACE_TYPE, EGL_WINDOW_BIT,
+		EGL_BLUE_SIZE, 8,
+		EGL_GREEN_SIZE, 8,
+		EGL_RED_SIZE, 8,
+		EGL_DEPTH_SIZE, 16,
+		EGL_CONFIG_CAVEAT, EGL_NONE,
+		EGL_NONE
+	};
 	if (!eglChooseConfig(display, RGB_888, &config, 1, &numConfigs)) {
 		LOG_ERROR("EGL choose RGB_888 config failed");
-		return;
+		return EGL_FALSE;
 	}
 	if (numConfigs <= 0) {
-		LOG_ERROR("EGL no config found");
-		return;
+		LOG_ERROR("EGL no config found for OpenGL ES %d", version);
+		return EGL_FALSE;
 	}
 
 	eglGetConfigAttrib(display, config, EGL_NATIVE_VISUAL_ID, &format);
 	if (ANativeWindow_setBuffersGeometry(window, 0, 0, format) != 0) {
 		LOG_ERROR("EGL set buffers geometry failed");
-		return;
+		return EGL_FALSE;
 	}
 
 	surface = eglCreateWindowSurface(display, config, window, NULL);
 	if (surface == EGL_NO_SURFACE) {
 		LOG_ERROR("EGL create surface failed");
-		return;
+		return EGL_FALSE;
 	}
 
-	const EGLint contextAttribs[] = { EGL_CONTEXT_CLIENT_VERSION, 2, EGL_NONE };
+	const EGLint contextAttribs[] = { EGL_CONTEXT_CLIENT_VERSION, version, EGL_NONE };
 	context = eglCreateContext(display, config, EGL_NO_CONTEXT, contextAttribs);
+	if (context == EGL_NO_CONTEXT) {
+		LOG_ERROR("EGL create OpenGL ES %d context failed", version);
+		eglDestroySurface(display, surface);
+		surface = EGL_NO_SURFACE;
+		return EGL_FALSE;
+	}
 
 	if (eglMakeCurrent(display, surface, surface, context) == EGL_FALSE) {
 		LOG_ERROR("eglMakeCurrent failed");
-		return;
+		eglDestroyContext(display, context);
+		eglDestroySurface(display, surface);
+		surface = EGL_NO_SURFACE;
+		return EGL_FALSE;
 	}
 
 	querySurfaceWidthAndHeight();
+	return EGL_TRUE;
 }
 
 #undef LOG_ERROR
@@ -84,14 +204,19 @@ void createEGLWindow(ANativeWindow* window) {
 import "C"
 import (
 	"log"
+	"time"
 
-	"golang.org/x/mobile/event"
 	"golang.org/x/mobile/geom"
 	"golang.org/x/mobile/gl"
 )
 
 func windowDrawLoop(cb Callbacks, w *C.ANativeWindow, queue *C.AInputQueue) {
-	C.createEGLWindow(w)
+	create := func(version int) bool {
+		return C.createEGLWindow(w, C.EGLint(version)) == C.EGL_TRUE
+	}
+	if _, err := negotiateGLVersion(cb.GLVersion, create); err != nil {
+		log.Print(err)
+	}
 
 	// TODO: is the library or the app responsible for clearing the buffers?
 	gl.ClearColor(0, 0, 0, 1)
@@ -105,6 +230,19 @@ func windowDrawLoop(cb Callbacks, w *C.ANativeWindow, queue *C.AInputQueue) {
 	geom.Width = geom.Pt(float32(C.windowWidth) / geom.PixelsPerPt)
 	geom.Height = geom.Pt(float32(C.windowHeight) / geom.PixelsPerPt)
 
+	// The Choreographer paces the draw loop to the vsync of the
+	// display, and times its frames.
+	var clock frameClock
+	var setup glSetup
+	var period time.Duration
+	if rate := float32(C.displayRefreshRate(C.current_native_activity)); rate > 0 {
+		period = time.Duration(float32(time.Second) / rate)
+	}
+	vsync := C.initChoreographer() != 0
+
+	// The battery and thermal state are polled between frames.
+	battery := powerPoller{read: readPower}
+
 	// Wait until geometry and GL is initialized before cb.Start.
 	runStart(cb)
 
@@ -116,12 +254,28 @@ func windowDrawLoop(cb Callbacks, w *C.ANativeWindow, queue *C.AInputQueue) {
 			C.querySurfaceWidthAndHeight()
 			geom.Width = geom.Pt(float32(C.windowWidth) / geom.PixelsPerPt)
 			geom.Height = geom.Pt(float32(C.windowHeight) / geom.PixelsPerPt)
+		case e := <-lowMemory:
+			handleLowMemory(cb, e)
+		case e := <-keyboard:
+			handleKeyboard(cb, e)
+		case e := <-power:
+			handlePower(cb, e)
 		case <-windowDestroyed:
 			if cb.Stop != nil {
 				cb.Stop()
 			}
 			return
 		default:
+			var t C.int64_t
+			if vsync {
+				t = C.waitFrame(100)
+			}
+			if t == 0 {
+				t = C.monotonicNanos()
+			}
+			battery.poll(time.Duration(t))
+			handleFrame(cb, &clock, time.Duration(t), period)
+			setup.beforeDraw(cb, nil)
 			if cb.Draw != nil {
 				cb.Draw()
 			}
@@ -150,31 +304,31 @@ func processEvent(cb Callbacks, e *C.AInputEvent) {
 			return
 		}
 
-		// At most one of the events in this batch is an up or down event; get its index and type.
-		upDownIndex := C.size_t(C.AMotionEvent_getAction(e)&C.AMOTION_EVENT_ACTION_POINTER_INDEX_MASK) >> C.AMOTION_EVENT_ACTION_POINTER_INDEX_SHIFT
-		upDownTyp := event.TouchMove
-		switch C.AMotionEvent_getAction(e) & C.AMOTION_EVENT_ACTION_MASK {
-		case C.AMOTION_EVENT_ACTION_DOWN, C.AMOTION_EVENT_ACTION_POINTER_DOWN:
-			upDownTyp = event.TouchStart
-		case C.AMOTION_EVENT_ACTION_UP, C.AMOTION_EVENT_ACTION_POINTER_UP:
-			upDownTyp = event.TouchEnd
-		}
-
-		for i, n := C.size_t(0), C.AMotionEvent_getPointerCount(e); i < n; i++ {
-			typ := event.TouchMove
-			if i == upDownIndex {
-				typ = upDownTyp
+		pointers := make([]motionPointer, C.AMotionEvent_getPointerCount(e))
+		for i := range pointers {
+			ci := C.size_t(i)
+			pointers[i] = motionPointer{
+				id:       int32(C.AMotionEvent_getPointerId(e, ci)),
+				x:        float32(C.AMotionEvent_getX(e, ci)),
+				y:        float32(C.AMotionEvent_getY(e, ci)),
+				pressure: float32(C.AMotionEvent_getPressure(e, ci)),
+				size:     float32(C.AMotionEvent_getSize(e, ci)),
+				tool:     int32(C.motionToolType(e, ci)),
 			}
-			cb.Touch(event.Touch{
-				ID:   event.TouchSequenceID(C.AMotionEvent_getPointerId(e, i)),
-				Type: typ,
-				Loc: geom.Point{
-					X: geom.Pt(float32(C.AMotionEvent_getX(e, i)) / geom.PixelsPerPt),
-					Y: geom.Pt(float32(C.AMotionEvent_getY(e, i)) / geom.PixelsPerPt),
-				},
-			})
+		}
+		for _, t := range motionTouches(int32(C.AMotionEvent_getAction(e)), pointers) {
+			cb.Touch(t)
 		}
 	default:
 		log.Printf("unknown input event, type=%d", C.AInputEvent_getType(e))
 	}
 }
+
+// onContentRectChanged is called when the rect of the window showing the
+// content of the activity changes, as the soft keyboard covers the
+// bottom of the window.
+//
+//export onContentRectChanged
+func onContentRectChanged(activity *C.ANativeActivity, rect *C.ARect) {
+	sendKeyboard(keyboardCovering(int(C.windowHeight), int(rect.bottom), geom.PixelsPerPt))
+}
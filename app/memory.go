@@ -0,0 +1,47 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"runtime/debug"
+
+	"golang.org/x/mobile/event"
+)
+
+// lowMemory carries the memory warnings of the operating system, which
+// arrive on its threads, to the goroutine running the app. It holds one
+// warning, so the operating system is never blocked: a warning arriving
+// while another is pending replaces it only if it is more severe.
+var lowMemory = make(chan event.LowMemory, 1)
+
+// sendLowMemory queues a memory warning for the app.
+func sendLowMemory(e event.LowMemory) {
+	for {
+		select {
+		case lowMemory <- e:
+			return
+		default:
+		}
+		select {
+		case pending := <-lowMemory:
+			if pending.Level > e.Level {
+				e = pending
+			}
+		default:
+		}
+	}
+}
+
+// handleLowMemory calls Callbacks.LowMemory on the goroutine running the
+// app, and then returns the memory released by the app to the operating
+// system.
+func handleLowMemory(cb Callbacks, e event.LowMemory) {
+	if cb.LowMemory != nil {
+		cb.LowMemory(e)
+	}
+	debug.FreeOSMemory()
+}
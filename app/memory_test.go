@@ -0,0 +1,48 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"testing"
+
+	"golang.org/x/mobile/event"
+)
+
+func TestLowMemoryDispatch(t *testing.T) {
+	// The operating system calls in on its own thread, and must not
+	// block while the app is busy.
+	done := make(chan bool)
+	go func() {
+		sendLowMemory(event.LowMemory{Level: event.TrimRunningLow})
+		sendLowMemory(event.LowMemory{Level: event.TrimComplete})
+		sendLowMemory(event.LowMemory{Level: event.TrimUIHidden})
+		done <- true
+	}()
+	<-done
+
+	// The app goroutine receives the most severe pending warning.
+	var got []event.LowMemory
+	cb := Callbacks{LowMemory: func(e event.LowMemory) { got = append(got, e) }}
+	select {
+	case e := <-lowMemory:
+		handleLowMemory(cb, e)
+	default:
+		t.Fatal("no pending warning")
+	}
+	if len(got) != 1 || got[0].Level != event.TrimComplete {
+		t.Errorf("LowMemory called with %v, want one call with level %v", got, event.TrimComplete)
+	}
+	select {
+	case e := <-lowMemory:
+		t.Errorf("second pending warning %v", e)
+	default:
+	}
+
+	// Apps without the callback still have warnings consumed.
+	sendLowMemory(event.LowMemory{Level: event.TrimModerate})
+	handleLowMemory(Callbacks{}, <-lowMemory)
+}
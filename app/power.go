@@ -0,0 +1,145 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"time"
+
+	"golang.org/x/mobile/event"
+)
+
+// power carries the changes of the battery and thermal state to the
+// goroutine running the app. It holds the latest change, which replaces
+// a pending one.
+var power = make(chan event.Power, 1)
+
+// sendPower queues a change of the power state for the app.
+func sendPower(e event.Power) {
+	for {
+		select {
+		case power <- e:
+			return
+		default:
+		}
+		select {
+		case <-power:
+		default:
+		}
+	}
+}
+
+// handlePower calls Callbacks.Power on the goroutine running the app.
+func handlePower(cb Callbacks, e event.Power) {
+	if cb.Power != nil {
+		cb.Power(e)
+	}
+}
+
+// powerPollInterval is how often the power state is read on Android,
+// which only broadcasts its changes to receivers written in Java.
+const powerPollInterval = 5 * time.Second
+
+// A powerPoller reads the power state at most once per
+// powerPollInterval, and sends it when it changes.
+type powerPoller struct {
+	read func() event.Power
+
+	polled bool
+	last   time.Duration // time of the last read
+	state  event.Power   // state last read
+}
+
+// poll reads the power state, if it was not read in the interval before
+// now, and sends it if it is the first read or a change.
+func (p *powerPoller) poll(now time.Duration) {
+	if p.polled && now-p.last < powerPollInterval {
+		return
+	}
+	e := p.read()
+	if !p.polled || e != p.state {
+		sendPower(e)
+	}
+	p.polled, p.last, p.state = true, now, e
+}
+
+// Android constants of the BatteryManager and the PowerManager.
+const (
+	androidBatteryStatusCharging    = 2 // BATTERY_STATUS_CHARGING
+	androidBatteryStatusDischarging = 3 // BATTERY_STATUS_DISCHARGING
+	androidBatteryStatusNotCharging = 4 // BATTERY_STATUS_NOT_CHARGING
+	androidBatteryStatusFull        = 5 // BATTERY_STATUS_FULL
+
+	androidThermalStatusNone     = 0 // THERMAL_STATUS_NONE
+	androidThermalStatusLight    = 1 // THERMAL_STATUS_LIGHT
+	androidThermalStatusModerate = 2 // THERMAL_STATUS_MODERATE
+	androidThermalStatusSevere   = 3 // THERMAL_STATUS_SEVERE
+	androidThermalStatusCritical = 4 // THERMAL_STATUS_CRITICAL
+	androidThermalStatusShutdown = 6 // THERMAL_STATUS_SHUTDOWN
+)
+
+// androidPower returns the Power of the level, scale, status and present
+// extras of the ACTION_BATTERY_CHANGED intent, and of the thermal status
+// of the PowerManager. Missing extras are -1, or false for present, and
+// a missing thermal status is -1.
+func androidPower(level, scale, status int, present bool, thermal int) event.Power {
+	e := event.Power{Battery: -1}
+	if present && level >= 0 && scale > 0 && level <= scale {
+		e.Battery = float32(level) / float32(scale)
+	}
+	if present {
+		switch status {
+		case androidBatteryStatusCharging:
+			e.Charging = event.Charging
+		case androidBatteryStatusDischarging, androidBatteryStatusNotCharging:
+			e.Charging = event.Discharging
+		case androidBatteryStatusFull:
+			e.Charging = event.ChargingFull
+		}
+	}
+	switch {
+	case thermal == androidThermalStatusNone:
+		e.Thermal = event.ThermalNominal
+	case thermal == androidThermalStatusLight, thermal == androidThermalStatusModerate:
+		e.Thermal = event.ThermalFair
+	case thermal == androidThermalStatusSevere:
+		e.Thermal = event.ThermalSerious
+	case thermal >= androidThermalStatusCritical && thermal <= androidThermalStatusShutdown:
+		e.Thermal = event.ThermalCritical
+	}
+	return e
+}
+
+// iOS constants of UIDeviceBatteryState.
+const (
+	iosBatteryStateUnplugged = 1 // UIDeviceBatteryStateUnplugged
+	iosBatteryStateCharging  = 2 // UIDeviceBatteryStateCharging
+	iosBatteryStateFull      = 3 // UIDeviceBatteryStateFull
+)
+
+// iosPower returns the Power of the batteryLevel and batteryState of
+// UIDevice, and of the thermalState of NSProcessInfo, which is -1 before
+// iOS 11. The simulator reports a level of -1 and an unknown state.
+func iosPower(level float32, state, thermal int) event.Power {
+	e := event.Power{Battery: -1}
+	if level >= 0 && level <= 1 {
+		e.Battery = level
+	}
+	switch state {
+	case iosBatteryStateUnplugged:
+		e.Charging = event.Discharging
+	case iosBatteryStateCharging:
+		e.Charging = event.Charging
+	case iosBatteryStateFull:
+		e.Charging = event.ChargingFull
+	}
+	// NSProcessInfoThermalStateNominal to NSProcessInfoThermalStateCritical
+	// are 0 to 3, in the order of the ThermalStates.
+	if thermal >= 0 && thermal <= 3 {
+		e.Thermal = event.ThermalNominal + event.ThermalState(thermal)
+	}
+	return e
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+/*
+#include <jni.h>
+#include <stdint.h>
+
+extern jobject current_ctx;
+
+JNIEnv* activityEnv();
+
+// readPower reads the level, scale, status and present extras of the
+// battery intent of the context of the app, and its thermal status, into
+// out, with the IDs of the powerMethods and of the thermalStatusMethod,
+// or 0 if there is none. What cannot be read is left as it is.
+static void readPower(uintptr_t* ids, uintptr_t thermalID, int* out) {
+	JNIEnv* env = activityEnv();
+	if (env == NULL || (*env)->PushLocalFrame(env, 16) != JNI_OK) {
+		return;
+	}
+	jobject ctx = current_ctx;
+	jclass filterClass = (*env)->FindClass(env, "android/content/IntentFilter");
+	jstring action = (*env)->NewStringUTF(env, "android.intent.action.BATTERY_CHANGED");
+	jobject filter = (*env)->NewObject(env, filterClass, (jmethodID)ids[0], action);
+	jobject intent = (*env)->CallObjectMethod(env, ctx, (jmethodID)ids[1], NULL, filter);
+	if (intent != NULL && !(*env)->ExceptionCheck(env)) {
+		const char* extras[] = {"level", "scale", "status"};
+		int i;
+		for (i = 0; i < 3; i++) {
+			jstring name = (*env)->NewStringUTF(env, extras[i]);
+			out[i] = (*env)->CallIntMethod(env, intent, (jmethodID)ids[2], name, -1);
+		}
+		jstring present = (*env)->NewStringUTF(env, "present");
+		out[3] = (*env)->CallBooleanMethod(env, intent, (jmethodID)ids[3], present, JNI_FALSE);
+	}
+	if (thermalID != 0 && !(*env)->ExceptionCheck(env)) {
+		jstring service = (*env)->NewStringUTF(env, "power");
+		jobject pm = (*env)->CallObjectMethod(env, ctx, (jmethodID)ids[4], service);
+		if (pm != NULL) {
+			out[4] = (*env)->CallIntMethod(env, pm, (jmethodID)thermalID);
+		}
+	}
+	if ((*env)->ExceptionCheck(env)) {
+		(*env)->ExceptionDescribe(env);
+		(*env)->ExceptionClear(env);
+	}
+	(*env)->PopLocalFrame(env, NULL);
+}
+*/
+import "C"
+import (
+	"log"
+	"unsafe"
+
+	"golang.org/x/mobile/event"
+)
+
+// readPower returns the battery and thermal state of the device.
+// A device without a battery, or with an unknown thermal status, as
+// before API level 29, reports them as unknown.
+func readPower() event.Power {
+	out := [5]C.int{-1, -1, -1, 0, -1}
+	ids, err := activityMethodIDs.get(powerMethods...)
+	if err != nil {
+		log.Print(err)
+		return androidPower(-1, -1, -1, false, -1)
+	}
+	var thermalID uintptr
+	if id, err := activityMethodIDs.get(thermalStatusMethod); err == nil {
+		thermalID = id[0]
+	}
+	C.readPower((*C.uintptr_t)(unsafe.Pointer(&ids[0])), C.uintptr_t(thermalID), &out[0])
+	return androidPower(int(out[0]), int(out[1]), int(out[2]), out[3] != 0, int(out[4]))
+}
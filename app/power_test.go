@@ -0,0 +1,133 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/mobile/event"
+)
+
+func TestAndroidPower(t *testing.T) {
+	tests := []struct {
+		level, scale, status int
+		present              bool
+		thermal              int
+		want                 event.Power
+	}{
+		{50, 100, 3, true, 0, event.Power{Battery: 0.5, Charging: event.Discharging, Thermal: event.ThermalNominal}},
+		{200, 200, 5, true, 1, event.Power{Battery: 1, Charging: event.ChargingFull, Thermal: event.ThermalFair}},
+		{20, 100, 2, true, 2, event.Power{Battery: 0.2, Charging: event.Charging, Thermal: event.ThermalFair}},
+		{80, 100, 4, true, 3, event.Power{Battery: 0.8, Charging: event.Discharging, Thermal: event.ThermalSerious}},
+		{80, 100, 1, true, 4, event.Power{Battery: 0.8, Charging: event.ChargingUnknown, Thermal: event.ThermalCritical}},
+		{80, 100, 3, true, 5, event.Power{Battery: 0.8, Charging: event.Discharging, Thermal: event.ThermalCritical}},
+		{80, 100, 3, true, 6, event.Power{Battery: 0.8, Charging: event.Discharging, Thermal: event.ThermalCritical}},
+		{80, 100, 3, true, 7, event.Power{Battery: 0.8, Charging: event.Discharging, Thermal: event.ThermalUnknown}},
+		// Before API level 29.
+		{80, 100, 3, true, -1, event.Power{Battery: 0.8, Charging: event.Discharging, Thermal: event.ThermalUnknown}},
+		// A device without a battery.
+		{0, 100, 1, false, 0, event.Power{Battery: -1, Charging: event.ChargingUnknown, Thermal: event.ThermalNominal}},
+		// No battery intent.
+		{-1, -1, -1, false, -1, event.Power{Battery: -1, Charging: event.ChargingUnknown, Thermal: event.ThermalUnknown}},
+		{50, 0, 2, true, 0, event.Power{Battery: -1, Charging: event.Charging, Thermal: event.ThermalNominal}},
+		{150, 100, 2, true, 0, event.Power{Battery: -1, Charging: event.Charging, Thermal: event.ThermalNominal}},
+	}
+	for _, tt := range tests {
+		got := androidPower(tt.level, tt.scale, tt.status, tt.present, tt.thermal)
+		if got != tt.want {
+			t.Errorf("androidPower(%d, %d, %d, %v, %d) = %+v, want %+v", tt.level, tt.scale, tt.status, tt.present, tt.thermal, got, tt.want)
+		}
+	}
+}
+
+func TestIOSPower(t *testing.T) {
+	tests := []struct {
+		level          float32
+		state, thermal int
+		want           event.Power
+	}{
+		{0.5, 1, 0, event.Power{Battery: 0.5, Charging: event.Discharging, Thermal: event.ThermalNominal}},
+		{0.25, 2, 1, event.Power{Battery: 0.25, Charging: event.Charging, Thermal: event.ThermalFair}},
+		{1, 3, 2, event.Power{Battery: 1, Charging: event.ChargingFull, Thermal: event.ThermalSerious}},
+		{0.05, 1, 3, event.Power{Battery: 0.05, Charging: event.Discharging, Thermal: event.ThermalCritical}},
+		{0.05, 1, 4, event.Power{Battery: 0.05, Charging: event.Discharging, Thermal: event.ThermalUnknown}},
+		// Before iOS 11.
+		{0.5, 1, -1, event.Power{Battery: 0.5, Charging: event.Discharging, Thermal: event.ThermalUnknown}},
+		// The simulator.
+		{-1, 0, 0, event.Power{Battery: -1, Charging: event.ChargingUnknown, Thermal: event.ThermalNominal}},
+	}
+	for _, tt := range tests {
+		got := iosPower(tt.level, tt.state, tt.thermal)
+		if got != tt.want {
+			t.Errorf("iosPower(%v, %d, %d) = %+v, want %+v", tt.level, tt.state, tt.thermal, got, tt.want)
+		}
+	}
+}
+
+func TestPowerPoller(t *testing.T) {
+	state := event.Power{Battery: 0.5, Charging: event.Discharging, Thermal: event.ThermalNominal}
+	reads := 0
+	p := powerPoller{read: func() event.Power {
+		reads++
+		return state
+	}}
+	pending := func() (event.Power, bool) {
+		select {
+		case e := <-power:
+			return e, true
+		default:
+			return event.Power{}, false
+		}
+	}
+
+	// The first read is sent, whatever the state.
+	p.poll(10 * time.Second)
+	if e, ok := pending(); !ok || e != state {
+		t.Fatalf("first poll sent %+v, %v, want %+v", e, ok, state)
+	}
+
+	// The state is not read again within the interval.
+	state.Battery = 0.4
+	p.poll(10*time.Second + powerPollInterval - 1)
+	if reads != 1 {
+		t.Errorf("%d reads within the interval, want 1", reads)
+	}
+	if e, ok := pending(); ok {
+		t.Errorf("poll within the interval sent %+v", e)
+	}
+
+	// A change is sent, an unchanged state is not.
+	p.poll(10*time.Second + powerPollInterval)
+	if e, ok := pending(); !ok || e != state {
+		t.Errorf("change sent %+v, %v, want %+v", e, ok, state)
+	}
+	p.poll(10*time.Second + 2*powerPollInterval)
+	if e, ok := pending(); ok {
+		t.Errorf("unchanged state sent %+v", e)
+	}
+	if reads != 3 {
+		t.Errorf("%d reads, want 3", reads)
+	}
+
+	// The latest change replaces a pending one.
+	sendPower(event.Power{Battery: 0.3})
+	sendPower(event.Power{Battery: 0.2})
+	var got []event.Power
+	handlePower(Callbacks{Power: func(e event.Power) { got = append(got, e) }}, <-power)
+	if len(got) != 1 || got[0].Battery != 0.2 {
+		t.Errorf("Power called with %v, want one call with battery 0.2", got)
+	}
+}
+
+func TestPowerMethods(t *testing.T) {
+	for _, m := range append(powerMethods, thermalStatusMethod) {
+		if !validJNISig(m.sig) {
+			t.Errorf("%v: bad signature", m)
+		}
+	}
+}
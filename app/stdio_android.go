@@ -9,6 +9,10 @@ package app
 // we redirect them to logcat.
 //
 // Unfortunately, logcat is line oriented, so we must buffer.
+//
+// The lines are written with the tag and minimum priority set by
+// SetLogTag and SetLogLevel, and a priority given by their prefix,
+// such as "warning:", or else by their output.
 
 /*
 #cgo LDFLAGS: -llog
@@ -21,24 +25,43 @@ import (
 	"bufio"
 	"log"
 	"os"
+	"strings"
+	"syscall"
 	"unsafe"
 )
 
-var (
-	ctag    = C.CString("GoStdio")
-	ctagLog = C.CString("GoLog")
-)
+// androidLogWrite writes line to the Android log.
+func androidLogWrite(priority LogPriority, tag, line string) {
+	ctag := C.CString(tag)
+	cstr := C.CString(line)
+	C.__android_log_write(C.int(priority), ctag, cstr)
+	C.free(unsafe.Pointer(ctag))
+	C.free(unsafe.Pointer(cstr))
+}
+
+// androidWriteCrash writes the report of a crash to the Android log
+// with the fatal priority, one line at a time, as the log truncates long
+// entries.
+func androidWriteCrash(report string) {
+	logConfig.Lock()
+	tag := logConfig.tag
+	logConfig.Unlock()
+	for _, line := range strings.Split(strings.TrimSuffix(report, "\n"), "\n") {
+		androidLogWrite(LogFatal, tag, line)
+	}
+}
+
+// logOutput is the output of the log package.
+var logOutput = &logLines{def: LogInfo, write: androidLogWrite}
 
 type infoWriter struct{}
 
 func (infoWriter) Write(p []byte) (n int, err error) {
-	cstr := C.CString(string(p))
-	C.__android_log_write(C.ANDROID_LOG_INFO, ctagLog, cstr)
-	C.free(unsafe.Pointer(cstr))
+	logOutput.line(strings.TrimSuffix(string(p), "\n"))
 	return len(p), nil
 }
 
-func lineLog(f *os.File, priority C.int) {
+func lineLog(f *os.File, l *logLines) {
 	const logSize = 1024 // matches android/log.h.
 	r := bufio.NewReaderSize(f, logSize)
 	for {
@@ -47,9 +70,7 @@ func lineLog(f *os.File, priority C.int) {
 		if err != nil {
 			str += " " + err.Error()
 		}
-		cstr := C.CString(str)
-		C.__android_log_write(priority, ctag, cstr)
-		C.free(unsafe.Pointer(cstr))
+		l.line(str)
 		if err != nil {
 			break
 		}
@@ -57,6 +78,7 @@ func lineLog(f *os.File, priority C.int) {
 }
 
 func init() {
+	writeCrash = androidWriteCrash
 	log.SetOutput(infoWriter{})
 	// android logcat includes all of log.LstdFlags
 	log.SetFlags(log.Flags() &^ log.LstdFlags)
@@ -65,13 +87,17 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	// The runtime writes panics to file descriptor 2, not os.Stderr.
+	if err := syscall.Dup2(int(w.Fd()), 2); err != nil {
+		panic(err)
+	}
 	os.Stderr = w
-	go lineLog(r, C.ANDROID_LOG_ERROR)
+	go lineLog(r, &logLines{def: LogError, write: androidLogWrite, crashes: true})
 
 	r, w, err = os.Pipe()
 	if err != nil {
 		panic(err)
 	}
 	os.Stdout = w
-	go lineLog(r, C.ANDROID_LOG_INFO)
+	go lineLog(r, &logLines{def: LogInfo, write: androidLogWrite})
 }
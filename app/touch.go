@@ -0,0 +1,89 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"golang.org/x/mobile/event"
+	"golang.org/x/mobile/geom"
+)
+
+// Values of the action and tool type of an Android motion event, from
+// the NDK: include/android/input.h.
+const (
+	motionActionMask              = 0xff
+	motionActionPointerIndexMask  = 0xff00
+	motionActionPointerIndexShift = 8
+
+	motionActionDown        = 0
+	motionActionUp          = 1
+	motionActionPointerDown = 5
+	motionActionPointerUp   = 6
+
+	motionToolFinger = 1
+	motionToolStylus = 2
+	motionToolMouse  = 3
+	motionToolEraser = 4
+)
+
+// motionPointer is one pointer of an Android motion event, as read from
+// the AMotionEvent by processEvent.
+type motionPointer struct {
+	id       int32
+	x, y     float32 // in pixels
+	pressure float32
+	size     float32
+	tool     int32 // zero where AMotionEvent_getToolType is unavailable
+}
+
+// motionTouches returns the touch events of an Android motion event with
+// the given action and pointers.
+func motionTouches(action int32, pointers []motionPointer) []event.Touch {
+	// At most one of the pointers is going up or down; get its index and type.
+	upDownIndex := int(action&motionActionPointerIndexMask) >> motionActionPointerIndexShift
+	upDownTyp := event.TouchMove
+	switch action & motionActionMask {
+	case motionActionDown, motionActionPointerDown:
+		upDownTyp = event.TouchStart
+	case motionActionUp, motionActionPointerUp:
+		upDownTyp = event.TouchEnd
+	}
+
+	touches := make([]event.Touch, len(pointers))
+	for i, p := range pointers {
+		typ := event.TouchMove
+		if i == upDownIndex {
+			typ = upDownTyp
+		}
+		touches[i] = event.Touch{
+			ID:   event.TouchSequenceID(p.id),
+			Type: typ,
+			Loc: geom.Point{
+				X: geom.Pt(p.x / geom.PixelsPerPt),
+				Y: geom.Pt(p.y / geom.PixelsPerPt),
+			},
+			Pressure: p.pressure,
+			Size:     p.size,
+			Tool:     motionTool(p.tool),
+		}
+	}
+	return touches
+}
+
+// motionTool returns the tool of an Android AMOTION_EVENT_TOOL_TYPE.
+func motionTool(tool int32) event.ToolType {
+	switch tool {
+	case motionToolFinger:
+		return event.ToolFinger
+	case motionToolStylus:
+		return event.ToolStylus
+	case motionToolMouse:
+		return event.ToolMouse
+	case motionToolEraser:
+		return event.ToolEraser
+	}
+	return event.ToolUnknown
+}
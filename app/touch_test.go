@@ -0,0 +1,76 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package app
+
+import (
+	"testing"
+
+	"golang.org/x/mobile/event"
+	"golang.org/x/mobile/geom"
+)
+
+func TestMotionTouches(t *testing.T) {
+	defer func(ppp float32) { geom.PixelsPerPt = ppp }(geom.PixelsPerPt)
+	geom.PixelsPerPt = 2
+
+	pointers := []motionPointer{
+		{id: 3, x: 10, y: 20, pressure: 0.5, size: 0.25, tool: motionToolFinger},
+		{id: 7, x: 30, y: 40, pressure: 1.25, size: 0.1, tool: motionToolStylus},
+	}
+	tests := []struct {
+		desc   string
+		action int32
+		types  []event.TouchType
+	}{
+		{"down", motionActionDown, []event.TouchType{event.TouchStart, event.TouchMove}},
+		{"move", 2, []event.TouchType{event.TouchMove, event.TouchMove}},
+		{"second pointer down", motionActionPointerDown | 1<<motionActionPointerIndexShift, []event.TouchType{event.TouchMove, event.TouchStart}},
+		{"second pointer up", motionActionPointerUp | 1<<motionActionPointerIndexShift, []event.TouchType{event.TouchMove, event.TouchEnd}},
+		{"up", motionActionUp, []event.TouchType{event.TouchEnd, event.TouchMove}},
+	}
+	for _, tt := range tests {
+		touches := motionTouches(tt.action, pointers)
+		if len(touches) != len(pointers) {
+			t.Fatalf("%s: %d touches, want %d", tt.desc, len(touches), len(pointers))
+		}
+		for i, typ := range tt.types {
+			if touches[i].Type != typ {
+				t.Errorf("%s: touch %d has type %d, want %d", tt.desc, i, touches[i].Type, typ)
+			}
+		}
+	}
+
+	touches := motionTouches(motionActionDown, pointers)
+	want := []event.Touch{
+		{ID: 3, Type: event.TouchStart, Loc: geom.Point{X: 5, Y: 10}, Pressure: 0.5, Size: 0.25, Tool: event.ToolFinger},
+		{ID: 7, Type: event.TouchMove, Loc: geom.Point{X: 15, Y: 20}, Pressure: 1.25, Size: 0.1, Tool: event.ToolStylus},
+	}
+	for i := range want {
+		if touches[i] != want[i] {
+			t.Errorf("touch %d = %+v, want %+v", i, touches[i], want[i])
+		}
+	}
+}
+
+func TestMotionTool(t *testing.T) {
+	tests := []struct {
+		tool int32
+		want event.ToolType
+	}{
+		{0, event.ToolUnknown}, // before API level 14
+		{motionToolFinger, event.ToolFinger},
+		{motionToolStylus, event.ToolStylus},
+		{motionToolMouse, event.ToolMouse},
+		{motionToolEraser, event.ToolEraser},
+		{99, event.ToolUnknown},
+	}
+	for _, tt := range tests {
+		if got := motionTool(tt.tool); got != tt.want {
+			t.Errorf("motionTool(%d) = %v, want %v", tt.tool, got, tt.want)
+		}
+	}
+}
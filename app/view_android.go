@@ -0,0 +1,140 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+// An all-Go app built by gomobile build -activity=fragment is shown by
+// the GoView of the Java package go, a GLSurfaceView that can be placed
+// in the layout of any activity, instead of by a NativeActivity. The
+// renderer thread of the view calls the functions below, through the
+// GO_VIEW functions of android.c, and they call the Callbacks of the
+// app on it, as drawgl does on iOS.
+
+/*
+#include <jni.h>
+#include <stdint.h>
+*/
+import "C"
+import (
+	"log"
+	"time"
+	"unsafe"
+
+	"golang.org/x/mobile/geom"
+)
+
+// view is the state of an app shown by a GoView.
+var view struct {
+	cb      Callbacks
+	started bool // Start was called, and Stop was not since
+	clock   frameClock
+	setup   glSetup
+	period  time.Duration // refresh period of the display, or 0
+	battery powerPoller
+}
+
+// runView keeps the callbacks of an app shown by a GoView, for its
+// renderer to call.
+func runView(cb Callbacks) {
+	view.cb = cb
+	view.battery = powerPoller{read: readPower}
+}
+
+//export onViewGLVersion
+func onViewGLVersion(es3 C.jboolean) C.jint {
+	v, err := negotiateGLVersion(view.cb.GLVersion, func(version int) bool {
+		return version == 2 || es3 != 0
+	})
+	if err != nil {
+		log.Print(err)
+	}
+	return C.jint(v)
+}
+
+//export onViewSurfaceCreated
+func onViewSurfaceCreated() {
+	// The resources of the app are lost with the OpenGL ES context it
+	// replaces. The app stops, and starts again to create them.
+	onViewStop()
+	view.setup = glSetup{}
+}
+
+//export onViewSurfaceChanged
+func onViewSurfaceChanged(width, height C.jint, refreshRate C.jfloat) {
+	geom.Width = geom.Pt(float32(width) / geom.PixelsPerPt)
+	geom.Height = geom.Pt(float32(height) / geom.PixelsPerPt)
+	view.period = 0
+	if refreshRate > 0 {
+		view.period = time.Duration(float32(time.Second) / float32(refreshRate))
+	}
+}
+
+//export onViewDrawFrame
+func onViewDrawFrame(nanos C.jlong) {
+	defer reportCrash()
+
+	cb := view.cb
+	if !view.started {
+		view.started = true
+		runStart(cb)
+	}
+
+	select {
+	case e := <-lowMemory:
+		handleLowMemory(cb, e)
+	default:
+	}
+	select {
+	case e := <-keyboard:
+		handleKeyboard(cb, e)
+	default:
+	}
+	view.battery.poll(time.Duration(nanos))
+	select {
+	case e := <-power:
+		handlePower(cb, e)
+	default:
+	}
+
+	handleFrame(cb, &view.clock, time.Duration(nanos), view.period)
+	view.setup.beforeDraw(cb, nil)
+	if cb.Draw != nil {
+		cb.Draw()
+	}
+}
+
+//export onViewTouch
+func onViewTouch(action, n C.jint, ids, tools *C.jint, values *C.jfloat) {
+	if view.cb.Touch == nil || n <= 0 {
+		return
+	}
+	cids := (*[1 << 20]C.jint)(unsafe.Pointer(ids))[:n:n]
+	ctools := (*[1 << 20]C.jint)(unsafe.Pointer(tools))[:n:n]
+	cvalues := (*[1 << 20]C.jfloat)(unsafe.Pointer(values))[: 4*n : 4*n]
+	pointers := make([]motionPointer, n)
+	for i := range pointers {
+		pointers[i] = motionPointer{
+			id:       int32(cids[i]),
+			x:        float32(cvalues[4*i]),
+			y:        float32(cvalues[4*i+1]),
+			pressure: float32(cvalues[4*i+2]),
+			size:     float32(cvalues[4*i+3]),
+			tool:     int32(ctools[i]),
+		}
+	}
+	for _, t := range motionTouches(int32(action), pointers) {
+		view.cb.Touch(t)
+	}
+}
+
+//export onViewStop
+func onViewStop() {
+	if !view.started {
+		return
+	}
+	view.started = false
+	if view.cb.Stop != nil {
+		view.cb.Stop()
+	}
+}
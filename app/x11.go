@@ -24,6 +24,7 @@ import "C"
 import (
 	"runtime"
 	"sync"
+	"time"
 
 	"golang.org/x/mobile/event"
 	"golang.org/x/mobile/geom"
@@ -31,6 +32,14 @@ import (
 
 var cb Callbacks
 
+// clock times the frames of X11, which reports no vsync, by the time
+// they are drawn.
+var (
+	clock     frameClock
+	startTime = time.Now()
+	setup     glSetup
+)
+
 func run(callbacks Callbacks) {
 	runtime.LockOSThread()
 	cb = callbacks
@@ -60,6 +69,7 @@ func sendTouch(ty event.TouchType, x, y float32) {
 			X: geom.Pt(x / geom.PixelsPerPt),
 			Y: geom.Height - geom.Pt(y/geom.PixelsPerPt),
 		},
+		Tool: event.ToolMouse,
 	})
 	touchEvents.Unlock()
 }
@@ -85,6 +95,8 @@ func onDraw() {
 		}
 	}
 
+	handleFrame(cb, &clock, time.Since(startTime), 0)
+	setup.beforeDraw(cb, nil)
 	if cb.Draw != nil {
 		cb.Draw()
 	}
@@ -103,3 +115,7 @@ func onStop() {
 		cb.Stop()
 	}
 }
+
+// Desktops have a physical keyboard, and no soft keyboard to show.
+func showKeyboard(mode KeyboardMode) {}
+func hideKeyboard()                  {}
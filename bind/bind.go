@@ -8,10 +8,10 @@
 package bind // import "golang.org/x/mobile/bind"
 
 // TODO(crawshaw): slice support
-// TODO(crawshaw): channel support
 
 import (
 	"bytes"
+	"fmt"
 	"go/format"
 	"go/token"
 	"io"
@@ -19,14 +19,124 @@ import (
 	"golang.org/x/tools/go/types"
 )
 
+// Options configures the generated bindings. The Java API and the Go
+// stub of a package must be generated with the same Options.
+type Options struct {
+	// Annotations names the package of the Nullable annotation that
+	// marks nullable Java results: "androidx" (androidx.annotation) or
+	// "javax" (javax.annotation, JSR 305). If empty, no annotations are
+	// generated.
+	Annotations string
+
+	// ByteBuffers binds the []byte parameters of Go functions and struct
+	// methods to direct java.nio.ByteBuffers instead of byte arrays. Go
+	// reads the remaining bytes of the buffer in place, without copying,
+	// and may only use them until the call returns. Parameters of
+	// interface methods are still byte arrays.
+	ByteBuffers bool
+
+	// CommaOK selects the Java results of the Go functions and methods
+	// following the comma-ok idiom, func Lookup(k string) (v Value, ok
+	// bool): "nullable" returns the value, boxed if it is primitive, or
+	// null when the bool is false; "result" returns a result class of
+	// both values, as for other multiple results. If empty, CommaOK is
+	// "nullable". Interface methods are not bound either way.
+	CommaOK string
+
+	// JavaPkg is the Java package containing the generated Java
+	// packages. The class of a Go package named p is generated in the
+	// Java package JavaPkg.p, so packages bound together under the same
+	// JavaPkg do not collide. If empty, JavaPkg is "go".
+	JavaPkg string
+
+	// SupportPkg is the Java package of the support classes Seq and Go
+	// that the generated classes use. The support classes of libraries
+	// bound separately collide when an app uses several of them; a
+	// SupportPkg of their own relocates them, with the JNI functions of
+	// their native code, so the libraries coexist. If empty, SupportPkg
+	// is "go".
+	SupportPkg string
+
+	// Packages are the packages bound together, in one library, with
+	// the generated package. The API of the package may use the
+	// exported struct and interface types of the other packages, which
+	// refer to the classes generated for those packages. All the
+	// packages bound together must be generated with the same Packages.
+	// Packages are identified by import path.
+	Packages []*types.Package
+}
+
+// boundPkgs returns the packages bound together with pkg, other than
+// pkg, keyed by import path.
+func (opts *Options) boundPkgs(pkg *types.Package) map[string]*types.Package {
+	bound := make(map[string]*types.Package)
+	for _, p := range opts.Packages {
+		if p.Path() != pkg.Path() {
+			bound[p.Path()] = p
+		}
+	}
+	return bound
+}
+
+// javaPkg returns the Java package of the class generated for pkg.
+func (opts *Options) javaPkg(pkg *types.Package) string {
+	root := opts.JavaPkg
+	if root == "" {
+		root = "go"
+	}
+	return root + "." + pkg.Name()
+}
+
+// supportPkg returns the Java package of the support classes.
+func (opts *Options) supportPkg() string {
+	if opts.SupportPkg == "" {
+		return "go"
+	}
+	return opts.SupportPkg
+}
+
+// checkJavaPkgs checks the Java package names of the options.
+func (opts *Options) checkJavaPkgs() error {
+	if opts.JavaPkg != "" && !validJavaPkg(opts.JavaPkg) {
+		return fmt.Errorf("bind: invalid Java package name %q", opts.JavaPkg)
+	}
+	if opts.SupportPkg != "" && !validJavaPkg(opts.SupportPkg) {
+		return fmt.Errorf("bind: invalid Java support package name %q", opts.SupportPkg)
+	}
+	return nil
+}
+
 // GenJava generates a Java API from a Go package.
 func GenJava(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
-	buf := new(bytes.Buffer)
-	g := &javaGen{
-		printer: &printer{buf: buf, indentEach: []byte("    ")},
-		fset:    fset,
-		pkg:     pkg,
+	return GenJavaOptions(w, fset, pkg, nil)
+}
+
+// GenJavaAnnotated generates a Java API from a Go package, marking
+// nullable results with the Nullable annotation from the named package.
+// See Options.Annotations.
+func GenJavaAnnotated(w io.Writer, fset *token.FileSet, pkg *types.Package, annotations string) error {
+	return GenJavaOptions(w, fset, pkg, &Options{Annotations: annotations})
+}
+
+// GenJavaOptions generates a Java API from a Go package with the given
+// options. Nil options are the defaults.
+func GenJavaOptions(w io.Writer, fset *token.FileSet, pkg *types.Package, opts *Options) error {
+	if opts == nil {
+		opts = new(Options)
 	}
+	if _, ok := javaNullable[opts.Annotations]; opts.Annotations != "" && !ok {
+		return fmt.Errorf("bind: unknown annotations package %q", opts.Annotations)
+	}
+	switch opts.CommaOK {
+	case "", "nullable", "result":
+	default:
+		return fmt.Errorf("bind: unknown comma-ok mapping %q", opts.CommaOK)
+	}
+	if err := opts.checkJavaPkgs(); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	g := newJavaGen(buf, fset, pkg, opts)
 	if err := g.gen(); err != nil {
 		return err
 	}
@@ -34,14 +144,62 @@ func GenJava(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
 	return err
 }
 
+// GenJavaExample generates a Java class, Example, showing how to call the
+// Java API generated from a Go package with the given options. It is
+// compiled with the API, in the same Java package. Nil options are the
+// defaults.
+func GenJavaExample(w io.Writer, fset *token.FileSet, pkg *types.Package, opts *Options) error {
+	if opts == nil {
+		opts = new(Options)
+	}
+	if err := opts.checkJavaPkgs(); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	g := newJavaGen(buf, fset, pkg, opts)
+	if err := g.genExample(); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, buf)
+	return err
+}
+
+func newJavaGen(buf *bytes.Buffer, fset *token.FileSet, pkg *types.Package, opts *Options) *javaGen {
+	g := &javaGen{
+		printer:     &printer{buf: buf, indentEach: []byte("    ")},
+		fset:        fset,
+		pkg:         pkg,
+		annotations: opts.Annotations,
+		byteBuffers: opts.ByteBuffers,
+		commaOK:     opts.CommaOK != "result",
+		javaPkg:     opts.javaPkg(pkg),
+		supportPkg:  opts.supportPkg(),
+		bound:       make(map[string]string),
+	}
+	for path, p := range opts.boundPkgs(pkg) {
+		g.bound[path] = opts.javaPkg(p) + "." + javaClassName(p)
+	}
+	return g
+}
+
+// Warnf is called for each part of a Go package that the generators
+// omit without failing, such as a field or method whose promotion from
+// an embedded struct is ambiguous. By default warnings are discarded.
+var Warnf = func(format string, args ...interface{}) {}
+
 // GenGo generates a Go stub to support foreign language APIs.
 func GenGo(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
-	buf := new(bytes.Buffer)
-	g := &goGen{
-		printer: &printer{buf: buf, indentEach: []byte("\t")},
-		fset:    fset,
-		pkg:     pkg,
+	return GenGoOptions(w, fset, pkg, nil)
+}
+
+// GenGoOptions generates a Go stub with the given options. Nil options
+// are the defaults.
+func GenGoOptions(w io.Writer, fset *token.FileSet, pkg *types.Package, opts *Options) error {
+	if opts == nil {
+		opts = new(Options)
 	}
+	buf := new(bytes.Buffer)
+	g := newGoGen(buf, fset, pkg, opts)
 	if err := g.gen(); err != nil {
 		return err
 	}
@@ -54,3 +212,18 @@ func GenGo(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
 	_, err = w.Write(srcf)
 	return err
 }
+
+func newGoGen(buf *bytes.Buffer, fset *token.FileSet, pkg *types.Package, opts *Options) *goGen {
+	g := &goGen{
+		printer:     &printer{buf: buf, indentEach: []byte("\t")},
+		fset:        fset,
+		pkg:         pkg,
+		byteBuffers: opts.ByteBuffers,
+		imports:     make(map[string]bool),
+		bound:       make(map[string]bool),
+	}
+	for path := range opts.boundPkgs(pkg) {
+		g.bound[path] = true
+	}
+	return g
+}
@@ -3,17 +3,22 @@ package bind
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 
+	"golang.org/x/tools/go/exact"
 	_ "golang.org/x/tools/go/gcimporter"
 	"golang.org/x/tools/go/types"
 )
@@ -24,6 +29,26 @@ var tests = []string{
 	"testdata/basictypes.go",
 	"testdata/structs.go",
 	"testdata/interfaces.go",
+	"testdata/channels.go",
+	"testdata/embedded.go",
+	"testdata/times.go",
+	"testdata/enums.go",
+	"testdata/richerrors.go",
+	"testdata/contexts.go",
+	"testdata/maptypes.go",
+	"testdata/variadic.go",
+	"testdata/closures.go",
+	"testdata/multiresults.go",
+	"testdata/commaok.go",
+	"testdata/initonce.go",
+	"testdata/structslices.go",
+	"testdata/consts.go",
+	"testdata/stringers.go",
+	"testdata/structtags.go",
+	"testdata/visitors.go",
+	"testdata/vars.go",
+	"testdata/arrays.go",
+	"testdata/streams.go",
 }
 
 var fset = token.NewFileSet()
@@ -104,6 +129,327 @@ func TestGenJava(t *testing.T) {
 	}
 }
 
+func TestGenJavaExample(t *testing.T) {
+	filename := "testdata/examples.go"
+	pkg := typeCheck(t, filename)
+	var buf bytes.Buffer
+	if err := GenJavaExample(&buf, fset, pkg, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := writeTempFile(t, "java", buf.Bytes())
+	defer os.Remove(out)
+	golden := "testdata/examples.example.java.golden"
+	if diffstr := diff(golden, out); diffstr != "" {
+		t.Errorf("%s: does not match Java example golden:\n%s", filename, diffstr)
+
+		if *updateFlag {
+			t.Logf("Updating %s...", golden)
+			if err := exec.Command("/bin/cp", out, golden).Run(); err != nil {
+				t.Errorf("Update failed: %s", err)
+			}
+		}
+	}
+}
+
+func TestGenJavaAnnotations(t *testing.T) {
+	filename := "testdata/annotations.go"
+	pkg := typeCheck(t, filename)
+	for _, annotations := range []string{"androidx", "javax"} {
+		var buf bytes.Buffer
+		if err := GenJavaAnnotated(&buf, fset, pkg, annotations); err != nil {
+			t.Errorf("%s: %v", annotations, err)
+			continue
+		}
+		out := writeTempFile(t, "java", buf.Bytes())
+		defer os.Remove(out)
+		golden := "testdata/annotations." + annotations + ".java.golden"
+		if diffstr := diff(golden, out); diffstr != "" {
+			t.Errorf("%s: does not match Java golden:\n%s", annotations, diffstr)
+
+			if *updateFlag {
+				t.Logf("Updating %s...", golden)
+				if err := exec.Command("/bin/cp", out, golden).Run(); err != nil {
+					t.Errorf("Update failed: %s", err)
+				}
+			}
+		}
+	}
+
+	// A package with only primitive results needs no import.
+	pkg = typeCheck(t, "testdata/structs.go")
+	var plain, annotated bytes.Buffer
+	if err := GenJava(&plain, fset, pkg); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenJavaAnnotated(&annotated, fset, pkg, "androidx"); err != nil {
+		t.Fatal(err)
+	}
+	if plain.String() != annotated.String() {
+		t.Errorf("structs: annotated output differs from plain output:\n%s", annotated.String())
+	}
+
+	if err := GenJavaAnnotated(ioutil.Discard, fset, pkg, "jetbrains"); err == nil {
+		t.Error("GenJavaAnnotated with unknown annotations package: got nil error")
+	}
+}
+
+func TestGenUnsupportedChannels(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badchannels.go")
+	want := []string{
+		"I.Watch: channel parameters are not supported in interface methods",
+		"S.C: unsupported channel field type chan int",
+		"Recv: unsupported receive-only channel type <-chan int",
+		"Result: unsupported channel result type chan int",
+		"Elem: unsupported channel element type map[string]int",
+	}
+	err := GenJava(ioutil.Discard, fset, pkg)
+	if err == nil {
+		t.Fatal("GenJava: got nil error")
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("GenJava error does not contain %q:\n%v", w, err)
+		}
+	}
+
+	err = GenGo(ioutil.Discard, fset, pkg)
+	if err == nil {
+		t.Fatal("GenGo: got nil error")
+	}
+	want = []string{
+		"I.Watch: channel parameters are not supported in interface methods",
+		"S.C: unsupported channel field type chan int",
+		"unsupported receive-only channel type <-chan int",
+		"Result: unsupported channel result type chan int",
+		"unsupported channel element type map[string]int",
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("GenGo error does not contain %q:\n%v", w, err)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badchannels.go")
+	want := map[string]string{
+		"Elem":   "unsupported channel element type map[string]int",
+		"I":      "I.Watch: channel parameters are not supported in interface methods",
+		"Recv":   "unsupported receive-only channel type <-chan int",
+		"Result": "Result: unsupported channel result type chan int",
+		"S":      "S.C: unsupported channel field type chan int",
+	}
+	syms := Symbols(fset, pkg, nil)
+	if len(syms) != len(want) {
+		t.Errorf("got %d symbols, want %d: %v", len(syms), len(want), syms)
+	}
+	for _, sym := range syms {
+		if sym.Err == nil {
+			t.Errorf("%s: got nil error", sym.Name)
+		} else if w := want[sym.Name]; !strings.Contains(sym.Err.Error(), w) {
+			t.Errorf("%s error does not contain %q:\n%v", sym.Name, w, sym.Err)
+		}
+	}
+
+	pkg = typeCheck(t, "testdata/enums.go")
+	for _, sym := range Symbols(fset, pkg, nil) {
+		if sym.Err != nil {
+			t.Errorf("%s %s: %v", sym.Kind, sym.Name, sym.Err)
+		}
+	}
+}
+
+func TestGenUnsupportedMaps(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badmaps.go")
+	want := []string{
+		"unsupported map key type badmaps.K in map[badmaps.K]int",
+		"unsupported map value type chan int in map[string]chan int",
+	}
+	if err := GenJava(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenJava: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenJava error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+	if err := GenGo(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenGo: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenGo error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+}
+
+func TestGenUnsupportedSlices(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badslices.go")
+	want := []string{
+		"unsupported slice type []int",
+		"unsupported slice type [][]badslices.Point",
+		"unsupported slice type []error",
+	}
+	if err := GenJava(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenJava: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenJava error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+	if err := GenGo(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenGo: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenGo error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+}
+
+func TestGenUnsupportedConsts(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badconsts.go")
+	want := []string{
+		"unsupported constant type badconsts.Name of Alice",
+		"unsupported constant type uint of Unsigned",
+		"unsupported constant type untyped complex of Complex",
+	}
+	err := GenJava(ioutil.Discard, fset, pkg)
+	if err == nil {
+		t.Fatal("GenJava: got nil error")
+	}
+	for _, w := range append(want, "constant TooBig overflows long") {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("GenJava error does not contain %q:\n%v", w, err)
+		}
+	}
+	err = GenGo(ioutil.Discard, fset, pkg)
+	if err == nil {
+		t.Fatal("GenGo: got nil error")
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("GenGo error does not contain %q:\n%v", w, err)
+		}
+	}
+}
+
+func TestGenUnsupportedArrays(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badarrays.go")
+	want := "unsupported array type [2][]byte"
+	if err := GenJava(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenJava: got nil error")
+	} else if !strings.Contains(err.Error(), want) {
+		t.Errorf("GenJava error does not contain %q:\n%v", want, err)
+	}
+	if err := GenGo(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenGo: got nil error")
+	} else if !strings.Contains(err.Error(), want) {
+		t.Errorf("GenGo error does not contain %q:\n%v", want, err)
+	}
+}
+
+func TestGenBadStructTags(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badstructtags.go")
+	want := []string{
+		"Collision.ID and Collision.Identifier are both bound as iD",
+		`Keyword.Class: gomobile tag name "class" is not a valid identifier`,
+		`Unknown.X: unknown gomobile tag option "omitempty"`,
+	}
+	for _, gen := range []struct {
+		name string
+		fn   func(io.Writer, *token.FileSet, *types.Package) error
+	}{{"GenJava", GenJava}, {"GenGo", GenGo}} {
+		err := gen.fn(ioutil.Discard, fset, pkg)
+		if err == nil {
+			t.Errorf("%s: got nil error", gen.name)
+			continue
+		}
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("%s error does not contain %q:\n%v", gen.name, w, err)
+			}
+		}
+	}
+}
+
+func TestJavaConstValue(t *testing.T) {
+	tests := []struct {
+		v    exact.Value
+		kind types.BasicKind
+		want string
+	}{
+		{exact.MakeString("a \"b\"\\\n\u00e9\U0001F600"), types.String, `"a \"b\"\\\n\u00e9\ud83d\ude00"`},
+		{exact.MakeFloat64(math.Pi), types.Float64, "3.141592653589793"},
+		{exact.MakeFloat64(math.Pi), types.Float32, "3.1415927f"},
+		{exact.MakeInt64(3), types.Float64, "3.0"},
+		{exact.MakeBool(false), types.Bool, "false"},
+		{exact.MakeInt64(-1), types.Int, "-1L"},
+		{exact.MakeInt64(255), types.Uint8, "(byte)255"},
+		{exact.MakeInt64('x'), types.Int32, "120"},
+	}
+	for _, tt := range tests {
+		got, err := javaConstValue(tt.v, tt.kind)
+		if err != nil {
+			t.Errorf("javaConstValue(%v, %v): %v", tt.v, tt.kind, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("javaConstValue(%v, %v) = %s, want %s", tt.v, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestGenAmbiguousEmbedding(t *testing.T) {
+	pkg := typeCheck(t, "testdata/embedded.go")
+	var warnings []string
+	defer func(f func(string, ...interface{})) { Warnf = f }(Warnf)
+	Warnf = func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	if err := GenJava(ioutil.Discard, fset, pkg); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Both: ambiguous promoted field or method X omitted",
+		"Both: ambiguous promoted field or method Name omitted",
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
+
+func TestGenSkippedVars(t *testing.T) {
+	pkg := typeCheck(t, "testdata/vars.go")
+	var warnings []string
+	defer func(f func(string, ...interface{})) { Warnf = f }(Warnf)
+	Warnf = func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	if err := GenJava(ioutil.Discard, fset, pkg); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Done: variable skipped: unsupported variable type chan bool"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+
+	warnings = nil
+	for _, sym := range Symbols(fset, pkg, nil) {
+		if got := sym.Err != nil; got != (sym.Name == "Done") {
+			t.Errorf("%s %s: %v", sym.Kind, sym.Name, sym.Err)
+		}
+	}
+	if len(warnings) > 0 {
+		t.Errorf("Symbols warned: %q", warnings)
+	}
+}
+
 func TestGenGo(t *testing.T) {
 	for _, filename := range tests {
 		var buf bytes.Buffer
@@ -127,3 +473,274 @@ func TestGenGo(t *testing.T) {
 		}
 	}
 }
+
+func TestGenByteBuffers(t *testing.T) {
+	filename := "testdata/bytebuffers.go"
+	pkg := typeCheck(t, filename)
+	opts := &Options{ByteBuffers: true}
+	for _, gen := range []struct {
+		golden string
+		fn     func(w io.Writer, fset *token.FileSet, pkg *types.Package, opts *Options) error
+	}{
+		{"testdata/bytebuffers.java.golden", GenJavaOptions},
+		{"testdata/bytebuffers.go.golden", GenGoOptions},
+	} {
+		var buf bytes.Buffer
+		if err := gen.fn(&buf, fset, pkg, opts); err != nil {
+			t.Errorf("%s: %v", gen.golden, err)
+			continue
+		}
+		out := writeTempFile(t, "bytebuffers", buf.Bytes())
+		defer os.Remove(out)
+		if diffstr := diff(gen.golden, out); diffstr != "" {
+			t.Errorf("%s: does not match golden:\n%s", gen.golden, diffstr)
+
+			if *updateFlag {
+				t.Logf("Updating %s...", gen.golden)
+				if err := exec.Command("/bin/cp", out, gen.golden).Run(); err != nil {
+					t.Errorf("Update failed: %s", err)
+				}
+			}
+		}
+	}
+
+	// Without the option, []byte parameters are bound as byte[].
+	var plain, dflt bytes.Buffer
+	if err := GenJava(&plain, fset, pkg); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenJavaOptions(&dflt, fset, pkg, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if plain.String() != dflt.String() {
+		t.Error("GenJavaOptions with default options differs from GenJava")
+	}
+	if strings.Contains(plain.String(), "ByteBuffer") {
+		t.Errorf("GenJava uses ByteBuffer without the option:\n%s", plain.String())
+	}
+}
+
+func TestGenJavaPkg(t *testing.T) {
+	opts := &Options{JavaPkg: "com.example.app"}
+	for _, tc := range []struct {
+		filename, class string
+	}{
+		{"testdata/structs.go", "com.example.app.structs.Structs"},
+		{"testdata/interfaces.go", "com.example.app.interfaces.Interfaces"},
+	} {
+		var buf bytes.Buffer
+		if err := GenJavaOptions(&buf, fset, typeCheck(t, tc.filename), opts); err != nil {
+			t.Errorf("%s: %v", tc.filename, err)
+			continue
+		}
+		i := strings.LastIndex(tc.class, ".")
+		pkgDecl := "\npackage " + tc.class[:i] + ";\n"
+		classDecl := "\npublic abstract class " + tc.class[i+1:] + " {\n"
+		if src := buf.String(); !strings.Contains(src, pkgDecl) || !strings.Contains(src, classDecl) {
+			t.Errorf("%s: class %s not generated:\n%s", tc.filename, tc.class, src)
+		}
+	}
+
+	pkg := typeCheck(t, "testdata/structs.go")
+	for _, name := range []string{"com..example", "com.example.", "com.package", "1com", "com.ex-ample"} {
+		if err := GenJavaOptions(ioutil.Discard, fset, pkg, &Options{JavaPkg: name}); err == nil {
+			t.Errorf("JavaPkg %q: got nil error", name)
+		}
+	}
+	if err := GenJavaOptions(ioutil.Discard, fset, pkg, &Options{JavaPkg: "com.example_2.$app"}); err != nil {
+		t.Errorf("JavaPkg %q: %v", "com.example_2.$app", err)
+	}
+}
+
+func TestGenJavaSupportPkg(t *testing.T) {
+	// Two libraries with support packages of their own, as bound for
+	// the same app, name only their own support classes.
+	for _, supportPkg := range []string{"com.example.a.go", "com.example.b.go"} {
+		opts := &Options{JavaPkg: supportPkg[:len(supportPkg)-len(".go")], SupportPkg: supportPkg}
+		for _, filename := range []string{"testdata/contexts.go", "testdata/interfaces.go"} {
+			var buf bytes.Buffer
+			if err := GenJavaOptions(&buf, fset, typeCheck(t, filename), opts); err != nil {
+				t.Errorf("%s: %v", filename, err)
+				continue
+			}
+			src := buf.String()
+			if !strings.Contains(src, "\nimport "+supportPkg+".Seq;\n") {
+				t.Errorf("%s, SupportPkg %s: Seq not imported:\n%s", filename, supportPkg, src)
+			}
+			// The support packages end in go, so every go.Seq is
+			// part of a supportPkg.Seq.
+			if n := strings.Count(src, "go.Seq"); n == 0 || strings.Count(src, supportPkg+".Seq") != n {
+				t.Errorf("%s, SupportPkg %s: Seq used from another package:\n%s", filename, supportPkg, src)
+			}
+		}
+		var buf bytes.Buffer
+		if err := GenJavaExample(&buf, fset, typeCheck(t, "testdata/examples.go"), opts); err != nil {
+			t.Fatal(err)
+		}
+		if want := supportPkg + ".Go.init(ctx);"; !strings.Contains(buf.String(), want) {
+			t.Errorf("SupportPkg %s: example does not call %s:\n%s", supportPkg, want, buf.String())
+		}
+	}
+
+	pkg := typeCheck(t, "testdata/structs.go")
+	if err := GenJavaOptions(ioutil.Discard, fset, pkg, &Options{SupportPkg: "com.example.new"}); err == nil {
+		t.Error("SupportPkg com.example.new: got nil error")
+	}
+}
+
+func TestGenUnsupportedVariadic(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badvariadic.go")
+	want := []string{
+		"I.Log: variadic parameters are not supported in interface methods",
+		"Chans: unsupported variadic parameter type ...chan int",
+	}
+	if err := GenJava(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenJava: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenJava error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+	if err := GenGo(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenGo: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenGo error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+}
+
+func TestGenUnsupportedClosures(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badclosures.go")
+	want := []string{
+		"I.Callback: func results are not supported in interface methods",
+		"Chans: unsupported parameter type chan int in func result type func(chan int)",
+		"Pair: func result type func() (int, int) must return either zero or one values, and optionally an error",
+	}
+	if err := GenJava(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenJava: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenJava error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+	if err := GenGo(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenGo: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenGo error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+}
+
+func TestGenCommaOK(t *testing.T) {
+	pkg := typeCheck(t, "testdata/commaok.go")
+	var nullable, annotated, result bytes.Buffer
+	if err := GenJavaOptions(&nullable, fset, pkg, &Options{CommaOK: "nullable"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenJavaOptions(&annotated, fset, pkg, &Options{Annotations: "androidx"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenJavaOptions(&result, fset, pkg, &Options{CommaOK: "result"}); err != nil {
+		t.Fatal(err)
+	}
+	var dflt bytes.Buffer
+	if err := GenJava(&dflt, fset, pkg); err != nil {
+		t.Fatal(err)
+	}
+	if nullable.String() != dflt.String() {
+		t.Error("CommaOK \"nullable\" differs from the default")
+	}
+	for _, decl := range []string{
+		"@Nullable public static Long Lookup(String k)",
+		"@Nullable public static S Find(String k)",
+		"@Nullable public Double Get(String k)",
+	} {
+		if !strings.Contains(annotated.String(), decl) {
+			t.Errorf("annotated output does not contain %q:\n%s", decl, annotated.String())
+		}
+	}
+	for _, decl := range []string{
+		"public static LookupResult Lookup(String k)",
+		"public static NameResult Name(String k)",
+		"public GetResult Get(String k)",
+		"public static SplitResult Split(String k)",
+	} {
+		if !strings.Contains(result.String(), decl) {
+			t.Errorf("result output does not contain %q:\n%s", decl, result.String())
+		}
+	}
+	if err := GenJavaOptions(ioutil.Discard, fset, pkg, &Options{CommaOK: "optional"}); err == nil {
+		t.Error("unknown CommaOK: got nil error")
+	}
+}
+
+func TestGenUnsupportedMultiResults(t *testing.T) {
+	pkg := typeCheck(t, "testdata/badmultiresults.go")
+	want := []string{
+		"I.Pair: multiple results are not supported in interface methods",
+		"ErrFirst: an error result must be the last result",
+		"Chan: unsupported result type chan int in multiple results",
+	}
+	javaWant := append(want, "Clash: result class ClashResult conflicts with a declaration of package badmultiresults")
+	if err := GenJava(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenJava: got nil error")
+	} else {
+		for _, w := range javaWant {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenJava error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+	if err := GenGo(ioutil.Discard, fset, pkg); err == nil {
+		t.Error("GenGo: got nil error")
+	} else {
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("GenGo error does not contain %q:\n%v", w, err)
+			}
+		}
+	}
+}
+
+func TestInitFunc(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"func Init() {}", true},
+		{"func Init(key string) error { return nil }", true},
+		{"func Init() int { return 0 }", false},
+		{"func Init(keys ...string) error { return nil }", false},
+		{"func Init() (int, error) { return 0, nil }", false},
+		{"type T struct{}\n\nfunc (T) Init() {}", false},
+	}
+	for _, tt := range tests {
+		f, err := parser.ParseFile(fset, "init.go", "package p\n\n"+tt.src+"\n", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg, err := new(types.Config).Check("p", fset, []*ast.File{f}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var o *types.Func
+		if obj, ok := pkg.Scope().Lookup("T").(*types.TypeName); ok {
+			o = obj.Type().(*types.Named).Method(0)
+		} else {
+			o = pkg.Scope().Lookup("Init").(*types.Func)
+		}
+		if got := initFunc(o); got != tt.want {
+			t.Errorf("initFunc(%s) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/tools/go/exact"
+	"golang.org/x/tools/go/types"
+)
+
+// constKind returns the kind of the basic type of the constant c, the
+// default type of an untyped constant, or false if c is not of a
+// boolean, string or supported numeric type.
+func constKind(c *types.Const) (types.BasicKind, bool) {
+	T, ok := c.Type().(*types.Basic)
+	if !ok {
+		return 0, false // a named type
+	}
+	switch k := T.Kind(); k {
+	case types.Bool, types.String, types.Int, types.Int8, types.Int16,
+		types.Int32, types.Int64, types.Uint8, types.Float32, types.Float64:
+		return k, true
+	case types.UntypedBool:
+		return types.Bool, true
+	case types.UntypedInt:
+		return types.Int, true
+	case types.UntypedRune:
+		return types.Int32, true
+	case types.UntypedFloat:
+		return types.Float64, true
+	case types.UntypedString:
+		return types.String, true
+	}
+	return 0, false
+}
+
+// checkConst reports an error if the constant c cannot be bound.
+func checkConst(c *types.Const) error {
+	if _, ok := constKind(c); !ok {
+		return fmt.Errorf("unsupported constant type %s of %s: only booleans, strings and numbers of the supported types are bound", c.Type(), c.Name())
+	}
+	return nil
+}
+
+// genConst generates a Java constant holding the value of the Go
+// constant c. Untyped constants have the Java type of their default Go
+// type.
+func (g *javaGen) genConst(c *types.Const) {
+	if err := checkConst(c); err != nil {
+		g.errorf("%s: %v", g.fset.Position(c.Pos()), err)
+		return
+	}
+	kind, _ := constKind(c)
+	v, err := javaConstValue(c.Val(), kind)
+	if err != nil {
+		g.errorf("%s: constant %s %v", g.fset.Position(c.Pos()), c.Name(), err)
+		return
+	}
+	g.Printf("public static final %s %s = %s;\n\n", g.javaType(types.Typ[kind]), c.Name(), v)
+}
+
+// javaConstValue returns the Java literal of the constant value v of
+// the given kind. Floating point constants beyond the range of their
+// type, possible only for untyped constants, are written as infinities.
+func javaConstValue(v exact.Value, kind types.BasicKind) (string, error) {
+	switch kind {
+	case types.Bool:
+		return strconv.FormatBool(exact.BoolVal(v)), nil
+	case types.String:
+		return javaQuote(exact.StringVal(v)), nil
+	case types.Float32:
+		f, _ := exact.Float32Val(v)
+		return javaFloat(float64(f), 32, "Float", "f"), nil
+	case types.Float64:
+		f, _ := exact.Float64Val(v)
+		return javaFloat(f, 64, "Double", ""), nil
+	}
+	i, ok := exact.Int64Val(v)
+	if !ok {
+		return "", fmt.Errorf("overflows long")
+	}
+	switch kind {
+	case types.Int32:
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return "", fmt.Errorf("overflows int")
+		}
+	case types.Uint8:
+		if i > math.MaxInt8 {
+			// Java bytes are signed.
+			return fmt.Sprintf("(byte)%d", i), nil
+		}
+	case types.Int, types.Int64:
+		return fmt.Sprintf("%dL", i), nil
+	}
+	return strconv.FormatInt(i, 10), nil
+}
+
+// javaFloat returns the Java literal of f, of the given size in bits.
+// class is the Java class holding the infinities of the type, and suffix
+// the suffix of its literals.
+func javaFloat(f float64, bits int, class, suffix string) string {
+	switch {
+	case math.IsInf(f, 1):
+		return class + ".POSITIVE_INFINITY"
+	case math.IsInf(f, -1):
+		return class + ".NEGATIVE_INFINITY"
+	case math.IsNaN(f):
+		return class + ".NaN"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s + suffix
+}
+
+// javaQuote returns the Java string literal of s. Characters outside of
+// printable ASCII are written as \u escapes of their UTF-16 code units,
+// except for the line terminators and quotes: Java replaces \u escapes
+// before parsing, so those must take their character escapes.
+func javaQuote(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r >= 0x20 && r < 0x7f {
+				b.WriteRune(r)
+				continue
+			}
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04x`, u)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,272 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/types"
+)
+
+// The example shows at most this many package functions, and as many
+// methods of each struct.
+const (
+	exampleFuncs   = 3
+	exampleMethods = 2
+)
+
+const javaExamplePreamble = `// Java class Example shows how to call the Java API of Go package %s.
+//   gomobile bind -examples %s
+//
+// File is generated by gomobile. Copy and edit freely.
+package %s;
+
+import %s.*;
+
+`
+
+// genExample generates the Example class from the exported symbols of
+// the package: the implementation of its interfaces in Java, and calls
+// of its functions and of the methods of the structs that a function
+// constructs. Other arguments are zero values, so the calls show the API
+// but may fail at run time.
+func (g *javaGen) genExample() error {
+	className := javaClassName(g.pkg)
+	g.Printf(javaExamplePreamble, g.pkg.Name(), g.pkg.Path(), g.javaPkg, g.javaPkg+"."+className)
+	g.Printf("public final class Example {\n")
+	g.Indent()
+	g.Printf("private Example() {} // uninstantiable\n\n")
+	g.Printf("public static void run(android.content.Context ctx) throws Exception {\n")
+	g.Indent()
+	g.Printf("// Load the Go library before calling into Go.\n")
+	g.Printf("go.Go.init(ctx);\n")
+
+	scope := g.pkg.Scope()
+	var funcs []*types.Func
+	var structs, ifaces []*types.TypeName
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch o := obj.(type) {
+		case *types.Func:
+			if exampleCallable(o) {
+				funcs = append(funcs, o)
+			}
+		case *types.TypeName:
+			switch o.Type().Underlying().(type) {
+			case *types.Struct:
+				structs = append(structs, o)
+			case *types.Interface:
+				ifaces = append(ifaces, o)
+			}
+		}
+	}
+
+	// Implement the interfaces first, to pass them to the functions.
+	impls := make(map[types.Type]string)
+	for _, o := range ifaces {
+		iface := o.Type().Underlying().(*types.Interface)
+		if !exampleImplementable(iface) {
+			continue
+		}
+		v := exampleVar(o.Name())
+		impls[o.Type()] = v
+		g.Printf("\n// Implement %s in Java, to pass it to Go.\n", o.Name())
+		g.Printf("%s %s = new %s.Stub() {\n", o.Name(), v, o.Name())
+		g.Indent()
+		for i := 0; i < iface.NumMethods(); i++ {
+			m := iface.Method(i)
+			if err := g.funcSignature(m, false); err != nil {
+				return err
+			}
+			g.Printf(" {\n")
+			g.Indent()
+			res := m.Type().(*types.Signature).Results()
+			if res.Len() > 0 && !isErrorType(res.At(0).Type()) {
+				g.Printf("return %s;\n", g.javaTypeDefault(res.At(0).Type()))
+			}
+			g.Outdent()
+			g.Printf("}\n")
+		}
+		g.Outdent()
+		g.Printf("};\n")
+	}
+
+	ctors := make(map[*types.TypeName]*types.Func)
+	isCtor := make(map[*types.Func]bool)
+	for _, s := range structs {
+		if ctor := exampleConstructor(s, funcs); ctor != nil {
+			ctors[s] = ctor
+			isCtor[ctor] = true
+		}
+	}
+
+	n := 0 // the number of results, naming their variables
+	calls := 0
+	for _, f := range funcs {
+		if calls == exampleFuncs {
+			break
+		}
+		if isCtor[f] {
+			continue
+		}
+		if calls == 0 {
+			g.Printf("\n// Call the functions of package %s.\n", g.pkg.Name())
+		}
+		g.genExampleCall(className+"."+f.Name(), f, impls, &n)
+		calls++
+	}
+
+	for _, s := range structs {
+		ctor := ctors[s]
+		if ctor == nil {
+			continue
+		}
+		v := exampleVar(s.Name())
+		g.Printf("\n// Create a %s and call its methods.\n", s.Name())
+		g.Printf("%s %s = %s.%s(%s);\n", s.Name(), v, className, ctor.Name(), g.exampleArgs(ctor, impls))
+		if fields := exportedFields(s.Type().(*types.Named)); len(fields) > 0 {
+			f := fields[0]
+			g.Printf("System.out.println(\"%s.%s: \" + %s.get%s());\n", s.Name(), f.bound, v, f.Accessor())
+		}
+		calls := 0
+		for _, m := range exportedMethodSet(types.NewPointer(s.Type())) {
+			if calls == exampleMethods {
+				break
+			}
+			if exampleCallable(m) {
+				g.genExampleCall(v+"."+m.Name(), m, impls, &n)
+				calls++
+			}
+		}
+	}
+
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genExampleCall generates the call expr of f, printing its result, if
+// any, in a variable named after n. The arguments are as for exampleArgs.
+func (g *javaGen) genExampleCall(expr string, f *types.Func, impls map[types.Type]string, n *int) {
+	sig := f.Type().(*types.Signature)
+	res := sig.Results()
+	call := fmt.Sprintf("%s(%s)", expr, g.exampleArgs(f, impls))
+	var T string
+	switch {
+	case g.nullableCommaOK(sig):
+		T = g.javaBoxedType(res.At(0).Type())
+	case multiResult(sig):
+		T = g.resultClass(f)
+		if recv := sig.Recv(); recv != nil {
+			// The result class is nested in the class of the struct.
+			T = recv.Type().(*types.Pointer).Elem().(*types.Named).Obj().Name() + "." + T
+		}
+	case res.Len() > 0 && !isErrorType(res.At(0).Type()):
+		T = g.javaType(res.At(0).Type())
+	default:
+		g.Printf("%s;\n", call)
+		return
+	}
+	v := fmt.Sprintf("r%d", *n)
+	*n++
+	g.Printf("%s %s = %s;\n", T, v, call)
+	g.Printf("System.out.println(\"%s: \" + %s);\n", f.Name(), v)
+}
+
+// exampleArgs returns the arguments of a call of f: the implementations
+// in impls of the interface parameters, and zero values for the others.
+func (g *javaGen) exampleArgs(f *types.Func, impls map[types.Type]string) string {
+	sig := f.Type().(*types.Signature)
+	var args []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			break // no variadic arguments
+		}
+		T := sig.Params().At(i).Type()
+		arg := g.javaTypeDefault(T)
+		switch jt := g.javaType(T); {
+		case impls[T] != "":
+			arg = impls[T]
+		case jt == "String":
+			arg = `""`
+		case jt == "byte", jt == "short":
+			// Java does not narrow int constants passed as arguments.
+			arg = "(" + jt + ")" + arg
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, ", ")
+}
+
+// exampleCallable reports whether the example can call f with zero value
+// arguments.
+func exampleCallable(f *types.Func) bool {
+	sig := f.Type().(*types.Signature)
+	if hasCtx, err := contextParam(f); hasCtx || err != nil {
+		return false
+	}
+	if hasChanParam(sig) {
+		return false
+	}
+	if _, err := closureResult(f); err != nil {
+		return false
+	}
+	if multiResult(sig) {
+		return checkMultiResult(f) == nil
+	}
+	res := sig.Results()
+	return res.Len() < 2 || res.Len() == 2 && isErrorType(res.At(1).Type())
+}
+
+// exampleImplementable reports whether the example can implement iface
+// in Java.
+func exampleImplementable(iface *types.Interface) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		sig := m.Type().(*types.Signature)
+		if hasCtx, _ := contextParam(m); hasCtx || hasChanParam(sig) || sig.Variadic() || hasFuncResult(sig) || multiResult(sig) {
+			return false
+		}
+	}
+	return true
+}
+
+// exampleConstructor returns the first of funcs returning a pointer to
+// the struct s, or nil.
+func exampleConstructor(s *types.TypeName, funcs []*types.Func) *types.Func {
+	for _, f := range funcs {
+		res := f.Type().(*types.Signature).Results()
+		if res.Len() == 0 {
+			continue
+		}
+		if p, ok := res.At(0).Type().(*types.Pointer); ok && p.Elem() == s.Type() {
+			return f
+		}
+	}
+	return nil
+}
+
+var exampleResultRE = regexp.MustCompile(`^r[0-9]+$`)
+
+// exampleVar returns the name of the variable holding a value of the type
+// with the given name: the name with a lower case first letter, unless
+// that is taken by a keyword, the Context parameter or a result.
+func exampleVar(typeName string) string {
+	v := strings.ToLower(typeName[:1]) + typeName[1:]
+	if javaKeywords[v] || v == "ctx" || exampleResultRE.MatchString(v) {
+		v += "_"
+	}
+	return v
+}
@@ -6,18 +6,45 @@ package bind
 
 import (
 	"fmt"
+	"go/ast"
 	"go/token"
 	"log"
+	"reflect"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/tools/go/types"
 )
 
 type goGen struct {
 	*printer
-	fset *token.FileSet
-	pkg  *types.Package
-	err  ErrorList
+	fset        *token.FileSet
+	pkg         *types.Package
+	byteBuffers bool               // see Options.ByteBuffers
+	usesSink    bool               // a channel parameter is bound to a foreign Sink
+	funcs       []*types.Signature // func result types, called by handlers
+	imports     map[string]bool    // packages used by the generated code
+	errorTypes  []*types.Named     // see errorTypes
+	bound       map[string]bool    // import paths of the packages in Options.Packages
+	err         ErrorList
+}
+
+// checkPkg reports an error unless the type named by obj is defined in
+// the generated package or in a package bound together with it.
+func (g *goGen) checkPkg(obj *types.TypeName) bool {
+	if obj.Pkg() != g.pkg && !g.bound[obj.Pkg().Path()] {
+		g.errorf("type %s not defined in package %s", obj.Type(), g.pkg)
+		return false
+	}
+	return true
+}
+
+// isBound reports whether the types of pkg are bound with the generated
+// package.
+func (g *goGen) isBound(pkg *types.Package) bool {
+	return pkg == g.pkg || g.bound[pkg.Path()]
 }
 
 func (g *goGen) errorf(format string, args ...interface{}) {
@@ -31,33 +58,110 @@ const goPreamble = `// Package go_%s is an autogenerated binder stub for package
 package go_%s
 
 import (
-	"golang.org/x/mobile/bind/seq"
-	%q
-)
+	%s"golang.org/x/mobile/bind/seq"
+	%s%q
+%s)
 
 `
 
-func (g *goGen) genPreamble() {
+// genPreamble generates the package clause and imports of the Go
+// binding with the given body. A package binding only constants has a
+// body using neither seq nor the package, which are then imported for
+// their side effects alone.
+func (g *goGen) genPreamble(body string) {
 	n := g.pkg.Name()
-	g.Printf(goPreamble, n, n, g.pkg.Path(), n, g.pkg.Path())
+	seqName, pkgName := "", ""
+	if !strings.Contains(body, "seq.") {
+		seqName = "_ "
+	}
+	if !strings.Contains(body, n+".") {
+		pkgName = "_ "
+	}
+	var paths []string
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var imports string
+	for _, path := range paths {
+		imports += fmt.Sprintf("\t%q\n", path)
+	}
+	g.Printf(goPreamble, n, n, g.pkg.Path(), n, seqName, pkgName, g.pkg.Path(), imports)
 }
 
-func (g *goGen) genFuncBody(o *types.Func, selectorLHS string) {
+// genFuncBody generates the body of the handler calling the function or
+// method o, as the function value fn.
+func (g *goGen) genFuncBody(o *types.Func, fn string) {
 	sig := o.Type().(*types.Signature)
 	params := sig.Params()
+	hasCtx, err := contextParam(o)
+	if err != nil {
+		g.errorf("%v", err)
+		return
+	}
+	if err := checkVariadic(o); err != nil {
+		g.errorf("%v", err)
+		return
+	}
 	for i := 0; i < params.Len(); i++ {
 		p := params.At(i)
-		g.genRead("param_"+p.Name(), "in", p.Type())
+		if i == 0 && hasCtx {
+			// The context is cancelled by the foreign handle, and
+			// released when the call returns.
+			n := "param_" + paramName(params, i)
+			path := p.Type().(*types.Named).Obj().Pkg().Path()
+			g.imports[path] = true
+			g.Printf("%s_c := in.ReadCancellable()\n", n)
+			g.Printf("%s, %s_cancel := context.WithCancel(context.Background())\n", n, n)
+			g.Printf("defer %s_c.Register(%s_cancel)()\n", n, n)
+			g.Printf("defer %s_cancel()\n", n)
+			continue
+		}
+		if sig.Variadic() && i == params.Len()-1 {
+			g.genReadVariadic("param_"+paramName(params, i), "in", p.Type().(*types.Slice))
+			continue
+		}
+		if g.byteBuffers && isByteBufferParam(o, p.Type()) {
+			g.Printf("param_%s := in.ReadByteBuffer()\n", paramName(params, i))
+			continue
+		}
+		g.genRead("param_"+paramName(params, i), "in", p.Type())
 	}
 
 	res := sig.Results()
-	if res.Len() > 2 || res.Len() == 2 && !isErrorType(res.At(1).Type()) {
+	multi := multiResult(sig)
+	if multi {
+		if err := checkMultiResult(o); err != nil {
+			g.errorf("%v", err)
+			return
+		}
+	} else if res.Len() > 2 || res.Len() == 2 && !isErrorType(res.At(1).Type()) {
 		g.errorf("functions and methods must return either zero or one values, and optionally an error")
 		return
 	}
+	if res.Len() > 0 {
+		if _, ok := res.At(0).Type().(*types.Chan); ok {
+			g.errorf("%s: unsupported channel result type %s: channels are only supported as function and method parameters", o.Name(), res.At(0).Type())
+			return
+		}
+	}
+	if _, err := closureResult(o); err != nil {
+		g.errorf("%v", err)
+		return
+	}
 	returnsValue := false
 	returnsError := false
-	if res.Len() == 1 {
+	var resNames []string
+	if multi {
+		for i := 0; i < res.Len(); i++ {
+			if isErrorType(res.At(i).Type()) {
+				resNames = append(resNames, "err")
+			} else {
+				resNames = append(resNames, "res_"+resultName(res, i))
+			}
+		}
+		g.Printf("%s := ", strings.Join(resNames, ", "))
+	} else if res.Len() == 1 {
 		if isErrorType(res.At(0).Type()) {
 			returnsError = true
 			g.Printf("err := ")
@@ -71,15 +175,21 @@ func (g *goGen) genFuncBody(o *types.Func, selectorLHS string) {
 		g.Printf("res, err := ")
 	}
 
-	g.Printf("%s.%s(", selectorLHS, o.Name())
+	g.Printf("%s(", fn)
 	for i := 0; i < params.Len(); i++ {
 		if i > 0 {
 			g.Printf(", ")
 		}
-		g.Printf("param_%s", params.At(i).Name())
+		g.Printf("param_%s", paramName(params, i))
+	}
+	if sig.Variadic() {
+		g.Printf("...")
 	}
 	g.Printf(")\n")
 
+	for i, name := range resNames {
+		g.genWrite(name, "out", res.At(i).Type())
+	}
 	if returnsValue {
 		g.genWrite("res", "out", res.At(0).Type())
 	}
@@ -89,11 +199,29 @@ func (g *goGen) genFuncBody(o *types.Func, selectorLHS string) {
 }
 
 func (g *goGen) genWrite(valName, seqName string, T types.Type) {
+	if isTimeType(T) {
+		g.Printf("%s.WriteTime(%s)\n", seqName, valName)
+		return
+	}
+	if isStreamType(T) {
+		g.Printf("%s.Write%s(%s)\n", seqName, seqType(T), valName)
+		return
+	}
 	if isErrorType(T) {
 		g.Printf("if %s == nil {\n", valName)
 		g.Printf("    %s.WriteString(\"\");\n", seqName)
 		g.Printf("} else {\n")
-		g.Printf("    %s.WriteString(%s.Error());\n", seqName, valName)
+		// An empty message is read as no error.
+		g.Printf("    msg := %s.Error()\n", valName)
+		g.Printf("    %s.WriteString(msg)\n", seqName)
+		g.Printf("    if msg != \"\" {\n")
+		g.Indent()
+		if len(g.errorTypes) > 0 {
+			g.genWriteErrorType(valName, seqName)
+		}
+		g.Printf("    %s.WriteErrorCauses(%s)\n", seqName, valName)
+		g.Outdent()
+		g.Printf("    }\n")
 		g.Printf("}\n")
 		return
 	}
@@ -103,9 +231,7 @@ func (g *goGen) genWrite(valName, seqName string, T types.Type) {
 		// TODO(crawshaw): test **Generator
 		switch T := T.Elem().(type) {
 		case *types.Named:
-			obj := T.Obj()
-			if obj.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", T, g.pkg)
+			if !g.checkPkg(T.Obj()) {
 				return
 			}
 			g.Printf("%s.WriteGoRef(%s)\n", seqName, valName)
@@ -116,18 +242,156 @@ func (g *goGen) genWrite(valName, seqName string, T types.Type) {
 		switch u := T.Underlying().(type) {
 		case *types.Interface, *types.Pointer:
 			g.Printf("%s.WriteGoRef(%s)\n", seqName, valName)
+		case *types.Basic:
+			if !isEnumType(T) {
+				g.errorf("unsupported, direct named type %s: %s", T, u)
+				return
+			}
+			g.Printf("%s.Write%s(%s(%s))\n", seqName, seqType(T), u, valName)
 		default:
 			g.errorf("unsupported, direct named type %s: %s", T, u)
 		}
+	case *types.Signature:
+		// Func values cannot be map keys, so the reference table holds
+		// a pointer to the closure instead.
+		g.funcClass(T)
+		g.Printf("%s.WriteGoRef(&%s)\n", seqName, valName)
+	case *types.Slice:
+		if !isList(T) {
+			g.Printf("%s.Write%s(%s);\n", seqName, seqType(T), valName)
+			return
+		}
+		// A nil slice is written as length -1.
+		if err := checkList(T); err != nil {
+			g.errorf("%v", err)
+			return
+		}
+		g.Printf("if %s == nil {\n", valName)
+		g.Printf("    %s.WriteInt32(-1)\n", seqName)
+		g.Printf("} else {\n")
+		g.Indent()
+		g.Printf("%s.WriteInt32(int32(len(%s)))\n", seqName, valName)
+		g.Printf("for i := range %s {\n", valName)
+		g.Indent()
+		if isStructValue(T.Elem()) {
+			// Each element is copied, to be passed by reference.
+			g.Printf("%s_e := %s[i]\n", valName, valName)
+			g.genWrite("&"+valName+"_e", seqName, listElem(T))
+		} else {
+			g.genWrite(valName+"[i]", seqName, T.Elem())
+		}
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Array:
+		// An array is written as its length followed by its elements.
+		if err := checkArray(T); err != nil {
+			g.errorf("%v", err)
+			return
+		}
+		g.Printf("%s.WriteInt32(%d)\n", seqName, T.Len())
+		g.Printf("for _, %s_e := range %s {\n", valName, valName)
+		g.Indent()
+		g.genWrite(valName+"_e", seqName, T.Elem())
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Map:
+		if err := checkMap(T); err != nil {
+			g.errorf("%v", err)
+			return
+		}
+		g.Printf("if %s == nil {\n", valName)
+		g.Printf("    %s.WriteInt32(-1)\n", seqName)
+		g.Printf("} else {\n")
+		g.Indent()
+		g.Printf("%s.WriteInt32(int32(len(%s)))\n", seqName, valName)
+		g.Printf("for %s_k, %s_v := range %s {\n", valName, valName, valName)
+		g.Indent()
+		g.genWrite(valName+"_k", seqName, T.Key())
+		g.genWrite(valName+"_v", seqName, T.Elem())
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
 	default:
 		g.Printf("%s.Write%s(%s);\n", seqName, seqType(T), valName)
 	}
 }
 
+// genWriteErrorType writes the type of the error valName following
+// its non-empty message: 0 for errors of other types, or i for the
+// i-th of g.errorTypes, followed by the error object.
+func (g *goGen) genWriteErrorType(valName, seqName string) {
+	errorIface := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	g.Indent()
+	g.Printf("switch e := %s.(type) {\n", valName)
+	for i, T := range g.errorTypes {
+		g.Printf("case *%s:\n", g.typeString(T))
+		g.Printf("    %s.WriteInt32(%d)\n", seqName, i+1)
+		g.Printf("    %s.WriteGoRef(e)\n", seqName)
+		if types.Implements(T, errorIface) {
+			g.Printf("case %s:\n", g.typeString(T))
+			g.Printf("    %s.WriteInt32(%d)\n", seqName, i+1)
+			g.Printf("    %s.WriteGoRef(&e)\n", seqName)
+		}
+	}
+	g.Printf("default:\n")
+	g.Printf("    %s.WriteInt32(0)\n", seqName)
+	g.Printf("}\n")
+	g.Outdent()
+}
+
 func (g *goGen) genFunc(o *types.Func) {
+	fn := g.pkg.Name() + "." + o.Name()
+	if initFunc(o) {
+		g.genInitOnce(o)
+		fn = "proxyInit"
+	}
 	g.Printf("func proxy_%s(out, in *seq.Buffer) {\n", o.Name())
 	g.Indent()
-	g.genFuncBody(o, g.pkg.Name())
+	g.genFuncBody(o, fn)
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genInitOnce generates proxyInit, calling the init function o of the
+// package the first time only and returning the error of that call, if
+// any, every time. Concurrent calls wait for the first to return.
+func (g *goGen) genInitOnce(o *types.Func) {
+	sig := o.Type().(*types.Signature)
+	params := sig.Params()
+	returnsError := sig.Results().Len() == 1
+	g.imports["sync"] = true
+	g.Printf("// proxyInitOnce runs %s.%s once for all the foreign calls.\n", g.pkg.Name(), o.Name())
+	g.Printf("var proxyInitOnce sync.Once\n\n")
+	if returnsError {
+		g.Printf("// proxyInitErr is the error of the call run by proxyInitOnce.\n")
+		g.Printf("var proxyInitErr error\n\n")
+	}
+	var args, decls []string
+	for i := 0; i < params.Len(); i++ {
+		arg := fmt.Sprintf("p%d", i)
+		args = append(args, arg)
+		decls = append(decls, arg+" "+g.typeString(params.At(i).Type()))
+	}
+	g.Printf("func proxyInit(%s)", strings.Join(decls, ", "))
+	if returnsError {
+		g.Printf(" error")
+	}
+	g.Printf(" {\n")
+	g.Indent()
+	g.Printf("proxyInitOnce.Do(func() {\n")
+	g.Indent()
+	if returnsError {
+		g.Printf("proxyInitErr = ")
+	}
+	g.Printf("%s.%s(%s)\n", g.pkg.Name(), o.Name(), strings.Join(args, ", "))
+	g.Outdent()
+	g.Printf("})\n")
+	if returnsError {
+		g.Printf("return proxyInitErr\n")
+	}
 	g.Outdent()
 	g.Printf("}\n\n")
 }
@@ -150,21 +414,180 @@ func exportedMethodSet(T types.Type) []*types.Func {
 	return methods
 }
 
-func exportedFields(T *types.Struct) []*types.Var {
-	var fields []*types.Var
-	for i := 0; i < T.NumFields(); i++ {
-		f := T.Field(i)
-		if !f.Exported() {
+// A structField is an exported field of a bound struct.
+type structField struct {
+	*types.Var
+	bound string // the name in the bindings
+	err   error  // a bad gomobile tag
+}
+
+// Accessor returns the name of the getter and setter of the field
+// without their get and set prefix: the bound name, capitalized.
+func (f structField) Accessor() string {
+	r, size := utf8.DecodeRuneInString(f.bound)
+	return string(unicode.ToUpper(r)) + f.bound[size:]
+}
+
+// exportedFields returns the exported fields of the struct type T,
+// followed by the exported fields promoted from its embedded structs.
+// As in Go, a promoted field is shadowed by a field or method of the
+// same name at a shallower depth, and is omitted if the name is
+// ambiguous. Embedded structs are not returned as fields themselves.
+//
+// A field tagged gomobile:"-" is omitted, and one tagged
+// gomobile:"name=n" is bound with the name n.
+func exportedFields(T *types.Named) []structField {
+	var fields []structField
+	for _, name := range selectorNames(T) {
+		obj, index, _ := types.LookupFieldOrMethod(types.NewPointer(T), false, T.Obj().Pkg(), name)
+		f, ok := obj.(*types.Var)
+		if !ok || !f.Exported() || f.Anonymous() && embeddedStruct(f.Type()) != nil {
+			continue
+		}
+		bound, omit, err := parseFieldTag(fieldTag(T, index))
+		if omit {
 			continue
 		}
-		fields = append(fields, f)
+		if bound == "" {
+			bound = f.Name()
+		}
+		fields = append(fields, structField{Var: f, bound: bound, err: err})
 	}
 	return fields
 }
 
+// fieldTag returns the tag of the field of T at the index path returned
+// by types.LookupFieldOrMethod.
+func fieldTag(T *types.Named, index []int) string {
+	st := T.Underlying().(*types.Struct)
+	for _, i := range index[:len(index)-1] {
+		st = embeddedStruct(st.Field(i).Type()).Underlying().(*types.Struct)
+	}
+	return st.Tag(index[len(index)-1])
+}
+
+// parseFieldTag parses the gomobile key of a struct field tag. It
+// returns the name the field is bound as, or "" to keep the Go name, and
+// whether the field is omitted.
+func parseFieldTag(tag string) (name string, omit bool, err error) {
+	v := reflect.StructTag(tag).Get("gomobile")
+	if v == "-" {
+		return "", true, nil
+	}
+	if v == "" {
+		return "", false, nil
+	}
+	for _, opt := range strings.Split(v, ",") {
+		if !strings.HasPrefix(opt, "name=") {
+			return "", false, fmt.Errorf("unknown gomobile tag option %q", opt)
+		}
+		name = opt[len("name="):]
+		// A Java package name without dots is an identifier.
+		if strings.Contains(name, ".") || !validJavaPkg(name) {
+			return "", false, fmt.Errorf("gomobile tag name %q is not a valid identifier", name)
+		}
+	}
+	return name, false, nil
+}
+
+// checkFields reports the first bad tag of the fields of the struct
+// named n, and the fields whose getter and setter would collide.
+func checkFields(n string, fields []structField) error {
+	seen := make(map[string]structField)
+	for _, f := range fields {
+		if f.err != nil {
+			return fmt.Errorf("%s.%s: %v", n, f.Name(), f.err)
+		}
+		if g, dup := seen[f.Accessor()]; dup {
+			return fmt.Errorf("%s.%s and %s.%s are both bound as %s", n, g.Name(), n, f.Name(), f.bound)
+		}
+		seen[f.Accessor()] = f
+	}
+	return nil
+}
+
+// ambiguousSelectors returns the exported names of the fields and methods
+// of the structs embedded in T that cannot be promoted to T because they
+// appear more than once at the same depth.
+func ambiguousSelectors(T *types.Named) []string {
+	var names []string
+	for _, name := range selectorNames(T) {
+		obj, index, _ := types.LookupFieldOrMethod(types.NewPointer(T), false, T.Obj().Pkg(), name)
+		if obj == nil && index != nil && ast.IsExported(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// selectorNames returns the names of the fields of struct type T and of
+// the fields and methods of the structs embedded in it, at any depth,
+// each name once, in breadth-first order.
+func selectorNames(T *types.Named) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	visited := map[*types.Named]bool{T: true}
+	for level := []*types.Named{T}; len(level) > 0; {
+		var next []*types.Named
+		for _, N := range level {
+			st := N.Underlying().(*types.Struct)
+			for i := 0; i < st.NumFields(); i++ {
+				f := st.Field(i)
+				add(f.Name())
+				if E := embeddedStruct(f.Type()); f.Anonymous() && E != nil && !visited[E] {
+					visited[E] = true
+					next = append(next, E)
+				}
+			}
+			if N != T {
+				ms := types.NewMethodSet(types.NewPointer(N))
+				for i := 0; i < ms.Len(); i++ {
+					add(ms.At(i).Obj().Name())
+				}
+			}
+		}
+		level = next
+	}
+	return names
+}
+
+// embeddedStruct returns the named struct type of an embedded field of
+// type T or *T, or nil if T is not a named struct.
+func embeddedStruct(T types.Type) *types.Named {
+	if p, ok := T.(*types.Pointer); ok {
+		T = p.Elem()
+	}
+	if N, ok := T.(*types.Named); ok {
+		if _, ok := N.Underlying().(*types.Struct); ok {
+			return N
+		}
+	}
+	return nil
+}
+
+// genStruct generates the handlers of the fields and methods of the
+// struct type obj. Structs are bound by reference: the foreign object
+// refers to a *T, so its field setters and the methods with pointer
+// receivers change the struct shared with Go.
 func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
-	fields := exportedFields(T)
+	fields := exportedFields(obj.Type().(*types.Named))
 	methods := exportedMethodSet(types.NewPointer(obj.Type()))
+	if err := checkFields(obj.Name(), fields); err != nil {
+		g.errorf("%v", err)
+		return
+	}
+	for _, f := range fields {
+		if _, ok := f.Type().(*types.Chan); ok {
+			g.errorf("%s.%s: unsupported channel field type %s", obj.Name(), f.Name(), f.Type())
+			return
+		}
+	}
 
 	g.Printf("const (\n")
 	g.Indent()
@@ -185,7 +608,11 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 		g.Printf("func proxy%s%sSet(out, in *seq.Buffer) {\n", obj.Name(), f.Name())
 		g.Indent()
 		g.Printf("ref := in.ReadRef()\n")
-		g.Printf("v := in.Read%s()\n", seqType(f.Type()))
+		if _, ok := f.Type().(*types.Map); ok || isEnumType(f.Type()) || isList(f.Type()) {
+			g.genRead("v", "in", f.Type())
+		} else {
+			g.Printf("v := in.Read%s()\n", seqType(f.Type()))
+		}
 		// TODO(crawshaw): other kinds of non-ptr types.
 		g.Printf("ref.Get().(*%s.%s).%s = v\n", g.pkg.Name(), obj.Name(), f.Name())
 		g.Outdent()
@@ -195,7 +622,11 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 		g.Indent()
 		g.Printf("ref := in.ReadRef()\n")
 		g.Printf("v := ref.Get().(*%s.%s).%s\n", g.pkg.Name(), obj.Name(), f.Name())
-		g.Printf("out.Write%s(v)\n", seqType(f.Type()))
+		if _, ok := f.Type().(*types.Map); ok || isEnumType(f.Type()) || isList(f.Type()) {
+			g.genWrite("v", "out", f.Type())
+		} else {
+			g.Printf("out.Write%s(v)\n", seqType(f.Type()))
+		}
 		g.Outdent()
 		g.Printf("}\n\n")
 	}
@@ -205,7 +636,7 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 		g.Indent()
 		g.Printf("ref := in.ReadRef()\n")
 		g.Printf("v := ref.Get().(*%s.%s)\n", g.pkg.Name(), obj.Name())
-		g.genFuncBody(m, "v")
+		g.genFuncBody(m, "v."+m.Name())
 		g.Outdent()
 		g.Printf("}\n\n")
 	}
@@ -227,6 +658,29 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 
 func (g *goGen) genInterface(obj *types.TypeName) {
 	iface := obj.Type().(*types.Named).Underlying().(*types.Interface)
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if hasChanParam(m.Type().(*types.Signature)) {
+			g.errorf("%s.%s: channel parameters are not supported in interface methods", obj.Name(), m.Name())
+			return
+		}
+		if hasCtx, _ := contextParam(m); hasCtx {
+			g.errorf("%s.%s: context.Context parameters are not supported in interface methods", obj.Name(), m.Name())
+			return
+		}
+		if m.Type().(*types.Signature).Variadic() {
+			g.errorf("%s.%s: variadic parameters are not supported in interface methods", obj.Name(), m.Name())
+			return
+		}
+		if hasFuncResult(m.Type().(*types.Signature)) {
+			g.errorf("%s.%s: func results are not supported in interface methods", obj.Name(), m.Name())
+			return
+		}
+		if multiResult(m.Type().(*types.Signature)) {
+			g.errorf("%s.%s: multiple results are not supported in interface methods", obj.Name(), m.Name())
+			return
+		}
+	}
 
 	// Descriptor and code for interface methods.
 	g.Printf("const (\n")
@@ -245,7 +699,7 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 		g.Indent()
 		g.Printf("ref := in.ReadRef()\n")
 		g.Printf("v := ref.Get().(%s.%s)\n", g.pkg.Name(), obj.Name())
-		g.genFuncBody(m, "v")
+		g.genFuncBody(m, "v."+m.Name())
 		g.Outdent()
 		g.Printf("}\n\n")
 	}
@@ -257,6 +711,11 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 		g.Printf("seq.Register(proxy%sDescriptor, proxy%s%sCode, proxy%s%s)\n",
 			obj.Name(), obj.Name(), iface.Method(i).Name(), obj.Name(), iface.Method(i).Name())
 	}
+	if len(g.bound) > 0 {
+		// The packages bound together read foreign objects
+		// implementing the interface with the proxy.
+		g.Printf("seq.RegisterProxy(proxy%sDescriptor, func(ref *seq.Ref) interface{} { return (*proxy%s)(ref) })\n", obj.Name(), obj.Name())
+	}
 	g.Outdent()
 	g.Printf("}\n\n")
 
@@ -315,23 +774,59 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 	}
 }
 
+// genEnum generates the function reading a value of the enum type T,
+// which checks that the value is one of the constants of T.
+func (g *goGen) genEnum(T *types.Named) {
+	n := T.Obj().Name()
+	typ := g.typeString(T)
+	g.imports["fmt"] = true
+	g.Printf("func proxy%sRead(in *seq.Buffer) %s {\n", n, typ)
+	g.Indent()
+	g.Printf("v := %s(in.Read%s())\n", typ, seqType(T))
+	g.Printf("switch v {\n")
+	g.Printf("case ")
+	seen := make(map[int64]bool)
+	for _, c := range enumConsts(T) {
+		// Constants with the same value would be duplicate cases.
+		if v := constInt(c); !seen[v] {
+			if len(seen) > 0 {
+				g.Printf(", ")
+			}
+			seen[v] = true
+			g.Printf("%s.%s", g.pkg.Name(), c.Name())
+		}
+	}
+	g.Printf(":\n")
+	g.Printf("    return v\n")
+	g.Printf("}\n")
+	g.Printf("panic(fmt.Sprintf(\"bind: invalid %s value %%d\", v))\n", typ)
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
 func (g *goGen) genRead(valName, seqName string, typ types.Type) {
 	if isErrorType(typ) {
 		g.Printf("%s := %s.ReadError()\n", valName, seqName)
 		return
 	}
+	if isTimeType(typ) {
+		g.Printf("%s := %s.ReadTime()\n", valName, seqName)
+		return
+	}
+	if isStreamType(typ) {
+		g.Printf("%s := %s.Read%s()\n", valName, seqName, seqType(typ))
+		return
+	}
 	switch t := typ.(type) {
 	case *types.Pointer:
 		switch u := t.Elem().(type) {
 		case *types.Named:
-			o := u.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", u, g.pkg)
+			if !g.checkPkg(u.Obj()) {
 				return
 			}
 			g.Printf("// Must be a Go object\n")
 			g.Printf("%s_ref := %s.ReadRef()\n", valName, seqName)
-			g.Printf("%s := %s_ref.Get().(*%s.%s)\n", valName, valName, g.pkg.Name(), o.Name())
+			g.Printf("%s := %s_ref.Get().(%s)\n", valName, valName, g.typeString(t))
 		default:
 			g.errorf("unsupported type %s", t)
 		}
@@ -339,23 +834,136 @@ func (g *goGen) genRead(valName, seqName string, typ types.Type) {
 		switch t.Underlying().(type) {
 		case *types.Interface, *types.Pointer:
 			o := t.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", t, g.pkg)
+			if !g.checkPkg(o) {
 				return
 			}
 			g.Printf("var %s %s\n", valName, g.typeString(t))
 			g.Printf("%s_ref := %s.ReadRef()\n", valName, seqName)
 			g.Printf("if %s_ref.Num < 0 { // go object \n", valName)
-			g.Printf("   %s = %s_ref.Get().(%s.%s)\n", valName, valName, g.pkg.Name(), o.Name())
+			g.Printf("   %s = %s_ref.Get().(%s)\n", valName, valName, g.typeString(t))
 			g.Printf("} else {  // foreign object \n")
-			g.Printf("   %s = (*proxy%s)(%s_ref)\n", valName, o.Name(), valName)
+			if o.Pkg() == g.pkg {
+				g.Printf("   %s = (*proxy%s)(%s_ref)\n", valName, o.Name(), valName)
+			} else {
+				// The proxy is defined by the binding of the other package.
+				g.Printf("   %s = seq.Proxies[\"go.%s.%s\"](%s_ref).(%s)\n", valName, o.Pkg().Name(), o.Name(), valName, g.typeString(t))
+			}
 			g.Printf("}\n")
+		case *types.Basic:
+			if !isEnumType(t) {
+				g.errorf("unsupported, direct named type %s", t)
+				return
+			}
+			if t.Obj().Pkg() != g.pkg {
+				g.errorf("enum type %s of another package is not supported", t)
+				return
+			}
+			g.Printf("%s := proxy%sRead(%s)\n", valName, t.Obj().Name(), seqName)
+		}
+	case *types.Slice:
+		if !isList(t) {
+			g.Printf("%s := %s.Read%s()\n", valName, seqName, seqType(t))
+			return
+		}
+		// A nil slice is written as length -1.
+		if err := checkList(t); err != nil {
+			g.errorf("%v", err)
+			return
+		}
+		g.Printf("var %s %s\n", valName, g.typeString(t))
+		g.Printf("if %s_n := %s.ReadInt32(); %s_n >= 0 {\n", valName, seqName, valName)
+		g.Indent()
+		g.Printf("%s = make(%s, %s_n)\n", valName, g.typeString(t), valName)
+		g.Printf("for i := range %s {\n", valName)
+		g.Indent()
+		g.genRead(valName+"_e", seqName, listElem(t))
+		if isStructValue(t.Elem()) {
+			g.Printf("%s[i] = *%s_e\n", valName, valName)
+		} else {
+			g.Printf("%s[i] = %s_e\n", valName, valName)
+		}
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Array:
+		// The foreign language checks the length of the array it
+		// writes, and so does ReadArrayLen.
+		if err := checkArray(t); err != nil {
+			g.errorf("%v", err)
+			return
+		}
+		g.Printf("var %s %s\n", valName, g.typeString(t))
+		g.Printf("%s.ReadArrayLen(%d)\n", seqName, t.Len())
+		g.Printf("for i := range %s {\n", valName)
+		g.Indent()
+		g.genRead(valName+"_e", seqName, t.Elem())
+		g.Printf("%s[i] = %s_e\n", valName, valName)
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Map:
+		// A nil map is written as length -1.
+		if err := checkMap(t); err != nil {
+			g.errorf("%v", err)
+			return
 		}
+		typ := g.typeString(t)
+		g.Printf("var %s %s\n", valName, typ)
+		g.Printf("if %s_n := %s.ReadInt32(); %s_n >= 0 {\n", valName, seqName, valName)
+		g.Indent()
+		g.Printf("%s = make(%s, %s_n)\n", valName, typ, valName)
+		g.Printf("for i := int32(0); i < %s_n; i++ {\n", valName)
+		g.Indent()
+		g.genRead(valName+"_k", seqName, t.Key())
+		g.genRead(valName+"_v", seqName, t.Elem())
+		g.Printf("%s[%s_k] = %s_v\n", valName, valName, valName)
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Chan:
+		// The foreign language passes a Sink. Forward every value
+		// sent on the channel to it, and close it with the channel.
+		if err := checkChan(t, g.pkg); err != nil {
+			g.errorf("%v", err)
+			return
+		}
+		g.usesSink = true
+		g.Printf("%s_ref := %s.ReadRef()\n", valName, seqName)
+		g.Printf("%s := make(chan %s)\n", valName, g.typeString(t.Elem()))
+		g.Printf("go func() {\n")
+		g.Indent()
+		g.Printf("for elem := range %s {\n", valName)
+		g.Indent()
+		g.Printf("in := new(seq.Buffer)\n")
+		g.genWrite("elem", "in", t.Elem())
+		g.Printf("seq.Transact(%s_ref, proxySinkSendCode, in)\n", valName)
+		g.Outdent()
+		g.Printf("}\n")
+		g.Printf("seq.Transact(%s_ref, proxySinkCloseCode, new(seq.Buffer))\n", valName)
+		g.Outdent()
+		g.Printf("}()\n")
 	default:
 		g.Printf("%s := %s.Read%s()\n", valName, seqName, seqType(t))
 	}
 }
 
+// genReadVariadic reads the elements of a variadic parameter of type T,
+// preceded by their number.
+func (g *goGen) genReadVariadic(valName, seqName string, T *types.Slice) {
+	g.Printf("%s := make([]%s, %s.ReadInt32())\n", valName, g.typeString(T.Elem()), seqName)
+	g.Printf("for i := range %s {\n", valName)
+	g.Indent()
+	if isEmptyInterface(T.Elem()) {
+		g.Printf("%s[i] = %s.ReadInterface()\n", valName, seqName)
+	} else {
+		g.genRead(valName+"_e", seqName, T.Elem())
+		g.Printf("%s[i] = %s_e\n", valName, valName)
+	}
+	g.Outdent()
+	g.Printf("}\n")
+}
+
 func (g *goGen) typeString(typ types.Type) string {
 	pkg := g.pkg
 
@@ -365,13 +973,29 @@ func (g *goGen) typeString(typ types.Type) string {
 		if obj.Pkg() == nil { // e.g. error type is *types.Named.
 			return types.TypeString(pkg, typ)
 		}
+		if isTimeType(t) {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		if isStreamType(t) {
+			g.imports["io"] = true
+			return "io." + obj.Name()
+		}
+		if !g.checkPkg(obj) {
+			return ""
+		}
 		if obj.Pkg() != g.pkg {
-			g.errorf("type %s not defined in package %s", t, g.pkg)
+			g.imports[obj.Pkg().Path()] = true
 		}
 
 		switch t.Underlying().(type) {
 		case *types.Interface, *types.Struct:
-			return fmt.Sprintf("%s.%s", pkg.Name(), types.TypeString(pkg, typ))
+			return fmt.Sprintf("%s.%s", obj.Pkg().Name(), obj.Name())
+		case *types.Basic:
+			if isEnumType(t) {
+				return fmt.Sprintf("%s.%s", obj.Pkg().Name(), obj.Name())
+			}
+			g.errorf("unsupported named type %s", t)
 		default:
 			g.errorf("unsupported named type %s / %T", t, t)
 		}
@@ -382,44 +1006,168 @@ func (g *goGen) typeString(typ types.Type) string {
 		default:
 			g.errorf("not yet supported, pointer type %s / %T", t, t)
 		}
+	case *types.Map:
+		return fmt.Sprintf("map[%s]%s", g.typeString(t.Key()), g.typeString(t.Elem()))
+	case *types.Slice:
+		return "[]" + g.typeString(t.Elem())
+	case *types.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.typeString(t.Elem()))
+	case *types.Signature:
+		s := "func(" + g.tupleString(t.Params()) + ")"
+		switch t.Results().Len() {
+		case 0:
+		case 1:
+			s += " " + g.tupleString(t.Results())
+		default:
+			s += " (" + g.tupleString(t.Results()) + ")"
+		}
+		return s
 	default:
 		return types.TypeString(pkg, typ)
 	}
 	return ""
 }
 
-func (g *goGen) gen() error {
-	g.genPreamble()
+// tupleString returns the comma-separated types of the tuple t.
+func (g *goGen) tupleString(t *types.Tuple) string {
+	var ts []string
+	for i := 0; i < t.Len(); i++ {
+		ts = append(ts, g.typeString(t.At(i).Type()))
+	}
+	return strings.Join(ts, ", ")
+}
+
+// funcClass registers the generation of the handler calling closures of
+// type T.
+func (g *goGen) funcClass(T *types.Signature) {
+	name := funcName(T)
+	for _, f := range g.funcs {
+		if funcName(f) == name {
+			return
+		}
+	}
+	g.funcs = append(g.funcs, T)
+}
+
+// genFuncs generates the handlers calling the closures returned by Go.
+// A closure is called by its reference, followed by its arguments.
+func (g *goGen) genFuncs() {
+	for _, T := range g.funcs {
+		n := funcName(T)
+		g.Printf("const proxy%sDescriptor = \"go.%s.%s\"\n\n", n, g.pkg.Name(), n)
+		g.Printf("func proxy%s(out, in *seq.Buffer) {\n", n)
+		g.Indent()
+		g.Printf("ref := in.ReadRef()\n")
+		g.Printf("v := *ref.Get().(*%s)\n", g.typeString(T))
+		g.genFuncBody(types.NewFunc(token.NoPos, g.pkg, "call", T), "v")
+		g.Outdent()
+		g.Printf("}\n\n")
+		g.Printf("func init() {\n")
+		g.Printf("    seq.Register(proxy%sDescriptor, 0x%x, proxy%s)\n", n, closureCallCode, n)
+		g.Printf("}\n\n")
+	}
+}
 
+// genObject generates the binding of the exported package-level object
+// obj, and reports whether it is a function, to register with a call
+// code.
+func (g *goGen) genObject(obj types.Object) bool {
+	switch obj := obj.(type) {
+	case *types.Func:
+		g.genFunc(obj)
+		return true
+	case *types.TypeName:
+		named := obj.Type().(*types.Named)
+		switch T := named.Underlying().(type) {
+		case *types.Struct:
+			g.genStruct(obj, T)
+		case *types.Interface:
+			g.genInterface(obj)
+		case *types.Basic:
+			if isEnumType(named) {
+				g.genEnum(named)
+			}
+		}
+	case *types.Const:
+		// The values of constants are generated in Java.
+		if isEnumType(obj.Type()) {
+			break
+		}
+		if err := checkConst(obj); err != nil {
+			g.errorf("%s: %v", g.fset.Position(obj.Pos()), err)
+		}
+	default:
+		g.errorf("not yet supported, name for %v / %T", obj, obj)
+	}
+	return false
+}
+
+// genVar generates the handlers of the calls getting and setting the
+// exported package-level variable v, and reports whether v is bound.
+// Variables of types that cannot be bound are skipped. The accesses are
+// serialized by proxyVarMu, so they do not race with each other.
+func (g *goGen) genVar(v *types.Var) bool {
+	if err := checkVar(v, g.isBound); err != nil {
+		return false
+	}
+	g.Printf("func proxy_get_%s(out, in *seq.Buffer) {\n", v.Name())
+	g.Indent()
+	g.Printf("proxyVarMu.Lock()\n")
+	g.Printf("v := %s.%s\n", g.pkg.Name(), v.Name())
+	g.Printf("proxyVarMu.Unlock()\n")
+	g.genWrite("v", "out", v.Type())
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.Printf("func proxy_set_%s(out, in *seq.Buffer) {\n", v.Name())
+	g.Indent()
+	g.genRead("v", "in", v.Type())
+	g.Printf("proxyVarMu.Lock()\n")
+	g.Printf("%s.%s = v\n", g.pkg.Name(), v.Name())
+	g.Printf("proxyVarMu.Unlock()\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+	return true
+}
+
+func (g *goGen) gen() error {
 	var funcs []string
+	g.errorTypes = errorTypes(g.pkg)
 
 	scope := g.pkg.Scope()
 	names := scope.Names()
+	vars := false
 	for _, name := range names {
 		obj := scope.Lookup(name)
 		if !obj.Exported() {
 			continue
 		}
 
-		switch obj := obj.(type) {
-		// TODO(crawshaw): case *types.Const:
-		// TODO(crawshaw): case *types.Var:
-		case *types.Func:
-			g.genFunc(obj)
-			funcs = append(funcs, obj.Name())
-		case *types.TypeName:
-			named := obj.Type().(*types.Named)
-			switch T := named.Underlying().(type) {
-			case *types.Struct:
-				g.genStruct(obj, T)
-			case *types.Interface:
-				g.genInterface(obj)
+		if v, ok := obj.(*types.Var); ok {
+			if g.genVar(v) {
+				vars = true
+				funcs = append(funcs, "get_"+name, "set_"+name)
 			}
-
-		default:
-			g.errorf("not yet supported, name for %v / %T", obj, obj)
 			continue
 		}
+		if g.genObject(obj) {
+			funcs = append(funcs, obj.Name())
+		}
+	}
+
+	g.genFuncs()
+
+	if vars {
+		g.imports["sync"] = true
+		g.Printf("// proxyVarMu serializes the accesses to the variables of package %s.\n", g.pkg.Name())
+		g.Printf("var proxyVarMu sync.Mutex\n\n")
+	}
+
+	if g.usesSink {
+		g.Printf("const (\n")
+		g.Printf("proxySinkSendCode = 0x%x\n", sinkSendCode)
+		g.Printf("proxySinkCloseCode = 0x%x\n", sinkCloseCode)
+		g.Printf(")\n\n")
 	}
 
 	g.Printf("func init() {\n")
@@ -430,6 +1178,12 @@ func (g *goGen) gen() error {
 	g.Outdent()
 	g.Printf("}\n")
 
+	// The imports depend on the types used by the body.
+	body := g.buf.String()
+	g.buf.Reset()
+	g.genPreamble(body)
+	g.buf.WriteString(body)
+
 	if len(g.err) > 0 {
 		return g.err
 	}
@@ -10,6 +10,7 @@ import (
 	"go/token"
 	"io"
 	"regexp"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
@@ -35,22 +36,96 @@ func (list ErrorList) Error() string {
 
 type javaGen struct {
 	*printer
-	nextCode int
-	fset     *token.FileSet
-	pkg      *types.Package
-	err      ErrorList
+	nextCode    int
+	fset        *token.FileSet
+	pkg         *types.Package
+	annotations string             // key of javaNullable, or empty
+	nullable    bool               // the @Nullable annotation was used
+	sinks       []types.Type       // element types of channel parameters
+	maps        []*types.Map       // map types, copied by helper classes
+	lists       []*types.Slice     // slice types, copied by helper classes
+	arrays      []*types.Array     // array types, copied by helper classes
+	funcs       []*types.Signature // func result types, see genFuncs
+	byteBuffers bool               // see Options.ByteBuffers
+	commaOK     bool               // comma-ok results are nullable, see Options.CommaOK
+	javaPkg     string             // Java package of the generated class
+	supportPkg  string             // Java package of Seq and Go, see Printf
+	errorTypes  []*types.Named     // see errorTypes
+
+	// bound maps the import paths of the packages bound together with
+	// pkg to the names of their Java classes. See Options.Packages.
+	bound map[string]string
+	err   ErrorList
+}
+
+// Printf prints to the generated class. The formats name the support
+// classes in their default Java package, as go.Seq and go.Go; they are
+// printed in the support package of the generator.
+func (g *javaGen) Printf(format string, args ...interface{}) {
+	if g.supportPkg != "go" {
+		format = strings.NewReplacer("go.Seq", g.supportPkg+".Seq", "go.Go.", g.supportPkg+".Go.").Replace(format)
+	}
+	g.printer.Printf(format, args...)
+}
+
+// proxyAccess returns the access modifier of the constructors of the
+// Java proxies of Go objects and of Stub.refOf. They are public when
+// other packages are bound together, which use them to pass the
+// objects of this package.
+func (g *javaGen) proxyAccess(unbound string) string {
+	if len(g.bound) > 0 {
+		return "public "
+	}
+	return unbound
+}
+
+// javaClassName returns the name of the Java class of pkg.
+func javaClassName(pkg *types.Package) string {
+	firstRune, size := utf8.DecodeRuneInString(pkg.Name())
+	return string(unicode.ToUpper(firstRune)) + pkg.Name()[size:]
+}
+
+// javaNullable maps an annotation package name to its Nullable annotation.
+var javaNullable = map[string]string{
+	"androidx": "androidx.annotation.Nullable",
+	"javax":    "javax.annotation.Nullable",
 }
 
 func (g *javaGen) genStruct(obj *types.TypeName, T *types.Struct) {
-	fields := exportedFields(T)
+	fields := exportedFields(obj.Type().(*types.Named))
+	for _, name := range ambiguousSelectors(obj.Type().(*types.Named)) {
+		Warnf("%s: ambiguous promoted field or method %s omitted", obj.Name(), name)
+	}
 	methods := exportedMethodSet(types.NewPointer(obj.Type()))
+	if err := checkFields(obj.Name(), fields); err != nil {
+		g.errorf("%v", err)
+		return
+	}
+	for _, f := range fields {
+		if _, ok := f.Type().(*types.Chan); ok {
+			g.errorf("%s.%s: unsupported channel field type %s", obj.Name(), f.Name(), f.Type())
+			return
+		}
+	}
 
-	g.Printf("public static final class %s implements go.Seq.Object {\n", obj.Name())
+	isError := false
+	for _, T := range g.errorTypes {
+		if T == obj.Type() {
+			isError = true
+		}
+	}
+	if isError {
+		// Error types are thrown as exceptions by the Go
+		// functions returning them.
+		g.Printf("public static final class %s extends Exception implements go.Seq.Object {\n", obj.Name())
+	} else {
+		g.Printf("public static final class %s implements go.Seq.Object {\n", obj.Name())
+	}
 	g.Indent()
 	g.Printf("private static final String DESCRIPTOR = \"go.%s.%s\";\n", g.pkg.Name(), obj.Name())
 	for i, f := range fields {
-		g.Printf("private static final int FIELD_%s_GET = 0x%x0f;\n", f.Name(), i)
-		g.Printf("private static final int FIELD_%s_SET = 0x%x1f;\n", f.Name(), i)
+		g.Printf("private static final int FIELD_%s_GET = 0x%x0f;\n", f.Accessor(), i)
+		g.Printf("private static final int FIELD_%s_SET = 0x%x1f;\n", f.Accessor(), i)
 	}
 	for i, m := range methods {
 		g.Printf("private static final int CALL_%s = 0x%x0c;\n", m.Name(), i)
@@ -60,7 +135,7 @@ func (g *javaGen) genStruct(obj *types.TypeName, T *types.Struct) {
 	g.Printf("private go.Seq.Ref ref;\n\n")
 
 	n := obj.Name()
-	g.Printf("private %s(go.Seq.Ref ref) { this.ref = ref; }\n\n", n)
+	g.Printf("%s%s(go.Seq.Ref ref) { this.ref = ref; }\n\n", g.proxyAccess("private "), n)
 	g.Printf(`public go.Seq.Ref ref() { return ref; }
 
 public void call(int code, go.Seq in, go.Seq out) {
@@ -70,23 +145,23 @@ public void call(int code, go.Seq in, go.Seq out) {
 `)
 
 	for _, f := range fields {
-		g.Printf("public %s get%s() {\n", g.javaType(f.Type()), f.Name())
+		g.Printf("%spublic %s get%s() {\n", g.resultAnnotation(f.Type()), g.javaType(f.Type()), f.Accessor())
 		g.Indent()
 		g.Printf("Seq in = new Seq();\n")
 		g.Printf("Seq out = new Seq();\n")
 		g.Printf("in.writeRef(ref);\n")
-		g.Printf("Seq.send(DESCRIPTOR, FIELD_%s_GET, in, out);\n", f.Name())
-		g.Printf("return out.read%s;\n", seqRead(f.Type()))
+		g.Printf("Seq.send(DESCRIPTOR, FIELD_%s_GET, in, out);\n", f.Accessor())
+		g.Printf("return %s;\n", g.readExpr("out", f.Type()))
 		g.Outdent()
 		g.Printf("}\n\n")
 
-		g.Printf("public void set%s(%s v) {\n", f.Name(), g.javaType(f.Type()))
+		g.Printf("public void set%s(%s v) {\n", f.Accessor(), g.javaType(f.Type()))
 		g.Indent()
 		g.Printf("Seq in = new Seq();\n")
 		g.Printf("Seq out = new Seq();\n")
 		g.Printf("in.writeRef(ref);\n")
-		g.Printf("in.write%s;\n", seqWrite(f.Type(), "v"))
-		g.Printf("Seq.send(DESCRIPTOR, FIELD_%s_SET, in, out);\n", f.Name())
+		g.genWrite("in", "v", f.Type())
+		g.Printf("Seq.send(DESCRIPTOR, FIELD_%s_SET, in, out);\n", f.Accessor())
 		g.Outdent()
 		g.Printf("}\n")
 	}
@@ -94,54 +169,34 @@ public void call(int code, go.Seq in, go.Seq out) {
 
 	for _, m := range methods {
 		g.genFunc(m, true)
+		g.genResultClass(m)
 	}
 
-	g.Printf("@Override public boolean equals(Object o) {\n")
-	g.Indent()
-	g.Printf("if (o == null || !(o instanceof %s)) {\n    return false;\n}\n", n)
-	g.Printf("%s that = (%s)o;\n", n, n)
-	for _, f := range fields {
-		nf := f.Name()
-		g.Printf("%s this%s = get%s();\n", g.javaType(f.Type()), nf, nf)
-		g.Printf("%s that%s = that.get%s();\n", g.javaType(f.Type()), nf, nf)
-		if isJavaPrimitive(f.Type()) {
-			g.Printf("if (this%s != that%s) {\n    return false;\n}\n", nf, nf)
-		} else {
-			g.Printf("if (this%s == null) {\n", nf)
-			g.Indent()
-			g.Printf("if (that%s != null) {\n    return false;\n}\n", nf)
-			g.Outdent()
-			g.Printf("} else if (!this%s.equals(that%s)) {\n    return false;\n}\n", nf, nf)
-		}
+	if isError {
+		g.Printf("@Override public String getMessage() {\n")
+		g.Printf("    return Error();\n")
+		g.Printf("}\n\n")
 	}
-	g.Printf("return true;\n")
-	g.Outdent()
-	g.Printf("}\n\n")
 
-	g.Printf("@Override public int hashCode() {\n")
-	g.Printf("    return java.util.Arrays.hashCode(new Object[] {")
-	for i, f := range fields {
-		if i > 0 {
-			g.Printf(", ")
-		}
-		g.Printf("get%s()", f.Name())
-	}
-	g.Printf("});\n")
-	g.Printf("}\n\n")
+	g.genRefEquals(n)
 
-	// TODO(crawshaw): use String() string if it is defined.
+	// The description is that of the String method, if the Go type
+	// is a fmt.Stringer, or else lists the fields.
 	g.Printf("@Override public String toString() {\n")
 	g.Indent()
-	g.Printf("StringBuilder b = new StringBuilder();\n")
-	g.Printf(`b.append("%s").append("{");`, obj.Name())
-	g.Printf("\n")
-	for _, f := range fields {
-		n := f.Name()
-		g.Printf(`b.append("%s:").append(get%s()).append(",");`, n, n)
+	if hasStringer(methods) {
+		g.Printf("return String();\n")
+	} else {
+		g.Printf("StringBuilder b = new StringBuilder();\n")
+		g.Printf(`b.append("%s").append("{");`, obj.Name())
+		g.Printf("\n")
+		for _, f := range fields {
+			g.Printf(`b.append("%s:").append(get%s()).append(",");`, f.bound, f.Accessor())
+			g.Printf("\n")
+		}
+		g.Printf(`return b.append("}").toString();`)
 		g.Printf("\n")
 	}
-	g.Printf(`return b.append("}").toString();`)
-	g.Printf("\n")
 	g.Outdent()
 	g.Printf("}\n\n")
 
@@ -149,8 +204,37 @@ public void call(int code, go.Seq in, go.Seq out) {
 	g.Printf("}\n\n")
 }
 
+// genRefEquals generates the equals and hashCode methods of the Java
+// class named n of a Go object. Two instances are equal if they refer
+// to the same Go object, however often it was passed to Java.
+func (g *javaGen) genRefEquals(n string) {
+	g.Printf("@Override public boolean equals(Object o) {\n")
+	g.Printf("    return o instanceof %s && ref.equals(((%s)o).ref);\n", n, n)
+	g.Printf("}\n\n")
+	g.Printf("@Override public int hashCode() {\n")
+	g.Printf("    return ref.hashCode();\n")
+	g.Printf("}\n\n")
+}
+
+// hasStringer reports whether methods holds the String method of
+// fmt.Stringer, which the Java toString method then calls.
+func hasStringer(methods []*types.Func) bool {
+	for _, m := range methods {
+		if m.Name() != "String" {
+			continue
+		}
+		sig := m.Type().(*types.Signature)
+		if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+			return false
+		}
+		T, ok := sig.Results().At(0).Type().(*types.Basic)
+		return ok && T.Kind() == types.String
+	}
+	return false
+}
+
 func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
-	g.Printf("public static abstract class Stub implements %s {\n", o.Name())
+	g.Printf("public static abstract class Stub implements %s, go.Seq.Object {\n", o.Name())
 	g.Indent()
 
 	g.Printf("static final String DESCRIPTOR = \"go.%s.%s\";\n\n", g.pkg.Name(), o.Name())
@@ -171,7 +255,7 @@ func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
 		for i := 0; i < sig.Params().Len(); i++ {
 			p := sig.Params().At(i)
 			jt := g.javaType(p.Type())
-			g.Printf("%s param_%s = in.read%s;\n", jt, p.Name(), seqRead(p.Type()))
+			g.Printf("%s param_%s = %s;\n", jt, p.Name(), g.readExpr("in", p.Type()))
 		}
 
 		res := sig.Results()
@@ -202,7 +286,7 @@ func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
 		g.Printf(");\n")
 
 		if numRes > 0 {
-			g.Printf("out.write%s;\n", seqWrite(res.At(0).Type(), "result"))
+			g.genWrite("out", "result", res.At(0).Type())
 		}
 		if returnsError {
 			g.Printf("out.writeString(null);\n")
@@ -212,9 +296,9 @@ func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
 			if numRes > 0 {
 				resTyp := res.At(0).Type()
 				g.Printf("%s result = %s;\n", g.javaType(resTyp), g.javaTypeDefault(resTyp))
-				g.Printf("out.write%s;\n", seqWrite(resTyp, "result"))
+				g.genWrite("out", "result", resTyp)
 			}
-			g.Printf("out.writeString(e.getMessage());\n")
+			g.Printf("out.writeString(go.Seq.errorMessage(e));\n")
 			g.Outdent()
 			g.Printf("}\n")
 		}
@@ -226,18 +310,60 @@ func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
 	g.Printf("default:\n    throw new RuntimeException(\"unknown code: \"+ code);\n")
 	g.Printf("}\n")
 	g.Outdent()
-	g.Printf("}\n")
+	g.Printf("}\n\n")
+
+	g.genInterfaceRefOf(o, m)
 
 	g.Outdent()
 	g.Printf("}\n\n")
 }
 
-const javaProxyPreamble = `static final class Proxy implements %s {
+// genInterfaceRefOf generates Stub.refOf, which returns the reference
+// passed to Go for an implementation of the interface. Implementations
+// that are not Stubs or Proxies are wrapped in a Stub.
+func (g *javaGen) genInterfaceRefOf(o *types.TypeName, m *types.Interface) {
+	g.Printf("%sstatic go.Seq.Ref refOf(final %s impl) {\n", g.proxyAccess(""), o.Name())
+	g.Indent()
+	g.Printf("if (impl == null) {\n    throw new NullPointerException();\n}\n")
+	g.Printf("if (impl instanceof go.Seq.Object) {\n    return ((go.Seq.Object)impl).ref();\n}\n")
+	g.Printf("return new Stub() {\n")
+	g.Indent()
+	for i := 0; i < m.NumMethods(); i++ {
+		f := m.Method(i)
+		if err := g.funcSignature(f, false); err != nil {
+			g.errorf("%v", err)
+			continue
+		}
+		g.Printf(" {\n")
+		g.Indent()
+		sig := f.Type().(*types.Signature)
+		res := sig.Results()
+		if res.Len() > 0 && !isErrorType(res.At(0).Type()) {
+			g.Printf("return ")
+		}
+		g.Printf("impl.%s(", f.Name())
+		for i := 0; i < sig.Params().Len(); i++ {
+			if i > 0 {
+				g.Printf(", ")
+			}
+			g.Printf("%s", paramName(sig.Params(), i))
+		}
+		g.Printf(");\n")
+		g.Outdent()
+		g.Printf("}\n")
+	}
+	g.Outdent()
+	g.Printf("}.ref();\n")
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+const javaProxyPreamble = `static final class Proxy implements %s, go.Seq.Object {
     static final String DESCRIPTOR = Stub.DESCRIPTOR;
 
     private go.Seq.Ref ref;
 
-    Proxy(go.Seq.Ref ref) { this.ref = ref; }
+    %sProxy(go.Seq.Ref ref) { this.ref = ref; }
 
     public go.Seq.Ref ref() { return ref; }
 
@@ -250,12 +376,38 @@ const javaProxyPreamble = `static final class Proxy implements %s {
 func (g *javaGen) genInterface(o *types.TypeName) {
 	iface := o.Type().(*types.Named).Underlying().(*types.Interface)
 
-	g.Printf("public interface %s extends go.Seq.Object {\n", o.Name())
+	g.Printf("public interface %s {\n", o.Name())
 	g.Indent()
 
 	methodSigErr := false
 	for i := 0; i < iface.NumMethods(); i++ {
-		if err := g.funcSignature(iface.Method(i), false); err != nil {
+		m := iface.Method(i)
+		if hasChanParam(m.Type().(*types.Signature)) {
+			methodSigErr = true
+			g.errorf("%s.%s: channel parameters are not supported in interface methods", o.Name(), m.Name())
+			continue
+		}
+		if hasCtx, _ := contextParam(m); hasCtx {
+			methodSigErr = true
+			g.errorf("%s.%s: context.Context parameters are not supported in interface methods", o.Name(), m.Name())
+			continue
+		}
+		if m.Type().(*types.Signature).Variadic() {
+			methodSigErr = true
+			g.errorf("%s.%s: variadic parameters are not supported in interface methods", o.Name(), m.Name())
+			continue
+		}
+		if hasFuncResult(m.Type().(*types.Signature)) {
+			methodSigErr = true
+			g.errorf("%s.%s: func results are not supported in interface methods", o.Name(), m.Name())
+			continue
+		}
+		if multiResult(m.Type().(*types.Signature)) {
+			methodSigErr = true
+			g.errorf("%s.%s: multiple results are not supported in interface methods", o.Name(), m.Name())
+			continue
+		}
+		if err := g.funcSignature(m, false); err != nil {
 			methodSigErr = true
 			g.errorf("%v", err)
 		}
@@ -267,11 +419,17 @@ func (g *javaGen) genInterface(o *types.TypeName) {
 
 	g.genInterfaceStub(o, iface)
 
-	g.Printf(javaProxyPreamble, o.Name())
+	g.Printf(javaProxyPreamble, o.Name(), g.proxyAccess(""))
 	g.Indent()
 
+	var methods []*types.Func
 	for i := 0; i < iface.NumMethods(); i++ {
 		g.genFunc(iface.Method(i), true)
+		methods = append(methods, iface.Method(i))
+	}
+	g.genRefEquals("Proxy")
+	if hasStringer(methods) {
+		g.Printf("@Override public String toString() {\n    return String();\n}\n\n")
 	}
 	for i := 0; i < iface.NumMethods(); i++ {
 		g.Printf("static final int CALL_%s = 0x%x0a;\n", iface.Method(i).Name(), i+1)
@@ -284,10 +442,40 @@ func (g *javaGen) genInterface(o *types.TypeName) {
 	g.Printf("}\n\n")
 }
 
+// resultAnnotation returns the annotation to put before a method returning
+// a value of type T. Pointers and interfaces may be nil in Go, and Seq reads
+// empty strings and byte slices as null, so every result that is not a Java
+// primitive is nullable.
+func (g *javaGen) resultAnnotation(T types.Type) string {
+	if g.annotations == "" || isJavaPrimitive(T) || isEnumType(T) {
+		return ""
+	}
+	g.nullable = true
+	return "@Nullable "
+}
+
 func isErrorType(T types.Type) bool {
 	return T == types.Universe.Lookup("error").Type()
 }
 
+// isTimeType reports whether T is time.Time, which is passed by value
+// and bound to java.util.Date.
+func isTimeType(T types.Type) bool {
+	n, ok := T.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	return n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time"
+}
+
+// javaStreamType returns the Java stream class of io.Reader or io.Writer.
+func javaStreamType(T types.Type) string {
+	if seqType(T) == "Reader" {
+		return "java.io.InputStream"
+	}
+	return "java.io.OutputStream"
+}
+
 func isJavaPrimitive(T types.Type) bool {
 	b, ok := T.(*types.Basic)
 	if !ok {
@@ -334,8 +522,25 @@ func (g *javaGen) javaType(T types.Type) string {
 			return "TODO"
 		}
 	case *types.Slice:
+		if isList(T) {
+			if err := checkList(T); err != nil {
+				g.errorf("%v", err)
+				return "TODO"
+			}
+			return "java.util.List<" + g.javaBoxedType(T.Elem()) + ">"
+		}
 		elem := g.javaType(T.Elem())
 		return elem + "[]"
+	case *types.Array:
+		if err := checkArray(T); err != nil {
+			g.errorf("%v", err)
+			return "TODO"
+		}
+		return g.javaType(T.Elem()) + "[]"
+	case *types.Map:
+		return "java.util.Map<" + g.javaBoxedType(T.Key()) + ", " + g.javaBoxedType(T.Elem()) + ">"
+	case *types.Signature:
+		return g.funcClass(T)
 
 	case *types.Pointer:
 		if _, ok := T.Elem().(*types.Named); ok {
@@ -343,7 +548,16 @@ func (g *javaGen) javaType(T types.Type) string {
 		}
 		panic(fmt.Sprintf("unsupporter pointer to type: %s", T))
 	case *types.Named:
+		if isTimeType(T) {
+			return "java.util.Date"
+		}
+		if isStreamType(T) {
+			return javaStreamType(T)
+		}
 		n := T.Obj()
+		if class, ok := g.bound[n.Pkg().Path()]; ok {
+			return class + "." + n.Name()
+		}
 		if n.Pkg() != g.pkg {
 			panic(fmt.Sprintf("type %s is in package %s, must be defined in package %s", n.Name(), n.Pkg().Name(), g.pkg.Name()))
 		}
@@ -355,6 +569,423 @@ func (g *javaGen) javaType(T types.Type) string {
 	}
 }
 
+// javaBoxedType returns the Java reference type used for T as a type
+// argument.
+func (g *javaGen) javaBoxedType(T types.Type) string {
+	switch t := g.javaType(T); t {
+	case "boolean":
+		return "Boolean"
+	case "byte":
+		return "Byte"
+	case "short":
+		return "Short"
+	case "int":
+		return "Integer"
+	case "long":
+		return "Long"
+	case "float":
+		return "Float"
+	case "double":
+		return "Double"
+	default:
+		return t
+	}
+}
+
+// javaVarargsType returns the Java type of a variadic parameter of type
+// T. The elements of ...interface{} are passed as Objects.
+func (g *javaGen) javaVarargsType(T *types.Slice) string {
+	if isEmptyInterface(T.Elem()) {
+		return "Object..."
+	}
+	return g.javaType(T.Elem()) + "..."
+}
+
+// genWriteVarargs writes the elements of the variadic parameter name of
+// type T, preceded by their number.
+func (g *javaGen) genWriteVarargs(seqName, name string, T *types.Slice) {
+	elem := name + "_e"
+	g.Printf("%s.writeInt32(%s.length);\n", seqName, name)
+	g.Printf("for (%s %s : %s) {\n", strings.TrimSuffix(g.javaVarargsType(T), "..."), elem, name)
+	g.Indent()
+	if isEmptyInterface(T.Elem()) {
+		g.Printf("%s.writeInterface(%s);\n", seqName, elem)
+	} else {
+		g.genWrite(seqName, elem, T.Elem())
+	}
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// sinkClass returns the name of the class that delivers the values of
+// a channel parameter of type T to a Sink, registering its generation.
+func (g *javaGen) sinkClass(T *types.Chan) string {
+	name := sinkName(T.Elem())
+	for _, e := range g.sinks {
+		if sinkName(e) == name {
+			return name
+		}
+	}
+	g.sinks = append(g.sinks, T.Elem())
+	return name
+}
+
+// sinkName returns the class name for the Sink wrapper of element type T.
+// Names of Go types are exported, so they do not clash with the
+// lowercase names of basic types.
+func sinkName(T types.Type) string {
+	switch T := T.(type) {
+	case *types.Pointer:
+		return "Sink_" + T.Elem().(*types.Named).Obj().Name()
+	case *types.Named:
+		return "Sink_" + T.Obj().Name()
+	default:
+		return "Sink_" + strings.ToLower(seqType(T))
+	}
+}
+
+// mapClass returns the name of the class that copies maps of type T
+// across the language boundary, registering its generation.
+func (g *javaGen) mapClass(T *types.Map) string {
+	name := mapName(T)
+	for _, m := range g.maps {
+		if mapName(m) == name {
+			return name
+		}
+	}
+	if err := checkMap(T); err != nil {
+		g.errorf("%v", err)
+	}
+	g.maps = append(g.maps, T)
+	return name
+}
+
+// mapName returns the name of the class copying maps of type T.
+func mapName(T *types.Map) string {
+	return "Map_" + mapElemName(T.Key()) + "_" + mapElemName(T.Elem())
+}
+
+func mapElemName(T types.Type) string {
+	switch T := T.(type) {
+	case *types.Pointer:
+		return mapElemName(T.Elem())
+	case *types.Named:
+		return T.Obj().Name()
+	case *types.Map:
+		return mapName(T)
+	case *types.Slice:
+		return "bytes"
+	default:
+		return T.String()
+	}
+}
+
+// genMaps generates the classes that copy maps. A map is written as its
+// length, or -1 for a nil map, followed by its keys and values.
+func (g *javaGen) genMaps() {
+	// Generating a class may register the classes of nested maps.
+	for i := 0; i < len(g.maps); i++ {
+		T := g.maps[i]
+		if checkMap(T) != nil {
+			continue // reported by mapClass
+		}
+		jt := g.javaType(T)
+		g.Printf("private static final class %s {\n", mapName(T))
+		g.Indent()
+		g.Printf("static %s read(go.Seq in) {\n", jt)
+		g.Indent()
+		g.Printf("int n = in.readInt32();\n")
+		g.Printf("if (n < 0) {\n")
+		g.Printf("    return null;\n")
+		g.Printf("}\n")
+		g.Printf("%s m = new java.util.HashMap<%s, %s>();\n", jt, g.javaBoxedType(T.Key()), g.javaBoxedType(T.Elem()))
+		g.Printf("for (int i = 0; i < n; i++) {\n")
+		g.Indent()
+		g.Printf("%s k;\n", g.javaType(T.Key()))
+		g.genRead("k", "in", T.Key())
+		g.Printf("%s v;\n", g.javaType(T.Elem()))
+		g.genRead("v", "in", T.Elem())
+		g.Printf("m.put(k, v);\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Printf("return m;\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+		g.Printf("static void write(go.Seq out, %s m) {\n", jt)
+		g.Indent()
+		g.Printf("if (m == null) {\n")
+		g.Printf("    out.writeInt32(-1);\n")
+		g.Printf("    return;\n")
+		g.Printf("}\n")
+		g.Printf("out.writeInt32(m.size());\n")
+		g.Printf("for (java.util.Map.Entry<%s, %s> e : m.entrySet()) {\n", g.javaBoxedType(T.Key()), g.javaBoxedType(T.Elem()))
+		g.Indent()
+		g.genWrite("out", "e.getKey()", T.Key())
+		g.genWrite("out", "e.getValue()", T.Elem())
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+}
+
+// listClass returns the name of the class that copies slices of type T
+// across the language boundary, registering its generation.
+func (g *javaGen) listClass(T *types.Slice) string {
+	name := listName(T)
+	for _, l := range g.lists {
+		if listName(l) == name {
+			return name
+		}
+	}
+	g.lists = append(g.lists, T) // an unsupported T is reported by javaType
+	return name
+}
+
+// listName returns the name of the class copying slices of type T.
+// Slices of struct values and of pointers share the class.
+func listName(T *types.Slice) string {
+	return "List_" + mapElemName(T.Elem())
+}
+
+// genLists generates the classes that copy slices. A slice is written as
+// its length, or -1 for a nil slice, followed by its elements.
+func (g *javaGen) genLists() {
+	for _, T := range g.lists {
+		if checkList(T) != nil {
+			continue // reported by javaType
+		}
+		jt := g.javaType(T)
+		et := g.javaType(T.Elem())
+		g.Printf("private static final class %s {\n", listName(T))
+		g.Indent()
+		g.Printf("static %s read(go.Seq in) {\n", jt)
+		g.Indent()
+		g.Printf("int n = in.readInt32();\n")
+		g.Printf("if (n < 0) {\n")
+		g.Printf("    return null;\n")
+		g.Printf("}\n")
+		g.Printf("%s l = new java.util.ArrayList<%s>(n);\n", jt, et)
+		g.Printf("for (int i = 0; i < n; i++) {\n")
+		g.Indent()
+		g.Printf("%s e;\n", et)
+		g.genRead("e", "in", listElem(T))
+		g.Printf("l.add(e);\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Printf("return l;\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+		g.Printf("static void write(go.Seq out, %s l) {\n", jt)
+		g.Indent()
+		g.Printf("if (l == null) {\n")
+		g.Printf("    out.writeInt32(-1);\n")
+		g.Printf("    return;\n")
+		g.Printf("}\n")
+		g.Printf("out.writeInt32(l.size());\n")
+		g.Printf("for (%s e : l) {\n", et)
+		g.Indent()
+		g.genWrite("out", "e", listElem(T))
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+}
+
+// arrayClass returns the name of the class that copies arrays of type T
+// across the language boundary, registering its generation.
+func (g *javaGen) arrayClass(T *types.Array) string {
+	name := arrayName(T)
+	for _, a := range g.arrays {
+		if arrayName(a) == name {
+			return name
+		}
+	}
+	g.arrays = append(g.arrays, T) // an unsupported T is reported by javaType
+	return name
+}
+
+// arrayName returns the name of the class copying arrays of type T.
+func arrayName(T *types.Array) string {
+	return fmt.Sprintf("Array_%d_%s", T.Len(), T.Elem())
+}
+
+// genArrays generates the classes that copy arrays. An array is written
+// as its length followed by its elements. Java arrays of another length
+// than the Go array are rejected with an IllegalArgumentException.
+func (g *javaGen) genArrays() {
+	for _, T := range g.arrays {
+		if checkArray(T) != nil {
+			continue // reported by javaType
+		}
+		jt := g.javaType(T)
+		et := g.javaType(T.Elem())
+		g.Printf("private static final class %s {\n", arrayName(T))
+		g.Indent()
+		g.Printf("static %s read(go.Seq in) {\n", jt)
+		g.Indent()
+		g.Printf("%s a = new %s[in.readInt32()];\n", jt, et)
+		g.Printf("for (int i = 0; i < a.length; i++) {\n")
+		g.Indent()
+		g.genRead("a[i]", "in", T.Elem())
+		g.Outdent()
+		g.Printf("}\n")
+		g.Printf("return a;\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+		g.Printf("static void write(go.Seq out, %s a) {\n", jt)
+		g.Indent()
+		g.Printf("if (a.length != %d) {\n", T.Len())
+		g.Printf("    throw new IllegalArgumentException(\"%s needs an array of length %d, not \" + a.length);\n", T, T.Len())
+		g.Printf("}\n")
+		g.Printf("out.writeInt32(a.length);\n")
+		g.Printf("for (%s e : a) {\n", et)
+		g.Indent()
+		g.genWrite("out", "e", T.Elem())
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+}
+
+// funcClass returns the name of the interface of closures of type T,
+// registering its generation.
+func (g *javaGen) funcClass(T *types.Signature) string {
+	name := funcName(T)
+	for _, f := range g.funcs {
+		if funcName(f) == name {
+			return name
+		}
+	}
+	g.funcs = append(g.funcs, T)
+	return name
+}
+
+// genFuncs generates an interface with the single method call for each
+// type of closure returned by Go, and the proxy class implementing it,
+// which calls the closure in Go. The closure is released when the proxy
+// is collected.
+func (g *javaGen) genFuncs() {
+	for _, T := range g.funcs {
+		n := funcName(T)
+		if g.pkg.Scope().Lookup(n) != nil {
+			g.errorf("cannot generate interface %s for func type %s: package %s defines %s", n, T, g.pkg.Name(), n)
+			continue
+		}
+		call := types.NewFunc(token.NoPos, g.pkg, "call", T)
+		g.Printf("public interface %s {\n", n)
+		g.Indent()
+		if err := g.funcSignature(call, false); err != nil {
+			g.errorf("%v", err)
+		}
+		g.Printf(";\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+
+		g.Printf("private static final class %s_Proxy implements %s, go.Seq.Object {\n", n, n)
+		g.Indent()
+		g.Printf("private static final String DESCRIPTOR = \"go.%s.%s\";\n", g.pkg.Name(), n)
+		g.Printf("private static final int CALL_call = 0x%x;\n\n", closureCallCode)
+		g.Printf("private final go.Seq.Ref ref;\n\n")
+		g.Printf("%s_Proxy(go.Seq.Ref ref) { this.ref = ref; }\n\n", n)
+		g.Printf(`public go.Seq.Ref ref() { return ref; }
+
+public void call(int code, go.Seq in, go.Seq out) {
+    throw new RuntimeException("internal error: cycle: cannot call concrete proxy");
+}
+
+`)
+		g.genFunc(call, true)
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+}
+
+const javaSinkInterface = `public interface Sink<T> {
+    public void send(T v);
+    public void close();
+}
+
+`
+
+// genReadError generates readError, which makes the exception thrown
+// for an error returned by Go: an exception of the error type of the
+// package the error has, or else an Exception with the error message.
+func (g *javaGen) genReadError() {
+	if len(g.errorTypes) == 0 {
+		return
+	}
+	g.Printf("private static Exception readError(String msg, go.Seq in) {\n")
+	g.Indent()
+	g.Printf("switch (in.readInt32()) {\n")
+	for i, T := range g.errorTypes {
+		g.Printf("case %d:\n", i+1)
+		g.Printf("    return new %s(in.readRef());\n", T.Obj().Name())
+	}
+	g.Printf("default:\n")
+	g.Printf("    return new Exception(msg);\n")
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genSinks generates the Sink interface passed for channel parameters,
+// and a class for each channel element type that forwards the values
+// sent by Go to a Sink.
+func (g *javaGen) genSinks() {
+	if len(g.sinks) == 0 {
+		return
+	}
+	if g.pkg.Scope().Lookup("Sink") != nil {
+		g.errorf("cannot generate Sink interface for channel parameters: package %s defines Sink", g.pkg.Name())
+		return
+	}
+	g.Printf(javaSinkInterface)
+
+	for _, T := range g.sinks {
+		n := sinkName(T)
+		g.Printf("private static final class %s implements go.Seq.Object {\n", n)
+		g.Indent()
+		g.Printf("static final int CALL_send = 0x%x;\n", sinkSendCode)
+		g.Printf("static final int CALL_close = 0x%x;\n\n", sinkCloseCode)
+		g.Printf("private final Sink<%s> sink;\n", g.javaBoxedType(T))
+		g.Printf("private final go.Seq.Ref ref;\n\n")
+		g.Printf("%s(Sink<%s> sink) {\n", n, g.javaBoxedType(T))
+		g.Printf("    this.sink = sink;\n")
+		g.Printf("    ref = go.Seq.createRef(this);\n")
+		g.Printf("}\n\n")
+		g.Printf("public go.Seq.Ref ref() { return ref; }\n\n")
+		g.Printf("public void call(int code, go.Seq in, go.Seq out) {\n")
+		g.Indent()
+		g.Printf("switch (code) {\n")
+		g.Printf("case CALL_send: {\n")
+		g.Indent()
+		g.Printf("%s v;\n", g.javaType(T))
+		g.genRead("v", "in", T)
+		g.Printf("sink.send(v);\n")
+		g.Printf("return;\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Printf("case CALL_close:\n")
+		g.Printf("    sink.close();\n")
+		g.Printf("    return;\n")
+		g.Printf("default:\n    throw new RuntimeException(\"unknown code: \"+ code);\n")
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+}
+
 // javaTypeDefault returns a string that represents the default value of the mapped java type.
 // TODO(hyangah): Combine javaType and javaTypeDefault?
 func (g *javaGen) javaTypeDefault(T types.Type) string {
@@ -372,7 +1003,12 @@ func (g *javaGen) javaTypeDefault(T types.Type) string {
 			g.errorf("unsupported return type: %s", T)
 			return "TODO"
 		}
-	case *types.Slice, *types.Pointer, *types.Named:
+	case *types.Named:
+		if isEnumType(T) {
+			return g.javaType(T) + "." + enumConsts(T)[0].Name()
+		}
+		return "null"
+	case *types.Slice, *types.Pointer, *types.Map, *types.Signature:
 		return "null"
 
 	default:
@@ -399,40 +1035,91 @@ func (g *javaGen) funcSignature(o *types.Func, static bool) error {
 	res := sig.Results()
 
 	var returnsError bool
-	var ret string
-	switch res.Len() {
-	case 2:
+	var ret, ann string
+	switch {
+	case g.nullableCommaOK(sig):
+		if err := checkMultiResult(o); err != nil {
+			return err
+		}
+		ret = g.javaBoxedType(res.At(0).Type())
+		if g.annotations != "" {
+			g.nullable = true
+			ann = "@Nullable "
+		}
+	case multiResult(sig):
+		if err := checkMultiResult(o); err != nil {
+			return err
+		}
+		returnsError = isErrorType(res.At(res.Len() - 1).Type())
+		ret = g.resultClass(o)
+	case res.Len() == 2:
 		if !isErrorType(res.At(1).Type()) {
 			return fmt.Errorf("second result value must be of type error: %s", o)
 		}
 		returnsError = true
 		ret = g.javaType(res.At(0).Type())
-	case 1:
+		ann = g.resultAnnotation(res.At(0).Type())
+	case res.Len() == 1:
 		if isErrorType(res.At(0).Type()) {
 			returnsError = true
 			ret = "void"
 		} else {
 			ret = g.javaType(res.At(0).Type())
+			ann = g.resultAnnotation(res.At(0).Type())
 		}
-	case 0:
+	case res.Len() == 0:
 		ret = "void"
 	default:
 		return fmt.Errorf("too many result values: %s", o)
 	}
 
-	g.Printf("public ")
+	if res.Len() > 0 {
+		if _, ok := res.At(0).Type().(*types.Chan); ok {
+			return fmt.Errorf("%s: unsupported channel result type %s: channels are only supported as function and method parameters", o.Name(), res.At(0).Type())
+		}
+	}
+	if _, err := closureResult(o); err != nil {
+		return err
+	}
+	hasCtx, err := contextParam(o)
+	if err != nil {
+		return err
+	}
+	if err := checkVariadic(o); err != nil {
+		return err
+	}
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if ch, ok := params.At(i).Type().(*types.Chan); ok {
+			if err := checkChan(ch, g.pkg); err != nil {
+				return fmt.Errorf("%s: %v", o.Name(), err)
+			}
+		}
+	}
+
+	g.Printf("%spublic ", ann)
 	if static {
 		g.Printf("static ")
 	}
 	g.Printf("%s %s(", ret, o.Name())
-	params := sig.Params()
 	for i := 0; i < params.Len(); i++ {
 		if i > 0 {
 			g.Printf(", ")
 		}
 		v := sig.Params().At(i)
 		name := paramName(params, i)
-		jt := g.javaType(v.Type())
+		var jt string
+		if i == 0 && hasCtx {
+			jt = g.supportPkg + ".Seq.Cancellable"
+		} else if sig.Variadic() && i == params.Len()-1 {
+			jt = g.javaVarargsType(v.Type().(*types.Slice))
+		} else if ch, ok := v.Type().(*types.Chan); ok {
+			jt = "Sink<" + g.javaBoxedType(ch.Elem()) + ">"
+		} else if g.byteBuffers && isByteBufferParam(o, v.Type()) {
+			jt = "java.nio.ByteBuffer"
+		} else {
+			jt = g.javaType(v.Type())
+		}
 		g.Printf("%s %s", jt, name)
 	}
 	g.Printf(")")
@@ -449,6 +1136,14 @@ func (g *javaGen) genFunc(o *types.Func, method bool) {
 	}
 	sig := o.Type().(*types.Signature)
 	res := sig.Results()
+	if g.nullableCommaOK(sig) {
+		g.genCommaOKBody(o, method)
+		return
+	}
+	if multiResult(sig) {
+		g.genMultiResultBody(o, method)
+		return
+	}
 
 	g.Printf(" {\n")
 	g.Indent()
@@ -473,81 +1168,470 @@ func (g *javaGen) genFunc(o *types.Func, method bool) {
 	if method {
 		g.Printf("_in.writeRef(ref);\n")
 	}
-	params := sig.Params()
-	for i := 0; i < params.Len(); i++ {
-		p := params.At(i)
-		g.Printf("_in.write%s;\n", seqWrite(p.Type(), p.Name()))
-	}
+	g.genWriteParams(o)
 	g.Printf("Seq.send(DESCRIPTOR, CALL_%s, _in, _out);\n", o.Name())
 	if resultType != nil {
 		g.genRead("_result", "_out", resultType)
 	}
 	if returnsError {
+		g.genThrowError()
+	}
+	if resultType != nil {
+		g.Printf("return _result;\n")
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genWriteParams writes the parameters of o to _in.
+func (g *javaGen) genWriteParams(o *types.Func) {
+	sig := o.Type().(*types.Signature)
+	hasCtx, _ := contextParam(o)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		name := paramName(params, i)
+		if i == 0 && hasCtx {
+			g.Printf("_in.writeCancellable(%s);\n", name)
+			continue
+		}
+		if ch, ok := p.Type().(*types.Chan); ok {
+			g.Printf("_in.writeRef(new %s(%s).ref());\n", g.sinkClass(ch), name)
+			continue
+		}
+		if sig.Variadic() && i == params.Len()-1 {
+			g.genWriteVarargs("_in", paramName(params, i), p.Type().(*types.Slice))
+			continue
+		}
+		if g.byteBuffers && isByteBufferParam(o, p.Type()) {
+			g.Printf("_in.writeByteBuffer(%s);\n", name)
+			continue
+		}
+		g.genWrite("_in", name, p.Type())
+	}
+}
+
+// genThrowError reads the error result of a call from _out, and throws
+// it if it is not null, with the errors it wraps as its causes.
+func (g *javaGen) genThrowError() {
+	if len(g.errorTypes) > 0 {
+		g.Printf(`String _err = _out.readString();
+if (_err != null) {
+    throw go.Seq.withCause(readError(_err, _out), _out.readErrorCause());
+}
+`)
+	} else {
 		g.Printf(`String _err = _out.readString();
 if (_err != null) {
-    throw new Exception(_err);
+    throw go.Seq.withCause(new Exception(_err), _out.readErrorCause());
 }
 `)
 	}
-	if resultType != nil {
-		g.Printf("return _result;\n")
+}
+
+// resultClass returns the name of the Java class holding the results of
+// o, a function returning multiple values.
+func (g *javaGen) resultClass(o *types.Func) string {
+	return o.Name() + "Result"
+}
+
+// genMultiResultBody generates the body of o, a function returning
+// multiple values. The values are read into the fields of an object of
+// its result class, and a trailing error is thrown.
+func (g *javaGen) genMultiResultBody(o *types.Func, method bool) {
+	res := o.Type().(*types.Signature).Results()
+	cls := g.resultClass(o)
+	g.Printf(" {\n")
+	g.Indent()
+	g.Printf("go.Seq _in = new go.Seq();\n")
+	g.Printf("go.Seq _out = new go.Seq();\n")
+	if method {
+		g.Printf("_in.writeRef(ref);\n")
+	}
+	g.genWriteParams(o)
+	g.Printf("Seq.send(DESCRIPTOR, CALL_%s, _in, _out);\n", o.Name())
+	var args []string
+	for i := 0; i < res.Len(); i++ {
+		T := res.At(i).Type()
+		if isErrorType(T) {
+			g.genThrowError()
+			continue
+		}
+		v := "_res_" + resultName(res, i)
+		g.Printf("%s %s;\n", g.javaType(T), v)
+		g.genRead(v, "_out", T)
+		args = append(args, v)
+	}
+	g.Printf("return new %s(%s);\n", cls, strings.Join(args, ", "))
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// nullableCommaOK reports whether the function signature follows the
+// comma-ok idiom and its value is returned alone, null when the bool is
+// false. See Options.CommaOK.
+func (g *javaGen) nullableCommaOK(sig *types.Signature) bool {
+	return g.commaOK && commaOK(sig)
+}
+
+// genCommaOKBody generates the body of o, a function following the
+// comma-ok idiom. It returns the value, or null when the bool is false.
+func (g *javaGen) genCommaOKBody(o *types.Func, method bool) {
+	T := o.Type().(*types.Signature).Results().At(0).Type()
+	g.Printf(" {\n")
+	g.Indent()
+	g.Printf("go.Seq _in = new go.Seq();\n")
+	g.Printf("go.Seq _out = new go.Seq();\n")
+	if method {
+		g.Printf("_in.writeRef(ref);\n")
+	}
+	g.genWriteParams(o)
+	g.Printf("Seq.send(DESCRIPTOR, CALL_%s, _in, _out);\n", o.Name())
+	g.Printf("%s _result;\n", g.javaType(T))
+	g.genRead("_result", "_out", T)
+	g.Printf("if (!_out.readBool()) {\n")
+	g.Printf("    return null;\n")
+	g.Printf("}\n")
+	g.Printf("return _result;\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genResultClass generates the result class of o, if it returns multiple
+// values. The class has a final field for each value, named after the Go
+// result.
+func (g *javaGen) genResultClass(o *types.Func) {
+	sig := o.Type().(*types.Signature)
+	if !multiResult(sig) || g.nullableCommaOK(sig) || checkMultiResult(o) != nil {
+		return
+	}
+	cls := g.resultClass(o)
+	if g.pkg.Scope().Lookup(cls) != nil {
+		g.errorf("%s: result class %s conflicts with a declaration of package %s", o.Name(), cls, g.pkg.Name())
+		return
+	}
+	res := sig.Results()
+	var fields, params []string
+	for i := 0; i < res.Len(); i++ {
+		if T := res.At(i).Type(); !isErrorType(T) {
+			name := resultName(res, i)
+			fields = append(fields, name)
+			params = append(params, g.javaType(T)+" "+name)
+		}
+	}
+	g.Printf("public static final class %s {\n", cls)
+	g.Indent()
+	for _, p := range params {
+		g.Printf("public final %s;\n", p)
+	}
+	g.Printf("\n")
+	g.Printf("%s(%s) {\n", cls, strings.Join(params, ", "))
+	g.Indent()
+	for _, f := range fields {
+		g.Printf("this.%s = %s;\n", f, f)
 	}
 	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
 	g.Printf("}\n\n")
 }
 
 func (g *javaGen) genRead(resName, seqName string, T types.Type) {
+	if isTimeType(T) {
+		g.Printf("%s = %s.readTime();\n", resName, seqName)
+		return
+	}
+	if isStreamType(T) {
+		g.Printf("%s = %s.read%s();\n", resName, seqName, seqType(T))
+		return
+	}
 	switch T := T.(type) {
 	case *types.Pointer:
 		// TODO(crawshaw): test *int
 		// TODO(crawshaw): test **Generator
 		switch T := T.Elem().(type) {
 		case *types.Named:
-			o := T.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", T, g.pkg)
+			if !g.checkPkg(T.Obj()) {
 				return
 			}
-			g.Printf("%s = new %s(%s.readRef());\n", resName, o.Name(), seqName)
+			g.Printf("%s = new %s(%s.readRef());\n", resName, g.javaType(T), seqName)
 		default:
 			g.errorf("unsupported type %s", T)
 		}
 	case *types.Named:
 		switch T.Underlying().(type) {
 		case *types.Interface, *types.Pointer:
-			o := T.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", T, g.pkg)
+			if !g.checkPkg(T.Obj()) {
 				return
 			}
-			g.Printf("%s = new %s.Proxy(%s.readRef());\n", resName, o.Name(), seqName)
+			g.Printf("%s = new %s.Proxy(%s.readRef());\n", resName, g.javaType(T), seqName)
+		case *types.Basic:
+			if !isEnumType(T) {
+				g.errorf("unsupported, direct named type %s", T)
+				return
+			}
+			g.Printf("%s = %s;\n", resName, g.readExpr(seqName, T))
 		default:
 			g.errorf("unsupported, direct named type %s", T)
 		}
+	case *types.Map, *types.Array:
+		g.Printf("%s = %s;\n", resName, g.readExpr(seqName, T))
+	case *types.Slice:
+		if isList(T) {
+			g.Printf("%s = %s;\n", resName, g.readExpr(seqName, T))
+			return
+		}
+		g.Printf("%s = %s.read%s();\n", resName, seqName, seqType(T))
+	case *types.Signature:
+		g.Printf("%s = new %s_Proxy(%s.readRef());\n", resName, g.funcClass(T), seqName)
 	default:
 		g.Printf("%s = %s.read%s();\n", resName, seqName, seqType(T))
 	}
 }
 
+// javaKeywords are the reserved words of Java, which cannot be used as
+// identifiers.
+var javaKeywords = map[string]bool{
+	"abstract": true, "assert": true, "boolean": true, "break": true,
+	"byte": true, "case": true, "catch": true, "char": true,
+	"class": true, "const": true, "continue": true, "default": true,
+	"do": true, "double": true, "else": true, "enum": true,
+	"extends": true, "false": true, "final": true, "finally": true,
+	"float": true, "for": true, "goto": true, "if": true,
+	"implements": true, "import": true, "instanceof": true, "int": true,
+	"interface": true, "long": true, "native": true, "new": true,
+	"null": true, "package": true, "private": true, "protected": true,
+	"public": true, "return": true, "short": true, "static": true,
+	"strictfp": true, "super": true, "switch": true, "synchronized": true,
+	"this": true, "throw": true, "throws": true, "transient": true,
+	"true": true, "try": true, "void": true, "volatile": true,
+	"while": true,
+}
+
+// IsJavaKeyword reports whether s is a reserved word of Java.
+func IsJavaKeyword(s string) bool { return javaKeywords[s] }
+
+// validJavaPkg reports whether name is a valid Java package name: a
+// dot-separated list of identifiers that are not Java keywords.
+func validJavaPkg(name string) bool {
+	for _, id := range strings.Split(name, ".") {
+		if id == "" || javaKeywords[id] {
+			return false
+		}
+		for i, r := range id {
+			if !unicode.IsLetter(r) && r != '_' && r != '$' && (i == 0 || !unicode.IsDigit(r)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readExpr returns the Java expression reading a value of type T from
+// the Seq named seqName.
+func (g *javaGen) readExpr(seqName string, T types.Type) string {
+	if m, ok := T.(*types.Map); ok {
+		return g.mapClass(m) + ".read(" + seqName + ")"
+	}
+	if isList(T) {
+		return g.listClass(T.(*types.Slice)) + ".read(" + seqName + ")"
+	}
+	if a, ok := T.(*types.Array); ok {
+		return g.arrayClass(a) + ".read(" + seqName + ")"
+	}
+	if isEnumType(T) {
+		return fmt.Sprintf("%s.fromValue(%s.read%s)", g.javaType(T), seqName, seqRead(T))
+	}
+	if isStreamType(T) {
+		return seqName + ".read" + seqRead(T)
+	}
+	// References are wrapped in the Java class of their type.
+	switch T := T.(type) {
+	case *types.Pointer:
+		if _, ok := T.Elem().(*types.Named); ok {
+			return fmt.Sprintf("new %s(%s.readRef())", g.javaType(T), seqName)
+		}
+	case *types.Named:
+		switch T.Underlying().(type) {
+		case *types.Interface, *types.Pointer:
+			return fmt.Sprintf("new %s.Proxy(%s.readRef())", g.javaType(T), seqName)
+		}
+	case *types.Signature:
+		return fmt.Sprintf("new %s_Proxy(%s.readRef())", g.funcClass(T), seqName)
+	}
+	return seqName + ".read" + seqRead(T)
+}
+
+// checkPkg reports an error unless the type named by obj is defined in
+// the generated package or in a package bound together with it.
+func (g *javaGen) checkPkg(obj *types.TypeName) bool {
+	if _, ok := g.bound[obj.Pkg().Path()]; obj.Pkg() != g.pkg && !ok {
+		g.errorf("type %s not defined in package %s", obj.Type(), g.pkg)
+		return false
+	}
+	return true
+}
+
+// isBound reports whether the types of pkg are bound with the generated
+// package.
+func (g *javaGen) isBound(pkg *types.Package) bool {
+	_, ok := g.bound[pkg.Path()]
+	return pkg == g.pkg || ok
+}
+
+// genWrite generates the statement writing the value name of type T to
+// the Seq named seqName.
+func (g *javaGen) genWrite(seqName, name string, T types.Type) {
+	if m, ok := T.(*types.Map); ok {
+		g.Printf("%s.write(%s, %s);\n", g.mapClass(m), seqName, name)
+		return
+	}
+	if isList(T) {
+		g.Printf("%s.write(%s, %s);\n", g.listClass(T.(*types.Slice)), seqName, name)
+		return
+	}
+	if a, ok := T.(*types.Array); ok {
+		g.Printf("%s.write(%s, %s);\n", g.arrayClass(a), seqName, name)
+		return
+	}
+	g.Printf("%s.write%s;\n", seqName, g.seqWrite(T, name))
+}
+
+// seqWrite returns the Seq method call writing the value name of type T.
+func (g *javaGen) seqWrite(T types.Type, name string) string {
+	t := seqType(T)
+	if t == "Ref" {
+		// TODO(crawshaw): do something cleaner, i.e. genWrite.
+		if _, ok := T.(*types.Named); ok {
+			// Any Java implementation of an interface can be passed.
+			return t + "(" + g.javaType(T) + ".Stub.refOf(" + name + "))"
+		}
+		return t + "(" + name + ".ref())"
+	}
+	if isEnumType(T) {
+		return t + "(" + name + ".value())"
+	}
+	return t + "(" + name + ")"
+}
+
+// genEnum generates a Java enum of the constants of the enum type T.
+// The constants are passed by their Go values.
+func (g *javaGen) genEnum(T *types.Named) {
+	n := T.Obj().Name()
+	vt := g.javaType(T.Underlying())
+	g.Printf("public enum %s {\n", n)
+	g.Indent()
+	consts := enumConsts(T)
+	for i, c := range consts {
+		sep := ","
+		if i == len(consts)-1 {
+			sep = ";"
+		}
+		if vt == "long" {
+			g.Printf("%s(%dL)%s\n", c.Name(), constInt(c), sep)
+		} else {
+			g.Printf("%s(%d)%s\n", c.Name(), constInt(c), sep)
+		}
+	}
+	g.Printf("\n")
+	g.Printf("private final %s value;\n\n", vt)
+	g.Printf("private %s(%s value) { this.value = value; }\n\n", n, vt)
+	g.Printf("// value returns the Go value of the constant.\n")
+	g.Printf("public %s value() { return value; }\n\n", vt)
+	g.Printf("// fromValue returns the constant with the given Go value.\n")
+	g.Printf("public static %s fromValue(%s value) {\n", n, vt)
+	g.Indent()
+	g.Printf("for (%s c : values()) {\n", n)
+	g.Printf("    if (c.value == value) {\n")
+	g.Printf("        return c;\n")
+	g.Printf("    }\n")
+	g.Printf("}\n")
+	g.Printf("throw new IllegalArgumentException(\"unknown %s value: \" + value);\n", n)
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
 func (g *javaGen) errorf(format string, args ...interface{}) {
 	g.err = append(g.err, fmt.Errorf(format, args...))
 }
 
+// genObject generates the binding of the exported package-level object
+// obj, and reports whether it is a function, to register with a call
+// code.
+func (g *javaGen) genObject(obj types.Object) bool {
+	switch o := obj.(type) {
+	case *types.Func:
+		g.genFunc(o, false)
+		g.genResultClass(o)
+		return true
+	case *types.TypeName:
+		named := o.Type().(*types.Named)
+		switch t := named.Underlying().(type) {
+		case *types.Struct:
+			g.genStruct(o, t)
+		case *types.Interface:
+			g.genInterface(o)
+		default:
+			if isEnumType(named) {
+				g.genEnum(named)
+				return false
+			}
+			g.errorf("%s: cannot generate binding for %s: %T", g.fset.Position(o.Pos()), o.Name(), t)
+		}
+	case *types.Const:
+		if isEnumType(o.Type()) {
+			return false // generated with its type
+		}
+		g.genConst(o)
+	default:
+		g.errorf("unsupported exported type: %v", obj)
+	}
+	return false
+}
+
+// genVar generates the static accessors get<Name> and set<Name> of the
+// exported package-level variable v, and reports whether v is bound.
+// Variables of types that cannot be bound are skipped with a warning.
+func (g *javaGen) genVar(v *types.Var) bool {
+	if err := checkVar(v, g.isBound); err != nil {
+		Warnf("%s: variable skipped: %v", v.Name(), err)
+		return false
+	}
+	T, n := v.Type(), v.Name()
+	g.Printf("%spublic static %s get%s() {\n", g.resultAnnotation(T), g.javaType(T), n)
+	g.Indent()
+	g.Printf("Seq in = new Seq();\n")
+	g.Printf("Seq out = new Seq();\n")
+	g.Printf("Seq.send(DESCRIPTOR, CALL_get_%s, in, out);\n", n)
+	g.Printf("return %s;\n", g.readExpr("out", T))
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.Printf("public static void set%s(%s v) {\n", n, g.javaType(T))
+	g.Indent()
+	g.Printf("Seq in = new Seq();\n")
+	g.Printf("Seq out = new Seq();\n")
+	g.genWrite("in", "v", T)
+	g.Printf("Seq.send(DESCRIPTOR, CALL_set_%s, in, out);\n", n)
+	g.Outdent()
+	g.Printf("}\n\n")
+	return true
+}
+
 const javaPreamble = `// Java Package %s is a proxy for talking to a Go program.
 //   gobind -lang=java %s
 //
 // File is generated by gobind. Do not edit.
-package go.%s;
+package %s;
 
 import go.Seq;
-
 `
 
 func (g *javaGen) gen() error {
-	g.Printf(javaPreamble, g.pkg.Name(), g.pkg.Path(), g.pkg.Name())
-
-	firstRune, size := utf8.DecodeRuneInString(g.pkg.Name())
-	className := string(unicode.ToUpper(firstRune)) + g.pkg.Name()[size:]
+	g.errorTypes = errorTypes(g.pkg)
+	className := javaClassName(g.pkg)
 
 	g.Printf("public abstract class %s {\n", className)
 	g.Indent()
@@ -560,29 +1644,24 @@ func (g *javaGen) gen() error {
 		if !obj.Exported() {
 			continue
 		}
-
-		switch o := obj.(type) {
-		// TODO(crawshaw): case *types.Const:
-		// TODO(crawshaw): case *types.Var:
-		case *types.Func:
-			g.genFunc(o, false)
-			funcs = append(funcs, o.Name())
-		case *types.TypeName:
-			named := o.Type().(*types.Named)
-			switch t := named.Underlying().(type) {
-			case *types.Struct:
-				g.genStruct(o, t)
-			case *types.Interface:
-				g.genInterface(o)
-			default:
-				g.errorf("%s: cannot generate binding for %s: %T", g.fset.Position(o.Pos()), o.Name(), t)
-				continue
+		if v, ok := obj.(*types.Var); ok {
+			if g.genVar(v) {
+				funcs = append(funcs, "get_"+name, "set_"+name)
 			}
-		default:
-			g.errorf("unsupported exported type: ", obj)
+			continue
+		}
+		if g.genObject(obj) {
+			funcs = append(funcs, obj.Name())
 		}
 	}
 
+	g.genSinks()
+	g.genMaps()
+	g.genLists()
+	g.genArrays()
+	g.genFuncs()
+	g.genReadError()
+
 	for i, name := range funcs {
 		g.Printf("private static final int CALL_%s = %d;\n", name, i+1)
 	}
@@ -594,5 +1673,16 @@ func (g *javaGen) gen() error {
 	if len(g.err) > 0 {
 		return g.err
 	}
+
+	// The imports depend on the annotations used by the class body,
+	// so the preamble is written last.
+	body := g.buf.String()
+	g.buf.Reset()
+	g.Printf(javaPreamble, g.pkg.Name(), g.pkg.Path(), g.javaPkg)
+	if g.nullable {
+		g.Printf("import %s;\n", javaNullable[g.annotations])
+	}
+	g.Printf("\n")
+	g.buf.WriteString(body)
 	return nil
 }
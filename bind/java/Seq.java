@@ -36,6 +36,7 @@ public class Seq {
 	public native int readInt32();
 	public native long readInt64();
 	public long readInt() { return readInt64(); }
+	public boolean readBool() { return readInt32() != 0; }
 
 	public native float readFloat32();
 	public native double readFloat64();
@@ -43,11 +44,36 @@ public class Seq {
 	public String readString() { return readUTF16(); }
 	public native byte[] readByteArray();
 
+	// Go times are sent in milliseconds since the epoch, with the zero
+	// Go time sent as Long.MIN_VALUE and read as null.
+	public java.util.Date readTime() {
+		long ms = readInt64();
+		return ms == Long.MIN_VALUE ? null : new java.util.Date(ms);
+	}
+
+	// readErrorCause reads the messages of the errors wrapped by a Go
+	// error, outermost first, and returns them as a chain of exceptions,
+	// or null if the error wraps none.
+	public Throwable readErrorCause() {
+		Throwable cause = null, last = null;
+		for (int n = readInt32(); n > 0; n--) {
+			Exception e = new Exception(readString());
+			if (last == null) {
+				cause = e;
+			} else {
+				last.initCause(e);
+			}
+			last = e;
+		}
+		return cause;
+	}
+
 	public native void writeInt8(byte v);
 	public native void writeInt16(short v);
 	public native void writeInt32(int v);
 	public native void writeInt64(long v);
 	public void writeInt(long v) { writeInt64(v); }
+	public void writeBool(boolean v) { writeInt32(v ? 1 : 0); }
 
 	public native void writeFloat32(float v);
 	public native void writeFloat64(double v);
@@ -55,10 +81,118 @@ public class Seq {
 	public void writeString(String v) { writeUTF16(v); }
 	public native void writeByteArray(byte[] v);
 
+	// writeByteBuffer passes the remaining bytes of a direct buffer to
+	// Go without copying. The buffer is referenced by this Seq, so it is
+	// not freed while Go reads from it during the call.
+	public void writeByteBuffer(java.nio.ByteBuffer v) {
+		if (v == null) {
+			writeInt64(0);
+			return;
+		}
+		if (!v.isDirect()) {
+			throw new IllegalArgumentException("ByteBuffer passed to Go must be direct");
+		}
+		if (buffers == null) {
+			buffers = new java.util.ArrayList<java.nio.ByteBuffer>();
+		}
+		buffers.add(v);
+		writeDirectBuffer(v, v.position(), v.remaining());
+	}
+	private java.util.ArrayList<java.nio.ByteBuffer> buffers;
+	private native void writeDirectBuffer(java.nio.ByteBuffer v, int off, int len);
+
+	public void writeTime(java.util.Date v) {
+		writeInt64(v == null ? Long.MIN_VALUE : v.getTime());
+	}
+
 	public void writeRef(Ref ref) {
+		tracker.passToGo(ref);
 		writeInt32(ref.refnum);
 	}
 
+	public void writeCancellable(Cancellable c) {
+		writeInt32(c == null ? 0 : c.ref.refnum);
+	}
+
+	// Go io.Readers and io.Writers are passed as InputStreams and
+	// OutputStreams. A stream passed to Go and back is the same stream.
+	public void writeReader(java.io.InputStream v) {
+		if (v == null) {
+			writeInt32(0);
+		} else if (v instanceof GoInputStream) {
+			writeRef(((GoInputStream)v).ref);
+		} else {
+			writeRef(new InputStreamStub(v).ref());
+		}
+	}
+
+	public void writeWriter(java.io.OutputStream v) {
+		if (v == null) {
+			writeInt32(0);
+		} else if (v instanceof GoOutputStream) {
+			writeRef(((GoOutputStream)v).ref);
+		} else {
+			writeRef(new OutputStreamStub(v).ref());
+		}
+	}
+
+	public java.io.InputStream readReader() {
+		int refnum = readInt32();
+		if (refnum == 0) {
+			return null;
+		}
+		Ref ref = tracker.get(refnum);
+		if (refnum > 0) {
+			return ((InputStreamStub)ref.obj).stream;
+		}
+		return new GoInputStream(ref);
+	}
+
+	public java.io.OutputStream readWriter() {
+		int refnum = readInt32();
+		if (refnum == 0) {
+			return null;
+		}
+		Ref ref = tracker.get(refnum);
+		if (refnum > 0) {
+			return ((OutputStreamStub)ref.obj).stream;
+		}
+		return new GoOutputStream(ref);
+	}
+
+	// writeInterface writes an element of a Go ...interface{} parameter,
+	// preceded by its kind, as read by Buffer.ReadInterface in Go. Only
+	// strings, Long, Integer, Double and Float numbers, byte arrays and
+	// Go objects can be passed.
+	public void writeInterface(java.lang.Object v) {
+		if (v == null) {
+			writeInt32(0);
+		} else if (v instanceof String) {
+			writeInt32(1);
+			writeString((String)v);
+		} else if (v instanceof Long) {
+			writeInt32(2);
+			writeInt((Long)v);
+		} else if (v instanceof Integer) {
+			writeInt32(3);
+			writeInt32((Integer)v);
+		} else if (v instanceof Double) {
+			writeInt32(4);
+			writeFloat64((Double)v);
+		} else if (v instanceof Float) {
+			writeInt32(5);
+			writeFloat32((Float)v);
+		} else if (v instanceof byte[]) {
+			writeInt32(6);
+			writeByteArray((byte[])v);
+		} else if (v instanceof Seq.Object && ((Seq.Object)v).ref().refnum < 0) {
+			writeInt32(7);
+			writeRef(((Seq.Object)v).ref());
+		} else {
+			throw new IllegalArgumentException("cannot pass " + v.getClass().getName() + " to Go as an interface{} value");
+		}
+	}
+
 	public Ref readRef() {
 		int refnum = readInt32();
 		return tracker.get(refnum);
@@ -82,8 +216,9 @@ public class Seq {
 	// recv returns the next request from Go for a Java call.
 	static native void recv(Seq in, Receive params);
 
-	// recvRes sends the result of a Java call back to Go.
-	static native void recvRes(int handle, Seq out);
+	// recvRes sends the result of a Java call back to Go. If the call
+	// threw, exception describes it, and the Go caller panics with it.
+	static native void recvRes(int handle, Seq out, String exception);
 
 	static final class Receive {
 		int refnum;
@@ -115,21 +250,270 @@ public class Seq {
 				// Special signal from seq.FinalizeRef.
 				tracker.dec(refnum);
 				Seq out = new Seq();
-				Seq.recvRes(handle, out);
+				Seq.recvRes(handle, out, null);
 				continue;
 			}
 
 			receivePool.execute(new Runnable() {
 				public void run() {
-					Ref r = tracker.get(refnum);
+					// Every call is answered, so the Go caller
+					// never waits for a call that threw.
 					Seq out = new Seq();
-					r.obj.call(code, in, out);
-					Seq.recvRes(handle, out);
+					String exception = null;
+					try {
+						Ref r = tracker.get(refnum);
+						r.obj.call(code, in, out);
+					} catch (Throwable t) {
+						out = new Seq();
+						exception = t.toString();
+					}
+					Seq.recvRes(handle, out, exception);
 				}
 			});
 		}
 	}
 
+	// withCause returns e, the exception thrown for a Go error, with the
+	// cause read by readErrorCause.
+	public static <T extends Throwable> T withCause(T e, Throwable cause) {
+		if (cause != null) {
+			e.initCause(cause);
+		}
+		return e;
+	}
+
+	// errorMessage returns the message of the Go error returned for an
+	// exception thrown by a Java implementation of a Go interface method.
+	// Exceptions without a message are described by their class, so they
+	// are not mistaken for a nil error.
+	public static String errorMessage(Throwable t) {
+		String msg = t.getMessage();
+		if (msg == null || msg.isEmpty()) {
+			return t.toString();
+		}
+		return msg;
+	}
+
+	// A Cancellable cancels the context.Context passed to the Go functions
+	// it is given to. It may be passed to any number of calls; cancel
+	// cancels all of them, including calls made after cancel.
+	//
+	// A Cancellable is a reference to a Go object, released when the
+	// Cancellable is garbage collected.
+	public static final class Cancellable {
+		private static final String DESCRIPTOR = "go.Cancellable";
+		private static final int CALL_NEW = 1;
+		private static final int CALL_CANCEL = 2;
+
+		private final Ref ref;
+
+		public Cancellable() {
+			Seq _in = new Seq();
+			Seq _out = new Seq();
+			Seq.send(DESCRIPTOR, CALL_NEW, _in, _out);
+			ref = _out.readRef();
+		}
+
+		public void cancel() {
+			Seq _in = new Seq();
+			Seq _out = new Seq();
+			_in.writeRef(ref);
+			Seq.send(DESCRIPTOR, CALL_CANCEL, _in, _out);
+		}
+	}
+
+	// Calls to streams move at most STREAM_CHUNK bytes. The bytes are
+	// followed by a status, and the message of the error for
+	// STREAM_ERROR. They must match the constants of stream.go in Go.
+	private static final String READER_DESCRIPTOR = "go.io.Reader";
+	private static final String WRITER_DESCRIPTOR = "go.io.Writer";
+	private static final int CALL_READ = 1;
+	private static final int CALL_WRITE = 1;
+	private static final int CALL_CLOSE = 2;
+	private static final int STREAM_OK = 0;
+	private static final int STREAM_EOF = 1;
+	private static final int STREAM_ERROR = 2;
+	private static final int STREAM_CHUNK = 64 << 10;
+
+	private static void writeStreamStatus(Seq out, java.io.IOException e) {
+		if (e == null) {
+			out.writeInt32(STREAM_OK);
+			return;
+		}
+		out.writeInt32(STREAM_ERROR);
+		out.writeString(errorMessage(e));
+	}
+
+	// readStreamStatus reports whether the stream is at its end, and
+	// throws the error of a failed call.
+	private static boolean readStreamStatus(Seq out) throws java.io.IOException {
+		switch (out.readInt32()) {
+		case STREAM_OK:
+			return false;
+		case STREAM_EOF:
+			return true;
+		default:
+			throw new java.io.IOException(out.readString());
+		}
+	}
+
+	// A GoInputStream reads a Go io.Reader.
+	static final class GoInputStream extends java.io.InputStream {
+		final Ref ref;
+		private boolean eof;
+
+		GoInputStream(Ref ref) {
+			this.ref = ref;
+		}
+
+		@Override public int read() throws java.io.IOException {
+			byte[] b = new byte[1];
+			return read(b, 0, 1) < 0 ? -1 : b[0] & 0xff;
+		}
+
+		@Override public int read(byte[] b, int off, int len) throws java.io.IOException {
+			if (len == 0) {
+				return 0;
+			}
+			// A Go Reader may return no bytes and no error; InputStreams
+			// block until at least one byte is read.
+			while (!eof) {
+				Seq _in = new Seq();
+				Seq _out = new Seq();
+				_in.writeRef(ref);
+				_in.writeInt32(Math.min(len, STREAM_CHUNK));
+				Seq.send(READER_DESCRIPTOR, CALL_READ, _in, _out);
+				byte[] data = _out.readByteArray();
+				eof = readStreamStatus(_out);
+				if (data != null) {
+					System.arraycopy(data, 0, b, off, data.length);
+					return data.length;
+				}
+			}
+			return -1;
+		}
+
+		@Override public void close() throws java.io.IOException {
+			Seq _in = new Seq();
+			Seq _out = new Seq();
+			_in.writeRef(ref);
+			Seq.send(READER_DESCRIPTOR, CALL_CLOSE, _in, _out);
+			readStreamStatus(_out);
+		}
+	}
+
+	// A GoOutputStream writes to a Go io.Writer.
+	static final class GoOutputStream extends java.io.OutputStream {
+		final Ref ref;
+
+		GoOutputStream(Ref ref) {
+			this.ref = ref;
+		}
+
+		@Override public void write(int b) throws java.io.IOException {
+			write(new byte[]{(byte)b}, 0, 1);
+		}
+
+		@Override public void write(byte[] b, int off, int len) throws java.io.IOException {
+			while (len > 0) {
+				int n = Math.min(len, STREAM_CHUNK);
+				Seq _in = new Seq();
+				Seq _out = new Seq();
+				_in.writeRef(ref);
+				_in.writeByteArray(java.util.Arrays.copyOfRange(b, off, off+n));
+				Seq.send(WRITER_DESCRIPTOR, CALL_WRITE, _in, _out);
+				readStreamStatus(_out);
+				off += n;
+				len -= n;
+			}
+		}
+
+		@Override public void close() throws java.io.IOException {
+			Seq _in = new Seq();
+			Seq _out = new Seq();
+			_in.writeRef(ref);
+			Seq.send(WRITER_DESCRIPTOR, CALL_CLOSE, _in, _out);
+			readStreamStatus(_out);
+		}
+	}
+
+	// An InputStreamStub serves the reads of Go from an InputStream.
+	static final class InputStreamStub implements Seq.Object {
+		final java.io.InputStream stream;
+		private final Ref ref;
+
+		InputStreamStub(java.io.InputStream stream) {
+			this.stream = stream;
+			this.ref = createRef(this);
+		}
+
+		public Ref ref() { return ref; }
+
+		public void call(int code, Seq in, Seq out) {
+			try {
+				switch (code) {
+				case CALL_READ:
+					byte[] b = new byte[Math.min(in.readInt32(), STREAM_CHUNK)];
+					int n = stream.read(b, 0, b.length);
+					if (n < 0) {
+						out.writeByteArray(null);
+						out.writeInt32(STREAM_EOF);
+						return;
+					}
+					out.writeByteArray(java.util.Arrays.copyOf(b, n));
+					break;
+				case CALL_CLOSE:
+					stream.close();
+					break;
+				default:
+					throw new RuntimeException("unknown code: "+ code);
+				}
+			} catch (java.io.IOException e) {
+				if (code == CALL_READ) {
+					out.writeByteArray(null);
+				}
+				writeStreamStatus(out, e);
+				return;
+			}
+			writeStreamStatus(out, null);
+		}
+	}
+
+	// An OutputStreamStub serves the writes of Go to an OutputStream.
+	static final class OutputStreamStub implements Seq.Object {
+		final java.io.OutputStream stream;
+		private final Ref ref;
+
+		OutputStreamStub(java.io.OutputStream stream) {
+			this.stream = stream;
+			this.ref = createRef(this);
+		}
+
+		public Ref ref() { return ref; }
+
+		public void call(int code, Seq in, Seq out) {
+			java.io.IOException err = null;
+			try {
+				switch (code) {
+				case CALL_WRITE:
+					byte[] b = in.readByteArray();
+					if (b != null) {
+						stream.write(b);
+					}
+					break;
+				case CALL_CLOSE:
+					stream.close();
+					break;
+				default:
+					throw new RuntimeException("unknown code: "+ code);
+				}
+			} catch (java.io.IOException e) {
+				err = e;
+			}
+			writeStreamStatus(out, err);
+		}
+	}
+
 	// An Object is a Java object that matches a Go object.
 	// The implementation of the object may be in either Java or Go,
 	// with a proxy instance in the other language passing calls
@@ -163,9 +547,25 @@ public class Seq {
 			tracker.inc(refnum);
 		}
 
+		// Refs are equal if they refer to the same object. Go passes
+		// an object with the same reference number until Java has
+		// released every reference to it.
+		@Override
+		public boolean equals(Object o) {
+			return o instanceof Ref && ((Ref)o).refnum == refnum;
+		}
+
+		@Override
+		public int hashCode() {
+			return refnum;
+		}
+
 		@Override
 		protected void finalize() throws Throwable {
-			tracker.dec(refnum);
+			if (refnum < 0) {
+				// Java objects are released by Go.
+				tracker.dec(refnum);
+			}
 			super.finalize();
 		}
 	}
@@ -197,6 +597,25 @@ public class Seq {
 		// only reference to them is held by Go code.
 		private SparseArray<Ref> javaObjs = new SparseArray<Ref>();
 
+		// Number of references held by Go to a Java object in javaObjs.
+		// Go holds one reference, released by its finalizer, for every
+		// time the object is passed to Go. refnum -> count
+		private SparseIntArray javaRefs = new SparseIntArray();
+
+		// passToGo records that ref is passed to Go. A Java object is
+		// pinned until Go releases every reference to it.
+		synchronized void passToGo(Ref ref) {
+			if (ref.refnum < 0) {
+				return; // Go objects are tracked by Go
+			}
+			int count = javaRefs.get(ref.refnum);
+			if (count == Integer.MAX_VALUE) {
+				throw new RuntimeException("refnum " + ref.refnum + " overflow");
+			}
+			javaObjs.put(ref.refnum, ref);
+			javaRefs.put(ref.refnum, count+1);
+		}
+
 		// inc increments the reference count to a Go object.
 		synchronized void inc(int refnum) {
 			if (refnum > 0) {
@@ -213,8 +632,18 @@ public class Seq {
 		// If the count reaches zero, the Go reference tracker is informed.
 		synchronized void dec(int refnum) {
 			if (refnum > 0) {
-				// Java objects are removed on request of Go.
-				javaObjs.remove(refnum);
+				// Java objects are removed on request of Go, once
+				// Go released all its references.
+				int count = javaRefs.get(refnum);
+				if (count == 0) {
+					throw new RuntimeException("java refnum " + refnum + " underflow");
+				}
+				if (count == 1) {
+					javaRefs.delete(refnum);
+					javaObjs.remove(refnum);
+				} else {
+					javaRefs.put(refnum, count-1);
+				}
 				return;
 			}
 			int count = goObjs.get(refnum);
@@ -236,9 +665,7 @@ public class Seq {
 				throw new RuntimeException("createRef overflow for " + o);
 			}
 			int refnum = next++;
-			Ref ref = new Ref(refnum, o);
-			javaObjs.put(refnum, ref);
-			return ref;
+			return new Ref(refnum, o);
 		}
 
 		// get returns an existing Ref to either a Java or Go object.
@@ -55,6 +55,107 @@ public class SeqTest extends AndroidTestCase {
       fail("expected non-nil error to be turned into an exception");
     } catch (Exception e) {
       assertEquals("messages should match", msg, e.getMessage());
+      assertNull("an error wrapping none has no cause", e.getCause());
+    }
+  }
+
+  public void testWrappedErr() {
+    try {
+      Testpkg.WrappedErr();
+      fail("expected a wrapped error");
+    } catch (Exception e) {
+      assertEquals("load: open config: file not found", e.getMessage());
+      String[] causes = {"open config: file not found", "file not found"};
+      Throwable t = e;
+      for (String msg : causes) {
+        t = t.getCause();
+        assertNotNull("missing cause " + msg, t);
+        assertEquals("cause messages should match", msg, t.getMessage());
+      }
+      assertNull("the chain should end with the CodeError", t.getCause());
+    }
+  }
+
+  public void testCodeErr() {
+    try {
+      Testpkg.CodeErr(404, "not found");
+      fail("expected a CodeError");
+    } catch (Testpkg.CodeError e) {
+      assertEquals("code should match", 404, e.getCode());
+      assertEquals("messages should match", "not found", e.getMessage());
+    } catch (Exception e) {
+      fail("expected a CodeError, got " + e);
+    }
+  }
+
+  public void testCancel() throws InterruptedException {
+    final go.Seq.Cancellable c = new go.Seq.Cancellable();
+    final String[] err = new String[1];
+    Thread t = new Thread(new Runnable() {
+      public void run() {
+        try {
+          Testpkg.WaitDone(c);
+        } catch (Exception e) {
+          err[0] = e.getMessage();
+        }
+      }
+    });
+    t.start();
+    c.cancel();
+    t.join(5000);
+    assertFalse("WaitDone should return after cancel", t.isAlive());
+    assertEquals("context should be cancelled", "context canceled", err[0]);
+  }
+
+  public void testMaps() {
+    java.util.Map<String, Long> counts = new java.util.HashMap<String, Long>();
+    counts.put("one", 1L);
+    counts.put("two", 2L);
+    assertEquals("map[string]int should round trip", counts, Testpkg.MapStringInt(counts));
+
+    java.util.Map<Long, String> names = new java.util.HashMap<Long, String>();
+    names.put(1L, "one");
+    names.put(-2L, "");
+    assertEquals("map[int]string should round trip", names, Testpkg.MapIntString(names));
+
+    assertTrue("empty map should round trip", Testpkg.MapIntString(new java.util.HashMap<Long, String>()).isEmpty());
+    assertNull("nil map should be null", Testpkg.MapStringInt(null));
+  }
+
+  public void testVariadic() {
+    assertEquals("Sum()", 0, Testpkg.Sum());
+    assertEquals("Sum(1, 2, 3)", 6, Testpkg.Sum(1, 2, 3));
+    assertEquals("Sum(array)", 10, Testpkg.Sum(new long[]{4, 6}));
+    assertEquals("Sprint", "a 1 2 0.5 <nil>", Testpkg.Sprint("a", " ", 1L, " ", 2, " ", 0.5, " ", null));
+    try {
+      Testpkg.Sprint(new java.util.ArrayList<String>());
+      fail("Sprint of an ArrayList should throw");
+    } catch (IllegalArgumentException e) {
+      // expected
+    }
+  }
+
+  public void testTime() {
+    java.util.Date want = new java.util.Date(1433161815123L);
+    assertEquals("Go should see the Java time", 1433161815123L, Testpkg.UnixMillis(want));
+    java.util.Date got = Testpkg.AddMillis(want, 1000);
+    assertEquals("Java should see the Go time", want.getTime() + 1000, got.getTime());
+  }
+
+  public void testZeroTime() {
+    assertNull("zero Go time should be null", Testpkg.ZeroTime());
+    assertEquals("null should be the zero Go time", -1, Testpkg.UnixMillis(null));
+  }
+
+  public void testEnum() {
+    assertEquals("Opposite(North)", Testpkg.Direction.South, Testpkg.Opposite(Testpkg.Direction.North));
+    assertEquals("Opposite(West)", Testpkg.Direction.East, Testpkg.Opposite(Testpkg.Direction.West));
+    assertEquals("West value", 3, Testpkg.Direction.West.value());
+    try {
+      Testpkg.BadDirection();
+      fail("BadDirection should throw");
+    } catch (IllegalArgumentException e) {
+      // expected
     }
   }
 
@@ -117,6 +218,25 @@ public class SeqTest extends AndroidTestCase {
     assertEquals("S should be collected", 1, collected);
   }
 
+  public void testEquals() {
+    Testpkg.S s = Testpkg.New();
+    Testpkg.S same = Testpkg.Same(s);
+    assertNotSame("Same(s) is a new Java object", s, same);
+    assertTrue("s.equals(Same(s))", s.equals(same));
+    assertEquals("Same(s).hashCode()", s.hashCode(), same.hashCode());
+    java.util.Set<Testpkg.S> set = new java.util.HashSet<Testpkg.S>();
+    set.add(s);
+    assertTrue("set of s contains Same(s)", set.contains(same));
+
+    Testpkg.S other = Testpkg.New();
+    assertEquals("String of another S", s.String(), other.String());
+    assertFalse("s.equals(New())", s.equals(other));
+    assertFalse("s.equals(null)", s.equals(null));
+
+    // S is a fmt.Stringer.
+    assertEquals("s.toString()", "new", s.toString());
+  }
+
   boolean finalizedAnI;
 
   private class AnI extends Testpkg.I.Stub {
@@ -159,6 +279,54 @@ public class SeqTest extends AndroidTestCase {
   }
   // TODO(hyangah): add tests for methods that take parameters.
 
+  // PlainI implements Testpkg.I without extending Testpkg.I.Stub.
+  private class PlainI implements Testpkg.I {
+    boolean calledF;
+    public void F() {
+      calledF = true;
+    }
+
+    public void E() throws Exception {
+      throw new Exception("my exception from PlainI.E");
+    }
+
+    public Testpkg.I I() {
+      return this;
+    }
+
+    public Testpkg.S S() {
+      return Testpkg.New();
+    }
+
+    public long V() {
+      return 5678;
+    }
+
+    public long VE() throws Exception {
+      return 91;
+    }
+
+    public String String() {
+      return "PlainI";
+    }
+  }
+
+  public void testPlainInterfaceImplementation() throws Exception {
+    PlainI obj = new PlainI();
+    Testpkg.CallF(obj);
+    assertTrue("Want PlainI.F to be called", obj.calledF);
+    assertEquals("Values must match", 5678, Testpkg.CallV(obj));
+    assertEquals("Values must match", 91, Testpkg.CallVE(obj));
+    try {
+      Testpkg.CallE(obj);
+      fail("Expecting exception but none was thrown.");
+    } catch (Exception e) {
+      assertEquals("Error messages should match", "my exception from PlainI.E", e.getMessage());
+    }
+    Testpkg.I i = Testpkg.CallI(obj);
+    assertEquals("Want PlainI.I to return itself", "PlainI", i.String());
+  }
+
   public void testInterfaceMethodReturnsError() {
     final AnI obj = new AnI();
     try {
@@ -224,6 +392,215 @@ public class SeqTest extends AndroidTestCase {
     assertFalse("want obj to be kept live by Go", finalizedAnI);
   }
 
+  public void testJavaRefKeepAfterCall() {
+    AnI obj = new AnI();
+    Testpkg.Keep(obj);
+    Testpkg.CallF(obj);
+    // The reference of the CallF call is released, but not the one
+    // kept by Go.
+    runGC();
+    obj.calledF = false;
+    Testpkg.CallKept();
+    assertTrue("want kept obj to be called", obj.calledF);
+  }
+
+  private class ThrowingI extends AnI {
+    @Override
+    public void F() {
+      throw new IllegalStateException("boom");
+    }
+
+    @Override
+    public void E() throws Exception {
+      throw new Exception();
+    }
+  }
+
+  public void testCallbackThrows() {
+    ThrowingI obj = new ThrowingI();
+    assertEquals("Go should recover the exception", "java.lang.IllegalStateException: boom", Testpkg.CallFRecover(obj));
+    assertEquals("Go should recover nothing", "", Testpkg.CallFRecover(new AnI()));
+    try {
+      Testpkg.CallF(obj);
+      fail("unrecovered exception should be thrown to Java");
+    } catch (RuntimeException e) {
+      assertEquals("java.lang.IllegalStateException: boom", e.getMessage());
+    }
+    try {
+      Testpkg.CallE(obj);
+      fail("exception without a message should be a Go error");
+    } catch (Exception e) {
+      assertEquals("java.lang.Exception", e.getMessage());
+    }
+  }
+
+  public void testGoPanic() {
+    try {
+      Testpkg.Panic("oops");
+      fail("Go panic should throw");
+    } catch (RuntimeException e) {
+      assertEquals("panic: oops", e.getMessage());
+    }
+  }
+
+  public void testMultipleResults() throws Exception {
+    Testpkg.DivModResult r = Testpkg.DivMod(7, 2);
+    assertEquals("DivMod(7, 2).quo", 3, r.quo);
+    assertEquals("DivMod(7, 2).rem", 1, r.rem);
+    try {
+      Testpkg.DivMod(1, 0);
+      fail("DivMod(1, 0) should throw");
+    } catch (Exception e) {
+      assertEquals("division by zero", e.getMessage());
+    }
+  }
+
+  public void testCommaOK() {
+    assertEquals("Half(42)", Long.valueOf(21), Testpkg.Half(42));
+    assertNull("Half(3)", Testpkg.Half(3));
+  }
+
+  public void testInitOnce() throws InterruptedException {
+    final String[] err = new String[8];
+    Thread[] threads = new Thread[err.length];
+    for (int i = 0; i < threads.length; i++) {
+      final int n = i;
+      threads[i] = new Thread(new Runnable() {
+        public void run() {
+          try {
+            Testpkg.Init("key" + n);
+          } catch (Exception e) {
+            err[n] = e.getMessage();
+          }
+        }
+      });
+      threads[i].start();
+    }
+    for (Thread t : threads) {
+      t.join(5000);
+      assertFalse("Init should return", t.isAlive());
+    }
+    for (String e : err) {
+      assertNull("Init should succeed", e);
+    }
+    assertEquals("Init should run once", 1, Testpkg.InitCount());
+    try {
+      Testpkg.Init("");
+    } catch (Exception e) {
+      fail("a later Init should not run: " + e);
+    }
+    assertEquals("Init should run once", 1, Testpkg.InitCount());
+  }
+
+  public void testClosure() {
+    Testpkg.Func_int_To_int add2 = Testpkg.NewAdder(2);
+    assertEquals("add2(3)", 5, add2.call(3));
+    assertEquals("add2(-2)", 0, add2.call(-2));
+    assertEquals("NewAdder(40).call(2)", 42, Testpkg.NewAdder(40).call(2));
+  }
+
+  public void testSliceOfStructs() {
+    java.util.List<Testpkg.Point> line = Testpkg.Line(3);
+    assertEquals("Line(3).size()", 3, line.size());
+    for (int i = 0; i < line.size(); i++) {
+      assertEquals("Line(3)[" + i + "].X", i, line.get(i).getX());
+    }
+    Testpkg.Point sum = Testpkg.SumPoints(line);
+    assertEquals("SumPoints(Line(3)).X", 3, sum.getX());
+    assertEquals("SumPoints(Line(3)).Y", 3, sum.getY());
+
+    // The elements are copies: changing them does not change the slice.
+    line.get(0).setX(10);
+    assertEquals("SumPoints after set", 13, Testpkg.SumPoints(line).getX());
+    assertEquals("Line(3)[0].X", 0, Testpkg.Line(3).get(0).getX());
+
+    assertNull("Line(-1)", Testpkg.Line(-1));
+    assertEquals("Line(0).size()", 0, Testpkg.Line(0).size());
+    assertTrue("IsNilPoints(null)", Testpkg.IsNilPoints(null));
+    assertFalse("IsNilPoints(empty)", Testpkg.IsNilPoints(new java.util.ArrayList<Testpkg.Point>()));
+  }
+
+  public void testPointerReceiver() {
+    Testpkg.Counter c = Testpkg.NewCounter();
+    c.Inc();
+    c.Inc();
+    assertEquals("CounterN after two Inc", 2, Testpkg.CounterN(c));
+    assertEquals("Value after two Inc", 2, c.Value());
+    assertEquals("N after two Inc", 2, c.getN());
+
+    c.setN(10);
+    assertEquals("CounterN after setN(10)", 10, Testpkg.CounterN(c));
+  }
+
+  public void testValueInInterface() {
+    Testpkg.Valuer v = Testpkg.NewValuer(7);
+    assertEquals("NewValuer(7).Value()", 7, v.Value());
+    // Struct values are copied as they cross: each is a distinct object.
+    assertFalse("equal values are distinct objects", v.equals(Testpkg.NewValuer(7)));
+  }
+
+  public void testVar() {
+    Testpkg.setCount(21);
+    assertEquals("getCount()", 21, Testpkg.getCount());
+    assertEquals("CountTwice()", 42, Testpkg.CountTwice());
+    Testpkg.setCount(0);
+    assertEquals("getCount() after reset", 0, Testpkg.getCount());
+  }
+
+  public void testArray() {
+    float[] got = Testpkg.ScaleVec(new float[]{1, 2, 3}, 2);
+    assertEquals("ScaleVec length", 3, got.length);
+    assertEquals("ScaleVec[0]", 2f, got[0]);
+    assertEquals("ScaleVec[1]", 4f, got[1]);
+    assertEquals("ScaleVec[2]", 6f, got[2]);
+    try {
+      Testpkg.ScaleVec(new float[]{1, 2}, 2);
+      fail("ScaleVec of a length 2 array should throw");
+    } catch (IllegalArgumentException e) {
+      // expected
+    }
+  }
+
+  public void testReader() throws java.io.IOException {
+    java.io.InputStream in = Testpkg.Counting(5000);
+    byte[] b = new byte[512];
+    int total = 0;
+    int n;
+    while ((n = in.read(b)) >= 0) {
+      for (int i = 0; i < n; i++) {
+        assertEquals("byte " + (total+i), (byte)(total+i), b[i]);
+      }
+      total += n;
+    }
+    assertEquals("bytes read", 5000, total);
+    in.close();
+  }
+
+  public void testWriter() throws Exception {
+    byte[] data = new byte[5000];
+    for (int i = 0; i < data.length; i++) {
+      data[i] = (byte)(i*7);
+    }
+    java.io.ByteArrayOutputStream out = new java.io.ByteArrayOutputStream();
+    long n = Testpkg.CopyChunks(out, new java.io.ByteArrayInputStream(data));
+    assertEquals("bytes copied", 5000, n);
+    assertTrue("bytes written should match", java.util.Arrays.equals(data, out.toByteArray()));
+  }
+
+  public void testGoStreams() throws Exception {
+    // Copy between two Go streams, read and written from Java.
+    java.io.InputStream in = Testpkg.Counting(3000);
+    java.io.ByteArrayOutputStream out = new java.io.ByteArrayOutputStream();
+    byte[] b = new byte[700];
+    int n;
+    while ((n = in.read(b)) >= 0) {
+      out.write(b, 0, n);
+    }
+    assertEquals("bytes read", 3000, out.size());
+    assertEquals("CopyChunks of a Go stream", 3000, Testpkg.CopyChunks(out, Testpkg.Counting(3000)));
+    assertEquals("bytes written", 6000, out.size());
+  }
+
   private void runGC() {
     System.gc();
     System.runFinalization();
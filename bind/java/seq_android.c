@@ -7,6 +7,7 @@
 #include <jni.h>
 #include <stdint.h>
 #include <stdio.h>
+#include <stdlib.h>
 #include <unistd.h>
 #include "seq_android.h"
 #include "_cgo_export.h"
@@ -14,6 +15,21 @@
 #define LOG_INFO(...) __android_log_print(ANDROID_LOG_INFO, "go/Seq", __VA_ARGS__)
 #define LOG_FATAL(...) __android_log_print(ANDROID_LOG_FATAL, "go/Seq", __VA_ARGS__)
 
+// The Seq class is in the Java package go, unless gomobile bind
+// -supportpkg relocates it. The package is then defined by
+// GOMOBILE_SUPPORT_PKG, mangled as in JNI function names, and
+// GOMOBILE_SUPPORT_PATH, a string of its path, as com/example/go.
+#ifndef GOMOBILE_SUPPORT_PKG
+#define GOMOBILE_SUPPORT_PKG go
+#define GOMOBILE_SUPPORT_PATH "go"
+#endif
+
+#define JNI_NAME(pkg, name) Java_ ## pkg ## _Seq_ ## name
+#define SEQ_JNI(pkg, name) JNI_NAME(pkg, name)
+// SEQ(name) is the name of the JNI function of the native method name.
+#define SEQ(name) SEQ_JNI(GOMOBILE_SUPPORT_PKG, name)
+#define SEQ_CLASS GOMOBILE_SUPPORT_PATH "/Seq"
+
 static jfieldID memptr_id;
 static jfieldID receive_refnum_id;
 static jfieldID receive_code_id;
@@ -197,10 +213,10 @@ void init_seq(void *javavm) {
 		LOG_FATAL("bad vm env: %d", res);
 	}
 
-	memptr_id = find_field(env, "go/Seq", "memptr", "J");
-	receive_refnum_id = find_field(env, "go/Seq$Receive", "refnum", "I");
-	receive_handle_id = find_field(env, "go/Seq$Receive", "handle", "I");
-	receive_code_id = find_field(env, "go/Seq$Receive", "code", "I");
+	memptr_id = find_field(env, SEQ_CLASS, "memptr", "J");
+	receive_refnum_id = find_field(env, SEQ_CLASS "$Receive", "refnum", "I");
+	receive_handle_id = find_field(env, SEQ_CLASS "$Receive", "handle", "I");
+	receive_code_id = find_field(env, SEQ_CLASS "$Receive", "code", "I");
 
 	jbytearray_clazz = find_class(env, "[B");
 
@@ -212,7 +228,7 @@ void init_seq(void *javavm) {
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_ensure(JNIEnv *env, jobject obj, jint size) {
+SEQ(ensure)(JNIEnv *env, jobject obj, jint size) {
 	mem *m = mem_get(env, obj);
 	if (m == NULL || m->off+size > m->cap) {
 		m = mem_ensure(m, size);
@@ -221,7 +237,7 @@ Java_go_Seq_ensure(JNIEnv *env, jobject obj, jint size) {
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_free(JNIEnv *env, jobject obj) {
+SEQ(free)(JNIEnv *env, jobject obj) {
 	mem *m = mem_get(env, obj);
 	if (m != NULL) {
 		unpin_arrays(env, m);
@@ -233,7 +249,7 @@ Java_go_Seq_free(JNIEnv *env, jobject obj) {
 #define MEM_READ(obj, ty) ((ty*)mem_read(env, obj, sizeof(ty), sizeof(ty)))
 
 JNIEXPORT jbyte JNICALL
-Java_go_Seq_readInt8(JNIEnv *env, jobject obj) {
+SEQ(readInt8)(JNIEnv *env, jobject obj) {
 	uint8_t *v = MEM_READ(obj, uint8_t);
 	if (v == NULL) {
 		return 0;
@@ -242,37 +258,37 @@ Java_go_Seq_readInt8(JNIEnv *env, jobject obj) {
 }
 
 JNIEXPORT jshort JNICALL
-Java_go_Seq_readInt16(JNIEnv *env, jobject obj) {
+SEQ(readInt16)(JNIEnv *env, jobject obj) {
 	int16_t *v = MEM_READ(obj, int16_t);
 	return v == NULL ? 0 : *v;
 }
 
 JNIEXPORT jint JNICALL
-Java_go_Seq_readInt32(JNIEnv *env, jobject obj) {
+SEQ(readInt32)(JNIEnv *env, jobject obj) {
 	int32_t *v = MEM_READ(obj, int32_t);
 	return v == NULL ? 0 : *v;
 }
 
 JNIEXPORT jlong JNICALL
-Java_go_Seq_readInt64(JNIEnv *env, jobject obj) {
+SEQ(readInt64)(JNIEnv *env, jobject obj) {
 	int64_t *v = MEM_READ(obj, int64_t);
 	return v == NULL ? 0 : *v;
 }
 
 JNIEXPORT jfloat JNICALL
-Java_go_Seq_readFloat32(JNIEnv *env, jobject obj) {
+SEQ(readFloat32)(JNIEnv *env, jobject obj) {
 	float *v = MEM_READ(obj, float);
 	return v == NULL ? 0 : *v;
 }
 
 JNIEXPORT jdouble JNICALL
-Java_go_Seq_readFloat64(JNIEnv *env, jobject obj) {
+SEQ(readFloat64)(JNIEnv *env, jobject obj) {
 	double *v = MEM_READ(obj, double);
 	return v == NULL ? 0 : *v;
 }
 
 JNIEXPORT jstring JNICALL
-Java_go_Seq_readUTF16(JNIEnv *env, jobject obj) {
+SEQ(readUTF16)(JNIEnv *env, jobject obj) {
 	int32_t size = *MEM_READ(obj, int32_t);
 	if (size == 0) {
 		return NULL;
@@ -281,15 +297,15 @@ Java_go_Seq_readUTF16(JNIEnv *env, jobject obj) {
 }
 
 JNIEXPORT jbyteArray JNICALL
-Java_go_Seq_readByteArray(JNIEnv *env, jobject obj) {
+SEQ(readByteArray)(JNIEnv *env, jobject obj) {
 	// Send the (array length, pointer) pair encoded as two int64.
 	// The pointer value is omitted if array length is 0.
-	jlong size = Java_go_Seq_readInt64(env, obj);
+	jlong size = SEQ(readInt64)(env, obj);
 	if (size == 0) {
 		return NULL;
 	}
 	jbyteArray res = (*env)->NewByteArray(env, size);
-	jlong ptr = Java_go_Seq_readInt64(env, obj);
+	jlong ptr = SEQ(readInt64)(env, obj);
 	(*env)->SetByteArrayRegion(env, res, 0, size, (jbyte*)(intptr_t)(ptr));
 	return res;
 }
@@ -297,37 +313,37 @@ Java_go_Seq_readByteArray(JNIEnv *env, jobject obj) {
 #define MEM_WRITE(ty) (*(ty*)mem_write(env, obj, sizeof(ty), sizeof(ty)))
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeInt8(JNIEnv *env, jobject obj, jbyte v) {
+SEQ(writeInt8)(JNIEnv *env, jobject obj, jbyte v) {
 	MEM_WRITE(int8_t) = v;
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeInt16(JNIEnv *env, jobject obj, jshort v) {
+SEQ(writeInt16)(JNIEnv *env, jobject obj, jshort v) {
 	MEM_WRITE(int16_t) = v;
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeInt32(JNIEnv *env, jobject obj, jint v) {
+SEQ(writeInt32)(JNIEnv *env, jobject obj, jint v) {
 	MEM_WRITE(int32_t) = v;
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeInt64(JNIEnv *env, jobject obj, jlong v) {
+SEQ(writeInt64)(JNIEnv *env, jobject obj, jlong v) {
 	MEM_WRITE(int64_t) = v;
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeFloat32(JNIEnv *env, jobject obj, jfloat v) {
+SEQ(writeFloat32)(JNIEnv *env, jobject obj, jfloat v) {
 	MEM_WRITE(float) = v;
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeFloat64(JNIEnv *env, jobject obj, jdouble v) {
+SEQ(writeFloat64)(JNIEnv *env, jobject obj, jdouble v) {
 	MEM_WRITE(double) = v;
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeUTF16(JNIEnv *env, jobject obj, jstring v) {
+SEQ(writeUTF16)(JNIEnv *env, jobject obj, jstring v) {
 	if (v == NULL) {
 		MEM_WRITE(int32_t) = 0;
 		return;
@@ -338,7 +354,7 @@ Java_go_Seq_writeUTF16(JNIEnv *env, jobject obj, jstring v) {
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_writeByteArray(JNIEnv *env, jobject obj, jbyteArray v) {
+SEQ(writeByteArray)(JNIEnv *env, jobject obj, jbyteArray v) {
 	// For Byte array, we pass only the (array length, pointer) pair
 	// encoded as two int64 values. If the array length is 0,
 	// the pointer value is omitted.
@@ -358,7 +374,23 @@ Java_go_Seq_writeByteArray(JNIEnv *env, jobject obj, jbyteArray v) {
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_resetOffset(JNIEnv *env, jobject obj) {
+SEQ(writeDirectBuffer)(JNIEnv *env, jobject obj, jobject v, jint off, jint len) {
+	// Like a byte array, a direct buffer is passed as the (length,
+	// pointer) pair. Its memory does not move, so it needs no pinning
+	// and Go reads it in place.
+	MEM_WRITE(int64_t) = len;
+	if (len == 0) {
+		return;
+	}
+	uint8_t *b = (*env)->GetDirectBufferAddress(env, v);
+	if (b == NULL) {
+		LOG_FATAL("writeDirectBuffer: not a direct buffer");
+	}
+	MEM_WRITE(int64_t) = (jlong)(uintptr_t)(b+off);
+}
+
+JNIEXPORT void JNICALL
+SEQ(resetOffset)(JNIEnv *env, jobject obj) {
 	mem *m = mem_get(env, obj);
 	if (m == NULL) {
 		LOG_FATAL("resetOffset on NULL mem");
@@ -367,7 +399,7 @@ Java_go_Seq_resetOffset(JNIEnv *env, jobject obj) {
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_log(JNIEnv *env, jobject obj, jstring v) {
+SEQ(log)(JNIEnv *env, jobject obj, jstring v) {
 	mem *m = mem_get(env, obj);
 	const char *label = (*env)->GetStringUTFChars(env, v, NULL);
 	if (label == NULL) {
@@ -382,12 +414,12 @@ Java_go_Seq_log(JNIEnv *env, jobject obj, jstring v) {
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_destroyRef(JNIEnv *env, jclass clazz, jint refnum) {
+SEQ(destroyRef)(JNIEnv *env, jclass clazz, jint refnum) {
 	DestroyRef(refnum);
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_send(JNIEnv *env, jclass clazz, jstring descriptor, jint code, jobject src_obj, jobject dst_obj) {
+SEQ(send)(JNIEnv *env, jclass clazz, jstring descriptor, jint code, jobject src_obj, jobject dst_obj) {
 	mem *src = mem_get(env, src_obj);
 	if (src == NULL) {
 		LOG_FATAL("send src is NULL");
@@ -403,13 +435,19 @@ Java_go_Seq_send(JNIEnv *env, jclass clazz, jstring descriptor, jint code, jobje
 		LOG_FATAL("send GetStringUTFChars failed");
 	}
 	desc.n = (*env)->GetStringUTFLength(env, descriptor);
-	Send(desc, (GoInt)code, src->buf, src->len, &dst->buf, &dst->len);
+	char *exc = Send(desc, (GoInt)code, src->buf, src->len, &dst->buf, &dst->len);
 	(*env)->ReleaseStringUTFChars(env, descriptor, desc.p);
 	unpin_arrays(env, src);  // assume 'src' is no longer needed.
+	if (exc != NULL) {
+		// The Go function panicked.
+		jclass exc_class = (*env)->FindClass(env, "java/lang/RuntimeException");
+		(*env)->ThrowNew(env, exc_class, exc);
+		free(exc);
+	}
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_recv(JNIEnv *env, jclass clazz, jobject in_obj, jobject receive) {
+SEQ(recv)(JNIEnv *env, jclass clazz, jobject in_obj, jobject receive) {
 	mem *in = mem_get(env, in_obj);
 	if (in == NULL) {
 		LOG_FATAL("recv in is NULL");
@@ -421,10 +459,19 @@ Java_go_Seq_recv(JNIEnv *env, jclass clazz, jobject in_obj, jobject receive) {
 }
 
 JNIEXPORT void JNICALL
-Java_go_Seq_recvRes(JNIEnv *env, jclass clazz, jint handle, jobject out_obj) {
+SEQ(recvRes)(JNIEnv *env, jclass clazz, jint handle, jobject out_obj, jstring exception) {
 	mem *out = mem_get(env, out_obj);
 	if (out == NULL) {
 		LOG_FATAL("recvRes out is NULL");
 	}
-	RecvRes((int32_t)handle, out->buf, out->len);
+	if (exception == NULL) {
+		RecvRes((int32_t)handle, out->buf, out->len, NULL);
+		return;
+	}
+	const char *exc = (*env)->GetStringUTFChars(env, exception, NULL);
+	if (exc == NULL) {
+		LOG_FATAL("recvRes GetStringUTFChars failed");
+	}
+	RecvRes((int32_t)handle, out->buf, out->len, (char*)exc);
+	(*env)->ReleaseStringUTFChars(env, exception, exc);
 }
@@ -7,6 +7,7 @@ package java // import "golang.org/x/mobile/bind/java"
 //#cgo LDFLAGS: -llog
 //#include <android/log.h>
 //#include <stdint.h>
+//#include <stdlib.h>
 //#include <string.h>
 //#include "seq_android.h"
 import "C"
@@ -24,8 +25,15 @@ const maxSliceLen = 1<<31 - 1
 const debug = false
 
 // Send is called by Java to send a request to run a Go function.
+// If the function panics, Send returns the description of the panic,
+// allocated with malloc, and Java throws it as a RuntimeException.
 //export Send
-func Send(descriptor string, code int, req *C.uint8_t, reqlen C.size_t, res **C.uint8_t, reslen *C.size_t) {
+func Send(descriptor string, code int, req *C.uint8_t, reqlen C.size_t, res **C.uint8_t, reslen *C.size_t) (exc *C.char) {
+	defer func() {
+		if p := recover(); p != nil {
+			exc = C.CString(panicMessage(p))
+		}
+	}()
 	fn := seq.Registry[descriptor][code]
 	if fn == nil {
 		panic(fmt.Sprintf("invalid descriptor(%s) and code(0x%x)", descriptor, code))
@@ -42,6 +50,16 @@ func Send(descriptor string, code int, req *C.uint8_t, reqlen C.size_t, res **C.
 	// Without pinning support from Go side, it will be hard to fix it without extra copying.
 
 	seqToBuf(res, reslen, out)
+	return nil
+}
+
+// panicMessage describes the panic value p for the exception thrown in
+// Java. Exceptions thrown by Java callbacks keep their description.
+func panicMessage(p interface{}) string {
+	if e, ok := p.(*seq.Exception); ok {
+		return e.Message
+	}
+	return fmt.Sprintf("panic: %v", p)
 }
 
 // DestroyRef is called by Java to inform Go it is done with a reference.
@@ -64,10 +82,16 @@ var recv struct {
 	next int32 // next handle value
 }
 
+// A response is the result of a Java call made by transact.
+type response struct {
+	out *seq.Buffer
+	exc *seq.Exception // the exception thrown by the call, or nil
+}
+
 var res struct {
 	sync.Mutex
-	cond sync.Cond             // signals a response is filled in
-	out  map[int32]*seq.Buffer // handle -> output
+	cond sync.Cond          // signals a response is filled in
+	out  map[int32]response // handle -> output
 }
 
 func init() {
@@ -75,7 +99,7 @@ func init() {
 	recv.next = 411 // arbitrary starting point distinct from Go and Java obj ref nums
 
 	res.cond.L = &res.Mutex
-	res.out = make(map[int32]*seq.Buffer)
+	res.out = make(map[int32]response)
 }
 
 func initSeq() {
@@ -124,21 +148,28 @@ func Recv(in **C.uint8_t, inlen *C.size_t) (ref, code, handle C.int32_t) {
 }
 
 // RecvRes is called by JNI to return the result of a requested callback.
+// If the callback threw an exception, exc describes it; otherwise it is
+// NULL.
 //export RecvRes
-func RecvRes(handle C.int32_t, out *C.uint8_t, outlen C.size_t) {
+func RecvRes(handle C.int32_t, out *C.uint8_t, outlen C.size_t, exc *C.char) {
 	outBuf := &seq.Buffer{
 		Data: make([]byte, outlen),
 	}
 	copy(outBuf.Data, (*[maxSliceLen]byte)(unsafe.Pointer(out))[:outlen])
+	r := response{out: outBuf}
+	if exc != nil {
+		r.exc = &seq.Exception{Message: C.GoString(exc)}
+	}
 
 	res.Lock()
-	res.out[int32(handle)] = outBuf
+	res.out[int32(handle)] = r
 	res.Unlock()
 	res.cond.Broadcast()
 }
 
 // transact calls a method on a Java object instance.
-// It blocks until the call is complete.
+// It blocks until the call is complete, and panics with the exception
+// thrown by the method, if any.
 func transact(ref *seq.Ref, code int, in *seq.Buffer) *seq.Buffer {
 	recv.Lock()
 	if recv.next == 1<<31-1 {
@@ -156,14 +187,18 @@ func transact(ref *seq.Ref, code int, in *seq.Buffer) *seq.Buffer {
 	recv.cond.Signal()
 
 	res.Lock()
-	for res.out[handle] == nil {
+	r, ok := res.out[handle]
+	for !ok {
 		res.cond.Wait()
+		r, ok = res.out[handle]
 	}
-	out := res.out[handle]
 	delete(res.out, handle)
 	res.Unlock()
 
-	return out
+	if r.exc != nil {
+		panic(r.exc)
+	}
+	return r.out
 }
 
 func encodeString(out *seq.Buffer, v string) {
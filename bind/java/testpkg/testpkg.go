@@ -9,9 +9,13 @@ package testpkg
 //go:generate gobind -lang=go -outdir=go_testpkg .
 //go:generate gobind -lang=java -outdir=. .
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"runtime"
+	"sync/atomic"
 	"time"
 )
 
@@ -59,6 +63,63 @@ func Keep(i I) {
 
 var numSCollected int
 
+// A Counter is changed from Java by its pointer-receiver methods.
+type Counter struct {
+	N int
+}
+
+func NewCounter() *Counter {
+	return new(Counter)
+}
+
+func (c *Counter) Inc() {
+	c.N++
+}
+
+// Value has a value receiver, and is called on a copy of the counter.
+func (c Counter) Value() int {
+	return c.N
+}
+
+// CounterN returns the count of c, as seen by Go.
+func CounterN(c *Counter) int {
+	return c.N
+}
+
+// A Valuer is implemented by the struct value Fixed.
+type Valuer interface {
+	Value() int
+}
+
+type Fixed struct {
+	V int
+}
+
+func (f Fixed) Value() int {
+	return f.V
+}
+
+// NewValuer returns the struct value Fixed{v} in a Valuer.
+func NewValuer(v int) Valuer {
+	return Fixed{v}
+}
+
+// Count is read and written from Java with getCount and setCount.
+var Count int
+
+// CountTwice returns twice the value of Count.
+func CountTwice() int {
+	return 2 * Count
+}
+
+// ScaleVec returns v scaled by k.
+func ScaleVec(v [3]float32, k float32) [3]float32 {
+	for i := range v {
+		v[i] *= k
+	}
+	return v
+}
+
 type S struct {
 	// *S already has a finalizer, so we need another object
 	// to count successful collections.
@@ -85,6 +146,11 @@ func New() *S {
 	return s
 }
 
+// Same returns s, to pass a second reference to it to Java.
+func Same(s *S) *S {
+	return s
+}
+
 func GC() {
 	runtime.GC()
 	time.Sleep(10 * time.Millisecond)
@@ -119,3 +185,195 @@ func AppendToString(str string, someBytes []byte) []byte {
 	fmt.Printf("str=%q (len=%d), someBytes=%v (len=%d)\n", str, len(str), someBytes, len(someBytes))
 	return append(a, someBytes...)
 }
+
+func AddMillis(t time.Time, ms int) time.Time {
+	return t.Add(time.Duration(ms) * time.Millisecond)
+}
+
+func UnixMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return -1
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func ZeroTime() time.Time {
+	return time.Time{}
+}
+
+type Direction int
+
+const (
+	North Direction = iota
+	East
+	South
+	West
+)
+
+func Opposite(d Direction) Direction {
+	return (d + 2) % 4
+}
+
+// BadDirection returns a Direction that is not a constant.
+func BadDirection() Direction {
+	return 7
+}
+
+// CodeError is an error with a code, thrown as a Java exception.
+type CodeError struct {
+	Code int
+	Msg  string
+}
+
+func (e *CodeError) Error() string {
+	return e.Msg
+}
+
+func CodeErr(code int, msg string) error {
+	return &CodeError{Code: code, Msg: msg}
+}
+
+// wrapError is an error wrapping another, as made by fmt.Errorf with %w.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *wrapError) Unwrap() error { return e.err }
+
+// WrappedErr returns an error wrapping an error that wraps a CodeError.
+func WrappedErr() error {
+	return &wrapError{"load", &wrapError{"open config", &CodeError{Code: 2, Msg: "file not found"}}}
+}
+
+func MapStringInt(m map[string]int) map[string]int {
+	return m
+}
+
+func MapIntString(m map[int]string) map[int]string {
+	return m
+}
+
+// WaitDone blocks until ctx is cancelled.
+func WaitDone(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func Sum(xs ...int) int {
+	s := 0
+	for _, x := range xs {
+		s += x
+	}
+	return s
+}
+
+func Sprint(args ...interface{}) string {
+	return fmt.Sprint(args...)
+}
+
+func DivMod(a, b int) (quo, rem int, err error) {
+	if b == 0 {
+		return 0, 0, errors.New("division by zero")
+	}
+	return a / b, a % b, nil
+}
+
+// Half returns half of n, if n is even.
+func Half(n int) (int, bool) {
+	if n%2 != 0 {
+		return 0, false
+	}
+	return n / 2, true
+}
+
+var initCount int32
+
+// Init is the init function of the package: the bindings call it once,
+// however many times Java calls it.
+func Init(key string) error {
+	atomic.AddInt32(&initCount, 1)
+	time.Sleep(10 * time.Millisecond) // let the concurrent calls overlap
+	if key == "" {
+		return errors.New("no key")
+	}
+	return nil
+}
+
+// InitCount returns the number of calls to Init that ran.
+func InitCount() int {
+	return int(atomic.LoadInt32(&initCount))
+}
+
+func NewAdder(n int) func(int) int {
+	return func(x int) int { return x + n }
+}
+
+// Point is passed by value in slices.
+type Point struct {
+	X, Y int
+}
+
+// Line returns n points on the diagonal, or nil if n is negative.
+func Line(n int) []Point {
+	if n < 0 {
+		return nil
+	}
+	ps := make([]Point, n)
+	for i := range ps {
+		ps[i] = Point{X: i, Y: i}
+	}
+	return ps
+}
+
+// SumPoints returns the sum of the points.
+func SumPoints(ps []Point) *Point {
+	sum := new(Point)
+	for _, p := range ps {
+		sum.X += p.X
+		sum.Y += p.Y
+	}
+	return sum
+}
+
+func IsNilPoints(ps []Point) bool {
+	return ps == nil
+}
+
+// CallFRecover calls i.F and returns the description of the exception
+// it throws, if any.
+func CallFRecover(i I) (exc string) {
+	defer func() {
+		if err, ok := recover().(error); ok {
+			exc = err.Error()
+		}
+	}()
+	i.F()
+	return ""
+}
+
+func Panic(msg string) {
+	panic(msg)
+}
+
+// CallKept calls the F method of the objects passed to Keep.
+func CallKept() {
+	for _, i := range keep {
+		i.F()
+	}
+}
+
+// Counting returns a Reader of n bytes counting up from zero.
+func Counting(n int) io.Reader {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return bytes.NewReader(b)
+}
+
+// CopyChunks copies r to w in chunks of 1000 bytes.
+func CopyChunks(w io.Writer, r io.Reader) (int64, error) {
+	return io.CopyBuffer(w, r, make([]byte, 1000))
+}
@@ -2,6 +2,8 @@ package bind
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 
 	"golang.org/x/tools/go/types"
 )
@@ -13,9 +15,17 @@ func seqType(t types.Type) string {
 	if isErrorType(t) {
 		return "String"
 	}
+	if isTimeType(t) {
+		return "Time"
+	}
+	if isStreamType(t) {
+		return t.(*types.Named).Obj().Name()
+	}
 	switch t := t.(type) {
 	case *types.Basic:
 		switch t.Kind() {
+		case types.Bool:
+			return "Bool"
 		case types.Int:
 			return "Int"
 		case types.Int8:
@@ -44,6 +54,11 @@ func seqType(t types.Type) string {
 		switch u := t.Underlying().(type) {
 		case *types.Interface:
 			return "Ref"
+		case *types.Basic:
+			if isEnumType(t) {
+				return seqType(u)
+			}
+			panic(fmt.Sprintf("unsupported named seqType: %s / %T", u, u))
 		default:
 			panic(fmt.Sprintf("unsupported named seqType: %s / %T", u, u))
 		}
@@ -59,7 +74,6 @@ func seqType(t types.Type) string {
 		default:
 			panic(fmt.Sprintf("unsupported seqType: %s(%s) / %T(%T)", t, e, t, e))
 		}
-	// TODO: let the types.Array case handled like types.Slice?
 	case *types.Pointer:
 		if _, ok := t.Elem().(*types.Named); ok {
 			return "Ref"
@@ -76,11 +90,575 @@ func seqRead(o types.Type) string {
 	return t + "()"
 }
 
-func seqWrite(o types.Type, name string) string {
-	t := seqType(o)
-	if t == "Ref" {
-		// TODO(crawshaw): do something cleaner, i.e. genWrite.
-		return t + "(" + name + ".ref())"
+// errorTypes returns the exported struct types of pkg that implement
+// error, either as values or through pointers. Errors of these types
+// returned by Go are passed as objects, so the foreign language can see
+// their fields and methods.
+func errorTypes(pkg *types.Package) []*types.Named {
+	errorIface := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	var named []*types.Named
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !obj.Exported() {
+			continue
+		}
+		T, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		if _, ok := T.Underlying().(*types.Struct); !ok {
+			continue
+		}
+		if types.Implements(types.NewPointer(T), errorIface) {
+			named = append(named, T)
+		}
+	}
+	return named
+}
+
+// isEnumType reports whether T is an enum type: a named int, int32 or
+// int64 type with exported constants of the type declared in its package. Enum
+// types are bound to Java enums of the constants.
+func isEnumType(T types.Type) bool {
+	n, ok := T.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	b, ok := n.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch b.Kind() {
+	case types.Int, types.Int32, types.Int64:
+		return len(enumConsts(n)) > 0
+	}
+	return false
+}
+
+// enumConsts returns the exported constants of type T in the package of
+// T, in order of their values. Constants with the same value are in
+// order of their names.
+func enumConsts(T *types.Named) []*types.Const {
+	var consts []*types.Const
+	scope := T.Obj().Pkg().Scope()
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if ok && c.Exported() && types.Identical(c.Type(), T) {
+			consts = append(consts, c)
+		}
+	}
+	sort.Stable(byConstValue(consts))
+	return consts
+}
+
+// constInt returns the value of the integer constant c.
+func constInt(c *types.Const) int64 {
+	v, err := strconv.ParseInt(c.Val().String(), 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("bad integer constant %s: %v", c.Name(), err))
+	}
+	return v
+}
+
+type byConstValue []*types.Const
+
+func (a byConstValue) Len() int           { return len(a) }
+func (a byConstValue) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byConstValue) Less(i, j int) bool { return constInt(a[i]) < constInt(a[j]) }
+
+// isByteBufferParam reports whether a parameter of type T of the Go
+// function or method o can be bound to a java.nio.ByteBuffer. The
+// buffer is read in place by Go, so only the []byte parameters of calls
+// into Go qualify. Interface methods may be implemented in the foreign
+// language, so their parameters do not.
+func isByteBufferParam(o *types.Func, T types.Type) bool {
+	s, ok := T.(*types.Slice)
+	if !ok {
+		return false
+	}
+	if b, ok := s.Elem().(*types.Basic); !ok || b.Kind() != types.Uint8 {
+		return false
+	}
+	if recv := o.Type().(*types.Signature).Recv(); recv != nil {
+		if _, ok := recv.Type().Underlying().(*types.Interface); ok {
+			return false
+		}
 	}
-	return t + "(" + name + ")"
+	return true
 }
+
+// checkChan reports whether a channel parameter of type T can be bound.
+// The foreign language receives the values Go sends on the channel, so
+// only channels Go can send on are supported, and their elements must be
+// of a type that can be passed across the language boundary.
+func checkChan(T *types.Chan, pkg *types.Package) error {
+	if T.Dir() == types.RecvOnly {
+		return fmt.Errorf("unsupported receive-only channel type %s: only chan and chan<- parameters are supported", T)
+	}
+	switch e := T.Elem().(type) {
+	case *types.Basic:
+		switch e.Kind() {
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint8, types.Float32, types.Float64, types.String:
+			return nil
+		}
+	case *types.Slice:
+		if b, ok := e.Elem().(*types.Basic); ok && b.Kind() == types.Uint8 {
+			return nil
+		}
+	case *types.Pointer:
+		if n, ok := e.Elem().(*types.Named); ok && n.Obj().Pkg() == pkg {
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				return nil
+			}
+		}
+	case *types.Named:
+		if _, ok := e.Underlying().(*types.Interface); ok && e.Obj().Pkg() == pkg && !isErrorType(e) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported channel element type %s in %s", T.Elem(), T)
+}
+
+// checkMap reports whether a map of type T can be bound. Maps are copied
+// across the language boundary, so their keys must be strings, numbers
+// or enums, and their values of a type that can be passed across the
+// boundary.
+func checkMap(T *types.Map) error {
+	if !isMapKey(T.Key()) {
+		return fmt.Errorf("unsupported map key type %s in %s: keys must be strings, numbers or enums", T.Key(), T)
+	}
+	switch e := T.Elem().(type) {
+	case *types.Basic:
+		switch e.Kind() {
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint8, types.Float32, types.Float64, types.String:
+			return nil
+		}
+	case *types.Slice:
+		if b, ok := e.Elem().(*types.Basic); ok && b.Kind() == types.Uint8 {
+			return nil
+		}
+	case *types.Pointer:
+		if n, ok := e.Elem().(*types.Named); ok {
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				return nil
+			}
+		}
+	case *types.Named:
+		if isTimeType(e) || isEnumType(e) {
+			return nil
+		}
+		if _, ok := e.Underlying().(*types.Interface); ok && !isErrorType(e) {
+			return nil
+		}
+	case *types.Map:
+		return checkMap(e)
+	}
+	return fmt.Errorf("unsupported map value type %s in %s", T.Elem(), T)
+}
+
+func isMapKey(T types.Type) bool {
+	if isEnumType(T) {
+		return true
+	}
+	b, ok := T.(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch b.Kind() {
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint8, types.Float32, types.Float64, types.String:
+		return true
+	}
+	return false
+}
+
+// isList reports whether T is a slice passed across the language
+// boundary as a list of its elements: any slice but []byte, which is
+// passed as a byte array.
+func isList(T types.Type) bool {
+	s, ok := T.(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := s.Elem().(*types.Basic)
+	return !ok || b.Kind() != types.Uint8
+}
+
+// checkList reports whether a slice of type T can be bound as a list.
+// The elements are passed as references, so they must be structs, by
+// value or by pointer, or interfaces. Struct values are copied as they
+// cross the boundary.
+func checkList(T *types.Slice) error {
+	switch e := T.Elem().(type) {
+	case *types.Pointer:
+		if n, ok := e.Elem().(*types.Named); ok {
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				return nil
+			}
+		}
+	case *types.Named:
+		if isStructValue(e) {
+			return nil
+		}
+		if _, ok := e.Underlying().(*types.Interface); ok && !isErrorType(e) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported slice type %s: only []byte and slices of structs and interfaces are supported", T)
+}
+
+// listElem returns the type of the elements of a list of type T as
+// they are passed: a pointer for a struct value.
+func listElem(T *types.Slice) types.Type {
+	if isStructValue(T.Elem()) {
+		return types.NewPointer(T.Elem())
+	}
+	return T.Elem()
+}
+
+// isStructValue reports whether T is a named struct type, other than
+// time.Time.
+func isStructValue(T types.Type) bool {
+	n, ok := T.(*types.Named)
+	if !ok || isTimeType(n) {
+		return false
+	}
+	_, ok = n.Underlying().(*types.Struct)
+	return ok
+}
+
+// checkVariadic reports whether the variadic parameter of the function
+// or method o, if any, can be bound. The foreign language passes an
+// array of the elements, which must be of a type that can be passed
+// across the language boundary. The elements of a ...interface{}
+// parameter are converted one by one as they are passed, and values
+// without a Go counterpart are rejected by the foreign language.
+func checkVariadic(o *types.Func) error {
+	sig := o.Type().(*types.Signature)
+	if !sig.Variadic() {
+		return nil
+	}
+	T := sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice).Elem()
+	switch e := T.(type) {
+	case *types.Basic:
+		switch e.Kind() {
+		case types.Int, types.Int32, types.Int64, types.Float32, types.Float64, types.String:
+			return nil
+		}
+	case *types.Slice:
+		if b, ok := e.Elem().(*types.Basic); ok && b.Kind() == types.Uint8 {
+			return nil
+		}
+	case *types.Pointer:
+		if n, ok := e.Elem().(*types.Named); ok {
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				return nil
+			}
+		}
+	case *types.Named:
+		if isTimeType(e) || isEnumType(e) {
+			return nil
+		}
+		if _, ok := e.Underlying().(*types.Interface); ok && !isErrorType(e) {
+			return nil
+		}
+	case *types.Map:
+		return checkMap(e)
+	case *types.Interface:
+		if isEmptyInterface(e) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: unsupported variadic parameter type ...%s", o.Name(), T)
+}
+
+// checkArray reports whether an array of type T can be bound. Arrays
+// are copied across the language boundary to arrays of the same length,
+// so their elements must be booleans, numbers or strings.
+func checkArray(T *types.Array) error {
+	if b, ok := T.Elem().(*types.Basic); ok {
+		switch b.Kind() {
+		case types.Bool, types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint8, types.Float32, types.Float64, types.String:
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported array type %s: elements must be booleans, numbers or strings", T)
+}
+
+// checkVar reports whether the exported package-level variable v can be
+// bound. Its value is copied across the language boundary by its
+// accessors, so it must be a number, a bool, a string, []byte, a
+// time.Time, an enum, a map, a list, an interface other than error, or
+// a pointer to a struct. The named types must be defined in a package
+// for which bound reports true.
+func checkVar(v *types.Var, bound func(*types.Package) bool) error {
+	var named *types.Named
+	switch T := v.Type().(type) {
+	case *types.Basic:
+		switch T.Kind() {
+		case types.Bool, types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint8, types.Float32, types.Float64, types.String:
+			return nil
+		}
+	case *types.Slice:
+		if !isList(T) {
+			return nil
+		}
+		return checkList(T)
+	case *types.Map:
+		return checkMap(T)
+	case *types.Pointer:
+		if n, ok := T.Elem().(*types.Named); ok {
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				named = n
+			}
+		}
+	case *types.Named:
+		if isTimeType(T) {
+			return nil
+		}
+		if _, ok := T.Underlying().(*types.Interface); ok && !isErrorType(T) || isEnumType(T) {
+			named = T
+		}
+	}
+	if named == nil {
+		return fmt.Errorf("unsupported variable type %s", v.Type())
+	}
+	if !bound(named.Obj().Pkg()) {
+		return fmt.Errorf("variable type %s not defined in package %s", v.Type(), v.Pkg().Name())
+	}
+	return nil
+}
+
+// isEmptyInterface reports whether T is interface{}.
+func isEmptyInterface(T types.Type) bool {
+	i, ok := T.(*types.Interface)
+	return ok && i.NumMethods() == 0
+}
+
+// multiResult reports whether the function returns more than one value
+// besides a trailing error. The foreign language receives the values as
+// a single result object, with a field for each value.
+func multiResult(sig *types.Signature) bool {
+	n := sig.Results().Len()
+	if n > 0 && isErrorType(sig.Results().At(n-1).Type()) {
+		n--
+	}
+	return n > 1
+}
+
+// commaOK reports whether the function signature follows the comma-ok
+// idiom, func Lookup(k string) (v Value, ok bool): it returns a value
+// and the predeclared bool, which must be named ok if the results are
+// named. The Java API of such functions returns the value, or null when
+// the bool is false, unless Options.CommaOK is "result".
+func commaOK(sig *types.Signature) bool {
+	res := sig.Results()
+	if res.Len() != 2 || isErrorType(res.At(0).Type()) {
+		return false
+	}
+	ok := res.At(1)
+	if ok.Type() != types.Typ[types.Bool] {
+		return false
+	}
+	return ok.Name() == "" || ok.Name() == "ok"
+}
+
+// initFunc reports whether o is the init function of its package, the
+// package-level func Init returning nothing or an error. Its binding
+// calls it once, on the first call from the foreign side, and returns
+// the error of that call to every later one. An Init function taking a
+// context or variadic parameters is bound as any other function.
+func initFunc(o *types.Func) bool {
+	sig := o.Type().(*types.Signature)
+	if o.Name() != "Init" || sig.Recv() != nil || sig.Variadic() {
+		return false
+	}
+	if hasCtx, _ := contextParam(o); hasCtx {
+		return false
+	}
+	res := sig.Results()
+	return res.Len() == 0 || res.Len() == 1 && isErrorType(res.At(0).Type())
+}
+
+// checkMultiResult reports an error if the results of o, a function
+// returning multiple values, cannot be bound.
+func checkMultiResult(o *types.Func) error {
+	res := o.Type().(*types.Signature).Results()
+	for i := 0; i < res.Len(); i++ {
+		T := res.At(i).Type()
+		switch T.(type) {
+		case *types.Chan, *types.Signature:
+			return fmt.Errorf("%s: unsupported result type %s in multiple results", o.Name(), T)
+		}
+		if isErrorType(T) && i != res.Len()-1 {
+			return fmt.Errorf("%s: an error result must be the last result", o.Name())
+		}
+	}
+	return nil
+}
+
+// resultName returns the name of the field holding result i of a
+// function returning multiple values: the name of the Go result, or
+// r0, r1, ... for unnamed results.
+func resultName(res *types.Tuple, i int) string {
+	name := res.At(i).Name()
+	if name == "" || name == "_" {
+		name = fmt.Sprintf("r%d", i)
+	}
+	return name
+}
+
+// hasFuncResult reports whether the function signature returns a func.
+// Closures are not passed from the foreign language to Go, so interface
+// methods, which may be implemented in the foreign language, cannot
+// return them.
+func hasFuncResult(sig *types.Signature) bool {
+	for i := 0; i < sig.Results().Len(); i++ {
+		if _, ok := sig.Results().At(i).Type().(*types.Signature); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasChanParam reports whether the function signature takes a channel.
+func hasChanParam(sig *types.Signature) bool {
+	for i := 0; i < sig.Params().Len(); i++ {
+		if _, ok := sig.Params().At(i).Type().(*types.Chan); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isContextType reports whether T is context.Context, either from the
+// standard library or from golang.org/x/net/context.
+func isContextType(T types.Type) bool {
+	n, ok := T.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil || n.Obj().Name() != "Context" {
+		return false
+	}
+	path := n.Obj().Pkg().Path()
+	return path == "context" || path == "golang.org/x/net/context"
+}
+
+// isStreamType reports whether T is io.Reader or io.Writer, which are
+// passed as streams of the foreign language.
+func isStreamType(T types.Type) bool {
+	n, ok := T.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil || n.Obj().Pkg().Path() != "io" {
+		return false
+	}
+	return n.Obj().Name() == "Reader" || n.Obj().Name() == "Writer"
+}
+
+// contextParam reports whether the function signature takes a context
+// as its first parameter, which the foreign language passes as a
+// cancellation handle. A context elsewhere in the signature is an error.
+func contextParam(o *types.Func) (bool, error) {
+	sig := o.Type().(*types.Signature)
+	for i := 0; i < sig.Params().Len(); i++ {
+		if i > 0 && isContextType(sig.Params().At(i).Type()) {
+			return false, fmt.Errorf("%s: context.Context is only supported as the first parameter", o.Name())
+		}
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		if isContextType(sig.Results().At(i).Type()) {
+			return false, fmt.Errorf("%s: unsupported context.Context result", o.Name())
+		}
+	}
+	return sig.Params().Len() > 0 && isContextType(sig.Params().At(0).Type()), nil
+}
+
+// closureResult returns the type of the func result of the function or
+// method o, or nil if it returns no func. A returned Go closure becomes a
+// foreign object calling back into Go, and is released when the foreign
+// object is collected. The parameters and results of a closure are
+// restricted to types that can be passed across the language boundary.
+func closureResult(o *types.Func) (*types.Signature, error) {
+	sig := o.Type().(*types.Signature)
+	if sig.Results().Len() == 0 {
+		return nil, nil
+	}
+	T, ok := sig.Results().At(0).Type().(*types.Signature)
+	if !ok {
+		return nil, nil
+	}
+	if T.Variadic() {
+		return nil, fmt.Errorf("%s: unsupported variadic func result type %s", o.Name(), T)
+	}
+	for i := 0; i < T.Params().Len(); i++ {
+		if p := T.Params().At(i).Type(); !isClosureValue(p) || isErrorType(p) {
+			return nil, fmt.Errorf("%s: unsupported parameter type %s in func result type %s", o.Name(), p, T)
+		}
+	}
+	res := T.Results()
+	switch {
+	case res.Len() > 2, res.Len() == 2 && !isErrorType(res.At(1).Type()):
+		return nil, fmt.Errorf("%s: func result type %s must return either zero or one values, and optionally an error", o.Name(), T)
+	case res.Len() > 0 && !isClosureValue(res.At(0).Type()):
+		return nil, fmt.Errorf("%s: unsupported result type %s in func result type %s", o.Name(), res.At(0).Type(), T)
+	}
+	return T, nil
+}
+
+// isClosureValue reports whether values of type T can be passed to or
+// returned by a closure: numbers, strings, byte slices, Go objects,
+// interfaces, times, enums and errors.
+func isClosureValue(T types.Type) bool {
+	switch T := T.(type) {
+	case *types.Basic:
+		switch T.Kind() {
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint8, types.Float32, types.Float64, types.String:
+			return true
+		}
+	case *types.Slice:
+		b, ok := T.Elem().(*types.Basic)
+		return ok && b.Kind() == types.Uint8
+	case *types.Pointer:
+		if n, ok := T.Elem().(*types.Named); ok {
+			_, ok := n.Underlying().(*types.Struct)
+			return ok
+		}
+	case *types.Named:
+		if isTimeType(T) || isEnumType(T) || isErrorType(T) {
+			return true
+		}
+		_, ok := T.Underlying().(*types.Interface)
+		return ok && !isContextType(T)
+	}
+	return false
+}
+
+// funcName returns the name of the Java interface of closures of type T,
+// such as Func_int_To_string for func(int) string. Closures returning an
+// error have the suffix _Err.
+func funcName(T *types.Signature) string {
+	name := "Func"
+	for i := 0; i < T.Params().Len(); i++ {
+		name += "_" + mapElemName(T.Params().At(i).Type())
+	}
+	res := T.Results()
+	if res.Len() > 0 && !isErrorType(res.At(0).Type()) {
+		name += "_To_" + mapElemName(res.At(0).Type())
+	}
+	if res.Len() > 0 && isErrorType(res.At(res.Len()-1).Type()) {
+		name += "_Err"
+	}
+	return name
+}
+
+// closureCallCode is the code of the handler calling a closure.
+const closureCallCode = 0x0c
+
+// Codes of the Sink methods called by Go to deliver channel values.
+const (
+	sinkSendCode  = 0x10a
+	sinkCloseCode = 0x20a
+)
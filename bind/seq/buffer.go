@@ -7,7 +7,9 @@ package seq
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"runtime"
+	"time"
 	"unsafe"
 )
 
@@ -60,6 +62,14 @@ func align(offset, alignment int) int {
 	return pad + offset
 }
 
+// ReadArrayLen reads the length of an array written by the foreign
+// language, and panics unless it is n, the length of the Go array.
+func (b *Buffer) ReadArrayLen(n int) {
+	if got := b.ReadInt32(); int(got) != n {
+		panic(fmt.Sprintf("seq: array of length %d passed for an array of length %d", got, n))
+	}
+}
+
 func (b *Buffer) ReadInt32() int32 {
 	offset := align(b.Offset, 4)
 	if len(b.Data)-offset < 4 {
@@ -82,6 +92,11 @@ func (b *Buffer) ReadInt64() int64 {
 
 // TODO(hyangah): int8, int16?
 
+// ReadBool reads a bool, sent as an int32 that is 0 for false.
+func (b *Buffer) ReadBool() bool {
+	return b.ReadInt32() != 0
+}
+
 func (b *Buffer) ReadInt() int {
 	return int(b.ReadInt64())
 }
@@ -122,6 +137,19 @@ func (b *Buffer) ReadByteArray() []byte {
 	return slice
 }
 
+// ReadByteBuffer reads bytes written by the foreign language without
+// copying them, as for a java.nio.ByteBuffer. The returned slice refers
+// to foreign memory that is only valid until the call returns, so it
+// must not be retained or used by another goroutine after that.
+func (b *Buffer) ReadByteBuffer() []byte {
+	sz := b.ReadInt64()
+	if sz == 0 {
+		return nil
+	}
+	ptr := b.ReadInt64()
+	return (*[1 << 30]byte)(unsafe.Pointer(uintptr(ptr)))[:sz:sz]
+}
+
 func (b *Buffer) ReadRef() *Ref {
 	ref := &Ref{b.ReadInt32()}
 	if ref.Num > 0 {
@@ -136,6 +164,20 @@ func (b *Buffer) ReadString() string {
 	return DecString(b)
 }
 
+// ReadTime reads a time written by WriteTime or by the foreign
+// language, in the UTC location. A null time is read as the zero Time.
+func (b *Buffer) ReadTime() time.Time {
+	ms := b.ReadInt64()
+	if ms == noTime {
+		return time.Time{}
+	}
+	sec, rem := ms/1000, ms%1000
+	if rem < 0 {
+		sec, rem = sec-1, rem+1000
+	}
+	return time.Unix(sec, rem*int64(time.Millisecond)).UTC()
+}
+
 func (b *Buffer) WriteInt32(v int32) {
 	offset := align(b.Offset, 4)
 	if len(b.Data)-offset < 4 {
@@ -158,6 +200,15 @@ func (b *Buffer) WriteInt(v int) {
 	b.WriteInt64(int64(v))
 }
 
+// WriteBool writes v as an int32, 1 for true and 0 for false.
+func (b *Buffer) WriteBool(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	b.WriteInt32(i)
+}
+
 func (b *Buffer) WriteFloat32(v float32) {
 	offset := align(b.Offset, 4)
 	if len(b.Data)-offset < 4 {
@@ -193,7 +244,54 @@ func (b *Buffer) WriteString(v string) {
 	EncString(b, v)
 }
 
+// maxErrorCauses bounds the causes written for an error, so an error
+// that unwraps to itself cannot loop forever.
+const maxErrorCauses = 64
+
+// WriteErrorCauses writes the messages of the errors wrapped by err,
+// which the foreign language attaches to the exception thrown for err
+// as its chain of causes. The errors are those returned in turn by an
+// Unwrap method, as for errors.Unwrap, such as by fmt.Errorf with %w.
+func (b *Buffer) WriteErrorCauses(err error) {
+	var causes []string
+	for len(causes) < maxErrorCauses {
+		u, ok := err.(interface {
+			Unwrap() error
+		})
+		if !ok {
+			break
+		}
+		if err = u.Unwrap(); err == nil {
+			break
+		}
+		causes = append(causes, err.Error())
+	}
+	b.WriteInt32(int32(len(causes)))
+	for _, msg := range causes {
+		b.WriteString(msg)
+	}
+}
+
+// Times cross the language boundary as milliseconds since the Unix
+// epoch, the precision of java.util.Date. The zero Time cannot be
+// represented in milliseconds, so it is sent as noTime instead, which
+// the foreign language reads as null.
+const noTime = math.MinInt64
+
+// WriteTime writes t truncated to the millisecond.
+func (b *Buffer) WriteTime(t time.Time) {
+	if t.IsZero() {
+		b.WriteInt64(noTime)
+		return
+	}
+	b.WriteInt64(t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond))
+}
+
+// WriteGoRef writes a reference to the Go object obj. A pointer written
+// again is written as the same reference; any other value is copied,
+// with a reference of its own.
 func (b *Buffer) WriteGoRef(obj interface{}) {
+	obj = refKey(obj)
 	refs.Lock()
 	num := refs.refs[obj]
 	if num == 0 {
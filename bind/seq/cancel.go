@@ -0,0 +1,86 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import "sync"
+
+// A Cancellable is the Go side of a cancellation handle created by the
+// foreign language. Bindings of functions taking a context.Context
+// register the cancel func of the context they pass to the function,
+// and the foreign language cancels it through the handle.
+//
+// The handle is an ordinary Go object reference: it is released when
+// the foreign handle is garbage collected.
+type Cancellable struct {
+	mu        sync.Mutex
+	cancelled bool
+	next      int
+	cancels   map[int]func()
+}
+
+const cancellableDescriptor = "go.Cancellable"
+
+const (
+	cancellableNewCode    = 1
+	cancellableCancelCode = 2
+)
+
+func init() {
+	Register(cancellableDescriptor, cancellableNewCode, func(out, in *Buffer) {
+		out.WriteGoRef(new(Cancellable))
+	})
+	Register(cancellableDescriptor, cancellableCancelCode, func(out, in *Buffer) {
+		in.ReadRef().Get().(*Cancellable).Cancel()
+	})
+}
+
+// Cancel calls the registered cancel funcs. Funcs registered after
+// Cancel are called immediately.
+func (c *Cancellable) Cancel() {
+	c.mu.Lock()
+	c.cancelled = true
+	cancels := c.cancels
+	c.cancels = nil
+	c.mu.Unlock()
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+// Register registers cancel to be called by Cancel and returns a func
+// that unregisters it. Register on a nil Cancellable does nothing.
+func (c *Cancellable) Register(cancel func()) (unregister func()) {
+	if c == nil {
+		return func() {}
+	}
+	c.mu.Lock()
+	if c.cancelled {
+		c.mu.Unlock()
+		cancel()
+		return func() {}
+	}
+	if c.cancels == nil {
+		c.cancels = make(map[int]func())
+	}
+	id := c.next
+	c.next++
+	c.cancels[id] = cancel
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		delete(c.cancels, id)
+		c.mu.Unlock()
+	}
+}
+
+// ReadCancellable reads a handle written by the foreign language, or
+// nil for a null handle.
+func (b *Buffer) ReadCancellable() *Cancellable {
+	num := b.ReadInt32()
+	if num == 0 {
+		return nil
+	}
+	return (&Ref{num}).Get().(*Cancellable)
+}
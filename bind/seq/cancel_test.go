@@ -0,0 +1,78 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newCancellable creates a handle as the foreign language does, and
+// returns its reference number.
+func newCancellable() int32 {
+	in, out := new(Buffer), new(Buffer)
+	Registry[cancellableDescriptor][cancellableNewCode](out, in)
+	out.Offset = 0
+	return out.ReadInt32()
+}
+
+func cancelCancellable(num int32) {
+	in, out := new(Buffer), new(Buffer)
+	in.WriteInt32(num)
+	in.Offset = 0
+	Registry[cancellableDescriptor][cancellableCancelCode](out, in)
+}
+
+func TestCancellable(t *testing.T) {
+	num := newCancellable()
+	defer Delete(num)
+
+	// Call a function selecting on ctx.Done() the way the generated
+	// bindings do.
+	in := new(Buffer)
+	in.WriteInt32(num)
+	in.Offset = 0
+	done := make(chan error)
+	go func() {
+		c := in.ReadCancellable()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer c.Register(cancel)()
+		defer cancel()
+		select {
+		case <-ctx.Done():
+			done <- ctx.Err()
+		case <-time.After(10 * time.Second):
+			done <- nil
+		}
+	}()
+
+	cancelCancellable(num)
+	if err := <-done; err != context.Canceled {
+		t.Errorf("cancelled function returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCancellableRegister(t *testing.T) {
+	c := new(Cancellable)
+	calls := 0
+	unregister := c.Register(func() { calls++ })
+	unregister()
+	c.Register(func() { calls += 10 })
+	c.Cancel()
+	if calls != 10 {
+		t.Errorf("Cancel called %d, want only the registered func", calls)
+	}
+	c.Register(func() { calls += 100 })
+	if calls != 110 {
+		t.Errorf("Register after Cancel did not cancel")
+	}
+
+	var nilC *Cancellable
+	nilC.Register(func() { t.Error("nil Cancellable called cancel") })()
+	if c := (&Buffer{Data: make([]byte, 4)}).ReadCancellable(); c != nil {
+		t.Errorf("ReadCancellable of a null handle = %v, want nil", c)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import "fmt"
+
+// Kinds of the values of an interface{} written by the foreign
+// language, preceding each value. They must match the constants of
+// writeInterface in Seq.java.
+const (
+	interfaceNil     = 0
+	interfaceString  = 1
+	interfaceInt     = 2 // long
+	interfaceInt32   = 3 // int
+	interfaceFloat64 = 4 // double
+	interfaceFloat32 = 5 // float
+	interfaceBytes   = 6 // byte[]
+	interfaceRef     = 7 // Go object
+)
+
+// ReadInterface reads a value passed by the foreign language for an
+// element of a ...interface{} parameter. Only strings, numbers, byte
+// arrays and Go objects can be passed; the foreign language rejects
+// other values before writing them.
+func (b *Buffer) ReadInterface() interface{} {
+	switch kind := b.ReadInt32(); kind {
+	case interfaceNil:
+		return nil
+	case interfaceString:
+		return b.ReadString()
+	case interfaceInt:
+		return b.ReadInt()
+	case interfaceInt32:
+		return b.ReadInt32()
+	case interfaceFloat64:
+		return b.ReadFloat64()
+	case interfaceFloat32:
+		return b.ReadFloat32()
+	case interfaceBytes:
+		return b.ReadByteArray()
+	case interfaceRef:
+		return b.ReadRef().Get()
+	default:
+		panic(fmt.Sprintf("seq: unknown interface value kind %d", kind))
+	}
+}
@@ -11,6 +11,7 @@ package seq
 
 import (
 	"fmt"
+	"reflect"
 	"sync"
 )
 
@@ -30,6 +31,31 @@ func init() {
 	refs.Unlock()
 }
 
+// A valueRef holds a Go value passed to another language whose type is
+// not a pointer, such as a struct stored in an interface. Such values
+// have no identity: equal values would share one reference, and values
+// of types that are not comparable could not be referenced. Instead
+// each value passed is held by a valueRef of its own, so it is copied
+// as it crosses the boundary, and the methods called on it from the
+// other language do not change the value it was copied from.
+type valueRef struct {
+	v interface{}
+}
+
+// refKey returns the object stored in refs for obj: obj itself if it
+// is a pointer or a channel, which the other language refers to in
+// place, and a new valueRef holding obj otherwise.
+func refKey(obj interface{}) interface{} {
+	if obj == nil {
+		return nil
+	}
+	switch reflect.TypeOf(obj).Kind() {
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return obj
+	}
+	return &valueRef{obj}
+}
+
 // A Ref represents a Java or Go object passed across the language
 // boundary.
 type Ref struct {
@@ -44,6 +70,9 @@ func (r *Ref) Get() interface{} {
 	if !ok {
 		panic(fmt.Sprintf("unknown ref %d", r.Num))
 	}
+	if v, ok := obj.(*valueRef); ok {
+		return v.v
+	}
 	return obj
 }
 
@@ -19,9 +19,20 @@ package seq // import "golang.org/x/mobile/bind/seq"
 import "fmt"
 
 // Transact calls a method on a foreign object instance.
-// It blocks until the call is complete.
+// It blocks until the call is complete. If the method throws an
+// exception, Transact panics with an *Exception.
 var Transact func(ref *Ref, code int, in *Buffer) (out *Buffer)
 
+// An Exception is the panic value of a call to a foreign object whose
+// method threw an exception. Go code calling foreign implementations of
+// interfaces can recover it. A panic left unrecovered by a Go function
+// called by the foreign language is thrown as an exception there.
+type Exception struct {
+	Message string // description of the exception
+}
+
+func (e *Exception) Error() string { return e.Message }
+
 // FinalizeRef is the finalizer used on foreign objects.
 var FinalizeRef func(ref *Ref)
 
@@ -45,6 +56,20 @@ func Register(descriptor string, code int, fn Func) {
 	m[code] = fn
 }
 
+// Proxies holds the constructors of the Go proxies of foreign objects
+// implementing bound interfaces, keyed by interface descriptor. The
+// bindings of packages bound together use them to read foreign objects
+// implementing each other's interfaces.
+var Proxies = make(map[string]func(ref *Ref) interface{})
+
+// RegisterProxy registers the proxy constructor of an interface in Proxies.
+func RegisterProxy(descriptor string, fn func(ref *Ref) interface{}) {
+	if Proxies[descriptor] != nil {
+		panic(fmt.Sprintf("registry.RegisterProxy: %q already registered", descriptor))
+	}
+	Proxies[descriptor] = fn
+}
+
 // DecString decodes a string encoded in the Buffer.
 var DecString func(in *Buffer) string
 
@@ -4,7 +4,12 @@
 
 package seq
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
 
 func TestBuffer(t *testing.T) {
 	buf := new(Buffer)
@@ -13,6 +18,8 @@ func TestBuffer(t *testing.T) {
 	buf.WriteUTF16("Hello, world")
 	buf.WriteFloat64(4.02)
 	buf.WriteFloat32(1.2)
+	buf.WriteBool(true)
+	buf.WriteBool(false)
 	buf.WriteGoRef(new(int))
 	buf.WriteGoRef(new(int))
 
@@ -33,4 +40,233 @@ func TestBuffer(t *testing.T) {
 	if got, want := buf.ReadFloat32(), float32(1.2); got != want {
 		t.Errorf("buf.ReadFloat32()=%f, want %f", got, want)
 	}
+	if got, want := buf.ReadBool(), true; got != want {
+		t.Errorf("buf.ReadBool()=%v, want %v", got, want)
+	}
+	if got, want := buf.ReadBool(), false; got != want {
+		t.Errorf("buf.ReadBool()=%v, want %v", got, want)
+	}
+}
+
+func TestBufferGoRefIdentity(t *testing.T) {
+	obj := new(int)
+	buf := new(Buffer)
+	buf.WriteGoRef(obj)
+	buf.WriteGoRef(obj)
+	buf.WriteGoRef(new(int))
+	buf.Offset = 0
+	num := buf.ReadInt32()
+	defer Delete(num)
+	if got := buf.ReadInt32(); got != num {
+		t.Errorf("second reference to the object is %d, want %d", got, num)
+	}
+	other := buf.ReadInt32()
+	defer Delete(other)
+	if other == num {
+		t.Errorf("another object has the same reference %d", num)
+	}
+}
+
+func TestBufferGoRefValue(t *testing.T) {
+	type point struct{ X, Y int }
+	type path struct{ points []point } // not comparable
+	p := point{1, 2}
+	buf := new(Buffer)
+	buf.WriteGoRef(p)
+	buf.WriteGoRef(p)
+	buf.WriteGoRef(path{})
+	buf.Offset = 0
+	var nums []int32
+	for i := 0; i < 3; i++ {
+		num := buf.ReadInt32()
+		defer Delete(num)
+		nums = append(nums, num)
+	}
+	if nums[0] == nums[1] {
+		t.Errorf("copies of a struct value have the same reference %d", nums[0])
+	}
+	got := (&Ref{nums[0]}).Get()
+	if got != p {
+		t.Errorf("Get()=%#v, want %#v", got, p)
+	}
+	if _, ok := (&Ref{nums[2]}).Get().(path); !ok {
+		t.Errorf("Get()=%#v, want a path", (&Ref{nums[2]}).Get())
+	}
+}
+
+func TestBufferTime(t *testing.T) {
+	times := []time.Time{
+		time.Date(2015, time.June, 1, 12, 30, 15, 123456789, time.UTC),
+		time.Date(1969, time.December, 31, 23, 59, 59, 999000000, time.UTC),
+		time.Unix(0, 0),
+		{},
+	}
+	buf := new(Buffer)
+	for _, tm := range times {
+		buf.WriteTime(tm)
+	}
+	buf.Offset = 0
+	for _, tm := range times {
+		want := tm.Truncate(time.Millisecond)
+		if got := buf.ReadTime(); !got.Equal(want) || got.IsZero() != tm.IsZero() {
+			t.Errorf("ReadTime()=%v, want %v", got, want)
+		}
+	}
+
+	buf = new(Buffer)
+	buf.WriteTime(time.Unix(1433161815, 123456789))
+	buf.Offset = 0
+	if got, want := buf.ReadInt64(), int64(1433161815123); got != want {
+		t.Errorf("WriteTime wrote %d ms, want %d", got, want)
+	}
+}
+
+func TestBufferByteBuffer(t *testing.T) {
+	b := []byte("hello")
+	buf := new(Buffer)
+	buf.WriteByteArray(b)
+	buf.WriteByteArray(nil)
+	buf.Offset = 0
+	got := buf.ReadByteBuffer()
+	if string(got) != "hello" {
+		t.Errorf("ReadByteBuffer()=%q, want %q", got, "hello")
+	}
+	if &got[0] != &b[0] {
+		t.Error("ReadByteBuffer copied the bytes")
+	}
+	if got := buf.ReadByteBuffer(); got != nil {
+		t.Errorf("ReadByteBuffer()=%q, want nil", got)
+	}
+}
+
+func TestBufferReadArrayLen(t *testing.T) {
+	buf := new(Buffer)
+	buf.WriteInt32(3)
+	buf.WriteInt32(2)
+	buf.Offset = 0
+	buf.ReadArrayLen(3)
+	defer func() {
+		if recover() == nil {
+			t.Error("ReadArrayLen(3) of a length 2 array did not panic")
+		}
+	}()
+	buf.ReadArrayLen(3)
+}
+
+func TestBufferReadInterface(t *testing.T) {
+	if DecString == nil {
+		EncString, DecString = (*Buffer).WriteUTF16, (*Buffer).ReadUTF16
+		defer func() { EncString, DecString = nil, nil }()
+	}
+	obj := new(int)
+
+	// Write the values the way the foreign language does, each
+	// preceded by its kind.
+	buf := new(Buffer)
+	buf.WriteInt32(interfaceNil)
+	buf.WriteInt32(interfaceString)
+	buf.WriteString("hello")
+	buf.WriteInt32(interfaceInt)
+	buf.WriteInt(1 << 40)
+	buf.WriteInt32(interfaceInt32)
+	buf.WriteInt32(-7)
+	buf.WriteInt32(interfaceFloat64)
+	buf.WriteFloat64(2.5)
+	buf.WriteInt32(interfaceFloat32)
+	buf.WriteFloat32(0.5)
+	buf.WriteInt32(interfaceBytes)
+	buf.WriteByteArray([]byte("bytes"))
+	buf.WriteInt32(interfaceRef)
+	buf.WriteGoRef(obj)
+	buf.Offset = 0
+
+	want := []interface{}{nil, "hello", 1 << 40, int32(-7), 2.5, float32(0.5)}
+	for _, w := range want {
+		if got := buf.ReadInterface(); got != w {
+			t.Errorf("ReadInterface()=%#v, want %#v", got, w)
+		}
+	}
+	if got := buf.ReadInterface(); !reflect.DeepEqual(got, []byte("bytes")) {
+		t.Errorf("ReadInterface()=%#v, want %#v", got, []byte("bytes"))
+	}
+	if got := buf.ReadInterface(); got != obj {
+		t.Errorf("ReadInterface()=%#v, want the Go object %#v", got, obj)
+	}
+}
+
+// wrapError is an error wrapping another, as made by fmt.Errorf with %w.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string { return e.msg }
+func (e *wrapError) Unwrap() error { return e.err }
+
+// loopError is an error that unwraps to itself.
+type loopError struct{}
+
+func (e *loopError) Error() string { return "loop" }
+func (e *loopError) Unwrap() error { return e }
+
+func TestBufferWriteErrorCauses(t *testing.T) {
+	if DecString == nil {
+		EncString, DecString = (*Buffer).WriteUTF16, (*Buffer).ReadUTF16
+		defer func() { EncString, DecString = nil, nil }()
+	}
+	root := errors.New("file not found")
+	mid := &wrapError{"open config: file not found", root}
+	top := &wrapError{"load: open config: file not found", mid}
+	tests := []struct {
+		err  error
+		want []string
+	}{
+		{root, nil},
+		{top, []string{mid.msg, root.Error()}},
+		{&wrapError{"nothing wrapped", nil}, nil},
+	}
+	for _, tt := range tests {
+		buf := new(Buffer)
+		buf.WriteErrorCauses(tt.err)
+		buf.Offset = 0
+		n := int(buf.ReadInt32())
+		var got []string
+		for i := 0; i < n; i++ {
+			got = append(got, buf.ReadString())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("causes of %q = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+
+	buf := new(Buffer)
+	buf.WriteErrorCauses(new(loopError))
+	buf.Offset = 0
+	if n := buf.ReadInt32(); n != maxErrorCauses {
+		t.Errorf("%d causes of an error unwrapping to itself, want %d", n, maxErrorCauses)
+	}
+}
+
+var benchBytes = make([]byte, 1<<20)
+
+func BenchmarkReadByteArray(b *testing.B) {
+	buf := new(Buffer)
+	buf.WriteByteArray(benchBytes)
+	b.SetBytes(int64(len(benchBytes)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Offset = 0
+		buf.ReadByteArray()
+	}
+}
+
+func BenchmarkReadByteBuffer(b *testing.B) {
+	buf := new(Buffer)
+	buf.WriteByteArray(benchBytes)
+	b.SetBytes(int64(len(benchBytes)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Offset = 0
+		buf.ReadByteBuffer()
+	}
 }
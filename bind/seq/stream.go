@@ -0,0 +1,193 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"errors"
+	"io"
+)
+
+// An io.Reader or io.Writer crosses the language boundary as a stream
+// of the foreign language: a Go Reader is read through a foreign input
+// stream and a Go Writer written through a foreign output stream, and
+// the foreign streams passed to Go are read and written through the
+// proxies streamReader and streamWriter. Each call moves at most
+// maxStreamChunk bytes, so large reads and writes are never buffered
+// whole by either side.
+//
+// The bytes of a call are followed by a status: streamOK, streamEOF at
+// the end of the stream, or streamError followed by the error message.
+
+const (
+	readerDescriptor = "go.io.Reader"
+	writerDescriptor = "go.io.Writer"
+)
+
+const (
+	streamReadCode  = 1
+	streamWriteCode = 1
+	streamCloseCode = 2
+)
+
+const (
+	streamOK    = 0
+	streamEOF   = 1
+	streamError = 2
+)
+
+const maxStreamChunk = 64 << 10
+
+func init() {
+	Register(readerDescriptor, streamReadCode, func(out, in *Buffer) {
+		r := in.ReadRef().Get().(io.Reader)
+		buf := make([]byte, chunkLen(int(in.ReadInt32())))
+		n, err := r.Read(buf)
+		out.WriteByteArray(buf[:n])
+		writeStreamStatus(out, err)
+	})
+	Register(readerDescriptor, streamCloseCode, closeStream)
+	Register(writerDescriptor, streamWriteCode, func(out, in *Buffer) {
+		w := in.ReadRef().Get().(io.Writer)
+		_, err := w.Write(in.ReadByteArray())
+		writeStreamStatus(out, err)
+	})
+	Register(writerDescriptor, streamCloseCode, closeStream)
+}
+
+// closeStream closes a Go Reader or Writer closed by the foreign
+// language, if it is an io.Closer.
+func closeStream(out, in *Buffer) {
+	var err error
+	if c, ok := in.ReadRef().Get().(io.Closer); ok {
+		err = c.Close()
+	}
+	writeStreamStatus(out, err)
+}
+
+func chunkLen(n int) int {
+	if n > maxStreamChunk {
+		return maxStreamChunk
+	}
+	return n
+}
+
+func writeStreamStatus(out *Buffer, err error) {
+	switch err {
+	case nil:
+		out.WriteInt32(streamOK)
+	case io.EOF:
+		out.WriteInt32(streamEOF)
+	default:
+		out.WriteInt32(streamError)
+		out.WriteString(err.Error())
+	}
+}
+
+func readStreamStatus(in *Buffer) error {
+	switch status := in.ReadInt32(); status {
+	case streamOK:
+		return nil
+	case streamEOF:
+		return io.EOF
+	default:
+		return errors.New(in.ReadString())
+	}
+}
+
+// streamReader is a foreign input stream passed to Go as an io.Reader.
+type streamReader Ref
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	in := new(Buffer)
+	in.WriteInt32(int32(chunkLen(len(p))))
+	out := Transact((*Ref)(r), streamReadCode, in)
+	n := copy(p, out.ReadByteArray())
+	return n, readStreamStatus(out)
+}
+
+// Close closes the foreign stream.
+func (r *streamReader) Close() error {
+	out := Transact((*Ref)(r), streamCloseCode, new(Buffer))
+	return readStreamStatus(out)
+}
+
+// streamWriter is a foreign output stream passed to Go as an io.Writer.
+type streamWriter Ref
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	for n := 0; n < len(p); {
+		chunk := p[n:]
+		chunk = chunk[:chunkLen(len(chunk))]
+		in := new(Buffer)
+		in.WriteByteArray(chunk)
+		out := Transact((*Ref)(w), streamWriteCode, in)
+		if err := readStreamStatus(out); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return len(p), nil
+}
+
+// Close closes the foreign stream.
+func (w *streamWriter) Close() error {
+	out := Transact((*Ref)(w), streamCloseCode, new(Buffer))
+	return readStreamStatus(out)
+}
+
+// ReadReader reads an io.Reader written by WriteReader or by the
+// foreign language, or nil for a null stream.
+func (b *Buffer) ReadReader() io.Reader {
+	ref := b.ReadRef()
+	switch {
+	case ref.Num == 0:
+		return nil
+	case ref.Num < 0:
+		return ref.Get().(io.Reader)
+	}
+	return (*streamReader)(ref)
+}
+
+// WriteReader writes r, which the foreign language reads as an input
+// stream. A foreign stream is passed back as itself.
+func (b *Buffer) WriteReader(r io.Reader) {
+	switch r := r.(type) {
+	case nil:
+		b.WriteInt32(0)
+	case *streamReader:
+		b.WriteInt32(r.Num)
+	default:
+		b.WriteGoRef(r)
+	}
+}
+
+// ReadWriter reads an io.Writer written by WriteWriter or by the
+// foreign language, or nil for a null stream.
+func (b *Buffer) ReadWriter() io.Writer {
+	ref := b.ReadRef()
+	switch {
+	case ref.Num == 0:
+		return nil
+	case ref.Num < 0:
+		return ref.Get().(io.Writer)
+	}
+	return (*streamWriter)(ref)
+}
+
+// WriteWriter writes w, which the foreign language reads as an output
+// stream. A foreign stream is passed back as itself.
+func (b *Buffer) WriteWriter(w io.Writer) {
+	switch w := w.(type) {
+	case nil:
+		b.WriteInt32(0)
+	case *streamWriter:
+		b.WriteInt32(w.Num)
+	default:
+		b.WriteGoRef(w)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func streamData(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return b
+}
+
+// callStream calls a Go stream the way the foreign language does.
+func callStream(descriptor string, code int, num int32, write func(in *Buffer)) *Buffer {
+	in, out := new(Buffer), new(Buffer)
+	in.WriteInt32(num)
+	if write != nil {
+		write(in)
+	}
+	in.Offset = 0
+	Registry[descriptor][code](out, in)
+	out.Offset = 0
+	return out
+}
+
+func TestGoReader(t *testing.T) {
+	data := streamData(5000)
+	buf := new(Buffer)
+	buf.WriteReader(bytes.NewReader(data))
+	buf.Offset = 0
+	num := buf.ReadInt32()
+	defer Delete(num)
+
+	var got []byte
+	for i := 0; ; i++ {
+		if i > 10 {
+			t.Fatalf("no EOF after %d reads", i)
+		}
+		out := callStream(readerDescriptor, streamReadCode, num, func(in *Buffer) {
+			in.WriteInt32(1024)
+		})
+		b := out.ReadByteArray()
+		if len(b) > 1024 {
+			t.Fatalf("read %d bytes, want at most 1024", len(b))
+		}
+		got = append(got, b...)
+		if err := readStreamStatus(out); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes, want the %d bytes of the Reader", len(got), len(data))
+	}
+}
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestGoWriter(t *testing.T) {
+	data := streamData(5000)
+	w := new(closeBuffer)
+	buf := new(Buffer)
+	buf.WriteWriter(w)
+	buf.Offset = 0
+	num := buf.ReadInt32()
+	defer Delete(num)
+
+	for b := data; len(b) > 0; {
+		n := 1000
+		if n > len(b) {
+			n = len(b)
+		}
+		out := callStream(writerDescriptor, streamWriteCode, num, func(in *Buffer) {
+			in.WriteByteArray(b[:n])
+		})
+		if err := readStreamStatus(out); err != nil {
+			t.Fatal(err)
+		}
+		b = b[n:]
+	}
+	out := callStream(writerDescriptor, streamCloseCode, num, nil)
+	if err := readStreamStatus(out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Errorf("wrote %d bytes, want %d bytes", w.Len(), len(data))
+	}
+	if !w.closed {
+		t.Error("Writer not closed")
+	}
+}
+
+// foreignStreams replaces Transact with streams of a fake foreign
+// language: an input stream returning at most 700 bytes per read, and
+// an output stream recording the chunks written to it.
+type foreignStreams struct {
+	r      *bytes.Reader
+	chunks [][]byte
+	err    error // returned by writes
+}
+
+const (
+	foreignReaderNum = 42
+	foreignWriterNum = 43
+)
+
+func (s *foreignStreams) transact(ref *Ref, code int, in *Buffer) *Buffer {
+	in.Offset = 0
+	out := new(Buffer)
+	switch {
+	case ref.Num == foreignReaderNum && code == streamReadCode:
+		b := make([]byte, chunkLen(int(in.ReadInt32())))
+		if len(b) > 700 {
+			b = b[:700]
+		}
+		n, err := s.r.Read(b)
+		out.WriteByteArray(b[:n])
+		writeStreamStatus(out, err)
+	case ref.Num == foreignWriterNum && code == streamWriteCode:
+		s.chunks = append(s.chunks, in.ReadByteArray())
+		writeStreamStatus(out, s.err)
+	default:
+		panic("unexpected call")
+	}
+	out.Offset = 0
+	return out
+}
+
+func withForeignStreams(s *foreignStreams) func() {
+	enc, dec := EncString, DecString
+	if dec == nil {
+		EncString, DecString = (*Buffer).WriteUTF16, (*Buffer).ReadUTF16
+	}
+	transact, finalize := Transact, FinalizeRef
+	Transact = s.transact
+	FinalizeRef = func(*Ref) {}
+	return func() {
+		Transact, FinalizeRef = transact, finalize
+		EncString, DecString = enc, dec
+	}
+}
+
+func TestForeignReader(t *testing.T) {
+	data := streamData(5000)
+	defer withForeignStreams(&foreignStreams{r: bytes.NewReader(data)})()
+
+	buf := new(Buffer)
+	buf.WriteInt32(foreignReaderNum)
+	buf.Offset = 0
+	r := buf.ReadReader()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes, want %d bytes", len(got), len(data))
+	}
+
+	// The stream is passed back as itself.
+	buf = new(Buffer)
+	buf.WriteReader(r)
+	buf.Offset = 0
+	if num := buf.ReadInt32(); num != foreignReaderNum {
+		t.Errorf("foreign stream written as %d, want %d", num, foreignReaderNum)
+	}
+}
+
+func TestForeignWriter(t *testing.T) {
+	s := new(foreignStreams)
+	defer withForeignStreams(s)()
+
+	buf := new(Buffer)
+	buf.WriteInt32(foreignWriterNum)
+	buf.Offset = 0
+	w := buf.ReadWriter()
+	data := streamData(maxStreamChunk + 3000)
+	for _, b := range [][]byte{data[:2000], data[2000:]} {
+		if n, err := w.Write(b); n != len(b) || err != nil {
+			t.Fatalf("Write of %d bytes = %d, %v", len(b), n, err)
+		}
+	}
+	if len(s.chunks) != 3 {
+		t.Errorf("wrote %d chunks, want 3", len(s.chunks))
+	}
+	if got := bytes.Join(s.chunks, nil); !bytes.Equal(got, data) {
+		t.Errorf("wrote %d bytes, want %d bytes", len(got), len(data))
+	}
+
+	s.err = errors.New("disk full")
+	if _, err := w.Write(data[:10]); err == nil || err.Error() != "disk full" {
+		t.Errorf("Write error = %v, want disk full", err)
+	}
+}
+
+func TestNilStreams(t *testing.T) {
+	buf := new(Buffer)
+	buf.WriteReader(nil)
+	buf.WriteWriter(nil)
+	buf.Offset = 0
+	if r := buf.ReadReader(); r != nil {
+		t.Errorf("ReadReader() = %v, want nil", r)
+	}
+	if w := buf.ReadWriter(); w != nil {
+		t.Errorf("ReadWriter() = %v, want nil", w)
+	}
+}
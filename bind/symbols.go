@@ -0,0 +1,109 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+
+	"golang.org/x/tools/go/types"
+)
+
+// A Symbol is an exported package-level declaration of a Go package, and
+// whether the generators bind it.
+type Symbol struct {
+	Name string
+	Kind string // func, struct, interface, enum, const, var or type
+	Pos  token.Position
+
+	// Err holds the reasons the generators cannot bind the declaration,
+	// or is nil if it is bound.
+	Err error
+}
+
+// Symbols returns the exported declarations of a Go package, sorted by
+// name, each generated as with the given options but without writing
+// the bindings, to report those that cannot be bound. Nil options are
+// the defaults.
+func Symbols(fset *token.FileSet, pkg *types.Package, opts *Options) []Symbol {
+	if opts == nil {
+		opts = new(Options)
+	}
+	errTypes := errorTypes(pkg)
+	scope := pkg.Scope()
+	var syms []Symbol
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		sym := Symbol{Name: name, Kind: symbolKind(obj), Pos: fset.Position(obj.Pos())}
+
+		// The Java binding is checked first: the Go binding of a
+		// declaration failing in Java is seldom more informative.
+		jg := newJavaGen(new(bytes.Buffer), fset, pkg, opts)
+		jg.errorTypes = errTypes
+		gg := newGoGen(new(bytes.Buffer), fset, pkg, opts)
+		gg.errorTypes = errTypes
+		genJava := func() { jg.genObject(obj) }
+		genGo := func() { gg.genObject(obj) }
+		if v, ok := obj.(*types.Var); ok {
+			genJava = func() {
+				// The generators skip the variables they cannot
+				// bind, so the reason is reported here.
+				if err := checkVar(v, jg.isBound); err != nil {
+					jg.errorf("%v", err)
+					return
+				}
+				jg.genVar(v)
+			}
+			genGo = func() { gg.genVar(v) }
+		}
+		errs := genSymbol(genJava, &jg.err)
+		if len(errs) == 0 {
+			errs = genSymbol(genGo, &gg.err)
+		}
+		if len(errs) > 0 {
+			sym.Err = errs
+		}
+		syms = append(syms, sym)
+	}
+	return syms
+}
+
+// genSymbol runs gen, returning the errors it appends to *errs, or the
+// panic of a generator meeting an unsupported type.
+func genSymbol(gen func(), errs *ErrorList) (list ErrorList) {
+	defer func() {
+		if r := recover(); r != nil {
+			list = append(*errs, fmt.Errorf("%v", r))
+		}
+	}()
+	gen()
+	return *errs
+}
+
+func symbolKind(obj types.Object) string {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return "func"
+	case *types.Const:
+		return "const"
+	case *types.Var:
+		return "var"
+	case *types.TypeName:
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			return "struct"
+		case *types.Interface:
+			return "interface"
+		}
+		if isEnumType(obj.Type()) {
+			return "enum"
+		}
+	}
+	return "type"
+}
@@ -0,0 +1,23 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package annotations
+
+type T struct {
+	Name  string
+	Count int
+}
+
+func (t *T) Next() (*T, error) { return nil, nil }
+
+type I interface {
+	Bytes() []byte
+	Len() int
+}
+
+func NewT() *T { return &T{} }
+
+func Greeting(name string) string { return "hello, " + name }
+
+func Unannotated(x int) (float64, error) { return 0, nil }
@@ -0,0 +1,16 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package arrays
+
+func Scale(v [3]float32, k float32) [3]float32 {
+	for i := range v {
+		v[i] *= k
+	}
+	return v
+}
+
+func Names() [2]string {
+	return [2]string{"x", "y"}
+}
@@ -0,0 +1,19 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package badchannels
+
+type I interface {
+	Watch(c chan<- int)
+}
+
+type S struct {
+	C chan int
+}
+
+func Recv(c <-chan int) {}
+
+func Result() chan int { return nil }
+
+func Elem(c chan<- map[string]int) {}
@@ -0,0 +1,13 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package badclosures
+
+type I interface {
+	Callback() func()
+}
+
+func Chans() func(chan int) { return nil }
+
+func Pair() func() (int, int) { return nil }
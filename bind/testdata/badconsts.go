@@ -0,0 +1,14 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package badconsts
+
+type Name string
+
+const (
+	Alice    Name = "alice"
+	Unsigned uint = 7
+	Complex       = 1 + 2i
+	TooBig        = 1 << 70
+)
@@ -0,0 +1,11 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package badmaps
+
+type K struct{}
+
+func StructKey(m map[K]int) {}
+
+func ChanValue() map[string]chan int { return nil }
@@ -0,0 +1,17 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package badmultiresults
+
+type I interface {
+	Pair() (int, int)
+}
+
+func ErrFirst() (error, int, int) { return nil, 0, 0 }
+
+func Chan() (int, chan int) { return 0, nil }
+
+func Clash() (int, int) { return 0, 0 }
+
+type ClashResult struct{}
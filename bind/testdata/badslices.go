@@ -0,0 +1,15 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package badslices
+
+type Point struct {
+	X, Y int
+}
+
+func Ints() []int { return nil }
+
+func Nested(ps [][]Point) {}
+
+func Errors() []error { return nil }
@@ -0,0 +1,18 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package badstructtags
+
+type Collision struct {
+	ID         int
+	Identifier int `gomobile:"name=iD"`
+}
+
+type Keyword struct {
+	Class int `gomobile:"name=class"`
+}
+
+type Unknown struct {
+	X int `gomobile:"omitempty"`
+}
@@ -0,0 +1,29 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytebuffers
+
+func Sum(b []byte) int64 {
+	var n int64
+	for _, c := range b {
+		n += int64(c)
+	}
+	return n
+}
+
+func Copy(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+type Hash struct {
+	N int64
+}
+
+func (h *Hash) Write(b []byte) {
+	h.N += Sum(b)
+}
+
+type Writer interface {
+	Write(b []byte)
+}
@@ -0,0 +1,27 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package channels
+
+type Event struct {
+	Name string
+}
+
+type Feed struct{}
+
+func (f *Feed) Events(c chan<- *Event) {
+	defer close(c)
+	c <- &Event{Name: "start"}
+}
+
+func Count(n int, c chan<- int) {
+	for i := 0; i < n; i++ {
+		c <- i
+	}
+	close(c)
+}
+
+func Lines(c chan string) {
+	close(c)
+}
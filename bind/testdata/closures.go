@@ -0,0 +1,21 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package closures
+
+func NewAdder(n int) func(int) int {
+	return func(x int) int { return x + n }
+}
+
+func NewGreeter(greeting string) func(name string) (string, error) {
+	return func(name string) (string, error) { return greeting + ", " + name, nil }
+}
+
+type Counter struct {
+	N int
+}
+
+func (c *Counter) Incrementer() func() {
+	return func() { c.N++ }
+}
@@ -0,0 +1,39 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commaok
+
+var m = map[string]int{"one": 1}
+
+func Lookup(k string) (int, bool) {
+	v, ok := m[k]
+	return v, ok
+}
+
+func Name(k string) (name string, ok bool) {
+	if _, ok := m[k]; !ok {
+		return "", false
+	}
+	return k, true
+}
+
+type S struct{}
+
+func Find(k string) (*S, bool) {
+	if _, ok := m[k]; !ok {
+		return nil, false
+	}
+	return new(S), true
+}
+
+func (s *S) Get(k string) (float64, bool) {
+	v, ok := m[k]
+	return float64(v), ok
+}
+
+// Below, the second result is not the ok of a comma-ok.
+
+func Split(k string) (n int, valid bool) {
+	return len(k), k != ""
+}
@@ -0,0 +1,27 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package consts
+
+import "math"
+
+const (
+	Greeting         = "hello, world"
+	Escapes          = "quote \" backslash \\ tab\t newline\n e\u0301 \u00e9 \U0001F600"
+	Flag             = true
+	Rune             = 'é'
+	Answer           = 42
+	Small    int8    = -8
+	Large    int64   = 1 << 40
+	Byte     byte    = 200
+	Pi               = math.Pi
+	Pi32     float32 = math.Pi
+	Whole    float64 = 3
+
+	// Untyped constants beyond the range of a double.
+	Huge    = 1e400
+	NegHuge = -1e400
+)
+
+const unexported = "not bound"
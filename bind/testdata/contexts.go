@@ -0,0 +1,18 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexts
+
+import "context"
+
+func Wait(ctx context.Context, d int) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type Server struct{}
+
+func (s *Server) Serve(ctx context.Context) {
+	<-ctx.Done()
+}
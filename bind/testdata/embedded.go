@@ -0,0 +1,45 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embedded
+
+type Inner struct {
+	A, B int
+}
+
+func (i *Inner) Sum() int { return i.A + i.B }
+
+type Middle struct {
+	Inner
+	C int
+}
+
+func (m *Middle) Product() int { return m.A * m.C }
+
+// Outer promotes A and Sum from Inner through Middle, and C and Product
+// from Middle. Its own field B shadows Inner's B.
+type Outer struct {
+	*Middle
+	B string
+}
+
+type left struct {
+	X int
+	L string
+}
+
+func (l *left) Name() string { return "left" }
+
+type right struct {
+	X int
+	R string
+}
+
+func (r *right) Name() string { return "right" }
+
+// Both promotes L and R; X and Name are ambiguous and omitted.
+type Both struct {
+	left
+	right
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package enums
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	_
+	Blue // not contiguous
+)
+
+// More constants of Color, in another block.
+const (
+	Black Color = -1
+	White Color = 10
+)
+
+type Level int64
+
+const (
+	Low  Level = 1
+	High Level = 2
+	Max        = High // same value as High
+)
+
+type Flag int32
+
+const (
+	FlagA Flag = 1 << iota
+	FlagB
+)
+
+func Mix(a, b Color) Color {
+	return (a + b) / 2
+}
+
+type Pixel struct {
+	C Color
+}
+
+type Painter interface {
+	Paint(c Color) Level
+}
+
+func SetFlag(f Flag) {}
@@ -0,0 +1,29 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package examples
+
+type Counter struct {
+	Value int
+}
+
+func NewCounter() *Counter { return new(Counter) }
+
+func (c *Counter) Inc() { c.Value++ }
+
+func (c *Counter) Add(n int8) int {
+	c.Value += int(n)
+	return c.Value
+}
+
+func Hello(name string) string { return "Hello, " + name }
+
+func Sum(xs ...int) int { return 0 }
+
+type Listener interface {
+	OnEvent(name string) error
+	Count() int32
+}
+
+func Notify(l Listener, name string) error { return l.OnEvent(name) }
@@ -0,0 +1,23 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package initonce
+
+type Config struct {
+	Key string
+}
+
+var config *Config
+
+// Init is called once, however many times Java calls it.
+func Init(key string, c *Config) error {
+	c.Key = key
+	config = c
+	return nil
+}
+
+type S struct{}
+
+// Methods named Init are bound as any other method.
+func (s *S) Init() {}
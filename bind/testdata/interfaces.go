@@ -19,3 +19,11 @@ type seven struct{}
 func (seven) Rand() int32 { return 7 }
 
 func Seven() I { return seven{} }
+
+type Listener interface {
+	OnEvent(name string) error
+}
+
+func Notify(l Listener, name string) error {
+	return l.OnEvent(name)
+}
@@ -0,0 +1,29 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package maptypes
+
+type S struct {
+	Counts map[string]int
+}
+
+type I interface {
+	Names(ids map[int]string) map[int]string
+}
+
+func Counts(words map[string]int) map[string]int {
+	return words
+}
+
+func Names() map[int]string {
+	return nil
+}
+
+func Objects() map[string]*S {
+	return nil
+}
+
+func Nested(m map[string]map[int]string) map[string]map[int]string {
+	return m
+}
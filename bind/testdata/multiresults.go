@@ -0,0 +1,28 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multiresults
+
+import "errors"
+
+func DivMod(a, b int) (quo, rem int) {
+	return a / b, a % b
+}
+
+func Parse(s string) (int, string, error) {
+	if s == "" {
+		return 0, "", errors.New("empty")
+	}
+	return len(s), s, nil
+}
+
+func Flags() (a, b bool) {
+	return true, false
+}
+
+type S struct{}
+
+func (s *S) Bounds() (lo, hi float64, err error) {
+	return 0, 1, nil
+}
@@ -0,0 +1,24 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package richerrors
+
+// CodeError is an error type with a field of its own.
+type CodeError struct {
+	Code int
+	Msg  string
+}
+
+func (e *CodeError) Error() string { return e.Msg }
+
+// ValueError implements error with a value receiver.
+type ValueError struct {
+	Reason string
+}
+
+func (e ValueError) Error() string { return e.Reason }
+
+func Lookup(key string) (int, error) { return 0, &CodeError{Code: 404, Msg: key} }
+
+func Validate() error { return ValueError{"invalid"} }
@@ -0,0 +1,31 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package streams
+
+import (
+	"io"
+	"strings"
+)
+
+func Copy(w io.Writer, r io.Reader) (int64, error) {
+	return io.Copy(w, r)
+}
+
+func Open(s string) io.Reader {
+	return strings.NewReader(s)
+}
+
+type Log struct {
+	lines []string
+}
+
+func (l *Log) Writer() io.Writer {
+	return nil
+}
+
+type Source interface {
+	Open(name string) io.Reader
+	Save(r io.Reader) error
+}
@@ -0,0 +1,28 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stringers
+
+// Name is a fmt.Stringer, so its Java toString calls String.
+type Name struct {
+	First, Last string
+}
+
+func (n *Name) String() string {
+	return n.First + " " + n.Last
+}
+
+// Named is implemented by Go objects described by their String method.
+type Named interface {
+	String() string
+}
+
+// Greeting has a String method that is not that of fmt.Stringer.
+type Greeting struct {
+	Text string
+}
+
+func (g *Greeting) String(name string) string {
+	return g.Text + ", " + name
+}
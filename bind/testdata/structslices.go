@@ -0,0 +1,29 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package structslices
+
+type Point struct {
+	X, Y int
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Path struct {
+	Points []Point
+}
+
+func Line(n int) []Point { return nil }
+
+func Centroid(ps []Point) *Point { return nil }
+
+func Refs(ps []*Point) []*Point { return ps }
+
+func TotalArea(shapes []Shape) float64 { return 0 }
+
+type Canvas interface {
+	Draw(ps []Point) []Shape
+}
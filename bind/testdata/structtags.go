@@ -0,0 +1,21 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package structtags
+
+type Record struct {
+	ID     int    `gomobile:"name=identifier"`
+	Name   string `json:"name"`
+	Secret string `gomobile:"-"`
+	Base
+}
+
+// Base is embedded in Record, which binds the tagged names of its
+// promoted fields too.
+type Base struct {
+	Version int `gomobile:"name=revision"`
+	Cache   int `gomobile:"-"`
+}
+
+func NewRecord() *Record { return new(Record) }
@@ -0,0 +1,24 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package times
+
+import "time"
+
+func Add(t time.Time, ms int) time.Time {
+	return t.Add(time.Duration(ms) * time.Millisecond)
+}
+
+type Event struct {
+	At time.Time
+}
+
+func (e *Event) Since(t time.Time) int64 {
+	return int64(e.At.Sub(t) / time.Millisecond)
+}
+
+type Clock interface {
+	Now() time.Time
+	Set(t time.Time)
+}
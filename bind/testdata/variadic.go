@@ -0,0 +1,31 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package variadic
+
+import "fmt"
+
+func Sum(xs ...int) int {
+	s := 0
+	for _, x := range xs {
+		s += x
+	}
+	return s
+}
+
+func Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(format, args...)
+}
+
+type S struct{}
+
+func (s *S) Join(sep string, ss ...string) string { return "" }
+
+func Count(s ...*S) int { return len(s) }
+
+type I interface {
+	F()
+}
+
+func Each(is ...I) {}
@@ -0,0 +1,15 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vars
+
+var (
+	Count int
+	Name  string
+
+	// Done cannot be bound, and is skipped.
+	Done chan bool
+)
+
+var unexported int
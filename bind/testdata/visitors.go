@@ -0,0 +1,22 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+// A Visitor is declared before the Node it visits.
+type Visitor interface {
+	Visit(n Node) Visitor
+}
+
+// A Node refers to itself and to the Visitor.
+type Node interface {
+	Accept(v Visitor)
+	Child(i int) Node
+}
+
+func Walk(v Visitor, n Node) {
+	if w := v.Visit(n); w != nil {
+		n.Accept(w)
+	}
+}
@@ -32,6 +32,27 @@ The generated Go package, go_hi, must be linked into your Go program:
 
 	import _ "github.com/crawshaw/hi/go_hi"
 
+The generated Java class, Hi, is in the Java package go.hi. The -javapkg
+flag replaces the go prefix, so with -javapkg=com.example the class is
+com.example.hi.Hi. Packages bound with the same -javapkg are generated
+in distinct Java packages named after the Go packages.
+
+The generated classes use the support classes go.Seq and go.Go, found
+in golang.org/x/mobile/bind/java and golang.org/x/mobile/app. The
+-supportpkg flag names another Java package for them, so that libraries
+bound separately do not collide in one app. The support classes must
+then be copied into that package, and their native code compiled with
+the matching JNI names; gomobile bind -supportpkg does both.
+
+Packages named on the same command line are bound together: the API of
+each package may use the exported struct and interface types of the
+others, which refer to the Java classes and Go bindings generated for
+them. The bindings of packages bound together must be generated and
+linked together, with the same list of packages:
+
+	gobind -lang=go -outdir=go example.com/shapes example.com/canvas
+	gobind -lang=java -outdir=java example.com/shapes example.com/canvas
+
 Type restrictions
 
 At present, only a subset of Go types are supported.
@@ -45,17 +66,152 @@ Supported types include:
 
 	- Byte slice types.
 
+	- Slices of structs, of pointers to structs, and of interfaces,
+	  as java.util.List. Slices are copied across the language
+	  boundary, to an ArrayList in Java, with an object for each
+	  element; the elements of a slice of struct values are copies.
+	  A nil slice is null, and an empty slice an empty List. The
+	  elements cannot be null.
+
+	- Arrays of boolean, string, signed integer, byte and floating
+	  point types, as Java arrays. A [3]float32 is a float[]; the
+	  array is copied across the language boundary, and passing an
+	  array of another length than 3 throws an IllegalArgumentException
+	  in Java.
+
+	- time.Time, as java.util.Date. Times are truncated to the
+	  millisecond and arrive in Go in the UTC location. The zero
+	  time.Time is a null Date, and a null Date is the zero time.Time.
+
+	- io.Reader and io.Writer, as java.io.InputStream and
+	  java.io.OutputStream. The streams call back into the other
+	  language for each read and write, moving at most 64 KB per
+	  call, so nothing is buffered whole. io.EOF ends an InputStream,
+	  other errors are IOExceptions in Java, and the IOExceptions of
+	  a Java stream are errors in Go. Closing a Go stream closes the
+	  Go value if it is an io.Closer, and the Go proxies of Java
+	  streams are io.Closers. A stream passed to the other language
+	  and back is the original stream.
+
+	- Enum types: named int, int32 or int64 types with exported
+	  constants of the type. Each is bound to a Java enum of its
+	  constants, in order of their values, with a value method
+	  returning the Go value and a static fromValue method mapping
+	  a Go value back to a constant. A value that is not one of the
+	  constants cannot be passed: fromValue throws an
+	  IllegalArgumentException in Java, and Go panics.
+
+	- Constants of boolean, string, signed integer, byte and floating
+	  point types, as public static final fields of the package class.
+	  Untyped constants have the Java type of their default Go type:
+	  long for integers, int for runes and double for floats. Strings
+	  are escaped for Java, and untyped floating point constants
+	  beyond the range of a double are Double.POSITIVE_INFINITY or
+	  Double.NEGATIVE_INFINITY. Constants of an enum type are bound
+	  with their enum.
+
+	- Variables of boolean, string, signed integer, byte and floating
+	  point types, []byte, time.Time, and the map, slice, enum,
+	  interface and struct pointer types supported as parameters.
+	  A variable Count is bound to the static methods getCount and
+	  setCount of the package class, which copy its value across the
+	  language boundary. These accesses are serialized with each
+	  other, but not with the Go code using the variable. Variables
+	  of other types are skipped with a warning.
+
+	- Map types, as java.util.Map. Keys must be of a string, signed
+	  integer, floating point or enum type, and values of any other
+	  supported type except channels and error. Maps are copied
+	  across the language boundary, to a HashMap in Java, so changes
+	  to a map are not seen by the other language. A nil map is null.
+
 	- Any function type all of whose parameters and results have
 	  supported types. Functions must return either no results,
 	  one result, or two results where the type of the second is
 	  the built-in 'error' type.
 
+	- Functions and struct methods returning more than one value,
+	  optionally followed by an error. In Java they return an object
+	  of a class named after the function with a Result suffix, such
+	  as DivModResult for DivMod, holding a final field for each
+	  value. The fields are named after the Go results, or r0, r1,
+	  and so on for unnamed results. The values cannot be channels
+	  or functions, and interface methods cannot return more than
+	  one value. Functions returning a value and a bool, the comma-ok
+	  idiom, return the value alone; see Comma-ok results below.
+
+	- Variadic parameters of functions and struct methods, as Java
+	  varargs. The elements must be of a type supported as a map
+	  value, except byte, int8 and int16; a ...interface{} parameter
+	  is an Object... parameter whose elements may be null, String,
+	  Long (int in Go), Integer (int32), Double (float64), Float
+	  (float32), byte[] or Go objects. Other values throw an
+	  IllegalArgumentException. Interface methods cannot be variadic.
+
+	- Function types, as the only result of functions and struct
+	  methods. The parameters and results of the returned function
+	  must be of a type supported as a map value, or an error result
+	  as above. In Java the result is an interface with a single call
+	  method, implemented by calling the Go function value, which is
+	  released when the Java object is collected. Interface methods
+	  cannot return functions, and functions cannot be passed to Go.
+
+	- Channel types, as parameters of functions and struct methods.
+	  The channel must be bidirectional or send-only, and its element
+	  type must be a signed integer, floating point, string, or byte
+	  slice type, a pointer to an exported struct, or an exported
+	  interface type. In Java the parameter is a Sink<T>, with a send
+	  method called for every value the Go function sends and a close
+	  method called when it closes the channel. Interface methods,
+	  results and struct fields cannot use channels.
+
 	- Any interface type, all of whose exported methods have
 	  supported function types.
 
 	- Any struct type, all of whose exported methods have
 	  supported function types and all of whose exported fields
-	  have supported types.
+	  have supported types. The exported fields and methods promoted
+	  from embedded structs are bound as well, following the Go
+	  rules for shadowing. A name promoted from more than one
+	  embedded struct at the same depth is ambiguous in Go; it is
+	  omitted with a warning.
+
+	  A field tagged gomobile:"-" is not bound, and a field tagged
+	  gomobile:"name=identifier" is bound under the given name, so
+	  that the Go field ID is read with getIdentifier in Java. The Go
+	  name is unchanged. It is an error for two fields to be bound
+	  under the same name.
+
+	  Structs are passed by reference, as pointers: the Java object
+	  refers to the Go struct, and calling a method with a pointer
+	  receiver or setting a field changes that struct, as seen by Go.
+	  Methods with value receivers are called on a copy. A struct
+	  value stored in an interface, rather than a pointer to it, is
+	  copied as it is passed to Java: each value passed is a Java
+	  object of its own, unequal to the others.
+
+	  The Java objects of Go structs, and of Go values of interface
+	  types, are equal if they refer to the same Go object, and have
+	  the same hashCode, however often the object was passed to Java.
+	  Their toString method calls the String method of a Go type
+	  implementing fmt.Stringer, and otherwise lists the fields of a
+	  struct.
+
+	- Error types: exported struct types T for which *T implements
+	  error. Their Java classes extend Exception, with the Go Error
+	  method as the message. A Go function returning an error of one
+	  of these types throws it in Java, so its fields can be read in
+	  a catch clause. Errors of other types are thrown as Exceptions
+	  with the error message. The errors wrapped by an error, those
+	  returned in turn by its Unwrap method, as for errors.Unwrap, are
+	  the chain of causes of the exception, each an Exception with the
+	  message of the wrapped error.
+
+	- Contexts: a function or method whose first parameter is a
+	  context.Context takes a go.Seq.Cancellable in Java. Calling its
+	  cancel method cancels the contexts of the calls it was passed to.
+	  The context is also cancelled when the Go function returns.
+	  Contexts are not supported in interface methods.
 
 Unexported symbols have no effect on the cross-language interface, and
 as such are not restricted.
@@ -63,8 +219,69 @@ as such are not restricted.
 The set of supported types will eventually be expanded to cover all Go
 types, but this is a work in progress.
 
-Exceptions and panics are not yet supported. If either pass a language
-boundary, the program will exit.
+Nullability annotations
+
+With -annotations=androidx or -annotations=javax, the Java methods whose
+results may be null are marked with that package's Nullable annotation,
+so Kotlin code sees nullable types instead of platform types. Results
+of Java primitive types are not annotated.
+
+Comma-ok results
+
+A function or struct method returning exactly two results, a value and
+the predeclared bool type, follows the comma-ok idiom:
+
+	func Lookup(k string) (v Value, ok bool)
+
+If its results are named, the bool must be named ok. By default its Java
+method returns the value, or null when the bool is false, so primitive
+values are boxed: Lookup returning (int, bool) returns a Long. With
+-annotations, the result is marked Nullable. With -commaok=result, the
+method instead returns a result class of both values, as for other
+functions returning multiple values.
+
+The other multiple results, such as (n int, valid bool), a named bool
+not named ok, (T, error) or a value, a bool and an error, are not
+comma-ok results. Interface methods cannot return a value and a bool.
+
+Init functions
+
+A package-level function named Init returning nothing or an error is
+the init function of the package, for the one-time setup the other
+calls need, such as setting API keys or opening a database:
+
+	func Init(apiKey string, dbPath string) error
+
+Its Java method may be called any number of times, from any thread: the
+binding runs the Go function on the first call only, and every call
+returns, or throws, as that first call did. Calls made while it runs
+wait for it to return. The arguments of the later calls are ignored.
+An Init function taking a context or variadic parameters, and methods
+named Init, are bound as any other function.
+
+Like every bound function, Init can only be called once Go.init has
+loaded the library and started the Go runtime, which runs the init
+functions and variable initializers of the Go packages first. Go code
+cannot call the init function of the package through the binding, so a
+Go caller of Init is not guarded.
+
+Direct byte buffers
+
+By default a []byte parameter is bound as a Java byte[], which is
+copied into Go memory on every call. With -bytebuffer, []byte parameters
+of functions and struct methods are bound as java.nio.ByteBuffer instead,
+and Go reads the remaining bytes of the buffer, from its position to its
+limit, in place. The buffer must be direct, as returned by
+ByteBuffer.allocateDirect; passing any other buffer throws an
+IllegalArgumentException.
+
+The Go slice refers to the buffer's memory and is only valid until
+the call returns. The Go function must not retain the slice, or use it
+from another goroutine, after it returns. The Java caller must not
+modify the buffer during the call.
+
+Results and the parameters of interface methods are still bound as
+byte[]: Go cannot lend its memory to Java beyond a call.
 
 Passing Go objects to foreign languages
 
@@ -120,7 +337,7 @@ gobind generates a Java stub that can be used to implement a Printer:
 		public interface Printer {
 			public void Print(String s);
 
-			public static abstract class Stub implements Printer {
+			public static abstract class Stub implements Printer, go.Seq.Object {
 				...
 			}
 
@@ -131,7 +348,10 @@ gobind generates a Java stub that can be used to implement a Printer:
 	}
 
 You can extend Myfmt.Printer.Stub to implement the Printer interface, and
-pass it to Go using the PrintHello package function:
+pass it to Go using the PrintHello package function. A class that
+implements Printer without extending Stub can be passed too. It is
+wrapped in a new Stub each time it is passed to Go, so extending Stub is
+cheaper and lets Go see the same object every time.
 
 	public class SysPrint extends Myfmt.Printer.Stub {
 		public void Print(String s) {
@@ -144,6 +364,25 @@ The Java implementation can be used like so:
 	Myfmt.Printer printer = new SysPrint();
 	Myfmt.PrintHello(printer);
 
+Go keeps a Java object alive for as long as it holds a reference to it.
+Every time the object is passed to Go counts as a reference, released
+when the Go garbage collector finds it unreachable.
+
+Exceptions and panics
+
+A Java implementation of an interface method with an error result
+returns an error to Go by throwing an Exception. The error message is
+the message of the exception, or its class if it has none. If a method
+without an error result throws, or any method throws an Error, the Go
+call panics with a *seq.Exception, of the package
+golang.org/x/mobile/bind/seq, describing it. Go code may recover it, as
+it recovers any panic.
+
+In the other direction, a Go function called from Java that panics
+throws a RuntimeException with the panic value in Java. An exception of
+a Java callback left unrecovered by Go is thrown to the Java caller with
+its original description.
+
 Avoid reference cycles
 
 The language bindings maintain a reference to each object that has been
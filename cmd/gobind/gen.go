@@ -23,42 +23,62 @@ import (
 	"golang.org/x/tools/go/types"
 )
 
-func genPkg(pkg *build.Package) {
-	if len(pkg.CgoFiles) > 0 {
-		errorf("gobind: cannot use cgo-dependent package as service definition: %s", pkg.CgoFiles[0])
-		return
-	}
-
-	files := parseFiles(pkg.Dir, pkg.GoFiles)
-	if len(files) == 0 {
-		return // some error has been reported
-	}
-
+// genPkgs generates the bindings of pkgs. The packages are bound
+// together, so each may use the types of the others.
+func genPkgs(pkgs []*build.Package) {
 	conf := loader.Config{
 		Fset: fset,
 	}
 	conf.TypeChecker.Error = func(err error) {
 		errorf("%v", err)
 	}
-	conf.CreateFromFiles(pkg.ImportPath, files...)
+	for _, pkg := range pkgs {
+		if len(pkg.CgoFiles) > 0 {
+			errorf("gobind: cannot use cgo-dependent package as service definition: %s", pkg.CgoFiles[0])
+			return
+		}
+		files := parseFiles(pkg.Dir, pkg.GoFiles)
+		if len(files) == 0 {
+			return // some error has been reported
+		}
+		conf.CreateFromFiles(pkg.ImportPath, files...)
+	}
 	program, err := conf.Load()
 	if err != nil {
 		errorf("%v", err)
 		return
 	}
-	p := program.Created[0].Pkg
+	var bound []*types.Package
+	for _, info := range program.Created {
+		bound = append(bound, info.Pkg)
+	}
+	for _, p := range bound {
+		genPkg(p, bound)
+	}
+}
 
+func genPkg(p *types.Package, bound []*types.Package) {
 	w, closer, err := writer(*lang, p)
 	if err != nil {
 		errorf("%v", err)
 		return
 	}
 
+	opts := &bind.Options{
+		Annotations: *annotations,
+		ByteBuffers: *byteBuffers,
+		CommaOK:     *commaOK,
+		JavaPkg:     *javaPkg,
+		SupportPkg:  *supportPkg,
+	}
+	if len(bound) > 1 {
+		opts.Packages = bound
+	}
 	switch *lang {
 	case "java":
-		err = bind.GenJava(w, fset, p)
+		err = bind.GenJavaOptions(w, fset, p, opts)
 	case "go":
-		err = bind.GenGo(w, fset, p)
+		err = bind.GenGoOptions(w, fset, p, opts)
 	default:
 		errorf("unknown target language: %q", *lang)
 	}
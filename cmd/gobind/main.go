@@ -10,11 +10,19 @@ import (
 	"go/build"
 	"log"
 	"os"
+
+	"golang.org/x/mobile/bind"
 )
 
 var (
 	lang   = flag.String("lang", "java", "target language for bindings, either java or go.")
 	outdir = flag.String("outdir", "", "result will be written to the directory instead of stdout.")
+
+	annotations = flag.String("annotations", "", "Java nullability annotations package, either androidx or javax.")
+	javaPkg     = flag.String("javapkg", "", "Java package containing the generated Java package, default go.")
+	supportPkg  = flag.String("supportpkg", "", "Java package of the support classes Seq and Go, default go.")
+	byteBuffers = flag.Bool("bytebuffer", false, "bind []byte parameters as direct java.nio.ByteBuffers.")
+	commaOK     = flag.String("commaok", "nullable", "Java result of (T, bool) functions, either nullable or result.")
 )
 
 var usage = `The Gobind tool generates Java language bindings for Go.
@@ -23,19 +31,24 @@ For usage details, see doc.go.`
 
 func main() {
 	flag.Parse()
+	bind.Warnf = func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "gobind: warning: "+format+"\n", args...)
+	}
 
 	cwd, err := os.Getwd()
 	if err != nil {
 		log.Fatal(err)
 	}
+	var pkgs []*build.Package
 	for _, arg := range flag.Args() {
 		pkg, err := build.Import(arg, cwd, 0)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s: %v\n", arg, err)
 			os.Exit(1)
 		}
-		genPkg(pkg)
+		pkgs = append(pkgs, pkg)
 	}
+	genPkgs(pkgs)
 	os.Exit(exitStatus)
 }
 
@@ -0,0 +1,206 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// An Android App Bundle (AAB) is the publishing format for Google Play.
+// It is a signed ZIP archive, like an APK, but the files of the app are
+// placed in a module directory and the manifest is stored as a protocol
+// buffer instead of binary XML:
+//
+//	BundleConfig.pb
+//	base/manifest/AndroidManifest.xml
+//	base/lib/<abi>/lib<name>.so
+//	base/assets/...
+//
+// The protocol buffer messages are defined by aapt2 in Resources.proto
+// and by bundletool in config.proto:
+//
+//	https://android.googlesource.com/platform/frameworks/base/+/master/tools/aapt2/Resources.proto
+//	https://github.com/google/bundletool/blob/master/src/main/proto/config.proto
+//
+// Only the handful of messages needed to describe a gomobile manifest are
+// encoded here.
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// bundletoolVersion is the bundletool version recorded in BundleConfig.pb.
+const bundletoolVersion = "1.15.6"
+
+// aabEntryName maps the name of a file in an APK to its name in an
+// App Bundle base module.
+func aabEntryName(name string) string {
+	if name == "AndroidManifest.xml" {
+		return "base/manifest/AndroidManifest.xml"
+	}
+	return "base/" + name
+}
+
+// bundleConfig returns the encoded BundleConfig message.
+func bundleConfig() []byte {
+	var bundletool []byte
+	bundletool = appendProtoString(bundletool, 2, bundletoolVersion) // version
+	return appendProtoBytes(nil, 1, bundletool)                      // bundletool
+}
+
+// protoXML converts XML into the aapt2 XmlNode protocol buffer format.
+func protoXML(r io.Reader) ([]byte, error) {
+	d := xml.NewDecoder(r)
+
+	// Each open element is encoded once its children are known.
+	type element struct {
+		b     []byte // XmlElement fields encoded so far
+		nsdec []byte // namespace declarations
+	}
+	var stack []*element
+	var root []byte
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			e := new(element)
+			var attrs []byte
+			for _, a := range tok.Attr {
+				if a.Name.Space == "xmlns" {
+					var ns []byte
+					ns = appendProtoString(ns, 1, a.Name.Local) // prefix
+					ns = appendProtoString(ns, 2, a.Value)      // uri
+					e.nsdec = appendProtoBytes(e.nsdec, 1, ns)
+					continue
+				}
+				pa, err := protoAttr(a)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", a.Name.Local, err)
+				}
+				attrs = appendProtoBytes(attrs, 4, pa)
+			}
+			e.b = append(e.b, e.nsdec...)
+			e.b = appendProtoString(e.b, 2, tok.Name.Space) // namespace_uri
+			e.b = appendProtoString(e.b, 3, tok.Name.Local) // name
+			e.b = append(e.b, attrs...)
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("aab: unexpected end element %s", tok.Name.Local)
+			}
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			node := appendProtoBytes(nil, 1, e.b) // element
+			if len(stack) == 0 {
+				root = node
+				continue
+			}
+			parent := stack[len(stack)-1]
+			parent.b = appendProtoBytes(parent.b, 5, node) // child
+		case xml.CharData:
+			text := strings.TrimSpace(string(tok))
+			if text == "" || len(stack) == 0 {
+				continue
+			}
+			node := appendProtoString(nil, 2, text) // text
+			parent := stack[len(stack)-1]
+			parent.b = appendProtoBytes(parent.b, 5, node)
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			// Not represented in the proto format.
+		default:
+			return nil, fmt.Errorf("aab: unexpected token: %v (%T)", tok, tok)
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("aab: no root element")
+	}
+	return root, nil
+}
+
+// protoAttr encodes an XmlAttribute. Android attributes that the platform
+// reads as numbers or booleans are compiled, as aapt2 does.
+func protoAttr(attr xml.Attr) ([]byte, error) {
+	var b []byte
+	b = appendProtoString(b, 1, attr.Name.Space) // namespace_uri
+	b = appendProtoString(b, 2, attr.Name.Local) // name
+	b = appendProtoString(b, 3, attr.Value)      // value
+	if attr.Name.Space != "http://schemas.android.com/apk/res/android" {
+		return b, nil
+	}
+	if c, ok := resourceCodes[attr.Name.Local]; ok {
+		b = appendProtoVarint(b, 5, uint64(c)) // resource_id
+	}
+
+	var prim []byte
+	switch attr.Name.Local {
+	case "versionCode", "minSdkVersion", "targetSdkVersion":
+		v, err := strconv.Atoi(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		prim = appendProtoVarint(prim, 6, uint64(int64(v))) // int_decimal_value
+	case "hasCode", "debuggable":
+		v, err := strconv.ParseBool(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		bv := uint64(0)
+		if v {
+			bv = 1
+		}
+		prim = appendProtoVarint(prim, 8, bv) // boolean_value
+	case "configChanges":
+		v := uint32(0)
+		for _, c := range strings.Split(attr.Value, "|") {
+			v |= configChanges[c]
+		}
+		prim = appendProtoVarint(prim, 7, uint64(v)) // int_hexadecimal_value
+	default:
+		return b, nil
+	}
+	item := appendProtoBytes(nil, 7, prim)   // prim
+	return appendProtoBytes(b, 6, item), nil // compiled_item
+}
+
+// Protocol buffer wire types.
+const (
+	protoVarint = 0
+	protoBytes  = 2
+)
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendUvarint(b, uint64(field)<<3|protoVarint)
+	return appendUvarint(b, v)
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = appendUvarint(b, uint64(field)<<3|protoBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendProtoString appends a string field. Empty strings are the proto3
+// default and are omitted.
+func appendProtoString(b []byte, field int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	return appendProtoBytes(b, field, []byte(v))
+}
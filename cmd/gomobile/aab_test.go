@@ -0,0 +1,118 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// protoField is a decoded protocol buffer field.
+type protoField struct {
+	num    int
+	varint uint64
+	bytes  []byte
+}
+
+func decodeProto(t *testing.T, b []byte) []protoField {
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad field key in %x", b)
+		}
+		b = b[n:]
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case protoVarint:
+			f.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("bad varint in %x", b)
+			}
+			b = b[n:]
+		case protoBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || int(l) > len(b[n:]) {
+				t.Fatalf("bad length in %x", b)
+			}
+			f.bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func field(t *testing.T, fields []protoField, num int) protoField {
+	for _, f := range fields {
+		if f.num == num {
+			return f
+		}
+	}
+	t.Fatalf("field %d not found", num)
+	return protoField{}
+}
+
+func TestProtoXML(t *testing.T) {
+	const manifest = `<?xml version="1.0" encoding="utf-8"?>
+<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="org.golang.todo.basic">
+	<uses-sdk android:minSdkVersion="9" />
+</manifest>`
+	b, err := protoXML(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := decodeProto(t, field(t, decodeProto(t, b), 1).bytes) // XmlNode.element
+	ns := decodeProto(t, field(t, root, 1).bytes)                // namespace_declaration
+	if got := string(field(t, ns, 1).bytes); got != "android" {
+		t.Errorf("namespace prefix=%q, want android", got)
+	}
+	if got := string(field(t, root, 3).bytes); got != "manifest" {
+		t.Errorf("root name=%q, want manifest", got)
+	}
+	pkg := decodeProto(t, field(t, root, 4).bytes) // attribute
+	if got := string(field(t, pkg, 3).bytes); got != "org.golang.todo.basic" {
+		t.Errorf("package=%q, want org.golang.todo.basic", got)
+	}
+
+	child := decodeProto(t, field(t, root, 5).bytes)    // child XmlNode
+	usesSDK := decodeProto(t, field(t, child, 1).bytes) // element
+	if got := string(field(t, usesSDK, 3).bytes); got != "uses-sdk" {
+		t.Errorf("child name=%q, want uses-sdk", got)
+	}
+	minSDK := decodeProto(t, field(t, usesSDK, 4).bytes)
+	if got, want := field(t, minSDK, 5).varint, uint64(0x0101020c); got != want {
+		t.Errorf("minSdkVersion resource_id=%#x, want %#x", got, want)
+	}
+	item := decodeProto(t, field(t, minSDK, 6).bytes) // compiled_item
+	prim := decodeProto(t, field(t, item, 7).bytes)
+	if got := field(t, prim, 6).varint; got != 9 {
+		t.Errorf("minSdkVersion int_decimal_value=%d, want 9", got)
+	}
+}
+
+func TestBundleConfig(t *testing.T) {
+	bundletool := decodeProto(t, field(t, decodeProto(t, bundleConfig()), 1).bytes)
+	if got := string(field(t, bundletool, 2).bytes); got != bundletoolVersion {
+		t.Errorf("bundletool version=%q, want %q", got, bundletoolVersion)
+	}
+}
+
+func TestAABEntryName(t *testing.T) {
+	tests := []struct{ apk, aab string }{
+		{"AndroidManifest.xml", "base/manifest/AndroidManifest.xml"},
+		{"lib/armeabi/libbasic.so", "base/lib/armeabi/libbasic.so"},
+		{"assets/img.png", "base/assets/img.png"},
+	}
+	for _, tt := range tests {
+		if got := aabEntryName(tt.apk); got != tt.aab {
+			t.Errorf("aabEntryName(%q)=%q, want %q", tt.apk, got, tt.aab)
+		}
+	}
+}
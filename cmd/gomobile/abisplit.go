@@ -0,0 +1,90 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// buildABISplit is the value of the -abisplit flag: "true" to write one
+// APK per ABI, "universal" to also write the APK holding every ABI, and
+// "false" or "" to write only that one.
+var buildABISplit abiSplitFlag
+
+// abiSplitFlag is a boolean flag that also accepts the value universal.
+type abiSplitFlag string
+
+func (f *abiSplitFlag) Set(s string) error {
+	if s == "universal" {
+		*f = "universal"
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("must be true, false or universal")
+	}
+	*f = abiSplitFlag(strconv.FormatBool(v))
+	return nil
+}
+
+func (f *abiSplitFlag) String() string   { return string(*f) }
+func (f *abiSplitFlag) IsBoolFlag() bool { return true }
+
+// split reports whether an APK is written per ABI.
+func (f abiSplitFlag) split() bool { return f == "true" || f == "universal" }
+
+// apkLibDir returns the directory under lib/ of the APK holding the
+// libraries built for goarch. The 32-bit ARM libraries are packed in
+// armeabi, which the armeabi-v7a devices load as well. Architectures
+// with no known ABI, built with -targets, are packed in the directory
+// named after their GOARCH.
+func apkLibDir(goarch string) string {
+	if goarch == "arm" {
+		return "armeabi"
+	}
+	if abi, ok := androidABIs[goarch]; ok {
+		return abi
+	}
+	return goarch
+}
+
+// abiSplitPath returns the name of the APK of goarch split from the APK
+// out: app.apk becomes app-arm.apk.
+func abiSplitPath(out, goarch string) string {
+	return strings.TrimSuffix(out, ".apk") + "-" + goarch + ".apk"
+}
+
+// abiLibs returns the libraries of libs packed in the directory abi.
+func abiLibs(libs []apkLib, abi string) []apkLib {
+	var l []apkLib
+	for _, lib := range libs {
+		if lib.abi == abi {
+			l = append(l, lib)
+		}
+	}
+	return l
+}
+
+// writeABISplits writes an APK per architecture of archs holding c
+// with only the libraries of its ABI, named by abiSplitPath after out.
+// With -abisplit=universal, c is written to out as well.
+func writeABISplits(out string, archs []string, c *apkContents, privKey *rsa.PrivateKey, cert []byte) error {
+	for _, arch := range archs {
+		path := abiSplitPath(out, arch)
+		planf("split %s for lib/%s into %s", out, apkLibDir(arch), path)
+		split := *c
+		split.libs = abiLibs(c.libs, apkLibDir(arch))
+		if err := writeAPK(path, &split, privKey, cert); err != nil {
+			return err
+		}
+	}
+	if buildABISplit == "universal" {
+		return writeAPK(out, c, privKey, cert)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestABISplitFlag(t *testing.T) {
+	defer func() { buildABISplit = "" }()
+	tests := []struct {
+		args  []string
+		want  abiSplitFlag
+		split bool
+	}{
+		{nil, "", false},
+		{[]string{"-abisplit"}, "true", true},
+		{[]string{"-abisplit=false"}, "false", false},
+		{[]string{"-abisplit=universal"}, "universal", true},
+	}
+	for _, tt := range tests {
+		buildABISplit = ""
+		if err := cmdBuild.flag.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if buildABISplit != tt.want || buildABISplit.split() != tt.split {
+			t.Errorf("%q: -abisplit %q, split %v, want %q, %v", tt.args, buildABISplit, buildABISplit.split(), tt.want, tt.split)
+		}
+	}
+	if err := buildABISplit.Set("fat"); err == nil {
+		t.Error("-abisplit=fat: got nil error")
+	}
+}
+
+func TestABISplitPath(t *testing.T) {
+	if got, want := abiSplitPath("out/app.apk", "arm"), "out/app-arm.apk"; got != want {
+		t.Errorf("abiSplitPath = %q, want %q", got, want)
+	}
+}
+
+func TestWriteABISplits(t *testing.T) {
+	defer func() { buildABISplit = "" }()
+	block, _ := pem.Decode([]byte(debugCert))
+	if block == nil {
+		t.Fatal("no cert")
+	}
+	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// contents writes the given libraries and returns the APK contents
+	// holding them.
+	contents := func(libs ...apkLib) *apkContents {
+		c := &apkContents{manifest: []byte("manifest")}
+		for _, l := range libs {
+			l.path = filepath.Join(dir, l.abi+"-"+l.name)
+			if err := ioutil.WriteFile(l.path, []byte(l.abi), 0644); err != nil {
+				t.Fatal(err)
+			}
+			c.libs = append(c.libs, l)
+		}
+		return c
+	}
+
+	apkEntries := func(path string) []string {
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		var names []string
+		for _, f := range r.File {
+			if filepath.Ext(f.Name) == ".so" {
+				names = append(names, f.Name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// The libraries of -targets=android/arm64,android/riscv64.
+	out := filepath.Join(dir, "app.apk")
+	buildABISplit = "universal"
+	c := contents(
+		apkLib{abi: apkLibDir("arm64"), name: "libapp.so"},
+		apkLib{abi: apkLibDir("riscv64"), name: "libapp.so"},
+	)
+	if err := writeABISplits(out, []string{"arm64", "riscv64"}, c, privKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		path string
+		want []string
+	}{
+		{"app-arm64.apk", []string{"lib/arm64-v8a/libapp.so"}},
+		{"app-riscv64.apk", []string{"lib/riscv64/libapp.so"}},
+		{"app.apk", []string{"lib/arm64-v8a/libapp.so", "lib/riscv64/libapp.so"}},
+	} {
+		if got := apkEntries(filepath.Join(dir, tt.path)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: libraries %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Without -targets, the arm libraries, with OpenAL, are split into
+	// one APK. Without universal, only the split APK is written.
+	if err := os.Remove(out); err != nil {
+		t.Fatal(err)
+	}
+	buildABISplit = "true"
+	c = contents(
+		apkLib{abi: "armeabi", name: "libapp.so"},
+		apkLib{abi: "armeabi", name: "libopenal.so"},
+	)
+	if err := writeABISplits(out, buildArchs, c, privKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"lib/armeabi/libapp.so", "lib/armeabi/libopenal.so"}
+	if got := apkEntries(filepath.Join(dir, "app-arm.apk")); !reflect.DeepEqual(got, want) {
+		t.Errorf("app-arm.apk: libraries %q, want %q", got, want)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("-abisplit wrote the universal APK %s", out)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/mobile/bind"
+)
+
+// checkAppID reports whether id is a valid application ID: a package
+// name in reverse DNS form, such as com.example.app, of at least two
+// elements separated by dots. Each element starts with a letter,
+// followed by letters, digits and underscores, and is not a Java
+// keyword.
+func checkAppID(id string) error {
+	if id == "" {
+		return fmt.Errorf("application ID is empty")
+	}
+	elems := strings.Split(id, ".")
+	if len(elems) < 2 {
+		return fmt.Errorf("application ID %q must have at least two elements, as in com.example.app", id)
+	}
+	for _, e := range elems {
+		if e == "" {
+			return fmt.Errorf("application ID %q has an empty element", id)
+		}
+		if !isASCIILetter(e[0]) {
+			return fmt.Errorf("application ID %q: element %q must start with a letter", id, e)
+		}
+		for i := 0; i < len(e); i++ {
+			if c := e[i]; !isASCIILetter(c) && !('0' <= c && c <= '9') && c != '_' {
+				return fmt.Errorf("application ID %q: element %q contains %q, only letters, digits and _ are allowed", id, e, c)
+			}
+		}
+		if bind.IsJavaKeyword(e) {
+			return fmt.Errorf("application ID %q: element %q is a Java keyword", id, e)
+		}
+	}
+	return nil
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// defaultAppID returns the application ID of the app of importPath when
+// -appid is not given: the domain of the import path reversed, followed
+// by the rest of the path, so github.com/user/my-app becomes
+// com.github.user.my_app. An import path without a domain, such as that
+// of a directory outside GOPATH, gives org.golang.todo followed by the
+// last element of the path.
+func defaultAppID(importPath string) string {
+	var elems []string
+	parts := strings.Split(importPath, "/")
+	if len(parts) > 1 && strings.Contains(parts[0], ".") {
+		domain := strings.Split(parts[0], ".")
+		for i := len(domain) - 1; i >= 0; i-- {
+			elems = append(elems, domain[i])
+		}
+		elems = append(elems, parts[1:]...)
+	} else {
+		elems = []string{"org", "golang", "todo", path.Base(importPath)}
+	}
+	var id []string
+	for _, e := range elems {
+		if e = appIDElem(e); e != "" {
+			id = append(id, e)
+		}
+	}
+	return strings.Join(id, ".")
+}
+
+// appIDElem turns an element of an import path into an element of an
+// application ID, lower-cased, with the characters not allowed in it
+// replaced by underscores.
+func appIDElem(e string) string {
+	e = strings.Trim(strings.ToLower(e), "_-.")
+	if e == "" {
+		return ""
+	}
+	b := []byte(e)
+	for i, c := range b {
+		if !isASCIILetter(c) && !('0' <= c && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	if !isASCIILetter(b[0]) {
+		b = append([]byte("go"), b...)
+	}
+	if bind.IsJavaKeyword(string(b)) {
+		b = append(b, '_')
+	}
+	return string(b)
+}
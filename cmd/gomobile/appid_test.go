@@ -0,0 +1,106 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckAppID(t *testing.T) {
+	for _, id := range []string{
+		"com.example.app",
+		"org.golang.todo.basic",
+		"com.Example.my_app2",
+	} {
+		if err := checkAppID(id); err != nil {
+			t.Errorf("checkAppID(%q) = %v", id, err)
+		}
+	}
+	for _, id := range []string{
+		"",
+		"app",
+		"com..app",
+		"com.example.",
+		".com.example",
+		"com.example.2app",
+		"com.example._app",
+		"com.example.my-app",
+		"com.example.app!",
+		"com.exämple.app",
+		"com.example.new",
+	} {
+		if err := checkAppID(id); err == nil {
+			t.Errorf("checkAppID(%q) = nil, want error", id)
+		}
+	}
+}
+
+func TestDefaultAppID(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"golang.org/x/mobile/example/basic", "org.golang.x.mobile.example.basic"},
+		{"github.com/User/my-app", "com.github.user.my_app"},
+		{"gopkg.in/app.v1", "in.gopkg.app_v1"},
+		{"example.com/2048", "com.example.go2048"},
+		{"example.com/new", "com.example.new_"},
+		{"example.com/_x-", "com.example.x"},
+		{"myapp", "org.golang.todo.myapp"},
+		{"_/home/user/my-app", "org.golang.todo.my_app"},
+	}
+	for _, tt := range tests {
+		got := defaultAppID(tt.path)
+		if got != tt.want {
+			t.Errorf("defaultAppID(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+		if err := checkAppID(got); err != nil {
+			t.Errorf("defaultAppID(%q): %v", tt.path, err)
+		}
+	}
+}
+
+func TestBuildAppIDInvalid(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	buildN, buildX = false, false // set by -dry-run
+	defer func() {
+		buildPlan = false
+		buildAppID = ""
+		*buildO = ""
+	}()
+
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const src = "package main\n\nimport \"golang.org/x/mobile/app\"\n\nfunc main() { app.Run(app.Callbacks{}) }\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	if err := cmdBuild.flag.Parse([]string{"-dry-run", "-appid", "com.example.my-app"}); err != nil {
+		t.Fatal(err)
+	}
+	err = runBuild(cmdBuild)
+	if err == nil || !strings.Contains(err.Error(), "-appid") {
+		t.Fatalf("runBuild: got %v, want an -appid error", err)
+	}
+	if out := buf.String(); strings.Contains(out, " go build ") {
+		t.Errorf("invalid -appid compiled the app:\n%s", out)
+	}
+}
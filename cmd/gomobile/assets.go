@@ -0,0 +1,130 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// An assetDir is a directory whose files are packed as assets, named
+// by their path under the directory following prefix.
+type assetDir struct {
+	dir    string
+	prefix string // slash-separated, without leading or trailing slash
+}
+
+// An assetFile is a file packed as an asset.
+type assetFile struct {
+	name string // asset name, as passed to app.Open
+	path string // file path
+}
+
+// assetDirIndex is the name of the asset listing the directories of the
+// other assets, one slash-separated name per line. The NDK lists only the
+// files of an asset directory, so app.ReadDir reads the index to find its
+// subdirectories. It must match the name in golang.org/x/mobile/app.
+const assetDirIndex = "_gomobile_dirs"
+
+// parseAssetDirs parses the arguments of the -assets flag, of the form
+// dir or dir:prefix.
+func parseAssetDirs(args []string) ([]assetDir, error) {
+	var dirs []assetDir
+	for _, arg := range args {
+		// Skip the volume name, as in C:\assets, before looking for
+		// the prefix.
+		vol := len(filepath.VolumeName(arg))
+		d := assetDir{dir: arg}
+		if i := strings.LastIndex(arg[vol:], ":"); i >= 0 {
+			d.dir = arg[:vol+i]
+			d.prefix = strings.Trim(path.Clean("/"+arg[vol+i+1:]), "/")
+		}
+		if d.dir == "" {
+			return nil, fmt.Errorf("-assets %q: missing directory", arg)
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// collectAssets returns the files in the asset directories, sorted by
+// name. It is an error for two files to have the same asset name.
+func collectAssets(dirs []assetDir) ([]assetFile, error) {
+	var files []assetFile
+	seen := make(map[string]string) // asset name to file path
+	for _, d := range dirs {
+		fi, err := os.Stat(d.dir)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("asset directory %s is not a directory", d.dir)
+		}
+		err = filepath.Walk(d.dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(d.dir, p)
+			if err != nil {
+				return err
+			}
+			name := path.Join(d.prefix, filepath.ToSlash(rel))
+			if name == assetDirIndex {
+				return fmt.Errorf("asset name %s of %s is reserved", name, p)
+			}
+			if other, ok := seen[name]; ok {
+				return fmt.Errorf("asset %s is provided by both %s and %s", name, other, p)
+			}
+			seen[name] = p
+			files = append(files, assetFile{name: name, path: p})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Sort(byAssetName(files))
+	return files, nil
+}
+
+// assetDirNames returns the directories holding the asset files, sorted
+// by name, for the asset directory index.
+func assetDirNames(files []assetFile) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, f := range files {
+		for dir := path.Dir(f.name); dir != "." && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// copyAsset copies the file at path to w.
+func copyAsset(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+type byAssetName []assetFile
+
+func (s byAssetName) Len() int           { return len(s) }
+func (s byAssetName) Less(i, j int) bool { return s[i].name < s[j].name }
+func (s byAssetName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,105 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAssetDirs(t *testing.T) {
+	dirs, err := parseAssetDirs([]string{"assets", "images:img", "fonts:/ui/fonts/", "data:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []assetDir{
+		{dir: "assets"},
+		{dir: "images", prefix: "img"},
+		{dir: "fonts", prefix: "ui/fonts"},
+		{dir: "data"},
+	}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("parseAssetDirs = %v, want %v", dirs, want)
+	}
+	if _, err := parseAssetDirs([]string{":img"}); err == nil {
+		t.Error("missing directory: got nil error")
+	}
+}
+
+func TestCollectAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-assets-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("assets/hello.txt", "hello")
+	write("images/icons/a.png", "png")
+	write("other/hello.txt", "other")
+
+	assets, err := collectAssets([]assetDir{
+		{dir: filepath.Join(dir, "assets")},
+		{dir: filepath.Join(dir, "images"), prefix: "img"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The asset names are the names the app opens at runtime, and
+	// are stored in the APK under assets/.
+	var names []string
+	for _, a := range assets {
+		names = append(names, a.name)
+	}
+	if want := []string{"hello.txt", "img/icons/a.png"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("asset names %q, want %q", names, want)
+	}
+	if got, want := assets[1].path, filepath.Join(dir, "images", "icons", "a.png"); got != want {
+		t.Errorf("asset img/icons/a.png has path %s, want %s", got, want)
+	}
+
+	_, err = collectAssets([]assetDir{
+		{dir: filepath.Join(dir, "assets")},
+		{dir: filepath.Join(dir, "other")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "hello.txt") {
+		t.Errorf("colliding assets: got %v, want an error naming hello.txt", err)
+	}
+	if _, err := collectAssets([]assetDir{{dir: filepath.Join(dir, "missing")}}); err == nil {
+		t.Error("missing asset directory: got nil error")
+	}
+
+	write("reserved/"+assetDirIndex, "")
+	if _, err := collectAssets([]assetDir{{dir: filepath.Join(dir, "reserved")}}); err == nil {
+		t.Errorf("asset named %s: got nil error", assetDirIndex)
+	}
+}
+
+func TestAssetDirNames(t *testing.T) {
+	files := []assetFile{
+		{name: "hello.txt"},
+		{name: "img/a.png"},
+		{name: "img/icons/b.png"},
+		{name: "ui/fonts/mono/c.ttf"},
+	}
+	want := []string{"img", "img/icons", "ui", "ui/fonts", "ui/fonts/mono"}
+	if got := assetDirNames(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("assetDirNames = %q, want %q", got, want)
+	}
+	if got := assetDirNames(files[:1]); len(got) != 0 {
+		t.Errorf("assetDirNames of top-level files = %q, want none", got)
+	}
+}
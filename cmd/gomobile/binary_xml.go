@@ -214,6 +214,12 @@ const (
 	headerStartElement              = 0x0102
 	headerEndElement                = 0x0103
 	headerCharData                  = 0x0104
+
+	// Chunks of resource tables; see iconTable.
+	headerTable         = 0x0002
+	headerTablePackage  = 0x0200
+	headerTableType     = 0x0201
+	headerTableTypeSpec = 0x0202
 )
 
 func appendU16(b []byte, v uint16) []byte {
@@ -240,6 +246,7 @@ var resourceCodes = map[string]uint32{
 	"versionCode":      0x0101021b,
 	"versionName":      0x0101021c,
 	"minSdkVersion":    0x0101020c,
+	"targetSdkVersion": 0x01010270,
 	"windowFullscreen": 0x0101020d,
 	"label":            0x01010001,
 	"hasCode":          0x0101000c,
@@ -247,6 +254,8 @@ var resourceCodes = map[string]uint32{
 	"name":             0x01010003,
 	"configChanges":    0x0101001f,
 	"value":            0x01010024,
+	"required":         0x0101028e,
+	"icon":             0x01010002,
 }
 
 // http://developer.android.com/reference/android/R.attr.html#configChanges
@@ -382,13 +391,13 @@ func (p *binStringPool) getAttr(attr xml.Attr) (*binAttr, error) {
 
 	// Some android attributes have interesting values.
 	switch attr.Name.Local {
-	case "versionCode", "minSdkVersion":
+	case "versionCode", "minSdkVersion", "targetSdkVersion":
 		v, err := strconv.Atoi(attr.Value)
 		if err != nil {
 			return nil, err
 		}
 		a.data = int(v)
-	case "hasCode", "debuggable":
+	case "hasCode", "debuggable", "required":
 		v, err := strconv.ParseBool(attr.Value)
 		if err != nil {
 			return nil, err
@@ -400,6 +409,22 @@ func (p *binStringPool) getAttr(attr xml.Attr) (*binAttr, error) {
 			v |= configChanges[c]
 		}
 		a.data = v
+	case "icon":
+		// The launcher icon of -icon is the only resource gomobile
+		// writes; other references are left as strings.
+		if attr.Value == launcherIconRef {
+			a.data = resRef(launcherIconID)
+		} else {
+			a.data = p.get(attr.Value)
+		}
+	case "value":
+		// Like aapt, write the boolean meta-data values as booleans,
+		// so Bundle.getBoolean finds them.
+		if attr.Value == "true" || attr.Value == "false" {
+			a.data = attr.Value == "true"
+		} else {
+			a.data = p.get(attr.Value)
+		}
 	default:
 		a.data = p.get(attr.Value)
 	}
@@ -537,9 +562,13 @@ func (e *binStartElement) append(b []byte) []byte {
 type binAttr struct {
 	ns   *bstring
 	name *bstring
-	data interface{} // either int (INT_DEC) or *bstring (STRING)
+	data interface{} // int (INT_DEC), bool, uint32 (INT_HEX), resRef or *bstring (STRING)
 }
 
+// A resRef is the ID of a resource of the app, referenced by an
+// attribute.
+type resRef uint32
+
 func (a *binAttr) append(b []byte) []byte {
 	if a.ns != nil {
 		b = appendU32(b, a.ns.ind)
@@ -570,6 +599,12 @@ func (a *binAttr) append(b []byte) []byte {
 		b = append(b, 0)             // unused padding
 		b = append(b, 0x11)          // INT_HEX
 		b = appendU32(b, uint32(v))
+	case resRef:
+		b = appendU32(b, 0xffffffff) // raw value
+		b = appendU16(b, 8)          // size
+		b = append(b, 0)             // unused padding
+		b = append(b, 0x01)          // REFERENCE
+		b = appendU32(b, uint32(v))
 	case *bstring:
 		b = appendU32(b, v.ind) // raw value
 		b = appendU16(b, 8)     // size
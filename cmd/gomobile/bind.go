@@ -6,6 +6,8 @@ package main
 
 import (
 	"archive/zip"
+	"bytes"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -18,9 +20,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
@@ -34,7 +38,7 @@ import (
 var cmdBind = &command{
 	run:   runBind,
 	Name:  "bind",
-	Usage: "[package]",
+	Usage: "[-outputkind=aar|src] [-o dir] [-annotations=androidx|javax] [-bytebuffer] [-commaok=nullable|result] [-examples] [-count [-json]] [-javapkg name] [-supportpkg name] [-maven group:artifact:version] [-versionname name] [-versioncode code] [-minsdk level] [-androidapi level] [package...]",
 	Short: "build a shared library for android APK and iOS app",
 	Long: `
 Bind generates language bindings like gobind (golang.org/x/mobile/cmd/gobind)
@@ -46,6 +50,17 @@ archives the precompiled Java API stub classes, the compiled shared libraries,
 and all asset files in the /assets subdirectory under the package directory.
 The output AAR file name is '<package_name>.aar'.
 
+Several packages can be bound together into one AAR, named after the
+first package. The API of each package may then use the exported struct
+and interface types of the others, which are bound to the Java classes
+of the package defining them. The bound packages must have distinct
+names, and the assets of all the packages are included.
+
+A package that exports no declaration cannot be bound: bind reports an
+error before building anything. If every declaration a package exports
+is skipped, as variables of types that cannot be bound are, bind warns
+and lists them; 'gomobile list' prints the reasons.
+
 The AAR file is commonly used for binary distribution of an Android library
 project and most Android IDEs support AAR import. For example, in Android
 Studio (1.2+), an AAR file can be imported using the module import wizard
@@ -56,7 +71,119 @@ This command requires 'javac' (version 1.7+) and Android SDK (API level 9
 or newer) to build the library for Android. The environment variable
 ANDROID_HOME must be set to the path to Android SDK.
 
-The -v flag provides verbose output, including the list of packages built.
+The NDK is found as by the build command: an NDK installed on the
+system, named by ANDROID_NDK_HOME or found in ANDROID_HOME, is used
+instead of the NDK of gomobile init if it is compatible.
+
+The -androidapi flag selects the Android SDK platform of that API level
+in ANDROID_HOME to compile the Java classes against, instead of the
+latest installed platform. The -minsdk flag declares the minimum API
+level of the library in the AAR manifest.
+
+The -versionname and -versioncode flags set the android:versionName and
+android:versionCode attributes of the AAR manifest, so the library's
+metadata matches the app that includes it. Unset, the manifest declares
+no version.
+
+The entries of the AAR and its classes.jar are written in a fixed order
+with a fixed modification time, so the same sources produce the same
+archive. If the SOURCE_DATE_EPOCH environment variable is set to a number
+of seconds since the Unix epoch, that time is recorded; otherwise no
+time is recorded.
+
+The -annotations flag marks the results of the generated Java methods that
+may be null with a Nullable annotation, so the API is null-safe in Kotlin.
+It names the annotation package: androidx (androidx.annotation) or javax
+(javax.annotation, JSR 305). The app using the AAR must depend on the
+chosen annotation library.
+
+The Java API of a Go package named p is generated in the Java package
+go.p. The -javapkg flag replaces the go prefix, so with -javapkg=com.example
+the API of package p is in com.example.p.
+
+The Java support classes of the bindings, Go and Seq, are in the Java
+package go, and the native library of the bindings is libgojni.so. An
+app using two AARs bound separately would get both twice, and fails to
+build with duplicate classes. The -supportpkg flag relocates the support
+classes of an AAR to a Java package of its own, such as
+-supportpkg=com.example.mylib.go, together with the JNI functions
+implementing their native methods, and names the native library after
+it, as libgojni_com_example_mylib_go.so. AARs bound with distinct
+-supportpkg packages coexist in one app, each with its own Go runtime.
+The generated classes and the -examples use the relocated classes.
+
+The -bytebuffer flag binds []byte parameters of functions and methods
+as direct java.nio.ByteBuffers, which Go reads in place without copying.
+The bytes are only valid in Go until the call returns. See the gobind
+documentation for details.
+
+Functions and methods returning a value and a bool, the comma-ok idiom
+of func Lookup(k string) (v Value, ok bool), return the value in Java,
+or null when the bool is false. The -commaok=result flag returns a
+result class of both values instead, as for other multiple results.
+See the gobind documentation for the results taken as comma-ok.
+
+The -maven flag, of the form groupId:artifactId:version, prepares the
+AAR for a Maven repository, such as a local repository used by Gradle.
+The AAR is named '<artifactId>-<version>.aar' and written with a POM
+file, '<artifactId>-<version>.pom', declaring the artifact and the
+libraries it depends on, such as the annotation library chosen by
+-annotations, so that they are resolved transitively.
+
+The -outputkind flag selects what bind produces. The default, aar,
+builds the AAR described above. With -outputkind=src, bind stops after
+generating the binding sources and writes them to the directory named
+by -o, '<package_name>-src' by default: the Go binding package in go/
+and the Java API, with the Java support classes it depends on, in java/.
+Neither the Android SDK nor the Android toolchain is needed. With -o -,
+the generated Go and Java sources are written to standard output, each
+preceded by a '// File:' comment line with its path in the directory.
+The -o flag is only used with -outputkind=src.
+
+The -examples flag also writes a Java class Example for each bound
+package, in the Java package of its API, showing how to call it: the
+Go library is loaded, the interfaces are implemented in Java, and a few
+functions and the methods of the structs they construct are called.
+With -outputkind=src, Example.java is written with the Java API and
+compiles with it; otherwise it is written to the directory
+'<package_name>-examples', to copy into an app using the AAR. No
+Objective-C example is written, as bind generates no Objective-C API.
+
+The -count flag prints a summary of the API exported by bind when it
+is done: the numbers of exported types, of their exported methods, of
+exported functions and of declarations skipped, as listed by gomobile
+list, and the size in bytes of the AAR, or of the source directory with
+-outputkind=src. With -json, the summary is printed as a JSON object.
+The -count flag cannot be used with -o -.
+
+The Java support classes of the bindings, the same for every bound
+package, are compiled once and kept in the build cache in
+$GOPATH/pkg/gomobile/cache, keyed by the gomobile version. A cached copy
+that does not match its recorded checksum is compiled again, and the -a
+flag always compiles them. As with gomobile build, the -cache flag or
+the GOMOBILE_CACHE environment variable names another gomobile
+directory.
+
+Run by go generate, as by a line
+
+	//go:generate gomobile bind -outputkind=src -o ../binding .
+
+bind finds the file holding the line from the GOFILE environment
+variable, and resolves relative package paths, the -o flag and the
+other output paths against its directory, instead of the current
+directory. Without package arguments, the package bound is the one in
+that directory, which must be the package named by GOPACKAGE, or the
+package tested by it. Both variables are set by go generate.
+
+By default, the command prints the files it writes. The -v flag also
+prints the steps of the build and the output of the tools it runs,
+including the list of packages built, and the -x flag also prints the
+commands run. The -quiet flag prints nothing but errors, for scripts;
+it cannot be used with -n, -v or -x. Errors are always printed to
+standard error.
+
+The -work flag prints the name of the temporary work directory and keeps
+it in place when the command exits, instead of removing it.
 
 These build flags are shared by the build command.
 For documentation, see 'go help build':
@@ -64,63 +191,175 @@ For documentation, see 'go help build':
 	-i
 	-n
 	-x
+	-work
 	-tags 'tag list'
+	-gcflags '[pattern=]flag list'
+	-ldflags 'flag list'
 `,
 }
 
 // TODO: -mobile
 // TODO: reuse the -o option to specify the output file name?
 
+var (
+	bindAnnotations string // -annotations
+	bindByteBuffers bool   // -bytebuffer
+	bindCommaOK     string // -commaok
+	bindCount       bool   // -count
+	bindExamples    bool   // -examples
+	bindJSON        bool   // -json
+	bindJavaPkg     string // -javapkg
+	bindMaven       string // -maven
+	bindOutputKind  string // -outputkind
+	bindSupportPkg  string // -supportpkg
+	bindVersionName string // -versionname
+	bindVersionCode int    // -versioncode
+)
+
+func init() {
+	cmdBind.flag.StringVar(&bindAnnotations, "annotations", "", "Java nullability annotations package: androidx or javax")
+	cmdBind.flag.StringVar(&bindJavaPkg, "javapkg", "", "Java package containing the generated package, default go")
+	cmdBind.flag.BoolVar(&bindByteBuffers, "bytebuffer", false, "bind []byte parameters as direct ByteBuffers")
+	cmdBind.flag.StringVar(&bindCommaOK, "commaok", "nullable", "Java result of (T, bool) functions: nullable or result")
+	cmdBind.flag.BoolVar(&bindCount, "count", false, "print the numbers of exported declarations and the output size")
+	cmdBind.flag.BoolVar(&bindExamples, "examples", false, "write a Java example calling the API of each package")
+	cmdBind.flag.BoolVar(&bindJSON, "json", false, "print the -count summary as JSON")
+	cmdBind.flag.StringVar(&bindMaven, "maven", "", "Maven coordinates groupId:artifactId:version of the AAR")
+	cmdBind.flag.StringVar(&bindOutputKind, "outputkind", "aar", "output kind: aar or src")
+	cmdBind.flag.StringVar(&bindSupportPkg, "supportpkg", "", "Java package of the support classes Go and Seq, default go")
+	cmdBind.flag.StringVar(&bindVersionName, "versionname", "", "android:versionName of the AAR manifest")
+	cmdBind.flag.IntVar(&bindVersionCode, "versioncode", 0, "android:versionCode of the AAR manifest")
+}
+
 func runBind(cmd *command) error {
 	cwd, err := os.Getwd()
 	if err != nil {
 		panic(err)
 	}
-	args := cmd.flag.Args()
+	dir := generateDir(cwd)
+	bindPkgs, err := importBindPkgs(cmd.flag.Args(), dir)
+	if err != nil {
+		return err
+	}
+	if err := checkGeneratePkg(cmd.flag.Args(), bindPkgs); err != nil {
+		return err
+	}
+	// Output paths are relative to dir.
+	outPath := func(name string) string {
+		if dir == cwd || name == "-" || filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
 
-	var bindPkg *build.Package
-	switch len(args) {
-	case 0:
-		bindPkg, err = ctx.ImportDir(cwd, build.ImportComment)
-	case 1:
-		bindPkg, err = ctx.Import(args[0], cwd, build.ImportComment)
+	if err := checkSDKFlags(); err != nil {
+		return err
+	}
+	switch bindAnnotations {
+	case "", "androidx", "javax":
 	default:
-		cmd.usage()
-		os.Exit(1)
+		return fmt.Errorf(`unknown -annotations %q, want "androidx" or "javax"`, bindAnnotations)
 	}
-	if err != nil {
-		return err
+	switch bindCommaOK {
+	case "nullable", "result":
+	default:
+		return fmt.Errorf(`unknown -commaok %q, want "nullable" or "result"`, bindCommaOK)
+	}
+	switch bindOutputKind {
+	case "aar":
+		if *buildO != "" {
+			return errors.New("-o is only supported with -outputkind=src")
+		}
+	case "src":
+		if bindMaven != "" {
+			return errors.New("-maven is only supported with -outputkind=aar")
+		}
+		if bindVersionName != "" || bindVersionCode != 0 {
+			return errors.New("-versionname and -versioncode are only supported with -outputkind=aar")
+		}
+	default:
+		return fmt.Errorf(`unknown -outputkind %q, want "aar" or "src"`, bindOutputKind)
+	}
+	if bindJSON && !bindCount {
+		return errors.New("-json is only supported with -count")
+	}
+	if bindCount && bindOutputKind == "src" && *buildO == "-" {
+		return errors.New("-count is not supported with -o -")
+	}
+	if bindVersionCode < 0 {
+		return fmt.Errorf("-versioncode=%d is negative", bindVersionCode)
+	}
+	if bindSupportPkg != "" {
+		if err := checkSupportPkg(bindSupportPkg); err != nil {
+			return err
+		}
+	}
+	var maven *mavenCoords
+	if bindMaven != "" {
+		if maven, err = parseMavenCoords(bindMaven); err != nil {
+			return err
+		}
+	}
+	bind.Warnf = func(format string, args ...interface{}) {
+		warnf(format, args...)
 	}
 
-	if sdkDir := os.Getenv("ANDROID_HOME"); sdkDir == "" {
-		return fmt.Errorf("this command requires ANDROID_HOME environment variable (path to the Android SDK)")
+	p, err := ctx.Import("golang.org/x/mobile/app", cwd, build.ImportComment)
+	if err != nil {
+		return fmt.Errorf(`"golang.org/x/mobile/app" is not found; run go get golang.org/x/mobile/app`)
 	}
+	repo := filepath.Clean(filepath.Join(p.Dir, "..")) // golang.org/x/mobile directory.
 
-	if buildN {
-		tmpdir = "$WORK"
-	} else {
-		tmpdir, err = ioutil.TempDir("", "gomobile-bind-work-")
-		if err != nil {
+	binders, err := newBinders(bindPkgs)
+	if err != nil {
+		return err
+	}
+	for _, binder := range binders {
+		if err := binder.checkExports(); err != nil {
 			return err
 		}
 	}
-	defer removeAll(tmpdir)
-	if buildX {
-		fmt.Fprintln(os.Stderr, "WORK="+tmpdir)
+
+	if bindOutputKind == "src" {
+		srcDir := *buildO
+		if srcDir == "" {
+			srcDir = bindPkgs[0].Name + "-src"
+		}
+		for _, binder := range binders {
+			if err := binder.writeSources(outPath(srcDir), repo); err != nil {
+				return err
+			}
+		}
+		if srcDir != "-" && !buildN {
+			logf(levelNormal, "wrote %s", outPath(srcDir))
+		}
+		if bindCount {
+			return printBindCount(os.Stdout, binders, outPath(srcDir))
+		}
+		return nil
+	}
+
+	if sdkDir := os.Getenv("ANDROID_HOME"); sdkDir == "" {
+		return fmt.Errorf("this command requires ANDROID_HOME environment variable (path to the Android SDK)")
 	}
 
-	binder, err := newBinder(bindPkg)
+	cleanup, err := makeWorkDir("gomobile-bind-work-")
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
-	if err := binder.GenGo(tmpdir); err != nil {
-		return err
+	var bindings []string
+	for _, binder := range binders {
+		if err := binder.GenGo(tmpdir); err != nil {
+			return err
+		}
+		bindings = append(bindings, "../go_"+binder.pkg.Name())
 	}
 
 	mainFile := filepath.Join(tmpdir, "androidlib/main.go")
 	err = writeFile(mainFile, func(w io.Writer) error {
-		return androidMainTmpl.Execute(w, "../go_"+binder.pkg.Name())
+		return androidMainTmpl.Execute(w, bindings)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create the main package for android: %v", err)
@@ -128,56 +367,123 @@ func runBind(cmd *command) error {
 
 	androidDir := filepath.Join(tmpdir, "android")
 
-	err = gobuild(mainFile, filepath.Join(androidDir, "src/main/jniLibs/armeabi-v7a/libgojni.so"))
+	err = gobuild(mainFile, filepath.Join(androidDir, "src/main/jniLibs/armeabi-v7a/lib"+supportLib()+".so"))
 	if err != nil {
 		return err
 	}
 
-	p, err := ctx.Import("golang.org/x/mobile/app", cwd, build.ImportComment)
-	if err != nil {
-		return fmt.Errorf(`"golang.org/x/mobile/app" is not found; run go get golang.org/x/mobile/app`)
+	// TODO(crawshaw): use a better package path derived from the go package.
+	for _, binder := range binders {
+		if err := binder.GenJava(filepath.Join(androidDir, "src/main/java")); err != nil {
+			return err
+		}
+		if bindExamples {
+			if err := binder.GenJavaExample(outPath(bindPkgs[0].Name + "-examples")); err != nil {
+				return err
+			}
+		}
 	}
-	repo := filepath.Clean(filepath.Join(p.Dir, "..")) // golang.org/x/mobile directory.
 
-	// TODO(crawshaw): use a better package path derived from the go package.
-	if err := binder.GenJava(filepath.Join(androidDir, "src/main/java/go/"+binder.pkg.Name())); err != nil {
+	aarPath := outPath(bindPkgs[0].Name + ".aar")
+	if maven != nil {
+		aarPath = outPath(maven.fileName(".aar"))
+	}
+	if err := buildAAR(aarPath, androidDir, repo, bindPkgs, binders[0].javaPkg()); err != nil {
 		return err
 	}
+	if !buildN {
+		logf(levelNormal, "wrote %s", aarPath)
+	}
+	if maven != nil {
+		err := writeFile(outPath(maven.fileName(".pom")), func(w io.Writer) error {
+			return writePOM(w, maven)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if bindCount {
+		return printBindCount(os.Stdout, binders, aarPath)
+	}
+	return nil
+}
 
-	src := filepath.Join(repo, "app/Go.java")
-	dst := filepath.Join(androidDir, "src/main/java/go/Go.java")
-	rm(dst)
-	if err := symlink(src, dst); err != nil {
-		return err
+// generateDir returns the directory the package arguments and output
+// paths of bind are relative to. Run by go generate, which sets GOFILE
+// to the file holding the //go:generate line, it is the directory of
+// that file, and otherwise the current directory cwd.
+func generateDir(cwd string) string {
+	file := os.Getenv("GOFILE")
+	if file == "" || os.Getenv("GOPACKAGE") == "" {
+		return cwd
 	}
+	if filepath.IsAbs(file) {
+		return filepath.Dir(file)
+	}
+	return filepath.Join(cwd, filepath.Dir(file))
+}
 
-	src = filepath.Join(repo, "bind/java/Seq.java")
-	dst = filepath.Join(androidDir, "src/main/java/go/Seq.java")
-	rm(dst)
-	if err := symlink(src, dst); err != nil {
-		return err
+// checkGeneratePkg checks, when run by go generate without package
+// arguments, that the bound package is the package GOPACKAGE of the
+// //go:generate line. The line may be in an external test file of the
+// package.
+func checkGeneratePkg(args []string, pkgs []*build.Package) error {
+	name := strings.TrimSuffix(os.Getenv("GOPACKAGE"), "_test")
+	if len(args) > 0 || name == "" || os.Getenv("GOFILE") == "" {
+		return nil
+	}
+	if pkgs[0].Name != name {
+		return fmt.Errorf("go generate runs in package %s, but %s is package %s", name, pkgs[0].Dir, pkgs[0].Name)
 	}
+	return nil
+}
 
-	return buildAAR(androidDir, bindPkg)
+// importBindPkgs imports the packages to bind named by args, or the
+// package in the directory cwd if there are none.
+func importBindPkgs(args []string, cwd string) ([]*build.Package, error) {
+	if len(args) == 0 {
+		bindPkg, err := ctx.ImportDir(cwd, build.ImportComment)
+		if err != nil {
+			return nil, err
+		}
+		return []*build.Package{bindPkg}, nil
+	}
+	var bindPkgs []*build.Package
+	names := make(map[string]string)
+	for _, arg := range args {
+		bindPkg, err := ctx.Import(arg, cwd, build.ImportComment)
+		if err != nil {
+			return nil, err
+		}
+		// The Java classes and Go binding packages are named after
+		// the Go packages.
+		if path, ok := names[bindPkg.Name]; ok {
+			return nil, fmt.Errorf("cannot bind packages %s and %s with the same name %s", path, bindPkg.ImportPath, bindPkg.Name)
+		}
+		names[bindPkg.Name] = bindPkg.ImportPath
+		bindPkgs = append(bindPkgs, bindPkg)
+	}
+	return bindPkgs, nil
 }
 
 type binder struct {
 	files []*ast.File
 	fset  *token.FileSet
 	pkg   *types.Package
+	bound []*types.Package // the packages bound together, if more than one
 }
 
-func (b *binder) GenJava(outdir string) error {
-	firstRune, size := utf8.DecodeRuneInString(b.pkg.Name())
-	className := string(unicode.ToUpper(firstRune)) + b.pkg.Name()[size:]
-	javaFile := filepath.Join(outdir, className+".java")
+// GenJava writes the Java API to its package directory in the Java
+// source directory srcDir.
+func (b *binder) GenJava(srcDir string) error {
+	javaFile := filepath.Join(srcDir, b.javaFile())
 
 	if buildX {
-		printcmd("gobind -lang=java %s > %s", b.pkg.Path(), javaFile)
+		printcmd("gobind -lang=java%s %s > %s", b.gobindFlags(true), b.pkg.Path(), javaFile)
 	}
 
 	generate := func(w io.Writer) error {
-		return bind.GenJava(w, b.fset, b.pkg)
+		return bind.GenJavaOptions(w, b.fset, b.pkg, b.options())
 	}
 	if err := writeFile(javaFile, generate); err != nil {
 		return err
@@ -185,16 +491,26 @@ func (b *binder) GenJava(outdir string) error {
 	return nil
 }
 
+// GenJavaExample writes the Java class Example, calling the Java API,
+// next to the Java API in the Java source directory srcDir.
+func (b *binder) GenJavaExample(srcDir string) error {
+	exampleFile := filepath.Join(srcDir, b.exampleFile())
+	generate := func(w io.Writer) error {
+		return bind.GenJavaExample(w, b.fset, b.pkg, b.options())
+	}
+	return writeFile(exampleFile, generate)
+}
+
 func (b *binder) GenGo(outdir string) error {
 	pkgName := "go_" + b.pkg.Name()
 	goFile := filepath.Join(outdir, pkgName, pkgName+".go")
 
 	if buildX {
-		printcmd("gobind -lang=go %s > %s", b.pkg.Path(), goFile)
+		printcmd("gobind -lang=go%s %s > %s", b.gobindFlags(false), b.pkg.Path(), goFile)
 	}
 
 	generate := func(w io.Writer) error {
-		return bind.GenGo(w, b.fset, b.pkg)
+		return bind.GenGoOptions(w, b.fset, b.pkg, b.options())
 	}
 	if err := writeFile(goFile, generate); err != nil {
 		return err
@@ -202,11 +518,120 @@ func (b *binder) GenGo(outdir string) error {
 	return nil
 }
 
-func writeFile(filename string, generate func(io.Writer) error) error {
-	if buildV {
-		fmt.Fprintf(os.Stderr, "write %s\n", filename)
+// javaFile returns the path of the generated Java file, relative to the
+// Java source directory.
+func (b *binder) javaFile() string {
+	firstRune, size := utf8.DecodeRuneInString(b.pkg.Name())
+	className := string(unicode.ToUpper(firstRune)) + b.pkg.Name()[size:]
+	return filepath.Join(filepath.FromSlash(strings.Replace(b.javaPkg(), ".", "/", -1)), className+".java")
+}
+
+// exampleFile returns the path of the generated Java example, relative
+// to the Java source directory.
+func (b *binder) exampleFile() string {
+	return filepath.Join(filepath.Dir(b.javaFile()), "Example.java")
+}
+
+// writeSources writes the generated binding sources to dir, for
+// -outputkind=src. If dir is "-", the generated Go and Java files are
+// written to standard output instead, without the Java support classes.
+func (b *binder) writeSources(dir, repo string) error {
+	pkgName := "go_" + b.pkg.Name()
+	goFile := filepath.Join("go", pkgName, pkgName+".go")
+	javaFile := filepath.Join("java", b.javaFile())
+
+	if dir == "-" {
+		w := os.Stdout
+		fmt.Fprintf(w, "// File: %s\n", filepath.ToSlash(goFile))
+		if err := bind.GenGoOptions(w, b.fset, b.pkg, b.options()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\n// File: %s\n", filepath.ToSlash(javaFile))
+		if err := bind.GenJavaOptions(w, b.fset, b.pkg, b.options()); err != nil {
+			return err
+		}
+		if !bindExamples {
+			return nil
+		}
+		exampleFile := filepath.Join("java", b.exampleFile())
+		fmt.Fprintf(w, "\n// File: %s\n", filepath.ToSlash(exampleFile))
+		return bind.GenJavaExample(w, b.fset, b.pkg, b.options())
 	}
 
+	if err := b.GenGo(filepath.Join(dir, "go")); err != nil {
+		return err
+	}
+	javaDir := filepath.Join(dir, "java")
+	if err := b.GenJava(javaDir); err != nil {
+		return err
+	}
+	if bindExamples {
+		if err := b.GenJavaExample(javaDir); err != nil {
+			return err
+		}
+	}
+	for _, src := range supportSources {
+		data, err := supportSource(repo, src)
+		if err != nil {
+			return err
+		}
+		err = writeFile(filepath.Join(javaDir, supportFile(src)), func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// javaPkg returns the Java package of the generated Java API.
+func (b *binder) javaPkg() string {
+	root := bindJavaPkg
+	if root == "" {
+		root = "go"
+	}
+	return root + "." + b.pkg.Name()
+}
+
+// options returns the binding generator options set by the bind flags.
+func (b *binder) options() *bind.Options {
+	return &bind.Options{
+		Annotations: bindAnnotations,
+		ByteBuffers: bindByteBuffers,
+		CommaOK:     bindCommaOK,
+		JavaPkg:     bindJavaPkg,
+		SupportPkg:  bindSupportPkg,
+		Packages:    b.bound,
+	}
+}
+
+// gobindFlags returns the gobind flags equivalent to the bind flags,
+// for printing the gobind commands.
+func (b *binder) gobindFlags(java bool) string {
+	var flags string
+	if java && bindAnnotations != "" {
+		flags += " -annotations=" + bindAnnotations
+	}
+	if bindByteBuffers {
+		flags += " -bytebuffer"
+	}
+	if java && bindCommaOK != "nullable" {
+		flags += " -commaok=" + bindCommaOK
+	}
+	if java && bindJavaPkg != "" {
+		flags += " -javapkg=" + bindJavaPkg
+	}
+	if java && bindSupportPkg != "" {
+		flags += " -supportpkg=" + bindSupportPkg
+	}
+	return flags
+}
+
+func writeFile(filename string, generate func(io.Writer) error) error {
+	logf(levelVerbose, "write %s", filename)
+
 	err := mkdir(filepath.Dir(filename))
 	if err != nil {
 		return err
@@ -229,57 +654,100 @@ func writeFile(filename string, generate func(io.Writer) error) error {
 	return generate(f)
 }
 
-func newBinder(bindPkg *build.Package) (*binder, error) {
-	if bindPkg.Name == "main" {
-		return nil, fmt.Errorf("package %q: can only bind a library package", bindPkg.Name)
+// checkExports reports an error if the package of b exports no
+// declaration, which would be bound into empty classes. If it exports
+// only declarations that cannot be bound, they are listed in a warning.
+func (b *binder) checkExports() error {
+	syms := bind.Symbols(b.fset, b.pkg, b.options())
+	if len(syms) == 0 {
+		return fmt.Errorf("package %s exports no bindable symbols", b.pkg.Path())
+	}
+	var skipped []string
+	for _, s := range syms {
+		if s.Err == nil {
+			return nil
+		}
+		skipped = append(skipped, s.Name)
 	}
+	warnf("package %s exports no bindable symbols: %s are skipped for unsupported types; run gomobile list for the reasons", b.pkg.Path(), strings.Join(skipped, ", "))
+	return nil
+}
 
-	if len(bindPkg.CgoFiles) > 0 {
-		return nil, fmt.Errorf("cannot use cgo-dependent package as service definition: %s", bindPkg.CgoFiles[0])
+func newBinder(bindPkg *build.Package) (*binder, error) {
+	binders, err := newBinders([]*build.Package{bindPkg})
+	if err != nil {
+		return nil, err
 	}
+	return binders[0], nil
+}
 
+// newBinders type checks the packages bound together and returns their
+// binders. The API of each package may use the types of the others.
+func newBinders(bindPkgs []*build.Package) ([]*binder, error) {
 	fset := token.NewFileSet()
+	conf := loader.Config{
+		Fset:  fset,
+		Build: &ctx, // resolve imports with the same build tags
+	}
+	conf.TypeChecker.Error = func(err error) {
+		fmt.Fprintln(os.Stderr, err)
+	}
 
-	hasErr := false
-	var files []*ast.File
-	for _, filename := range bindPkg.GoFiles {
-		p := filepath.Join(bindPkg.Dir, filename)
-		file, err := parser.ParseFile(fset, p, nil, parser.AllErrors)
-		if err != nil {
-			hasErr = true
-			if list, _ := err.(scanner.ErrorList); len(list) > 0 {
-				for _, err := range list {
+	var pkgFiles [][]*ast.File
+	for _, bindPkg := range bindPkgs {
+		if bindPkg.Name == "main" {
+			return nil, fmt.Errorf("package %q: can only bind a library package", bindPkg.Name)
+		}
+
+		if len(bindPkg.CgoFiles) > 0 {
+			return nil, fmt.Errorf("cannot use cgo-dependent package as service definition: %s", bindPkg.CgoFiles[0])
+		}
+
+		hasErr := false
+		var files []*ast.File
+		for _, filename := range bindPkg.GoFiles {
+			p := filepath.Join(bindPkg.Dir, filename)
+			file, err := parser.ParseFile(fset, p, nil, parser.AllErrors)
+			if err != nil {
+				hasErr = true
+				if list, _ := err.(scanner.ErrorList); len(list) > 0 {
+					for _, err := range list {
+						fmt.Fprintln(os.Stderr, err)
+					}
+				} else {
 					fmt.Fprintln(os.Stderr, err)
 				}
-			} else {
-				fmt.Fprintln(os.Stderr, err)
 			}
+			files = append(files, file)
 		}
-		files = append(files, file)
-	}
 
-	if hasErr {
-		return nil, errors.New("package parsing failed.")
-	}
-
-	conf := loader.Config{
-		Fset: fset,
-	}
-	conf.TypeChecker.Error = func(err error) {
-		fmt.Fprintln(os.Stderr, err)
+		if hasErr {
+			return nil, errors.New("package parsing failed.")
+		}
+		conf.CreateFromFiles(bindPkg.ImportPath, files...)
+		pkgFiles = append(pkgFiles, files)
 	}
 
-	conf.CreateFromFiles(bindPkg.ImportPath, files...)
 	program, err := conf.Load()
 	if err != nil {
 		return nil, err
 	}
-	b := &binder{
-		files: files,
-		fset:  fset,
-		pkg:   program.Created[0].Pkg,
+	var bound []*types.Package
+	if len(bindPkgs) > 1 {
+		for _, info := range program.Created {
+			bound = append(bound, info.Pkg)
+		}
+	}
+	var binders []*binder
+	for i, info := range program.Created {
+		binders = append(binders, &binder{
+			files: pkgFiles[i],
+			fset:  fset,
+			pkg:   info.Pkg,
+			bound: bound,
+		})
 	}
-	return b, nil
+	return binders, nil
 }
 
 var androidMainTmpl = template.Must(template.New("android.go").Parse(`
@@ -288,8 +756,8 @@ package main
 import (
 	"golang.org/x/mobile/app"
 	"golang.org/x/mobile/bind/java"
-
-	_ "{{.}}"
+{{range .}}
+	_ "{{.}}"{{end}}
 )
 
 func main() {
@@ -306,7 +774,7 @@ func main() {
 //	AndroidManifest.xml (mandatory)
 // 	classes.jar (mandatory)
 //	assets/ (optional)
-//	jni/<abi>/libgojni.so
+//	jni/<abi>/libgojni.so, or as named by supportLib
 //	R.txt (mandatory)
 //	res/ (mandatory)
 //	libs/*.jar (optional, not relevant)
@@ -314,11 +782,36 @@ func main() {
 //	lint.jar (optional, not relevant)
 //	aidl (optional, not relevant)
 //
-// javac and jar commands are needed to build classes.jar.
-func buildAAR(androidDir string, pkg *build.Package) (err error) {
+// javac and jar commands are needed to build classes.jar. The Java
+// support classes are compiled from the golang.org/x/mobile directory
+// repo.
+// writeAARManifest writes the AndroidManifest.xml of an AAR whose Java
+// classes are in javaPkg, stamped with the -versionname, -versioncode
+// and -minsdk flags.
+func writeAARManifest(w io.Writer, javaPkg string) error {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package=%q`, javaPkg+".gojni")
+	if bindVersionCode != 0 {
+		fmt.Fprintf(buf, ` android:versionCode="%d"`, bindVersionCode)
+	}
+	if bindVersionName != "" {
+		buf.WriteString(` android:versionName="`)
+		xml.EscapeText(buf, []byte(bindVersionName))
+		buf.WriteString(`"`)
+	}
+	if buildMinSDK != 0 {
+		fmt.Fprintf(buf, `><uses-sdk android:minSdkVersion="%d" /></manifest>`, buildMinSDK)
+	} else {
+		buf.WriteString(` />`)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func buildAAR(aarPath, androidDir, repo string, pkgs []*build.Package, javaPkg string) (err error) {
 	var out io.Writer = ioutil.Discard
 	if !buildN {
-		f, err := os.Create(pkg.Name + ".aar")
+		f, err := os.Create(aarPath)
 		if err != nil {
 			return err
 		}
@@ -330,66 +823,36 @@ func buildAAR(androidDir string, pkg *build.Package) (err error) {
 		out = f
 	}
 
-	aarw := zip.NewWriter(out)
-	aarwcreate := func(name string) (io.Writer, error) {
-		if buildV {
-			fmt.Fprintf(os.Stderr, "aar: %s\n", name)
-		}
-		return aarw.Create(name)
-	}
-	w, err := aarwcreate("AndroidManifest.xml")
+	aarw, err := newArchiveWriter(out, "aar")
 	if err != nil {
 		return err
 	}
-	const manifestFmt = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package=%q />`
-	fmt.Fprintf(w, manifestFmt, "go."+pkg.Name+".gojni")
-
-	w, err = aarwcreate("classes.jar")
+	w, err := aarw.Create("AndroidManifest.xml")
 	if err != nil {
 		return err
 	}
-	src := filepath.Join(androidDir, "src/main/java")
-	if err := buildJar(w, src); err != nil {
+	if err := writeAARManifest(w, javaPkg); err != nil {
 		return err
 	}
 
-	assetsDir := filepath.Join(pkg.Dir, "assets")
-	assetsDirExists := false
-	if fi, err := os.Stat(assetsDir); err == nil {
-		assetsDirExists = fi.IsDir()
-	} else if !os.IsNotExist(err) {
+	w, err = aarw.Create("classes.jar")
+	if err != nil {
+		return err
+	}
+	src := filepath.Join(androidDir, "src/main/java")
+	if err := buildJar(w, src, repo); err != nil {
 		return err
 	}
 
-	if assetsDirExists {
-		err := filepath.Walk(
-			assetsDir, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
-				if info.IsDir() {
-					return nil
-				}
-				f, err := os.Open(path)
-				if err != nil {
-					return err
-				}
-				defer f.Close()
-				name := "assets/" + path[len(assetsDir)+1:]
-				w, err := aarwcreate(name)
-				if err != nil {
-					return nil
-				}
-				_, err = io.Copy(w, f)
-				return err
-			})
-		if err != nil {
+	assets := make(map[string]string) // asset name to package path
+	for _, pkg := range pkgs {
+		if err := writeAssets(aarw, pkg, assets); err != nil {
 			return err
 		}
 	}
 
-	lib := "armeabi-v7a/libgojni.so"
-	w, err = aarwcreate("jni/" + lib)
+	lib := "armeabi-v7a/lib" + supportLib() + ".so"
+	w, err = aarw.Create("jni/" + lib)
 	if err != nil {
 		return err
 	}
@@ -405,12 +868,12 @@ func buildAAR(androidDir string, pkg *build.Package) (err error) {
 	}
 
 	// TODO(hyangah): do we need to use aapt to create R.txt?
-	w, err = aarwcreate("R.txt")
+	w, err = aarw.Create("R.txt")
 	if err != nil {
 		return err
 	}
 
-	w, err = aarwcreate("res/")
+	w, err = aarw.Create("res/")
 	if err != nil {
 		return err
 	}
@@ -418,12 +881,127 @@ func buildAAR(androidDir string, pkg *build.Package) (err error) {
 	return aarw.Close()
 }
 
+// writeAssets writes the files in the assets directory of pkg, if any,
+// to the AAR. assets records the package of each asset written, so the
+// same asset is not written by two packages.
+func writeAssets(aarw *archiveWriter, pkg *build.Package, assets map[string]string) error {
+	assetsDir := filepath.Join(pkg.Dir, "assets")
+	if fi, err := os.Stat(assetsDir); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return nil
+	}
+
+	// Walk visits the files in lexical order, so the entries of
+	// the archive do not depend on the file system.
+	return filepath.Walk(
+		assetsDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			name := "assets/" + filepath.ToSlash(path[len(assetsDir)+1:])
+			if other, ok := assets[name]; ok {
+				return fmt.Errorf("packages %s and %s both have the asset %s", other, pkg.ImportPath, name)
+			}
+			assets[name] = pkg.ImportPath
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w, err := aarw.Create(name)
+			if err != nil {
+				return nil
+			}
+			_, err = io.Copy(w, f)
+			return err
+		})
+}
+
+// nullableStub is the source of a Nullable annotation, formatted with
+// the package name and retention policy of the real annotation.
+const nullableStub = `package %s;
+
+import java.lang.annotation.Retention;
+import java.lang.annotation.RetentionPolicy;
+
+@Retention(RetentionPolicy.%s)
+public @interface Nullable {}
+`
+
+func writeNullableStub(dir string) error {
+	pkg, retention := "androidx.annotation", "CLASS"
+	if bindAnnotations == "javax" {
+		pkg, retention = "javax.annotation", "RUNTIME"
+	}
+	path := filepath.Join(dir, filepath.FromSlash(strings.Replace(pkg, ".", "/", -1)), "Nullable.java")
+	return writeFile(path, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, nullableStub, pkg, retention)
+		return err
+	})
+}
+
 const (
 	javacTargetVer = "1.7"
 	minAndroidAPI  = 9
 )
 
-func buildJar(w io.Writer, srcDir string) error {
+// buildJar compiles the Java sources in srcDir and writes the jar of
+// their classes and of the support classes to w.
+func buildJar(w io.Writer, srcDir, repo string) error {
+	dst := filepath.Join(tmpdir, "javac-output")
+	if !buildN {
+		if err := os.MkdirAll(dst, 0700); err != nil {
+			return err
+		}
+	}
+	defer removeAll(dst)
+
+	apiPath, err := androidAPIPath()
+	if err != nil {
+		return err
+	}
+
+	support, err := supportClasses(repo, apiPath)
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"-bootclasspath", filepath.Join(apiPath, "android.jar"),
+		"-classpath", support,
+	}
+	if bindAnnotations != "" {
+		// The annotation library is a dependency of the app using
+		// the AAR. Compile against a stub of the annotation, and
+		// leave it out of classes.jar.
+		stubDir := filepath.Join(tmpdir, "annotations-src")
+		if err := writeNullableStub(stubDir); err != nil {
+			return err
+		}
+		args = append(args, "-sourcepath", stubDir, "-implicit:none")
+	}
+	if err := runJavac(srcDir, dst, args...); err != nil {
+		return err
+	}
+
+	if buildX {
+		printcmd("jar c -C %s . -C %s .", dst, support)
+	}
+	if buildN {
+		return nil
+	}
+	return writeJar(w, dst, support)
+}
+
+// runJavac compiles the Java sources in srcDir into the classes of dst,
+// with the javac flags args, such as its class paths.
+func runJavac(srcDir, dst string, args ...string) error {
 	var srcFiles []string
 	if buildN {
 		srcFiles = []string{"*.java"}
@@ -442,90 +1020,130 @@ func buildJar(w io.Writer, srcDir string) error {
 		}
 	}
 
-	dst := filepath.Join(tmpdir, "javac-output")
-	if !buildN {
-		if err := os.MkdirAll(dst, 0700); err != nil {
-			return err
-		}
-	}
-	defer removeAll(dst)
-
-	apiPath, err := androidAPIPath()
-	if err != nil {
-		return err
-	}
-
-	args := []string{
+	args = append([]string{
 		"-d", dst,
 		"-source", javacTargetVer,
 		"-target", javacTargetVer,
-		"-bootclasspath", filepath.Join(apiPath, "android.jar"),
-	}
+	}, args...)
 	args = append(args, srcFiles...)
 
 	javac := exec.Command("javac", args...)
 	javac.Dir = srcDir
-	if buildV {
+	if verbose() {
 		javac.Stdout = os.Stdout
 		javac.Stderr = os.Stderr
 	}
 	if buildX {
 		printcmd("%s", strings.Join(javac.Args, " "))
 	}
-	if !buildN {
-		if err := javac.Run(); err != nil {
-			return err
-		}
-	}
-
-	if buildX {
-		printcmd("jar c -C %s .", dst)
-	}
 	if buildN {
 		return nil
 	}
+	return javac.Run()
+}
 
-	jarw := zip.NewWriter(w)
-	jarwcreate := func(name string) (io.Writer, error) {
-		if buildV {
-			fmt.Fprintf(os.Stderr, "jar: %s\n", name)
-		}
-		return jarw.Create(name)
+// writeJar writes a jar of the files in dirs to w. The jar is
+// deterministic: the entries are in lexical order and have the
+// modification time returned by archiveTime.
+func writeJar(w io.Writer, dirs ...string) error {
+	jarw, err := newArchiveWriter(w, "jar")
+	if err != nil {
+		return err
 	}
-	f, err := jarwcreate("META-INF/MANIFEST.MF")
+	f, err := jarw.Create("META-INF/MANIFEST.MF")
 	if err != nil {
 		return err
 	}
 	fmt.Fprintf(f, manifestHeader)
 
-	err = filepath.Walk(dst, func(path string, info os.FileInfo, err error) error {
+	files := make(map[string]string) // entry name to file path
+	var names []string
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			name := filepath.ToSlash(path[len(dir)+1:])
+			if _, dup := files[name]; dup {
+				return fmt.Errorf("%s is in both %s and %s", name, files[name], path)
+			}
+			files[name] = path
+			names = append(names, name)
+			return nil
+		})
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			return nil
-		}
-		out, err := jarwcreate(filepath.ToSlash(path[len(dst)+1:]))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out, err := jarw.Create(name)
 		if err != nil {
 			return err
 		}
-		in, err := os.Open(path)
+		in, err := os.Open(files[name])
 		if err != nil {
 			return err
 		}
-		defer in.Close()
 		_, err = io.Copy(out, in)
-		return err
-	})
-	if err != nil {
-		return err
+		in.Close()
+		if err != nil {
+			return err
+		}
 	}
 	return jarw.Close()
 }
 
+// archiveTime returns the modification time recorded in the entries of
+// the AAR and its classes.jar, so that building the same sources twice
+// produces identical archives. It is the time in the SOURCE_DATE_EPOCH
+// environment variable, in seconds since the Unix epoch, or else the
+// zero time, which is recorded as no time at all.
+func archiveTime() (time.Time, error) {
+	s := os.Getenv("SOURCE_DATE_EPOCH")
+	if s == "" {
+		return time.Time{}, nil
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || sec < 0 {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", s)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// An archiveWriter is a zip.Writer that records the same modification
+// time, from archiveTime, in every entry.
+type archiveWriter struct {
+	*zip.Writer
+	kind    string // "aar" or "jar", for -v
+	modTime time.Time
+}
+
+func newArchiveWriter(w io.Writer, kind string) (*archiveWriter, error) {
+	modTime, err := archiveTime()
+	if err != nil {
+		return nil, err
+	}
+	return &archiveWriter{Writer: zip.NewWriter(w), kind: kind, modTime: modTime}, nil
+}
+
+// Create adds a compressed entry to the archive.
+func (w *archiveWriter) Create(name string) (io.Writer, error) {
+	logf(levelVerbose, "%s: %s", w.kind, name)
+	h := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	if !w.modTime.IsZero() {
+		h.SetModTime(w.modTime)
+	}
+	return w.Writer.CreateHeader(h)
+}
+
 // androidAPIPath returns an android SDK platform directory under ANDROID_HOME.
-// If there are multiple platforms that satisfy the minimum version requirement
-// androidAPIPath returns the latest one among them.
+// With -androidapi, it returns the platform of that API level. Otherwise,
+// if there are multiple platforms that satisfy the minimum version
+// requirement androidAPIPath returns the latest one among them.
 func androidAPIPath() (string, error) {
 	sdk := os.Getenv("ANDROID_HOME")
 	if sdk == "" {
@@ -543,6 +1161,7 @@ func androidAPIPath() (string, error) {
 
 	var apiPath string
 	var apiVer int
+	var installed []int
 	for _, fi := range fis {
 		name := fi.Name()
 		if !fi.IsDir() || !strings.HasPrefix(name, "android-") {
@@ -553,12 +1172,30 @@ func androidAPIPath() (string, error) {
 			continue
 		}
 		p := filepath.Join(sdkDir.Name(), name)
-		_, err = os.Stat(filepath.Join(p, "android.jar"))
-		if err == nil && apiVer < n {
+		if _, err := os.Stat(filepath.Join(p, "android.jar")); err != nil {
+			continue
+		}
+		installed = append(installed, n)
+		if buildAndroidAPI != 0 && n != buildAndroidAPI {
+			continue
+		}
+		if apiVer < n {
 			apiPath = p
 			apiVer = n
 		}
 	}
+	if apiVer == 0 && buildAndroidAPI != 0 {
+		sort.Ints(installed)
+		var names []string
+		for _, n := range installed {
+			names = append(names, "android-"+strconv.Itoa(n))
+		}
+		if len(names) == 0 {
+			names = []string{"none"}
+		}
+		return "", fmt.Errorf("android SDK platform android-%d is not installed in %s; installed platforms: %s",
+			buildAndroidAPI, sdkDir.Name(), strings.Join(names, " "))
+	}
 	if apiVer == 0 {
 		return "", fmt.Errorf("failed to find android SDK platform (min API level: %d) in %s",
 			minAndroidAPI, sdkDir.Name())
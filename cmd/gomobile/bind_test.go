@@ -0,0 +1,711 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/mobile/bind"
+)
+
+var bindTagsFiles = map[string]string{
+	"tagged/tagged.go": `package tagged
+
+func Common() {}
+`,
+	"tagged/mobile.go": `//go:build mobile
+// +build mobile
+
+package tagged
+
+import "example.com/dep"
+
+type Mobile struct {
+	Kind int
+}
+
+func NewMobile() *Mobile { return &Mobile{Kind: dep.MobileKind} }
+`,
+	"dep/mobile.go": `//go:build mobile
+// +build mobile
+
+package dep
+
+const MobileKind = 1
+`,
+}
+
+func TestBindTags(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	for name, src := range bindTagsFiles {
+		path := filepath.Join(gopath, "src", "example.com", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldCtx := ctx
+	defer func() { ctx = oldCtx }()
+
+	for _, tags := range []string{"", "mobile"} {
+		ctx = oldCtx
+		ctx.GOPATH = gopath
+		if err := cmdBind.flag.Parse([]string{"-tags", tags}); err != nil {
+			t.Fatal(err)
+		}
+		pkg, err := ctx.Import("example.com/tagged", "", build.ImportComment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := newBinder(pkg)
+		if err != nil {
+			t.Fatalf("-tags=%q: %v", tags, err)
+		}
+		java := new(bytes.Buffer)
+		if err := bind.GenJava(java, b.fset, b.pkg); err != nil {
+			t.Fatalf("-tags=%q: %v", tags, err)
+		}
+
+		want := tags == "mobile"
+		if got := b.pkg.Scope().Lookup("Mobile") != nil; got != want {
+			t.Errorf("-tags=%q: type Mobile bound: %v, want %v", tags, got, want)
+		}
+		if got := strings.Contains(java.String(), "class Mobile "); got != want {
+			t.Errorf("-tags=%q: class Mobile generated: %v, want %v", tags, got, want)
+		}
+	}
+}
+
+var bindMultipleFiles = map[string]string{
+	"shapes/shapes.go": `package shapes
+
+type Point struct {
+	X, Y int
+}
+
+type Shape interface {
+	Area() float64
+}
+`,
+	"canvas/canvas.go": `package canvas
+
+import "example.com/shapes"
+
+func Origin() *shapes.Point { return &shapes.Point{} }
+
+func Draw(s shapes.Shape, at *shapes.Point) float64 { return s.Area() }
+`,
+}
+
+func TestBindMultiple(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	for name, src := range bindMultipleFiles {
+		path := filepath.Join(gopath, "src", "example.com", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldCtx := ctx
+	defer func() { ctx = oldCtx }()
+	ctx.GOPATH = gopath
+	var pkgs []*build.Package
+	for _, path := range []string{"example.com/shapes", "example.com/canvas"} {
+		pkg, err := ctx.Import(path, "", build.ImportComment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	binders, err := newBinders(pkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen := func(b *binder, lang string) string {
+		buf := new(bytes.Buffer)
+		var err error
+		if lang == "java" {
+			err = bind.GenJavaOptions(buf, b.fset, b.pkg, b.options())
+		} else {
+			err = bind.GenGoOptions(buf, b.fset, b.pkg, b.options())
+		}
+		if err != nil {
+			t.Fatalf("%s %s: %v", b.pkg.Name(), lang, err)
+		}
+		return buf.String()
+	}
+	check := func(name, src string, want ...string) {
+		for _, w := range want {
+			if !strings.Contains(src, w) {
+				t.Errorf("%s does not contain %q:\n%s", name, w, src)
+			}
+		}
+	}
+
+	// The shapes classes can be made by the canvas class.
+	check("Shapes.java", gen(binders[0], "java"),
+		"public Point(go.Seq.Ref ref)",
+		"public Proxy(go.Seq.Ref ref)",
+		"public static go.Seq.Ref refOf(final Shape impl)")
+	check("go_shapes.go", gen(binders[0], "go"),
+		`seq.RegisterProxy(proxyShapeDescriptor, func(ref *seq.Ref) interface{} { return (*proxyShape)(ref) })`)
+
+	// The canvas API refers to the shapes classes.
+	check("Canvas.java", gen(binders[1], "java"),
+		"public static go.shapes.Shapes.Point Origin()",
+		"new go.shapes.Shapes.Point(_out.readRef())",
+		"_in.writeRef(go.shapes.Shapes.Shape.Stub.refOf(s))")
+	check("go_canvas.go", gen(binders[1], "go"),
+		`"example.com/shapes"`,
+		`param_at_ref.Get().(*shapes.Point)`,
+		`seq.Proxies["go.shapes.Shape"](param_s_ref).(shapes.Shape)`)
+}
+
+func TestBindSources(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	files := map[string]string{
+		"src/example.com/hello/hello.go": "package hello\n\nfunc Hello() string { return \"hello\" }\n",
+		"repo/app/Go.java":               "// Go.java\n",
+		"repo/bind/java/Seq.java":        "// Seq.java\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(gopath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldCtx := ctx
+	defer func() { ctx = oldCtx }()
+	ctx.GOPATH = gopath
+	pkg, err := ctx.Import("example.com/hello", "", build.ImportComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newBinder(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(gopath, "out")
+	if err := b.writeSources(out, filepath.Join(gopath, "repo")); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"go/go_hello/go_hello.go":  "package go_hello",
+		"java/go/hello/Hello.java": "package go.hello;",
+		"java/go/Go.java":          "// Go.java",
+		"java/go/Seq.java":         "// Seq.java",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not contain %q:\n%s", name, want, data)
+		}
+	}
+}
+
+func TestBindSupportPkg(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	files := map[string]string{
+		"src/example.com/hello/hello.go": "package hello\n\nfunc Hello() string { return \"hello\" }\n",
+		"repo/app/Go.java":               "package go;\n\npublic final class Go {\n\tstatic { System.loadLibrary(\"gojni\"); }\n}\n",
+		"repo/bind/java/Seq.java":        "// Seq.java\n\npackage go;\n\npublic class Seq {}\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(gopath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldCtx := ctx
+	defer func() {
+		ctx = oldCtx
+		bindSupportPkg = ""
+	}()
+	ctx.GOPATH = gopath
+	pkg, err := ctx.Import("example.com/hello", "", build.ImportComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newBinder(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two libraries bound with their own support packages, for one app,
+	// have distinct support classes and native libraries.
+	libs := make(map[string]bool)
+	for _, tt := range []struct {
+		pkg, dir, lib, jni string
+	}{
+		{"com.example.a.go", "com/example/a/go", "gojni_com_example_a_go", "com_example_a_go"},
+		{"com.example.my_b.go", "com/example/my_b/go", "gojni_com_example_my_1b_go", "com_example_my_1b_go"},
+	} {
+		bindSupportPkg = tt.pkg
+		if err := checkSupportPkg(tt.pkg); err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(gopath, "out-"+tt.pkg)
+		if err := b.writeSources(out, filepath.Join(gopath, "repo")); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range []struct{ name, want string }{
+			{"java/go/hello/Hello.java", "import " + tt.pkg + ".Seq;"},
+			{"java/" + tt.dir + "/Go.java", "package " + tt.pkg + ";\n"},
+			{"java/" + tt.dir + "/Go.java", `System.loadLibrary("` + tt.lib + `");`},
+			{"java/" + tt.dir + "/Seq.java", "\npackage " + tt.pkg + ";\n"},
+		} {
+			data, err := ioutil.ReadFile(filepath.Join(out, filepath.FromSlash(f.name)))
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if !strings.Contains(string(data), f.want) {
+				t.Errorf("%s does not contain %q:\n%s", f.name, f.want, data)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(out, "java", "go", "Seq.java")); !os.IsNotExist(err) {
+			t.Errorf("-supportpkg=%s: Seq.java written to the go package", tt.pkg)
+		}
+		if lib := supportLib(); lib != tt.lib || libs[lib] {
+			t.Errorf("-supportpkg=%s: library %s, want %s", tt.pkg, lib, tt.lib)
+		}
+		libs[supportLib()] = true
+		if want := "-DGOMOBILE_SUPPORT_PKG=" + tt.jni; !strings.Contains(strings.Join(cgoEnv("arm"), " "), want) {
+			t.Errorf("-supportpkg=%s: cgo environment %q does not contain %s", tt.pkg, cgoEnv("arm"), want)
+		}
+	}
+
+	for _, name := range []string{"com..go", "com.example.new", "1com", "com.ex-ample"} {
+		if err := checkSupportPkg(name); err == nil {
+			t.Errorf("-supportpkg=%s: got nil error", name)
+		}
+	}
+}
+
+func TestBindExamples(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	src := "package hello\n\ntype Greeter struct{ Name string }\n\nfunc NewGreeter() *Greeter { return new(Greeter) }\n\nfunc (g *Greeter) Greet() string { return \"hello \" + g.Name }\n\nfunc Hello() string { return \"hello\" }\n"
+	path := filepath.Join(gopath, "src", "example.com", "hello", "hello.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldCtx := ctx
+	defer func() { ctx = oldCtx }()
+	ctx.GOPATH = gopath
+	pkg, err := ctx.Import("example.com/hello", "", build.ImportComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newBinder(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(gopath, "out")
+	if err := b.GenJavaExample(out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(out, "go", "hello", "Example.java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package go.hello;",
+		"Hello.Hello()",
+		"Greeter greeter = Hello.NewGreeter();",
+		"greeter.Greet()",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Example.java does not contain %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestWriteJarDeterministic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []string{"go/Seq.class", "go/pkg/Pkg.class", "go/pkg/Pkg$T.class"}
+	for _, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch := func(mtime time.Time) {
+		for _, name := range files {
+			if err := os.Chtimes(filepath.Join(dir, filepath.FromSlash(name)), mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	jar := func() []byte {
+		buf := new(bytes.Buffer)
+		if err := writeJar(buf, dir); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	defer os.Setenv("SOURCE_DATE_EPOCH", os.Getenv("SOURCE_DATE_EPOCH"))
+	for _, epoch := range []string{"", "1433160000"} {
+		os.Setenv("SOURCE_DATE_EPOCH", epoch)
+		touch(time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC))
+		jar1 := jar()
+		touch(time.Now())
+		jar2 := jar()
+		if !bytes.Equal(jar1, jar2) {
+			t.Errorf("SOURCE_DATE_EPOCH=%q: jars differ after touching the class files", epoch)
+		}
+
+		r, err := zip.NewReader(bytes.NewReader(jar1), int64(len(jar1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range r.File {
+			names = append(names, f.Name)
+			if epoch != "" && f.ModTime().Unix() != 1433160000 {
+				t.Errorf("SOURCE_DATE_EPOCH=%q: %s modified at %v", epoch, f.Name, f.ModTime())
+			}
+		}
+		want := "META-INF/MANIFEST.MF go/Seq.class go/pkg/Pkg$T.class go/pkg/Pkg.class"
+		if got := strings.Join(names, " "); got != want {
+			t.Errorf("SOURCE_DATE_EPOCH=%q: entries %s, want %s", epoch, got, want)
+		}
+	}
+
+	os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if err := writeJar(ioutil.Discard, dir); err == nil {
+		t.Error("invalid SOURCE_DATE_EPOCH: got nil error")
+	}
+}
+
+func TestAARManifest(t *testing.T) {
+	defer func(name string, code, min int) {
+		bindVersionName, bindVersionCode, buildMinSDK = name, code, min
+	}(bindVersionName, bindVersionCode, buildMinSDK)
+	bindVersionName, bindVersionCode, buildMinSDK = "1.2 <beta>", 12, 16
+
+	buf := new(bytes.Buffer)
+	aarw, err := newArchiveWriter(buf, "aar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := aarw.Create("AndroidManifest.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeAARManifest(w, "com.example"); err != nil {
+		t.Fatal(err)
+	}
+	if err := aarw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 1 || r.File[0].Name != "AndroidManifest.xml" {
+		t.Fatalf("AAR entries %v, want AndroidManifest.xml", r.File)
+	}
+	f, err := r.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var manifest struct {
+		Package     string `xml:"package,attr"`
+		VersionCode string `xml:"versionCode,attr"`
+		VersionName string `xml:"versionName,attr"`
+		UsesSDK     struct {
+			MinSDK string `xml:"minSdkVersion,attr"`
+		} `xml:"uses-sdk"`
+	}
+	if err := xml.NewDecoder(f).Decode(&manifest); err != nil {
+		t.Fatalf("decoding the manifest: %v", err)
+	}
+	if manifest.Package != "com.example.gojni" {
+		t.Errorf("package=%q, want com.example.gojni", manifest.Package)
+	}
+	if manifest.VersionCode != "12" {
+		t.Errorf("versionCode=%q, want 12", manifest.VersionCode)
+	}
+	if manifest.VersionName != "1.2 <beta>" {
+		t.Errorf("versionName=%q, want %q", manifest.VersionName, "1.2 <beta>")
+	}
+	if manifest.UsesSDK.MinSDK != "16" {
+		t.Errorf("minSdkVersion=%q, want 16", manifest.UsesSDK.MinSDK)
+	}
+
+	bindVersionName, bindVersionCode, buildMinSDK = "", 0, 0
+	buf.Reset()
+	if err := writeAARManifest(buf, "go"); err != nil {
+		t.Fatal(err)
+	}
+	want := `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="go.gojni" />`
+	if got := buf.String(); got != want {
+		t.Errorf("unstamped manifest:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAndroidAPIPath(t *testing.T) {
+	sdk, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sdk)
+	for _, api := range []string{"android-8", "android-15", "android-21", "android-22"} {
+		dir := filepath.Join(sdk, "platforms", api)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if api == "android-22" {
+			continue // incomplete platform
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "android.jar"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.Setenv("ANDROID_HOME", os.Getenv("ANDROID_HOME"))
+	os.Setenv("ANDROID_HOME", sdk)
+	defer func(api int) { buildAndroidAPI = api }(buildAndroidAPI)
+
+	for _, tc := range []struct {
+		api  int
+		want string
+	}{
+		{0, "android-21"},
+		{15, "android-15"},
+		{21, "android-21"},
+	} {
+		buildAndroidAPI = tc.api
+		got, err := androidAPIPath()
+		if err != nil {
+			t.Errorf("-androidapi=%d: %v", tc.api, err)
+			continue
+		}
+		if want := filepath.Join(sdk, "platforms", tc.want); got != want {
+			t.Errorf("-androidapi=%d: got %s, want %s", tc.api, got, want)
+		}
+	}
+
+	buildAndroidAPI = 22
+	_, err = androidAPIPath()
+	if err == nil || !strings.Contains(err.Error(), "installed platforms: android-15 android-21") {
+		t.Errorf("-androidapi=22: got %v, want an error listing the installed platforms", err)
+	}
+}
+
+func TestCheckSDKFlags(t *testing.T) {
+	defer func(min, api int) { buildMinSDK, buildAndroidAPI = min, api }(buildMinSDK, buildAndroidAPI)
+	for _, tc := range []struct {
+		min, api int
+		ok       bool
+	}{
+		{0, 0, true},
+		{15, 0, true},
+		{15, 21, true},
+		{0, 9, true},
+		{8, 0, false},
+		{0, 8, false},
+		{21, 15, false},
+	} {
+		buildMinSDK, buildAndroidAPI = tc.min, tc.api
+		if err := checkSDKFlags(); (err == nil) != tc.ok {
+			t.Errorf("-minsdk=%d -androidapi=%d: got error %v, want ok=%v", tc.min, tc.api, err, tc.ok)
+		}
+	}
+}
+
+func TestBindGenerateDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, src := range map[string]string{
+		"api/api.go":     "package api\n\n//go:generate gomobile bind ./impl\n\nfunc F() {}\n",
+		"api/impl/x.go":  "package impl\n\nfunc G() {}\n",
+		"other/other.go": "package other\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer os.Setenv("GOFILE", os.Getenv("GOFILE"))
+	defer os.Setenv("GOPACKAGE", os.Getenv("GOPACKAGE"))
+	os.Setenv("GOFILE", "")
+	os.Setenv("GOPACKAGE", "")
+	if got := generateDir(dir); got != dir {
+		t.Errorf("without go generate: generateDir(%q) = %q, want the current directory", dir, got)
+	}
+
+	// go generate names the file of the //go:generate line.
+	os.Setenv("GOFILE", "api.go")
+	os.Setenv("GOPACKAGE", "api")
+	cwd := filepath.Join(dir, "api")
+	if got := generateDir(cwd); got != cwd {
+		t.Errorf("generateDir(%q) = %q, want %q", cwd, got, cwd)
+	}
+	os.Setenv("GOFILE", filepath.Join(dir, "api", "api.go"))
+	gen := generateDir(filepath.Join(dir, "other"))
+	if gen != cwd {
+		t.Errorf("GOFILE=%s: generateDir = %q, want %q", os.Getenv("GOFILE"), gen, cwd)
+	}
+
+	// Relative package paths are resolved against the directory of GOFILE.
+	pkgs, err := importBindPkgs([]string{"./impl"}, gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pkgs[0].Dir, filepath.Join(cwd, "impl"); len(pkgs) != 1 || got != want {
+		t.Errorf("./impl imported from %s, want %s", got, want)
+	}
+	if err := checkGeneratePkg([]string{"./impl"}, pkgs); err != nil {
+		t.Errorf("package arguments: %v", err)
+	}
+
+	// Without arguments, the package of GOFILE is bound.
+	pkgs, err = importBindPkgs(nil, gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkGeneratePkg(nil, pkgs); err != nil {
+		t.Error(err)
+	}
+	os.Setenv("GOPACKAGE", "api_test")
+	if err := checkGeneratePkg(nil, pkgs); err != nil {
+		t.Errorf("external test file: %v", err)
+	}
+	os.Setenv("GOPACKAGE", "other")
+	if err := checkGeneratePkg(nil, pkgs); err == nil || !strings.Contains(err.Error(), "go generate runs in package other") {
+		t.Errorf("GOPACKAGE=other: error %v, want a package mismatch", err)
+	}
+}
+
+func TestBindExports(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	files := map[string]string{
+		"src/example.com/empty/empty.go":     "package empty\n\nfunc hello() string { return \"hello\" }\n",
+		"src/example.com/skipped/skipped.go": "package skipped\n\nvar Handler func(int)\n\nvar Mask uint32\n",
+		"src/example.com/hello/hello.go":     "package hello\n\nvar Mask uint32\n\nfunc Hello() string { return \"hello\" }\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(gopath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldCtx := ctx
+	defer func() {
+		ctx = oldCtx
+		logout = os.Stderr
+	}()
+	ctx.GOPATH = gopath
+	tests := []struct {
+		path    string
+		err     string // the error, if any
+		warning string // the warning, if any
+	}{
+		{"example.com/empty", "package example.com/empty exports no bindable symbols", ""},
+		{"example.com/skipped", "", "package example.com/skipped exports no bindable symbols: Handler, Mask are skipped"},
+		{"example.com/hello", "", ""},
+	}
+	for _, tt := range tests {
+		pkg, err := ctx.Import(tt.path, "", build.ImportComment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := newBinder(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		logout = buf
+		err = b.checkExports()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.path, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("%s: got error %v, want %q", tt.path, err, tt.err)
+		}
+		switch {
+		case tt.warning == "" && buf.Len() > 0:
+			t.Errorf("%s: unexpected warning %q", tt.path, buf.String())
+		case tt.warning != "" && !strings.Contains(buf.String(), tt.warning):
+			t.Errorf("%s: warning %q does not contain %q", tt.path, buf.String(), tt.warning)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"golang.org/x/mobile/bind"
+	"golang.org/x/tools/go/types"
+)
+
+// bindSummary is the size of the API exported by bind, printed by
+// gomobile bind -count.
+type bindSummary struct {
+	Types   int    // exported struct, interface, enum and other types
+	Methods int    // exported methods of the exported types
+	Funcs   int    // exported functions
+	Skipped int    // declarations that cannot be bound
+	Output  string // the AAR or source directory
+	Size    int64  // size of Output in bytes, 0 with -n
+}
+
+// countBind counts the declarations exported by the binders, as
+// listed by gomobile list.
+func countBind(binders []*binder) bindSummary {
+	var c bindSummary
+	for _, b := range binders {
+		for _, s := range bind.Symbols(b.fset, b.pkg, b.options()) {
+			if s.Err != nil {
+				c.Skipped++
+				continue
+			}
+			switch s.Kind {
+			case "func":
+				c.Funcs++
+			case "struct", "interface", "enum", "type":
+				c.Types++
+				c.Methods += exportedMethods(b.pkg.Scope().Lookup(s.Name).Type())
+			}
+		}
+	}
+	return c
+}
+
+// exportedMethods returns the number of exported methods of T and *T.
+func exportedMethods(T types.Type) int {
+	if _, ok := T.Underlying().(*types.Interface); !ok {
+		T = types.NewPointer(T)
+	}
+	mset := types.NewMethodSet(T)
+	n := 0
+	for i := 0; i < mset.Len(); i++ {
+		if mset.At(i).Obj().Exported() {
+			n++
+		}
+	}
+	return n
+}
+
+// outputSize returns the size of the file or the total size of the
+// files in the directory at path.
+func outputSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// printBindCount prints the counts of the binders and the size of the
+// bind output at out to w, as text or, with -json, as JSON.
+func printBindCount(w io.Writer, binders []*binder, out string) error {
+	c := countBind(binders)
+	c.Output = out
+	if !buildN {
+		size, err := outputSize(out)
+		if err != nil {
+			return err
+		}
+		c.Size = size
+	}
+
+	if bindJSON {
+		b, err := json.MarshalIndent(c, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(tw, "types:\t%d\n", c.Types)
+	fmt.Fprintf(tw, "methods:\t%d\n", c.Methods)
+	fmt.Fprintf(tw, "funcs:\t%d\n", c.Funcs)
+	fmt.Fprintf(tw, "skipped:\t%d\n", c.Skipped)
+	fmt.Fprintf(tw, "size:\t%d bytes (%s)\n", c.Size, c.Output)
+	return tw.Flush()
+}
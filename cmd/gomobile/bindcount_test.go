@@ -0,0 +1,109 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const countSrc = `package counted
+
+type Point struct{ X, Y int }
+
+func (p *Point) Scale(k int) {}
+func (p Point) Len() int      { return 0 }
+func (p *Point) reset()       {}
+
+type Shape interface {
+	Area() float64
+	Name() string
+}
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+func NewPoint() *Point { return new(Point) }
+
+func Add(x, y int) int { return x + y }
+
+func Events() chan int { return nil }
+`
+
+func TestBindCount(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	path := filepath.Join(gopath, "src", "example.com", "counted", "counted.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(countSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldCtx := ctx
+	defer func() { ctx = oldCtx }()
+	ctx.GOPATH = gopath
+	pkg, err := ctx.Import("example.com/counted", "", build.ImportComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binders, err := newBinders([]*build.Package{pkg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := bindSummary{Types: 3, Methods: 4, Funcs: 2, Skipped: 1}
+	if got := countBind(binders); got != want {
+		t.Errorf("countBind = %+v, want %+v", got, want)
+	}
+
+	// The size of the output is the size of the package source.
+	want.Output = filepath.Dir(path)
+	want.Size = int64(len(countSrc))
+	buf := new(bytes.Buffer)
+	if err := printBindCount(buf, binders, want.Output); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"types:   3\n",
+		"methods: 4\n",
+		"funcs:   2\n",
+		"skipped: 1\n",
+		"size:    " + strconv.Itoa(len(countSrc)) + " bytes (" + want.Output + ")\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("bind -count output does not contain %q:\n%s", line, buf)
+		}
+	}
+
+	defer func() { bindJSON = false }()
+	bindJSON = true
+	buf.Reset()
+	if err := printBindCount(buf, binders, want.Output); err != nil {
+		t.Fatal(err)
+	}
+	var got bindSummary
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%v:\n%s", err, buf)
+	}
+	if got != want {
+		t.Errorf("bind -count -json = %+v, want %+v", got, want)
+	}
+}
@@ -6,11 +6,13 @@ package main
 
 import (
 	"bytes"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"go/build"
+	"image"
 	"io"
 	"io/ioutil"
 	"os"
@@ -25,28 +27,261 @@ var ctx = build.Default
 var pkg *build.Package
 var ndkccpath string
 var tmpdir string
+var appPkgPath string     // Java package path of the app, set by runBuild
+var sharedCorePath string // shared Go core the app links against, set by gobuild
 
 var cmdBuild = &command{
 	run:   runBuild,
 	Name:  "build",
-	Usage: "[-o output] [-i] [build flags] [package]",
+	Usage: "[-o output] [-format apk|aab] [-androidmanifest file] [-assets dirs] [-icon file] [-minsdk level] [-androidapi level] [-androidtarget phone|tv|wear] [-appid id] [-buildmode mode] [-keystore file -keyalias alias] [-abisplit[=universal]] [-maxsize bytes] [-targets goos/goarch,...] [-activity native|fragment] [-debug] [-dry-run] [-i] [build flags] [package]",
 	Short: "compile android APK and iOS app",
 	Long: `
 Build compiles and encodes the app named by the import path.
 
 The named package must define a main function.
 
-If an AndroidManifest.xml is defined in the package directory, it is
-added to the APK file. Otherwise, a default manifest is generated.
+If an AndroidManifest.xml is defined in the package directory, or named
+by the -androidmanifest flag, the entries gomobile requires are merged
+into it and the result is added to the APK file: the package name, the
+minimum SDK version, and a NativeActivity with the meta-data naming the
+library of the app. Permissions, activities and other entries of the
+manifest are kept, and where the manifest already declares the
+NativeActivity or its library name, its values are used. Without a
+manifest, a default manifest is generated.
+
+The -minsdk flag sets the minimum Android API level of the app, the
+minSdkVersion of the manifest, which is 9 by default. The -androidapi
+flag sets the API level the app targets, the targetSdkVersion. It must
+not be below the minimum. If the manifest declares uses-sdk itself,
+these flags cannot be used.
+
+The -androidtarget flag selects the kind of device the manifest is for:
+'phone' (the default), 'tv' or 'wear'. For 'tv', the NativeActivity is
+also started by the leanback launcher of Android TV, and the manifest
+declares the leanback feature and that no touchscreen is required. For
+'wear', the manifest requires a watch and marks the app as a standalone
+Wear app. The app is a NativeActivity in every case. A manifest given
+by the user gets the uses-feature entries it lacks; the intent filter
+and the Wear meta-data are only added where gomobile adds the
+NativeActivity or the application element.
+
+The -appid flag sets the application ID of the app, the package name of
+the manifest, which identifies the app on the device and on Google
+Play. It is in reverse DNS form, as in com.example.app: at least two
+elements separated by dots, each starting with a letter followed by
+letters, digits and underscores, and none a Java keyword. A malformed
+ID fails the build before anything is compiled. Without -appid, the ID
+is derived from the import path of the package, its domain reversed and
+followed by the rest of the path, with the characters not allowed in
+an ID replaced by underscores: github.com/user/my-app becomes
+com.github.user.my_app. A package without a domain in its import path,
+such as a directory outside GOPATH, gets org.golang.todo followed by
+the name of its directory. If the manifest declares a package, it must
+be a valid ID too, and -appid, if given, must match it.
 
 If the package directory contains an assets subdirectory, its contents
-are copied into the APK file.
+are copied into the APK file. The -assets flag names the asset
+directories instead, as a space-separated list. Each is of the form dir
+or dir:prefix, where the prefix is prepended to the names of the assets
+in dir, and relative directories are relative to the current directory.
+For example, -assets 'assets images:img' packs images/a.png as
+the asset opened by app.Open("img/a.png"). Two files with the same asset
+name are an error. The names of the asset directories are packed as
+well, so the app can list them with app.ReadDir.
+
+The -icon flag names a square PNG file, at least 192x192 pixels, used
+as the launcher icon of the app. It is scaled down to the icon size of
+each screen density, from mdpi (48x48) to xxxhdpi (192x192), and packed
+as the resource mipmap/ic_launcher, the icon of the application in
+the manifest. An AndroidManifest.xml declaring another icon conflicts
+with -icon. App Bundles do not support -icon.
 
 The -o flag specifies the output file name. If not specified, the
 output file name depends on the package built. The output file must end
-in '.apk'.
+in '.apk', or in '.aab' when building an App Bundle.
+
+The -format flag selects the output format: 'apk' (the default) for an
+installable Android package, or 'aab' for an Android App Bundle suitable
+for uploading to Google Play. App Bundles are signed with the same key
+as APK files.
+
+The -abisplit flag writes an APK per ABI instead of a single APK, each
+holding only the libraries of its ABI, named after the output file and
+the GOARCH of the ABI. One APK is split per architecture built: arm
+only, unless -targets is set, so app.apk becomes app-arm.apk alone; or
+one per -targets entry, so that -targets=android/arm64,android/riscv64
+splits app.apk into app-arm64.apk and app-riscv64.apk. The split APKs
+are smaller to download and install. With -abisplit=universal, the
+APK holding every ABI is written as well. To publish the split APKs on
+Google Play as multiple APKs, each must have its own versionCode, set
+in the AndroidManifest.xml of each build. -abisplit cannot be used with
+-format aab, as Google Play splits App Bundles by ABI itself.
+
+APK and App Bundle files are signed with a debug key, unless the
+-keystore flag names a keystore holding a release key, of the alias
+named by -keyalias. The keystore may be a PKCS#12 file, read with
+openssl, or a JKS keystore as written by keytool and Android Studio,
+which first requires keytool. Only RSA keys are supported. The -storepass
+flag sets the password of the keystore, and -keypass the password of the
+key, if it differs. To keep the passwords out of shell histories and
+process listings, set them in the GOMOBILE_STOREPASS and
+GOMOBILE_KEYPASS environment variables instead. The keystore is read
+before building, so a wrong password or alias fails early. Passwords
+are never printed, not even by -v or -x.
+
+Compiled libraries are kept in a build cache in $GOPATH/pkg/gomobile/cache.
+When the sources of the package and of its dependencies, the target, and
+the build flags are unchanged, the cached library is reused instead of
+compiling again. The -a flag forces a rebuild. Use 'gomobile clean' to
+empty the cache.
+
+The -cache flag names the gomobile directory to use instead of
+$GOPATH/pkg/gomobile: the directory of the toolchain installed by
+'gomobile init -cache', the shared core and the build cache. It
+defaults to the GOMOBILE_CACHE environment variable, if set. Keeping
+the directory between CI jobs, which otherwise start from scratch,
+saves installing the toolchain and compiling the libraries again.
+Builds sharing the directory, at the same time or not, lock the cache
+entries they write, so no build sees or leaves a partial entry.
+
+The -buildmode flag selects how the Go code of the app is linked. With
+'default', the runtime and standard library are linked into the library
+of the app. With 'shared', they are compiled once into a shared library,
+the shared core, kept in $GOPATH/pkg/gomobile/pkg_android_arm_shared,
+and the app library is linked against it. Later builds of any app reuse
+the core instead of compiling and linking the standard library again.
+The core is packed as lib/armeabi/libstd.so next to the app library,
+which the manifest names as before; Android loads the core as a
+dependency of the app library. This requires a minimum API level of 23,
+so -minsdk, or the uses-sdk of the manifest, must be at least 23.
+
+An app must be packed with the core it was linked against: the core
+holds the compiled standard library, and the Go runtime checks at
+startup that it matches the one seen by the linker, aborting the app
+otherwise. The core is therefore rebuilt when the Go version, the build
+tags or the -gcflags change, and apps built against an older core must
+be rebuilt too. Rerun 'gomobile init' after updating Go.
+
+With -buildmode=c-shared, gomobile build compiles a main package into
+a shared library for hand-written JNI code, skipping the Java bindings
+of gomobile bind. The functions marked //export in its cgo files are
+callable from C. The output directory, named by -o or else after the
+package directory with a -jni suffix, holds the library in
+jni/armeabi-v7a/lib<name>.so and its C header in include/lib<name>.h.
+The build fails if an exported function is missing from the dynamic
+symbol table of the library.
+
+The -activity flag selects what hosts the app on Android. With
+'native', the default, the app is the NativeActivity of an APK. With
+'fragment', gomobile build writes an AAR instead, named by -o or else
+after the package directory, for an app written in Java to show the Go
+app in its own activities. The AAR holds the library of the app, its
+assets, and the Java classes go.GoView, a GLSurfaceView whose renderer
+thread runs the callbacks of the app, and go.GoFragment, a Fragment
+showing a GoView, which can be declared in a layout:
+
+	<fragment android:name="go.GoFragment"
+		android:layout_width="match_parent"
+		android:layout_height="match_parent" />
+
+An activity showing a GoView itself must pass its onPause and onResume
+calls on to the view. The app starts with the first GoView created, and
+only one may show it at a time. The -appid flag sets the package of the
+manifest of the AAR. Its minimum API level, set by -minsdk, is 11 by
+default and cannot be lower. The AAR is not signed, and
+-activity=fragment cannot be used with -format aab, -abisplit,
+-keystore, -androidmanifest, -icon, -targets, -debug or
+-buildmode=shared.
+
+The -gcflags flag is passed to the Go compiler. As with go build, the
+flags may be prefixed with a package pattern, as in -gcflags 'all=-N -l'
+to disable optimizations and inlining in every package when debugging.
+
+The -strip flag removes the symbol table and debug information from the
+shared libraries built, with the strip tool of the NDK, shrinking the
+APK. The exported symbols of the libraries, the entry points of JNI and
+NativeActivity, are kept. With -strip=ldflags, the linker leaves them
+out instead, as with -ldflags '-s -w'. Release builds, signed with a
+-keystore key, are stripped unless -strip=false is given; other builds
+are not stripped by default. Under -v, the library sizes before and
+after stripping are printed.
+
+The -maxsize flag sets a size budget, in bytes, for the native libraries
+packed, not counting the gdbserver of -debug: the build fails if a
+library, or the libraries of an ABI together, are larger. The sizes are
+measured as packed, after stripping. Under -v, a table of the size of
+each library and the total of each ABI is printed, with or without
+-maxsize. -maxsize also applies to the
+library of -buildmode=c-shared and -activity=fragment builds, and is not
+checked by -n or -dry-run, which build nothing.
+
+The -debug flag builds an APK for debugging the native code of the app
+with gdb. The application is marked android:debuggable in the manifest,
+also in a manifest given by the user, the libraries are not stripped,
+even for a release build, and the gdbserver of the NDK is packed next
+to them, as lib/armeabi/gdbserver. -debug cannot be combined with
+-strip. To debug the app, build it with -gcflags 'all=-N -l' as well,
+install it and start it, and then attach to it:
 
-The -v flag provides verbose output, including the list of packages built.
+	adb forward tcp:5039 localfilesystem:/data/data/$PKG/debug-socket
+	adb shell run-as $PKG lib/gdbserver +debug-socket --attach $PID
+	$GOPATH/pkg/gomobile/android-ndk-r10d/arm/bin/arm-linux-androideabi-gdb libapp.so
+	(gdb) target remote :5039
+
+where $PKG is the package of the app, $PID its process ID, as listed by
+adb shell ps, and libapp.so the library of the app, unzipped from
+lib/armeabi in the APK.
+
+The C compiler of the NDK installed by gomobile init builds the cgo
+code, unless an NDK is installed on the system, as by the Android SDK
+manager of a CI image: the NDK named by the ANDROID_NDK_HOME environment
+variable, or else the NDK in the ndk-bundle directory of ANDROID_HOME,
+or the newest one in its ndk directory. A system NDK must be a revision
+from r10 to r17, the last one providing GCC. An incompatible NDK named
+by ANDROID_NDK_HOME is an error naming the revisions required, and one
+found in ANDROID_HOME is ignored with a warning. gomobile init is still
+needed for the Go cross compiler.
+
+The -cflags and -clibs flags pass flags to the NDK C compiler and linker
+of the cgo packages built, such as the -I and -L paths of the C libraries
+they use, after those of the CGO_CFLAGS and CGO_LDFLAGS environment
+variables. The tokens ${ABI} and ${GOARCH} in the flags and variables
+are replaced by the Android ABI (armeabi-v7a) and GOARCH (arm) of the
+target, as in -clibs '-L/opt/libfoo/${ABI} -lfoo'.
+
+The -targets flag builds the app for a comma-separated list of
+GOOS/GOARCH pairs instead of android/arm, bypassing the ABI tables of
+gomobile to try targets it does not know, as in -targets android/riscv64.
+The go tools of the host compile each target, without the toolchain of
+gomobile init, and its library is packed in the lib directory of the
+Android ABI of its GOARCH, or else in lib/<GOARCH>. cgo needs a C
+compiler for each target, named by the CC_FOR_goos_goarch environment
+variable, as CC_FOR_android_riscv64, and, for C++ code, a C++ compiler
+named by CXX_FOR_goos_goarch; a target with no C compiler is an error.
+The libraries stripped by -strip are stripped by the linker. -targets
+cannot be used with -buildmode=shared or c-shared, with
+-activity=fragment, or with -debug.
+
+The -mod flag is passed to every go command building packages for the
+target, as -mod=vendor to build a module from its vendor directory
+without network access. These go commands inherit the GOFLAGS
+environment variable, and the -mod flag overrides a -mod setting in it.
+
+The -dry-run flag prints the plan of the build without running any of
+it: the output file and the ABI, the commands the build would run, in
+order, as printed by -x, and, as comments starting with '#', the steps
+gomobile does itself, such as packing each file into the APK and
+signing it. Like -n, nothing is built or written.
+
+By default, the command prints the files it writes. The -v flag also
+prints the steps of the build and the output of the tools it runs,
+including the list of packages built, and the -x flag also prints the
+commands run. The -quiet flag prints nothing but errors, for scripts;
+it cannot be used with -n, -v or -x. Errors are always printed to
+standard error.
+
+The -work flag prints the name of the temporary work directory and keeps
+it in place when the command exits, instead of removing it.
 
 These build flags are shared by the build, install, and test commands.
 For documentation, see 'go help build':
@@ -54,7 +289,11 @@ For documentation, see 'go help build':
 	-i
 	-n
 	-x
+	-work
 	-tags 'tag list'
+	-gcflags '[pattern=]flag list'
+	-ldflags 'flag list'
+	-mod mode
 `,
 }
 
@@ -66,6 +305,9 @@ func runBuild(cmd *command) (err error) {
 		panic(err)
 	}
 	args := cmd.flag.Args()
+	if buildPlan {
+		buildN, buildX = true, true
+	}
 
 	switch len(args) {
 	case 0:
@@ -80,9 +322,60 @@ func runBuild(cmd *command) (err error) {
 		return err
 	}
 
+	if err := checkSDKFlags(); err != nil {
+		return err
+	}
+	if buildDebug && buildStrip != "" && buildStrip != "false" {
+		return fmt.Errorf("-debug cannot be used with -strip=%s: it keeps the symbols of the libraries", buildStrip)
+	}
+	if _, ok := androidTargetFeatures[buildAndroidTarget]; !ok {
+		return fmt.Errorf("unknown -androidtarget %q, must be phone, tv or wear", buildAndroidTarget)
+	}
+	switch buildActivity {
+	case "", "native", "fragment":
+	default:
+		return fmt.Errorf("unknown -activity %q, must be native or fragment", buildActivity)
+	}
+	if buildAppID != "" {
+		if err := checkAppID(buildAppID); err != nil {
+			return fmt.Errorf("invalid -appid: %v", err)
+		}
+	}
+	if buildMaxSize < 0 {
+		return fmt.Errorf("-maxsize=%d is negative", buildMaxSize)
+	}
+	targets, err := parseTargets(buildTargets)
+	if err != nil {
+		return err
+	}
+	if targets != nil {
+		if err := checkTargetFlags(); err != nil {
+			return err
+		}
+	}
+	switch buildMode {
+	case "default", "shared":
+	case "c-shared":
+		if cmd.Name != "build" {
+			return fmt.Errorf("-buildmode=c-shared is only supported by gomobile build")
+		}
+		return buildCShared(pkg)
+	default:
+		return fmt.Errorf("unknown -buildmode %q, must be default, shared or c-shared", buildMode)
+	}
+
 	if pkg.Name != "main" {
 		// Not an app, don't build a final package.
-		return gobuild(pkg.ImportPath, "")
+		planf("build %s, no output", pkg.ImportPath)
+		if targets == nil {
+			return gobuild(pkg.ImportPath, "")
+		}
+		for i := range targets {
+			if err := gobuildTarget(&targets[i], pkg.ImportPath, ""); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	// Building a program, make sure it is appropriate for mobile.
@@ -96,70 +389,261 @@ func runBuild(cmd *command) (err error) {
 	if !importsApp {
 		return fmt.Errorf(`%s does not import "golang.org/x/mobile/app"`, pkg.ImportPath)
 	}
+	if buildActivity == "fragment" {
+		return buildViewHost(pkg)
+	}
 
-	if buildN {
-		tmpdir = "$WORK"
-	} else {
-		tmpdir, err = ioutil.TempDir("", "gobuildapk-work-")
-		if err != nil {
+	cleanup, err := makeWorkDir("gobuildapk-work-")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Read the signing key and the icon before building, so a bad
+	// keystore, password, alias or icon fails early.
+	privKey, cert, err := loadKeystore()
+	if err != nil {
+		return err
+	}
+	var icon image.Image
+	if buildIcon != "" {
+		if buildFormat == "aab" {
+			return errors.New("-icon is not supported with -format aab")
+		}
+		if icon, err = readIcon(buildIcon); err != nil {
 			return err
 		}
 	}
-	defer removeAll(tmpdir)
-	if buildX {
-		fmt.Fprintln(os.Stderr, "WORK="+tmpdir)
-	}
 
 	libName := path.Base(pkg.ImportPath)
-	manifestData, err := ioutil.ReadFile(filepath.Join(pkg.Dir, "AndroidManifest.xml"))
+	appID := buildAppID
+	if appID == "" {
+		appID = defaultAppID(pkg.ImportPath)
+	}
+	manifestDefaults := manifestTmplData{
+		JavaPkgPath: appID,
+		Name:        strings.ToUpper(pkg.Name[:1]) + pkg.Name[1:],
+		LibName:     libName,
+		MinSDK:      minAndroidAPI,
+		TargetSDK:   buildAndroidAPI,
+		SDKFlags:    buildMinSDK != 0 || buildAndroidAPI != 0,
+		Target:      buildAndroidTarget,
+		Debug:       buildDebug,
+		Icon:        buildIcon != "",
+	}
+	if buildMinSDK != 0 {
+		manifestDefaults.MinSDK = buildMinSDK
+	}
+	manifestPath := buildAndroidManifest
+	if manifestPath == "" {
+		manifestPath = filepath.Join(pkg.Dir, "AndroidManifest.xml")
+	}
+	manifestData, err := ioutil.ReadFile(manifestPath)
 	if err != nil {
-		if !os.IsNotExist(err) {
+		if !os.IsNotExist(err) || buildAndroidManifest != "" {
 			return err
 		}
 		buf := new(bytes.Buffer)
 		buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
-		err := manifestTmpl.Execute(buf, manifestTmplData{
-			// TODO(crawshaw): a better package path.
-			JavaPkgPath: "org.golang.todo." + pkg.Name,
-			Name:        strings.ToUpper(pkg.Name[:1]) + pkg.Name[1:],
-			LibName:     libName,
-		})
-		if err != nil {
+		if err := manifestTmpl.Execute(buf, manifestDefaults); err != nil {
 			return err
 		}
 		manifestData = buf.Bytes()
-		if buildV {
-			fmt.Fprintf(os.Stderr, "generated AndroidManifest.xml:\n%s\n", manifestData)
+		logf(levelVerbose, "generated AndroidManifest.xml:\n%s", manifestData)
+	} else {
+		manifestData, libName, err = mergeManifest(manifestData, manifestDefaults)
+		if err != nil {
+			return err
 		}
+		logf(levelVerbose, "merged AndroidManifest.xml:\n%s", manifestData)
+	}
+	appPkgPath, err = manifestPackage(manifestData)
+	if err != nil {
+		return err
+	}
+	if buildAppID != "" && appPkgPath != buildAppID {
+		return fmt.Errorf("AndroidManifest.xml declares package %q, which conflicts with -appid %q", appPkgPath, buildAppID)
+	}
+	if err := checkAppID(appPkgPath); err != nil {
+		return fmt.Errorf("AndroidManifest.xml: %v", err)
+	}
+	if buildMode == "shared" {
+		minSDK, err := manifestMinSDK(manifestData)
+		if err != nil {
+			return err
+		}
+		if minSDK < sharedCoreMinSDK {
+			return fmt.Errorf("-buildmode=shared requires a minimum API level of %d, the app has %d", sharedCoreMinSDK, minSDK)
+		}
+	}
+	if buildFormat != "apk" && buildFormat != "aab" {
+		return fmt.Errorf("unknown -format %q, must be apk or aab", buildFormat)
+	}
+	if *buildO == "" {
+		*buildO = filepath.Base(pkg.Dir) + "." + buildFormat
+	}
+	if !strings.HasSuffix(*buildO, "."+buildFormat) {
+		return fmt.Errorf("output file name %q does not end in '.%s'", *buildO, buildFormat)
+	}
+	if buildABISplit.split() && buildFormat != "apk" {
+		return fmt.Errorf("-abisplit cannot be used with -format %s: Google Play splits App Bundles itself", buildFormat)
+	}
+	planf("build %s into %s", pkg.ImportPath, *buildO)
+
+	var libs []apkLib
+	archs := buildArchs
+	if targets == nil {
+		planf("abi %s (GOARCH=arm GOARM=7), packed in lib/armeabi", androidABIs["arm"])
+		libPath := filepath.Join(tmpdir, "lib"+libName+".so")
+		if err := gobuild(pkg.ImportPath, libPath); err != nil {
+			return err
+		}
+		libs = append(libs, apkLib{abi: "armeabi", name: "lib" + libName + ".so", path: libPath})
 	} else {
-		libName, err = manifestLibName(manifestData)
+		archs = nil
+		for i := range targets {
+			t := &targets[i]
+			planf("target %s (GOOS=%s GOARCH=%s CC=%s), packed in lib/%s", t, t.goos, t.goarch, t.cc, t.libDir())
+			libPath := filepath.Join(tmpdir, t.libDir(), "lib"+libName+".so")
+			if err := mkdir(filepath.Dir(libPath)); err != nil {
+				return err
+			}
+			if err := gobuildTarget(t, pkg.ImportPath, libPath); err != nil {
+				return err
+			}
+			libs = append(libs, apkLib{abi: t.libDir(), name: "lib" + libName + ".so", path: libPath})
+			archs = append(archs, t.goarch)
+		}
+	}
+	if privKey == nil {
+		block, _ := pem.Decode([]byte(debugCert))
+		if block == nil {
+			return errors.New("no debug cert")
+		}
+		privKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
 		if err != nil {
 			return err
 		}
 	}
-	libPath := filepath.Join(tmpdir, "lib"+libName+".so")
 
-	if err := gobuild(pkg.ImportPath, libPath); err != nil {
+	if sharedCorePath != "" {
+		libs = append(libs, apkLib{abi: "armeabi", name: filepath.Base(sharedCorePath), path: sharedCorePath})
+	}
+	audio, err := pkgImportsAudio(pkg)
+	if err != nil {
 		return err
 	}
-	block, _ := pem.Decode([]byte(debugCert))
-	if block == nil {
-		return errors.New("no debug cert")
+	if audio {
+		al, err := openALLibs(filepath.Join(ndkccpath, "openal/lib"))
+		if err != nil && !buildN {
+			return err
+		}
+		libs = append(libs, al...)
+	}
+	// The budget is for the .so libraries, not for the gdbserver
+	// executable added by -debug.
+	if err := checkLibSizes(libs); err != nil {
+		return err
 	}
-	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if buildDebug {
+		gdbserver := filepath.Join(ndkccpath, "arm", "gdbserver", "gdbserver")
+		if systemNDK != nil {
+			gdbserver = systemNDK.gdbserver()
+		}
+		if _, err := os.Stat(gdbserver); !buildN && os.IsNotExist(err) {
+			return errors.New("gdbserver not installed, run:\n\tgomobile init -u")
+		}
+		libs = append(libs, apkLib{abi: "armeabi", name: "gdbserver", path: gdbserver})
+	}
+
+	// Add any assets.
+	assets, err := appAssets(pkg)
 	if err != nil {
 		return err
 	}
 
-	if *buildO == "" {
-		*buildO = filepath.Base(pkg.Dir) + ".apk"
+	if buildFormat == "aab" {
+		manifestData, err = protoXML(bytes.NewReader(manifestData))
+		if err != nil {
+			return err
+		}
+	}
+	apk := &apkContents{manifest: manifestData, libs: libs, assets: assets}
+	if icon != nil {
+		if apk.icon, err = launcherIcon(icon, appPkgPath); err != nil {
+			return err
+		}
 	}
-	if !strings.HasSuffix(*buildO, ".apk") {
-		return fmt.Errorf("output file name %q does not end in '.apk'", *buildO)
+	if !buildABISplit.split() {
+		return writeAPK(*buildO, apk, privKey, cert)
 	}
+	return writeABISplits(*buildO, archs, apk, privKey, cert)
+}
+
+// appAssets returns the assets of the app pkg: those of the -assets
+// directories, or else of the assets directory of the package, if any.
+func appAssets(pkg *build.Package) ([]assetFile, error) {
+	var dirs []assetDir
+	if buildAssets == nil {
+		dir := filepath.Join(pkg.Dir, "assets")
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			dirs = append(dirs, assetDir{dir: dir})
+		} else if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else {
+		var err error
+		if dirs, err = parseAssetDirs(buildAssets); err != nil {
+			return nil, err
+		}
+	}
+	return collectAssets(dirs)
+}
+
+// apkContents is what an APK or App Bundle holds.
+type apkContents struct {
+	manifest []byte // AndroidManifest.xml, in the binary format of the output
+	libs     []apkLib
+	assets   []assetFile
+	icon     *appIcon // -icon, or nil
+}
+
+// An apkLib is a file packed in the library directory of an ABI, such
+// as a shared library.
+type apkLib struct {
+	abi  string // directory of the ABI under lib/, such as armeabi
+	name string // name of the file in the directory
+	path string // the file to pack
+}
+
+// openALLibs returns the OpenAL libraries in dir, laid out as the lib
+// directory of an APK.
+func openALLibs(dir string) ([]apkLib, error) {
+	var libs []apkLib
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel := filepath.ToSlash(path[len(dir)+1:])
+		i := strings.Index(rel, "/")
+		if i < 0 {
+			return fmt.Errorf("%s is not in the directory of an ABI", path)
+		}
+		libs = append(libs, apkLib{abi: rel[:i], name: rel[i+1:], path: path})
+		return nil
+	})
+	return libs, err
+}
+
+// writeAPK writes the APK, or App Bundle with -format aab, holding c to
+// path, signed with privKey and its certificate cert.
+func writeAPK(path string, c *apkContents, privKey *rsa.PrivateKey, cert []byte) (err error) {
 	var out io.Writer
 	if !buildN {
-		f, err := os.Create(*buildO)
+		f, err := os.Create(path)
 		if err != nil {
 			return err
 		}
@@ -173,12 +657,14 @@ func runBuild(cmd *command) (err error) {
 
 	var apkw *Writer
 	if !buildN {
-		apkw = NewWriter(out, privKey)
+		apkw = NewWriterCert(out, privKey, cert)
 	}
 	apkwcreate := func(name string) (io.Writer, error) {
-		if buildV {
-			fmt.Fprintf(os.Stderr, "apk: %s\n", name)
+		if buildFormat == "aab" {
+			name = aabEntryName(name)
 		}
+		logf(levelVerbose, "apk: %s", name)
+		planf("pack %s", name)
 		if buildN {
 			return ioutil.Discard, nil
 		}
@@ -189,93 +675,97 @@ func runBuild(cmd *command) (err error) {
 	if err != nil {
 		return err
 	}
-	if _, err := w.Write(manifestData); err != nil {
+	if _, err := w.Write(c.manifest); err != nil {
 		return err
 	}
 
-	w, err = apkwcreate("lib/armeabi/lib" + libName + ".so")
-	if err != nil {
-		return err
-	}
-	if !buildN {
-		r, err := os.Open(libPath)
+	if c.icon != nil {
+		w, err := apkwcreate("resources.arsc")
 		if err != nil {
 			return err
 		}
-		if _, err := io.Copy(w, r); err != nil {
+		if _, err := w.Write(c.icon.table); err != nil {
 			return err
 		}
-	}
-
-	importsAudio := pkgImportsAudio(pkg)
-	if importsAudio {
-		alDir := filepath.Join(ndkccpath, "openal/lib")
-		filepath.Walk(alDir, func(path string, info os.FileInfo, err error) error {
+		for i, d := range iconDensities {
+			w, err := apkwcreate(iconPath(d.name))
 			if err != nil {
 				return err
 			}
-			if info.IsDir() {
-				return nil
-			}
-			name := "lib/" + path[len(alDir)+1:]
-			w, err := apkwcreate(name)
-			if err != nil {
+			if _, err := w.Write(c.icon.pngs[i]); err != nil {
 				return err
 			}
-			if !buildN {
-				f, err := os.Open(path)
-				if err != nil {
-					return err
-				}
-				defer f.Close()
-				_, err = io.Copy(w, f)
-			}
+		}
+	}
+
+	for _, lib := range c.libs {
+		w, err := apkwcreate("lib/" + lib.abi + "/" + lib.name)
+		if err != nil {
 			return err
-		})
+		}
+		if !buildN {
+			if err := copyAsset(w, lib.path); err != nil {
+				return err
+			}
+		}
 	}
 
-	// Add any assets.
-	assetsDir := filepath.Join(pkg.Dir, "assets")
-	assetsDirExists := true
-	fi, err := os.Stat(assetsDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			assetsDirExists = false
-		} else {
+	for _, a := range c.assets {
+		w, err := apkwcreate("assets/" + a.name)
+		if err != nil {
+			return err
+		}
+		if err := copyAsset(w, a.path); err != nil {
 			return err
 		}
-	} else {
-		assetsDirExists = fi.IsDir()
 	}
-	if assetsDirExists {
-		filepath.Walk(assetsDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
+	if len(c.assets) > 0 {
+		w, err := apkwcreate("assets/" + assetDirIndex)
+		if err != nil {
+			return err
+		}
+		for _, dir := range assetDirNames(c.assets) {
+			if _, err := fmt.Fprintln(w, dir); err != nil {
 				return err
 			}
-			if info.IsDir() {
-				return nil
-			}
-			name := "assets/" + path[len(assetsDir)+1:]
-			w, err := apkwcreate(name)
+		}
+	}
+
+	if buildFormat == "aab" {
+		logf(levelVerbose, "aab: BundleConfig.pb")
+		planf("pack BundleConfig.pb")
+		if !buildN {
+			w, err := apkw.Create("BundleConfig.pb")
 			if err != nil {
 				return err
 			}
-			f, err := os.Open(path)
-			if err != nil {
+			if _, err := w.Write(bundleConfig()); err != nil {
 				return err
 			}
-			defer f.Close()
-			_, err = io.Copy(w, f)
-			return err
-		})
+		}
 	}
 
-	// TODO: add gdbserver to apk?
-
+	if buildKeystore != "" {
+		planf("sign %s with key %s of %s", path, buildKeyAlias, buildKeystore)
+	} else {
+		planf("sign %s with the debug key", path)
+	}
 	if buildN {
 		return nil
 	}
-	return apkw.Close()
+	if err := apkw.Close(); err != nil {
+		return err
+	}
+	logf(levelNormal, "wrote %s", path)
+	return nil
+}
+
+// planf prints a step of the build done by gomobile itself, rather than
+// by a command, as a comment among the commands printed by -dry-run.
+func planf(format string, args ...interface{}) {
+	if buildPlan {
+		printcmd("# "+format, args...)
+	}
 }
 
 var xout io.Writer = os.Stderr
@@ -302,65 +792,206 @@ func printcmd(format string, args ...interface{}) {
 
 // "Build flags", used by multiple commands.
 var (
-	buildA bool    // -a
-	buildI bool    // -i
-	buildN bool    // -n
-	buildV bool    // -v
-	buildX bool    // -x
-	buildO *string // -o
+	buildA    bool    // -a
+	buildI    bool    // -i
+	buildN    bool    // -n
+	buildPlan bool    // -dry-run
+	buildV    bool    // -v
+	buildX    bool    // -x
+	buildWork bool    // -work
+	buildO    *string // -o
+
+	buildFormat          string   // -format
+	buildGcflags         []string // -gcflags
+	buildLdflags         []string // -ldflags
+	buildAndroidManifest string   // -androidmanifest
+	buildAssets          []string // -assets
+	buildIcon            string   // -icon
+	buildMinSDK          int      // -minsdk
+	buildAndroidAPI      int      // -androidapi
+	buildAndroidTarget   string   // -androidtarget
+	buildAppID           string   // -appid
+	buildMode            string   // -buildmode
+	buildDebug           bool     // -debug
+	buildActivity        string   // -activity
+	buildMod             string   // -mod
 )
 
+// makeWorkDir sets tmpdir to a new work directory, named with prefix,
+// for the files of a build. It returns the function removing the
+// directory when the build is done. With -work, the directory is printed
+// and kept for inspection.
+func makeWorkDir(prefix string) (cleanup func(), err error) {
+	if buildN {
+		tmpdir = "$WORK"
+	} else {
+		tmpdir, err = ioutil.TempDir("", prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if buildX || buildWork {
+		fmt.Fprintln(xout, "WORK="+tmpdir)
+	}
+	if buildWork {
+		return func() {}, nil
+	}
+	return func() { removeAll(tmpdir) }, nil
+}
+
+// The -buildmode=shared flag links the app against a shared library
+// holding the Go runtime and standard library, the shared core. The core
+// is compiled once into sharedCoreDir of the toolchain by go install
+// -buildmode=shared, and reused by the builds of every app until the Go
+// version or the build flags change.
+//
+// Android loads the libraries an app library depends on from the
+// library directory of the app only from API level 23 on. Older
+// versions look in the system directories alone and fail to start the
+// app.
+const (
+	sharedCoreDir    = "pkg_android_arm_shared"
+	sharedCoreLib    = "libstd.so"
+	sharedCoreMinSDK = 23
+)
+
+// addBuildModeFlag registers the -buildmode flag of the commands
+// building apps.
+func addBuildModeFlag(cmd *command) {
+	cmd.flag.StringVar(&buildMode, "buildmode", "default", "default, shared to link against a shared Go core, or c-shared for a JNI library")
+}
+
+// addSDKFlags registers the -minsdk and -androidapi flags.
+func addSDKFlags(cmd *command) {
+	cmd.flag.IntVar(&buildMinSDK, "minsdk", 0, "minimum Android API level")
+	cmd.flag.IntVar(&buildAndroidAPI, "androidapi", 0, "target Android API level")
+}
+
+// addAndroidTargetFlag registers the -androidtarget flag.
+func addAndroidTargetFlag(cmd *command) {
+	cmd.flag.StringVar(&buildAndroidTarget, "androidtarget", "phone", "device type of the manifest: phone, tv or wear")
+}
+
+func addAppIDFlag(cmd *command) {
+	cmd.flag.StringVar(&buildAppID, "appid", "", "application ID, the package name of the manifest")
+}
+
+// checkSDKFlags checks the values of -minsdk and -androidapi.
+func checkSDKFlags() error {
+	if buildMinSDK != 0 && buildMinSDK < minAndroidAPI {
+		return fmt.Errorf("-minsdk=%d is below the minimum supported API level %d", buildMinSDK, minAndroidAPI)
+	}
+	minSDK := buildMinSDK
+	if minSDK == 0 {
+		minSDK = minAndroidAPI
+	}
+	if buildAndroidAPI != 0 && buildAndroidAPI < minSDK {
+		return fmt.Errorf("-androidapi=%d is below the minimum API level %d", buildAndroidAPI, minSDK)
+	}
+	return nil
+}
+
 func addBuildFlags(cmd *command) {
 	cmd.flag.BoolVar(&buildA, "a", false, "")
 	cmd.flag.BoolVar(&buildI, "i", false, "")
+	cmd.flag.BoolVar(&buildWork, "work", false, "")
 	cmd.flag.Var((*stringsFlag)(&ctx.BuildTags), "tags", "")
+	cmd.flag.Var((*stringsFlag)(&buildGcflags), "gcflags", "")
+	cmd.flag.Var((*stringsFlag)(&buildLdflags), "ldflags", "")
+	cmd.flag.Var(&buildStrip, "strip", "strip the shared libraries: true, false or ldflags")
+	cmd.flag.Var((*stringsFlag)(&buildCflags), "cflags", "flags for the C compiler of cgo packages")
+	cmd.flag.Var((*stringsFlag)(&buildClibs), "clibs", "flags for the C linker of cgo packages")
+	cmd.flag.StringVar(&buildMod, "mod", "", "module download mode: readonly, vendor or mod")
+	addCacheFlag(cmd)
+}
+
+// modFlags returns the -mod flag of the go commands building packages.
+func modFlags() ([]string, error) {
+	switch buildMod {
+	case "":
+		return nil, nil
+	case "readonly", "vendor", "mod":
+		return []string{"-mod=" + buildMod}, nil
+	}
+	return nil, fmt.Errorf("unknown -mod %q, must be readonly, vendor or mod", buildMod)
 }
 
 func addBuildFlagsNVX(cmd *command) {
 	cmd.flag.BoolVar(&buildN, "n", false, "")
 	cmd.flag.BoolVar(&buildV, "v", false, "")
 	cmd.flag.BoolVar(&buildX, "x", false, "")
+	cmd.flag.BoolVar(&buildQuiet, "quiet", false, "print errors only")
 }
 
-// gobuild builds a package.
+// gobuild builds a package for android/arm.
 // If libPath is specified then it builds as a shared library.
 func gobuild(src, libPath string) error {
-	version, err := goVersion()
-	if err != nil {
-		return err
-	}
+	return gobuildTarget(nil, src, libPath)
+}
 
-	gopath := goEnv("GOPATH")
-	gomobilepath := ""
-	for _, p := range filepath.SplitList(gopath) {
-		gomobilepath = filepath.Join(p, "pkg", "gomobile")
-		if _, err = os.Stat(gomobilepath); err == nil {
-			break
-		}
-	}
-	if err != nil || gomobilepath == "" {
+// checkInstalledToolchain reports an error if the toolchain of gomobile
+// init is not installed in gomobilepath for the go command of the given
+// version.
+func checkInstalledToolchain(gomobilepath string, version []byte) error {
+	if gomobilepath == "" {
 		return errors.New("android toolchain not installed, run:\n\tgomobile init")
 	}
 	verpath := filepath.Join(gomobilepath, "version")
 	installedVersion, err := ioutil.ReadFile(verpath)
 	if err != nil {
-		return errors.New("android toolchain partially installed, run:\n\tgomobile init")
+		return errors.New("android toolchain partially installed, run:\n\tgomobile init -u")
 	}
 	if !bytes.Equal(installedVersion, version) {
-		return errors.New("android toolchain out of date, run:\n\tgomobile init")
+		return errors.New("android toolchain out of date, run:\n\tgomobile init -u")
+	}
+	return nil
+}
+
+// gobuildTarget builds a package for the -targets entry t, or for
+// android/arm with the toolchain of gomobile init if t is nil. The
+// targets of -targets do not need that toolchain: the host go tools
+// compile them, and their libraries are stripped by the linker.
+// If libPath is specified then it builds as a shared library.
+func gobuildTarget(t *goTarget, src, libPath string) error {
+	version, err := goVersion()
+	if err != nil {
+		return err
 	}
 
-	ndkccpath = filepath.Join(gomobilepath, "android-"+ndkVersion)
-	ndkccbin := filepath.Join(ndkccpath, "arm", "bin")
-	if buildX {
-		fmt.Fprintln(os.Stderr, "NDKCCPATH="+ndkccpath)
+	gopath := goEnv("GOPATH")
+	gomobilepath := gomobileDir()
+	var ndkccbin, ccbin string
+	if t == nil {
+		if err := checkInstalledToolchain(gomobilepath, version); err != nil {
+			return err
+		}
+		ndkccpath = filepath.Join(gomobilepath, "android-"+ndkVersion)
+		ndkccbin = filepath.Join(ndkccpath, "arm", "bin")
+		logf(levelDebug, "NDKCCPATH=%s", ndkccpath)
+		if err := findSystemNDK(); err != nil {
+			return err
+		}
+		if systemNDK != nil {
+			logf(levelDebug, "NDK=%s (%s)", systemNDK.root, systemNDK.revision)
+		}
+		ccbin = ndkBin()
 	}
 
+	mod, err := modFlags()
+	if err != nil {
+		return err
+	}
 	gocmd := exec.Command(
 		`go`,
 		`build`,
-		`-tags=`+strconv.Quote(strings.Join(ctx.BuildTags, ",")),
-		`-toolexec=`+filepath.Join(ndkccbin, "toolexec"))
+		`-tags=`+strconv.Quote(strings.Join(ctx.BuildTags, ",")))
+	if t == nil {
+		gocmd.Args = append(gocmd.Args, `-toolexec=`+filepath.Join(ndkccbin, "toolexec"))
+	}
+	gocmd.Args = append(gocmd.Args, mod...)
+	if buildA {
+		gocmd.Args = append(gocmd.Args, "-a")
+	}
 	if buildV {
 		gocmd.Args = append(gocmd.Args, "-v")
 	}
@@ -370,33 +1001,91 @@ func gobuild(src, libPath string) error {
 	if buildX {
 		gocmd.Args = append(gocmd.Args, "-x")
 	}
+	if len(buildGcflags) > 0 {
+		gocmd.Args = append(gocmd.Args, `-gcflags=`+quoteFields(buildGcflags))
+	}
+	ldflags := buildLdflags
+	strip := stripLdflags()
+	if t != nil && stripMode() == "true" {
+		strip = []string{"-s", "-w"}
+	}
+	if libPath != "" {
+		if buildMode == "c-shared" {
+			gocmd.Args = append(gocmd.Args, "-buildmode=c-shared")
+			ldflags = append(ldflags, strip...)
+		} else {
+			ldflags = append(append([]string{"-shared"}, ldflags...), strip...)
+		}
+	}
+	if len(ldflags) > 0 {
+		gocmd.Args = append(gocmd.Args, `-ldflags=`+quoteFields(ldflags))
+	}
 	if libPath == "" {
 		if *buildO != "" {
 			gocmd.Args = append(gocmd.Args, `-o`, *buildO)
 		}
 	} else {
-		gocmd.Args = append(gocmd.Args,
-			`-ldflags="-shared"`,
-			`-o`, libPath,
-		)
+		gocmd.Args = append(gocmd.Args, `-o`, libPath)
+	}
+
+	sharedCorePath = ""
+	if libPath != "" && buildMode == "shared" {
+		pkgdir := filepath.Join(gomobilepath, sharedCoreDir)
+		gocmd.Args = append(gocmd.Args, `-linkshared`, `-pkgdir=`+pkgdir)
+		sharedCorePath = filepath.Join(pkgdir, sharedCoreLib)
 	}
 
 	gocmd.Args = append(gocmd.Args, src)
 
 	gocmd.Stdout = os.Stdout
 	gocmd.Stderr = os.Stderr
-	gocmd.Env = []string{
-		`GOOS=android`,
-		`GOARCH=arm`,
-		`GOARM=7`,
-		`CGO_ENABLED=1`,
-		`CC=` + filepath.Join(ndkccbin, "arm-linux-androideabi-gcc"),
-		`CXX=` + filepath.Join(ndkccbin, "arm-linux-androideabi-g++"),
-		`GOGCCFLAGS="-fPIC -marm -pthread -fmessage-length=0"`,
-		`GOROOT=` + goEnv("GOROOT"),
-		`GOPATH=` + gopath,
-		`GOMOBILEPATH=` + ndkccbin, // for toolexec
+	if t != nil {
+		gocmd.Env = t.env(gopath)
+	} else {
+		gocmd.Env = []string{
+			`GOOS=android`,
+			`GOARCH=arm`,
+			`GOARM=7`,
+			`CGO_ENABLED=1`,
+			`CC=` + filepath.Join(ccbin, "arm-linux-androideabi-gcc"),
+			`CXX=` + filepath.Join(ccbin, "arm-linux-androideabi-g++"),
+			`GOGCCFLAGS="-fPIC -marm -pthread -fmessage-length=0"`,
+			`GOROOT=` + goEnv("GOROOT"),
+			`GOPATH=` + gopath,
+			`GOMOBILEPATH=` + ndkccbin, // for toolexec
+		}
+		gocmd.Env = append(gocmd.Env, cgoEnv("arm")...)
 	}
+
+	if sharedCorePath != "" {
+		if err := installSharedCore(gocmd.Env, ndkccbin, filepath.Dir(sharedCorePath)); err != nil {
+			return err
+		}
+	}
+
+	// Shared libraries are kept in the build cache. A library whose
+	// sources and build settings are unchanged is reused, unless -a
+	// forces a rebuild. The C header of -buildmode=c-shared is not kept,
+	// so those libraries are always built.
+	cachePath := ""
+	if libPath != "" && !buildN && buildMode != "c-shared" && gomobilepath != "" {
+		key, err := buildCacheKey(src, gocmd.Env, version)
+		if err != nil {
+			return err
+		}
+		cachePath = filepath.Join(gomobilepath, "cache", key+".so")
+		if _, err := os.Stat(cachePath); err == nil && !buildA {
+			logf(levelVerbose, "using cached %s", cachePath)
+			if err := copyFile(libPath, cachePath); err != nil {
+				return err
+			}
+			if t != nil {
+				return nil
+			}
+			return stripLib(ccbin, libPath)
+		}
+	}
+
 	if buildX {
 		printcmd("%s", strings.Join(gocmd.Env, " ")+" "+strings.Join(gocmd.Args, " "))
 	}
@@ -406,14 +1095,72 @@ func gobuild(src, libPath string) error {
 			return err
 		}
 	}
+	if cachePath != "" {
+		if err := writeCacheFile(cachePath, libPath); err != nil {
+			return err
+		}
+	}
+	if libPath != "" && t == nil {
+		return stripLib(ccbin, libPath)
+	}
 	return nil
 }
 
+// installSharedCore compiles the standard library into the shared core
+// in pkgdir, for the target of the build environment env. The go command
+// rebuilds only what is out of date, so the core is compiled once for
+// all the apps built against it.
+func installSharedCore(env []string, ndkccbin, pkgdir string) error {
+	cmd := exec.Command(
+		`go`,
+		`install`,
+		`-buildmode=shared`,
+		`-pkgdir=`+pkgdir,
+		`-tags=`+strconv.Quote(strings.Join(ctx.BuildTags, ",")),
+		`-toolexec=`+filepath.Join(ndkccbin, "toolexec"))
+	mod, err := modFlags()
+	if err != nil {
+		return err
+	}
+	cmd.Args = append(cmd.Args, mod...)
+	if buildA {
+		cmd.Args = append(cmd.Args, "-a")
+	}
+	if buildV {
+		cmd.Args = append(cmd.Args, "-v")
+	}
+	if buildX {
+		cmd.Args = append(cmd.Args, "-x")
+	}
+	if len(buildGcflags) > 0 {
+		cmd.Args = append(cmd.Args, `-gcflags=`+quoteFields(buildGcflags))
+	}
+	cmd.Args = append(cmd.Args, "std")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+
+	if buildX {
+		printcmd("%s", strings.Join(cmd.Env, " ")+" "+strings.Join(cmd.Args, " "))
+	}
+	if buildN {
+		return nil
+	}
+	// Builds sharing the gomobile directory install the core in turn.
+	unlock, err := lockFile(pkgdir + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cmd.Env = environ(cmd.Env)
+	return cmd.Run()
+}
+
 var importsAudioPkgs = make(map[string]struct{})
 
-// pkgImportsAudio returns true if the given package or one of its
+// pkgImportsAudio reports whether the given package or one of its
 // dependencies imports the mobile/audio package.
-func pkgImportsAudio(pkg *build.Package) bool {
+func pkgImportsAudio(pkg *build.Package) (bool, error) {
 	for _, path := range pkg.Imports {
 		if path == "C" {
 			continue
@@ -423,30 +1170,69 @@ func pkgImportsAudio(pkg *build.Package) bool {
 		}
 		importsAudioPkgs[path] = struct{}{}
 		if strings.HasPrefix(path, "golang.org/x/mobile/audio") {
-			return true
+			return true, nil
 		}
 		dPkg, err := ctx.Import(path, "", build.ImportComment)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error while looking up for the audio package: %v", err)
-			os.Exit(2)
+			return false, fmt.Errorf("looking up the audio package: %v", err)
 		}
-		if pkgImportsAudio(dPkg) {
-			return true
+		if ok, err := pkgImportsAudio(dPkg); ok || err != nil {
+			return ok, err
 		}
 	}
-	return false
+	return false, nil
 }
 
 func init() {
 	buildO = cmdBuild.flag.String("o", "", "output file")
+	cmdBuild.flag.StringVar(&buildFormat, "format", "apk", "output format: apk or aab")
+	cmdBuild.flag.StringVar(&buildAndroidManifest, "androidmanifest", "", "AndroidManifest.xml to merge")
+	cmdBuild.flag.Var((*stringsFlag)(&buildAssets), "assets", "asset directories, as dir or dir:prefix")
+	cmdBuild.flag.StringVar(&buildIcon, "icon", "", "PNG file of the launcher icon, at least 192x192 pixels")
+	addSDKFlags(cmdBuild)
+	addAndroidTargetFlag(cmdBuild)
+	addAppIDFlag(cmdBuild)
+	addKeystoreFlags(cmdBuild)
+	addBuildModeFlag(cmdBuild)
 	addBuildFlags(cmdBuild)
 	addBuildFlagsNVX(cmdBuild)
+	cmdBuild.flag.Int64Var(&buildMaxSize, "maxsize", 0, "fail if a native library, or those of an ABI together, exceed this many bytes")
+	cmdBuild.flag.StringVar(&buildTargets, "targets", "", "comma-separated GOOS/GOARCH pairs to build for instead of android/arm, with the C compilers of $CC_FOR_goos_goarch")
+	cmdBuild.flag.Var(&buildABISplit, "abisplit", "write an APK per ABI: true, false or universal")
+	cmdBuild.flag.BoolVar(&buildDebug, "debug", false, "build a debuggable APK with gdbserver and unstripped libraries")
+	cmdBuild.flag.BoolVar(&buildPlan, "dry-run", false, "print the plan of the build without running it")
+	cmdBuild.flag.StringVar(&buildActivity, "activity", "native", "host of the app: native for a NativeActivity, or fragment for an AAR with a Java view")
 
+	cmdInstall.flag.StringVar(&buildAndroidManifest, "androidmanifest", "", "AndroidManifest.xml to merge")
+	cmdInstall.flag.Var((*stringsFlag)(&buildAssets), "assets", "asset directories, as dir or dir:prefix")
+	cmdInstall.flag.StringVar(&buildIcon, "icon", "", "PNG file of the launcher icon, at least 192x192 pixels")
+	addSDKFlags(cmdInstall)
+	addAndroidTargetFlag(cmdInstall)
+	addAppIDFlag(cmdInstall)
+	addKeystoreFlags(cmdInstall)
+	addBuildModeFlag(cmdInstall)
 	addBuildFlags(cmdInstall)
 	addBuildFlagsNVX(cmdInstall)
 
+	cmdRun.flag.StringVar(buildO, "o", "", "output file")
+	cmdRun.flag.StringVar(&buildAndroidManifest, "androidmanifest", "", "AndroidManifest.xml to merge")
+	cmdRun.flag.Var((*stringsFlag)(&buildAssets), "assets", "asset directories, as dir or dir:prefix")
+	cmdRun.flag.StringVar(&buildIcon, "icon", "", "PNG file of the launcher icon, at least 192x192 pixels")
+	addSDKFlags(cmdRun)
+	addAndroidTargetFlag(cmdRun)
+	addAppIDFlag(cmdRun)
+	addBuildModeFlag(cmdRun)
+	addBuildFlags(cmdRun)
+	addBuildFlagsNVX(cmdRun)
+
 	addBuildFlagsNVX(cmdInit)
+	addCacheFlag(cmdInit)
+
+	addBuildFlagsNVX(cmdClean)
+	addCacheFlag(cmdClean)
 
+	cmdBind.flag.StringVar(buildO, "o", "", "output directory, or - for standard output, with -outputkind=src")
+	addSDKFlags(cmdBind)
 	addBuildFlags(cmdBind)
 	addBuildFlagsNVX(cmdBind)
 }
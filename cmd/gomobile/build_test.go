@@ -0,0 +1,377 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuoteFields(t *testing.T) {
+	tests := [][]string{
+		{"-s", "-w"},
+		{"-X", "main.version=1.0"},
+		{"-X", "main.name=hello world"},
+		{"-X", `main.quote=it's`},
+		{"-X", ""},
+	}
+	for _, fields := range tests {
+		s := quoteFields(fields)
+		got, err := splitQuotedFields(s)
+		if err != nil {
+			t.Errorf("splitQuotedFields(%q): %v", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, fields) {
+			t.Errorf("quoteFields(%q) = %q, splits into %q", fields, s, got)
+		}
+	}
+}
+
+// fakeToolchain points GOPATH at a temporary directory holding the
+// version file of an installed toolchain, and captures the commands
+// printed by gobuild under -n -x in the returned buffer. The returned
+// function restores the environment.
+func fakeToolchain(t *testing.T) (*bytes.Buffer, func()) {
+	version, err := goVersion()
+	if err != nil {
+		t.Skip(err)
+	}
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gomobilepath := filepath.Join(gopath, "pkg", "gomobile")
+	if err := os.MkdirAll(gomobilepath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(gomobilepath, "version"), version, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	oldGopath := os.Getenv("GOPATH")
+	oldNDK, oldSDK := os.Getenv("ANDROID_NDK_HOME"), os.Getenv("ANDROID_HOME")
+	xout = buf
+	buildN = true
+	buildX = true
+	os.Setenv("GOPATH", gopath)
+	// Build with the NDK of gomobile init, whatever NDK is installed.
+	os.Setenv("ANDROID_NDK_HOME", "")
+	os.Setenv("ANDROID_HOME", "")
+	return buf, func() {
+		xout = os.Stderr
+		buildN = false
+		buildX = false
+		buildGcflags = nil
+		buildLdflags = nil
+		systemNDK = nil
+		os.Setenv("GOPATH", oldGopath)
+		os.Setenv("ANDROID_NDK_HOME", oldNDK)
+		os.Setenv("ANDROID_HOME", oldSDK)
+		os.RemoveAll(gopath)
+	}
+}
+
+func TestBuildLdflags(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	if err := cmdBuild.flag.Parse([]string{"-ldflags", "-s -X 'main.name=hello world'"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		libPath string
+		want    string
+	}{
+		{"", ` -ldflags=-s -X 'main.name=hello world' `},
+		{"libapp.so", ` -ldflags=-shared -s -X 'main.name=hello world' -o libapp.so `},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		if err := gobuild("example.com/app", tt.libPath); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("gobuild(%q) command does not contain %q:\n%s", tt.libPath, tt.want, buf.String())
+		}
+	}
+}
+
+func TestBuildGcflags(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+
+	tests := []struct {
+		gcflags string
+		want    string
+	}{
+		{"all=-N -l", ` -gcflags=all=-N -l `},
+		{"example.com/app=-m", ` -gcflags=example.com/app=-m `},
+		{"-trimpath '/tmp/a b'", ` -gcflags=-trimpath '/tmp/a b' `},
+	}
+	for _, tt := range tests {
+		if err := cmdInstall.flag.Parse([]string{"-gcflags", tt.gcflags}); err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		if err := gobuild("example.com/app", "libapp.so"); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("-gcflags %q: command does not contain %q:\n%s", tt.gcflags, tt.want, buf.String())
+		}
+	}
+}
+
+func TestMakeWorkDir(t *testing.T) {
+	defer func() {
+		xout = os.Stderr
+		buildWork = false
+		tmpdir = ""
+	}()
+
+	for _, work := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		xout = buf
+		buildWork = work
+		cleanup, err := makeWorkDir("gomobile-test-work-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := tmpdir
+		cleanup()
+		_, err = os.Stat(dir)
+		if work {
+			if err != nil {
+				t.Errorf("-work: work directory removed: %v", err)
+			}
+			if want := "WORK=" + dir + "\n"; buf.String() != want {
+				t.Errorf("-work: printed %q, want %q", buf.String(), want)
+			}
+			os.RemoveAll(dir)
+		} else {
+			if !os.IsNotExist(err) {
+				t.Errorf("work directory %s left in place without -work", dir)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("printed %q without -work or -x", buf.String())
+			}
+		}
+	}
+}
+
+func TestBuildSharedCore(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	defer func() {
+		buildMode = "default"
+		sharedCorePath = ""
+	}()
+	if err := cmdBuild.flag.Parse([]string{"-buildmode", "shared"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gobuild("example.com/app", "libapp.so"); err != nil {
+		t.Fatal(err)
+	}
+	pkgdir := filepath.Join(os.Getenv("GOPATH"), "pkg", "gomobile", sharedCoreDir)
+	if want := filepath.Join(pkgdir, "libstd.so"); sharedCorePath != want {
+		t.Errorf("shared core %q, want %q", sharedCorePath, want)
+	}
+	out := buf.String()
+	install := strings.Index(out, " go install -buildmode=shared -pkgdir="+pkgdir+" ")
+	link := strings.Index(out, " -o libapp.so -linkshared -pkgdir="+pkgdir+" example.com/app")
+	if install < 0 {
+		t.Errorf("shared core not installed:\n%s", out)
+	}
+	if link < 0 {
+		t.Errorf("app not linked against the shared core:\n%s", out)
+	}
+	if install > link {
+		t.Errorf("shared core installed after linking the app:\n%s", out)
+	}
+
+	// Packages that are not apps are built as usual.
+	buf.Reset()
+	if err := gobuild("example.com/app", ""); err != nil {
+		t.Fatal(err)
+	}
+	if sharedCorePath != "" || strings.Contains(buf.String(), "-linkshared") {
+		t.Errorf("-buildmode=shared without a library:\n%s", buf.String())
+	}
+}
+
+func TestBuildMod(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	defer func() {
+		buildMode = "default"
+		buildMod = ""
+		sharedCorePath = ""
+	}()
+	// The shared core adds the go install of the standard library.
+	if err := cmdBuild.flag.Parse([]string{"-buildmode", "shared", "-mod", "vendor"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gobuild("example.com/app", "libapp.so"); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.Contains(line, " go build ") && !strings.Contains(line, " go install ") {
+			continue
+		}
+		n++
+		if !strings.Contains(line, " -mod=vendor ") {
+			t.Errorf("go command without -mod=vendor: %s", line)
+		}
+	}
+	if n != 2 {
+		t.Errorf("found %d go commands, want 2:\n%s", n, buf.String())
+	}
+
+	buildMod = "download"
+	if err := gobuild("example.com/app", "libapp.so"); err == nil || !strings.Contains(err.Error(), `unknown -mod "download"`) {
+		t.Errorf("gobuild with -mod=download: %v", err)
+	}
+}
+
+func TestBuildDebug(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	buildN, buildX = false, false // set by -dry-run
+	defer func() {
+		buildPlan = false
+		buildDebug = false
+		buildStrip = ""
+		*buildO = ""
+	}()
+
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const src = "package main\n\nimport \"golang.org/x/mobile/app\"\n\nfunc main() { app.Run(app.Callbacks{}) }\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	if err := cmdBuild.flag.Parse([]string{"-dry-run", "-debug"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBuild(cmdBuild); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "# pack lib/armeabi/gdbserver\n") {
+		t.Errorf("plan does not pack gdbserver:\n%s", out)
+	}
+	if strings.Contains(out, "androideabi-strip") {
+		t.Errorf("debug build is stripped:\n%s", out)
+	}
+
+	buildDebug, buildPlan = false, false
+	if err := cmdBuild.flag.Parse([]string{"-dry-run", "-debug", "-strip"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBuild(cmdBuild); err == nil {
+		t.Error("-debug -strip: got nil error")
+	}
+}
+
+func TestBuildDryRun(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	buildN, buildX = false, false // set by -dry-run
+	defer func() {
+		buildPlan = false
+		*buildO = ""
+	}()
+
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const src = "package main\n\nimport \"golang.org/x/mobile/app\"\n\nfunc main() { app.Run(app.Callbacks{}) }\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	if err := cmdBuild.flag.Parse([]string{"-dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBuild(cmdBuild); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	apk := filepath.Base(dir) + ".apk"
+	builds := 0
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, " go build ") {
+			builds++
+		}
+	}
+	if want := len(buildArchs); builds != want {
+		t.Errorf("plan has %d go builds, want %d, one per ABI:\n%s", builds, want, out)
+	}
+	for _, want := range []string{
+		" into " + apk + "\n",
+		"# abi armeabi-v7a",
+		"# pack AndroidManifest.xml\n",
+		"# sign " + apk + " with the debug key\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan does not contain %q:\n%s", want, out)
+		}
+	}
+	if _, err := os.Stat(apk); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote %s: %v", apk, err)
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("GOPATH"), "pkg", "gomobile", "cache")); !os.IsNotExist(err) {
+		t.Errorf("dry run filled the build cache: %v", err)
+	}
+}
+
+func TestPkgImportsAudioError(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	oldCtx := ctx
+	defer func() { ctx = oldCtx }()
+	ctx.GOPATH = gopath
+
+	pkg := &build.Package{Imports: []string{"example.com/missing"}}
+	if _, err := pkgImportsAudio(pkg); err == nil {
+		t.Error("missing import: got nil error")
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"go/build"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The build cache holds the shared libraries compiled by gobuild, in
+// the cache directory of the gomobile directory. A library is stored
+// under a key that hashes everything its compilation depends on: the Go
+// and NDK versions, the target, the build flags, and the source files
+// of the package and its dependencies outside GOROOT. The standard
+// library is covered by the Go version, as it is compiled for android
+// by gomobile init.
+
+// gomobileCache is the -cache flag: the gomobile directory, holding the
+// toolchain installed by gomobile init, the shared core and the build
+// cache. Several builds, in CI jobs for instance, may share it.
+var gomobileCache string
+
+func addCacheFlag(cmd *command) {
+	cmd.flag.StringVar(&gomobileCache, "cache", "", "gomobile directory of the toolchain and build cache")
+}
+
+// gomobileDirs returns the directories that may be the gomobile
+// directory, in order of preference: the -cache directory, then
+// $GOMOBILE_CACHE, then pkg/gomobile in each GOPATH entry.
+func gomobileDirs() []string {
+	if gomobileCache != "" {
+		return []string{gomobileCache}
+	}
+	if dir := os.Getenv("GOMOBILE_CACHE"); dir != "" {
+		return []string{dir}
+	}
+	var dirs []string
+	for _, p := range filepath.SplitList(goEnv("GOPATH")) {
+		dirs = append(dirs, filepath.Join(p, "pkg", "gomobile"))
+	}
+	return dirs
+}
+
+// gomobileDir returns the first of gomobileDirs that exists, or the
+// empty string if the toolchain is not installed.
+func gomobileDir() string {
+	for _, dir := range gomobileDirs() {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// writeCacheFile stores a copy of the file src as the cache entry dst.
+// The entry is copied to a temporary file renamed to dst, while holding
+// the lock of the entry, so builds sharing the cache never see a
+// partial entry or write the same entry at the same time.
+func writeCacheFile(dst, src string) error {
+	if buildX {
+		printcmd("cp %s %s", src, dst)
+	}
+	if buildN {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(dst + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := ioutil.TempFile(filepath.Dir(dst), filepath.Base(dst)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(w.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(w.Name())
+	}
+	return err
+}
+
+// buildCacheKey returns the cache key of the shared library built from
+// src for the target described by env, the environment of go build.
+func buildCacheKey(src string, env []string, goVersion []byte) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "go %s\n", goVersion)
+	fmt.Fprintf(h, "ndk %s\n", ndkVersion)
+	if systemNDK != nil {
+		fmt.Fprintf(h, "system ndk %s %s\n", systemNDK.root, systemNDK.revision)
+	}
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GOOS=") || strings.HasPrefix(kv, "GOARCH=") || strings.HasPrefix(kv, "GOARM=") || strings.HasPrefix(kv, "CGO_CFLAGS=") || strings.HasPrefix(kv, "CGO_LDFLAGS=") {
+			fmt.Fprintf(h, "env %s\n", kv)
+		}
+	}
+	fmt.Fprintf(h, "tags %q\n", ctx.BuildTags)
+	fmt.Fprintf(h, "gcflags %q\n", buildGcflags)
+	fmt.Fprintf(h, "ldflags %q\n", buildLdflags)
+	fmt.Fprintf(h, "buildmode %s\n", buildMode)
+	fmt.Fprintf(h, "mod %s\n", buildMod)
+	fmt.Fprintf(h, "goflags %q\n", os.Getenv("GOFLAGS"))
+	fmt.Fprintf(h, "strip %q\n", stripLdflags())
+
+	// gobuild is given either an import path or a .go file.
+	path, srcDir := src, ""
+	if strings.HasSuffix(src, ".go") {
+		path, srcDir = ".", filepath.Dir(src)
+	} else {
+		var err error
+		if srcDir, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	if err := hashPackage(h, path, srcDir, make(map[string]bool)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// hashPackage writes the names and contents of the source files of the
+// package and its dependencies outside GOROOT to h.
+func hashPackage(h hash.Hash, path, srcDir string, seen map[string]bool) error {
+	pkg, err := ctx.Import(path, srcDir, build.ImportComment)
+	if err != nil {
+		return err
+	}
+	if pkg.Goroot || seen[pkg.Dir] {
+		return nil
+	}
+	seen[pkg.Dir] = true
+
+	fmt.Fprintf(h, "package %s %s\n", pkg.ImportPath, pkg.Dir)
+	var files []string
+	for _, list := range [][]string{
+		pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles,
+		pkg.HFiles, pkg.SFiles, pkg.SysoFiles,
+	} {
+		files = append(files, list...)
+	}
+	for _, name := range files {
+		f, err := os.Open(filepath.Join(pkg.Dir, name))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "file %s\n", name)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, imp := range pkg.Imports {
+		if imp == "C" {
+			continue
+		}
+		if err := hashPackage(h, imp, pkg.Dir, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file src to dst, creating the directory of dst.
+func copyFile(dst, src string) error {
+	if buildX {
+		printcmd("cp %s %s", src, dst)
+	}
+	if buildN {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,192 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuildCacheKey(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	write := func(name, src string) {
+		path := filepath.Join(gopath, "src", "example.com", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app/main.go", "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/lib\"\n)\n\nfunc main() { fmt.Println(lib.X) }\n")
+	write("lib/lib.go", "package lib\n\nconst X = 1\n")
+
+	oldCtx, oldTags := ctx, ctx.BuildTags
+	defer func() {
+		ctx = oldCtx
+		ctx.BuildTags = oldTags
+	}()
+	ctx.GOPATH = gopath
+
+	env := []string{"GOOS=android", "GOARCH=arm", "GOARM=7", "GOPATH=" + gopath}
+	version := []byte("go version go1.5 linux/amd64")
+	key := func(env []string) string {
+		k, err := buildCacheKey("example.com/app", env, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	k1 := key(env)
+	if k2 := key(env); k1 != k2 {
+		t.Errorf("key changed without changes: %s, %s", k1, k2)
+	}
+	if k2 := key([]string{"GOOS=android", "GOARCH=arm", "GOARM=7", "GOPATH=/other"}); k1 != k2 {
+		t.Errorf("key depends on GOPATH: %s, %s", k1, k2)
+	}
+	if k2 := key([]string{"GOOS=android", "GOARCH=386", "GOPATH=" + gopath}); k1 == k2 {
+		t.Error("key does not depend on GOARCH")
+	}
+
+	ctx.BuildTags = []string{"mobile"}
+	if k2 := key(env); k1 == k2 {
+		t.Error("key does not depend on build tags")
+	}
+	ctx.BuildTags = oldTags
+
+	write("lib/lib.go", "package lib\n\nconst X = 2\n")
+	if k2 := key(env); k1 == k2 {
+		t.Error("key does not depend on dependency sources")
+	}
+}
+
+func TestGomobileDirs(t *testing.T) {
+	defer func(cache, env string) {
+		gomobileCache = cache
+		os.Setenv("GOMOBILE_CACHE", env)
+	}(gomobileCache, os.Getenv("GOMOBILE_CACHE"))
+
+	gomobileCache = ""
+	os.Setenv("GOMOBILE_CACHE", "/env")
+	if got := gomobileDirs(); len(got) != 1 || got[0] != "/env" {
+		t.Errorf("with GOMOBILE_CACHE: gomobileDirs() = %q, want [/env]", got)
+	}
+	gomobileCache = "/flag"
+	if got := gomobileDirs(); len(got) != 1 || got[0] != "/flag" {
+		t.Errorf("with -cache: gomobileDirs() = %q, want [/flag]", got)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "entry.lock")
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan error)
+	go func() {
+		unlock, err := lockFile(path)
+		if err == nil {
+			err = unlock()
+		}
+		locked <- err
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second lock taken while the first is held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lock not taken after the first is released")
+	}
+}
+
+func TestWriteCacheFileConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Two builds store different libraries as the same entry at once.
+	var srcs []string
+	var contents [][]byte
+	for _, c := range []byte("ab") {
+		b := bytes.Repeat([]byte{c}, 1<<20)
+		src := filepath.Join(dir, string(c)+".so")
+		if err := ioutil.WriteFile(src, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, src)
+		contents = append(contents, b)
+	}
+	dst := filepath.Join(dir, "cache", "key.so")
+
+	var wg sync.WaitGroup
+	errc := make(chan error, len(srcs))
+	for _, src := range srcs {
+		wg.Add(1)
+		go func(src string) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if err := writeCacheFile(dst, src); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}(src)
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, contents[0]) && !bytes.Equal(got, contents[1]) {
+		t.Errorf("cache entry is corrupt: %d bytes, neither source", len(got))
+	}
+	f, err := os.Open(filepath.Dir(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if want := []string{"key.so", "key.so.lock"}; len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("cache directory holds %q, want %q", names, want)
+	}
+}
@@ -11,16 +11,16 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"errors"
 	"io"
 	"math/big"
-	"time"
 )
 
 // signPKCS7 does the minimal amount of work necessary to embed an RSA
 // signature into a PKCS#7 certificate.
 //
-// We prepare the certificate using the x509 package, read it back in
-// to our custom data type and then write it back out with the signature.
+// We prepare a self-signed certificate using the x509 package and sign
+// msg with it, as debug builds need only be signed by some key.
 func signPKCS7(rand io.Reader, priv *rsa.PrivateKey, msg []byte) ([]byte, error) {
 	const serialNumber = 0x5462c4dd // arbitrary
 	name := pkix.Name{CommonName: "gomobile"}
@@ -35,11 +35,21 @@ func signPKCS7(rand io.Reader, priv *rsa.PrivateKey, msg []byte) ([]byte, error)
 	if err != nil {
 		return nil, err
 	}
+	return signPKCS7Cert(rand, priv, b, msg)
+}
 
-	c := certificate{}
-	if _, err := asn1.Unmarshal(b, &c); err != nil {
+// signPKCS7Cert embeds an RSA signature of msg by priv into a PKCS#7
+// certificate, along with cert, the DER-encoded certificate of priv.
+// Release builds are signed with the certificate of their keystore, which
+// identifies the app to app stores and to updates.
+func signPKCS7Cert(rand io.Reader, priv *rsa.PrivateKey, cert, msg []byte) ([]byte, error) {
+	c, err := x509.ParseCertificate(cert)
+	if err != nil {
 		return nil, err
 	}
+	if pub, ok := c.PublicKey.(*rsa.PublicKey); !ok || pub.N.Cmp(priv.N) != 0 || pub.E != priv.E {
+		return nil, errors.New("certificate does not match the signing key")
+	}
 
 	h := sha1.New()
 	h.Write(msg)
@@ -59,12 +69,12 @@ func signPKCS7(rand io.Reader, priv *rsa.PrivateKey, msg []byte) ([]byte, error)
 				Parameters: asn1.RawValue{Tag: 5},
 			}},
 			ContentInfo:  contentInfo{Type: oidData},
-			Certificates: c,
+			Certificates: asn1.RawValue{FullBytes: cert},
 			SignerInfos: []signerInfo{{
 				Version: 1,
 				IssuerAndSerialNumber: issuerAndSerialNumber{
-					Issuer:       name.ToRDNSequence(),
-					SerialNumber: serialNumber,
+					Issuer:       asn1.RawValue{FullBytes: c.RawIssuer},
+					SerialNumber: c.SerialNumber,
 				},
 				DigestAlgorithm: pkix.AlgorithmIdentifier{
 					Algorithm:  oidSHA1,
@@ -92,8 +102,8 @@ type signedData struct {
 	Version          int
 	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
 	ContentInfo      contentInfo
-	Certificates     certificate  `asn1:"tag0,explicit"`
-	SignerInfos      []signerInfo `asn1:"set"`
+	Certificates     asn1.RawValue // DER-encoded certificate
+	SignerInfos      []signerInfo  `asn1:"set"`
 }
 
 type contentInfo struct {
@@ -101,36 +111,6 @@ type contentInfo struct {
 	// Content is optional in PKCS#7 and not provided here.
 }
 
-// certificate is defined in rfc2459, section 4.1.
-type certificate struct {
-	TBSCertificate     tbsCertificate
-	SignatureAlgorithm pkix.AlgorithmIdentifier
-	SignatureValue     asn1.BitString
-}
-
-// tbsCertificate is defined in rfc2459, section 4.1.
-type tbsCertificate struct {
-	Version      int `asn1:"tag:0,default:2,explicit"`
-	SerialNumber int
-	Signature    pkix.AlgorithmIdentifier
-	Issuer       pkix.RDNSequence // pkix.Name
-	Validity     validity
-	Subject      pkix.RDNSequence // pkix.Name
-	SubjectPKI   subjectPublicKeyInfo
-}
-
-// validity is defined in rfc2459, section 4.1.
-type validity struct {
-	NotBefore time.Time
-	NotAfter  time.Time
-}
-
-// subjectPublicKeyInfo is defined in rfc2459, section 4.1.
-type subjectPublicKeyInfo struct {
-	Algorithm        pkix.AlgorithmIdentifier
-	SubjectPublicKey asn1.BitString
-}
-
 type signerInfo struct {
 	Version                   int
 	IssuerAndSerialNumber     issuerAndSerialNumber
@@ -140,8 +120,8 @@ type signerInfo struct {
 }
 
 type issuerAndSerialNumber struct {
-	Issuer       pkix.RDNSequence // pkix.Name
-	SerialNumber int
+	Issuer       asn1.RawValue // DER-encoded pkix.Name
+	SerialNumber *big.Int
 }
 
 // Various ASN.1 Object Identifies, mostly from rfc3852.
@@ -0,0 +1,66 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+var (
+	buildCflags []string // -cflags
+	buildClibs  []string // -clibs
+)
+
+// androidABIs maps the GOARCH values to the Android ABI names, used for
+// the jniLibs directories of native libraries.
+var androidABIs = map[string]string{
+	"arm":   "armeabi-v7a",
+	"arm64": "arm64-v8a",
+	"386":   "x86",
+	"amd64": "x86_64",
+}
+
+// cgoEnv returns the CGO_CFLAGS and CGO_LDFLAGS of the cross-compile
+// environment for goarch: the values of the variables in the gomobile
+// environment followed by the sysroot of a system NDK, the -cflags and
+// -clibs flags, and by the definitions relocating the support classes
+// of bind -supportpkg. The
+// tokens ${ABI} and ${GOARCH} are replaced by the Android ABI and GOARCH
+// of the target, so one setting can name the C libraries of each
+// architecture.
+// Variables with no flags are left out of the environment.
+func cgoEnv(goarch string) []string {
+	return cgoFlagsEnv(goarch, ndkCflags())
+}
+
+// cgoFlagsEnv is cgoEnv with the sysroot flags sysroot in place of
+// those of the NDK. The targets of -targets have none: their C compiler
+// knows its sysroot.
+func cgoFlagsEnv(goarch string, sysroot []string) []string {
+	abi := androidABIs[goarch]
+	if abi == "" {
+		abi = goarch
+	}
+	r := strings.NewReplacer("${ABI}", abi, "${GOARCH}", goarch)
+	cflags := append(append(append([]string{}, sysroot...), buildCflags...), supportCflags()...)
+	ldflags := append(append([]string{}, sysroot...), buildClibs...)
+	var env []string
+	for _, v := range []struct {
+		name  string
+		flags []string
+	}{
+		{"CGO_CFLAGS", cflags},
+		{"CGO_LDFLAGS", ldflags},
+	} {
+		flags := strings.Fields(os.Getenv(v.name))
+		flags = append(flags, v.flags...)
+		if len(flags) == 0 {
+			continue
+		}
+		env = append(env, v.name+"="+r.Replace(strings.Join(flags, " ")))
+	}
+	return env
+}
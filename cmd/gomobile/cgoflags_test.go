@@ -0,0 +1,75 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCgoEnv(t *testing.T) {
+	defer func(cflags, ldflags string) {
+		os.Setenv("CGO_CFLAGS", cflags)
+		os.Setenv("CGO_LDFLAGS", ldflags)
+		buildCflags, buildClibs = nil, nil
+	}(os.Getenv("CGO_CFLAGS"), os.Getenv("CGO_LDFLAGS"))
+	os.Setenv("CGO_CFLAGS", "")
+	os.Setenv("CGO_LDFLAGS", "-L/env/${GOARCH}")
+	buildCflags = []string{"-I/opt/foo/${ABI}/include", "-DFOO"}
+	buildClibs = []string{"-L/opt/foo/${ABI}/lib", "-lfoo"}
+
+	tests := []struct {
+		goarch string
+		want   []string
+	}{
+		{"arm64", []string{
+			"CGO_CFLAGS=-I/opt/foo/arm64-v8a/include -DFOO",
+			"CGO_LDFLAGS=-L/env/arm64 -L/opt/foo/arm64-v8a/lib -lfoo",
+		}},
+		{"amd64", []string{
+			"CGO_CFLAGS=-I/opt/foo/x86_64/include -DFOO",
+			"CGO_LDFLAGS=-L/env/amd64 -L/opt/foo/x86_64/lib -lfoo",
+		}},
+	}
+	for _, tt := range tests {
+		if got := cgoEnv(tt.goarch); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cgoEnv(%q) = %q, want %q", tt.goarch, got, tt.want)
+		}
+	}
+
+	os.Setenv("CGO_LDFLAGS", "")
+	buildCflags, buildClibs = nil, nil
+	if got := cgoEnv("arm"); got != nil {
+		t.Errorf("cgoEnv(%q) with no flags = %q, want none", "arm", got)
+	}
+}
+
+func TestBuildCflags(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	defer func(cflags string) {
+		os.Setenv("CGO_CFLAGS", cflags)
+		buildCflags, buildClibs = nil, nil
+	}(os.Getenv("CGO_CFLAGS"))
+	os.Setenv("CGO_CFLAGS", "")
+
+	if err := cmdBuild.flag.Parse([]string{"-cflags", "-I/opt/foo/${ABI}", "-clibs", "-L/opt/foo/${ABI} -lfoo"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gobuild("example.com/app", "libapp.so"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"CGO_CFLAGS=-I/opt/foo/armeabi-v7a ",
+		"CGO_LDFLAGS=-L/opt/foo/armeabi-v7a -lfoo ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("go build environment does not contain %q:\n%s", want, out)
+		}
+	}
+}
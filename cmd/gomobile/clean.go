@@ -0,0 +1,110 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+var cmdClean = &command{
+	run:   runClean,
+	Name:  "clean",
+	Usage: "[-n] [-x] [-quiet] [-toolchain] [-cache dir]",
+	Short: "remove gomobile build caches",
+	Long: `
+Clean removes the build cache of compiled libraries in
+$GOPATH/pkg/gomobile/cache, and the work directories left behind by
+interrupted gomobile commands: the build and bind work directories in
+the system temporary directory, and the init work directories in
+$GOPATH/pkg/gomobile. The packages and files in GOPATH are not
+modified.
+
+The -toolchain flag also removes the Android toolchain installed by
+'gomobile init' from $GOPATH/pkg/gomobile, so the next build requires
+running 'gomobile init' again.
+
+With the -cache flag or the GOMOBILE_CACHE environment variable, the
+gomobile directory they name is cleaned instead of $GOPATH/pkg/gomobile.
+
+The -n flag prints the directories that would be removed, without
+removing them. The -x flag prints the directories as they are removed.
+
+Clean prints the number of bytes reclaimed, unless -quiet.
+`,
+}
+
+var cleanToolchain bool // -toolchain
+
+func init() {
+	cmdClean.flag.BoolVar(&cleanToolchain, "toolchain", false, "also remove the android toolchain")
+}
+
+func runClean(cmd *command) error {
+	if buildN {
+		// Print what would be removed, as with -x.
+		buildX = true
+	}
+
+	var dirs []string
+	for _, pattern := range []string{"gobuildapk-work-*", "gomobile-bind-work-*"} {
+		m, err := filepath.Glob(filepath.Join(os.TempDir(), pattern))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, m...)
+	}
+	for _, gomobilepath := range gomobileDirs() {
+		if cleanToolchain {
+			if _, err := os.Stat(gomobilepath); err == nil {
+				dirs = append(dirs, gomobilepath)
+			}
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(gomobilepath, "cache")); err == nil {
+			dirs = append(dirs, filepath.Join(gomobilepath, "cache"))
+		}
+		m, err := filepath.Glob(filepath.Join(gomobilepath, "android-"+ndkVersion, "gomobile-init-*"))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, m...)
+	}
+
+	var reclaimed int64
+	for _, dir := range dirs {
+		n, err := diskUsage(dir)
+		if err != nil {
+			return err
+		}
+		if err := removeAll(dir); err != nil {
+			return err
+		}
+		reclaimed += n
+	}
+
+	if buildN {
+		resultf("%d bytes would be reclaimed", reclaimed)
+	} else {
+		resultf("%d bytes reclaimed", reclaimed)
+	}
+	return nil
+}
+
+// diskUsage returns the total size of the regular files in the
+// directory tree rooted at path.
+func diskUsage(path string) (int64, error) {
+	var n int64
+	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			n += info.Size()
+		}
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gopath := filepath.Join(dir, "gopath")
+	gomobilepath := filepath.Join(gopath, "pkg", "gomobile")
+	initWork := filepath.Join(gomobilepath, "android-"+ndkVersion, "gomobile-init-1")
+	cache := filepath.Join(gomobilepath, "cache")
+	buildWork := filepath.Join(dir, "tmp", "gobuildapk-work-1")
+	bindWork := filepath.Join(dir, "tmp", "gomobile-bind-work-1")
+	other := filepath.Join(dir, "tmp", "other")
+	files := map[string]int{
+		filepath.Join(gomobilepath, "version"):           10,
+		filepath.Join(initWork, "go", "file"):            100,
+		filepath.Join(cache, "0123.so"):                  1000000,
+		filepath.Join(buildWork, "lib", "libapp.so"):     1000,
+		filepath.Join(bindWork, "androidlib", "main.go"): 10000,
+		filepath.Join(other, "file"):                     100000,
+	}
+	for name, size := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	oldGopath, oldTmpdir := os.Getenv("GOPATH"), os.Getenv("TMPDIR")
+	defer func() {
+		xout = os.Stderr
+		buildN = false
+		buildX = false
+		cleanToolchain = false
+		os.Setenv("GOPATH", oldGopath)
+		os.Setenv("TMPDIR", oldTmpdir)
+	}()
+	xout = buf
+	os.Setenv("GOPATH", gopath)
+	os.Setenv("TMPDIR", filepath.Join(dir, "tmp"))
+
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	buildN = true
+	if err := runClean(cmdClean); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{initWork, cache, buildWork, bindWork} {
+		if !exists(path) {
+			t.Errorf("clean -n removed %s", path)
+		}
+		if !strings.Contains(buf.String(), path) {
+			t.Errorf("clean -n output does not mention %s:\n%s", path, buf.String())
+		}
+	}
+
+	buildN, buildX = false, false
+	if err := runClean(cmdClean); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{initWork, cache, buildWork, bindWork} {
+		if exists(path) {
+			t.Errorf("clean did not remove %s", path)
+		}
+	}
+	for _, path := range []string{gomobilepath, other} {
+		if !exists(path) {
+			t.Errorf("clean removed %s", path)
+		}
+	}
+
+	cleanToolchain = true
+	if err := runClean(cmdClean); err != nil {
+		t.Fatal(err)
+	}
+	if exists(gomobilepath) {
+		t.Errorf("clean -toolchain did not remove %s", gomobilepath)
+	}
+	if !exists(other) {
+		t.Errorf("clean -toolchain removed %s", other)
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"x": 3, "a/y": 5, "a/b/z": 7} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := diskUsage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 15 {
+		t.Errorf("diskUsage = %d, want 15", n)
+	}
+}
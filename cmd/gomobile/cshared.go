@@ -0,0 +1,129 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"debug/elf"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// buildCShared builds the main package pkg with -buildmode=c-shared into
+// a JNI library with no generated bindings. The output directory holds
+//
+//	jni/armeabi-v7a/lib<name>.so
+//	include/lib<name>.h
+//
+// the layout of prebuilt libraries in Android.mk and Gradle projects.
+func buildCShared(pkg *build.Package) error {
+	if pkg.Name != "main" {
+		return fmt.Errorf("-buildmode=c-shared requires a main package, %s is package %s", pkg.ImportPath, pkg.Name)
+	}
+	exports, err := cgoExports(pkg)
+	if err != nil {
+		return err
+	}
+	if len(exports) == 0 {
+		warnf("%s has no //export functions to call with JNI", pkg.ImportPath)
+	}
+
+	outDir := *buildO
+	if outDir == "" {
+		outDir = filepath.Base(pkg.Dir) + "-jni"
+	}
+	libName := "lib" + filepath.Base(pkg.Dir)
+	libPath := filepath.Join(outDir, "jni", androidABIs["arm"], libName+".so")
+	planf("build %s into %s", pkg.ImportPath, outDir)
+	planf("abi %s (GOARCH=arm GOARM=7)", androidABIs["arm"])
+	if err := mkdir(filepath.Dir(libPath)); err != nil {
+		return err
+	}
+	if err := gobuild(pkg.ImportPath, libPath); err != nil {
+		return err
+	}
+	if err := checkLibSizes([]apkLib{{abi: androidABIs["arm"], name: libName + ".so", path: libPath}}); err != nil {
+		return err
+	}
+
+	// The go command writes the header next to the library.
+	header := strings.TrimSuffix(libPath, ".so") + ".h"
+	includeDir := filepath.Join(outDir, "include")
+	if err := mkdir(includeDir); err != nil {
+		return err
+	}
+	if buildX {
+		printcmd("mv %s %s", header, includeDir)
+	}
+	if buildN {
+		return nil
+	}
+	if err := os.Rename(header, filepath.Join(includeDir, libName+".h")); err != nil {
+		return err
+	}
+	if err := checkExports(libPath, exports); err != nil {
+		return err
+	}
+	logf(levelNormal, "wrote %s", outDir)
+	return nil
+}
+
+// cgoExports returns the names of the functions of pkg exported to C
+// with //export comments, sorted.
+func cgoExports(pkg *build.Package) ([]string, error) {
+	var names []string
+	fset := token.NewFileSet()
+	for _, name := range pkg.CgoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if fields := strings.Fields(c.Text); len(fields) == 2 && fields[0] == "//export" {
+					names = append(names, fields[1])
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// checkExports checks that the dynamic symbol table of the shared
+// library at libPath holds the exported functions, to be found by
+// System.loadLibrary and JNI.
+func checkExports(libPath string, exports []string) error {
+	f, err := elf.Open(libPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	syms, err := f.DynamicSymbols()
+	if err != nil {
+		return fmt.Errorf("%s: %v", filepath.Base(libPath), err)
+	}
+	defined := make(map[string]bool)
+	for _, s := range syms {
+		if s.Section != elf.SHN_UNDEF {
+			defined[s.Name] = true
+		}
+	}
+	var missing []string
+	for _, name := range exports {
+		if !defined[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s does not export %s", filepath.Base(libPath), strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const cSharedSrc = `package main
+
+import "C"
+
+//export Add
+func Add(a, b C.int) C.int { return a + b }
+
+// Hello is exported too.
+//export Hello
+func Hello() {}
+
+func main() {}
+`
+
+// cSharedPkg writes a main package with exported functions to a
+// temporary directory and imports it with cgo enabled.
+func cSharedPkg(t *testing.T) (*build.Package, func()) {
+	dir, err := ioutil.TempDir("", "gomobile-cshared-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "hello.go"), []byte(cSharedSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := build.Default
+	c.CgoEnabled = true
+	p, err := c.ImportDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, func() { os.RemoveAll(dir) }
+}
+
+func TestCgoExports(t *testing.T) {
+	p, cleanup := cSharedPkg(t)
+	defer cleanup()
+	got, err := cgoExports(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Add", "Hello"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cgoExports=%q, want %q", got, want)
+	}
+}
+
+func TestCheckExports(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("no C compiler to build a c-shared library")
+	}
+	p, cleanup := cSharedPkg(t)
+	defer cleanup()
+
+	// A host library stands in for the Android one: both are ELF.
+	lib := filepath.Join(p.Dir, "libhello.so")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", lib, "hello.go")
+	cmd.Dir = p.Dir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=1", "GO111MODULE=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("cannot build a host c-shared library: %v\n%s", err, out)
+	}
+
+	if err := checkExports(lib, []string{"Add", "Hello"}); err != nil {
+		t.Errorf("checkExports: %v", err)
+	}
+	err := checkExports(lib, []string{"Add", "Missing"})
+	if err == nil || !strings.Contains(err.Error(), "does not export Missing") {
+		t.Errorf("checkExports with a missing function: %v", err)
+	}
+}
+
+func TestBuildCShared(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	defer func() {
+		buildMode = "default"
+		*buildO = ""
+	}()
+	p, pcleanup := cSharedPkg(t)
+	defer pcleanup()
+	if err := cmdBuild.flag.Parse([]string{"-buildmode", "c-shared", "-o", "out"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := buildCShared(p); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	lib := filepath.Join("out", "jni", "armeabi-v7a", "lib"+filepath.Base(p.Dir)+".so")
+	for _, want := range []string{
+		" -buildmode=c-shared -o " + lib + " ",
+		"mv " + strings.TrimSuffix(lib, ".so") + ".h " + filepath.Join("out", "include"),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("command does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "-ldflags=-shared") {
+		t.Errorf("c-shared library linked with -shared:\n%s", out)
+	}
+
+	p.Name = "hello"
+	if err := buildCShared(p); err == nil || !strings.Contains(err.Error(), "requires a main package") {
+		t.Errorf("buildCShared of a non-main package: %v", err)
+	}
+}
@@ -57,8 +57,13 @@ Commands:
 
 	bind        build a shared library for android APK and iOS app
 	build       compile android APK and iOS app
+	clean       remove gomobile build caches
+	doctor      check the environment for problems
 	init        install android compiler toolchain
 	install     compile android APK and iOS app and install on device
+	list        list the symbols bind exports from a package
+	run         compile android APK, install and run it on device
+	version     print version and toolchain information
 
 Use 'gomobile help [command]' for more information about that command.
 
@@ -69,7 +74,7 @@ Build a shared library for android APK and iOS app
 
 Usage:
 
-	gomobile bind [package]
+	gomobile bind [-outputkind=aar|src] [-o dir] [-annotations=androidx|javax] [-bytebuffer] [-commaok=nullable|result] [-examples] [-count [-json]] [-javapkg name] [-supportpkg name] [-maven group:artifact:version] [-versionname name] [-versioncode code] [-minsdk level] [-androidapi level] [package...]
 
 Bind generates language bindings like gobind (golang.org/x/mobile/cmd/gobind)
 for a package and builds a shared library for each platform from the go binding
@@ -80,6 +85,17 @@ archives the precompiled Java API stub classes, the compiled shared libraries,
 and all asset files in the /assets subdirectory under the package directory.
 The output AAR file name is '<package_name>.aar'.
 
+Several packages can be bound together into one AAR, named after the
+first package. The API of each package may then use the exported struct
+and interface types of the others, which are bound to the Java classes
+of the package defining them. The bound packages must have distinct
+names, and the assets of all the packages are included.
+
+A package that exports no declaration cannot be bound: bind reports an
+error before building anything. If every declaration a package exports
+is skipped, as variables of types that cannot be bound are, bind warns
+and lists them; 'gomobile list' prints the reasons.
+
 The AAR file is commonly used for binary distribution of an Android library
 project and most Android IDEs support AAR import. For example, in Android
 Studio (1.2+), an AAR file can be imported using the module import wizard
@@ -90,7 +106,119 @@ This command requires 'javac' (version 1.7+) and Android SDK (API level 9
 or newer) to build the library for Android. The environment variable
 ANDROID_HOME must be set to the path to Android SDK.
 
-The -v flag provides verbose output, including the list of packages built.
+The NDK is found as by the build command: an NDK installed on the
+system, named by ANDROID_NDK_HOME or found in ANDROID_HOME, is used
+instead of the NDK of gomobile init if it is compatible.
+
+The -androidapi flag selects the Android SDK platform of that API level
+in ANDROID_HOME to compile the Java classes against, instead of the
+latest installed platform. The -minsdk flag declares the minimum API
+level of the library in the AAR manifest.
+
+The -versionname and -versioncode flags set the android:versionName and
+android:versionCode attributes of the AAR manifest, so the library's
+metadata matches the app that includes it. Unset, the manifest declares
+no version.
+
+The entries of the AAR and its classes.jar are written in a fixed order
+with a fixed modification time, so the same sources produce the same
+archive. If the SOURCE_DATE_EPOCH environment variable is set to a number
+of seconds since the Unix epoch, that time is recorded; otherwise no
+time is recorded.
+
+The -annotations flag marks the results of the generated Java methods that
+may be null with a Nullable annotation, so the API is null-safe in Kotlin.
+It names the annotation package: androidx (androidx.annotation) or javax
+(javax.annotation, JSR 305). The app using the AAR must depend on the
+chosen annotation library.
+
+The Java API of a Go package named p is generated in the Java package
+go.p. The -javapkg flag replaces the go prefix, so with -javapkg=com.example
+the API of package p is in com.example.p.
+
+The Java support classes of the bindings, Go and Seq, are in the Java
+package go, and the native library of the bindings is libgojni.so. An
+app using two AARs bound separately would get both twice, and fails to
+build with duplicate classes. The -supportpkg flag relocates the support
+classes of an AAR to a Java package of its own, such as
+-supportpkg=com.example.mylib.go, together with the JNI functions
+implementing their native methods, and names the native library after
+it, as libgojni_com_example_mylib_go.so. AARs bound with distinct
+-supportpkg packages coexist in one app, each with its own Go runtime.
+The generated classes and the -examples use the relocated classes.
+
+The -bytebuffer flag binds []byte parameters of functions and methods
+as direct java.nio.ByteBuffers, which Go reads in place without copying.
+The bytes are only valid in Go until the call returns. See the gobind
+documentation for details.
+
+Functions and methods returning a value and a bool, the comma-ok idiom
+of func Lookup(k string) (v Value, ok bool), return the value in Java,
+or null when the bool is false. The -commaok=result flag returns a
+result class of both values instead, as for other multiple results.
+See the gobind documentation for the results taken as comma-ok.
+
+The -maven flag, of the form groupId:artifactId:version, prepares the
+AAR for a Maven repository, such as a local repository used by Gradle.
+The AAR is named '<artifactId>-<version>.aar' and written with a POM
+file, '<artifactId>-<version>.pom', declaring the artifact and the
+libraries it depends on, such as the annotation library chosen by
+-annotations, so that they are resolved transitively.
+
+The -outputkind flag selects what bind produces. The default, aar,
+builds the AAR described above. With -outputkind=src, bind stops after
+generating the binding sources and writes them to the directory named
+by -o, '<package_name>-src' by default: the Go binding package in go/
+and the Java API, with the Java support classes it depends on, in java/.
+Neither the Android SDK nor the Android toolchain is needed. With -o -,
+the generated Go and Java sources are written to standard output, each
+preceded by a '// File:' comment line with its path in the directory.
+The -o flag is only used with -outputkind=src.
+
+The -examples flag also writes a Java class Example for each bound
+package, in the Java package of its API, showing how to call it: the
+Go library is loaded, the interfaces are implemented in Java, and a few
+functions and the methods of the structs they construct are called.
+With -outputkind=src, Example.java is written with the Java API and
+compiles with it; otherwise it is written to the directory
+'<package_name>-examples', to copy into an app using the AAR. No
+Objective-C example is written, as bind generates no Objective-C API.
+
+The -count flag prints a summary of the API exported by bind when it
+is done: the numbers of exported types, of their exported methods, of
+exported functions and of declarations skipped, as listed by gomobile
+list, and the size in bytes of the AAR, or of the source directory with
+-outputkind=src. With -json, the summary is printed as a JSON object.
+The -count flag cannot be used with -o -.
+
+The Java support classes of the bindings, the same for every bound
+package, are compiled once and kept in the build cache in
+$GOPATH/pkg/gomobile/cache, keyed by the gomobile version. A cached copy
+that does not match its recorded checksum is compiled again, and the -a
+flag always compiles them. As with gomobile build, the -cache flag or
+the GOMOBILE_CACHE environment variable names another gomobile
+directory.
+
+Run by go generate, as by a line
+
+	//go:generate gomobile bind -outputkind=src -o ../binding .
+
+bind finds the file holding the line from the GOFILE environment
+variable, and resolves relative package paths, the -o flag and the
+other output paths against its directory, instead of the current
+directory. Without package arguments, the package bound is the one in
+that directory, which must be the package named by GOPACKAGE, or the
+package tested by it. Both variables are set by go generate.
+
+By default, the command prints the files it writes. The -v flag also
+prints the steps of the build and the output of the tools it runs,
+including the list of packages built, and the -x flag also prints the
+commands run. The -quiet flag prints nothing but errors, for scripts;
+it cannot be used with -n, -v or -x. Errors are always printed to
+standard error.
+
+The -work flag prints the name of the temporary work directory and keeps
+it in place when the command exits, instead of removing it.
 
 These build flags are shared by the build command.
 For documentation, see 'go help build':
@@ -98,30 +226,264 @@ For documentation, see 'go help build':
 	-i
 	-n
 	-x
+	-work
 	-tags 'tag list'
+	-gcflags '[pattern=]flag list'
+	-ldflags 'flag list'
 
 
 Compile android APK and iOS app
 
 Usage:
 
-	gomobile build [-o output] [-i] [build flags] [package]
+	gomobile build [-o output] [-format apk|aab] [-androidmanifest file] [-assets dirs] [-icon file] [-minsdk level] [-androidapi level] [-androidtarget phone|tv|wear] [-appid id] [-buildmode mode] [-keystore file -keyalias alias] [-abisplit[=universal]] [-maxsize bytes] [-targets goos/goarch,...] [-activity native|fragment] [-debug] [-dry-run] [-i] [build flags] [package]
 
 Build compiles and encodes the app named by the import path.
 
 The named package must define a main function.
 
-If an AndroidManifest.xml is defined in the package directory, it is
-added to the APK file. Otherwise, a default manifest is generated.
+If an AndroidManifest.xml is defined in the package directory, or named
+by the -androidmanifest flag, the entries gomobile requires are merged
+into it and the result is added to the APK file: the package name, the
+minimum SDK version, and a NativeActivity with the meta-data naming the
+library of the app. Permissions, activities and other entries of the
+manifest are kept, and where the manifest already declares the
+NativeActivity or its library name, its values are used. Without a
+manifest, a default manifest is generated.
+
+The -minsdk flag sets the minimum Android API level of the app, the
+minSdkVersion of the manifest, which is 9 by default. The -androidapi
+flag sets the API level the app targets, the targetSdkVersion. It must
+not be below the minimum. If the manifest declares uses-sdk itself,
+these flags cannot be used.
+
+The -androidtarget flag selects the kind of device the manifest is for:
+'phone' (the default), 'tv' or 'wear'. For 'tv', the NativeActivity is
+also started by the leanback launcher of Android TV, and the manifest
+declares the leanback feature and that no touchscreen is required. For
+'wear', the manifest requires a watch and marks the app as a standalone
+Wear app. The app is a NativeActivity in every case. A manifest given
+by the user gets the uses-feature entries it lacks; the intent filter
+and the Wear meta-data are only added where gomobile adds the
+NativeActivity or the application element.
+
+The -appid flag sets the application ID of the app, the package name of
+the manifest, which identifies the app on the device and on Google
+Play. It is in reverse DNS form, as in com.example.app: at least two
+elements separated by dots, each starting with a letter followed by
+letters, digits and underscores, and none a Java keyword. A malformed
+ID fails the build before anything is compiled. Without -appid, the ID
+is derived from the import path of the package, its domain reversed and
+followed by the rest of the path, with the characters not allowed in
+an ID replaced by underscores: github.com/user/my-app becomes
+com.github.user.my_app. A package without a domain in its import path,
+such as a directory outside GOPATH, gets org.golang.todo followed by
+the name of its directory. If the manifest declares a package, it must
+be a valid ID too, and -appid, if given, must match it.
 
 If the package directory contains an assets subdirectory, its contents
-are copied into the APK file.
+are copied into the APK file. The -assets flag names the asset
+directories instead, as a space-separated list. Each is of the form dir
+or dir:prefix, where the prefix is prepended to the names of the assets
+in dir, and relative directories are relative to the current directory.
+For example, -assets 'assets images:img' packs images/a.png as
+the asset opened by app.Open("img/a.png"). Two files with the same asset
+name are an error. The names of the asset directories are packed as
+well, so the app can list them with app.ReadDir.
+
+The -icon flag names a square PNG file, at least 192x192 pixels, used
+as the launcher icon of the app. It is scaled down to the icon size of
+each screen density, from mdpi (48x48) to xxxhdpi (192x192), and packed
+as the resource mipmap/ic_launcher, the icon of the application in
+the manifest. An AndroidManifest.xml declaring another icon conflicts
+with -icon. App Bundles do not support -icon.
 
 The -o flag specifies the output file name. If not specified, the
 output file name depends on the package built. The output file must end
-in '.apk'.
-
-The -v flag provides verbose output, including the list of packages built.
+in '.apk', or in '.aab' when building an App Bundle.
+
+The -format flag selects the output format: 'apk' (the default) for an
+installable Android package, or 'aab' for an Android App Bundle suitable
+for uploading to Google Play. App Bundles are signed with the same key
+as APK files.
+
+The -abisplit flag writes an APK per ABI instead of a single APK, each
+holding only the libraries of its ABI, named after the output file and
+the GOARCH of the ABI. One APK is split per architecture built: arm
+only, unless -targets is set, so app.apk becomes app-arm.apk alone; or
+one per -targets entry, so that -targets=android/arm64,android/riscv64
+splits app.apk into app-arm64.apk and app-riscv64.apk. The split APKs
+are smaller to download and install. With -abisplit=universal, the
+APK holding every ABI is written as well. To publish the split APKs on
+Google Play as multiple APKs, each must have its own versionCode, set
+in the AndroidManifest.xml of each build. -abisplit cannot be used with
+-format aab, as Google Play splits App Bundles by ABI itself.
+
+APK and App Bundle files are signed with a debug key, unless the
+-keystore flag names a keystore holding a release key, of the alias
+named by -keyalias. The keystore may be a PKCS#12 file, read with
+openssl, or a JKS keystore as written by keytool and Android Studio,
+which first requires keytool. Only RSA keys are supported. The -storepass
+flag sets the password of the keystore, and -keypass the password of the
+key, if it differs. To keep the passwords out of shell histories and
+process listings, set them in the GOMOBILE_STOREPASS and
+GOMOBILE_KEYPASS environment variables instead. The keystore is read
+before building, so a wrong password or alias fails early. Passwords
+are never printed, not even by -v or -x.
+
+Compiled libraries are kept in a build cache in $GOPATH/pkg/gomobile/cache.
+When the sources of the package and of its dependencies, the target, and
+the build flags are unchanged, the cached library is reused instead of
+compiling again. The -a flag forces a rebuild. Use 'gomobile clean' to
+empty the cache.
+
+The -cache flag names the gomobile directory to use instead of
+$GOPATH/pkg/gomobile: the directory of the toolchain installed by
+'gomobile init -cache', the shared core and the build cache. It
+defaults to the GOMOBILE_CACHE environment variable, if set. Keeping
+the directory between CI jobs, which otherwise start from scratch,
+saves installing the toolchain and compiling the libraries again.
+Builds sharing the directory, at the same time or not, lock the cache
+entries they write, so no build sees or leaves a partial entry.
+
+The -buildmode flag selects how the Go code of the app is linked. With
+'default', the runtime and standard library are linked into the library
+of the app. With 'shared', they are compiled once into a shared library,
+the shared core, kept in $GOPATH/pkg/gomobile/pkg_android_arm_shared,
+and the app library is linked against it. Later builds of any app reuse
+the core instead of compiling and linking the standard library again.
+The core is packed as lib/armeabi/libstd.so next to the app library,
+which the manifest names as before; Android loads the core as a
+dependency of the app library. This requires a minimum API level of 23,
+so -minsdk, or the uses-sdk of the manifest, must be at least 23.
+
+An app must be packed with the core it was linked against: the core
+holds the compiled standard library, and the Go runtime checks at
+startup that it matches the one seen by the linker, aborting the app
+otherwise. The core is therefore rebuilt when the Go version, the build
+tags or the -gcflags change, and apps built against an older core must
+be rebuilt too. Rerun 'gomobile init' after updating Go.
+
+With -buildmode=c-shared, gomobile build compiles a main package into
+a shared library for hand-written JNI code, skipping the Java bindings
+of gomobile bind. The functions marked //export in its cgo files are
+callable from C. The output directory, named by -o or else after the
+package directory with a -jni suffix, holds the library in
+jni/armeabi-v7a/lib<name>.so and its C header in include/lib<name>.h.
+The build fails if an exported function is missing from the dynamic
+symbol table of the library.
+
+The -activity flag selects what hosts the app on Android. With
+'native', the default, the app is the NativeActivity of an APK. With
+'fragment', gomobile build writes an AAR instead, named by -o or else
+after the package directory, for an app written in Java to show the Go
+app in its own activities. The AAR holds the library of the app, its
+assets, and the Java classes go.GoView, a GLSurfaceView whose renderer
+thread runs the callbacks of the app, and go.GoFragment, a Fragment
+showing a GoView, which can be declared in a layout:
+
+	<fragment android:name="go.GoFragment"
+		android:layout_width="match_parent"
+		android:layout_height="match_parent" />
+
+An activity showing a GoView itself must pass its onPause and onResume
+calls on to the view. The app starts with the first GoView created, and
+only one may show it at a time. The -appid flag sets the package of the
+manifest of the AAR. Its minimum API level, set by -minsdk, is 11 by
+default and cannot be lower. The AAR is not signed, and
+-activity=fragment cannot be used with -format aab, -abisplit,
+-keystore, -androidmanifest, -icon, -targets, -debug or
+-buildmode=shared.
+
+The -gcflags flag is passed to the Go compiler. As with go build, the
+flags may be prefixed with a package pattern, as in -gcflags 'all=-N -l'
+to disable optimizations and inlining in every package when debugging.
+
+The -strip flag removes the symbol table and debug information from the
+shared libraries built, with the strip tool of the NDK, shrinking the
+APK. The exported symbols of the libraries, the entry points of JNI and
+NativeActivity, are kept. With -strip=ldflags, the linker leaves them
+out instead, as with -ldflags '-s -w'. Release builds, signed with a
+-keystore key, are stripped unless -strip=false is given; other builds
+are not stripped by default. Under -v, the library sizes before and
+after stripping are printed.
+
+The -maxsize flag sets a size budget, in bytes, for the native libraries
+packed, not counting the gdbserver of -debug: the build fails if a
+library, or the libraries of an ABI together, are larger. The sizes are
+measured as packed, after stripping. Under -v, a table of the size of
+each library and the total of each ABI is printed, with or without
+-maxsize. -maxsize also applies to the
+library of -buildmode=c-shared and -activity=fragment builds, and is not
+checked by -n or -dry-run, which build nothing.
+
+The -debug flag builds an APK for debugging the native code of the app
+with gdb. The application is marked android:debuggable in the manifest,
+also in a manifest given by the user, the libraries are not stripped,
+even for a release build, and the gdbserver of the NDK is packed next
+to them, as lib/armeabi/gdbserver. -debug cannot be combined with
+-strip. To debug the app, build it with -gcflags 'all=-N -l' as well,
+install it and start it, and then attach to it:
+
+	adb forward tcp:5039 localfilesystem:/data/data/$PKG/debug-socket
+	adb shell run-as $PKG lib/gdbserver +debug-socket --attach $PID
+	$GOPATH/pkg/gomobile/android-ndk-r10d/arm/bin/arm-linux-androideabi-gdb libapp.so
+	(gdb) target remote :5039
+
+where $PKG is the package of the app, $PID its process ID, as listed by
+adb shell ps, and libapp.so the library of the app, unzipped from
+lib/armeabi in the APK.
+
+The C compiler of the NDK installed by gomobile init builds the cgo
+code, unless an NDK is installed on the system, as by the Android SDK
+manager of a CI image: the NDK named by the ANDROID_NDK_HOME environment
+variable, or else the NDK in the ndk-bundle directory of ANDROID_HOME,
+or the newest one in its ndk directory. A system NDK must be a revision
+from r10 to r17, the last one providing GCC. An incompatible NDK named
+by ANDROID_NDK_HOME is an error naming the revisions required, and one
+found in ANDROID_HOME is ignored with a warning. gomobile init is still
+needed for the Go cross compiler.
+
+The -cflags and -clibs flags pass flags to the NDK C compiler and linker
+of the cgo packages built, such as the -I and -L paths of the C libraries
+they use, after those of the CGO_CFLAGS and CGO_LDFLAGS environment
+variables. The tokens ${ABI} and ${GOARCH} in the flags and variables
+are replaced by the Android ABI (armeabi-v7a) and GOARCH (arm) of the
+target, as in -clibs '-L/opt/libfoo/${ABI} -lfoo'.
+
+The -targets flag builds the app for a comma-separated list of
+GOOS/GOARCH pairs instead of android/arm, bypassing the ABI tables of
+gomobile to try targets it does not know, as in -targets android/riscv64.
+The go tools of the host compile each target, without the toolchain of
+gomobile init, and its library is packed in the lib directory of the
+Android ABI of its GOARCH, or else in lib/<GOARCH>. cgo needs a C
+compiler for each target, named by the CC_FOR_goos_goarch environment
+variable, as CC_FOR_android_riscv64, and, for C++ code, a C++ compiler
+named by CXX_FOR_goos_goarch; a target with no C compiler is an error.
+The libraries stripped by -strip are stripped by the linker. -targets
+cannot be used with -buildmode=shared or c-shared, with
+-activity=fragment, or with -debug.
+
+The -mod flag is passed to every go command building packages for the
+target, as -mod=vendor to build a module from its vendor directory
+without network access. These go commands inherit the GOFLAGS
+environment variable, and the -mod flag overrides a -mod setting in it.
+
+The -dry-run flag prints the plan of the build without running any of
+it: the output file and the ABI, the commands the build would run, in
+order, as printed by -x, and, as comments starting with '#', the steps
+gomobile does itself, such as packing each file into the APK and
+signing it. Like -n, nothing is built or written.
+
+By default, the command prints the files it writes. The -v flag also
+prints the steps of the build and the output of the tools it runs,
+including the list of packages built, and the -x flag also prints the
+commands run. The -quiet flag prints nothing but errors, for scripts;
+it cannot be used with -n, -v or -x. Errors are always printed to
+standard error.
+
+The -work flag prints the name of the temporary work directory and keeps
+it in place when the command exits, instead of removing it.
 
 These build flags are shared by the build, install, and test commands.
 For documentation, see 'go help build':
@@ -129,36 +491,209 @@ For documentation, see 'go help build':
 	-i
 	-n
 	-x
+	-work
 	-tags 'tag list'
+	-gcflags '[pattern=]flag list'
+	-ldflags 'flag list'
+	-mod mode
+
+
+Remove gomobile build caches
+
+Usage:
+
+	gomobile clean [-n] [-x] [-quiet] [-toolchain] [-cache dir]
+
+Clean removes the build cache of compiled libraries in
+$GOPATH/pkg/gomobile/cache, and the work directories left behind by
+interrupted gomobile commands: the build and bind work directories in
+the system temporary directory, and the init work directories in
+$GOPATH/pkg/gomobile. The packages and files in GOPATH are not
+modified.
+
+The -toolchain flag also removes the Android toolchain installed by
+'gomobile init' from $GOPATH/pkg/gomobile, so the next build requires
+running 'gomobile init' again.
+
+With the -cache flag or the GOMOBILE_CACHE environment variable, the
+gomobile directory they name is cleaned instead of $GOPATH/pkg/gomobile.
+
+The -n flag prints the directories that would be removed, without
+removing them. The -x flag prints the directories as they are removed.
+
+Clean prints the number of bytes reclaimed, unless -quiet.
+
+
+Check the environment for problems
+
+Usage:
+
+	gomobile doctor 
+
+Doctor checks that the environment is set up for gomobile and prints
+the result of each check, with a hint on how to fix any problem:
+
+	go          a Go 1.5 tool on $PATH, matching the Go gomobile was built with
+	GOPATH      GOPATH is set and its bin directory, or GOBIN, is on $PATH
+	toolchain   the Android NDK and Go cross compiler installed by 'gomobile init'
+	cgo         the Go standard library compiled with cgo for android/arm
+	adb         the 'adb' tool on $PATH, needed by the install and run commands
+
+A failed check prevents building apps and bindings, and doctor exits
+with a nonzero status. A warning only affects some commands.
 
 
 Install android compiler toolchain
 
 Usage:
 
-	gomobile init [-u]
+	gomobile init [-u] [-json] [-ndk-url 'url list'] [-cache dir]
 
 Init downloads and installs the Android C++ compiler toolchain.
 
-The toolchain is installed in $GOPATH/pkg/gomobile.
+The toolchain is installed in $GOPATH/pkg/gomobile, or in the directory
+named by the -cache flag or the GOMOBILE_CACHE environment variable.
+Inits sharing the directory install the toolchain one at a time.
 If the Android C++ compiler toolchain already exists in the path,
 it skips download and uses the existing toolchain.
 
-The -u option forces download and installation of the new toolchain
-even when the toolchain exists.
+If an NDK is installed on the system, as described by 'gomobile help
+build', init uses it instead of downloading one, and only builds the
+Go cross compiler and the android/arm standard library with it. An
+incompatible NDK is reported with the revisions required.
+
+The -u option updates an installed toolchain in place. Init compares the
+toolchain against the Go tool on the PATH and rebuilds only what is out
+of date: the cross compiler and the android/arm standard library after
+a Go update, without downloading the NDK again. A missing or partially
+downloaded NDK, or a toolchain left incomplete by an interrupted init,
+is installed again. Init prints what was updated, or that the toolchain
+is up to date. To install everything from scratch, run
+'gomobile clean -toolchain' first.
+
+Downloads are kept in $GOPATH/pkg/gomobile/dl until they complete, so an
+interrupted init resumes them where they stopped. A completed archive
+is checked against its known SHA-256 checksum before it is extracted;
+on a mismatch the download is discarded and init fails.
+
+The -ndk-url flag names a space-separated list of mirror URLs from
+which the toolchain archives are downloaded instead of the default
+Google servers. Each archive is fetched from the first mirror serving
+it, with the archive name appended to the mirror URL. The mirrors may
+also be set by the GOMOBILE_NDK_URL environment variable; the flag takes
+precedence. Downloads failing with a network error or a server error
+are retried a few times, waiting longer after each attempt, before the
+next mirror is tried. If every mirror fails, init reports the error of
+each URL. Proxies set by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+environment variables are used.
+
+Init prints each step to standard error, and with -v the download
+progress; -quiet prints nothing but errors. The -json flag prints them
+to standard output as a stream of JSON objects, one per line, for use
+by other programs:
+
+	type Event struct {
+		Step  string // "download", "verify", "extract", "build", "install" or "update"
+		Msg   string // description of the step
+		Bytes int64  // bytes downloaded so far, for "download"
+		Total int64  // size of the download, if known
+	}
 
 
 Compile android APK and iOS app and install on device
 
 Usage:
 
-	gomobile install [package]
+	gomobile install [-device serial|all] [-arch arm] [-r] [-d] [-timeout d] [-androidmanifest file] [-androidtarget phone|tv|wear] [-appid id] [-assets dirs] [-icon file] [build flags] [package]
 
 Install compiles and installs the app named by the import path on the
 attached mobile device.
 
+If more than one device or emulator is attached, the -device flag
+selects one by its adb serial number, as listed by 'adb devices'.
+With -device=all, the app is installed on every attached device in
+turn, and the result for each device is reported at the end: on
+standard output, unless -quiet, or on standard error if any failed.
+
+Before the build, install asks each device for its primary ABI with
+'adb shell getprop ro.product.cpu.abi' and checks that the app can run
+there. gomobile compiles Android apps for arm only, which arm64 devices
+run too. The -arch flag names the architecture to compile for and, as
+only arm is supported for now, accepts only -arch=arm. It skips the
+ABI check, to install on a device that misreports its ABI.
+
+The -r flag replaces an app that is already installed, keeping its
+data. The -d flag allows installing an app with a lower version code
+than the installed one. An app signed with a different key cannot be
+replaced; uninstall it first with 'adb uninstall'.
+
+The -timeout flag limits the time each adb command may run, 5m by
+default, or 0 for no limit. An adb command running longer, such as an
+install hung on a flaky USB connection, is killed and reported. An
+install killed this way may leave the app partially installed.
+
+This command requires the 'adb' tool on the PATH.
+
+See the build command help for common flags and common behavior.
+
+
+List the symbols bind exports from a package
+
+Usage:
+
+	gomobile list [-json] [package...]
+
+List prints the exported declarations of the packages, in the current
+directory if none are named, that bind would export: each function,
+type and constant, with its kind and status. Declarations that bind
+cannot export, such as a function returning a channel, are listed as
+skipped, with the reasons. Bind fails on those declarations, so they
+must be changed or unexported before binding the package.
+
+The packages are checked as by bind, without generating any code, so
+neither the Android SDK nor the toolchain is needed.
+
+The -json flag prints the declarations as a JSON array of objects.
+
+
+Compile android APK, install and run it on device
+
+Usage:
+
+	gomobile run [-o output] [-r] [-d] [-androidmanifest file] [-androidtarget phone|tv|wear] [-appid id] [-assets dirs] [-icon file] [-timeout d] [-logcat=false] [build flags] [package]
+
+Run compiles and installs the app named by the import path on the
+attached mobile device, launches its main activity, and streams the
+app's log output until interrupted.
+
+The -logcat flag controls whether the log output of the app process is
+streamed after launch. It is on by default. With -logcat=false, run
+exits once the app has been started.
+
+The -r, -d and -timeout flags are as for the install command. The log
+output is streamed without a time limit.
+
 This command requires the 'adb' tool on the PATH.
 
 See the build command help for common flags and common behavior.
+
+
+Print version and toolchain information
+
+Usage:
+
+	gomobile version [-json]
+
+Version prints the revision of the golang.org/x/mobile repository
+gomobile was built from, the Go version used to build gomobile, the Go
+tool found on $PATH, and the state of the Android toolchain installed
+by 'gomobile init': the NDK version and directory, and the OpenGL ES
+headers it provides.
+
+If the toolchain is missing, partially installed or was installed for
+a different Go version, version says so and suggests running
+'gomobile init'.
+
+The -json flag prints the same information as a JSON object.
 */
 package main
@@ -0,0 +1,161 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var cmdDoctor = &command{
+	run:   runDoctor,
+	Name:  "doctor",
+	Usage: "",
+	Short: "check the environment for problems",
+	Long: `
+Doctor checks that the environment is set up for gomobile and prints
+the result of each check, with a hint on how to fix any problem:
+
+	go          a Go 1.5 tool on $PATH, matching the Go gomobile was built with
+	GOPATH      GOPATH is set and its bin directory, or GOBIN, is on $PATH
+	toolchain   the Android NDK and Go cross compiler installed by 'gomobile init'
+	cgo         the Go standard library compiled with cgo for android/arm
+	adb         the 'adb' tool on $PATH, needed by the install and run commands
+
+A failed check prevents building apps and bindings, and doctor exits
+with a nonzero status. A warning only affects some commands.
+`,
+}
+
+// A doctorResult is the result of one check of gomobile doctor.
+type doctorResult struct {
+	name   string
+	status string // "ok", "warn" or "FAIL"
+	msg    string
+	hint   string // how to fix a warning or failure
+}
+
+// doctorEnv is the environment inspected by gomobile doctor.
+type doctorEnv struct {
+	info   *versionInfo
+	goErr  error  // error running the Go tool, if any
+	gopath string // GOPATH
+	gobin  string // GOBIN
+	path   string // PATH
+	cgo    bool   // the android/arm standard library includes runtime/cgo
+	adbErr error  // error looking for adb, if any
+}
+
+func runDoctor(cmd *command) error {
+	env := doctorEnv{
+		info:  toolchainInfo(),
+		path:  os.Getenv("PATH"),
+		gobin: os.Getenv("GOBIN"),
+	}
+	_, env.goErr = goVersion()
+	if env.goErr == nil {
+		env.gopath = goEnv("GOPATH")
+		env.gobin = goEnv("GOBIN")
+		cgo := filepath.Join(goEnv("GOROOT"), "pkg", "android_arm", "runtime", "cgo.a")
+		_, err := os.Stat(cgo)
+		env.cgo = err == nil
+	}
+	_, env.adbErr = exec.LookPath("adb")
+
+	results := doctorChecks(env)
+	printDoctor(os.Stdout, results)
+	for _, r := range results {
+		if r.status == "FAIL" {
+			return errors.New("") // the failures are already printed
+		}
+	}
+	return nil
+}
+
+// doctorChecks checks env and returns the results in the order in which
+// they should be fixed.
+func doctorChecks(env doctorEnv) []doctorResult {
+	var results []doctorResult
+	add := func(name, status, msg, hint string) {
+		results = append(results, doctorResult{name, status, msg, hint})
+	}
+
+	if env.goErr != nil {
+		add("go", "FAIL", env.goErr.Error(), "install Go 1.5 or newer and add its bin directory to $PATH")
+		// The other checks ask the Go tool.
+		return results
+	}
+	switch {
+	case !strings.Contains(env.info.Go, env.info.GoBuild+" "):
+		add("go", "warn", fmt.Sprintf("%s, but gomobile was built with %s", env.info.Go, env.info.GoBuild),
+			"reinstall gomobile with 'go install golang.org/x/mobile/cmd/gomobile'")
+	default:
+		add("go", "ok", env.info.Go, "")
+	}
+
+	if env.gopath == "" {
+		add("GOPATH", "FAIL", "GOPATH is not set", "set GOPATH, for example 'export GOPATH=$HOME'")
+	} else {
+		bin := env.gobin
+		if bin == "" {
+			bin = filepath.Join(filepath.SplitList(env.gopath)[0], "bin")
+		}
+		if onPath(bin, env.path) {
+			add("GOPATH", "ok", env.gopath, "")
+		} else {
+			add("GOPATH", "warn", fmt.Sprintf("%s is not on $PATH", bin),
+				fmt.Sprintf("add %s to $PATH to run the installed gomobile", bin))
+		}
+	}
+
+	switch env.info.Toolchain {
+	case "installed":
+		add("toolchain", "ok", fmt.Sprintf("NDK %s in %s", env.info.NDK, env.info.NDKPath), "")
+	case "outdated":
+		add("toolchain", "FAIL", "installed for a different Go version", "run 'gomobile init'")
+	case "partial":
+		add("toolchain", "FAIL", fmt.Sprintf("partially installed, NDK %s missing or incomplete", env.info.NDK), "run 'gomobile init'")
+	default:
+		add("toolchain", "FAIL", "not installed", "run 'gomobile init'")
+	}
+
+	if env.cgo {
+		add("cgo", "ok", "android/arm standard library built with cgo", "")
+	} else {
+		add("cgo", "FAIL", "android/arm standard library not installed in GOROOT", "run 'gomobile init'")
+	}
+
+	if env.adbErr == nil {
+		add("adb", "ok", "found on $PATH", "")
+	} else {
+		add("adb", "warn", "not found on $PATH, the install and run commands will fail",
+			"install the Android SDK platform tools and add them to $PATH")
+	}
+	return results
+}
+
+// onPath reports whether dir is in the list of directories path.
+func onPath(dir, path string) bool {
+	for _, p := range filepath.SplitList(path) {
+		if filepath.Clean(p) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func printDoctor(w io.Writer, results []doctorResult) {
+	for _, r := range results {
+		fmt.Fprintf(w, "%-7s %-10s %s\n", "["+r.status+"]", r.name, r.msg)
+		if r.hint != "" && r.status != "ok" {
+			fmt.Fprintf(w, "%-18s %s\n", "", r.hint)
+		}
+	}
+}
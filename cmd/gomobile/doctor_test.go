@@ -0,0 +1,82 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDoctorChecks(t *testing.T) {
+	gopath := filepath.FromSlash("/home/gopher")
+	good := doctorEnv{
+		info: &versionInfo{
+			GoBuild:   "go1.5",
+			Go:        "go version go1.5 linux/amd64",
+			Toolchain: "installed",
+			NDK:       ndkVersion,
+			NDKPath:   filepath.Join(gopath, "pkg", "gomobile", "android-"+ndkVersion),
+		},
+		gopath: gopath,
+		path:   filepath.FromSlash("/usr/bin") + string(filepath.ListSeparator) + filepath.Join(gopath, "bin"),
+		cgo:    true,
+	}
+	status := func(env doctorEnv) map[string]string {
+		m := make(map[string]string)
+		for _, r := range doctorChecks(env) {
+			m[r.name] = r.status
+		}
+		return m
+	}
+	check := func(desc string, env doctorEnv, name, want string) {
+		if got := status(env)[name]; got != want {
+			t.Errorf("%s: %s check is %q, want %q", desc, name, got, want)
+		}
+	}
+
+	for name, s := range status(good) {
+		if s != "ok" {
+			t.Errorf("good environment: %s check is %q, want ok", name, s)
+		}
+	}
+
+	env := good
+	env.goErr = errors.New("no Go tool on $PATH")
+	if got := doctorChecks(env); len(got) != 1 || got[0].status != "FAIL" {
+		t.Errorf("no Go tool: got %v, want a single failure", got)
+	}
+
+	info := *good.info
+	info.Go = "go version go1.4.2 linux/amd64"
+	env = good
+	env.info = &info
+	check("old Go", env, "go", "warn")
+
+	env = good
+	env.gopath = ""
+	check("no GOPATH", env, "GOPATH", "FAIL")
+	env = good
+	env.path = filepath.FromSlash("/usr/bin")
+	check("GOPATH/bin not on PATH", env, "GOPATH", "warn")
+	env.gobin = filepath.FromSlash("/usr/bin")
+	check("GOBIN on PATH", env, "GOPATH", "ok")
+
+	for _, tc := range []string{"outdated", "partial", "missing"} {
+		info := *good.info
+		info.Toolchain = tc
+		env = good
+		env.info = &info
+		check(tc+" toolchain", env, "toolchain", "FAIL")
+	}
+
+	env = good
+	env.cgo = false
+	check("no cgo", env, "cgo", "FAIL")
+
+	env = good
+	env.adbErr = errors.New("not found")
+	check("no adb", env, "adb", "warn")
+}
@@ -0,0 +1,202 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"unicode/utf16"
+)
+
+// The launcher icon of an app built with -icon is the resource
+// mipmap/ic_launcher, the only resource of the app. Android picks the
+// PNG file of the density closest to that of the screen.
+const (
+	launcherIconRef = "@mipmap/ic_launcher"
+	launcherIconID  = 0x7f010000 // package 0x7f, type 1 (mipmap), entry 0
+)
+
+// iconDensities are the density buckets of the launcher icon, with the
+// dots per inch and the size in pixels of the icon in each.
+var iconDensities = []struct {
+	name string
+	dpi  int
+	size int
+}{
+	{"mdpi", 160, 48},
+	{"hdpi", 240, 72},
+	{"xhdpi", 320, 96},
+	{"xxhdpi", 480, 144},
+	{"xxxhdpi", 640, 192},
+}
+
+// iconPath returns the path in the APK of the launcher icon of the
+// named density.
+func iconPath(density string) string {
+	return "res/mipmap-" + density + "/ic_launcher.png"
+}
+
+// An appIcon is the launcher icon of an app, as packed in its APK.
+type appIcon struct {
+	table []byte   // resources.arsc
+	pngs  [][]byte // the PNG file of each of the iconDensities
+}
+
+// readIcon reads the PNG image of -icon. It must be square, and at least
+// as large as the icon of the highest density.
+func readIcon(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("-icon %s: %v", path, err)
+	}
+	max := iconDensities[len(iconDensities)-1]
+	b := img.Bounds()
+	switch {
+	case b.Dx() != b.Dy():
+		return nil, fmt.Errorf("-icon %s: the icon is %dx%d pixels, it must be square", path, b.Dx(), b.Dy())
+	case b.Dx() < max.size:
+		return nil, fmt.Errorf("-icon %s: the icon is %dx%d pixels, it must be at least %dx%d for %s screens", path, b.Dx(), b.Dy(), max.size, max.size, max.name)
+	}
+	return img, nil
+}
+
+// launcherIcon returns the launcher icon of the app with the Java
+// package pkg, src scaled down to each density.
+func launcherIcon(src image.Image, pkg string) (*appIcon, error) {
+	icon := &appIcon{table: iconTable(pkg)}
+	for _, d := range iconDensities {
+		buf := new(bytes.Buffer)
+		if err := png.Encode(buf, scaleIcon(src, d.size)); err != nil {
+			return nil, err
+		}
+		icon.pngs = append(icon.pngs, buf.Bytes())
+	}
+	return icon, nil
+}
+
+// scaleIcon returns src scaled down to size x size pixels. Each pixel is
+// the average of the pixels of src it covers, with premultiplied alpha,
+// so that transparent pixels do not darken the edges of the icon.
+func scaleIcon(src image.Image, size int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/size, b.Min.Y+(y+1)*b.Dy()/size
+		for x := 0; x < size; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/size, b.Min.X+(x+1)*b.Dx()/size
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// Sizes of the chunks of iconTable.
+const (
+	tablePackageHeaderSize = 288
+	tableTypeSpecSize      = 16 + 4 // header, the flags of the entry
+	tableConfigSize        = 64     // ResTable_config
+	tableTypeHeaderSize    = 20 + tableConfigSize
+	tableTypeSize          = tableTypeHeaderSize + 4 + 8 + 8 // header, entry offset, ResTable_entry, Res_value
+)
+
+// iconTable returns the resource table, resources.arsc, of an app with
+// the Java package pkg whose only resource is its launcher icon: a
+// mipmap with a PNG file for each of the iconDensities. The format is
+// that of ResTable in ResourceTypes.h; see binaryXML.
+//
+//	Table header
+//	String Pool: the paths of the PNG files
+//	Package 0x7f
+//		String Pool: the type names, "mipmap"
+//		String Pool: the entry names, "ic_launcher"
+//		Type Spec of type 1: the entry varies with the density
+//		Type of type 1, for each density: the entry, a string
+//		value indexing the path of its file
+func iconTable(pkg string) []byte {
+	values, types, keys := new(binStringPool), new(binStringPool), new(binStringPool)
+	for _, d := range iconDensities {
+		values.get(iconPath(d.name))
+	}
+	types.get("mipmap")
+	keys.get("ic_launcher")
+
+	pkgSize := tablePackageHeaderSize + types.size() + keys.size() + tableTypeSpecSize + len(iconDensities)*tableTypeSize
+	size := 12 + values.size() + pkgSize
+	b := make([]byte, 0, size)
+	b = appendChunkHeader(b, headerTable, 12, size)
+	b = appendU32(b, 1) // package count
+	b = values.append(b)
+
+	b = appendChunkHeader(b, headerTablePackage, tablePackageHeaderSize, pkgSize)
+	b = appendU32(b, launcherIconID>>24)
+	name := utf16.Encode([]rune(pkg))
+	for i := 0; i < 128; i++ {
+		var c uint16
+		if i < len(name) && i < 127 { // NUL-terminated
+			c = name[i]
+		}
+		b = appendU16(b, c)
+	}
+	b = appendU32(b, tablePackageHeaderSize)                      // type strings
+	b = appendU32(b, 0)                                           // last public type
+	b = appendU32(b, uint32(tablePackageHeaderSize+types.size())) // key strings
+	b = appendU32(b, 0)                                           // last public key
+	b = appendU32(b, 0)                                           // type ID offset
+	b = types.append(b)
+	b = keys.append(b)
+
+	typeID := byte(launcherIconID >> 16 & 0xff)
+	b = appendChunkHeader(b, headerTableTypeSpec, 16, tableTypeSpecSize)
+	b = append(b, typeID, 0)
+	b = appendU16(b, 0)
+	b = appendU32(b, 1)      // entry count
+	b = appendU32(b, 0x0100) // ACONFIGURATION_DENSITY
+
+	for i, d := range iconDensities {
+		b = appendChunkHeader(b, headerTableType, tableTypeHeaderSize, tableTypeSize)
+		b = append(b, typeID, 0)
+		b = appendU16(b, 0)
+		b = appendU32(b, 1)                     // entry count
+		b = appendU32(b, tableTypeHeaderSize+4) // entries start
+		config := make([]byte, tableConfigSize)
+		copy(config, appendU32(nil, tableConfigSize))
+		copy(config[14:], appendU16(nil, uint16(d.dpi)))
+		b = append(b, config...)
+		b = appendU32(b, 0) // offset of the entry
+
+		b = appendU16(b, 8)         // entry size
+		b = appendU16(b, 0)         // flags
+		b = appendU32(b, 0)         // key, "ic_launcher"
+		b = appendU16(b, 8)         // value size
+		b = append(b, 0, 0x03)      // TYPE_STRING
+		b = appendU32(b, uint32(i)) // the path of the file
+	}
+	return b
+}
+
+// appendChunkHeader appends a ResChunk_header with a header of
+// headerSize bytes.
+func appendChunkHeader(b []byte, typ headerType, headerSize, size int) []byte {
+	b = appendU16(b, uint16(typ))
+	b = appendU16(b, uint16(headerSize))
+	return appendU32(b, uint32(size))
+}
@@ -0,0 +1,172 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeIconPNG writes a PNG image of the given size, filled with c, to a
+// file in dir and returns its path.
+func writeIconPNG(t *testing.T, dir string, w, h int, c color.Color) string {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "icon.png")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLauncherIcon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := color.NRGBA{0x20, 0x80, 0xf0, 0x80}
+	src, err := readIcon(writeIconPNG(t, dir, 500, 500, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	icon, err := launcherIcon(src, "org.golang.todo.hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(icon.pngs) != len(iconDensities) {
+		t.Fatalf("%d icons, want one per density, %d", len(icon.pngs), len(iconDensities))
+	}
+	for i, d := range iconDensities {
+		img, err := png.Decode(bytes.NewReader(icon.pngs[i]))
+		if err != nil {
+			t.Errorf("%s: %v", d.name, err)
+			continue
+		}
+		if b := img.Bounds(); b.Dx() != d.size || b.Dy() != d.size {
+			t.Errorf("%s: icon is %dx%d, want %dx%d", d.name, b.Dx(), b.Dy(), d.size, d.size)
+		}
+		if got := color.NRGBAModel.Convert(img.At(d.size/2, d.size/2)); got != want {
+			t.Errorf("%s: pixel is %v, want %v", d.name, got, want)
+		}
+	}
+
+	for _, size := range [][2]int{{100, 100}, {191, 191}, {300, 200}} {
+		path := writeIconPNG(t, dir, size[0], size[1], want)
+		if _, err := readIcon(path); err == nil {
+			t.Errorf("%dx%d icon: got nil error", size[0], size[1])
+		}
+	}
+}
+
+func TestIconTable(t *testing.T) {
+	table := iconTable("org.golang.todo.hello")
+	u16 := func(off int) int { return int(binary.LittleEndian.Uint16(table[off:])) }
+	u32 := func(off int) int { return int(binary.LittleEndian.Uint32(table[off:])) }
+
+	if typ, size := u16(0), u32(4); typ != headerTable || size != len(table) {
+		t.Fatalf("table header: type %#x, size %d, want %#x, %d", typ, size, headerTable, len(table))
+	}
+	// Walk the chunks of the package, after the global string pool.
+	pkg := u16(2) + u32(u16(2)+4)
+	if typ := u16(pkg); typ != headerTablePackage {
+		t.Fatalf("chunk at %d is %#x, want the package", pkg, typ)
+	}
+	if end := pkg + u32(pkg+4); end != len(table) {
+		t.Errorf("package ends at %d, want %d", end, len(table))
+	}
+	var densities []int
+	for off := pkg + u16(pkg+2); off < len(table); off += u32(off + 4) {
+		if u16(off) == headerTableType {
+			config := off + 20
+			densities = append(densities, u16(config+14))
+		}
+	}
+	if len(densities) != len(iconDensities) {
+		t.Fatalf("%d types, want one per density, %d", len(densities), len(iconDensities))
+	}
+	for i, d := range iconDensities {
+		if densities[i] != d.dpi {
+			t.Errorf("type %d: density %d, want %d", i, densities[i], d.dpi)
+		}
+	}
+}
+
+func TestIconManifest(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d := manifestTmplData{JavaPkgPath: "org.golang.todo.hello", Name: "Hello", LibName: "hello", MinSDK: 15, Icon: true}
+	if err := manifestTmpl.Execute(buf, d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `android:icon="@mipmap/ic_launcher"`) {
+		t.Errorf("manifest has no icon:\n%s", buf.String())
+	}
+	bin, err := binaryXML(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The icon is a reference to the resource, not a string.
+	ref := []byte{8, 0, 0, 0x01}
+	ref = append(ref, 0, 0, 0x01, 0x7f)
+	if !bytes.Contains(bin, ref) {
+		t.Error("binary manifest does not reference the launcher icon")
+	}
+
+	merged, _, err := mergeManifest([]byte(`<manifest xmlns:android="http://schemas.android.com/apk/res/android"><application android:label="Hello"/></manifest>`), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(merged, []byte(`<application android:icon="@mipmap/ic_launcher" android:label="Hello"`)) {
+		t.Errorf("merged manifest has no icon:\n%s", merged)
+	}
+	_, _, err = mergeManifest([]byte(`<manifest xmlns:android="http://schemas.android.com/apk/res/android"><application android:icon="@drawable/other"/></manifest>`), d)
+	if err == nil {
+		t.Error("manifest with another icon: got nil error")
+	}
+}
+
+func TestIconPacked(t *testing.T) {
+	defer func() {
+		xout = os.Stderr
+		buildN, buildPlan = false, false
+	}()
+	buf := new(bytes.Buffer)
+	xout = buf
+	buildN, buildPlan = true, true
+
+	icon := &appIcon{table: iconTable("org.golang.todo.hello")}
+	for range iconDensities {
+		icon.pngs = append(icon.pngs, nil)
+	}
+	if err := writeAPK("hello.apk", &apkContents{manifest: []byte("manifest"), icon: icon}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"# pack resources.arsc\n"}
+	for _, d := range iconDensities {
+		want = append(want, "# pack "+iconPath(d.name)+"\n")
+	}
+	for _, w := range want {
+		if !strings.Contains(buf.String(), w) {
+			t.Errorf("plan does not contain %q:\n%s", w, buf.String())
+		}
+	}
+}
@@ -11,15 +11,20 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 )
 
 // useStrippedNDK determines whether the init subcommand fetches the GCC
@@ -49,24 +54,115 @@ func init() {
 var cmdInit = &command{
 	run:   runInit,
 	Name:  "init",
-	Usage: "[-u]",
+	Usage: "[-u] [-json] [-ndk-url 'url list'] [-cache dir]",
 	Short: "install android compiler toolchain",
 	Long: `
 Init downloads and installs the Android C++ compiler toolchain.
 
-The toolchain is installed in $GOPATH/pkg/gomobile.
+The toolchain is installed in $GOPATH/pkg/gomobile, or in the directory
+named by the -cache flag or the GOMOBILE_CACHE environment variable.
+Inits sharing the directory install the toolchain one at a time.
 If the Android C++ compiler toolchain already exists in the path,
 it skips download and uses the existing toolchain.
 
-The -u option forces download and installation of the new toolchain
-even when the toolchain exists.
+If an NDK is installed on the system, as described by 'gomobile help
+build', init uses it instead of downloading one, and only builds the
+Go cross compiler and the android/arm standard library with it. An
+incompatible NDK is reported with the revisions required.
+
+The -u option updates an installed toolchain in place. Init compares the
+toolchain against the Go tool on the PATH and rebuilds only what is out
+of date: the cross compiler and the android/arm standard library after
+a Go update, without downloading the NDK again. A missing or partially
+downloaded NDK, or a toolchain left incomplete by an interrupted init,
+is installed again. Init prints what was updated, or that the toolchain
+is up to date. To install everything from scratch, run
+'gomobile clean -toolchain' first.
+
+Downloads are kept in $GOPATH/pkg/gomobile/dl until they complete, so an
+interrupted init resumes them where they stopped. A completed archive
+is checked against its known SHA-256 checksum before it is extracted;
+on a mismatch the download is discarded and init fails.
+
+The -ndk-url flag names a space-separated list of mirror URLs from
+which the toolchain archives are downloaded instead of the default
+Google servers. Each archive is fetched from the first mirror serving
+it, with the archive name appended to the mirror URL. The mirrors may
+also be set by the GOMOBILE_NDK_URL environment variable; the flag takes
+precedence. Downloads failing with a network error or a server error
+are retried a few times, waiting longer after each attempt, before the
+next mirror is tried. If every mirror fails, init reports the error of
+each URL. Proxies set by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+environment variables are used.
+
+Init prints each step to standard error, and with -v the download
+progress; -quiet prints nothing but errors. The -json flag prints them
+to standard output as a stream of JSON objects, one per line, for use
+by other programs:
+
+	type Event struct {
+		Step  string // "download", "verify", "extract", "build", "install" or "update"
+		Msg   string // description of the step
+		Bytes int64  // bytes downloaded so far, for "download"
+		Total int64  // size of the download, if known
+	}
 `,
 }
 
-var initU bool // -u
+var (
+	initU       bool     // -u
+	initJSON    bool     // -json
+	initNDKURLs []string // -ndk-url
+)
 
 func init() {
-	cmdInit.flag.BoolVar(&initU, "u", false, "force toolchain download")
+	cmdInit.flag.BoolVar(&initU, "u", false, "update the installed toolchain")
+	cmdInit.flag.BoolVar(&initJSON, "json", false, "print progress as JSON")
+	cmdInit.flag.Var((*stringsFlag)(&initNDKURLs), "ndk-url", "mirror URLs of the toolchain archives")
+}
+
+// fetchHashes are the SHA-256 checksums, in hex, of the archives
+// downloaded by init, keyed by file name. Archives without a checksum
+// are not verified.
+var fetchHashes = map[string]string{}
+
+// fetchDir is the directory of incomplete downloads.
+var fetchDir string
+
+// fetchRetries is the number of times a download failing with a
+// transient error is retried from the same URL, and fetchRetryDelay the
+// wait before the first retry, doubled for each of the next.
+var (
+	fetchRetries    = 3
+	fetchRetryDelay = 2 * time.Second
+	fetchSleep      = time.Sleep
+)
+
+// An initEvent is one step or progress report of init, printed unless
+// -quiet, or as JSON with -json.
+type initEvent struct {
+	Step  string
+	Msg   string `json:",omitempty"`
+	Bytes int64  `json:",omitempty"`
+	Total int64  `json:",omitempty"`
+}
+
+// initProgress reports e: as a JSON object on standard output with
+// -json, or as a line on standard error. The bytes downloaded are only
+// reported with -v.
+func initProgress(e initEvent) {
+	switch {
+	case initJSON:
+		json.NewEncoder(os.Stdout).Encode(e)
+	case e.Step == "download" && e.Bytes > 0:
+		if e.Total > 0 {
+			logf(levelVerbose, "%s: %d of %d bytes (%d%%)", e.Msg, e.Bytes, e.Total, e.Bytes*100/e.Total)
+		} else {
+			logf(levelVerbose, "%s: %d bytes", e.Msg, e.Bytes)
+		}
+	default:
+		logf(levelNormal, "%s", e.Msg)
+	}
 }
 
 func runInit(cmd *command) error {
@@ -75,21 +171,50 @@ func runInit(cmd *command) error {
 		return err
 	}
 
-	gopaths := filepath.SplitList(goEnv("GOPATH"))
-	if len(gopaths) == 0 {
+	dirs := gomobileDirs()
+	if len(dirs) == 0 {
 		return fmt.Errorf("GOPATH is not set")
 	}
-	ndkccpath = filepath.Join(gopaths[0], "pkg/gomobile/android-"+ndkVersion)
+	gomobilepath := dirs[0]
+	ndkccpath = filepath.Join(gomobilepath, "android-"+ndkVersion)
 	ndkccdl := filepath.Join(ndkccpath, "downloaded")
-	verpath := filepath.Join(gopaths[0], "pkg/gomobile/version")
+	verpath := filepath.Join(gomobilepath, "version")
+	fetchDir = filepath.Join(gomobilepath, "dl")
+	if initNDKURLs == nil {
+		initNDKURLs = strings.Fields(os.Getenv("GOMOBILE_NDK_URL"))
+	}
 	if buildX {
 		fmt.Fprintln(xout, "NDKCCPATH="+ndkccpath)
 	}
+	if err := findSystemNDK(); err != nil {
+		return err
+	}
+	if systemNDK != nil {
+		initProgress(initEvent{Step: "install", Msg: fmt.Sprintf("using NDK %s in %s", systemNDK.revision, systemNDK.root)})
+	}
+
+	// Inits sharing the gomobile directory, as CI jobs may, install
+	// the toolchain in turn.
+	if !buildN {
+		if err := os.MkdirAll(gomobilepath, 0755); err != nil {
+			return err
+		}
+		unlock, err := lockFile(filepath.Join(gomobilepath, "init.lock"))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
 
-	needNDK := initU
-	if !needNDK {
-		if _, err := os.Stat(ndkccdl); err != nil {
-			needNDK = true
+	goroot := goEnv("GOROOT")
+	state := checkToolchain(verpath, goroot)
+	needNDK := !state.ndk
+	if initU {
+		var needGo bool
+		needNDK, needGo = state.updates(version)
+		if !needGo {
+			initReport("android toolchain is up to date")
+			return nil
 		}
 	}
 
@@ -116,15 +241,16 @@ func runInit(cmd *command) error {
 	}
 	defer removeAll(tmpdir)
 
-	goroot := goEnv("GOROOT")
 	tmpGoroot := filepath.Join(tmpdir, "go")
 	if err := copyGoroot(tmpGoroot, goroot); err != nil {
 		return err
 	}
 
 	if needNDK {
-		if err := fetchNDK(); err != nil {
-			return err
+		if systemNDK == nil {
+			if err := fetchNDK(); err != nil {
+				return err
+			}
 		}
 		if err := fetchOpenAL(); err != nil {
 			return err
@@ -134,11 +260,20 @@ func runInit(cmd *command) error {
 				return err
 			}
 		}
+		if initU && systemNDK == nil {
+			initReport("installed android NDK " + ndkVersion)
+		}
 	}
 
 	dst := filepath.Join(ndkccpath, "arm")
 
 	ndkccbin := filepath.Join(dst, "bin")
+	if systemNDK != nil {
+		// The Go tools are installed without the NDK.
+		if err := mkdir(ndkccbin); err != nil {
+			return err
+		}
+	}
 	envpath := os.Getenv("PATH")
 	if buildN {
 		envpath = "$PATH"
@@ -165,8 +300,11 @@ func runInit(cmd *command) error {
 		`GOARCH=arm`,
 		`GOARM=7`,
 		`CGO_ENABLED=1`,
-		`CC_FOR_TARGET=` + filepath.Join(ndkccbin, bin("arm-linux-androideabi-gcc")),
-		`CXX_FOR_TARGET=` + filepath.Join(ndkccbin, bin("arm-linux-androideabi-g++")),
+		`CC_FOR_TARGET=` + filepath.Join(ndkBin(), bin("arm-linux-androideabi-gcc")),
+		`CXX_FOR_TARGET=` + filepath.Join(ndkBin(), bin("arm-linux-androideabi-g++")),
+	}
+	if flags := ndkCflags(); flags != nil {
+		make.Env = append(make.Env, `CGO_CFLAGS=`+strings.Join(flags, " "), `CGO_LDFLAGS=`+strings.Join(flags, " "))
 	}
 	if goos == "windows" {
 		make.Env = append(make.Env, `TEMP=`+tmpdir)
@@ -181,8 +319,8 @@ func runInit(cmd *command) error {
 	if v := goEnv("GOROOT_BOOTSTRAP"); v != "" {
 		make.Env = append(make.Env, `GOROOT_BOOTSTRAP=`+v)
 	}
-	if buildV {
-		fmt.Fprintf(os.Stderr, "building android/arm cross compiler\n")
+	initProgress(initEvent{Step: "build", Msg: "building android/arm cross compiler"})
+	if verbose() {
 		make.Stdout = os.Stdout
 		make.Stderr = os.Stderr
 	}
@@ -214,8 +352,8 @@ func runInit(cmd *command) error {
 		}
 	}
 	make = exec.Command("go", "build", "-o", filepath.Join(ndkccbin, bin("toolexec")), toolexecSrc)
-	if buildV {
-		fmt.Fprintf(os.Stderr, "building gomobile toolexec\n")
+	initProgress(initEvent{Step: "build", Msg: "building gomobile toolexec"})
+	if verbose() {
 		make.Stdout = os.Stdout
 		make.Stderr = os.Stderr
 	}
@@ -230,6 +368,7 @@ func runInit(cmd *command) error {
 
 	// Move pre-compiled stdlib for android into GOROOT. This is
 	// the only time we modify the user's GOROOT.
+	initProgress(initEvent{Step: "install", Msg: "installing android/arm standard library in " + goroot})
 	cannotRemove := false
 	if err := removeAll(filepath.Join(goroot, "pkg/android_arm")); err != nil {
 		cannotRemove = true
@@ -270,10 +409,62 @@ Make GOROOT writable (possibly by becoming the super user, using sudo) and run:
 			return err
 		}
 	}
+	if initU {
+		msg := fmt.Sprintf("built android/arm toolchain for %s", bytes.TrimSpace(version))
+		if old := bytes.TrimSpace(state.version); len(old) > 0 && !bytes.Equal(old, bytes.TrimSpace(version)) {
+			msg += fmt.Sprintf(" (was %s)", old)
+		}
+		initReport(msg)
+	}
 
 	return nil
 }
 
+// A toolchainState describes the toolchain installed by a previous init.
+type toolchainState struct {
+	ndk     bool   // the NDK, or only OpenAL with a system NDK, is completely downloaded
+	version []byte // the output of go version recorded by init, or nil
+	goTools bool   // the toolexec command and the android/arm standard library are installed
+}
+
+// checkToolchain returns the state of the toolchain whose version file
+// is verpath, with the standard library installed in goroot. The
+// toolchain is in the directory ndkccpath.
+func checkToolchain(verpath, goroot string) toolchainState {
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	bin := filepath.Join(ndkccpath, "arm", "bin")
+	gcc, toolexec := "arm-linux-androideabi-gcc", "toolexec"
+	if goos == "windows" {
+		gcc, toolexec = gcc+".exe", toolexec+".exe"
+	}
+	var s toolchainState
+	s.ndk = exists(filepath.Join(ndkccpath, "downloaded")) && (systemNDK != nil || exists(filepath.Join(bin, gcc)))
+	s.version, _ = ioutil.ReadFile(verpath)
+	s.goTools = exists(filepath.Join(bin, toolexec)) && exists(filepath.Join(goroot, "pkg", "android_arm"))
+	return s
+}
+
+// updates returns the parts of the toolchain in state s that init -u
+// installs again for the Go tool of the given version: the NDK, if it
+// is missing or incomplete, and the Go cross compiler and standard
+// library, if the NDK is installed again, if they are incomplete or if
+// they were built by another version of Go. The version file is
+// written last, so a previous init that was interrupted is rebuilt.
+func (s toolchainState) updates(version []byte) (ndk, gotools bool) {
+	ndk = !s.ndk
+	gotools = ndk || !s.goTools || !bytes.Equal(bytes.TrimSpace(s.version), bytes.TrimSpace(version))
+	return ndk, gotools
+}
+
+// initReport prints msg, a summary of an update by init -u, to standard
+// error unless -quiet, or as an "update" event with -json.
+func initReport(msg string) {
+	initProgress(initEvent{Step: "update", Msg: msg})
+}
+
 // toolexec is the source of a small program designed to be passed to
 // the -toolexec flag of go build.
 const toolexec = `package main
@@ -369,8 +560,7 @@ func goVersion() ([]byte, error) {
 
 func fetchOpenAL() error {
 	name := "gomobile-" + openALVersion + ".tar.gz"
-	url := "https://dl.google.com/go/mobile/" + name
-	if err := fetch(filepath.Join(tmpdir, name), url); err != nil {
+	if err := fetch(filepath.Join(tmpdir, name), fetchURLs("https://dl.google.com/go/mobile/", name)); err != nil {
 		return err
 	}
 	if err := extract(name, "openal"); err != nil {
@@ -393,6 +583,7 @@ func fetchOpenAL() error {
 }
 
 func extract(name, dst string) error {
+	initProgress(initEvent{Step: "extract", Msg: "extracting " + name})
 	if buildX {
 		printcmd("tar xfz %s", name)
 	}
@@ -473,6 +664,15 @@ func fetchNDK() error {
 		return err
 	}
 
+	// gdbserver is packed into the APKs built with -debug.
+	gdbserver := filepath.Join(dst, "gdbserver")
+	if err := mkdir(gdbserver); err != nil {
+		return err
+	}
+	if err := move(gdbserver, filepath.Join(tmpdir, "android-ndk-r10d/prebuilt/android-arm/gdbserver"), "gdbserver"); err != nil {
+		return err
+	}
+
 	linkpath := filepath.Join(dst, "arm-linux-androideabi/bin")
 	if err := mkdir(linkpath); err != nil {
 		return err
@@ -490,8 +690,7 @@ func fetchNDK() error {
 
 func fetchStrippedNDK() error {
 	name := "gomobile-ndk-r10d-" + goos + "-" + ndkarch + ".tar.gz"
-	url := "https://dl.google.com/go/mobile/" + name
-	if err := fetch(filepath.Join(tmpdir, name), url); err != nil {
+	if err := fetch(filepath.Join(tmpdir, name), fetchURLs("https://dl.google.com/go/mobile/", name)); err != nil {
 		return err
 	}
 	return extract(name, "")
@@ -505,8 +704,7 @@ func fetchFullNDK() error {
 		ndkName += "bin"
 	}
 	archive := filepath.Join(tmpdir, ndkName)
-	url := "https://dl.google.com/android/ndk/" + ndkName
-	if err := fetch(archive, url); err != nil {
+	if err := fetch(archive, fetchURLs("https://dl.google.com/android/ndk/", ndkName)); err != nil {
 		return err
 	}
 
@@ -529,13 +727,14 @@ func fetchFullNDK() error {
 		inflate = exec.Command("7z.exe", "x", archive)
 	}
 	inflate.Dir = tmpdir
+	initProgress(initEvent{Step: "extract", Msg: "extracting " + ndkName})
 	if buildX {
 		printcmd("%s", archive)
 	}
 	if !buildN {
 		out, err := inflate.CombinedOutput()
 		if err != nil {
-			if buildV {
+			if verbose() {
 				os.Stderr.Write(out)
 			}
 			return err
@@ -544,31 +743,160 @@ func fetchFullNDK() error {
 	return nil
 }
 
-func fetch(dst, url string) error {
-	if buildV {
-		fmt.Fprintf(os.Stderr, "fetching %s\n", url)
+// fetchURLs returns the URLs from which the archive name is fetched:
+// those of the -ndk-url mirrors, or that of the default server base.
+func fetchURLs(base, name string) []string {
+	if len(initNDKURLs) == 0 {
+		return []string{base + name}
 	}
+	var urls []string
+	for _, m := range initNDKURLs {
+		urls = append(urls, strings.TrimSuffix(m, "/")+"/"+name)
+	}
+	return urls
+}
+
+// fetch downloads the file to dst from the first of urls serving it.
+// The download is written to fetchDir until it completes, so a later
+// fetch of the same file resumes it. The completed file is verified
+// with its checksum in fetchHashes. If every URL fails, the error lists
+// the error of each.
+func fetch(dst string, urls []string) error {
+	name := path.Base(urls[0])
+	initProgress(initEvent{Step: "download", Msg: "fetching " + urls[0]})
 	if buildX {
-		printcmd("curl -o%s %s", dst, url)
+		printcmd("curl -o%s %s", dst, urls[0])
 	}
 	if buildN {
 		return nil
 	}
 
-	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0755)
+	if err := os.MkdirAll(fetchDir, 0755); err != nil {
+		return err
+	}
+	partial := filepath.Join(fetchDir, name+".partial")
+	msg := fmt.Sprintf("cannot fetch %s:", name)
+	for i, url := range urls {
+		if i > 0 {
+			initProgress(initEvent{Step: "download", Msg: "fetching " + url})
+		}
+		err := downloadRetry(partial, url)
+		if err == nil {
+			err = verify(partial, name)
+		}
+		if err == nil {
+			return os.Rename(partial, dst)
+		}
+		msg += fmt.Sprintf("\n\t%s: %v", url, err)
+	}
+	return errors.New(msg)
+}
+
+// downloadRetry downloads url to the file partial, retrying with
+// increasing delays while the download fails with a transient error.
+// Each retry resumes the download.
+func downloadRetry(partial, url string) error {
+	delay := fetchRetryDelay
+	for i := 0; ; i++ {
+		err := download(partial, url)
+		if err == nil || i == fetchRetries || !isTransient(err) {
+			return err
+		}
+		initProgress(initEvent{Step: "download", Msg: fmt.Sprintf("fetching %s: %v; retrying in %v", url, err, delay)})
+		fetchSleep(delay)
+		delay *= 2
+	}
+}
+
+// verify checks the downloaded file partial against the checksum of
+// the archive name in fetchHashes, if any, and removes it on a mismatch.
+func verify(partial, name string) error {
+	want, ok := fetchHashes[name]
+	if !ok {
+		return nil
+	}
+	initProgress(initEvent{Step: "verify", Msg: "verifying " + name})
+	got, err := fileHash(partial)
 	if err != nil {
 		return err
 	}
+	if got != want {
+		os.Remove(partial)
+		return fmt.Errorf("checksum mismatch: SHA-256 %s, want %s; the download was removed, run 'gomobile init' again", got, want)
+	}
+	return nil
+}
+
+// A statusError is the unexpected HTTP status of a download.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string { return "server returned status " + e.status }
+
+// isTransient reports whether the download error err may go away when
+// the download is retried: a network error or a server error. Errors
+// writing the download and other HTTP statuses are not transient.
+func isTransient(err error) bool {
+	switch err := err.(type) {
+	case *statusError:
+		return err.code >= 500 || err.code == http.StatusRequestTimeout || err.code == 429
+	case *os.PathError:
+		return false
+	}
+	return true
+}
 
-	resp, err := http.Get(url)
+// download downloads url to the file partial. If partial already
+// holds the beginning of the file, only the rest is downloaded.
+func download(partial, url string) error {
+	f, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0755)
 	if err != nil {
 		return err
 	}
+	offset, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is complete.
+		resp.Body.Close()
+		return f.Close()
+	case resp.StatusCode == http.StatusOK:
+		// The server sends the whole file.
+		offset = 0
+		if err = f.Truncate(0); err == nil {
+			_, err = f.Seek(0, os.SEEK_SET)
+		}
+	default:
+		err = &statusError{code: resp.StatusCode, status: resp.Status}
+	}
 	var err2 error
-	if resp.StatusCode != http.StatusOK {
-		err = fmt.Errorf("error fetching %v, status: %v", url, resp.Status)
-	} else {
-		_, err2 = io.Copy(f, resp.Body)
+	if err == nil {
+		pw := &progressWriter{w: f, msg: "fetching " + url, n: offset}
+		if resp.ContentLength >= 0 {
+			pw.total = offset + resp.ContentLength
+		}
+		_, err2 = io.Copy(pw, resp.Body)
+		pw.report()
 	}
 	err3 := resp.Body.Close()
 	err4 := f.Close()
@@ -584,6 +912,42 @@ func fetch(dst, url string) error {
 	return err4
 }
 
+// progressWriter reports the progress of a download as it is written.
+type progressWriter struct {
+	w        io.Writer
+	msg      string
+	n, total int64
+	reported int64 // n at the last report
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	if p.n-p.reported >= 1<<20 {
+		p.report()
+	}
+	return n, err
+}
+
+func (p *progressWriter) report() {
+	p.reported = p.n
+	initProgress(initEvent{Step: "download", Msg: p.msg, Bytes: p.n, Total: p.total})
+}
+
+// fileHash returns the SHA-256 checksum of the named file, in hex.
+func fileHash(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
 // copyGoroot copies GOROOT from src to dst.
 //
 // It skips the pkg directory, which is not necessary for make.bash,
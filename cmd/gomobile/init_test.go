@@ -6,11 +6,17 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"text/template"
+	"time"
 )
 
 func TestInit(t *testing.T) {
@@ -47,6 +53,125 @@ func TestInit(t *testing.T) {
 	}
 }
 
+func TestFetch(t *testing.T) {
+	archive := bytes.Repeat([]byte("fake archive "), 1000)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "fake.tar.gz", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { fetchDir = d }(fetchDir)
+	fetchDir = filepath.Join(dir, "dl")
+	defer delete(fetchHashes, "fake.tar.gz")
+	partial := filepath.Join(fetchDir, "fake.tar.gz.partial")
+	url := server.URL + "/fake.tar.gz"
+
+	// A wrong checksum fails and removes the download.
+	fetchHashes["fake.tar.gz"] = fmt.Sprintf("%x", sha256.Sum256([]byte("another archive")))
+	dst := filepath.Join(dir, "fake.tar.gz")
+	err = fetch(dst, []string{url})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("fetch with wrong checksum: got error %v, want checksum mismatch", err)
+	}
+	for _, name := range []string{dst, partial} {
+		if _, err := os.Stat(name); err == nil {
+			t.Errorf("fetch with wrong checksum left %s", name)
+		}
+	}
+
+	// An interrupted download is resumed.
+	fetchHashes["fake.tar.gz"] = fmt.Sprintf("%x", sha256.Sum256(archive))
+	if err := os.MkdirAll(fetchDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(partial, archive[:5000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch(dst, []string{url}); err != nil {
+		t.Fatalf("fetch resuming a partial download: %v", err)
+	}
+	got, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, archive) {
+		t.Errorf("resumed download has %d bytes, want %d", len(got), len(archive))
+	}
+	if want := []string{"", "bytes=5000-"}; fmt.Sprint(ranges) != fmt.Sprint(want) {
+		t.Errorf("requested ranges %q, want %q", ranges, want)
+	}
+}
+
+func TestFetchRetry(t *testing.T) {
+	archive := []byte("fake archive")
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/missing/fake.tar.gz":
+			http.NotFound(w, r)
+		case failures > 0:
+			failures--
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+		default:
+			http.ServeContent(w, r, "fake.tar.gz", time.Time{}, bytes.NewReader(archive))
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { fetchDir = d }(fetchDir)
+	fetchDir = filepath.Join(dir, "dl")
+	var delays []time.Duration
+	fetchSleep = func(d time.Duration) { delays = append(delays, d) }
+	defer func() { fetchSleep = time.Sleep }()
+	defer func(urls []string) { initNDKURLs = urls }(initNDKURLs)
+
+	// The missing mirror is skipped without retrying, and the failing
+	// one retried with increasing delays until it succeeds.
+	initNDKURLs = []string{server.URL + "/missing/", server.URL + "/mirror"}
+	urls := fetchURLs("https://dl.google.com/go/mobile/", "fake.tar.gz")
+	if want := []string{server.URL + "/missing/fake.tar.gz", server.URL + "/mirror/fake.tar.gz"}; fmt.Sprint(urls) != fmt.Sprint(want) {
+		t.Fatalf("fetchURLs = %q, want %q", urls, want)
+	}
+	dst := filepath.Join(dir, "fake.tar.gz")
+	if err := fetch(dst, urls); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadFile(dst); err != nil || !bytes.Equal(got, archive) {
+		t.Errorf("fetched %q, %v; want %q", got, err, archive)
+	}
+	if want := []time.Duration{fetchRetryDelay, 2 * fetchRetryDelay}; fmt.Sprint(delays) != fmt.Sprint(want) {
+		t.Errorf("retry delays %v, want %v", delays, want)
+	}
+
+	// When every mirror fails, the error of each is reported.
+	failures = fetchRetries + 1
+	delays = nil
+	err = fetch(dst, []string{server.URL + "/missing/fake.tar.gz", server.URL + "/mirror/fake.tar.gz"})
+	if err == nil {
+		t.Fatal("fetch from failing mirrors: got nil error")
+	}
+	for _, want := range []string{server.URL + "/missing/fake.tar.gz: server returned status 404", server.URL + "/mirror/fake.tar.gz: server returned status 503"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("fetch error does not contain %q:\n%v", want, err)
+		}
+	}
+	if len(delays) != fetchRetries {
+		t.Errorf("retried %d times, want %d", len(delays), fetchRetries)
+	}
+}
+
 func diffOutput(got string, wantTmpl *template.Template) (string, error) {
 	got = filepath.ToSlash(got)
 
@@ -102,6 +227,8 @@ mv $WORK/android-{{.NDK}}/platforms/android-15/arch-arm/usr/lib $NDKCCPATH/arm/s
 mv $WORK/android-{{.NDK}}/toolchains/arm-linux-androideabi-4.8/prebuilt/{{.GOOS}}-{{.NDKARCH}}/bin $NDKCCPATH/arm/bin
 mv $WORK/android-{{.NDK}}/toolchains/arm-linux-androideabi-4.8/prebuilt/{{.GOOS}}-{{.NDKARCH}}/lib $NDKCCPATH/arm/lib
 mv $WORK/android-{{.NDK}}/toolchains/arm-linux-androideabi-4.8/prebuilt/{{.GOOS}}-{{.NDKARCH}}/libexec $NDKCCPATH/arm/libexec
+mkdir -p $NDKCCPATH/arm/gdbserver
+mv $WORK/android-{{.NDK}}/prebuilt/android-arm/gdbserver/gdbserver $NDKCCPATH/arm/gdbserver/gdbserver
 mkdir -p $NDKCCPATH/arm/arm-linux-androideabi/bin
 ln -s $NDKCCPATH/arm/bin/arm-linux-androideabi-ld{{.EXE}} $NDKCCPATH/arm/arm-linux-androideabi/bin/ld{{.EXE}}
 ln -s $NDKCCPATH/arm/bin/arm-linux-androideabi-as{{.EXE}} $NDKCCPATH/arm/arm-linux-androideabi/bin/as{{.EXE}}
@@ -127,3 +254,75 @@ mv $WORK/go/pkg/android_arm $GOROOT/pkg/android_arm
 go version > GOPATH1/pkg/gomobile/version
 rm -r -f "$WORK"
 `))
+
+func TestToolchainUpdates(t *testing.T) {
+	v15 := []byte("go version go1.5 linux/amd64\n")
+	v14 := []byte("go version go1.4 linux/amd64\n")
+	tests := []struct {
+		desc        string
+		state       toolchainState
+		ndk, gotool bool
+	}{
+		{"up to date", toolchainState{ndk: true, version: v15, goTools: true}, false, false},
+		{"trailing space", toolchainState{ndk: true, version: bytes.TrimSpace(v15), goTools: true}, false, false},
+		{"new Go version", toolchainState{ndk: true, version: v14, goTools: true}, false, true},
+		{"interrupted build", toolchainState{ndk: true, version: nil, goTools: true}, false, true},
+		{"missing standard library", toolchainState{ndk: true, version: v15, goTools: false}, false, true},
+		{"partial NDK", toolchainState{ndk: false, version: v15, goTools: true}, true, true},
+		{"nothing installed", toolchainState{}, true, true},
+	}
+	for _, tt := range tests {
+		ndk, gotools := tt.state.updates(v15)
+		if ndk != tt.ndk || gotools != tt.gotool {
+			t.Errorf("%s: updates=%v, %v, want %v, %v", tt.desc, ndk, gotools, tt.ndk, tt.gotool)
+		}
+	}
+}
+
+func TestCheckToolchain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(p string) { ndkccpath = p }(ndkccpath)
+	ndkccpath = filepath.Join(dir, "pkg/gomobile/android-"+ndkVersion)
+	verpath := filepath.Join(dir, "pkg/gomobile/version")
+	goroot := filepath.Join(dir, "goroot")
+	bin := filepath.Join(ndkccpath, "arm", "bin")
+	exe := ""
+	if goos == "windows" {
+		exe = ".exe"
+	}
+	touch := func(path string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An interrupted download leaves no marker.
+	touch(filepath.Join(bin, "arm-linux-androideabi-gcc"+exe))
+	if s := checkToolchain(verpath, goroot); s.ndk || s.goTools || s.version != nil {
+		t.Errorf("partial toolchain: %+v", s)
+	}
+
+	touch(filepath.Join(ndkccpath, "downloaded"))
+	touch(filepath.Join(bin, "toolexec"+exe))
+	if err := os.MkdirAll(filepath.Join(goroot, "pkg", "android_arm"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	version := []byte("go version go1.5 linux/amd64\n")
+	if err := ioutil.WriteFile(verpath, version, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := checkToolchain(verpath, goroot)
+	if !s.ndk || !s.goTools || !bytes.Equal(s.version, version) {
+		t.Errorf("installed toolchain: %+v", s)
+	}
+	if ndk, gotools := s.updates(version); ndk || gotools {
+		t.Errorf("installed toolchain is not up to date: ndk=%v gotools=%v", ndk, gotools)
+	}
+}
@@ -5,39 +5,365 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"strings"
+	"time"
 )
 
 var cmdInstall = &command{
 	run:   runInstall,
 	Name:  "install",
-	Usage: "[package]",
+	Usage: "[-device serial|all] [-arch arm] [-r] [-d] [-timeout d] [-androidmanifest file] [-androidtarget phone|tv|wear] [-appid id] [-assets dirs] [-icon file] [build flags] [package]",
 	Short: "compile android APK and iOS app and install on device",
 	Long: `
 Install compiles and installs the app named by the import path on the
 attached mobile device.
 
+If more than one device or emulator is attached, the -device flag
+selects one by its adb serial number, as listed by 'adb devices'.
+With -device=all, the app is installed on every attached device in
+turn, and the result for each device is reported at the end: on
+standard output, unless -quiet, or on standard error if any failed.
+
+Before the build, install asks each device for its primary ABI with
+'adb shell getprop ro.product.cpu.abi' and checks that the app can run
+there. gomobile compiles Android apps for arm only, which arm64 devices
+run too. The -arch flag names the architecture to compile for and, as
+only arm is supported for now, accepts only -arch=arm. It skips the
+ABI check, to install on a device that misreports its ABI.
+
+The -r flag replaces an app that is already installed, keeping its
+data. The -d flag allows installing an app with a lower version code
+than the installed one. An app signed with a different key cannot be
+replaced; uninstall it first with 'adb uninstall'.
+
+The -timeout flag limits the time each adb command may run, 5m by
+default, or 0 for no limit. An adb command running longer, such as an
+install hung on a flaky USB connection, is killed and reported. An
+install killed this way may leave the app partially installed.
+
 This command requires the 'adb' tool on the PATH.
 
 See the build command help for common flags and common behavior.
 `,
 }
 
+var (
+	installDevice    string // -device
+	installArch      string // -arch
+	installReinstall bool   // -r
+	installDowngrade bool   // -d
+
+	adbTimeout time.Duration // -timeout
+)
+
+func init() {
+	cmdInstall.flag.StringVar(&installDevice, "device", "", "serial number of the device, or all")
+	cmdInstall.flag.StringVar(&installArch, "arch", "", "architecture to compile for, only arm; skips the device ABI check")
+	addInstallFlags(cmdInstall)
+}
+
+// addInstallFlags registers the flags passed on to adb install, and the
+// time limit of the adb commands.
+func addInstallFlags(cmd *command) {
+	cmd.flag.BoolVar(&installReinstall, "r", false, "replace the installed app, keeping its data")
+	cmd.flag.BoolVar(&installDowngrade, "d", false, "allow a lower version code than the installed app")
+	cmd.flag.DurationVar(&adbTimeout, "timeout", 5*time.Minute, "time limit of each adb command, or 0 for none")
+}
+
+// installArgs returns the arguments of the adb command installing apk.
+func installArgs(apk string) []string {
+	args := []string{"install"}
+	if installReinstall {
+		args = append(args, "-r")
+	}
+	if installDowngrade {
+		args = append(args, "-d")
+	}
+	return append(args, apk)
+}
+
 func runInstall(cmd *command) error {
+	// -arch only selects what gomobile builds anyway, so it is checked
+	// and then stands in for the device ABI check.
+	if installArch != "" {
+		if _, err := parseArchs(installArch); err != nil {
+			return err
+		}
+	}
+
+	// Select the devices before the build, to check their ABI.
+	var serials []string
+	if !buildN {
+		devices, err := adbDevices()
+		if err != nil {
+			return err
+		}
+		serials, err = selectDevices(devices, installDevice)
+		if err != nil {
+			return err
+		}
+		if installArch == "" {
+			for _, serial := range serials {
+				if err := checkDeviceArch(serial); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	if err := runBuild(cmd); err != nil {
 		return err
 	}
-	install := exec.Command(
-		`adb`,
-		`install`,
-		`-r`,
-		filepath.Base(pkg.Dir)+`.apk`,
-	)
-	if buildV {
-		install.Stdout = os.Stdout
-		install.Stderr = os.Stderr
-	}
-	return install.Run()
+
+	if buildN {
+		// Without adb, install on the named device, if any.
+		serial := installDevice
+		if serial == "all" {
+			serial = ""
+		}
+		_, err := adbDevice(serial, installArgs(*buildO)...)
+		return err
+	}
+
+	if installDevice != "all" {
+		return installOn(serials[0])
+	}
+
+	var failed []string
+	results := new(bytes.Buffer)
+	for _, serial := range serials {
+		if err := installOn(serial); err != nil {
+			failed = append(failed, serial)
+			fmt.Fprintf(results, "%s: FAIL: %v\n", serial, err)
+		} else {
+			fmt.Fprintf(results, "%s: ok\n", serial)
+		}
+	}
+	if len(failed) > 0 {
+		// Failures are errors, reported even with -quiet.
+		fmt.Fprint(os.Stderr, results.String())
+		return fmt.Errorf("install failed on %d of %d devices", len(failed), len(serials))
+	}
+	resultf("%s", strings.TrimSuffix(results.String(), "\n"))
+	return nil
+}
+
+// installOn installs the built APK on the device with the given serial.
+func installOn(serial string) error {
+	out, err := adbDevice(serial, installArgs(*buildO)...)
+	if _, ok := err.(*adbTimeoutError); ok {
+		return fmt.Errorf("%v\nthe app may be partially installed; install it again with -r, or uninstall it with 'adb uninstall %s'", err, appPkgPath)
+	}
+	if err == nil {
+		// Older versions of adb do not report failure in their exit code.
+		if i := bytes.Index(out, []byte("Failure")); i >= 0 {
+			err = fmt.Errorf("adb install failed: %s", bytes.TrimSpace(out[i:]))
+		}
+	}
+	if err != nil {
+		if hint := installHint(out); hint != "" {
+			return fmt.Errorf("%v\n%s", err, hint)
+		}
+	}
+	return err
+}
+
+// installHint returns advice for the adb install failure reported in
+// out, or "" if there is none.
+func installHint(out []byte) string {
+	switch {
+	case bytes.Contains(out, []byte("INSTALL_FAILED_UPDATE_INCOMPATIBLE")),
+		bytes.Contains(out, []byte("INSTALL_PARSE_FAILED_INCONSISTENT_CERTIFICATES")):
+		return "the installed app is signed with a different key; uninstall it with 'adb uninstall " + appPkgPath + "' first"
+	case bytes.Contains(out, []byte("INSTALL_FAILED_ALREADY_EXISTS")) && !installReinstall:
+		return "the app is already installed; use -r to replace it, or uninstall it with 'adb uninstall " + appPkgPath + "'"
+	case bytes.Contains(out, []byte("INSTALL_FAILED_VERSION_DOWNGRADE")) && !installDowngrade:
+		return "the installed app has a higher version code; use -d to allow a downgrade"
+	}
+	return ""
+}
+
+// buildArchs lists the architectures gomobile compiles Android apps for.
+var buildArchs = []string{"arm"}
+
+// parseArchs returns the architectures in the comma-separated list of
+// the -arch flag, after checking that gomobile can compile for them. As
+// gomobile builds for arm only, the list can only name arm.
+func parseArchs(list string) ([]string, error) {
+	var archs []string
+	seen := make(map[string]bool)
+	for _, arch := range strings.Split(list, ",") {
+		arch = strings.TrimSpace(arch)
+		if arch == "" || seen[arch] {
+			continue
+		}
+		seen[arch] = true
+		if _, ok := androidABIs[arch]; !ok {
+			return nil, fmt.Errorf("unknown -arch %q, want a GOARCH such as arm or arm64", arch)
+		}
+		if !buildableArch(arch) {
+			return nil, fmt.Errorf("-arch %s is not supported, gomobile builds for %s", arch, strings.Join(buildArchs, ", "))
+		}
+		archs = append(archs, arch)
+	}
+	if len(archs) == 0 {
+		return nil, errors.New("-arch lists no architecture")
+	}
+	return archs, nil
+}
+
+func buildableArch(arch string) bool {
+	for _, a := range buildArchs {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDeviceABI returns the GOARCH of the primary ABI reported by
+// 'adb shell getprop ro.product.cpu.abi'.
+func parseDeviceABI(out []byte) (string, error) {
+	abi := strings.TrimSpace(string(out))
+	if abi == "" {
+		return "", errors.New("device reports no ABI")
+	}
+	if abi == "armeabi" {
+		return "arm", nil
+	}
+	for arch, name := range androidABIs {
+		if name == abi {
+			return arch, nil
+		}
+	}
+	return "", fmt.Errorf("unknown device ABI %q", abi)
+}
+
+// deviceBuildArch returns the architecture to compile for to run on a
+// device of the given GOARCH, or "" if there is none.
+func deviceBuildArch(arch string) string {
+	switch {
+	case buildableArch(arch):
+		return arch
+	case arch == "arm64" && buildableArch("arm"):
+		return "arm" // arm64 devices run 32-bit arm code
+	}
+	return ""
+}
+
+// checkDeviceArch checks that the device with the given serial can run
+// the app, using its primary ABI. Devices that do not report one are
+// not checked.
+func checkDeviceArch(serial string) error {
+	c, done := adbCommand(adbTimeout, `-s`, serial, `shell`, `getprop`, `ro.product.cpu.abi`)
+	out, err := c.Output()
+	if err := done(err); err != nil {
+		if _, ok := err.(*adbTimeoutError); ok {
+			return err
+		}
+		return nil
+	}
+	arch, err := parseDeviceABI(out)
+	if err != nil {
+		logf(levelVerbose, "%s: %v", serial, err)
+		return nil
+	}
+	build := deviceBuildArch(arch)
+	if build == "" {
+		return fmt.Errorf("android device %s has ABI %s, gomobile builds for %s", serial, androidABIs[arch], strings.Join(buildArchs, ", "))
+	}
+	logf(levelVerbose, "%s: ABI %s, compiling for %s", serial, androidABIs[arch], build)
+	return nil
+}
+
+// An androidDevice is a device listed by 'adb devices'.
+type androidDevice struct {
+	serial string
+	state  string // "device" when ready, or e.g. "offline", "unauthorized"
+}
+
+// adbDevices returns the attached devices.
+func adbDevices() ([]androidDevice, error) {
+	c, done := adbCommand(adbTimeout, `devices`)
+	out, err := c.Output()
+	if err := done(err); err != nil {
+		if _, ok := err.(*adbTimeoutError); ok {
+			return nil, err
+		}
+		if _, lerr := exec.LookPath("adb"); lerr != nil {
+			return nil, errors.New("this command requires the 'adb' tool on the PATH")
+		}
+		return nil, fmt.Errorf("adb devices failed: %v", err)
+	}
+	return parseDevices(out), nil
+}
+
+// parseDevices parses the output of 'adb devices'.
+func parseDevices(out []byte) []androidDevice {
+	var devices []androidDevice
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) != 2 || strings.HasPrefix(f[0], "*") {
+			continue // header, daemon messages
+		}
+		devices = append(devices, androidDevice{serial: f[0], state: f[1]})
+	}
+	return devices
+}
+
+// selectDevices returns the serial numbers of the devices to install
+// on: the device named by serial, all ready devices if serial is "all",
+// or the only attached device if serial is empty.
+func selectDevices(devices []androidDevice, serial string) ([]string, error) {
+	if len(devices) == 0 {
+		return nil, errors.New("no android device attached, connect a device or start an emulator")
+	}
+	ready := func(d androidDevice) error {
+		if d.state != "device" {
+			return fmt.Errorf("android device %s is not ready (state %q)", d.serial, d.state)
+		}
+		return nil
+	}
+	switch serial {
+	case "":
+		if len(devices) > 1 {
+			buf := new(bytes.Buffer)
+			for _, d := range devices {
+				fmt.Fprintf(buf, "\n\t%s\t%s", d.serial, d.state)
+			}
+			return nil, fmt.Errorf("more than one android device attached, select one with -device serial or use -device=all:%s", buf)
+		}
+		if err := ready(devices[0]); err != nil {
+			return nil, err
+		}
+		return []string{devices[0].serial}, nil
+	case "all":
+		var serials []string
+		for _, d := range devices {
+			if ready(d) == nil {
+				serials = append(serials, d.serial)
+			}
+		}
+		if len(serials) == 0 {
+			return nil, errors.New("no android device is ready")
+		}
+		return serials, nil
+	default:
+		for _, d := range devices {
+			if d.serial == serial {
+				if err := ready(d); err != nil {
+					return nil, err
+				}
+				return []string{serial}, nil
+			}
+		}
+		return nil, fmt.Errorf("android device %s is not attached", serial)
+	}
 }
@@ -0,0 +1,229 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const devicesOutput = `* daemon not running. starting it now on port 5037 *
+* daemon started successfully *
+List of devices attached
+0123456789ABCDEF	device
+emulator-5554	device
+HT4CTJT00001	unauthorized
+
+`
+
+func TestParseDevices(t *testing.T) {
+	want := []androidDevice{
+		{"0123456789ABCDEF", "device"},
+		{"emulator-5554", "device"},
+		{"HT4CTJT00001", "unauthorized"},
+	}
+	if got := parseDevices([]byte(devicesOutput)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDevices=%v, want %v", got, want)
+	}
+}
+
+func TestSelectDevices(t *testing.T) {
+	devices := parseDevices([]byte(devicesOutput))
+	one := devices[:1]
+	tests := []struct {
+		devices []androidDevice
+		serial  string
+		want    []string
+		err     string
+	}{
+		{one, "", []string{"0123456789ABCDEF"}, ""},
+		{nil, "", nil, "no android device attached"},
+		{devices, "", nil, "more than one android device attached"},
+		{devices, "emulator-5554", []string{"emulator-5554"}, ""},
+		{devices, "HT4CTJT00001", nil, `not ready (state "unauthorized")`},
+		{devices, "missing", nil, "android device missing is not attached"},
+		{devices, "all", []string{"0123456789ABCDEF", "emulator-5554"}, ""},
+		{devices[2:], "all", nil, "no android device is ready"},
+	}
+	for _, tt := range tests {
+		got, err := selectDevices(tt.devices, tt.serial)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("selectDevices(%v, %q) error %v, want %q", tt.devices, tt.serial, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("selectDevices(%v, %q): %v", tt.devices, tt.serial, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("selectDevices(%v, %q)=%q, want %q", tt.devices, tt.serial, got, tt.want)
+		}
+	}
+
+	// The error for several devices lists them.
+	_, err := selectDevices(devices, "")
+	for _, d := range devices {
+		if !strings.Contains(err.Error(), d.serial) {
+			t.Errorf("error does not list %s: %v", d.serial, err)
+		}
+	}
+}
+
+func TestInstallArgs(t *testing.T) {
+	defer func() {
+		installReinstall = false
+		installDowngrade = false
+	}()
+	tests := []struct {
+		reinstall, downgrade bool
+		want                 []string
+	}{
+		{false, false, []string{"install", "app.apk"}},
+		{true, false, []string{"install", "-r", "app.apk"}},
+		{true, true, []string{"install", "-r", "-d", "app.apk"}},
+		{false, true, []string{"install", "-d", "app.apk"}},
+	}
+	for _, tt := range tests {
+		installReinstall = tt.reinstall
+		installDowngrade = tt.downgrade
+		if got := installArgs("app.apk"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("installArgs with -r=%v -d=%v: %q, want %q", tt.reinstall, tt.downgrade, got, tt.want)
+		}
+	}
+}
+
+func TestInstallHint(t *testing.T) {
+	defer func() { installReinstall = false }()
+	tests := []struct {
+		out       string
+		reinstall bool
+		want      string
+	}{
+		{"Failure [INSTALL_FAILED_ALREADY_EXISTS]", false, "use -r"},
+		{"Failure [INSTALL_FAILED_ALREADY_EXISTS]", true, ""},
+		{"Failure [INSTALL_FAILED_UPDATE_INCOMPATIBLE: Package org.golang.todo.basic signatures do not match]", true, "adb uninstall"},
+		{"Failure [INSTALL_FAILED_VERSION_DOWNGRADE]", false, "use -d"},
+		{"Failure [INSTALL_FAILED_INSUFFICIENT_STORAGE]", false, ""},
+	}
+	for _, tt := range tests {
+		installReinstall = tt.reinstall
+		got := installHint([]byte(tt.out))
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("installHint(%q) with -r=%v: %q, want %q", tt.out, tt.reinstall, got, tt.want)
+		}
+	}
+}
+
+func TestParseDeviceABI(t *testing.T) {
+	tests := []struct {
+		out   string
+		arch  string
+		build string
+		err   string
+	}{
+		{"armeabi-v7a\r\n", "arm", "arm", ""},
+		{"armeabi\n", "arm", "arm", ""},
+		{"arm64-v8a\r\n", "arm64", "arm", ""},
+		{"x86\n", "386", "", ""},
+		{"x86_64\n", "amd64", "", ""},
+		{"\r\n", "", "", "no ABI"},
+		{"mips\n", "", "", `unknown device ABI "mips"`},
+	}
+	for _, tt := range tests {
+		arch, err := parseDeviceABI([]byte(tt.out))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseDeviceABI(%q) error %v, want %q", tt.out, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDeviceABI(%q): %v", tt.out, err)
+			continue
+		}
+		if arch != tt.arch {
+			t.Errorf("parseDeviceABI(%q)=%q, want %q", tt.out, arch, tt.arch)
+		}
+		if build := deviceBuildArch(arch); build != tt.build {
+			t.Errorf("deviceBuildArch(%q)=%q, want %q", arch, build, tt.build)
+		}
+	}
+}
+
+func TestParseArchs(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+		err  string
+	}{
+		{"arm", []string{"arm"}, ""},
+		{"arm, arm", []string{"arm"}, ""},
+		{"arm64", nil, "-arch arm64 is not supported"},
+		{"arm,mips", nil, `unknown -arch "mips"`},
+		{",", nil, "no architecture"},
+	}
+	for _, tt := range tests {
+		got, err := parseArchs(tt.list)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseArchs(%q) error %v, want %q", tt.list, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseArchs(%q)=%q, %v, want %q", tt.list, got, err, tt.want)
+		}
+	}
+}
+
+func TestInstallTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake adb is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// An adb hanging on a flaky connection.
+	if err := ioutil.WriteFile(filepath.Join(dir, "adb"), []byte("#!/bin/sh\nsleep 60\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	defer func(d time.Duration) { adbTimeout = d }(adbTimeout)
+	adbTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err = installOn("emulator-5554")
+	if d := time.Since(start); d > 30*time.Second {
+		t.Errorf("installOn returned after %v, adb was not killed", d)
+	}
+	if err == nil {
+		t.Fatal("installOn succeeded, want a timeout")
+	}
+	for _, want := range []string{"adb -s emulator-5554 install", "timed out after 100ms", "partially installed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("installOn error %q, want %q", err, want)
+		}
+	}
+
+	// Commands that finish in time are not affected.
+	if err := ioutil.WriteFile(filepath.Join(dir, "adb"), []byte("#!/bin/sh\necho Success\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := installOn("emulator-5554"); err != nil {
+		t.Errorf("installOn: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// supportSources are the Java support classes of the bindings, relative
+// to the golang.org/x/mobile directory. They are the same for every
+// bound package, so bind compiles them once and keeps the classes in the
+// build cache, once per Java package they are relocated to by
+// -supportpkg.
+var supportSources = []string{"app/Go.java", "bind/java/Seq.java"}
+
+// supportClasses returns the directory holding the compiled support
+// classes, compiling them if they are not in the build cache. The cache
+// entry is keyed by the gomobile version, the sources and the javac
+// settings, and it records a checksum of the classes: an entry that
+// does not match its checksum is compiled again. The -a flag forces the
+// classes to be compiled.
+func supportClasses(repo, apiPath string) (string, error) {
+	gomobilepath := gomobileDir()
+	if gomobilepath == "" {
+		return "", errors.New("android toolchain not installed, run:\n\tgomobile init")
+	}
+	key, err := supportCacheKey(repo, apiPath)
+	if err != nil {
+		return "", err
+	}
+	entry := filepath.Join(gomobilepath, "cache", "java-"+key)
+	classes := filepath.Join(entry, "classes")
+	if !buildA && !buildN {
+		ok, err := checkClassesSum(entry)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			logf(levelVerbose, "using cached %s", classes)
+			return classes, nil
+		}
+	}
+
+	// Compile into the work directory and move the classes into place,
+	// so an interrupted build leaves no partial cache entry.
+	dst := filepath.Join(tmpdir, "support-classes")
+	if !buildN {
+		if err := os.MkdirAll(dst, 0700); err != nil {
+			return "", err
+		}
+	}
+	args := []string{
+		"-d", dst,
+		"-source", javacTargetVer,
+		"-target", javacTargetVer,
+		"-bootclasspath", filepath.Join(apiPath, "android.jar"),
+	}
+	for _, src := range supportSources {
+		path := filepath.Join(repo, filepath.FromSlash(src))
+		if bindSupportPkg != "" {
+			// Compile the sources relocated to the package.
+			data, err := supportSource(repo, src)
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(tmpdir, "support-src", supportFile(src))
+			err = writeFile(path, func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+		args = append(args, path)
+	}
+	javac := exec.Command("javac", args...)
+	if verbose() {
+		javac.Stdout = os.Stdout
+		javac.Stderr = os.Stderr
+	}
+	if buildX {
+		printcmd("%s", strings.Join(javac.Args, " "))
+	}
+	if buildN {
+		return dst, nil
+	}
+	if err := javac.Run(); err != nil {
+		return "", err
+	}
+	sum, err := classesSum(dst)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return "", err
+	}
+	unlock, err := lockFile(entry + ".lock")
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	if err := os.RemoveAll(entry); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(entry, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(dst, classes); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(entry, "checksum"), []byte(sum+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return classes, nil
+}
+
+// supportCacheKey returns the cache key of the support classes compiled
+// from the sources in repo against the android.jar in apiPath.
+func supportCacheKey(repo, apiPath string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "gomobile %s\n", mobileRevision())
+	fmt.Fprintf(h, "javac -source %s -target %s\n", javacTargetVer, javacTargetVer)
+	fmt.Fprintf(h, "bootclasspath %s\n", filepath.Join(apiPath, "android.jar"))
+	for _, src := range supportSources {
+		data, err := supportSource(repo, src)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file %s\n", src)
+		h.Write(data)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// checkClassesSum reports whether the cache entry holds classes matching
+// its recorded checksum. A missing entry is not an error.
+func checkClassesSum(entry string) (bool, error) {
+	want, err := ioutil.ReadFile(filepath.Join(entry, "checksum"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	got, err := classesSum(filepath.Join(entry, "classes"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if got != string(bytes.TrimSpace(want)) {
+		warnf("cached Java support classes in %s do not match their checksum, compiling them again", entry)
+		return false, nil
+	}
+	return true, nil
+}
+
+// classesSum returns the SHA-256 checksum of the names and contents of
+// the files in dir, in lexical order.
+func classesSum(dir string) (string, error) {
+	var names []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, path := range names {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file %s %d\n", filepath.ToSlash(path[len(dir)+1:]), len(data))
+		h.Write(data)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
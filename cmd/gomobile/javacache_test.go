@@ -0,0 +1,100 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeJavac is a javac writing the classes of the sources it is given
+// to the -d directory, and logging each run.
+const fakeJavac = `#!/bin/sh
+echo run >> "$FAKE_JAVAC_LOG"
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-d) dst="$2"; shift 2 ;;
+	-source|-target|-bootclasspath|-classpath) shift 2 ;;
+	*) mkdir -p "$dst/go"; cp "$1" "$dst/go/$(basename "$1" .java).class"; shift ;;
+	esac
+done
+`
+
+func TestSupportClassesCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string, mode os.FileMode) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("bin/javac", fakeJavac, 0755)
+	write("repo/app/Go.java", "// Go.java\n", 0644)
+	write("repo/bind/java/Seq.java", "// Seq.java\n", 0644)
+	if err := os.MkdirAll(filepath.Join(dir, "gopath", "pkg", "gomobile"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(dir, "javac.log")
+
+	for _, kv := range []string{"PATH", "GOPATH", "FAKE_JAVAC_LOG"} {
+		defer os.Setenv(kv, os.Getenv(kv))
+	}
+	os.Setenv("PATH", filepath.Join(dir, "bin")+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	os.Setenv("GOPATH", filepath.Join(dir, "gopath"))
+	os.Setenv("FAKE_JAVAC_LOG", log)
+	oldTmpdir, oldA := tmpdir, buildA
+	defer func() { tmpdir, buildA = oldTmpdir, oldA }()
+
+	runs := 0
+	support := func(desc string, wantRun bool) string {
+		tmpdir = filepath.Join(dir, "work")
+		os.RemoveAll(tmpdir)
+		classes, err := supportClasses(filepath.Join(dir, "repo"), filepath.Join(dir, "android-21"))
+		if err != nil {
+			t.Fatalf("%s: %v", desc, err)
+		}
+		data, _ := ioutil.ReadFile(log)
+		n := strings.Count(string(data), "run")
+		if ran := n > runs; ran != wantRun {
+			t.Errorf("%s: javac run: %v, want %v", desc, ran, wantRun)
+		}
+		runs = n
+		if _, err := os.Stat(filepath.Join(classes, "go", "Seq.class")); err != nil {
+			t.Errorf("%s: %v", desc, err)
+		}
+		return classes
+	}
+
+	classes := support("first bind", true)
+	if got := support("second bind", false); got != classes {
+		t.Errorf("second bind: classes in %s, want the cached %s", got, classes)
+	}
+
+	// A modified class does not match the checksum.
+	if err := ioutil.WriteFile(filepath.Join(classes, "go", "Seq.class"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	support("tampered cache", true)
+	support("after recompiling", false)
+
+	buildA = true
+	support("-a", true)
+	buildA = false
+
+	write("repo/bind/java/Seq.java", "// Seq.java, changed\n", 0644)
+	if got := support("changed sources", true); got == classes {
+		t.Errorf("changed sources: classes in the old cache entry %s", got)
+	}
+}
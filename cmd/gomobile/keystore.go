@@ -0,0 +1,236 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Flags selecting the keystore release builds are signed with.
+var (
+	buildKeystore  string // -keystore
+	buildKeyAlias  string // -keyalias
+	buildStorePass string // -storepass
+	buildKeyPass   string // -keypass
+)
+
+// The environment variables holding the passwords of the keystore and
+// of its key, when not given by -storepass and -keypass. They are also
+// how the passwords are passed to keytool and openssl, so they never
+// appear on a command line.
+const (
+	storePassEnv = "GOMOBILE_STOREPASS"
+	keyPassEnv   = "GOMOBILE_KEYPASS"
+)
+
+// addKeystoreFlags registers the flags selecting the signing key.
+func addKeystoreFlags(cmd *command) {
+	cmd.flag.StringVar(&buildKeystore, "keystore", "", "keystore holding the release signing key")
+	cmd.flag.StringVar(&buildKeyAlias, "keyalias", "", "alias of the signing key in the keystore")
+	cmd.flag.StringVar(&buildStorePass, "storepass", "", "keystore password, or $"+storePassEnv)
+	cmd.flag.StringVar(&buildKeyPass, "keypass", "", "key password, or $"+keyPassEnv+"; defaults to the keystore password")
+}
+
+// Magic numbers of the Java keystore formats, which keytool converts to
+// PKCS#12 before openssl can read the key.
+const (
+	jksMagic   = 0xfeedfeed
+	jceksMagic = 0xcececece
+)
+
+// loadKeystore reads the signing key selected by -keystore and -keyalias
+// and its DER-encoded certificate. Without -keystore, it returns a nil
+// key, and the APK is signed with the debug key.
+//
+// The keystore may be in PKCS#12 format, read by openssl, or in one of
+// the JKS formats of Java, which keytool first converts into a PKCS#12
+// file in tmpdir.
+func loadKeystore() (*rsa.PrivateKey, []byte, error) {
+	if buildKeystore == "" {
+		if buildKeyAlias != "" {
+			return nil, nil, errors.New("-keyalias requires -keystore")
+		}
+		return nil, nil, nil
+	}
+	if buildKeyAlias == "" {
+		return nil, nil, errors.New("-keystore requires -keyalias")
+	}
+	f, err := os.Open(buildKeystore)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-keystore: %v", err)
+	}
+	var magic uint32
+	err = binary.Read(f, binary.BigEndian, &magic)
+	f.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("-keystore: cannot read %s: %v", buildKeystore, err)
+	}
+
+	storePass := buildStorePass
+	if storePass == "" {
+		storePass = os.Getenv(storePassEnv)
+	}
+	if storePass == "" {
+		return nil, nil, fmt.Errorf("-keystore requires a password: set -storepass or $%s", storePassEnv)
+	}
+	keyPass := buildKeyPass
+	if keyPass == "" {
+		keyPass = os.Getenv(keyPassEnv)
+	}
+	if keyPass == "" {
+		keyPass = storePass
+	}
+	env := []string{storePassEnv + "=" + storePass, keyPassEnv + "=" + keyPass}
+
+	p12 := buildKeystore
+	if magic == jksMagic || magic == jceksMagic {
+		p12 = filepath.Join(tmpdir, "keystore.p12")
+		// The key of a PKCS#12 keystore is protected by the keystore
+		// password, as openssl expects.
+		err := runKeystoreTool(env, nil, "keytool", "-importkeystore", "-noprompt",
+			"-srckeystore", buildKeystore,
+			"-srcalias", buildKeyAlias,
+			"-srcstorepass:env", storePassEnv,
+			"-srckeypass:env", keyPassEnv,
+			"-destkeystore", p12,
+			"-deststoretype", "PKCS12",
+			"-deststorepass:env", storePassEnv,
+			"-destkeypass:env", storePassEnv)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	out := new(bytes.Buffer)
+	if err := runKeystoreTool(env, out, "openssl", "pkcs12", "-in", p12, "-nodes", "-passin", "env:"+storePassEnv); err != nil {
+		return nil, nil, err
+	}
+	if buildN {
+		return nil, nil, nil
+	}
+	key, cert, err := parseKeystorePEM(out.Bytes(), buildKeyAlias)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-keystore %s: %v", buildKeystore, err)
+	}
+	logf(levelVerbose, "signing with key %q of %s", buildKeyAlias, buildKeystore)
+	return key, cert, nil
+}
+
+// runKeystoreTool runs the named tool with the passwords in env, writing
+// its standard output to stdout. Its errors are reported with the output
+// of the tool, which names the failure, such as a wrong password or a
+// missing alias.
+func runKeystoreTool(env []string, stdout io.Writer, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("-keystore requires the %s tool on the PATH", name)
+	}
+	if buildX {
+		printcmd("%s %s", name, strings.Join(args, " "))
+	}
+	if buildN {
+		return nil
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Env = environ(env)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if stdout == nil {
+		cmd.Stdout = stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("-keystore: cannot read %s: %s failed: %v\n%s", buildKeystore, name, err, stderr.Bytes())
+	}
+	return nil
+}
+
+// parseKeystorePEM returns the RSA private key with the given alias, the
+// friendlyName, in data, the output of openssl pkcs12 -nodes, and the
+// DER-encoded certificate of the key. Aliases are compared ignoring case,
+// as keytool does: it stores the aliases of JKS keystores in lower case.
+func parseKeystorePEM(data []byte, alias string) (*rsa.PrivateKey, []byte, error) {
+	var key *rsa.PrivateKey
+	var certs [][]byte
+	name := ""
+	var block []byte
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case block != nil:
+			block = append(block, line+"\n"...)
+			if !strings.HasPrefix(line, "-----END ") {
+				continue
+			}
+			b, _ := pem.Decode(block)
+			block = nil
+			if b == nil {
+				return nil, nil, errors.New("bad PEM block in keystore")
+			}
+			switch b.Type {
+			case "CERTIFICATE":
+				certs = append(certs, b.Bytes)
+			case "PRIVATE KEY", "RSA PRIVATE KEY":
+				if !strings.EqualFold(name, alias) {
+					continue
+				}
+				k, err := parsePrivateKey(b)
+				if err != nil {
+					return nil, nil, fmt.Errorf("key %q: %v", alias, err)
+				}
+				key = k
+			}
+		case strings.HasPrefix(line, "Bag Attributes"):
+			name = ""
+		case strings.HasPrefix(strings.TrimSpace(line), "friendlyName: "):
+			name = strings.TrimPrefix(strings.TrimSpace(line), "friendlyName: ")
+		case strings.HasPrefix(line, "-----BEGIN "):
+			block = []byte(line + "\n")
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+	if key == nil {
+		return nil, nil, fmt.Errorf("no key with alias %q", alias)
+	}
+	for _, cert := range certs {
+		c, err := x509.ParseCertificate(cert)
+		if err != nil {
+			continue
+		}
+		if pub, ok := c.PublicKey.(*rsa.PublicKey); ok && pub.N.Cmp(key.N) == 0 && pub.E == key.E {
+			return key, cert, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("no certificate for key %q", alias)
+}
+
+func parsePrivateKey(b *pem.Block) (*rsa.PrivateKey, error) {
+	if b.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(b.Bytes)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key; only RSA keys are supported")
+	}
+	return rsaKey, nil
+}
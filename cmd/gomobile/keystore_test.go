@@ -0,0 +1,127 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testKeystore generates a throwaway PKCS#12 keystore in dir, holding an
+// RSA key with the alias release and the password secret. It returns the
+// path of the keystore and the DER-encoded certificate of the key.
+func testKeystore(t *testing.T, dir string) (string, []byte) {
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("command openssl not found, skipping")
+	}
+	keyPath := filepath.Join(dir, "key.pem")
+	certPath := filepath.Join(dir, "cert.pem")
+	ksPath := filepath.Join(dir, "release.p12")
+	cmds := [][]string{
+		{openssl, "req", "-x509", "-newkey", "rsa:2048", "-nodes", "-days", "1",
+			"-subj", "/CN=gomobile test", "-keyout", keyPath, "-out", certPath},
+		{openssl, "pkcs12", "-export", "-name", "release", "-inkey", keyPath,
+			"-in", certPath, "-out", ksPath, "-passout", "pass:secret"},
+	}
+	for _, args := range cmds {
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			t.Fatalf("%v: %v\n%s", args, err, out)
+		}
+	}
+	data, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("no certificate")
+	}
+	return ksPath, block.Bytes
+}
+
+func TestLoadKeystore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ksPath, wantCert := testKeystore(t, dir)
+
+	buf := new(bytes.Buffer)
+	xout = buf
+	buildX = true
+	buildV = true
+	defer func() {
+		xout = os.Stderr
+		buildX = false
+		buildV = false
+		buildKeystore = ""
+		buildKeyAlias = ""
+		buildStorePass = ""
+		os.Setenv(storePassEnv, "")
+	}()
+
+	buildKeystore = ksPath
+	buildKeyAlias = "release"
+	buildStorePass = "secret"
+	key, cert, err := loadKeystore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cert, wantCert) {
+		t.Error("loadKeystore returned the wrong certificate")
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("password printed:\n%s", buf.String())
+	}
+	sig, err := signPKCS7Cert(rand.Reader, key, cert, []byte("Hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(sig, cert) {
+		t.Error("signature does not hold the keystore certificate")
+	}
+
+	// The password may come from the environment instead.
+	buildStorePass = ""
+	os.Setenv(storePassEnv, "secret")
+	if _, _, err := loadKeystore(); err != nil {
+		t.Errorf("password from $%s: %v", storePassEnv, err)
+	}
+
+	// Aliases are matched ignoring case.
+	buildKeyAlias = "Release"
+	if _, _, err := loadKeystore(); err != nil {
+		t.Errorf("-keyalias Release: %v", err)
+	}
+
+	tests := []struct {
+		desc                      string
+		keystore, alias, password string
+	}{
+		{"wrong password", ksPath, "release", "wrong"},
+		{"missing alias", ksPath, "debug", "secret"},
+		{"missing keystore", filepath.Join(dir, "missing.p12"), "release", "secret"},
+		{"no alias", ksPath, "", "secret"},
+	}
+	for _, tt := range tests {
+		buildKeystore = tt.keystore
+		buildKeyAlias = tt.alias
+		os.Setenv(storePassEnv, tt.password)
+		if _, _, err := loadKeystore(); err == nil {
+			t.Errorf("%s: got nil error", tt.desc)
+		} else if strings.Contains(err.Error(), tt.password) {
+			t.Errorf("%s: password in error: %v", tt.desc, err)
+		}
+	}
+}
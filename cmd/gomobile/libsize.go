@@ -0,0 +1,82 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+var buildMaxSize int64 // -maxsize
+
+// checkLibSizes prints the sizes of the native libraries libs under -v,
+// by ABI, and reports an error if a library, or the libraries of an ABI
+// together, exceed the -maxsize budget. The libraries are measured as
+// packed, after stripping. Nothing is built under -n, so nothing is
+// measured.
+func checkLibSizes(libs []apkLib) error {
+	if buildN {
+		return nil
+	}
+	sizes := make([]int64, len(libs))
+	for i, lib := range libs {
+		fi, err := os.Stat(lib.path)
+		if err != nil {
+			return err
+		}
+		sizes[i] = fi.Size()
+	}
+	if verbose() {
+		printLibSizes(logout, libs, sizes)
+	}
+	if buildMaxSize <= 0 {
+		return nil
+	}
+	var abis []string
+	totals := make(map[string]int64)
+	for i, lib := range libs {
+		if sizes[i] > buildMaxSize {
+			return fmt.Errorf("lib/%s/%s is %d bytes, over the -maxsize budget of %d bytes", lib.abi, lib.name, sizes[i], buildMaxSize)
+		}
+		if _, ok := totals[lib.abi]; !ok {
+			abis = append(abis, lib.abi)
+		}
+		totals[lib.abi] += sizes[i]
+	}
+	for _, abi := range abis {
+		if totals[abi] > buildMaxSize {
+			return fmt.Errorf("the libraries of lib/%s are %d bytes, over the -maxsize budget of %d bytes", abi, totals[abi], buildMaxSize)
+		}
+	}
+	return nil
+}
+
+// printLibSizes prints the table of the sizes of libs to w: a line per
+// library, grouped by ABI, and the total of each ABI.
+func printLibSizes(w io.Writer, libs []apkLib, sizes []int64) {
+	var abis []string
+	seen := make(map[string]bool)
+	for _, lib := range libs {
+		if !seen[lib.abi] {
+			seen[lib.abi] = true
+			abis = append(abis, lib.abi)
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(tw, "ABI\tlibrary\tbytes\n")
+	for _, abi := range abis {
+		var total int64
+		for i, lib := range libs {
+			if lib.abi == abi {
+				fmt.Fprintf(tw, "%s\t%s\t%d\n", abi, lib.name, sizes[i])
+				total += sizes[i]
+			}
+		}
+		fmt.Fprintf(tw, "%s\ttotal\t%d\n", abi, total)
+	}
+	tw.Flush()
+}
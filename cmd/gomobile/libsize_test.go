@@ -0,0 +1,94 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckLibSizes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		buildMaxSize = 0
+		buildV = false
+		logout = os.Stderr
+	}()
+
+	var libs []apkLib
+	for _, l := range []struct {
+		abi, name string
+		size      int
+	}{
+		{"armeabi", "libhello.so", 100},
+		{"x86", "libhello.so", 80},
+		{"armeabi", "libopenal.so", 50},
+	} {
+		path := filepath.Join(dir, l.abi+"-"+l.name)
+		if err := ioutil.WriteFile(path, make([]byte, l.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		libs = append(libs, apkLib{abi: l.abi, name: l.name, path: path})
+	}
+
+	tests := []struct {
+		maxSize int64
+		err     string // the error, if over budget
+	}{
+		{0, ""},
+		{150, ""},
+		{1000, ""},
+		{149, "the libraries of lib/armeabi are 150 bytes, over the -maxsize budget of 149 bytes"},
+		{99, "lib/armeabi/libhello.so is 100 bytes, over the -maxsize budget of 99 bytes"},
+	}
+	for _, tt := range tests {
+		buildMaxSize = tt.maxSize
+		err := checkLibSizes(libs)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("-maxsize=%d: %v", tt.maxSize, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("-maxsize=%d: got error %v, want %q", tt.maxSize, err, tt.err)
+		}
+	}
+
+	// The table is printed under -v, grouped by ABI.
+	buf := new(bytes.Buffer)
+	logout = buf
+	buildMaxSize = 0
+	if err := checkLibSizes(libs); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("printed sizes without -v:\n%s", buf.String())
+	}
+	buildV = true
+	if err := checkLibSizes(libs); err != nil {
+		t.Fatal(err)
+	}
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"ABI library bytes",
+		"armeabi libhello.so 100",
+		"armeabi libopenal.so 50",
+		"armeabi total 150",
+		"x86 libhello.so 80",
+		"x86 total 80",
+	}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("size table:\n%s\nwant rows:\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/mobile/bind"
+)
+
+var cmdList = &command{
+	run:   runList,
+	Name:  "list",
+	Usage: "[-json] [package...]",
+	Short: "list the symbols bind exports from a package",
+	Long: `
+List prints the exported declarations of the packages, in the current
+directory if none are named, that bind would export: each function,
+type and constant, with its kind and status. Declarations that bind
+cannot export, such as a function returning a channel, are listed as
+skipped, with the reasons. Bind fails on those declarations, so they
+must be changed or unexported before binding the package.
+
+The packages are checked as by bind, without generating any code, so
+neither the Android SDK nor the toolchain is needed.
+
+The -json flag prints the declarations as a JSON array of objects.
+`,
+}
+
+var listJSON bool // -json
+
+func init() {
+	cmdList.flag.BoolVar(&listJSON, "json", false, "print the symbols as JSON")
+}
+
+// listSymbol is a declaration printed by gomobile list.
+type listSymbol struct {
+	Package string   // import path of the package
+	Name    string   // name of the declaration
+	Kind    string   // func, struct, interface, enum, const, var or type
+	Pos     string   // position of the declaration
+	Status  string   // "exported" or "skipped"
+	Reasons []string `json:",omitempty"` // why a skipped declaration is skipped
+}
+
+func runList(cmd *command) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	pkgs, err := importBindPkgs(cmd.flag.Args(), cwd)
+	if err != nil {
+		return err
+	}
+	binders, err := newBinders(pkgs)
+	if err != nil {
+		return err
+	}
+	return listSymbols(os.Stdout, binders)
+}
+
+// listSymbols prints the declarations exported by the binders to w.
+func listSymbols(w io.Writer, binders []*binder) error {
+	var syms []listSymbol
+	for _, b := range binders {
+		for _, s := range bind.Symbols(b.fset, b.pkg, b.options()) {
+			sym := listSymbol{
+				Package: b.pkg.Path(),
+				Name:    s.Name,
+				Kind:    s.Kind,
+				Pos:     s.Pos.String(),
+				Status:  "exported",
+			}
+			if s.Err != nil {
+				sym.Status = "skipped"
+				for _, r := range strings.Split(s.Err.Error(), "\n") {
+					if r = strings.TrimSpace(r); r != "" {
+						sym.Reasons = append(sym.Reasons, r)
+					}
+				}
+			}
+			syms = append(syms, sym)
+		}
+	}
+
+	if listJSON {
+		if syms == nil {
+			syms = []listSymbol{}
+		}
+		out, err := json.MarshalIndent(syms, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", out)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	for _, sym := range syms {
+		status := sym.Status
+		if len(sym.Reasons) > 0 {
+			status += ": " + strings.Join(sym.Reasons, "; ")
+		}
+		fmt.Fprintf(tw, "%s\t%s.%s\t%s\n", sym.Kind, sym.Package, sym.Name, status)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const listSrc = `package hello
+
+func Hello() string { return "hello" }
+
+func Events() chan int { return nil }
+`
+
+func TestListSymbols(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	path := filepath.Join(gopath, "src", "example.com", "hello", "hello.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(listSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldCtx := ctx
+	defer func() { ctx = oldCtx }()
+	ctx.GOPATH = gopath
+	pkg, err := ctx.Import("example.com/hello", "", build.ImportComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binders, err := newBinders([]*build.Package{pkg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const reason = "Events: unsupported channel result type chan int"
+	buf := new(bytes.Buffer)
+	if err := listSymbols(buf, binders); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func example.com/hello.Events skipped: ",
+		reason,
+		"func example.com/hello.Hello  exported\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("list output does not contain %q:\n%s", want, buf)
+		}
+	}
+
+	defer func() { listJSON = false }()
+	listJSON = true
+	buf.Reset()
+	if err := listSymbols(buf, binders); err != nil {
+		t.Fatal(err)
+	}
+	var syms []listSymbol
+	if err := json.Unmarshal(buf.Bytes(), &syms); err != nil {
+		t.Fatalf("%v:\n%s", err, buf)
+	}
+	if len(syms) != 2 {
+		t.Fatalf("got %d symbols, want 2:\n%s", len(syms), buf)
+	}
+	if s := syms[0]; s.Name != "Events" || s.Status != "skipped" || !strings.Contains(strings.Join(s.Reasons, "\n"), reason) {
+		t.Errorf("Events: got %+v, want skipped: %s", s, reason)
+	}
+	if s := syms[1]; s.Name != "Hello" || s.Status != "exported" || s.Kind != "func" {
+		t.Errorf("Hello: got %+v, want an exported func", s)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// errorSharingViolation is ERROR_SHARING_VIOLATION, returned when
+// opening a file opened elsewhere without sharing.
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile takes an exclusive lock on the file path, creating it, and
+// waits until the lock is available. The lock is held until unlock is
+// called, or the process exits. A file opened without sharing cannot
+// be opened again until it is closed, so the file itself is the lock.
+func lockFile(path string) (unlock func() error, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		h, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+		if err == nil {
+			return func() error { return syscall.CloseHandle(h) }, nil
+		}
+		if err != errorSharingViolation {
+			return nil, &os.PathError{Op: "lock", Path: path, Err: err}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A logLevel is the verbosity of the output of a subcommand, set by the
+// -quiet, -v and -x flags. Errors are reported on standard error at
+// every level.
+type logLevel int
+
+const (
+	levelQuiet   logLevel = iota // -quiet: errors only
+	levelNormal                  // progress and results
+	levelVerbose                 // -v: the steps of a build, and the output of the tools it runs
+	levelDebug                   // -x: the command lines run, as well
+)
+
+var (
+	buildQuiet bool // -quiet
+
+	logout io.Writer = os.Stderr // progress and diagnostics
+	resout io.Writer = os.Stdout // results, such as the bytes reclaimed by clean
+)
+
+// currentLevel returns the log level set by the flags.
+func currentLevel() logLevel {
+	switch {
+	case buildQuiet:
+		return levelQuiet
+	case buildX:
+		return levelDebug
+	case buildV:
+		return levelVerbose
+	}
+	return levelNormal
+}
+
+// checkLogFlags reports an error if -quiet is used with a flag printing
+// more.
+func checkLogFlags() error {
+	if buildQuiet && (buildN || buildV || buildX || buildPlan) {
+		return errors.New("-quiet cannot be used with -n, -v, -x or -dry-run")
+	}
+	return nil
+}
+
+// logf prints a line to logout if the log level is at least level.
+func logf(level logLevel, format string, args ...interface{}) {
+	if currentLevel() >= level {
+		fmt.Fprintf(logout, format+"\n", args...)
+	}
+}
+
+// warnf prints a warning, unless -quiet.
+func warnf(format string, args ...interface{}) {
+	logf(levelNormal, "gomobile: warning: "+format, args...)
+}
+
+// resultf prints a line of the result of a subcommand to resout,
+// unless -quiet.
+func resultf(format string, args ...interface{}) {
+	if currentLevel() >= levelNormal {
+		fmt.Fprintf(resout, format+"\n", args...)
+	}
+}
+
+// verbose reports whether the output of the tools run is shown.
+func verbose() bool {
+	return currentLevel() >= levelVerbose
+}
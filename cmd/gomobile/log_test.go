@@ -0,0 +1,95 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogLevels(t *testing.T) {
+	defer func() {
+		logout = os.Stderr
+		buildQuiet, buildV, buildX = false, false, false
+	}()
+
+	tests := []struct {
+		quiet, v, x bool
+		want        string
+	}{
+		{true, false, false, ""},
+		{false, false, false, "normal\n"},
+		{false, true, false, "normal\nverbose\n"},
+		{false, false, true, "normal\nverbose\ndebug\n"},
+	}
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		logout = buf
+		buildQuiet, buildV, buildX = tt.quiet, tt.v, tt.x
+		logf(levelNormal, "normal")
+		logf(levelVerbose, "verbose")
+		logf(levelDebug, "debug")
+		if got := buf.String(); got != tt.want {
+			t.Errorf("-quiet=%v -v=%v -x=%v: printed %q, want %q", tt.quiet, tt.v, tt.x, got, tt.want)
+		}
+	}
+
+	buildQuiet, buildV, buildX = false, false, false
+	if err := checkLogFlags(); err != nil {
+		t.Errorf("no flags: %v", err)
+	}
+	buildQuiet = true
+	if err := checkLogFlags(); err != nil {
+		t.Errorf("-quiet: %v", err)
+	}
+	for _, flag := range []*bool{&buildN, &buildV, &buildX, &buildPlan} {
+		*flag = true
+		if err := checkLogFlags(); err == nil {
+			t.Error("-quiet with a verbose flag: got nil error")
+		}
+		*flag = false
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "gopath", "pkg", "gomobile", "cache")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldGopath, oldTmpdir := os.Getenv("GOPATH"), os.Getenv("TMPDIR")
+	defer func() {
+		logout, resout = os.Stderr, os.Stdout
+		buildQuiet = false
+		os.Setenv("GOPATH", oldGopath)
+		os.Setenv("TMPDIR", oldTmpdir)
+	}()
+	os.Setenv("GOPATH", filepath.Join(dir, "gopath"))
+	os.Setenv("TMPDIR", dir)
+
+	for _, quiet := range []bool{false, true} {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		resout, logout = stdout, stderr
+		buildQuiet = quiet
+		if err := runClean(cmdClean); err != nil {
+			t.Fatal(err)
+		}
+		if quiet {
+			if stdout.Len() != 0 || stderr.Len() != 0 {
+				t.Errorf("clean -quiet printed %q to stdout and %q to stderr", stdout.String(), stderr.String())
+			}
+		} else if stdout.Len() == 0 {
+			t.Error("clean printed nothing to stdout without -quiet")
+		}
+	}
+}
@@ -60,7 +60,11 @@ func main() {
 				os.Exit(1)
 			}
 			cmd.flag.Parse(args[1:])
-			if err := cmd.run(cmd); err != nil {
+			err := checkLogFlags()
+			if err == nil {
+				err = cmd.run(cmd)
+			}
+			if err != nil {
 				msg := err.Error()
 				if msg != "" {
 					fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
@@ -128,11 +132,15 @@ func helpDocumentation(path string) {
 }
 
 var commands = []*command{
-	// TODO(crawshaw): cmdRun
 	cmdBind,
 	cmdBuild,
+	cmdClean,
+	cmdDoctor,
 	cmdInit,
 	cmdInstall,
+	cmdList,
+	cmdRun,
+	cmdVersion,
 }
 
 type command struct {
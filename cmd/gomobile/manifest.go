@@ -5,53 +5,283 @@
 package main
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"strconv"
+	"strings"
 )
 
 type manifestXML struct {
-	Activity activityXML `xml:"application>activity"`
+	Package string `xml:"package,attr"`
+	UsesSDK struct {
+		MinSDK string `xml:"minSdkVersion,attr"`
+	} `xml:"uses-sdk"`
 }
 
-type activityXML struct {
-	Name     string        `xml:"name,attr"`
-	MetaData []metaDataXML `xml:"meta-data"`
-}
-
-type metaDataXML struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value,attr"`
+// manifestPackage parses the AndroidManifest.xml and finds the Java
+// package name of the app.
+func manifestPackage(data []byte) (string, error) {
+	manifest := new(manifestXML)
+	if err := xml.Unmarshal(data, manifest); err != nil {
+		return "", err
+	}
+	if manifest.Package == "" {
+		return "", errors.New("AndroidManifest.xml missing package attribute")
+	}
+	return manifest.Package, nil
 }
 
-// manifestLibName parses the AndroidManifest.xml and finds the library
-// name of the NativeActivity.
-func manifestLibName(data []byte) (string, error) {
+// manifestMinSDK parses the AndroidManifest.xml and finds the minimum
+// API level of the app. Without a minSdkVersion, Android assumes 1.
+func manifestMinSDK(data []byte) (int, error) {
 	manifest := new(manifestXML)
 	if err := xml.Unmarshal(data, manifest); err != nil {
-		return "", err
+		return 0, err
+	}
+	if manifest.UsesSDK.MinSDK == "" {
+		return 1, nil
 	}
-	if manifest.Activity.Name != "android.app.NativeActivity" {
-		return "", fmt.Errorf("can only build an .apk for NativeActivity, not %q", manifest.Activity.Name)
+	n, err := strconv.Atoi(manifest.UsesSDK.MinSDK)
+	if err != nil {
+		return 0, fmt.Errorf("AndroidManifest.xml: bad minSdkVersion %q", manifest.UsesSDK.MinSDK)
 	}
-	libName := ""
-	for _, md := range manifest.Activity.MetaData {
-		if md.Name == "android.app.lib_name" {
-			libName = md.Value
+	return n, nil
+}
+
+// manifestElem records the position of an element in an
+// AndroidManifest.xml. The start tag is data[start:end], and the end tag,
+// if any, begins at data[close].
+type manifestElem struct {
+	start, end, close int64
+	selfClosing       bool
+}
+
+// manifestEdit replaces data[start:end] with text.
+type manifestEdit struct {
+	start, end int64
+	text       string
+}
+
+// insertChild returns the edit adding child as the last child of e in
+// data, expanding a self-closing tag of the given name.
+func (e *manifestElem) insertChild(data []byte, name, child string) manifestEdit {
+	if e.selfClosing {
+		start := e.end - int64(len("/>"))
+		for start > e.start && strings.ContainsRune(" \t\r\n", rune(data[start-1])) {
+			start--
+		}
+		return manifestEdit{start, e.end, ">\n\t" + child + "\n\t</" + name + ">"}
+	}
+	return manifestEdit{e.close, e.close, "\t" + child + "\n"}
+}
+
+// mergeManifest merges the entries gomobile requires into an
+// AndroidManifest.xml provided by the user: the package attribute, the
+// SDK versions, the uses-feature entries of the -androidtarget, and a
+// NativeActivity with the meta-data naming the library that contains the
+// app. Entries the user declares are kept, so a user-declared
+// NativeActivity keeps its attributes, intent filters and library name.
+// The rest of the document is copied unchanged. With d.Debug, the
+// application is also marked debuggable, and with d.Icon, its icon is
+// the launcher icon of -icon.
+//
+// mergeManifest returns the merged manifest and the library name.
+func mergeManifest(data []byte, d manifestTmplData) ([]byte, string, error) {
+	var (
+		manifest, app, activity *manifestElem
+		hasPackage, hasUsesSDK  bool
+		debuggable, icon        string
+		libName                 string
+		path                    []string
+		features                = make(map[string]bool)
+	)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return nil, "", fmt.Errorf("AndroidManifest.xml: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			path = append(path, tok.Name.Local)
+			elem := &manifestElem{start: start, end: dec.InputOffset()}
+			elem.selfClosing = bytes.HasSuffix(data[elem.start:elem.end], []byte("/>"))
+			switch strings.Join(path, "/") {
+			case "manifest":
+				manifest = elem
+				hasPackage = manifestAttr(tok, "package") != ""
+			case "manifest/uses-sdk":
+				hasUsesSDK = true
+			case "manifest/uses-feature":
+				features[manifestAttr(tok, "name")] = true
+			case "manifest/application":
+				if app == nil {
+					app = elem
+					debuggable = manifestAttr(tok, "debuggable")
+					icon = manifestAttr(tok, "icon")
+				}
+			case "manifest/application/activity":
+				if activity == nil && manifestAttr(tok, "name") == "android.app.NativeActivity" {
+					activity = elem
+				}
+			case "manifest/application/activity/meta-data":
+				// Only the meta-data of the first NativeActivity, which
+				// is still open, counts.
+				if activity != nil && activity.close == 0 && manifestAttr(tok, "name") == "android.app.lib_name" {
+					libName = manifestAttr(tok, "value")
+				}
+			}
+		case xml.EndElement:
+			switch strings.Join(path, "/") {
+			case "manifest":
+				manifest.close = start
+			case "manifest/application":
+				if app.close == 0 {
+					app.close = start
+				}
+			case "manifest/application/activity":
+				if activity != nil && activity.close == 0 {
+					activity.close = start
+				}
+			}
+			path = path[:len(path)-1]
+		}
+	}
+	if manifest == nil || manifest.selfClosing {
+		return nil, "", errors.New("AndroidManifest.xml missing manifest element")
+	}
+	if hasUsesSDK && d.SDKFlags {
+		return nil, "", errors.New("AndroidManifest.xml declares uses-sdk, which conflicts with -minsdk and -androidapi")
+	}
+
+	if d.Debug && debuggable != "" && debuggable != "true" {
+		return nil, "", fmt.Errorf("AndroidManifest.xml declares android:debuggable=%q, which conflicts with -debug", debuggable)
+	}
+	if d.Icon && icon != "" && icon != launcherIconRef {
+		return nil, "", fmt.Errorf("AndroidManifest.xml declares android:icon=%q, which conflicts with -icon", icon)
+	}
+
+	// The edits are in document order.
+	var edits []manifestEdit
+	if !hasPackage {
+		off := manifest.start + int64(len("<manifest"))
+		edits = append(edits, manifestEdit{off, off, fmt.Sprintf(" package=%q", d.JavaPkgPath)})
+	}
+	if !hasUsesSDK {
+		buf := new(bytes.Buffer)
+		if err := manifestTmpl.ExecuteTemplate(buf, "usessdk", d); err != nil {
+			return nil, "", err
+		}
+		edits = append(edits, manifestEdit{manifest.end, manifest.end, "\n\t" + buf.String()})
 	}
-	if libName == "" {
-		return "", errors.New("AndroidManifest.xml missing meta-data android.app.lib_name")
+	for _, f := range d.Features() {
+		if features[f.Name] {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if err := manifestTmpl.ExecuteTemplate(buf, "feature", f); err != nil {
+			return nil, "", err
+		}
+		edits = append(edits, manifestEdit{manifest.end, manifest.end, "\n\t" + buf.String()})
+	}
+	if d.Debug && app != nil && debuggable == "" {
+		off := app.start + int64(len("<application"))
+		edits = append(edits, manifestEdit{off, off, ` android:debuggable="true"`})
+	}
+	if d.Icon && app != nil && icon == "" {
+		off := app.start + int64(len("<application"))
+		edits = append(edits, manifestEdit{off, off, ` android:icon="` + launcherIconRef + `"`})
+	}
+	if libName != "" {
+		d.LibName = libName
+	}
+	var child, name string
+	var parent *manifestElem
+	switch {
+	case app == nil:
+		child, name = "application", "manifest"
+		parent = manifest
+	case activity == nil:
+		child, name = "activity", "application"
+		parent = app
+	case libName == "":
+		child, name = "libname", "activity"
+		parent = activity
+	}
+	if parent != nil {
+		buf := new(bytes.Buffer)
+		if err := manifestTmpl.ExecuteTemplate(buf, child, d); err != nil {
+			return nil, "", err
+		}
+		edits = append(edits, parent.insertChild(data, name, buf.String()))
 	}
-	return libName, nil
+
+	merged := data
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		b := make([]byte, 0, len(merged)+len(e.text))
+		b = append(b, merged[:e.start]...)
+		b = append(b, e.text...)
+		b = append(b, merged[e.end:]...)
+		merged = b
+	}
+	return merged, d.LibName, nil
+}
+
+// manifestAttr returns the value of the attribute with the given local
+// name, ignoring its namespace.
+func manifestAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
 }
 
 type manifestTmplData struct {
 	JavaPkgPath string
 	Name        string
 	LibName     string
+	MinSDK      int    // minSdkVersion
+	TargetSDK   int    // targetSdkVersion, or 0 to leave it out
+	SDKFlags    bool   // MinSDK or TargetSDK were set by -minsdk or -androidapi
+	Target      string // the -androidtarget: phone, tv or wear
+	Debug       bool   // -debug: the application must be debuggable
+	Icon        bool   // -icon: the application has the launcher icon
+}
+
+// A manifestFeature is a uses-feature entry of a manifest.
+type manifestFeature struct {
+	Name     string
+	Required bool
+}
+
+// androidTargetFeatures maps the values of -androidtarget to the
+// features their manifest declares. A TV has no touchscreen, and the
+// leanback feature is not required so the app still installs on phones.
+var androidTargetFeatures = map[string][]manifestFeature{
+	"phone": nil,
+	"tv": {
+		{"android.software.leanback", false},
+		{"android.hardware.touchscreen", false},
+	},
+	"wear": {
+		{"android.hardware.type.watch", true},
+	},
+}
+
+// Features returns the uses-feature entries of the target.
+func (d manifestTmplData) Features() []manifestFeature {
+	return androidTargetFeatures[d.Target]
 }
 
 var manifestTmpl = template.Must(template.New("manifest").Parse(`
@@ -61,16 +291,20 @@ var manifestTmpl = template.Must(template.New("manifest").Parse(`
 	android:versionCode="1"
 	android:versionName="1.0">
 
-	<uses-sdk android:minSdkVersion="9" />
-	<application android:label="{{.Name}}" android:hasCode="false" android:debuggable="true">
-	<activity android:name="android.app.NativeActivity"
+	{{template "usessdk" .}}{{range .Features}}
+	{{template "feature" .}}{{end}}
+	{{template "application" .}}
+</manifest>{{define "application"}}<application android:label="{{.Name}}"{{if .Icon}} android:icon="@mipmap/ic_launcher"{{end}} android:hasCode="false" android:debuggable="true">{{if eq .Target "wear"}}
+	<uses-library android:name="com.google.android.wearable" android:required="false" />
+	<meta-data android:name="com.google.android.wearable.standalone" android:value="true" />{{end}}
+	{{template "activity" .}}
+	</application>{{end}}{{define "activity"}}<activity android:name="android.app.NativeActivity"
 		android:label="{{.Name}}"
 		android:configChanges="orientation|keyboardHidden">
-		<meta-data android:name="android.app.lib_name" android:value="{{.LibName}}" />
+		{{template "libname" .}}
 		<intent-filter>
 			<action android:name="android.intent.action.MAIN" />
-			<category android:name="android.intent.category.LAUNCHER" />
+			<category android:name="android.intent.category.LAUNCHER" />{{if eq .Target "tv"}}
+			<category android:name="android.intent.category.LEANBACK_LAUNCHER" />{{end}}
 		</intent-filter>
-	</activity>
-	</application>
-</manifest>`))
+	</activity>{{end}}{{define "usessdk"}}<uses-sdk android:minSdkVersion="{{.MinSDK}}"{{if .TargetSDK}} android:targetSdkVersion="{{.TargetSDK}}"{{end}} />{{end}}{{define "feature"}}<uses-feature android:name="{{.Name}}" android:required="{{.Required}}" />{{end}}{{define "libname"}}<meta-data android:name="android.app.lib_name" android:value="{{.LibName}}" />{{end}}`))
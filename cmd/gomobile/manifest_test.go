@@ -0,0 +1,426 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+type mergedManifestXML struct {
+	Package     string `xml:"package,attr"`
+	Permissions []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"uses-permission"`
+	UsesSDK []struct {
+		MinSDK    string `xml:"minSdkVersion,attr"`
+		TargetSDK string `xml:"targetSdkVersion,attr"`
+	} `xml:"uses-sdk"`
+	Application struct {
+		Name       string `xml:"name,attr"`
+		Activities []struct {
+			Name     string `xml:"name,attr"`
+			Label    string `xml:"label,attr"`
+			MetaData []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value,attr"`
+			} `xml:"meta-data"`
+		} `xml:"activity"`
+	} `xml:"application"`
+}
+
+func TestMergeManifest(t *testing.T) {
+	defaults := manifestTmplData{
+		JavaPkgPath: "org.golang.todo.basic",
+		Name:        "Basic",
+		LibName:     "basic",
+		MinSDK:      9,
+	}
+	tests := []struct {
+		name        string
+		manifest    string
+		pkg         string
+		libName     string
+		app         string
+		activities  int
+		nativeLabel string
+	}{
+		{
+			name: "permission only",
+			manifest: `<?xml version="1.0" encoding="utf-8"?>
+<manifest xmlns:android="http://schemas.android.com/apk/res/android">
+	<uses-permission android:name="android.permission.INTERNET" />
+</manifest>`,
+			pkg:         "org.golang.todo.basic",
+			libName:     "basic",
+			activities:  1,
+			nativeLabel: "Basic",
+		},
+		{
+			name: "custom application",
+			manifest: `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">
+	<uses-permission android:name="android.permission.INTERNET" />
+	<uses-sdk android:minSdkVersion="15" />
+	<application android:name="com.example.App">
+		<activity android:name="com.example.Settings" />
+	</application>
+</manifest>`,
+			pkg:         "com.example.app",
+			libName:     "basic",
+			app:         "com.example.App",
+			activities:  2,
+			nativeLabel: "Basic",
+		},
+		{
+			name: "self-closing application",
+			manifest: `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">
+	<uses-permission android:name="android.permission.INTERNET" />
+	<application android:name="com.example.App" />
+</manifest>`,
+			pkg:         "com.example.app",
+			libName:     "basic",
+			app:         "com.example.App",
+			activities:  1,
+			nativeLabel: "Basic",
+		},
+		{
+			name: "user NativeActivity",
+			manifest: `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">
+	<uses-permission android:name="android.permission.INTERNET" />
+	<application>
+		<activity android:name="android.app.NativeActivity" android:label="Mine">
+			<meta-data android:name="android.app.lib_name" android:value="mine" />
+		</activity>
+	</application>
+</manifest>`,
+			pkg:         "com.example.app",
+			libName:     "mine",
+			activities:  1,
+			nativeLabel: "Mine",
+		},
+		{
+			name: "user NativeActivity without library",
+			manifest: `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">
+	<uses-permission android:name="android.permission.INTERNET" />
+	<application>
+		<activity android:name="android.app.NativeActivity" android:label="Mine" />
+	</application>
+</manifest>`,
+			pkg:         "com.example.app",
+			libName:     "basic",
+			activities:  1,
+			nativeLabel: "Mine",
+		},
+	}
+	for _, tt := range tests {
+		data, libName, err := mergeManifest([]byte(tt.manifest), defaults)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if libName != tt.libName {
+			t.Errorf("%s: library name %q, want %q", tt.name, libName, tt.libName)
+		}
+		m := new(mergedManifestXML)
+		if err := xml.Unmarshal(data, m); err != nil {
+			t.Errorf("%s: merged manifest does not parse: %v\n%s", tt.name, err, data)
+			continue
+		}
+		if m.Package != tt.pkg {
+			t.Errorf("%s: package %q, want %q", tt.name, m.Package, tt.pkg)
+		}
+		if len(m.Permissions) != 1 || m.Permissions[0].Name != "android.permission.INTERNET" {
+			t.Errorf("%s: INTERNET permission lost:\n%s", tt.name, data)
+		}
+		if len(m.UsesSDK) != 1 {
+			t.Errorf("%s: %d uses-sdk elements, want 1:\n%s", tt.name, len(m.UsesSDK), data)
+		}
+		if m.Application.Name != tt.app {
+			t.Errorf("%s: application %q, want %q", tt.name, m.Application.Name, tt.app)
+		}
+		if len(m.Application.Activities) != tt.activities {
+			t.Errorf("%s: %d activities, want %d:\n%s", tt.name, len(m.Application.Activities), tt.activities, data)
+			continue
+		}
+		found := false
+		for _, a := range m.Application.Activities {
+			if a.Name != "android.app.NativeActivity" {
+				continue
+			}
+			found = true
+			if a.Label != tt.nativeLabel {
+				t.Errorf("%s: NativeActivity label %q, want %q", tt.name, a.Label, tt.nativeLabel)
+			}
+			if len(a.MetaData) != 1 || a.MetaData[0].Name != "android.app.lib_name" || a.MetaData[0].Value != tt.libName {
+				t.Errorf("%s: NativeActivity meta-data %v, want lib_name %q", tt.name, a.MetaData, tt.libName)
+			}
+		}
+		if !found {
+			t.Errorf("%s: no NativeActivity:\n%s", tt.name, data)
+		}
+	}
+}
+
+func TestManifestSDK(t *testing.T) {
+	d := manifestTmplData{
+		JavaPkgPath: "org.golang.todo.basic",
+		Name:        "Basic",
+		LibName:     "basic",
+		MinSDK:      15,
+		TargetSDK:   21,
+		SDKFlags:    true,
+	}
+	check := func(desc string, data []byte) {
+		m := new(mergedManifestXML)
+		if err := xml.Unmarshal(data, m); err != nil {
+			t.Fatalf("%s: manifest does not parse: %v\n%s", desc, err, data)
+		}
+		if len(m.UsesSDK) != 1 || m.UsesSDK[0].MinSDK != "15" || m.UsesSDK[0].TargetSDK != "21" {
+			t.Errorf("%s: uses-sdk %v, want minSdkVersion 15 and targetSdkVersion 21:\n%s", desc, m.UsesSDK, data)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := manifestTmpl.Execute(buf, d); err != nil {
+		t.Fatal(err)
+	}
+	check("generated", buf.Bytes())
+
+	const user = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">
+	<uses-permission android:name="android.permission.INTERNET" />
+</manifest>`
+	data, _, err := mergeManifest([]byte(user), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("merged", data)
+
+	const withSDK = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">
+	<uses-sdk android:minSdkVersion="19" />
+</manifest>`
+	if _, _, err := mergeManifest([]byte(withSDK), d); err == nil {
+		t.Error("uses-sdk with -minsdk: got nil error")
+	}
+	d.SDKFlags = false
+	if _, _, err := mergeManifest([]byte(withSDK), d); err != nil {
+		t.Errorf("uses-sdk without flags: %v", err)
+	}
+
+	d.TargetSDK = 0
+	buf.Reset()
+	if err := manifestTmpl.Execute(buf, d); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("targetSdkVersion")) {
+		t.Errorf("targetSdkVersion without -androidapi:\n%s", buf.Bytes())
+	}
+}
+
+func TestManifestDebug(t *testing.T) {
+	d := manifestTmplData{
+		JavaPkgPath: "org.golang.todo.basic",
+		Name:        "Basic",
+		LibName:     "basic",
+		MinSDK:      9,
+		Debug:       true,
+	}
+	debuggable := func(data []byte) string {
+		var m struct {
+			Application struct {
+				Debuggable string `xml:"debuggable,attr"`
+			} `xml:"application"`
+		}
+		if err := xml.Unmarshal(data, &m); err != nil {
+			t.Fatalf("manifest does not parse: %v\n%s", err, data)
+		}
+		return m.Application.Debuggable
+	}
+
+	tests := []struct {
+		app  string
+		want string
+	}{
+		{"", "true"}, // the generated application is debuggable
+		{`<application android:label="App" />`, "true"},
+		{`<application android:label="App"><activity android:name="App" /></application>`, "true"},
+		{`<application android:debuggable="true" />`, "true"},
+	}
+	for _, tt := range tests {
+		user := `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">` + tt.app + `</manifest>`
+		data, _, err := mergeManifest([]byte(user), d)
+		if err != nil {
+			t.Errorf("%s: %v", tt.app, err)
+			continue
+		}
+		if got := debuggable(data); got != tt.want {
+			t.Errorf("%s: android:debuggable=%q, want %q:\n%s", tt.app, got, tt.want, data)
+		}
+		if _, err := binaryXML(bytes.NewReader(data)); err != nil {
+			t.Errorf("%s: binaryXML: %v", tt.app, err)
+		}
+	}
+
+	const notDebuggable = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app"><application android:debuggable="false" /></manifest>`
+	if _, _, err := mergeManifest([]byte(notDebuggable), d); err == nil {
+		t.Error("debuggable=false with -debug: got nil error")
+	}
+	d.Debug = false
+	data, _, err := mergeManifest([]byte(notDebuggable), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := debuggable(data); got != "false" {
+		t.Errorf("without -debug: android:debuggable=%q, want false", got)
+	}
+}
+
+func TestManifestMinSDK(t *testing.T) {
+	tests := []struct {
+		manifest string
+		want     int
+	}{
+		{`<manifest package="a"><uses-sdk android:minSdkVersion="23" /></manifest>`, 23},
+		{`<manifest package="a"><uses-sdk android:targetSdkVersion="23" /></manifest>`, 1},
+		{`<manifest package="a"></manifest>`, 1},
+	}
+	for _, tt := range tests {
+		got, err := manifestMinSDK([]byte(tt.manifest))
+		if err != nil {
+			t.Errorf("%s: %v", tt.manifest, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: minimum API level %d, want %d", tt.manifest, got, tt.want)
+		}
+	}
+	if _, err := manifestMinSDK([]byte(`<manifest package="a"><uses-sdk android:minSdkVersion="M" /></manifest>`)); err == nil {
+		t.Error("bad minSdkVersion: got nil error")
+	}
+}
+
+type targetManifestXML struct {
+	Features []struct {
+		Name     string `xml:"name,attr"`
+		Required string `xml:"required,attr"`
+	} `xml:"uses-feature"`
+	Application struct {
+		MetaData []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"meta-data"`
+		Activities []struct {
+			Name          string `xml:"name,attr"`
+			IntentFilters []struct {
+				Categories []struct {
+					Name string `xml:"name,attr"`
+				} `xml:"category"`
+			} `xml:"intent-filter"`
+		} `xml:"activity"`
+	} `xml:"application"`
+}
+
+func (m *targetManifestXML) hasFeature(name string) bool {
+	for _, f := range m.Features {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *targetManifestXML) hasCategory(name string) bool {
+	for _, a := range m.Application.Activities {
+		if a.Name != "android.app.NativeActivity" {
+			continue
+		}
+		for _, f := range a.IntentFilters {
+			for _, c := range f.Categories {
+				if c.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestManifestAndroidTarget(t *testing.T) {
+	d := manifestTmplData{
+		JavaPkgPath: "org.golang.todo.basic",
+		Name:        "Basic",
+		LibName:     "basic",
+		MinSDK:      9,
+	}
+	generate := func(target string) (*targetManifestXML, []byte) {
+		d.Target = target
+		buf := new(bytes.Buffer)
+		if err := manifestTmpl.Execute(buf, d); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+		m := new(targetManifestXML)
+		if err := xml.Unmarshal(buf.Bytes(), m); err != nil {
+			t.Fatalf("%s: manifest does not parse: %v\n%s", target, err, buf.Bytes())
+		}
+		if _, err := binaryXML(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Errorf("%s: binaryXML: %v", target, err)
+		}
+		return m, buf.Bytes()
+	}
+
+	m, data := generate("tv")
+	if !m.hasCategory("android.intent.category.LEANBACK_LAUNCHER") {
+		t.Errorf("tv: no leanback launcher intent filter:\n%s", data)
+	}
+	if !m.hasCategory("android.intent.category.LAUNCHER") {
+		t.Errorf("tv: launcher intent filter lost:\n%s", data)
+	}
+	if !m.hasFeature("android.software.leanback") || !m.hasFeature("android.hardware.touchscreen") {
+		t.Errorf("tv: features %v, want leanback and touchscreen", m.Features)
+	}
+
+	m, data = generate("wear")
+	if !m.hasFeature("android.hardware.type.watch") {
+		t.Errorf("wear: no watch feature:\n%s", data)
+	}
+	if md := m.Application.MetaData; len(md) != 1 || md[0].Name != "com.google.android.wearable.standalone" {
+		t.Errorf("wear: application meta-data %v, want wearable.standalone", md)
+	}
+	if m.hasCategory("android.intent.category.LEANBACK_LAUNCHER") {
+		t.Errorf("wear: leanback launcher intent filter:\n%s", data)
+	}
+
+	for _, target := range []string{"", "phone"} {
+		m, data = generate(target)
+		if len(m.Features) != 0 || m.hasCategory("android.intent.category.LEANBACK_LAUNCHER") {
+			t.Errorf("%q: TV or Wear entries:\n%s", target, data)
+		}
+	}
+
+	const user = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.example.app">
+	<uses-feature android:name="android.hardware.touchscreen" android:required="true" />
+</manifest>`
+	d.Target = "tv"
+	merged, _, err := mergeManifest([]byte(user), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = new(targetManifestXML)
+	if err := xml.Unmarshal(merged, m); err != nil {
+		t.Fatalf("merged manifest does not parse: %v\n%s", err, merged)
+	}
+	required := ""
+	for _, f := range m.Features {
+		if f.Name == "android.hardware.touchscreen" {
+			required = f.Required
+		}
+	}
+	if len(m.Features) != 2 || !m.hasFeature("android.software.leanback") || required != "true" {
+		t.Errorf("merged tv: features %v, want the user touchscreen and leanback:\n%s", m.Features, merged)
+	}
+	if !m.hasCategory("android.intent.category.LEANBACK_LAUNCHER") {
+		t.Errorf("merged tv: no leanback launcher intent filter:\n%s", merged)
+	}
+}
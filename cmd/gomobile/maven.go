@@ -0,0 +1,91 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mavenCoords are the coordinates of a Maven artifact, as set by the
+// -maven flag of bind.
+type mavenCoords struct {
+	groupID    string
+	artifactID string
+	version    string
+}
+
+// parseMavenCoords parses coordinates of the form groupId:artifactId:version.
+func parseMavenCoords(s string) (*mavenCoords, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("-maven %q: want groupId:artifactId:version", s)
+	}
+	return &mavenCoords{groupID: parts[0], artifactID: parts[1], version: parts[2]}, nil
+}
+
+// fileName returns the name of the artifact file with the extension ext
+// in a Maven repository, such as artifactId-version.aar.
+func (c *mavenCoords) fileName(ext string) string {
+	return c.artifactID + "-" + c.version + ext
+}
+
+// pomProject is the POM of an AAR built by bind.
+type pomProject struct {
+	XMLName      xml.Name         `xml:"http://maven.apache.org/POM/4.0.0 project"`
+	ModelVersion string           `xml:"modelVersion"`
+	GroupID      string           `xml:"groupId"`
+	ArtifactID   string           `xml:"artifactId"`
+	Version      string           `xml:"version"`
+	Packaging    string           `xml:"packaging"`
+	Dependencies *pomDependencies `xml:"dependencies,omitempty"`
+}
+
+type pomDependencies struct {
+	Dependency []pomDependency `xml:"dependency"`
+}
+
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Scope      string `xml:"scope"`
+}
+
+// annotationDeps are the libraries providing the Nullable annotations
+// selected by the -annotations flag. The AAR is compiled against a stub
+// of the annotation, so the app needs the library itself.
+var annotationDeps = map[string]pomDependency{
+	"androidx": {GroupID: "androidx.annotation", ArtifactID: "annotation", Version: "1.0.0", Scope: "compile"},
+	"javax":    {GroupID: "com.google.code.findbugs", ArtifactID: "jsr305", Version: "3.0.2", Scope: "compile"},
+}
+
+// writePOM writes the POM of the AAR with coordinates c to w. The
+// libraries the bindings depend on are listed as dependencies, so
+// Gradle and Maven resolve them transitively.
+func writePOM(w io.Writer, c *mavenCoords) error {
+	p := pomProject{
+		ModelVersion: "4.0.0",
+		GroupID:      c.groupID,
+		ArtifactID:   c.artifactID,
+		Version:      c.version,
+		Packaging:    "aar",
+	}
+	if dep, ok := annotationDeps[bindAnnotations]; ok {
+		p.Dependencies = &pomDependencies{[]pomDependency{dep}}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(p); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMavenCoords(t *testing.T) {
+	c, err := parseMavenCoords("com.example:hello:1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (mavenCoords{"com.example", "hello", "1.2.0"}); *c != want {
+		t.Errorf("parseMavenCoords = %+v, want %+v", *c, want)
+	}
+	if got, want := c.fileName(".aar"), "hello-1.2.0.aar"; got != want {
+		t.Errorf("fileName = %q, want %q", got, want)
+	}
+	for _, s := range []string{"", "com.example:hello", "com.example::1.0", "a:b:c:d"} {
+		if _, err := parseMavenCoords(s); err == nil {
+			t.Errorf("parseMavenCoords(%q): got nil error", s)
+		}
+	}
+}
+
+func TestWritePOM(t *testing.T) {
+	defer func() { bindAnnotations = "" }()
+
+	c := &mavenCoords{"com.example", "hello", "1.2.0"}
+	tests := []struct {
+		annotations string
+		deps        []pomDependency
+	}{
+		{"", nil},
+		{"androidx", []pomDependency{{"androidx.annotation", "annotation", "1.0.0", "compile"}}},
+		{"javax", []pomDependency{{"com.google.code.findbugs", "jsr305", "3.0.2", "compile"}}},
+	}
+	for _, tt := range tests {
+		bindAnnotations = tt.annotations
+		buf := new(bytes.Buffer)
+		if err := writePOM(buf, c); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(buf.String(), xml.Header) {
+			t.Errorf("-annotations=%s: POM does not start with an XML declaration:\n%s", tt.annotations, buf)
+		}
+
+		// Decode into a generic structure, so the test checks the
+		// element names Maven reads, not those of pomProject.
+		var got struct {
+			XMLName      xml.Name
+			ModelVersion string `xml:"modelVersion"`
+			GroupID      string `xml:"groupId"`
+			ArtifactID   string `xml:"artifactId"`
+			Version      string `xml:"version"`
+			Packaging    string `xml:"packaging"`
+			Dependencies []struct {
+				GroupID    string `xml:"groupId"`
+				ArtifactID string `xml:"artifactId"`
+				Version    string `xml:"version"`
+				Scope      string `xml:"scope"`
+			} `xml:"dependencies>dependency"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("-annotations=%s: %v\n%s", tt.annotations, err, buf)
+		}
+		if want := (xml.Name{Space: "http://maven.apache.org/POM/4.0.0", Local: "project"}); got.XMLName != want {
+			t.Errorf("-annotations=%s: root element %v, want %v", tt.annotations, got.XMLName, want)
+		}
+		if got.ModelVersion != "4.0.0" || got.GroupID != c.groupID || got.ArtifactID != c.artifactID || got.Version != c.version || got.Packaging != "aar" {
+			t.Errorf("-annotations=%s: bad artifact in POM:\n%s", tt.annotations, buf)
+		}
+		var deps []pomDependency
+		for _, d := range got.Dependencies {
+			deps = append(deps, pomDependency{d.GroupID, d.ArtifactID, d.Version, d.Scope})
+		}
+		if !reflect.DeepEqual(deps, tt.deps) {
+			t.Errorf("-annotations=%s: dependencies %+v, want %+v", tt.annotations, deps, tt.deps)
+		}
+		if tt.deps == nil && strings.Contains(buf.String(), "<dependencies>") {
+			t.Errorf("-annotations=%s: empty dependencies element:\n%s", tt.annotations, buf)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The NDKs installed on the system that gomobile can use instead of the
+// NDK of gomobile init are the NDKs from r10, the NDK of gomobile init,
+// to r17, the last NDK providing GCC.
+const (
+	minNDKRevision = 10
+	maxNDKRevision = 17
+
+	// ndkGCC is the GCC toolchain of the system NDK compiling for
+	// android/arm.
+	ndkGCC = "arm-linux-androideabi-4.9"
+
+	// ndkPlatform is the platform of the system NDK whose headers and
+	// libraries the code is compiled against, the platform of the
+	// NDK of gomobile init.
+	ndkPlatform = "android-15"
+)
+
+// An ndk is an Android NDK installed on the system, such as by the
+// Android SDK manager.
+type ndk struct {
+	root     string // root directory of the NDK
+	revision string // revision of the NDK, such as r10d or 17.2.4988734
+}
+
+// systemNDK is the NDK on the system used instead of the NDK of
+// gomobile init, or nil. It is set by findSystemNDK.
+var systemNDK *ndk
+
+// findSystemNDK looks for an NDK on the system and sets systemNDK to
+// it, if it is compatible. The NDK is the one named by the environment
+// variable ANDROID_NDK_HOME, or else the one installed by the SDK
+// manager in ANDROID_HOME: in its ndk-bundle directory or, the newest
+// one, in its ndk directory. Without an NDK, systemNDK is nil and the
+// NDK of gomobile init is used. An incompatible NDK named by
+// ANDROID_NDK_HOME is an error; an incompatible NDK of ANDROID_HOME is
+// ignored with a warning.
+func findSystemNDK() error {
+	systemNDK = nil
+	if root := os.Getenv("ANDROID_NDK_HOME"); root != "" {
+		n, err := openNDK(root)
+		if err != nil {
+			return fmt.Errorf("ANDROID_NDK_HOME: %v", err)
+		}
+		systemNDK = n
+		return nil
+	}
+	root := sdkNDK(os.Getenv("ANDROID_HOME"))
+	if root == "" {
+		return nil
+	}
+	n, err := openNDK(root)
+	if err != nil {
+		warnf("using the NDK of gomobile init: %v", err)
+		return nil
+	}
+	systemNDK = n
+	return nil
+}
+
+// sdkNDK returns the directory of the NDK installed in the Android SDK
+// at sdk, or "" if there is none.
+func sdkNDK(sdk string) string {
+	if sdk == "" {
+		return ""
+	}
+	bundle := filepath.Join(sdk, "ndk-bundle")
+	if fi, err := os.Stat(bundle); err == nil && fi.IsDir() {
+		return bundle
+	}
+	// Side by side NDKs are in directories named after their
+	// revision, such as ndk/17.2.4988734.
+	fis, err := ioutil.ReadDir(filepath.Join(sdk, "ndk"))
+	if err != nil {
+		return ""
+	}
+	newest := ""
+	for _, fi := range fis {
+		if _, err := parseNDKRevision(fi.Name()); err != nil || !fi.IsDir() {
+			continue
+		}
+		if newest == "" || ndkRevisionLess(newest, fi.Name()) {
+			newest = fi.Name()
+		}
+	}
+	if newest == "" {
+		return ""
+	}
+	return filepath.Join(sdk, "ndk", newest)
+}
+
+// openNDK returns the NDK at root, or an error naming the required
+// revisions if it is not compatible with gomobile.
+func openNDK(root string) (*ndk, error) {
+	rev, err := readNDKRevision(root)
+	if err != nil {
+		return nil, err
+	}
+	major, err := parseNDKRevision(rev)
+	if err != nil {
+		return nil, fmt.Errorf("NDK in %s: %v", root, err)
+	}
+	if major < minNDKRevision || major > maxNDKRevision {
+		return nil, fmt.Errorf("NDK in %s is revision %s, gomobile requires an NDK from r%d to r%d", root, rev, minNDKRevision, maxNDKRevision)
+	}
+	n := &ndk{root: root, revision: rev}
+	for _, path := range []string{n.gcc(), n.sysroot()} {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("NDK in %s is incomplete: %s is missing", root, path)
+		}
+	}
+	return n, nil
+}
+
+// readNDKRevision reads the revision of the NDK at root: the
+// Pkg.Revision of source.properties, in the NDKs from r11, or the
+// first word of RELEASE.TXT in older NDKs.
+func readNDKRevision(root string) (string, error) {
+	if data, err := ioutil.ReadFile(filepath.Join(root, "source.properties")); err == nil {
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			kv := strings.SplitN(s.Text(), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "Pkg.Revision" {
+				return strings.TrimSpace(kv[1]), nil
+			}
+		}
+		return "", fmt.Errorf("NDK in %s: no Pkg.Revision in source.properties", root)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(root, "RELEASE.TXT"))
+	if err != nil {
+		return "", fmt.Errorf("no NDK in %s", root)
+	}
+	if f := strings.Fields(string(data)); len(f) > 0 {
+		return f[0], nil
+	}
+	return "", fmt.Errorf("NDK in %s: empty RELEASE.TXT", root)
+}
+
+var ndkRevisionRE = regexp.MustCompile(`^(?:r(\d+)[a-z]?|(\d+)\.\d+\.\d+)(?:-.*)?$`)
+
+// parseNDKRevision returns the major revision of an NDK revision such
+// as r10d or 17.2.4988734.
+func parseNDKRevision(rev string) (int, error) {
+	m := ndkRevisionRE.FindStringSubmatch(rev)
+	if m == nil {
+		return 0, fmt.Errorf("unknown NDK revision %q", rev)
+	}
+	major := m[1]
+	if major == "" {
+		major = m[2]
+	}
+	return strconv.Atoi(major)
+}
+
+// ndkRevisionLess reports whether the revision a of the form
+// 17.2.4988734 is older than b.
+func ndkRevisionLess(a, b string) bool {
+	fa, fb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(fa) && i < len(fb); i++ {
+		na, _ := strconv.Atoi(fa[i])
+		nb, _ := strconv.Atoi(fb[i])
+		if na != nb {
+			return na < nb
+		}
+	}
+	return len(fa) < len(fb)
+}
+
+// ndkHost returns the name of the prebuilt host tools of the NDK.
+func ndkHost() string {
+	if goos == "windows" && ndkarch != "x86_64" {
+		return "windows"
+	}
+	return goos + "-" + ndkarch
+}
+
+// bin returns the directory of the GCC tools of the NDK.
+func (n *ndk) bin() string {
+	return filepath.Join(n.root, "toolchains", ndkGCC, "prebuilt", ndkHost(), "bin")
+}
+
+func (n *ndk) gcc() string {
+	name := "arm-linux-androideabi-gcc"
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(n.bin(), name)
+}
+
+// sysroot returns the directory of the headers and libraries of the
+// android/arm platform the code is compiled against.
+func (n *ndk) sysroot() string {
+	return filepath.Join(n.root, "platforms", ndkPlatform, "arch-arm")
+}
+
+// gdbserver returns the path of the gdbserver packed into debug APKs.
+func (n *ndk) gdbserver() string {
+	return filepath.Join(n.root, "prebuilt", "android-arm", "gdbserver", "gdbserver")
+}
+
+// ndkBin returns the directory of the C compiler and the binutils for
+// android/arm: the GCC of the system NDK, or of the NDK of gomobile
+// init in ndkccpath.
+func ndkBin() string {
+	if systemNDK != nil {
+		return systemNDK.bin()
+	}
+	return filepath.Join(ndkccpath, "arm", "bin")
+}
+
+// ndkCflags returns the flags compiling and linking against the
+// platform of the system NDK. The GCC of the NDK of gomobile init has
+// its own sysroot.
+func ndkCflags() []string {
+	if systemNDK == nil {
+		return nil
+	}
+	return []string{"--sysroot=" + systemNDK.sysroot()}
+}
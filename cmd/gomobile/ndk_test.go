@@ -0,0 +1,159 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeNDK writes the layout of an NDK of revision rev in dir: its
+// revision file, the GCC of android/arm and the platform sysroot.
+func fakeNDK(t *testing.T, dir, rev string) {
+	n := &ndk{root: dir}
+	for _, d := range []string{n.bin(), n.sysroot()} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(n.gcc(), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	name, data := "source.properties", "Pkg.Desc = Android NDK\nPkg.Revision = "+rev+"\n"
+	if strings.HasPrefix(rev, "r") {
+		name, data = "RELEASE.TXT", rev+" (64-bit)\n"
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindSystemNDK(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("ANDROID_NDK_HOME", os.Getenv("ANDROID_NDK_HOME"))
+	defer os.Setenv("ANDROID_HOME", os.Getenv("ANDROID_HOME"))
+	defer func() { systemNDK = nil }()
+
+	r10d := filepath.Join(dir, "android-ndk-r10d")
+	fakeNDK(t, r10d, "r10d")
+	r21 := filepath.Join(dir, "android-ndk-r21")
+	fakeNDK(t, r21, "21.4.7075529")
+	incomplete := filepath.Join(dir, "android-ndk-r16b")
+	fakeNDK(t, incomplete, "16.1.4479499")
+	if err := os.RemoveAll((&ndk{root: incomplete}).sysroot()); err != nil {
+		t.Fatal(err)
+	}
+	sdk := filepath.Join(dir, "sdk")
+	fakeNDK(t, filepath.Join(sdk, "ndk", "16.1.4479499"), "16.1.4479499")
+	fakeNDK(t, filepath.Join(sdk, "ndk", "17.2.4988734"), "17.2.4988734")
+	fakeNDK(t, filepath.Join(sdk, "ndk", "9.0.0"), "9.0.0")
+	bundleSDK := filepath.Join(dir, "bundle-sdk")
+	fakeNDK(t, filepath.Join(bundleSDK, "ndk-bundle"), "r10e")
+	oldSDK := filepath.Join(dir, "old-sdk")
+	fakeNDK(t, filepath.Join(oldSDK, "ndk-bundle"), "21.4.7075529")
+
+	tests := []struct {
+		ndkHome, sdk string
+		root, rev    string // the NDK found, if any
+		err          string // the error, if any
+	}{
+		{ndkHome: "", sdk: "", root: ""},
+		{ndkHome: r10d, sdk: sdk, root: r10d, rev: "r10d"},
+		{ndkHome: r21, err: "revision 21.4.7075529, gomobile requires an NDK from r10 to r17"},
+		{ndkHome: incomplete, err: "is incomplete"},
+		{ndkHome: filepath.Join(dir, "none"), err: "no NDK in"},
+		{sdk: sdk, root: filepath.Join(sdk, "ndk", "17.2.4988734"), rev: "17.2.4988734"},
+		{sdk: bundleSDK, root: filepath.Join(bundleSDK, "ndk-bundle"), rev: "r10e"},
+		// An incompatible NDK of the SDK is ignored.
+		{sdk: oldSDK, root: ""},
+		{sdk: filepath.Join(dir, "empty-sdk"), root: ""},
+	}
+	for _, tt := range tests {
+		os.Setenv("ANDROID_NDK_HOME", tt.ndkHome)
+		os.Setenv("ANDROID_HOME", tt.sdk)
+		err := findSystemNDK()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ANDROID_NDK_HOME=%s: error %v, want %q", tt.ndkHome, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ANDROID_NDK_HOME=%q ANDROID_HOME=%q: %v", tt.ndkHome, tt.sdk, err)
+			continue
+		}
+		switch {
+		case tt.root == "" && systemNDK != nil:
+			t.Errorf("ANDROID_NDK_HOME=%q ANDROID_HOME=%q: found NDK %+v, want none", tt.ndkHome, tt.sdk, *systemNDK)
+		case tt.root != "" && systemNDK == nil:
+			t.Errorf("ANDROID_NDK_HOME=%q ANDROID_HOME=%q: no NDK, want %s", tt.ndkHome, tt.sdk, tt.root)
+		case tt.root != "" && (systemNDK.root != tt.root || systemNDK.revision != tt.rev):
+			t.Errorf("ANDROID_NDK_HOME=%q ANDROID_HOME=%q: found NDK %s (%s), want %s (%s)", tt.ndkHome, tt.sdk, systemNDK.root, systemNDK.revision, tt.root, tt.rev)
+		}
+	}
+}
+
+func TestSystemNDKEnv(t *testing.T) {
+	defer func(path string) {
+		systemNDK = nil
+		ndkccpath = path
+	}(ndkccpath)
+	ndkccpath = "/gomobile/android-ndk-r10d"
+	if got, want := ndkBin(), filepath.Join(ndkccpath, "arm", "bin"); got != want {
+		t.Errorf("ndkBin() = %s, want %s", got, want)
+	}
+
+	systemNDK = &ndk{root: "/opt/ndk", revision: "r10d"}
+	if got, want := ndkBin(), filepath.Join("/opt/ndk", "toolchains", ndkGCC, "prebuilt", ndkHost(), "bin"); got != want {
+		t.Errorf("ndkBin() = %s, want %s", got, want)
+	}
+	sysroot := "--sysroot=" + filepath.Join("/opt/ndk", "platforms", ndkPlatform, "arch-arm")
+	for _, kv := range cgoEnv("arm") {
+		if !strings.HasPrefix(kv, "CGO_CFLAGS=") && !strings.HasPrefix(kv, "CGO_LDFLAGS=") {
+			continue
+		}
+		if !strings.Contains(kv, sysroot) {
+			t.Errorf("%s does not contain %s", kv, sysroot)
+		}
+	}
+	if env := cgoEnv("arm"); len(env) != 2 {
+		t.Errorf("cgoEnv(arm) = %q, want CGO_CFLAGS and CGO_LDFLAGS", env)
+	}
+}
+
+func TestParseNDKRevision(t *testing.T) {
+	tests := []struct {
+		rev   string
+		major int
+	}{
+		{"r10d", 10},
+		{"r10", 10},
+		{"r10e-rc4", 10},
+		{"11.2.2725575", 11},
+		{"17.2.4988734", 17},
+		{"21.4.7075529-beta1", 21},
+		{"ndk", -1},
+		{"17", -1},
+	}
+	for _, tt := range tests {
+		major, err := parseNDKRevision(tt.rev)
+		if tt.major < 0 {
+			if err == nil {
+				t.Errorf("parseNDKRevision(%q) = %d, want error", tt.rev, major)
+			}
+			continue
+		}
+		if err != nil || major != tt.major {
+			t.Errorf("parseNDKRevision(%q) = %d, %v, want %d", tt.rev, major, err, tt.major)
+		}
+	}
+}
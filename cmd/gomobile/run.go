@@ -0,0 +1,266 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var cmdRun = &command{
+	run:   runRun,
+	Name:  "run",
+	Usage: "[-o output] [-r] [-d] [-androidmanifest file] [-androidtarget phone|tv|wear] [-appid id] [-assets dirs] [-icon file] [-timeout d] [-logcat=false] [build flags] [package]",
+	Short: "compile android APK, install and run it on device",
+	Long: `
+Run compiles and installs the app named by the import path on the
+attached mobile device, launches its main activity, and streams the
+app's log output until interrupted.
+
+The -logcat flag controls whether the log output of the app process is
+streamed after launch. It is on by default. With -logcat=false, run
+exits once the app has been started.
+
+The -r, -d and -timeout flags are as for the install command. The log
+output is streamed without a time limit.
+
+This command requires the 'adb' tool on the PATH.
+
+See the build command help for common flags and common behavior.
+`,
+}
+
+var runLogcat bool // -logcat
+
+func init() {
+	cmdRun.flag.BoolVar(&runLogcat, "logcat", true, "stream logcat output of the app")
+	addInstallFlags(cmdRun)
+}
+
+func runRun(cmd *command) error {
+	if !buildN {
+		if err := checkDevice(); err != nil {
+			return err
+		}
+	}
+	if err := runInstall(cmd); err != nil {
+		return err
+	}
+
+	// All-Go apps are hosted by NativeActivity, see mergeManifest.
+	component := appPkgPath + "/android.app.NativeActivity"
+	if runLogcat {
+		if err := adb("logcat", "-c"); err != nil {
+			return err
+		}
+	}
+	start, done := adbCommand(adbTimeout, `shell`, `am`, `start`, `-n`, component)
+	if buildX {
+		printcmd("%s", strings.Join(start.Args, " "))
+	}
+	if buildN {
+		done(nil)
+		return nil
+	}
+	out, err := start.CombinedOutput()
+	if verbose() {
+		os.Stderr.Write(out)
+	}
+	if err := done(err); err != nil {
+		return fmt.Errorf("adb shell am start failed: %v", err)
+	}
+	// am does not report failure in its exit code.
+	if bytes.Contains(out, []byte("Error")) {
+		return fmt.Errorf("failed to start %s:\n%s", component, out)
+	}
+	if !runLogcat {
+		return nil
+	}
+
+	pid, err := appPid(appPkgPath)
+	if err != nil {
+		return err
+	}
+	return streamLogcat(os.Stdout, pid)
+}
+
+// checkDevice reports an error if no device is attached.
+func checkDevice() error {
+	c, done := adbCommand(adbTimeout, `get-state`)
+	out, err := c.Output()
+	if err := done(err); err != nil {
+		if _, ok := err.(*adbTimeoutError); ok {
+			return err
+		}
+		if _, lerr := exec.LookPath("adb"); lerr != nil {
+			return errors.New("this command requires the 'adb' tool on the PATH")
+		}
+		return errors.New("no android device attached, connect a device or start an emulator")
+	}
+	if state := strings.TrimSpace(string(out)); state != "device" {
+		return fmt.Errorf("android device is not ready (state %q)", state)
+	}
+	return nil
+}
+
+// adb runs an adb command, printing it under -x.
+func adb(args ...string) error {
+	_, err := adbDevice("", args...)
+	return err
+}
+
+// adbDevice runs an adb command on the device with the given serial
+// number, or on the only attached device if serial is empty, and
+// returns its combined output. Unless -v shows it as it runs, the
+// output of adb is included in the returned error.
+func adbDevice(serial string, args ...string) ([]byte, error) {
+	if serial != "" {
+		args = append([]string{"-s", serial}, args...)
+	}
+	c, done := adbCommand(adbTimeout, args...)
+	if buildX {
+		printcmd("%s", strings.Join(c.Args, " "))
+	}
+	if buildN {
+		done(nil)
+		return nil, nil
+	}
+	out := new(bytes.Buffer)
+	c.Stdout = out
+	c.Stderr = out
+	if verbose() {
+		c.Stdout = io.MultiWriter(out, os.Stdout)
+		c.Stderr = io.MultiWriter(out, os.Stderr)
+	}
+	if err := done(c.Run()); err != nil {
+		if _, ok := err.(*adbTimeoutError); ok {
+			return out.Bytes(), err
+		}
+		if msg := strings.TrimSpace(out.String()); msg != "" && !verbose() {
+			return out.Bytes(), fmt.Errorf("%s failed: %v\n%s", strings.Join(c.Args, " "), err, msg)
+		}
+		return out.Bytes(), fmt.Errorf("%s failed: %v", strings.Join(c.Args, " "), err)
+	}
+	return out.Bytes(), nil
+}
+
+// An adbTimeoutError reports an adb command killed for running longer
+// than -timeout.
+type adbTimeoutError struct {
+	args    []string
+	timeout time.Duration
+}
+
+func (e *adbTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v, check the connection to the device or raise -timeout", strings.Join(e.args, " "), e.timeout)
+}
+
+// adbCommand returns the command running adb with args, which is killed
+// if it runs longer than timeout, unless timeout is 0. Once the command
+// has run, done must be called with its error, and returns the error to
+// report: an *adbTimeoutError if it was killed.
+func adbCommand(timeout time.Duration, args ...string) (c *exec.Cmd, done func(error) error) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	c = exec.CommandContext(ctx, `adb`, args...)
+	// The adb server started by a command may hold its output open.
+	c.WaitDelay = time.Second
+	done = func(err error) error {
+		defer cancel()
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return &adbTimeoutError{args: c.Args, timeout: timeout}
+		}
+		return err
+	}
+	return c, done
+}
+
+// appPid returns the process ID of the running app. The app process may
+// take a moment to appear after am start, so appPid polls for a while.
+func appPid(appPkg string) (string, error) {
+	for i := 0; i < 20; i++ {
+		c, done := adbCommand(adbTimeout, `shell`, `ps`)
+		out, err := c.Output()
+		if err := done(err); err != nil {
+			return "", fmt.Errorf("adb shell ps failed: %v", err)
+		}
+		if pid := psPid(out, appPkg); pid != "" {
+			return pid, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return "", fmt.Errorf("cannot find running process for %s", appPkg)
+}
+
+// psPid finds the PID of the named process in the output of the
+// android ps tool. The PID is the second column and the process name
+// is the last.
+func psPid(ps []byte, name string) string {
+	s := bufio.NewScanner(bytes.NewReader(ps))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) > 2 && f[len(f)-1] == name {
+			return f[1]
+		}
+	}
+	return ""
+}
+
+// logcatPidRE matches the process ID in logcat's brief format, e.g.
+//
+//	I/GoLog   ( 1234): hello
+var logcatPidRE = regexp.MustCompile(`^./[^(]*\(\s*(\d+)\)`)
+
+// streamLogcat copies the log lines of the process pid to w
+// until adb exits or the user interrupts it. The stream has no time
+// limit.
+func streamLogcat(w io.Writer, pid string) error {
+	logcat, done := adbCommand(0, `logcat`, `-v`, `brief`)
+	r, err := logcat.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := logcat.Start(); err != nil {
+		return err
+	}
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+	interrupted := make(chan struct{})
+	go func() {
+		<-sigc
+		close(interrupted)
+		logcat.Process.Kill()
+	}()
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if m := logcatPidRE.FindStringSubmatch(line); m != nil && m[1] == pid {
+			fmt.Fprintln(w, line)
+		}
+	}
+	if err := done(logcat.Wait()); err != nil {
+		select {
+		case <-interrupted:
+		default:
+			return fmt.Errorf("adb logcat failed: %v", err)
+		}
+	}
+	return nil
+}
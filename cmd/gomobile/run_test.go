@@ -0,0 +1,41 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+const psOutput = `USER     PID   PPID  VSIZE  RSS     WCHAN    PC         NAME
+root      1     0     656    520   c00fe8d8 0000e2b0 S /init
+u0_a52    1337  122   498320 29080 ffffffff b6f0d1e4 S org.golang.todo.basic
+u0_a53    1338  122   498320 29080 ffffffff b6f0d1e4 S org.golang.todo.basic2
+`
+
+func TestPsPid(t *testing.T) {
+	if got, want := psPid([]byte(psOutput), "org.golang.todo.basic"), "1337"; got != want {
+		t.Errorf("psPid=%q, want %q", got, want)
+	}
+	if got := psPid([]byte(psOutput), "org.golang.todo.missing"); got != "" {
+		t.Errorf("psPid=%q for a missing process, want empty", got)
+	}
+}
+
+func TestLogcatPid(t *testing.T) {
+	tests := []struct {
+		line, pid string
+	}{
+		{"I/GoLog   ( 1337): hello", "1337"},
+		{"E/GoStdio (  42): panic: boom", "42"},
+		{"--------- beginning of main", ""},
+	}
+	for _, tt := range tests {
+		pid := ""
+		if m := logcatPidRE.FindStringSubmatch(tt.line); m != nil {
+			pid = m[1]
+		}
+		if pid != tt.pid {
+			t.Errorf("%q: pid=%q, want %q", tt.line, pid, tt.pid)
+		}
+	}
+}
@@ -4,7 +4,10 @@
 
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type stringsFlag []string
 
@@ -57,6 +60,23 @@ func splitQuotedFields(s string) ([]string, error) {
 	return f, nil
 }
 
+// quoteFields joins fields into a string that splitQuotedFields, and the
+// go tool, split back into the same fields.
+func quoteFields(fields []string) string {
+	q := make([]string, len(fields))
+	for i, f := range fields {
+		switch {
+		case f != "" && !strings.ContainsAny(f, " \t\n\r\"'"):
+			q[i] = f
+		case !strings.ContainsRune(f, '\''):
+			q[i] = "'" + f + "'"
+		default:
+			q[i] = `"` + f + `"`
+		}
+	}
+	return strings.Join(q, " ")
+}
+
 func (v *stringsFlag) String() string {
 	return "<stringsFlag>"
 }
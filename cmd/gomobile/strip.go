@@ -0,0 +1,99 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// buildStrip is the value of the -strip flag: "true" to run the NDK strip
+// on the shared libraries built, "ldflags" to have the linker omit the
+// symbol table and DWARF instead, "false" to keep them, or "" when the
+// flag is not set.
+var buildStrip stripFlag
+
+// stripFlag is a boolean flag that also accepts the value ldflags.
+type stripFlag string
+
+func (f *stripFlag) Set(s string) error {
+	if s == "ldflags" {
+		*f = "ldflags"
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("must be true, false or ldflags")
+	}
+	*f = stripFlag(strconv.FormatBool(v))
+	return nil
+}
+
+func (f *stripFlag) String() string   { return string(*f) }
+func (f *stripFlag) IsBoolFlag() bool { return true }
+
+// stripMode returns how the shared libraries of the build are stripped:
+// as set by -strip, or else not at all with -debug, with the NDK strip
+// for release builds, signed with a -keystore key, and not at all for
+// debug builds.
+func stripMode() string {
+	switch {
+	case buildStrip != "":
+		return string(buildStrip)
+	case buildDebug:
+		return "false"
+	case buildKeystore != "":
+		return "true"
+	}
+	return "false"
+}
+
+// stripLdflags returns the flags added to -ldflags by -strip=ldflags.
+func stripLdflags() []string {
+	if stripMode() == "ldflags" {
+		return []string{"-s", "-w"}
+	}
+	return nil
+}
+
+// stripLib strips the shared library at path with the strip tool of the
+// NDK in ndkccbin, when -strip calls for it. Only the symbols not needed
+// for relocation are removed: the dynamic symbol table, holding the JNI
+// and NativeActivity entry points, is kept.
+func stripLib(ndkccbin, path string) error {
+	if stripMode() != "true" {
+		return nil
+	}
+	var before int64
+	if verbose() && !buildN {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		before = fi.Size()
+	}
+	cmd := exec.Command(filepath.Join(ndkccbin, "arm-linux-androideabi-strip"), "--strip-unneeded", path)
+	if buildX {
+		printcmd("%s", strings.Join(cmd.Args, " "))
+	}
+	if buildN {
+		return nil
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("strip %s failed: %v\n%s", filepath.Base(path), err, out)
+	}
+	if verbose() {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		logf(levelVerbose, "strip %s: %d -> %d bytes", filepath.Base(path), before, fi.Size())
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildStrip(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	defer func() {
+		buildStrip = ""
+		buildKeystore = ""
+		buildDebug = false
+	}()
+
+	const strip = "$NDKCCPATH/arm/bin/arm-linux-androideabi-strip --strip-unneeded libapp.so"
+	tests := []struct {
+		args     []string
+		keystore string
+		strip    bool
+		ldflags  string
+	}{
+		{nil, "", false, "-shared"},
+		{nil, "release.keystore", true, "-shared"},
+		{[]string{"-strip"}, "", true, "-shared"},
+		{[]string{"-strip=false"}, "release.keystore", false, "-shared"},
+		{[]string{"-strip=ldflags"}, "", false, "-shared -s -w"},
+		{[]string{"-strip=ldflags", "-ldflags", "-X main.v=1"}, "", false, "-shared -X main.v=1 -s -w"},
+		// -debug keeps the symbols, even of a release build.
+		{[]string{"-debug"}, "release.keystore", false, "-shared"},
+	}
+	for _, tt := range tests {
+		buildStrip = ""
+		buildDebug = false
+		buildLdflags = nil
+		buildKeystore = tt.keystore
+		if err := cmdBuild.flag.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		if err := gobuild("example.com/app", "libapp.so"); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if got := strings.Contains(out, strip); got != tt.strip {
+			t.Errorf("%q, keystore %q: strip run %v, want %v:\n%s", tt.args, tt.keystore, got, tt.strip, out)
+		}
+		if want := " -ldflags=" + tt.ldflags + " -o libapp.so "; !strings.Contains(out, want) {
+			t.Errorf("%q: command does not contain %q:\n%s", tt.args, want, out)
+		}
+	}
+
+	// Apps that are not shared libraries are not stripped.
+	buildStrip = "true"
+	buf.Reset()
+	if err := gobuild("example.com/app", ""); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "strip") {
+		t.Errorf("executable stripped:\n%s", buf.String())
+	}
+
+	if err := buildStrip.Set("sometimes"); err == nil {
+		t.Error("-strip=sometimes: got nil error")
+	}
+}
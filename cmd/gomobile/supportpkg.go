@@ -0,0 +1,113 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mobile/bind"
+)
+
+// checkSupportPkg checks the Java package name of -supportpkg: a
+// dot-separated list of Java identifiers that are not keywords.
+func checkSupportPkg(name string) error {
+	for _, id := range strings.Split(name, ".") {
+		if id == "" || bind.IsJavaKeyword(id) {
+			return fmt.Errorf("-supportpkg %q is not a valid Java package name", name)
+		}
+		for i, r := range id {
+			letter := r < 0x80 && isASCIILetter(byte(r))
+			if !letter && r != '_' && r != '$' && (i == 0 || r < '0' || r > '9') {
+				return fmt.Errorf("-supportpkg %q is not a valid Java package name", name)
+			}
+		}
+	}
+	return nil
+}
+
+// supportPkg returns the Java package of the support classes, go unless
+// relocated by -supportpkg.
+func supportPkg() string {
+	if bindSupportPkg == "" {
+		return "go"
+	}
+	return bindSupportPkg
+}
+
+// supportLib returns the name of the shared library of the bindings,
+// loaded by the Go class: gojni, or a name of its own for a library
+// whose support classes are relocated, so that the shared libraries of
+// several bound libraries do not collide either.
+func supportLib() string {
+	if bindSupportPkg == "" {
+		return "gojni"
+	}
+	return "gojni_" + jniMangle(bindSupportPkg)
+}
+
+// jniMangle mangles the Java name, such as a package name, as in the
+// names of the C functions implementing native methods.
+func jniMangle(name string) string {
+	var buf bytes.Buffer
+	for _, r := range name {
+		switch {
+		case r == '.' || r == '/':
+			buf.WriteByte('_')
+		case r == '_':
+			buf.WriteString("_1")
+		case r < 0x80 && (isASCIILetter(byte(r)) || '0' <= r && r <= '9'):
+			buf.WriteRune(r)
+		default:
+			fmt.Fprintf(&buf, "_0%04x", r)
+		}
+	}
+	return buf.String()
+}
+
+// supportCflags returns the C compiler flags naming the Java package of
+// the support classes to their native code, in the bindings and the app
+// package, when -supportpkg relocates them.
+func supportCflags() []string {
+	if bindSupportPkg == "" {
+		return nil
+	}
+	return []string{
+		"-DGOMOBILE_SUPPORT_PKG=" + jniMangle(bindSupportPkg),
+		`-DGOMOBILE_SUPPORT_PATH="` + strings.Replace(bindSupportPkg, ".", "/", -1) + `"`,
+	}
+}
+
+// supportSource returns the Java source of the support class src, one
+// of supportSources, in repo, relocated to the package of -supportpkg.
+func supportSource(repo, src string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(repo, filepath.FromSlash(src)))
+	if err != nil {
+		return nil, err
+	}
+	if bindSupportPkg == "" {
+		return data, nil
+	}
+	return relocateSupport(data, bindSupportPkg, supportLib()), nil
+}
+
+var supportPkgDecl = regexp.MustCompile(`(?m)^package go;$`)
+
+// relocateSupport returns the source of a support class moved to the
+// Java package pkg, loading the shared library lib.
+func relocateSupport(src []byte, pkg, lib string) []byte {
+	src = supportPkgDecl.ReplaceAllLiteral(src, []byte("package "+pkg+";"))
+	return bytes.Replace(src, []byte(`System.loadLibrary("gojni");`), []byte(`System.loadLibrary("`+lib+`");`), 1)
+}
+
+// supportFile returns the path of the support class src relative to the
+// Java source directory.
+func supportFile(src string) string {
+	return filepath.Join(filepath.FromSlash(strings.Replace(supportPkg(), ".", "/", -1)), filepath.Base(src))
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var buildTargets string // -targets
+
+// A goTarget is a GOOS/GOARCH pair of -targets, built with the C
+// compiler of the environment instead of the NDK toolchain of gomobile.
+type goTarget struct {
+	goos, goarch string
+	cc, cxx      string // C and C++ compilers for cgo; cxx may be empty
+}
+
+func (t goTarget) String() string { return t.goos + "/" + t.goarch }
+
+// libDir returns the directory under lib/ of the APK holding the
+// libraries built for t.
+func (t goTarget) libDir() string { return apkLibDir(t.goarch) }
+
+// env returns the variables of the environment of the go command
+// building for t. Unlike the android/arm environment of gobuild, the go
+// tools of the host compile the code, without the toolexec of gomobile
+// init.
+func (t goTarget) env(gopath string) []string {
+	env := []string{
+		`GOOS=` + t.goos,
+		`GOARCH=` + t.goarch,
+		`CGO_ENABLED=1`,
+		`CC=` + t.cc,
+	}
+	if t.cxx != "" {
+		env = append(env, `CXX=`+t.cxx)
+	}
+	env = append(env,
+		`GOROOT=`+goEnv("GOROOT"),
+		`GOPATH=`+gopath,
+	)
+	return append(env, cgoFlagsEnv(t.goarch, nil)...)
+}
+
+// targetCompilerVar returns the name of the environment variable
+// naming the C compiler of goos/goarch, as set for cmd/dist when
+// building Go itself: CC_FOR_android_riscv64. The C++ compiler is
+// named by the variable with the CXX_FOR_ prefix.
+func targetCompilerVar(prefix, goos, goarch string) string {
+	return prefix + "_FOR_" + goos + "_" + goarch
+}
+
+// parseTargets returns the targets in the comma-separated list of the
+// -targets flag, or nil if the list is empty. Any GOOS/GOARCH pair is
+// accepted, but cgo must be able to compile for it: each target needs a
+// C compiler, named by its CC_FOR_goos_goarch variable.
+func parseTargets(list string) ([]goTarget, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var targets []goTarget
+	seen := make(map[string]string) // lib directory -> target
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		i := strings.Index(s, "/")
+		if i <= 0 || i == len(s)-1 || strings.Count(s, "/") != 1 {
+			return nil, fmt.Errorf("invalid -targets entry %q, want GOOS/GOARCH such as android/arm64", s)
+		}
+		t := goTarget{goos: s[:i], goarch: s[i+1:]}
+		if prev, ok := seen[t.libDir()]; ok {
+			if prev == t.String() {
+				continue
+			}
+			return nil, fmt.Errorf("-targets %s and %s would both be packed in lib/%s", prev, t, t.libDir())
+		}
+		seen[t.libDir()] = t.String()
+
+		ccVar := targetCompilerVar("CC", t.goos, t.goarch)
+		cc := os.Getenv(ccVar)
+		if cc == "" {
+			return nil, fmt.Errorf("-targets %s: no C compiler for cgo, set %s", t, ccVar)
+		}
+		var err error
+		if t.cc, err = exec.LookPath(cc); err != nil {
+			return nil, fmt.Errorf("-targets %s: C compiler %s=%s: %v", t, ccVar, cc, err)
+		}
+		cxxVar := targetCompilerVar("CXX", t.goos, t.goarch)
+		if cxx := os.Getenv(cxxVar); cxx != "" {
+			if t.cxx, err = exec.LookPath(cxx); err != nil {
+				return nil, fmt.Errorf("-targets %s: C++ compiler %s=%s: %v", t, cxxVar, cxx, err)
+			}
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("-targets lists no target")
+	}
+	return targets, nil
+}
+
+// checkTargetFlags reports an error if a flag of the build needs the
+// android/arm toolchain of gomobile, which -targets replaces.
+func checkTargetFlags() error {
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{buildMode != "default", "-buildmode=" + buildMode},
+		{buildDebug, "-debug: gdbserver is only installed for android/arm"},
+	} {
+		if f.set {
+			return fmt.Errorf("-targets cannot be used with %s", f.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCompiler writes an executable file standing for the C compiler of
+// goos/goarch to dir, names it in CC_FOR_goos_goarch, and returns its
+// path. The returned function restores the variable.
+func fakeCompiler(t *testing.T, dir, goos, goarch string) (string, func()) {
+	path := filepath.Join(dir, goos+"-"+goarch+"-gcc")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	name := targetCompilerVar("CC", goos, goarch)
+	old := os.Getenv(name)
+	os.Setenv(name, path)
+	return path, func() { os.Setenv(name, old) }
+}
+
+func TestParseTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cc, restore := fakeCompiler(t, dir, "android", "riscv64")
+	defer restore()
+	old := os.Getenv("CC_FOR_android_mips64")
+	defer os.Setenv("CC_FOR_android_mips64", old)
+	os.Setenv("CC_FOR_android_mips64", filepath.Join(dir, "missing-gcc"))
+
+	if targets, err := parseTargets(""); targets != nil || err != nil {
+		t.Errorf(`parseTargets("") = %v, %v, want nil, nil`, targets, err)
+	}
+	targets, err := parseTargets("android/riscv64, android/riscv64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("got %d targets, want 1: %v", len(targets), targets)
+	}
+	if got := targets[0]; got.goos != "android" || got.goarch != "riscv64" || got.cc != cc {
+		t.Errorf("got target %s with CC %s, want android/riscv64 with CC %s", got, got.cc, cc)
+	}
+	if got, want := targets[0].libDir(), "riscv64"; got != want {
+		t.Errorf("android/riscv64 is packed in lib/%s, want lib/%s", got, want)
+	}
+
+	for _, tt := range []struct {
+		list, want string
+	}{
+		{"android", "invalid -targets entry"},
+		{"android/", "invalid -targets entry"},
+		{"/arm64", "invalid -targets entry"},
+		{"android/arm/v7", "invalid -targets entry"},
+		{",", "lists no target"},
+		{"android/riscv64,linux/riscv64", "both be packed in lib/riscv64"},
+		{"android/ppc64le", "set CC_FOR_android_ppc64le"},
+		{"android/mips64", "CC_FOR_android_mips64="},
+	} {
+		_, err := parseTargets(tt.list)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseTargets(%q): got error %v, want %q", tt.list, err, tt.want)
+		}
+	}
+}
+
+func TestBuildTargets(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	buildN, buildX = false, false // set by -dry-run
+	defer func() {
+		buildPlan = false
+		buildTargets = ""
+		*buildO = ""
+	}()
+
+	dir, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cc, restore := fakeCompiler(t, dir, "android", "riscv64")
+	defer restore()
+	const src = "package main\n\nimport \"golang.org/x/mobile/app\"\n\nfunc main() { app.Run(app.Callbacks{}) }\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	if err := cmdBuild.flag.Parse([]string{"-dry-run", "-targets", "android/riscv64"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBuild(cmdBuild); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"GOOS=android GOARCH=riscv64 CGO_ENABLED=1 CC=" + cc + " ",
+		"# pack lib/riscv64/lib",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan does not contain %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, " go build ") && strings.Contains(line, "-toolexec") {
+			t.Errorf("target built with the toolexec of gomobile init:\n%s", line)
+		}
+	}
+	if strings.Contains(out, "lib/armeabi/") {
+		t.Errorf("plan packs an android/arm library:\n%s", out)
+	}
+
+	buildPlan = false
+	if err := cmdBuild.flag.Parse([]string{"-dry-run", "-targets", "android/riscv64", "-debug"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { buildDebug = false }()
+	if err := runBuild(cmdBuild); err == nil {
+		t.Error("-targets -debug: got nil error")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var cmdVersion = &command{
+	run:   runVersion,
+	Name:  "version",
+	Usage: "[-json]",
+	Short: "print version and toolchain information",
+	Long: `
+Version prints the revision of the golang.org/x/mobile repository
+gomobile was built from, the Go version used to build gomobile, the Go
+tool found on $PATH, and the state of the Android toolchain installed
+by 'gomobile init': the NDK version and directory, and the OpenGL ES
+headers it provides.
+
+If the toolchain is missing, partially installed or was installed for
+a different Go version, version says so and suggests running
+'gomobile init'.
+
+The -json flag prints the same information as a JSON object.
+`,
+}
+
+var versionJSON bool // -json
+
+func init() {
+	cmdVersion.flag.BoolVar(&versionJSON, "json", false, "print version information as JSON")
+}
+
+// versionInfo is the information printed by gomobile version.
+type versionInfo struct {
+	Gomobile  string   // revision of golang.org/x/mobile, or "unknown"
+	GoBuild   string   // Go version gomobile was built with
+	Go        string   // output of go version, or empty
+	Toolchain string   // "installed", "outdated", "partial" or "missing"
+	NDK       string   // NDK version expected by gomobile
+	NDKPath   string   // NDK directory, or empty if missing
+	GLES      []string // OpenGL ES header directories in the NDK sysroot
+}
+
+func runVersion(cmd *command) error {
+	info := toolchainInfo()
+	if versionJSON {
+		out, err := json.MarshalIndent(info, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Printf("%s\n", out)
+		return err
+	}
+
+	fmt.Printf("gomobile version %s (built with %s)\n", info.Gomobile, info.GoBuild)
+	if info.Go != "" {
+		fmt.Printf("go tool: %s\n", info.Go)
+	} else {
+		fmt.Printf("go tool: not found\n")
+	}
+	switch info.Toolchain {
+	case "installed":
+		fmt.Printf("android toolchain: installed\n")
+	case "outdated":
+		fmt.Printf("android toolchain: out of date, run 'gomobile init'\n")
+	case "partial":
+		fmt.Printf("android toolchain: partially installed, run 'gomobile init'\n")
+	default:
+		fmt.Printf("android toolchain: not installed, run 'gomobile init'\n")
+	}
+	if info.NDKPath != "" {
+		fmt.Printf("android NDK: %s %s\n", info.NDK, info.NDKPath)
+	} else {
+		fmt.Printf("android NDK: %s not installed\n", info.NDK)
+	}
+	if len(info.GLES) > 0 {
+		fmt.Printf("OpenGL ES headers: %s\n", strings.Join(info.GLES, " "))
+	} else {
+		fmt.Printf("OpenGL ES headers: not installed\n")
+	}
+	return nil
+}
+
+// toolchainInfo inspects the installed toolchain. It does not fail:
+// anything it cannot find is reported as missing.
+func toolchainInfo() *versionInfo {
+	info := &versionInfo{
+		Gomobile:  mobileRevision(),
+		GoBuild:   runtime.Version(),
+		Toolchain: "missing",
+		NDK:       ndkVersion,
+	}
+	version, err := goVersion()
+	if err == nil {
+		info.Go = strings.TrimSpace(string(version))
+	}
+
+	gomobilepath := gomobileDir()
+	if gomobilepath == "" {
+		return info
+	}
+
+	info.Toolchain = "partial"
+	ndkpath := filepath.Join(gomobilepath, "android-"+ndkVersion)
+	if _, err := os.Stat(filepath.Join(ndkpath, "downloaded")); err == nil {
+		info.NDKPath = ndkpath
+	}
+	include := filepath.Join(ndkpath, "arm", "sysroot", "usr", "include")
+	for _, dir := range []string{"GLES", "GLES2", "GLES3"} {
+		if _, err := os.Stat(filepath.Join(include, dir)); err == nil {
+			info.GLES = append(info.GLES, dir)
+		}
+	}
+
+	installedVersion, err := ioutil.ReadFile(filepath.Join(gomobilepath, "version"))
+	if err != nil || info.NDKPath == "" {
+		return info
+	}
+	if version != nil && !bytes.Equal(installedVersion, version) {
+		info.Toolchain = "outdated"
+		return info
+	}
+	info.Toolchain = "installed"
+	return info
+}
+
+// mobileRevision returns the git revision and commit date of the
+// golang.org/x/mobile repository in GOPATH, or "unknown".
+func mobileRevision() string {
+	p, err := ctx.Import("golang.org/x/mobile/cmd/gomobile", "", build.FindOnly)
+	if err != nil {
+		return "unknown"
+	}
+	cmd := exec.Command("git", "log", "-n", "1", "--format=format:+%h %cd", "HEAD")
+	cmd.Dir = p.Dir
+	out, err := cmd.Output()
+	if err != nil || len(out) == 0 {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
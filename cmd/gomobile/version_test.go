@@ -0,0 +1,64 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestToolchainInfo(t *testing.T) {
+	version, err := goVersion()
+	if err != nil {
+		t.Skip(err)
+	}
+	gopath, err := ioutil.TempDir("", "gomobile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+
+	oldGopath := os.Getenv("GOPATH")
+	defer os.Setenv("GOPATH", oldGopath)
+	os.Setenv("GOPATH", gopath)
+
+	gomobilepath := filepath.Join(gopath, "pkg", "gomobile")
+	ndkpath := filepath.Join(gomobilepath, "android-"+ndkVersion)
+	write := func(name string, data []byte) {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := toolchainInfo(); got.Toolchain != "missing" || got.NDKPath != "" {
+		t.Errorf("no toolchain: got %q, NDKPath %q, want missing", got.Toolchain, got.NDKPath)
+	}
+
+	write(filepath.Join(gomobilepath, "version"), version)
+	if got := toolchainInfo(); got.Toolchain != "partial" {
+		t.Errorf("no NDK: got %q, want partial", got.Toolchain)
+	}
+
+	write(filepath.Join(ndkpath, "downloaded"), []byte("done"))
+	write(filepath.Join(ndkpath, "arm", "sysroot", "usr", "include", "GLES2", "gl2.h"), nil)
+	got := toolchainInfo()
+	if got.Toolchain != "installed" || got.NDKPath != ndkpath {
+		t.Errorf("installed: got %q, NDKPath %q, want installed, %q", got.Toolchain, got.NDKPath, ndkpath)
+	}
+	if want := []string{"GLES2"}; !reflect.DeepEqual(got.GLES, want) {
+		t.Errorf("GLES = %q, want %q", got.GLES, want)
+	}
+
+	write(filepath.Join(gomobilepath, "version"), []byte("go version go1.4 linux/amd64"))
+	if got := toolchainInfo(); got.Toolchain != "outdated" {
+		t.Errorf("old version: got %q, want outdated", got.Toolchain)
+	}
+}
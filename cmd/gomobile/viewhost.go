@@ -0,0 +1,402 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// viewHostMinSDK is the minimum API level of an app hosted in a view,
+// which added android.app.Fragment.
+const viewHostMinSDK = 11
+
+// buildViewHost builds the all-Go app pkg, for -activity=fragment, into
+// an AAR holding, instead of a NativeActivity, the Java classes showing
+// the app in the layout of an activity written in Java:
+//
+//	AndroidManifest.xml
+//	classes.jar, with go.GoView and go.GoFragment
+//	jni/armeabi-v7a/lib<name>.so
+//	assets/
+//	R.txt
+//	res/
+//
+// GoView is a GLSurfaceView whose renderer runs the app, and GoFragment
+// a Fragment showing a GoView.
+func buildViewHost(pkg *build.Package) error {
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{buildFormat != "apk", "-format " + buildFormat},
+		{buildABISplit.split(), "-abisplit"},
+		{buildKeystore != "", "-keystore"},
+		{buildAndroidManifest != "", "-androidmanifest"},
+		{buildIcon != "", "-icon"},
+		{buildTargets != "", "-targets"},
+		{buildDebug, "-debug"},
+		{buildMode == "shared", "-buildmode=shared"},
+	} {
+		if f.set {
+			return fmt.Errorf("-activity=fragment cannot be used with %s: it builds an AAR", f.name)
+		}
+	}
+	minSDK := buildMinSDK
+	if minSDK == 0 {
+		minSDK = viewHostMinSDK
+	}
+	if minSDK < viewHostMinSDK {
+		return fmt.Errorf("-activity=fragment requires a minimum API level of %d, -minsdk is %d", viewHostMinSDK, minSDK)
+	}
+	if buildAndroidAPI != 0 && buildAndroidAPI < minSDK {
+		return fmt.Errorf("-androidapi=%d is below the minimum API level %d", buildAndroidAPI, minSDK)
+	}
+
+	appID := buildAppID
+	if appID == "" {
+		appID = defaultAppID(pkg.ImportPath)
+	}
+	libName := path.Base(pkg.ImportPath)
+	if *buildO == "" {
+		*buildO = filepath.Base(pkg.Dir) + ".aar"
+	}
+	if !strings.HasSuffix(*buildO, ".aar") {
+		return fmt.Errorf("output file name %q does not end in '.aar'", *buildO)
+	}
+	assets, err := appAssets(pkg)
+	if err != nil {
+		return err
+	}
+
+	cleanup, err := makeWorkDir("gobuildaar-work-")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	planf("build %s into %s", pkg.ImportPath, *buildO)
+	planf("abi %s (GOARCH=arm GOARM=7), packed in jni/%s", androidABIs["arm"], androidABIs["arm"])
+
+	libPath := filepath.Join(tmpdir, "lib"+libName+".so")
+	if err := gobuild(pkg.ImportPath, libPath); err != nil {
+		return err
+	}
+	if err := checkLibSizes([]apkLib{{abi: androidABIs["arm"], name: "lib" + libName + ".so", path: libPath}}); err != nil {
+		return err
+	}
+
+	srcDir := filepath.Join(tmpdir, "viewhost-src")
+	if err := writeViewHostSources(srcDir, viewHostTmplData{Name: pkg.Name, LibName: libName}); err != nil {
+		return err
+	}
+	apiPath, err := androidAPIPath()
+	if err != nil {
+		return err
+	}
+	classes := filepath.Join(tmpdir, "viewhost-classes")
+	if err := mkdir(classes); err != nil {
+		return err
+	}
+	if err := runJavac(srcDir, classes, "-bootclasspath", filepath.Join(apiPath, "android.jar")); err != nil {
+		return err
+	}
+
+	return writeViewHostAAR(*buildO, appID, minSDK, libPath, classes, assets)
+}
+
+// writeViewHostAAR writes the AAR of buildViewHost to path, with the
+// manifest of the library appID, the app library at libPath, the
+// classes compiled into the directory classes, and the assets.
+func writeViewHostAAR(path, appID string, minSDK int, libPath, classes string, assets []assetFile) (err error) {
+	var out io.Writer = ioutil.Discard
+	if !buildN {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		out = f
+	}
+	aarw, err := newArchiveWriter(out, "aar")
+	if err != nil {
+		return err
+	}
+	create := func(name string) (io.Writer, error) {
+		planf("pack %s", name)
+		return aarw.Create(name)
+	}
+
+	w, err := create("AndroidManifest.xml")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package=%q><uses-sdk android:minSdkVersion="%d" /></manifest>`, appID, minSDK)
+
+	w, err = create("classes.jar")
+	if err != nil {
+		return err
+	}
+	if buildX {
+		printcmd("jar c -C %s .", classes)
+	}
+	if !buildN {
+		if err := writeJar(w, classes); err != nil {
+			return err
+		}
+	}
+
+	w, err = create("jni/" + androidABIs["arm"] + "/" + filepath.Base(libPath))
+	if err != nil {
+		return err
+	}
+	if !buildN {
+		if err := copyAsset(w, libPath); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range assets {
+		w, err := create("assets/" + a.name)
+		if err != nil {
+			return err
+		}
+		if err := copyAsset(w, a.path); err != nil {
+			return err
+		}
+	}
+	if len(assets) > 0 {
+		w, err := create("assets/" + assetDirIndex)
+		if err != nil {
+			return err
+		}
+		for _, dir := range assetDirNames(assets) {
+			if _, err := fmt.Fprintln(w, dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := create("R.txt"); err != nil {
+		return err
+	}
+	if _, err := create("res/"); err != nil {
+		return err
+	}
+	if err := aarw.Close(); err != nil {
+		return err
+	}
+	if !buildN {
+		logf(levelNormal, "wrote %s", path)
+	}
+	return nil
+}
+
+// viewHostTmplData is the data of the view host templates.
+type viewHostTmplData struct {
+	Name    string // package name of the app
+	LibName string // library of the app, loaded as lib<LibName>.so
+}
+
+// writeViewHostSources writes the Java sources of the view host classes
+// to dir, in the directory of the Java package go.
+func writeViewHostSources(dir string, data viewHostTmplData) error {
+	for _, t := range viewHostTmpls {
+		path := filepath.Join(dir, "go", t.Name())
+		err := writeFile(path, func(w io.Writer) error {
+			return t.Execute(w, data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// viewHostTmpls are the templates of the view host classes, named after
+// their files. The native methods of GoView are implemented by the
+// GO_VIEW functions of golang.org/x/mobile/app/android.c.
+var viewHostTmpls = []*template.Template{
+	template.Must(template.New("GoView.java").Parse(goViewJava)),
+	template.Must(template.New("GoFragment.java").Parse(goFragmentJava)),
+}
+
+const goViewJava = `// Code generated by gomobile build -activity=fragment. DO NOT EDIT.
+
+package go;
+
+import android.app.ActivityManager;
+import android.content.ComponentCallbacks2;
+import android.content.Context;
+import android.content.res.Configuration;
+import android.opengl.GLSurfaceView;
+import android.os.Build;
+import android.util.AttributeSet;
+import android.view.MotionEvent;
+import android.view.WindowManager;
+
+import javax.microedition.khronos.egl.EGLConfig;
+import javax.microedition.khronos.opengles.GL10;
+
+// GoView shows the Go app {{.Name}}, drawn with OpenGL ES by the
+// callbacks the app passes to app.Run. It can be placed in the layout
+// of any activity, or be shown by a GoFragment. The activity must pass
+// its onPause and onResume calls on to the view.
+//
+// The app is started by the first GoView created, and runs until its
+// process exits. Only one GoView may show it at a time.
+public class GoView extends GLSurfaceView {
+	static {
+		System.loadLibrary("{{.LibName}}");
+	}
+
+	private static boolean initialized = false;
+
+	public GoView(Context ctx) {
+		this(ctx, null);
+	}
+
+	public GoView(Context ctx, AttributeSet attrs) {
+		super(ctx, attrs);
+		synchronized (GoView.class) {
+			if (!initialized) {
+				initialized = true;
+				init(ctx.getApplicationContext(), getResources().getDisplayMetrics().densityDpi);
+
+				// Pass memory warnings to the app.LowMemory callback.
+				// ComponentCallbacks2 was added in API level 14.
+				if (Build.VERSION.SDK_INT >= 14) {
+					ctx.getApplicationContext().registerComponentCallbacks(new ComponentCallbacks2() {
+						public void onTrimMemory(int level) { trimMemory(level); }
+						public void onLowMemory() { trimMemory(TRIM_MEMORY_COMPLETE); }
+						public void onConfigurationChanged(Configuration config) {}
+					});
+				}
+			}
+		}
+
+		ActivityManager am = (ActivityManager)ctx.getSystemService(Context.ACTIVITY_SERVICE);
+		boolean es3 = am.getDeviceConfigurationInfo().reqGlEsVersion >= 0x30000;
+		setEGLContextClientVersion(glVersion(es3));
+		setPreserveEGLContextOnPause(true);
+		setRenderer(new GoRenderer());
+		setFocusableInTouchMode(true);
+	}
+
+	// GoRenderer runs the app on the renderer thread of the view.
+	private class GoRenderer implements GLSurfaceView.Renderer {
+		public void onSurfaceCreated(GL10 gl, EGLConfig config) {
+			surfaceCreated();
+		}
+
+		public void onSurfaceChanged(GL10 gl, int width, int height) {
+			WindowManager wm = (WindowManager)getContext().getSystemService(Context.WINDOW_SERVICE);
+			surfaceChanged(width, height, wm.getDefaultDisplay().getRefreshRate());
+		}
+
+		public void onDrawFrame(GL10 gl) {
+			drawFrame(System.nanoTime());
+		}
+	}
+
+	@Override
+	public boolean onTouchEvent(MotionEvent e) {
+		final int action = e.getAction();
+		final int n = e.getPointerCount();
+		final int[] ids = new int[n];
+		final int[] tools = new int[n];
+		final float[] values = new float[4*n];
+		for (int i = 0; i < n; i++) {
+			ids[i] = e.getPointerId(i);
+			// getToolType was added in API level 14.
+			if (Build.VERSION.SDK_INT >= 14) {
+				tools[i] = e.getToolType(i);
+			}
+			values[4*i] = e.getX(i);
+			values[4*i+1] = e.getY(i);
+			values[4*i+2] = e.getPressure(i);
+			values[4*i+3] = e.getSize(i);
+		}
+		queueEvent(new Runnable() {
+			public void run() { touch(action, ids, tools, values); }
+		});
+		return true;
+	}
+
+	@Override
+	public void onPause() {
+		// The renderer runs the events queued before it pauses.
+		queueEvent(new Runnable() {
+			public void run() { stop(); }
+		});
+		super.onPause();
+	}
+
+	private static native void init(Context ctx, int dpi);
+	private static native int glVersion(boolean es3);
+	private static native void surfaceCreated();
+	private static native void surfaceChanged(int width, int height, float refreshRate);
+	private static native void drawFrame(long nanos);
+	private static native void touch(int action, int[] ids, int[] tools, float[] values);
+	private static native void stop();
+	private static native void trimMemory(int level);
+}
+`
+
+const goFragmentJava = `// Code generated by gomobile build -activity=fragment. DO NOT EDIT.
+
+package go;
+
+import android.app.Fragment;
+import android.os.Bundle;
+import android.view.LayoutInflater;
+import android.view.View;
+import android.view.ViewGroup;
+
+// GoFragment shows the Go app {{.Name}} in a GoView. It can be declared
+// in the layout of an activity:
+//
+// 	<fragment android:name="go.GoFragment"
+// 		android:layout_width="match_parent"
+// 		android:layout_height="match_parent" />
+public class GoFragment extends Fragment {
+	private GoView view;
+
+	@Override
+	public View onCreateView(LayoutInflater inflater, ViewGroup container, Bundle state) {
+		view = new GoView(getActivity());
+		return view;
+	}
+
+	@Override
+	public void onResume() {
+		super.onResume();
+		view.onResume();
+	}
+
+	@Override
+	public void onPause() {
+		view.onPause();
+		super.onPause();
+	}
+
+	@Override
+	public void onDestroyView() {
+		view = null;
+		super.onDestroyView();
+	}
+}
+`
@@ -0,0 +1,159 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// viewHostSources writes the view host sources of the app basic to a
+// temporary directory.
+func viewHostSources(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "gomobile-viewhost-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeViewHostSources(dir, viewHostTmplData{Name: "main", LibName: "basic"}); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+var (
+	javaNativeRE = regexp.MustCompile(`native \w+ (\w+)\(`)
+	goViewJNIRE  = regexp.MustCompile(`GO_VIEW\((\w+)\)\(`)
+)
+
+// submatches returns the first submatches of re in data, sorted.
+func submatches(re *regexp.Regexp, data []byte) []string {
+	var names []string
+	for _, m := range re.FindAllSubmatch(data, -1) {
+		names = append(names, string(m[1]))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestViewHostNatives(t *testing.T) {
+	dir, cleanup := viewHostSources(t)
+	defer cleanup()
+	java, err := ioutil.ReadFile(filepath.Join(dir, "go", "GoView.java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `System.loadLibrary("basic");`; !strings.Contains(string(java), want) {
+		t.Errorf("GoView.java does not load the app library with %s", want)
+	}
+	natives := submatches(javaNativeRE, java)
+	if len(natives) == 0 {
+		t.Fatal("GoView.java declares no native methods")
+	}
+
+	app, err := ctx.Import("golang.org/x/mobile/app", "", build.FindOnly)
+	if err != nil {
+		t.Skipf("cannot find the app package: %v", err)
+	}
+	c, err := ioutil.ReadFile(filepath.Join(app.Dir, "android.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jni := submatches(goViewJNIRE, c); !reflect.DeepEqual(natives, jni) {
+		t.Errorf("GoView.java declares the native methods %q, app/android.c implements %q", natives, jni)
+	}
+}
+
+func TestViewHostFragment(t *testing.T) {
+	dir, cleanup := viewHostSources(t)
+	defer cleanup()
+	java, err := ioutil.ReadFile(filepath.Join(dir, "go", "GoFragment.java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"public class GoFragment extends Fragment",
+		"view = new GoView(getActivity());",
+		"view.onResume();",
+		"view.onPause();",
+	} {
+		if !strings.Contains(string(java), want) {
+			t.Errorf("GoFragment.java does not contain %q", want)
+		}
+	}
+}
+
+func TestBuildViewHost(t *testing.T) {
+	buf, cleanup := fakeToolchain(t)
+	defer cleanup()
+	defer func() {
+		buildPlan = false
+		buildActivity = "native"
+		buildFormat = "apk"
+		buildMinSDK = 0
+		*buildO = ""
+	}()
+
+	// A fake SDK provides the platform to compile against.
+	sdk, err := ioutil.TempDir("", "gomobile-viewhost-sdk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sdk)
+	platform := filepath.Join(sdk, "platforms", "android-15")
+	if err := os.MkdirAll(platform, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(platform, "android.jar"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("ANDROID_HOME", sdk)
+
+	pkg := &build.Package{Name: "main", ImportPath: "example.com/basic", Dir: sdk}
+	if err := cmdBuild.flag.Parse([]string{"-activity", "fragment", "-o", "basic.aar"}); err != nil {
+		t.Fatal(err)
+	}
+	buildPlan = true
+	if err := buildViewHost(pkg); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# build example.com/basic into basic.aar\n",
+		" -o $WORK/libbasic.so example.com/basic\n",
+		"javac -d $WORK/viewhost-classes -source 1.7 -target 1.7 -bootclasspath " + filepath.Join(platform, "android.jar") + " *.java\n",
+		"# pack AndroidManifest.xml\n",
+		"# pack classes.jar\n",
+		"# pack jni/armeabi-v7a/libbasic.so\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan does not contain %q:\n%s", want, out)
+		}
+	}
+
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-minsdk", "9"}, "requires a minimum API level of 11"},
+		{[]string{"-format", "aab"}, "cannot be used with -format aab"},
+		{[]string{"-o", "basic.apk"}, `does not end in '.aar'`},
+	} {
+		buildMinSDK, buildFormat, *buildO = 0, "apk", ""
+		if err := cmdBuild.flag.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := buildViewHost(pkg); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("buildViewHost with %q: %v, want an error containing %q", tt.args, err, tt.err)
+		}
+	}
+}
@@ -79,7 +79,14 @@ import (
 // NewWriter returns a new Writer writing an APK file to w.
 // The APK will be signed with key.
 func NewWriter(w io.Writer, priv *rsa.PrivateKey) *Writer {
-	apkw := &Writer{priv: priv}
+	return NewWriterCert(w, priv, nil)
+}
+
+// NewWriterCert returns a new Writer writing an APK file to w, signed
+// with key and its DER-encoded certificate cert, as read from a keystore.
+// With a nil cert, a self-signed certificate is generated for key.
+func NewWriterCert(w io.Writer, priv *rsa.PrivateKey, cert []byte) *Writer {
+	apkw := &Writer{priv: priv, cert: cert}
 	apkw.w = zip.NewWriter(&countWriter{apkw: apkw, w: w})
 	return apkw
 }
@@ -89,6 +96,7 @@ type Writer struct {
 	offset   int
 	w        *zip.Writer
 	priv     *rsa.PrivateKey
+	cert     []byte
 	manifest []manifestEntry
 	cur      *fileWriter
 }
@@ -186,7 +194,12 @@ func (w *Writer) Close() error {
 		return err
 	}
 
-	rsa, err := signPKCS7(rand.Reader, w.priv, cert.Bytes())
+	var rsa []byte
+	if w.cert != nil {
+		rsa, err = signPKCS7Cert(rand.Reader, w.priv, w.cert, cert.Bytes())
+	} else {
+		rsa, err = signPKCS7(rand.Reader, w.priv, cert.Bytes())
+	}
 	if err != nil {
 		return fmt.Errorf("apk: %v", err)
 	}
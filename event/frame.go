@@ -0,0 +1,27 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import "time"
+
+// Frame is the timing of a frame the app is about to draw. Animations
+// advanced by Delta move at the same speed whatever the frame rate.
+//
+// On Android, the timing is that of the frame callback of the
+// Choreographer. On iOS, it is that of a CADisplayLink.
+type Frame struct {
+	// Time is when the frame is expected to be presented on the
+	// display, measured from the first frame. It does not advance
+	// while the app is stopped.
+	Time time.Duration
+
+	// Delta is Time less the Time of the previous frame. It is zero
+	// for the first frame.
+	Delta time.Duration
+
+	// RefreshRate is the refresh rate of the display, in frames per
+	// second.
+	RefreshRate float32
+}
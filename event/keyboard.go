@@ -0,0 +1,22 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import "golang.org/x/mobile/geom"
+
+// Keyboard is a change of the soft keyboard: it was shown, hidden or
+// resized. The keyboard covers the bottom of the screen, so the app may
+// move what it draws there out of its way.
+//
+// On Android, this is a change of the content rect of the activity.
+// On iOS, it is a UIKeyboardWillChangeFrameNotification.
+type Keyboard struct {
+	// Visible reports whether the keyboard is on the screen.
+	Visible bool
+
+	// Height is the height of the screen the keyboard covers, or zero
+	// if it is hidden.
+	Height geom.Pt
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import "fmt"
+
+// LowMemory is a warning from the operating system that it is running
+// low on memory. The app should release what it can recreate later,
+// such as caches, or risk being terminated.
+//
+// On Android, this is a call to onTrimMemory or onLowMemory.
+// On iOS, it is a call to didReceiveMemoryWarning.
+type LowMemory struct {
+	Level TrimLevel
+}
+
+// TrimLevel is how severe a LowMemory warning is. Higher levels are
+// more severe.
+//
+// The levels are those of Android. Where the operating system does not
+// report a level, as on iOS and from onLowMemory on Android, the level is
+// TrimComplete.
+type TrimLevel int
+
+const (
+	// TrimRunningModerate is a running app being told the system is
+	// beginning to run low on memory.
+	//
+	// On Android, this is TRIM_MEMORY_RUNNING_MODERATE.
+	TrimRunningModerate TrimLevel = 5
+
+	// TrimRunningLow is a running app being told the system is running
+	// low on memory, and the app should release unneeded resources.
+	//
+	// On Android, this is TRIM_MEMORY_RUNNING_LOW.
+	TrimRunningLow TrimLevel = 10
+
+	// TrimRunningCritical is a running app being told the system has
+	// killed most background processes, and will kill the app next.
+	//
+	// On Android, this is TRIM_MEMORY_RUNNING_CRITICAL.
+	TrimRunningCritical TrimLevel = 15
+
+	// TrimUIHidden is an app whose user interface is no longer visible.
+	//
+	// On Android, this is TRIM_MEMORY_UI_HIDDEN.
+	TrimUIHidden TrimLevel = 20
+
+	// TrimBackground is a background app near the start of the list of
+	// apps the system kills to free memory.
+	//
+	// On Android, this is TRIM_MEMORY_BACKGROUND.
+	TrimBackground TrimLevel = 40
+
+	// TrimModerate is a background app in the middle of that list.
+	//
+	// On Android, this is TRIM_MEMORY_MODERATE.
+	TrimModerate TrimLevel = 60
+
+	// TrimComplete is an app that will be killed soon if the system is
+	// unable to free memory.
+	//
+	// On Android, this is TRIM_MEMORY_COMPLETE.
+	TrimComplete TrimLevel = 80
+)
+
+func (l TrimLevel) String() string {
+	switch l {
+	case TrimRunningModerate:
+		return "running moderate"
+	case TrimRunningLow:
+		return "running low"
+	case TrimRunningCritical:
+		return "running critical"
+	case TrimUIHidden:
+		return "ui hidden"
+	case TrimBackground:
+		return "background"
+	case TrimModerate:
+		return "moderate"
+	case TrimComplete:
+		return "complete"
+	}
+	return fmt.Sprintf("TrimLevel(%d)", int(l))
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import "fmt"
+
+// Power is a change of the battery or the thermal state of the device.
+// Apps may do less work while the battery is low or the device is hot.
+//
+// On Android, this is the ACTION_BATTERY_CHANGED state of the
+// BatteryManager and the thermal status of the PowerManager. On iOS, it
+// is the battery of UIDevice and the thermalState of NSProcessInfo.
+type Power struct {
+	// Battery is the charge of the battery, from 0 to 1, or -1 if it is
+	// unknown, as on devices and emulators without a battery.
+	Battery float32
+
+	Charging ChargingState
+	Thermal  ThermalState
+}
+
+// ChargingState is whether the battery is charging.
+type ChargingState int
+
+const (
+	// ChargingUnknown is a battery whose state is not reported, or a
+	// device without a battery.
+	ChargingUnknown ChargingState = iota
+
+	// Discharging is a battery not gaining charge. On Android, this
+	// includes a battery plugged in but not charging.
+	Discharging
+
+	// Charging is a battery plugged in and charging.
+	Charging
+
+	// ChargingFull is a battery plugged in and fully charged.
+	ChargingFull
+)
+
+func (s ChargingState) String() string {
+	switch s {
+	case ChargingUnknown:
+		return "unknown"
+	case Discharging:
+		return "discharging"
+	case Charging:
+		return "charging"
+	case ChargingFull:
+		return "full"
+	}
+	return fmt.Sprintf("ChargingState(%d)", int(s))
+}
+
+// ThermalState is how hot the device is. Higher states are hotter, and
+// the operating system slows the device down to cool it.
+//
+// The states are those of iOS. The thermal statuses of Android are
+// mapped to the state whose effect on the user is closest.
+type ThermalState int
+
+const (
+	// ThermalUnknown is a device that does not report its thermal
+	// state, such as on Android before API level 29.
+	ThermalUnknown ThermalState = iota
+
+	// ThermalNominal is a device within its normal range.
+	//
+	// On Android, this is THERMAL_STATUS_NONE.
+	ThermalNominal
+
+	// ThermalFair is a device slightly hot, slowed down without
+	// affecting the user.
+	//
+	// On Android, this is THERMAL_STATUS_LIGHT or THERMAL_STATUS_MODERATE.
+	ThermalFair
+
+	// ThermalSerious is a device slowed down enough to affect the user.
+	//
+	// On Android, this is THERMAL_STATUS_SEVERE.
+	ThermalSerious
+
+	// ThermalCritical is a device that must cool down, and may shut
+	// down features or itself.
+	//
+	// On Android, this is THERMAL_STATUS_CRITICAL, THERMAL_STATUS_EMERGENCY
+	// or THERMAL_STATUS_SHUTDOWN.
+	ThermalCritical
+)
+
+func (s ThermalState) String() string {
+	switch s {
+	case ThermalUnknown:
+		return "unknown"
+	case ThermalNominal:
+		return "nominal"
+	case ThermalFair:
+		return "fair"
+	case ThermalSerious:
+		return "serious"
+	case ThermalCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("ThermalState(%d)", int(s))
+}
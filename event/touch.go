@@ -27,10 +27,30 @@ import (
 //
 // On Android, this is an AInputEvent with AINPUT_EVENT_TYPE_MOTION.
 // On iOS, it is the UIEvent delivered to a UIView.
+//
+// Pressure, Size and Tool are zero where the platform does not report
+// them.
 type Touch struct {
 	ID   TouchSequenceID
 	Type TouchType
 	Loc  geom.Point
+
+	// Pressure is the pressure of the touch, normally from 0 (no
+	// pressure) to 1 (normal pressure), though it may exceed 1
+	// depending on the calibration of the device.
+	//
+	// On Android, this is AMotionEvent_getPressure.
+	Pressure float32
+
+	// Size is the size of the touched area, normalized to the range 0
+	// to 1 of the sizes the device reports. It is a coarse measure, for
+	// telling a fingertip from a palm.
+	//
+	// On Android, this is AMotionEvent_getSize.
+	Size float32
+
+	// Tool is the kind of tool touching the device.
+	Tool ToolType
 }
 
 func (t Touch) String() string {
@@ -43,7 +63,10 @@ func (t Touch) String() string {
 	case TouchEnd:
 		ty = "end  "
 	}
-	return fmt.Sprintf("Touch{ %s, %s }", ty, t.Loc)
+	if t.Tool == ToolUnknown && t.Pressure == 0 && t.Size == 0 {
+		return fmt.Sprintf("Touch{ %s, %s }", ty, t.Loc)
+	}
+	return fmt.Sprintf("Touch{ %s, %s, %s, pressure %.2f, size %.2f }", ty, t.Loc, t.Tool, t.Pressure, t.Size)
 }
 
 // TouchSequenceID identifies a sequence of Touch events.
@@ -73,3 +96,38 @@ const (
 	// On iOS, this is a call to touchesEnded.
 	TouchEnd
 )
+
+// ToolType is the kind of tool touching the device.
+type ToolType byte
+
+const (
+	// ToolUnknown is a tool the platform does not report.
+	ToolUnknown ToolType = iota
+
+	// ToolFinger is a finger.
+	ToolFinger
+
+	// ToolStylus is a stylus.
+	ToolStylus
+
+	// ToolMouse is a mouse or trackpad. A mouse touches the device
+	// while a button is held down.
+	ToolMouse
+
+	// ToolEraser is the eraser end of a stylus.
+	ToolEraser
+)
+
+func (t ToolType) String() string {
+	switch t {
+	case ToolFinger:
+		return "finger"
+	case ToolStylus:
+		return "stylus"
+	case ToolMouse:
+		return "mouse"
+	case ToolEraser:
+		return "eraser"
+	}
+	return "unknown"
+}
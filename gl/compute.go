@@ -0,0 +1,56 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+
+package gl
+
+// This file contains the OpenGL ES 3.1 entry points, independent of the
+// "gldebug" build tag. They are looked up when first called, so apps
+// still load where the library of the device only provides OpenGL ES 2.
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <stdint.h>
+
+#ifdef os_linux
+#include <dlfcn.h>
+
+static void (*glDispatchComputeFn)(uint32_t, uint32_t, uint32_t);
+
+// lookupDispatchCompute returns whether the GL library has
+// glDispatchCompute.
+int lookupDispatchCompute() {
+	if (glDispatchComputeFn == NULL) {
+		glDispatchComputeFn = dlsym(RTLD_DEFAULT, "glDispatchCompute");
+	}
+	return glDispatchComputeFn != NULL;
+}
+
+void dispatchCompute(uint32_t x, uint32_t y, uint32_t z) {
+	glDispatchComputeFn(x, y, z);
+}
+#else
+// Neither iOS nor the OpenGL of OS X provides compute shaders.
+int lookupDispatchCompute() { return 0; }
+void dispatchCompute(uint32_t x, uint32_t y, uint32_t z) {}
+#endif
+*/
+import "C"
+
+import "fmt"
+
+// dispatchCompute calls glDispatchCompute, after checking that the
+// current context is OpenGL ES 3.1 or later.
+func dispatchCompute(x, y, z uint32) error {
+	if err := needVersion("DispatchCompute", 3, 1); err != nil {
+		return err
+	}
+	if C.lookupDispatchCompute() == 0 {
+		return fmt.Errorf("gl: DispatchCompute is not provided by the OpenGL ES library")
+	}
+	C.dispatchCompute(C.uint32_t(x), C.uint32_t(y), C.uint32_t(z))
+	return nil
+}
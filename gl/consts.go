@@ -336,6 +336,7 @@ const (
 const (
 	FRAGMENT_SHADER = 0x8B30
 	VERTEX_SHADER   = 0x8B31
+	COMPUTE_SHADER  = 0x91B9 // OpenGL ES 3.1
 )
 
 const (
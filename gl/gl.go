@@ -374,6 +374,15 @@ func DisableVertexAttribArray(a Attrib) {
 	C.glDisableVertexAttribArray(a.c())
 }
 
+// DispatchCompute launches the work groups of the compute shader of the
+// current program. It needs an OpenGL ES 3.1 context, and returns an
+// error without calling OpenGL on an older one.
+//
+// http://www.khronos.org/opengles/sdk/docs/man31/html/glDispatchCompute.xhtml
+func DispatchCompute(numGroupsX, numGroupsY, numGroupsZ uint32) error {
+	return dispatchCompute(numGroupsX, numGroupsY, numGroupsZ)
+}
+
 // DrawArrays renders geometric primitives from the bound data.
 //
 // http://www.khronos.org/opengles/sdk/docs/man3/html/glDrawArrays.xhtml
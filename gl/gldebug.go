@@ -636,6 +636,8 @@ func (v Enum) String() string {
 		return "FRAGMENT_SHADER"
 	case 0x8b31:
 		return "VERTEX_SHADER"
+	case 0x91b9:
+		return "COMPUTE_SHADER"
 	default:
 		return fmt.Sprintf("gl.Enum(0x%x)", uint32(v))
 	}
@@ -1011,6 +1013,14 @@ func DisableVertexAttribArray(a Attrib) {
 	C.glDisableVertexAttribArray(a.c())
 }
 
+func DispatchCompute(numGroupsX, numGroupsY, numGroupsZ uint32) (r0 error) {
+	defer func() {
+		errstr := errDrain()
+		log.Printf("gl.DispatchCompute(%v, %v, %v) %v%v", numGroupsX, numGroupsY, numGroupsZ, r0, errstr)
+	}()
+	return dispatchCompute(numGroupsX, numGroupsY, numGroupsZ)
+}
+
 func DrawArrays(mode Enum, first, count int) {
 	defer func() {
 		errstr := errDrain()
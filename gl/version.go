@@ -0,0 +1,57 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+// +build cgo
+
+package gl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version returns the OpenGL ES version of the current context:
+// "GL_ES_2_0" or "GL_ES_3_0". On the desktop, where the bindings run
+// on a desktop OpenGL context, Version returns "GL_ES_2_0".
+//
+// The bindings mostly cover OpenGL ES 2. Apps request an OpenGL ES 3
+// context with app.Callbacks.GLVersion, and Version reports whether
+// they got one. The few functions of later versions, such as
+// DispatchCompute, return an error on an older context.
+func Version() string {
+	return version(GetString(VERSION))
+}
+
+// version returns the version of a context reporting VERSION s, which
+// for OpenGL ES is of the form "OpenGL ES major.minor vendor-info".
+func version(s string) string {
+	if strings.HasPrefix(s, "OpenGL ES 3") {
+		return "GL_ES_3_0"
+	}
+	return "GL_ES_2_0"
+}
+
+// esVersion returns the major and minor version of a context reporting
+// VERSION s, or 2.0 if it is not an OpenGL ES context.
+func esVersion(s string) (major, minor int) {
+	if _, err := fmt.Sscanf(s, "OpenGL ES %d.%d", &major, &minor); err != nil {
+		return 2, 0
+	}
+	return major, minor
+}
+
+// needVersion returns an error naming the function fn unless the
+// current context is at least OpenGL ES major.minor.
+func needVersion(fn string, major, minor int) error {
+	return checkVersion(GetString(VERSION), fn, major, minor)
+}
+
+func checkVersion(s, fn string, major, minor int) error {
+	maj, min := esVersion(s)
+	if maj > major || maj == major && min >= minor {
+		return nil
+	}
+	return fmt.Errorf("gl: %s needs OpenGL ES %d.%d, the context is OpenGL ES %d.%d", fn, major, minor, maj, min)
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin
+// +build cgo
+
+package gl
+
+import "testing"
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"OpenGL ES 2.0 build 1.9@2291151", "GL_ES_2_0"},
+		{"OpenGL ES 3.0 V@53.0 AU@  (CL@)", "GL_ES_3_0"},
+		{"OpenGL ES 3.1 NVIDIA 343.00", "GL_ES_3_0"},
+		{"2.1 NVIDIA-10.0.43 310.41.05f01", "GL_ES_2_0"},
+		{"", "GL_ES_2_0"},
+	}
+	for _, tt := range tests {
+		if got := version(tt.s); got != tt.want {
+			t.Errorf("version(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		s   string
+		err string
+	}{
+		{"OpenGL ES 3.1 NVIDIA 343.00", ""},
+		{"OpenGL ES 3.2 V@145.0", ""},
+		{"OpenGL ES 4.0", ""},
+		{"OpenGL ES 3.0 V@53.0 AU@  (CL@)", "gl: DispatchCompute needs OpenGL ES 3.1, the context is OpenGL ES 3.0"},
+		{"OpenGL ES 2.0 build 1.9@2291151", "gl: DispatchCompute needs OpenGL ES 3.1, the context is OpenGL ES 2.0"},
+		{"2.1 NVIDIA-10.0.43 310.41.05f01", "gl: DispatchCompute needs OpenGL ES 3.1, the context is OpenGL ES 2.0"},
+		{"", "gl: DispatchCompute needs OpenGL ES 3.1, the context is OpenGL ES 2.0"},
+	}
+	for _, tt := range tests {
+		err := checkVersion(tt.s, "DispatchCompute", 3, 1)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.err {
+			t.Errorf("checkVersion(%q) = %q, want %q", tt.s, got, tt.err)
+		}
+	}
+}
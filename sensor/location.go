@@ -0,0 +1,97 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sensor
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// LocationAccuracy is the accuracy of the updates of the Location sensor.
+type LocationAccuracy int
+
+const (
+	// FineLocation is the location from satellites, accurate to a few
+	// meters. On Android, it requires the ACCESS_FINE_LOCATION
+	// permission.
+	FineLocation = LocationAccuracy(0)
+
+	// CoarseLocation is the location from the cell and Wi-Fi networks,
+	// accurate to a city block. On Android, it requires the
+	// ACCESS_COARSE_LOCATION or ACCESS_FINE_LOCATION permission.
+	CoarseLocation = LocationAccuracy(1)
+)
+
+// ErrLocationPermission is returned by Enable for the Location sensor if
+// the app lacks the permission to get the location at the requested
+// accuracy. On Android, the permission is declared by a uses-permission
+// element of the app manifest, and from Android 6.0 on, the user must
+// also grant it to the app.
+var ErrLocationPermission = errors.New("sensor: app lacks the permission to get the location")
+
+// defaultLocationInterval is the interval between location updates when
+// Enable is given none.
+const defaultLocationInterval = time.Second
+
+// A locationFix is a location reported by the platform.
+type locationFix struct {
+	latitude, longitude float64 // in degrees
+	altitude            float64 // in meters
+	accuracy            float64 // in meters
+	hasAltitude         bool
+	hasAccuracy         bool
+	time                int64 // in milliseconds since the Unix epoch
+}
+
+// event returns the Location event of the fix.
+func (f locationFix) event() Event {
+	alt, acc := math.NaN(), math.NaN()
+	if f.hasAltitude {
+		alt = f.altitude
+	}
+	if f.hasAccuracy {
+		acc = f.accuracy
+	}
+	return Event{
+		Sensor:    Location,
+		Timestamp: f.time * int64(time.Millisecond),
+		Data:      []float64{f.latitude, f.longitude, alt, acc},
+	}
+}
+
+// A locationPoller polls the last known location of the platform at the
+// update interval, turning each new fix into a Location event.
+type locationPoller struct {
+	interval time.Duration
+	next     time.Time // the time of the next poll
+	last     int64     // the time of the last fix reported
+}
+
+func newLocationPoller(interval time.Duration) *locationPoller {
+	if interval <= 0 {
+		interval = defaultLocationInterval
+	}
+	return &locationPoller{interval: interval}
+}
+
+// wait returns the time from now until the next poll is due.
+func (p *locationPoller) wait(now time.Time) time.Duration {
+	if d := p.next.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// poll records a poll at now, which found the fix if ok, and returns its
+// event if the fix is newer than the last one reported.
+func (p *locationPoller) poll(now time.Time, fix locationFix, ok bool) (Event, bool) {
+	p.next = now.Add(p.interval)
+	if !ok || fix.time <= p.last {
+		return Event{}, false
+	}
+	p.last = fix.time
+	return fix.event(), true
+}
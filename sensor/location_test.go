@@ -0,0 +1,92 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sensor
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLocationFixEvent(t *testing.T) {
+	fix := locationFix{
+		latitude:    48.8584,
+		longitude:   2.2945,
+		altitude:    35,
+		accuracy:    4.5,
+		hasAltitude: true,
+		hasAccuracy: true,
+		time:        1444000000123,
+	}
+	e := fix.event()
+	want := Event{
+		Sensor:    Location,
+		Timestamp: 1444000000123000000,
+		Data:      []float64{48.8584, 2.2945, 35, 4.5},
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("event = %+v, want %+v", e, want)
+	}
+	if got := time.Unix(0, e.Timestamp).UTC(); got != time.Date(2015, 10, 4, 23, 6, 40, 123e6, time.UTC) {
+		t.Errorf("event time = %v", got)
+	}
+
+	fix.hasAltitude, fix.hasAccuracy = false, false
+	e = fix.event()
+	if !math.IsNaN(e.Data[2]) || !math.IsNaN(e.Data[3]) {
+		t.Errorf("event without altitude and accuracy has data %v, want NaN altitude and accuracy", e.Data)
+	}
+}
+
+func TestLocationPoller(t *testing.T) {
+	start := time.Unix(1444000000, 0)
+	p := newLocationPoller(2 * time.Second)
+	if d := p.wait(start); d != 0 {
+		t.Errorf("first poll wait = %v, want 0", d)
+	}
+
+	fix := locationFix{latitude: 1, longitude: 2, time: 1000}
+	tests := []struct {
+		desc  string
+		after time.Duration
+		fix   locationFix
+		ok    bool
+		event bool
+	}{
+		{"no fix yet", 0, locationFix{}, false, false},
+		{"first fix", 2 * time.Second, fix, true, true},
+		{"same fix", 4 * time.Second, fix, true, false},
+		{"new fix", 6 * time.Second, locationFix{latitude: 3, longitude: 4, time: 5000}, true, true},
+		{"older fix", 8 * time.Second, fix, true, false},
+	}
+	for _, tt := range tests {
+		now := start.Add(tt.after)
+		e, ok := p.poll(now, tt.fix, tt.ok)
+		if ok != tt.event {
+			t.Errorf("%s: poll reported event %v, want %v", tt.desc, ok, tt.event)
+		}
+		if ok && (e.Sensor != Location || e.Data[0] != tt.fix.latitude || e.Data[1] != tt.fix.longitude) {
+			t.Errorf("%s: event = %+v, want the fix %+v", tt.desc, e, tt.fix)
+		}
+		if d := p.wait(now.Add(500 * time.Millisecond)); d != 1500*time.Millisecond {
+			t.Errorf("%s: wait = %v, want 1.5s", tt.desc, d)
+		}
+	}
+
+	if p := newLocationPoller(0); p.interval != defaultLocationInterval {
+		t.Errorf("default interval = %v, want %v", p.interval, defaultLocationInterval)
+	}
+}
+
+func TestLocationType(t *testing.T) {
+	if got := Location.String(); got != "Location" {
+		t.Errorf("Location.String() = %q", got)
+	}
+	var m Manager
+	if err := m.Enable(Type(4), time.Second); err == nil {
+		t.Error("Enable of an unknown sensor type succeeded")
+	}
+}
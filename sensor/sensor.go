@@ -2,7 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package sensor provides sensor events from various movement sensors.
+// Package sensor provides sensor events from various movement sensors,
+// and the location of the device.
 package sensor
 
 import (
@@ -17,6 +18,7 @@ var sensorNames = map[Type]string{
 	Accelerometer: "Accelerometer",
 	Gyroscope:     "Gyrsocope",
 	Magnetometer:  "Magnetometer",
+	Location:      "Location",
 }
 
 // String returns the string representation of the sensor type.
@@ -31,6 +33,7 @@ const (
 	Accelerometer = Type(0)
 	Gyroscope     = Type(1)
 	Magnetometer  = Type(2)
+	Location      = Type(3)
 )
 
 // Event represents a sensor event.
@@ -41,6 +44,9 @@ type Event struct {
 	// Timestamp is a device specific event time in nanoseconds.
 	// Timestamps are not Unix times, they represent a time that is
 	// only valid for the device's default sensor.
+	//
+	// The Timestamp of a Location event is the Unix time of the fix,
+	// in nanoseconds.
 	Timestamp int64
 
 	// Data is the event data.
@@ -60,12 +66,20 @@ type Event struct {
 	//  - Data[1]: force of gravity along the y axis in m/s^2
 	//  - Data[2]: force of gravity along the z axis in m/s^2
 	//
+	// If the event source is Location,
+	//  - Data[0]: latitude in degrees
+	//  - Data[1]: longitude in degrees
+	//  - Data[2]: altitude above the WGS 84 ellipsoid in meters, or NaN
+	//  - Data[3]: radius of 68% confidence of the position in meters, or NaN
+	//
 	Data []float64
 }
 
 // Manager multiplexes sensor event data from various sensor sources.
 type Manager struct {
 	m *manager // platform-specific implementation of the underlying manager
+
+	accuracy LocationAccuracy // set by SetLocationAccuracy
 }
 
 // Enable enables a sensor with the specified delay rate.
@@ -73,7 +87,12 @@ type Manager struct {
 // the default one.
 // If there is no default sensor of type t on the device, an error returned.
 // Valid sensor types supported by this package are Accelerometer,
-// Gyroscope, Magnetometer and Altimeter.
+// Gyroscope, Magnetometer and Location.
+//
+// For Location, delay is the interval between the location updates, and
+// the accuracy is the one set by SetLocationAccuracy. If the app lacks
+// the permission to get the location, Enable returns
+// ErrLocationPermission.
 func (m *Manager) Enable(t Type, delay time.Duration) error {
 	if m.m == nil {
 		m.m = new(manager)
@@ -82,9 +101,18 @@ func (m *Manager) Enable(t Type, delay time.Duration) error {
 	if t < 0 || int(t) >= len(sensorNames) {
 		return errors.New("sensor: unknown sensor type")
 	}
+	if t == Location {
+		return m.m.enableLocation(delay, m.accuracy)
+	}
 	return m.m.enable(t, delay)
 }
 
+// SetLocationAccuracy sets the accuracy of the location updates of the
+// Location sensor enabled next. The default is FineLocation.
+func (m *Manager) SetLocationAccuracy(a LocationAccuracy) {
+	m.accuracy = a
+}
+
 // Disable disables to feed the manager with the specified sensor.
 func (m *Manager) Disable(t Type) error {
 	if m.m == nil {
@@ -94,6 +122,9 @@ func (m *Manager) Disable(t Type) error {
 	if t < 0 || int(t) >= len(sensorNames) {
 		return errors.New("sensor: unknown sensor type")
 	}
+	if t == Location {
+		return m.m.disableLocation()
+	}
 	return m.m.disable(t)
 }
 
@@ -5,8 +5,9 @@
 package sensor
 
 /*
-#cgo LDFLAGS: -landroid
+#cgo LDFLAGS: -landroid -llog
 
+#include <jni.h>
 #include <stdlib.h>
 #include <android/sensor.h>
 
@@ -14,13 +15,19 @@ package sensor
 */
 import "C"
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"sync/atomic"
 	"time"
 	"unsafe"
+
+	"golang.org/x/mobile/app"
 )
 
+// readTimeout is how long a read waits for events before returning none.
+const readTimeout = 30 * time.Second
+
 var nextLooperID int64 // each underlying ALooper should have a unique ID.
 
 // initSignal initializes an underlying looper and event queue.
@@ -53,6 +60,17 @@ type disableSignal struct {
 	t Type
 }
 
+// enableLocationSignal starts the location updates with the specified
+// interval and accuracy.
+type enableLocationSignal struct {
+	interval time.Duration
+	accuracy LocationAccuracy
+	err      *error
+}
+
+// disableLocationSignal stops the location updates.
+type disableLocationSignal struct{}
+
 type inOut struct {
 	in  interface{}
 	out chan struct{}
@@ -62,6 +80,11 @@ type inOut struct {
 type manager struct {
 	m     *C.android_SensorManager
 	inout chan inOut
+
+	// The location updates, if enabled.
+	loc         *locationPoller
+	locProvider C.jstring // the location provider
+	locIntent   C.jobject // the PendingIntent receiving the updates
 }
 
 // initialize inits the manager and creates a goroutine to proxy the CGO calls.
@@ -89,11 +112,16 @@ func (m *manager) initialize() {
 				}
 			case disableSignal:
 				C.android_disableSensor(m.m.queue, typeToInt(s.t))
+			case enableLocationSignal:
+				*s.err = m.startLocation(s.interval, s.accuracy)
+			case disableLocationSignal:
+				m.stopLocation()
 			case readSignal:
 				n, err := readEvents(m, s.dst)
 				*s.n = n
 				*s.err = err
 			case closeSignal:
+				m.stopLocation()
 				C.android_destroyManager(m.m)
 				close(v.out)
 				return // we don't need this goroutine anymore
@@ -133,6 +161,97 @@ func (m *manager) disable(t Type) error {
 	return nil
 }
 
+func (m *manager) enableLocation(interval time.Duration, a LocationAccuracy) error {
+	var err error
+	done := make(chan struct{})
+	m.inout <- inOut{
+		in:  enableLocationSignal{interval: interval, accuracy: a, err: &err},
+		out: done,
+	}
+	<-done
+	return err
+}
+
+func (m *manager) disableLocation() error {
+	done := make(chan struct{})
+	m.inout <- inOut{
+		in:  disableLocationSignal{},
+		out: done,
+	}
+	<-done
+	return nil
+}
+
+// androidContext returns the JavaVM and the android.content.Context of
+// the app.
+func androidContext() (vm *C.JavaVM, ctx C.jobject, err error) {
+	state, ok := app.State.(interface {
+		JavaVM() unsafe.Pointer
+		AndroidContext() unsafe.Pointer
+	})
+	if !ok || state.AndroidContext() == nil {
+		return nil, nil, errors.New("sensor: no Android context for the location")
+	}
+	return (*C.JavaVM)(state.JavaVM()), C.jobject(state.AndroidContext()), nil
+}
+
+// startLocation requests location updates from the Android
+// LocationManager, replacing any previous request.
+func (m *manager) startLocation(interval time.Duration, a LocationAccuracy) error {
+	m.stopLocation()
+	vm, ctx, err := androidContext()
+	if err != nil {
+		return err
+	}
+	p := newLocationPoller(interval)
+	fine := C.int(0)
+	if a == FineLocation {
+		fine = 1
+	}
+	millis := C.int64_t(p.interval / time.Millisecond)
+	switch C.android_startLocation(vm, ctx, fine, millis, &m.locProvider, &m.locIntent) {
+	case C.LOCATION_OK:
+	case C.LOCATION_NO_PERMISSION:
+		return ErrLocationPermission
+	default:
+		return errors.New("sensor: no location provider enabled on the device")
+	}
+	m.loc = p
+	return nil
+}
+
+func (m *manager) stopLocation() {
+	if m.loc == nil {
+		return
+	}
+	if vm, ctx, err := androidContext(); err == nil {
+		C.android_stopLocation(vm, ctx, m.locProvider, m.locIntent)
+	}
+	m.loc, m.locProvider, m.locIntent = nil, nil, nil
+}
+
+// lastLocation returns the last known location of the provider updated
+// by startLocation, if any.
+func (m *manager) lastLocation() (locationFix, bool) {
+	vm, ctx, err := androidContext()
+	if err != nil {
+		return locationFix{}, false
+	}
+	var f C.android_LocationFix
+	if C.android_lastLocation(vm, ctx, m.locProvider, &f) == 0 {
+		return locationFix{}, false
+	}
+	return locationFix{
+		latitude:    float64(f.latitude),
+		longitude:   float64(f.longitude),
+		altitude:    float64(f.altitude),
+		accuracy:    float64(f.accuracy),
+		hasAltitude: f.hasAltitude != 0,
+		hasAccuracy: f.hasAccuracy != 0,
+		time:        int64(f.time),
+	}, true
+}
+
 func (m *manager) read(e []Event) (n int, err error) {
 	done := make(chan struct{})
 	m.inout <- inOut{
@@ -144,17 +263,49 @@ func (m *manager) read(e []Event) (n int, err error) {
 }
 
 func readEvents(m *manager, e []Event) (n int, err error) {
+	if m.loc == nil {
+		return readQueue(m, e, readTimeout), nil
+	}
+	// The location is polled between the reads of the sensor events,
+	// until there are events or the read times out.
+	timeout := time.Now().Add(readTimeout)
+	for n == 0 && time.Now().Before(timeout) && len(e) > 0 {
+		now := time.Now()
+		if m.loc.wait(now) == 0 {
+			fix, ok := m.lastLocation()
+			if ev, ok := m.loc.poll(now, fix, ok); ok {
+				e[n] = ev
+				n++
+			}
+		}
+		now = time.Now()
+		wait := m.loc.wait(now)
+		if left := timeout.Sub(now); wait > left {
+			wait = left
+		}
+		n += readQueue(m, e[n:], wait)
+	}
+	return n, nil
+}
+
+// readQueue reads up to len(e) events from the sensor event queue,
+// waiting up to timeout for each.
+func readQueue(m *manager, e []Event, timeout time.Duration) int {
 	num := len(e)
+	if num == 0 {
+		return 0
+	}
 	types := make([]C.int32_t, num)
 	timestamps := make([]C.int64_t, num)
 	vectors := make([]C.float, 3*num)
 
-	n = int(C.android_readQueue(
+	n := int(C.android_readQueue(
 		m.m.looperId, m.m.queue,
 		C.int(num),
 		(*C.int32_t)(unsafe.Pointer(&types[0])),
 		(*C.int64_t)(unsafe.Pointer(&timestamps[0])),
-		(*C.float)(unsafe.Pointer(&vectors[0]))),
+		(*C.float)(unsafe.Pointer(&vectors[0])),
+		C.int(timeout/time.Millisecond)),
 	)
 	for i := 0; i < n; i++ {
 		e[i] = Event{
@@ -167,7 +318,7 @@ func readEvents(m *manager, e []Event) (n int, err error) {
 			},
 		}
 	}
-	return
+	return n
 }
 
 func (m *manager) close() error {
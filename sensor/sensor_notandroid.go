@@ -25,6 +25,14 @@ func (m *manager) disable(t Type) error {
 	return errors.New("sensor: no sensors available")
 }
 
+func (m *manager) enableLocation(interval time.Duration, a LocationAccuracy) error {
+	return errors.New("sensor: no location available")
+}
+
+func (m *manager) disableLocation() error {
+	return errors.New("sensor: no location available")
+}
+
 func (m *manager) read(e []Event) (n int, err error) {
 	return 0, errors.New("sensor: no sensor data available")
 }
@@ -5,6 +5,7 @@
 #include <stdlib.h>
 #include <jni.h>
 
+#include <android/log.h>
 #include <android/sensor.h>
 
 #include "sensors_android.h"
@@ -39,16 +40,16 @@ void android_disableSensor(ASensorEventQueue* q, int s) {
   ASensorEventQueue_disableSensor(q, sensor);
 }
 
-int android_readQueue(int looperId, ASensorEventQueue* q, int n, int32_t* types, int64_t* timestamps, float* vectors) {
+int android_readQueue(int looperId, ASensorEventQueue* q, int n, int32_t* types, int64_t* timestamps, float* vectors, int timeoutMillis) {
   int id;
   int events;
   ASensorEvent event;
   int i = 0;
-  // Block for 30 secs at most, timeout if nothing happens.
+  // Block for timeoutMillis at most, timeout if nothing happens.
   // Try n times read from the event queue.
   // If anytime timeout occurs, don't retry to read and immediately return.
   // Consume the event queue entirely between polls.
-  while (i < n && (id = ALooper_pollAll(30*1000, NULL, &events, NULL)) >= 0) {
+  while (i < n && (id = ALooper_pollAll(timeoutMillis, NULL, &events, NULL)) >= 0) {
     if (id != looperId) {
       continue;
     }
@@ -69,3 +70,189 @@ void android_destroyManager(android_SensorManager* m) {
   ASensorManager_destroyEventQueue(manager, m->queue);
   ALooper_release(m->looper);
 }
+
+#define LOG_INFO(...) __android_log_print(ANDROID_LOG_INFO, "Go/sensor", __VA_ARGS__)
+
+// attach returns the JNIEnv of the current thread, attaching the thread
+// to vm if needed, in which case *attached is set to 1.
+static JNIEnv* attach(JavaVM* vm, int* attached) {
+  JNIEnv* env;
+  *attached = 0;
+  switch ((*vm)->GetEnv(vm, (void**)&env, JNI_VERSION_1_6)) {
+  case JNI_OK:
+    return env;
+  case JNI_EDETACHED:
+    if ((*vm)->AttachCurrentThread(vm, &env, 0) != 0) {
+      return NULL;
+    }
+    *attached = 1;
+    return env;
+  }
+  return NULL;
+}
+
+static void detach(JavaVM* vm, int attached) {
+  if (attached) {
+    (*vm)->DetachCurrentThread(vm);
+  }
+}
+
+// has_exception clears and reports a pending Java exception, such as the
+// SecurityException of a call missing a permission.
+static int has_exception(JNIEnv* env) {
+  if ((*env)->ExceptionCheck(env)) {
+    (*env)->ExceptionClear(env);
+    return 1;
+  }
+  return 0;
+}
+
+static int has_permission(JNIEnv* env, jobject ctx, const char* name) {
+  jclass context_clazz = (*env)->FindClass(env, "android/content/Context");
+  jmethodID check = (*env)->GetMethodID(env, context_clazz, "checkCallingOrSelfPermission", "(Ljava/lang/String;)I");
+  jstring jname = (*env)->NewStringUTF(env, name);
+  jint res = (*env)->CallIntMethod(env, ctx, check, jname);
+  (*env)->DeleteLocalRef(env, jname);
+  return !has_exception(env) && res == 0; // PackageManager.PERMISSION_GRANTED
+}
+
+static jobject location_manager(JNIEnv* env, jobject ctx) {
+  jclass context_clazz = (*env)->FindClass(env, "android/content/Context");
+  jmethodID get_service = (*env)->GetMethodID(env, context_clazz, "getSystemService", "(Ljava/lang/String;)Ljava/lang/Object;");
+  jstring name = (*env)->NewStringUTF(env, "location"); // Context.LOCATION_SERVICE
+  jobject lm = (*env)->CallObjectMethod(env, ctx, get_service, name);
+  (*env)->DeleteLocalRef(env, name);
+  if (has_exception(env)) {
+    return NULL;
+  }
+  return lm;
+}
+
+static int provider_enabled(JNIEnv* env, jobject lm, jstring provider) {
+  jclass lm_clazz = (*env)->GetObjectClass(env, lm);
+  jmethodID enabled = (*env)->GetMethodID(env, lm_clazz, "isProviderEnabled", "(Ljava/lang/String;)Z");
+  jboolean res = (*env)->CallBooleanMethod(env, lm, enabled, provider);
+  return !has_exception(env) && res;
+}
+
+// android_startLocation requests location updates every intervalMillis
+// from the gps provider, or the network provider if fine is 0 or gps is
+// disabled. The updates are delivered to a PendingIntent broadcast with
+// no receiver: the app only reads the last known location, which the
+// updates keep current, so no Java class is needed. The provider and
+// intent are returned as global references for android_lastLocation and
+// android_stopLocation.
+int android_startLocation(JavaVM* vm, jobject ctx, int fine, int64_t intervalMillis, jstring* provider, jobject* intent) {
+  int attached;
+  JNIEnv* env = attach(vm, &attached);
+  if (env == NULL) {
+    return LOCATION_NO_PROVIDER;
+  }
+  int ret = LOCATION_OK;
+  if (fine) {
+    if (!has_permission(env, ctx, "android.permission.ACCESS_FINE_LOCATION")) {
+      ret = LOCATION_NO_PERMISSION;
+      goto done;
+    }
+  } else if (!has_permission(env, ctx, "android.permission.ACCESS_COARSE_LOCATION") &&
+      !has_permission(env, ctx, "android.permission.ACCESS_FINE_LOCATION")) {
+    ret = LOCATION_NO_PERMISSION;
+    goto done;
+  }
+  jobject lm = location_manager(env, ctx);
+  if (lm == NULL) {
+    ret = LOCATION_NO_PROVIDER;
+    goto done;
+  }
+  jstring p = (*env)->NewStringUTF(env, fine ? "gps" : "network");
+  if (!provider_enabled(env, lm, p)) {
+    (*env)->DeleteLocalRef(env, p);
+    p = (*env)->NewStringUTF(env, "network");
+    if (!provider_enabled(env, lm, p)) {
+      ret = LOCATION_NO_PROVIDER;
+      goto done;
+    }
+  }
+
+  // PendingIntent intent = PendingIntent.getBroadcast(ctx, 0, new Intent(action), 0);
+  jclass intent_clazz = (*env)->FindClass(env, "android/content/Intent");
+  jmethodID intent_init = (*env)->GetMethodID(env, intent_clazz, "<init>", "(Ljava/lang/String;)V");
+  jstring action = (*env)->NewStringUTF(env, "golang.org/x/mobile/sensor.LOCATION");
+  jobject i = (*env)->NewObject(env, intent_clazz, intent_init, action);
+  jclass pending_clazz = (*env)->FindClass(env, "android/app/PendingIntent");
+  jmethodID get_broadcast = (*env)->GetStaticMethodID(env, pending_clazz, "getBroadcast", "(Landroid/content/Context;ILandroid/content/Intent;I)Landroid/app/PendingIntent;");
+  jobject pi = (*env)->CallStaticObjectMethod(env, pending_clazz, get_broadcast, ctx, 0, i, 0);
+  if (has_exception(env) || pi == NULL) {
+    ret = LOCATION_NO_PROVIDER;
+    goto done;
+  }
+
+  // lm.requestLocationUpdates(provider, intervalMillis, 0, intent);
+  jclass lm_clazz = (*env)->GetObjectClass(env, lm);
+  jmethodID request = (*env)->GetMethodID(env, lm_clazz, "requestLocationUpdates", "(Ljava/lang/String;JFLandroid/app/PendingIntent;)V");
+  (*env)->CallVoidMethod(env, lm, request, p, (jlong)intervalMillis, (jfloat)0, pi);
+  if (has_exception(env)) {
+    // A SecurityException: the permission was revoked.
+    ret = LOCATION_NO_PERMISSION;
+    goto done;
+  }
+  *provider = (*env)->NewGlobalRef(env, p);
+  *intent = (*env)->NewGlobalRef(env, pi);
+  LOG_INFO("location updates from the %s provider", fine ? "fine" : "coarse");
+
+done:
+  detach(vm, attached);
+  return ret;
+}
+
+void android_stopLocation(JavaVM* vm, jobject ctx, jstring provider, jobject intent) {
+  int attached;
+  JNIEnv* env = attach(vm, &attached);
+  if (env == NULL) {
+    return;
+  }
+  jobject lm = location_manager(env, ctx);
+  if (lm != NULL) {
+    jclass lm_clazz = (*env)->GetObjectClass(env, lm);
+    jmethodID remove = (*env)->GetMethodID(env, lm_clazz, "removeUpdates", "(Landroid/app/PendingIntent;)V");
+    (*env)->CallVoidMethod(env, lm, remove, intent);
+    has_exception(env);
+  }
+  (*env)->DeleteGlobalRef(env, provider);
+  (*env)->DeleteGlobalRef(env, intent);
+  detach(vm, attached);
+}
+
+// android_lastLocation stores the last known location of the provider in
+// dst, and returns 1, or returns 0 if there is none yet.
+int android_lastLocation(JavaVM* vm, jobject ctx, jstring provider, android_LocationFix* dst) {
+  int attached;
+  JNIEnv* env = attach(vm, &attached);
+  if (env == NULL) {
+    return 0;
+  }
+  int ret = 0;
+  jobject lm = location_manager(env, ctx);
+  if (lm == NULL) {
+    goto done;
+  }
+  jclass lm_clazz = (*env)->GetObjectClass(env, lm);
+  jmethodID last = (*env)->GetMethodID(env, lm_clazz, "getLastKnownLocation", "(Ljava/lang/String;)Landroid/location/Location;");
+  jobject loc = (*env)->CallObjectMethod(env, lm, last, provider);
+  if (has_exception(env) || loc == NULL) {
+    goto done;
+  }
+  jclass loc_clazz = (*env)->FindClass(env, "android/location/Location");
+  dst->latitude = (*env)->CallDoubleMethod(env, loc, (*env)->GetMethodID(env, loc_clazz, "getLatitude", "()D"));
+  dst->longitude = (*env)->CallDoubleMethod(env, loc, (*env)->GetMethodID(env, loc_clazz, "getLongitude", "()D"));
+  dst->altitude = (*env)->CallDoubleMethod(env, loc, (*env)->GetMethodID(env, loc_clazz, "getAltitude", "()D"));
+  dst->accuracy = (*env)->CallFloatMethod(env, loc, (*env)->GetMethodID(env, loc_clazz, "getAccuracy", "()F"));
+  dst->hasAltitude = (*env)->CallBooleanMethod(env, loc, (*env)->GetMethodID(env, loc_clazz, "hasAltitude", "()Z"));
+  dst->hasAccuracy = (*env)->CallBooleanMethod(env, loc, (*env)->GetMethodID(env, loc_clazz, "hasAccuracy", "()Z"));
+  dst->time = (*env)->CallLongMethod(env, loc, (*env)->GetMethodID(env, loc_clazz, "getTime", "()J"));
+  ret = 1;
+
+done:
+  detach(vm, attached);
+  return ret;
+}
@@ -15,6 +15,25 @@ void android_createManager(int looperId, android_SensorManager* dst);
 void android_destroyManager(android_SensorManager* m);
 int  android_enableSensor(ASensorEventQueue*, int, int32_t);
 void android_disableSensor(ASensorEventQueue*, int);
-int  android_readQueue(int looperId, ASensorEventQueue* q, int n, int32_t* types, int64_t* timestamps, float* vectors);
+int  android_readQueue(int looperId, ASensorEventQueue* q, int n, int32_t* types, int64_t* timestamps, float* vectors, int timeoutMillis);
+
+// The results of android_startLocation.
+#define LOCATION_OK 0
+#define LOCATION_NO_PERMISSION 1
+#define LOCATION_NO_PROVIDER 2
+
+typedef struct android_LocationFix {
+  double latitude;
+  double longitude;
+  double altitude;
+  float accuracy;
+  int hasAltitude;
+  int hasAccuracy;
+  int64_t time;
+} android_LocationFix;
+
+int  android_startLocation(JavaVM* vm, jobject ctx, int fine, int64_t intervalMillis, jstring* provider, jobject* intent);
+void android_stopLocation(JavaVM* vm, jobject ctx, jstring provider, jobject intent);
+int  android_lastLocation(JavaVM* vm, jobject ctx, jstring provider, android_LocationFix* dst);
 
 #endif